@@ -4,11 +4,14 @@ import (
 	"context"
 	"embed"
 	"flag"
+	"fmt"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -19,6 +22,8 @@ import (
 	"specularium/internal/domain"
 	"specularium/internal/handler"
 	"specularium/internal/hub"
+	"specularium/internal/logging"
+	"specularium/internal/metrics"
 	"specularium/internal/repository/sqlite"
 	"specularium/internal/service"
 )
@@ -41,12 +46,38 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
-	if configPath != "" {
+
+	// Leveled logging; LOG_LEVEL overrides the configured level
+	logLevel := cfg.Logging.Level
+	if env := os.Getenv(logging.EnvLevel); env != "" {
+		logLevel = env
+	}
+	level, err := logging.ParseLevel(logLevel)
+	if err != nil {
+		log.Fatalf("Invalid log level: %v", err)
+	}
+	logger, err := logging.New(os.Stderr, level, cfg.Logging.Format)
+	if err != nil {
+		log.Fatalf("Invalid logging config: %v", err)
+	}
+	slog.SetDefault(logger)
+
+	// A fresh instance UUID is saved to an existing config file here;
+	// without one it is written along with the bootstrap results
+	newUUID := cfg.EnsureInstanceUUID()
+	haveConfigFile := configPath != ""
+	if haveConfigFile {
 		log.Printf("Loaded config from: %s", configPath)
+		if newUUID {
+			if err := cfg.Save(configPath); err != nil {
+				log.Printf("Warning: Failed to save instance UUID to config: %v", err)
+			}
+		}
 	} else {
 		log.Println("No config file found, using defaults")
 		configPath = config.DefaultConfigPath()
 	}
+	log.Printf("Instance: %s (%s), self node %s", cfg.Instance.EffectiveName(), cfg.Instance.UUID, cfg.Instance.SelfNodeID())
 
 	// Determine effective settings (flags override config)
 	addr := cfg.Database.Path // placeholder, replaced below
@@ -89,22 +120,57 @@ func main() {
 	_ = forceBootstrap // Will be used when Phase 3 is implemented
 
 	// Initialize SQLite repository
-	repo, err := sqlite.New(dbPath)
+	repo, err := sqlite.NewWithOptions(dbPath, sqlite.Options{
+		MaxOpenConns: cfg.Database.MaxOpenConns,
+		MaxIdleConns: cfg.Database.MaxIdleConns,
+		BusyTimeout:  time.Duration(cfg.Database.BusyTimeout),
+	})
 	if err != nil {
 		log.Fatalf("Failed to open database: %v", err)
 	}
 	defer repo.Close()
 	log.Printf("Database opened: %s", dbPath)
 
+	// Installs from before named instances keep their existing self node
+	if cfg.AdoptLegacySelfNode(func(id string) bool {
+		node, err := repo.GetNode(context.Background(), id)
+		return err == nil && node != nil
+	}) {
+		log.Printf("Keeping existing self node %s", cfg.Instance.SelfNodeID())
+		if haveConfigFile {
+			if err := cfg.Save(configPath); err != nil {
+				log.Printf("Warning: Failed to save self node ID to config: %v", err)
+			}
+		}
+	}
+	if len(behavior.VerifyBackoff) > 0 {
+		repo.SetVerifyBackoff(behavior.VerifyBackoff)
+		log.Printf("Verification backoff for unreachable nodes: %v", behavior.VerifyBackoff)
+	}
+
 	// Initialize event bus
 	eventBus := service.NewEventBus()
+	eventBus.SetHistorySize(cfg.Events.EffectiveHistorySize())
+	overflow, err := service.ParseOverflowPolicy(cfg.Events.Overflow)
+	if err != nil {
+		log.Fatalf("Invalid events config: %v", err)
+	}
+	eventBus.SetOverflowPolicy(overflow)
 
 	// Initialize SSE hub
 	sseHub := hub.New()
+	sseHub.SetReplay(func(seq uint64) ([]interface{}, bool) {
+		events, ok := eventBus.Since(seq)
+		replay := make([]interface{}, len(events))
+		for i, e := range events {
+			replay[i] = e
+		}
+		return replay, ok
+	})
 	go sseHub.Run()
 
 	// Connect event bus to SSE hub
-	eventChan := make(chan service.Event, 100)
+	eventChan := make(chan service.Event, cfg.Events.EffectiveBufferSize())
 	eventBus.Subscribe(eventChan)
 	go func() {
 		for event := range eventChan {
@@ -112,11 +178,55 @@ func main() {
 		}
 	}()
 
+	// Post selected events to configured webhooks
+	notifyCtx, notifyCancel := context.WithCancel(context.Background())
+	defer notifyCancel()
+	if len(cfg.Notify.Webhooks) > 0 {
+		hooks := make([]service.Webhook, len(cfg.Notify.Webhooks))
+		for i, wh := range cfg.Notify.Webhooks {
+			hooks[i] = service.Webhook{URL: wh.URL, Payload: wh.Payload, Headers: wh.Headers}
+			for _, t := range wh.Events {
+				hooks[i].Events = append(hooks[i].Events, service.EventType(t))
+			}
+		}
+		notifier, err := service.NewWebhookNotifier(hooks, service.WebhookOptions{
+			Timeout: time.Duration(cfg.Notify.Timeout),
+			Retries: cfg.Notify.Retries,
+		})
+		if err != nil {
+			log.Fatalf("Invalid notify config: %v", err)
+		}
+		notifier.SetLogger(logger.With("component", "webhook"))
+		notifier.Start(notifyCtx, eventBus)
+		log.Printf("Webhook notifications enabled for %d URL(s)", len(hooks))
+	}
+
 	// Initialize services
 	graphSvc := service.NewGraphService(repo, eventBus)
+
+	// Age capability evidence so stale observations are trusted less
+	graphSvc.SetEvidenceHalfLife(behavior.EvidenceHalfLife)
+	if behavior.EvidenceHalfLife > 0 {
+		log.Printf("Evidence confidence half-life: %s", behavior.EvidenceHalfLife)
+	}
+
 	truthSvc := service.NewTruthService(repo, eventBus)
 	secretsSvc := service.NewSecretsService(repo, eventBus)
 
+	// Encrypt operator secret data at rest when a key is configured
+	if key := os.Getenv(service.SecretKeyEnv); key != "" {
+		secretCipher, err := service.NewSecretCipher(key)
+		if err != nil {
+			log.Fatalf("Failed to initialize secret encryption: %v", err)
+		}
+		secretsSvc.SetCipher(secretCipher)
+		log.Println("Secret data encryption enabled")
+	} else {
+		log.Printf("Warning: %s not set, operator secret data is stored in plaintext", service.SecretKeyEnv)
+	}
+
+	secretsSvc.SetTester(adapter.NewSecretTester(0))
+
 	// Load mounted secrets at startup
 	if err := secretsSvc.LoadMountedSecrets(); err != nil {
 		log.Printf("Warning: Failed to load mounted secrets: %v", err)
@@ -124,13 +234,37 @@ func main() {
 
 	// Initialize capability manager for adapter access to secrets
 	capabilityMgr := adapter.NewCapabilityManager(secretsSvc)
+	capabilityMgr.SetSecretName("ssh", cfg.Capabilities.Plugins.SSHProbe.Secret)
+	capabilityMgr.SetSecretName("snmpv2", cfg.Capabilities.Plugins.SNMP.Secret)
+	capabilityMgr.SetSecretName("snmpv3", cfg.Capabilities.Plugins.SNMP.Secret)
 
 	// Initialize reconcile service for adapter discoveries
 	reconcileSvc := service.NewReconcileService(repo, truthSvc, eventBus)
+	reconcileSvc.SetLogger(logger.With("component", "reconcile"))
+	reconcileSvc.SetSourcePriorities(cfg.Reconcile.SourcePriority)
+	reconcileSvc.SetDebounce(time.Duration(cfg.Reconcile.Debounce))
+	if err := reconcileSvc.SetPropertyFilter(cfg.Reconcile.Allow, cfg.Reconcile.Deny); err != nil {
+		log.Fatalf("Invalid reconcile config: %v", err)
+	}
 
 	// Initialize adapter registry with reconcile function
 	adapterRegistry := adapter.NewRegistry(reconcileSvc.ReconcileFragment)
 
+	// Prometheus metrics, read from the repository and live counters at scrape time
+	metricsReg := metrics.NewRegistry()
+	graphSvc.RegisterMetrics(metricsReg)
+	truthSvc.RegisterMetrics(metricsReg)
+	eventBus.RegisterMetrics(metricsReg)
+	metricsReg.GaugeFunc("specularium_sse_clients", "Number of connected SSE clients.",
+		func(ctx context.Context) (float64, error) { return float64(sseHub.ClientCount()), nil })
+	syncMetrics := adapter.NewSyncMetrics(metricsReg)
+	adapterRegistry.SetMetrics(syncMetrics)
+
+	// Bound concurrent scans, syncs and verification across all adapters
+	discoveryLimiter := adapter.NewOperationLimiter(
+		cfg.Discovery.EffectiveMaxConcurrent(), cfg.Discovery.EffectiveMaxQueued())
+	adapterRegistry.SetLimiter(discoveryLimiter)
+
 	// Set up discovery event handler to broadcast to SSE
 	adapterRegistry.SetDiscoveryEventHandler(func(eventType string, payload interface{}) {
 		eventBus.Publish(service.Event{
@@ -140,11 +274,18 @@ func main() {
 	})
 
 	// Register verifier adapter (if basic_verification enabled and mode >= monitor)
+	var verifier *nodeVerifier
 	if cfg.Capabilities.IsEnabled("basic_verification", effectiveMode) {
 		verifierConfig := adapter.DefaultVerifierConfig()
 		verifierConfig.Capabilities = capabilityMgr
 		verifierConfig.PingTimeout = behavior.ProbeTimeout
 		verifierConfig.MaxConcurrent = behavior.MaxConcurrentProbes
+		verifierConfig.MinConcurrent = behavior.MinConcurrentProbes
+		if behavior.PreferredFamily != "" {
+			verifierConfig.PreferredFamily = adapter.AddressFamily(behavior.PreferredFamily)
+		}
+		verifierConfig.ProbeBothFamilies = behavior.ProbeBothFamilies
+		verifierConfig.EnableNativeICMP = behavior.NativeICMP
 		// Use custom DNS server for PTR lookups if configured
 		if cfg.Secrets.DNSServer != nil {
 			verifierConfig.DNSServer = *cfg.Secrets.DNSServer
@@ -152,17 +293,20 @@ func main() {
 			verifierConfig.DNSServer = dnsServer
 		}
 		verifierAdapter := adapter.NewVerifierAdapter(repo, verifierConfig)
+		verifierAdapter.SetLogger(logger.With("component", "verifier"))
 		adapterRegistry.Register(verifierAdapter, adapter.AdapterConfig{
 			Enabled:      true,
 			Priority:     50,
 			PollInterval: behavior.VerifyInterval.String(),
 		})
+		verifier = &nodeVerifier{registry: adapterRegistry, name: verifierAdapter.Name()}
 		log.Println("Verifier adapter enabled")
 	}
 
 	// Register SSH probe adapter (if enabled in config and mode >= discovery)
 	if cfg.Capabilities.IsEnabled("ssh_probe", effectiveMode) || os.Getenv("ENABLE_SSH_PROBE") == "true" {
 		sshProbeConfig := adapter.DefaultSSHProbeConfig()
+		sshProbeConfig.SecretName = cfg.Capabilities.Plugins.SSHProbe.Secret
 		sshProbeAdapter := adapter.NewSSHProbeAdapter(secretsSvc, sshProbeConfig)
 		sshProbeAdapter.SetEventPublisher(adapterRegistry)
 		adapterRegistry.Register(sshProbeAdapter, adapter.AdapterConfig{
@@ -173,26 +317,108 @@ func main() {
 		log.Println("SSH probe adapter enabled")
 	}
 
-	// Register nmap adapter (if enabled in config and mode >= discovery)
-	nmapEnabled := cfg.Capabilities.IsEnabled("nmap", effectiveMode)
-	nmapTargets := cfg.Targets.Primary
-	// Fall back to env var for backwards compatibility
-	if len(nmapTargets) == 0 {
-		if scanSubnets := os.Getenv("SCAN_SUBNETS"); scanSubnets != "" {
-			nmapTargets = strings.Split(scanSubnets, ",")
+	// Register SNMP adapter (if enabled in config and mode >= discovery)
+	if cfg.Capabilities.IsEnabled("snmp", effectiveMode) {
+		snmpConfig := adapter.DefaultSNMPConfig()
+		snmpConfig.Capabilities = capabilityMgr
+		snmpConfig.Timeout = behavior.ProbeTimeout
+		snmpAdapter := adapter.NewSNMPAdapter(repo, snmpConfig)
+		adapterRegistry.Register(snmpAdapter, adapter.AdapterConfig{
+			Enabled:      true,
+			Priority:     70,
+			PollInterval: "10m",
+		})
+		log.Println("SNMP adapter enabled")
+	}
+
+	// Register mDNS adapter (if enabled in config and mode >= discovery)
+	if cfg.Capabilities.IsEnabled("mdns", effectiveMode) {
+		mdnsAdapter := adapter.NewMDNSAdapter(repo, adapter.DefaultMDNSConfig())
+		adapterRegistry.Register(mdnsAdapter, adapter.AdapterConfig{
+			Enabled:  true,
+			Priority: 40,
+		})
+		// Hosts that only advertise over mDNS are new to the graph
+		reconcileSvc.AllowNodeCreation(mdnsAdapter.Name())
+		log.Println("mDNS adapter enabled")
+	}
+
+	// Register ARP adapter (if enabled in config and mode >= discovery)
+	if cfg.Capabilities.IsEnabled("arp", effectiveMode) {
+		arpConfig := adapter.DefaultARPConfig()
+		arpConfig.Subnets = cfg.Targets.EnabledPrimary()
+		arpAdapter := adapter.NewARPAdapter(repo, arpConfig)
+		arpAdapter.SetEventPublisher(adapterRegistry)
+		adapterRegistry.Register(arpAdapter, adapter.AdapterConfig{
+			Enabled:  true,
+			Priority: 45,
+		})
+		// Silent devices only visible at layer 2 are new to the graph
+		reconcileSvc.AllowNodeCreation(arpAdapter.Name())
+		log.Println("ARP adapter enabled")
+	}
+
+	// Register LLDP adapter (if enabled in config and mode >= monitor). Pods
+	// only see the cluster network, so the host's lldpd is out of reach.
+	if cfg.Capabilities.IsEnabled("lldp", effectiveMode) && os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		lldpConfig := adapter.DefaultLLDPConfig()
+		lldpConfig.OriginID = cfg.Instance.SelfNodeID()
+		lldpAdapter := adapter.NewLLDPAdapter(repo, lldpConfig)
+		adapterRegistry.Register(lldpAdapter, adapter.AdapterConfig{
+			Enabled:      true,
+			Priority:     65,
+			PollInterval: "5m",
+		})
+		// Switches announce themselves before anything else has seen them
+		reconcileSvc.AllowNodeCreation(lldpAdapter.Name())
+		log.Println("LLDP adapter enabled")
+	}
+
+	// Named port lists selectable per scan request or nmap target
+	portProfiles := adapter.PortProfiles(cfg.Discovery.PortProfiles)
+	if err := portProfiles.Validate(); err != nil {
+		log.Fatalf("Invalid discovery config: %v", err)
+	}
+	// Port evidence rules for node type inference, tunable per deployment
+	typeWeights := cfg.Discovery.TypeWeights
+	for _, w := range typeWeights {
+		if err := w.Validate(); err != nil {
+			log.Fatalf("Invalid discovery config: %v", err)
 		}
 	}
+
+	// Register nmap adapter (if enabled in config and mode >= discovery)
+	nmapEnabled := cfg.Capabilities.IsEnabled("nmap", effectiveMode)
+	nmapTargets := nmapTargetList(cfg)
 	if nmapEnabled && len(nmapTargets) > 0 {
-		nmapAdapter := adapter.NewNmapAdapter(
-			nmapTargets,
+		nmapOpts := []adapter.NmapOption{
 			adapter.WithCommonPorts(),
 			adapter.WithServiceDetection(true),
-		)
+			adapter.WithPortProfiles(portProfiles, cfg.Targets.PrimaryProfiles()),
+			adapter.WithTypeWeights(typeWeights),
+		}
+		// Skip rescans of targets scanned within the TTL
+		if ttl, err := time.ParseDuration(os.Getenv("NMAP_CACHE_TTL")); err == nil && ttl > 0 {
+			nmapOpts = append(nmapOpts, adapter.WithResultCacheTTL(ttl))
+			log.Printf("Nmap result cache TTL: %s", ttl)
+		}
+		// Also scan UDP services such as DNS, NTP and SNMP (needs root)
+		if udpPorts := os.Getenv("NMAP_UDP_PORTS"); udpPorts != "" {
+			var ports []int
+			for _, field := range strings.Split(udpPorts, ",") {
+				if port, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+					ports = append(ports, port)
+				}
+			}
+			nmapOpts = append(nmapOpts, adapter.WithUDPScan(ports))
+		}
+		nmapAdapter := adapter.NewNmapAdapter(nmapTargets, nmapOpts...)
 		nmapAdapter.SetEventPublisher(adapterRegistry)
 		adapterRegistry.Register(nmapAdapter, adapter.AdapterConfig{
 			Enabled:      true,
 			Priority:     80,
 			PollInterval: behavior.ScanInterval.String(),
+			Settings:     nmapSettings(cfg, nmapTargets),
 		})
 		log.Printf("Nmap adapter registered for targets: %v", nmapTargets)
 	} else if !nmapEnabled {
@@ -204,25 +430,83 @@ func main() {
 	// Create scanner adapter with service wrapper and capabilities
 	scannerConfig := adapter.DefaultScannerConfig()
 	scannerConfig.Capabilities = capabilityMgr
+	scannerConfig.PortProfiles = portProfiles
+	scannerConfig.TypeWeights = typeWeights
 	// Use custom DNS server for PTR lookups if configured (e.g., Technitium)
 	if dnsServer := os.Getenv("DNS_SERVER"); dnsServer != "" {
 		scannerConfig.DNSServer = dnsServer
 		log.Printf("Scanner using custom DNS server for PTR lookups: %s", dnsServer)
 	}
+	// Scan size limit follows the posture (behavior.max_scan_hosts), capped
+	// below discovery mode; SCAN_MAX_HOSTS overrides it
+	scannerConfig.MaxHosts = cfg.EffectiveMaxScanHosts()
+	if maxHosts, err := strconv.Atoi(os.Getenv("SCAN_MAX_HOSTS")); err == nil && maxHosts > 0 {
+		scannerConfig.MaxHosts = maxHosts
+	}
+	log.Printf("Scanner host limit: %d (per-scan max_hosts up to %d)", scannerConfig.MaxHosts, adapter.MaxScanHostsCeiling)
 	scannerAdapter := adapter.NewScannerAdapter(scannerConfig)
+	scannerAdapter.SetLogger(logger.With("component", "scanner"))
+
+	// Build the first-contact enrichment pipeline for newly discovered nodes
+	if !cfg.Enrichment.Disabled {
+		enricherConfig := adapter.DefaultEnricherConfig()
+		enricherConfig.DNSServer = scannerConfig.DNSServer
+		enricherConfig.Capabilities = capabilityMgr
+
+		steps := cfg.Enrichment.Steps
+		if len(steps) == 0 {
+			steps = adapter.DefaultEnrichmentSteps
+		}
+		var enrichers []service.Enricher
+		for _, step := range steps {
+			e, err := adapter.NewEnricher(step, enricherConfig)
+			if err != nil {
+				log.Printf("Warning: skipping enrichment step: %v", err)
+				continue
+			}
+			enrichers = append(enrichers, e)
+		}
+		pipeline := service.NewEnrichmentPipeline(enrichers, cfg.Enrichment.StopConfidence)
+		reconcileSvc.SetEnrichmentPipeline(pipeline)
+		log.Printf("First-contact enrichment steps: %v", pipeline.Steps())
+	}
 
 	// Create scanner service that saves discovered hosts
 	scannerSvc := &scannerService{
-		scanner:  scannerAdapter,
-		repo:     repo,
-		eventBus: eventBus,
+		scanner:   scannerAdapter,
+		repo:      repo,
+		eventBus:  eventBus,
+		reconcile: reconcileSvc,
+		metrics:   syncMetrics,
 	}
 	// Connect scanner to event bus for progress updates
 	scannerAdapter.SetEventPublisher(adapterRegistry)
 
+	// Trace paths to scanned subnets to reveal intermediate routers (optional)
+	if cfg.Capabilities.IsEnabled("traceroute", effectiveMode) {
+		tracerConfig := adapter.DefaultTracerouteConfig()
+		tracerConfig.OriginID = cfg.Instance.SelfNodeID()
+		tracer := adapter.NewTracerouteAdapter(tracerConfig)
+		if tracer.Available() {
+			scannerSvc.tracer = tracer
+			tracer.SetNodeLister(repo)
+			adapterRegistry.Register(tracer, adapter.AdapterConfig{
+				Enabled:      true,
+				Priority:     60,
+				PollInterval: "1h",
+			})
+			// Intermediate hops are usually routers nothing else has seen
+			reconcileSvc.AllowNodeCreation(tracer.Name())
+			log.Println("Traceroute path discovery enabled")
+		} else {
+			log.Println("Traceroute: enabled in config but neither traceroute nor mtr found")
+		}
+	}
+
 	// Create bootstrap adapter for self-discovery
 	bootstrapAdapter := adapter.NewBootstrapAdapter()
 	bootstrapAdapter.SetEventPublisher(adapterRegistry)
+	bootstrapAdapter.SetInstance(cfg.Instance)
 
 	// Start bootstrap adapter to detect environment
 	if err := bootstrapAdapter.Start(context.Background()); err != nil {
@@ -282,82 +566,177 @@ func main() {
 		log.Printf("Warning: Failed to start adapter registry: %v", err)
 	}
 
+	// Mark nodes that have not been seen for a while as stale
+	if !cfg.Stale.Disabled {
+		stalePolicy := service.StalePolicy{
+			StaleAfter:  cfg.Stale.EffectiveAfter(),
+			DeleteAfter: time.Duration(cfg.Stale.DeleteAfter),
+		}
+		go graphSvc.RunStaleSweeper(adapterCtx, cfg.Stale.EffectiveInterval(), stalePolicy)
+		log.Printf("Stale sweep: after=%s, delete_after=%s, interval=%s",
+			stalePolicy.StaleAfter, stalePolicy.DeleteAfter, cfg.Stale.EffectiveInterval())
+	}
+
+	// Warn about secrets coming due for rotation
+	go secretsSvc.RunExpirySweeper(adapterCtx, time.Hour, 7*24*time.Hour)
+
 	// Initialize HTTP handlers
 	graphHandler := handler.NewGraphHandler(graphSvc)
 	graphHandler.SetDiscoveryTrigger(adapterRegistry)
+	graphHandler.SetAdapterLister(adapterRegistry)
 	graphHandler.SetSubnetScanner(scannerSvc)
 	graphHandler.SetBootstrapper(bootstrapSvc)
+	graphHandler.SetTargetLister(&cfg.Targets)
+	graphHandler.SetDiscoveryLimiter(discoveryLimiter)
+	graphHandler.SetOperationContext(adapterCtx, cfg.Discovery.EffectiveOperationTimeout())
+	if verifier != nil {
+		graphHandler.SetNodeVerifier(verifier)
+	}
 	truthHandler := handler.NewTruthHandler(truthSvc)
 	secretsHandler := handler.NewSecretsHandler(secretsSvc)
 	secretsHandler.SetCapabilityChecker(capabilityMgr)
+	eventsHandler := handler.NewEventsHandler(eventBus)
+
+	// Applies config file changes to adapters without a restart
+	adminHandler := handler.NewAdminHandler(&adapterReloader{
+		path:     configPath,
+		registry: adapterRegistry,
+	}, cfg.Auth.Enabled)
+	adminHandler.SetVacuumer(repo)
+
+	// Replays discovery trigger responses for repeated Idempotency-Key headers
+	idempotency := handler.NewIdempotencyCache(0)
 
 	// Setup routes
 	mux := http.NewServeMux()
 
 	// Graph endpoint (complete graph with positions)
 	mux.HandleFunc("GET /api/graph", graphHandler.GetGraph)
+	mux.HandleFunc("GET /api/graph/stats", graphHandler.GetStats)
+	mux.HandleFunc("GET /api/graph/orphans", graphHandler.ListOrphans)
+	mux.HandleFunc("GET /api/graph/connectivity", graphHandler.GetConnectivity)
+	mux.HandleFunc("POST /api/graph/infer-edges", graphHandler.InferEdges)
+	mux.HandleFunc("POST /api/graph/diff", graphHandler.DiffGraph)
 	mux.HandleFunc("DELETE /api/graph", graphHandler.ClearGraph)
-	mux.HandleFunc("POST /api/discover", graphHandler.TriggerDiscovery)
+	mux.HandleFunc("POST /api/discover", idempotency.Wrap(graphHandler.TriggerDiscovery))
+	mux.HandleFunc("GET /api/discovery/status", graphHandler.GetDiscoveryStatus)
+	mux.HandleFunc("GET /api/adapters", graphHandler.ListAdapters)
+	mux.HandleFunc("POST /api/verify", graphHandler.VerifyNodes)
 
 	// Bootstrap / environment endpoints
 	mux.HandleFunc("POST /api/bootstrap", graphHandler.Bootstrap)
 	mux.HandleFunc("GET /api/environment", graphHandler.GetEnvironment)
 	mux.HandleFunc("POST /api/client", graphHandler.RegisterClient)
+	mux.HandleFunc("GET /api/targets", graphHandler.GetTargets)
 
 	// Node endpoints
 	mux.HandleFunc("GET /api/nodes", graphHandler.ListNodes)
 	mux.HandleFunc("POST /api/nodes", graphHandler.CreateNode)
+	mux.HandleFunc("POST /api/nodes/batch", graphHandler.CreateNodes)
 	mux.HandleFunc("POST /api/nodes/merge", graphHandler.MergeNodes)
+	// Not /api/nodes/unmerge/{merge_id}: that would clash with /api/nodes/{id}/restore
+	mux.HandleFunc("POST /api/nodes/merge/{merge_id}/undo", graphHandler.UnmergeNodes)
+	mux.HandleFunc("POST /api/nodes/merge-by-identity", graphHandler.MergeNodesByIdentity)
+	mux.HandleFunc("GET /api/nodes/search", graphHandler.SearchNodes)
 	mux.HandleFunc("GET /api/nodes/{id}", graphHandler.GetNode)
 	mux.HandleFunc("PUT /api/nodes/{id}", graphHandler.UpdateNode)
 	mux.HandleFunc("DELETE /api/nodes/{id}", graphHandler.DeleteNode)
+	mux.HandleFunc("POST /api/nodes/{id}/restore", graphHandler.RestoreNode)
+	mux.HandleFunc("GET /api/nodes/{id}/image", graphHandler.GetNodeImage)
+	mux.HandleFunc("PUT /api/nodes/{id}/image", graphHandler.PutNodeImage)
+	mux.HandleFunc("DELETE /api/nodes/{id}/image", graphHandler.DeleteNodeImage)
+	mux.HandleFunc("POST /api/nodes/{id}/tags", graphHandler.AddNodeTags)
+	mux.HandleFunc("DELETE /api/nodes/{id}/tags", graphHandler.RemoveNodeTags)
+	mux.HandleFunc("POST /api/tags/{tag}/apply", graphHandler.ApplyTag)
+	mux.HandleFunc("GET /api/nodes/{id}/history", graphHandler.GetNodeHistory)
+	mux.HandleFunc("GET /api/nodes/{id}/neighbors", graphHandler.GetNodeNeighbors)
+	mux.HandleFunc("GET /api/nodes/{id}/capabilities", graphHandler.GetNodeCapabilities)
 
 	// Edge endpoints
 	mux.HandleFunc("GET /api/edges", graphHandler.ListEdges)
 	mux.HandleFunc("POST /api/edges", graphHandler.CreateEdge)
+	mux.HandleFunc("GET /api/edges/between", graphHandler.GetEdgesBetween)
+	mux.HandleFunc("POST /api/edges/infer", graphHandler.SuggestEdgeType)
 	mux.HandleFunc("GET /api/edges/{id}", graphHandler.GetEdge)
 	mux.HandleFunc("PUT /api/edges/{id}", graphHandler.UpdateEdge)
 	mux.HandleFunc("DELETE /api/edges/{id}", graphHandler.DeleteEdge)
 
+	// Subnet endpoints; the CIDR's slash makes it a wildcard segment
+	mux.HandleFunc("GET /api/subnets", graphHandler.ListSubnets)
+	mux.HandleFunc("POST /api/subnets", graphHandler.CreateSubnet)
+	mux.HandleFunc("GET /api/subnets/{cidr...}", graphHandler.GetSubnet)
+	mux.HandleFunc("PUT /api/subnets/{cidr...}", graphHandler.UpdateSubnet)
+	mux.HandleFunc("DELETE /api/subnets/{cidr...}", graphHandler.DeleteSubnet)
+	mux.HandleFunc("GET /api/vlans", graphHandler.ListVLANs)
+
 	// Position endpoints
 	mux.HandleFunc("GET /api/positions", graphHandler.GetPositions)
 	mux.HandleFunc("POST /api/positions", graphHandler.SavePositions)
 	mux.HandleFunc("PUT /api/positions/{node_id}", graphHandler.UpdatePosition)
 
+	// Snapshot endpoints
+	mux.HandleFunc("GET /api/snapshots", graphHandler.ListSnapshots)
+	mux.HandleFunc("POST /api/snapshots", graphHandler.CreateSnapshot)
+	mux.HandleFunc("POST /api/snapshots/{id}/restore", graphHandler.RestoreSnapshot)
+
 	// Import endpoints
+	mux.HandleFunc("POST /api/import/validate", graphHandler.ValidateImport)
 	mux.HandleFunc("POST /api/import/yaml", graphHandler.ImportYAML)
 	mux.HandleFunc("POST /api/import/ansible-inventory", graphHandler.ImportAnsibleInventory)
-	mux.HandleFunc("POST /api/import/scan", graphHandler.ImportScan)
+	mux.HandleFunc("POST /api/import/csv", graphHandler.ImportCSV)
+	mux.HandleFunc("POST /api/import/prometheus-sd", graphHandler.ImportPrometheusSD)
+	mux.HandleFunc("POST /api/import/scan", idempotency.Wrap(graphHandler.ImportScan))
+	mux.HandleFunc("POST /api/import/truth-csv", truthHandler.ImportTruthCSV)
 
 	// Export endpoints
 	mux.HandleFunc("GET /api/export/json", graphHandler.ExportJSON)
 	mux.HandleFunc("GET /api/export/yaml", graphHandler.ExportYAML)
 	mux.HandleFunc("GET /api/export/ansible-inventory", graphHandler.ExportAnsibleInventory)
+	mux.HandleFunc("GET /api/export/csv", graphHandler.ExportCSV)
+	mux.HandleFunc("GET /api/export/graphml", graphHandler.ExportGraphML)
+	mux.HandleFunc("GET /api/export/dot", graphHandler.ExportDOT)
+	mux.HandleFunc("GET /api/export/mermaid", graphHandler.ExportMermaid)
 
 	// Truth endpoints
 	mux.HandleFunc("GET /api/nodes/{id}/truth", truthHandler.GetNodeTruth)
 	mux.HandleFunc("PUT /api/nodes/{id}/truth", truthHandler.SetNodeTruth)
 	mux.HandleFunc("DELETE /api/nodes/{id}/truth", truthHandler.ClearNodeTruth)
+	mux.HandleFunc("GET /api/nodes/{id}/hostname-candidates", truthHandler.GetHostnameCandidates)
+	mux.HandleFunc("POST /api/nodes/{id}/hostname-candidates", truthHandler.PinHostnameCandidate)
 	mux.HandleFunc("GET /api/nodes/{id}/discrepancies", truthHandler.GetNodeDiscrepancies)
+	mux.HandleFunc("GET /api/nodes/{id}/reconciliation", truthHandler.GetReconciliation)
 
 	// Discrepancy endpoints
 	mux.HandleFunc("GET /api/discrepancies", truthHandler.ListDiscrepancies)
+	mux.HandleFunc("GET /api/discrepancies/export.csv", truthHandler.ExportDiscrepanciesCSV)
 	mux.HandleFunc("GET /api/discrepancies/{id}", truthHandler.GetDiscrepancy)
 	mux.HandleFunc("POST /api/discrepancies/{id}/resolve", truthHandler.ResolveDiscrepancy)
+	mux.HandleFunc("DELETE /api/discrepancies/{id}", truthHandler.DeleteDiscrepancy)
 
 	// Secrets endpoints
 	mux.HandleFunc("GET /api/secrets/types", secretsHandler.GetSecretTypes)
 	mux.HandleFunc("POST /api/secrets/refresh", secretsHandler.RefreshMountedSecrets)
 	mux.HandleFunc("GET /api/secrets", secretsHandler.ListSecrets)
+	mux.HandleFunc("GET /api/secrets/expiring", secretsHandler.ListExpiringSecrets)
 	mux.HandleFunc("POST /api/secrets", secretsHandler.CreateSecret)
 	mux.HandleFunc("GET /api/secrets/{id}", secretsHandler.GetSecret)
 	mux.HandleFunc("PUT /api/secrets/{id}", secretsHandler.UpdateSecret)
 	mux.HandleFunc("DELETE /api/secrets/{id}", secretsHandler.DeleteSecret)
+	mux.HandleFunc("POST /api/secrets/{id}/test", secretsHandler.TestSecret)
 
 	// Capabilities endpoint
 	mux.HandleFunc("GET /api/capabilities", secretsHandler.GetCapabilities)
+	mux.HandleFunc("GET /api/capabilities/requirements", secretsHandler.GetCapabilityRequirements)
+
+	// Admin endpoints
+	mux.HandleFunc("POST /api/admin/reload", adminHandler.Reload)
+	mux.HandleFunc("POST /api/admin/vacuum", adminHandler.Vacuum)
+
+	// Prometheus metrics
+	mux.Handle("GET /metrics", metricsReg)
 
 	// SSE events endpoint
+	mux.HandleFunc("GET /api/events/history", eventsHandler.GetHistory)
 	mux.Handle("GET /events", sseHub)
 
 	// Static files from embedded filesystem
@@ -368,16 +747,33 @@ func main() {
 	mux.Handle("/", http.FileServer(http.FS(webContent)))
 
 	// Apply middleware
-	finalHandler := handler.Chain(mux,
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		log.Printf("CORS allowed origins: %s", strings.Join(cfg.CORS.AllowedOrigins, ", "))
+	} else if cfg.CORS.AllowCredentials {
+		log.Println("Warning: cors.allow_credentials ignored without cors.allowed_origins")
+	}
+	middlewares := []func(http.Handler) http.Handler{
 		handler.Recover,
-		handler.CORS,
-		handler.Logger,
-	)
+		handler.CORS(cfg.CORS),
+		handler.Logger(logger.With("component", "http")),
+	}
+	if cfg.Auth.Enabled {
+		middlewares = append(middlewares, handler.Auth(secretsSvc, cfg.Auth.ReadAnonymous))
+		log.Printf("API authentication enabled (read_anonymous=%v)", cfg.Auth.ReadAnonymous)
+	}
+	finalHandler := handler.Chain(mux, middlewares...)
+
+	// Kubernetes probes bypass auth and request logging
+	healthHandler := handler.NewHealthHandler(repo, adapterRegistry)
+	rootMux := http.NewServeMux()
+	rootMux.HandleFunc("GET /healthz", healthHandler.Healthz)
+	rootMux.HandleFunc("GET /readyz", healthHandler.Readyz)
+	rootMux.Handle("/", finalHandler)
 
 	// Create server
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      finalHandler,
+		Handler:      rootMux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  60 * time.Second,
@@ -415,17 +811,123 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// nodeVerifier re-verifies selected nodes through the verifier adapter
+type nodeVerifier struct {
+	registry *adapter.Registry
+	name     string
+}
+
+// VerifyNodes probes the given nodes now and reconciles the results
+func (v *nodeVerifier) VerifyNodes(ctx context.Context, ids []string) error {
+	return v.registry.TriggerNodeSync(ctx, v.name, ids)
+}
+
+// adapterCapabilities maps adapter names to the capability enabling them
+var adapterCapabilities = map[string]string{
+	"verifier":   "basic_verification",
+	"sshprobe":   "ssh_probe",
+	"snmp":       "snmp",
+	"mdns":       "mdns",
+	"arp":        "arp",
+	"lldp":       "lldp",
+	"nmap":       "nmap",
+	"traceroute": "traceroute",
+}
+
+// nmapTargetList returns the enabled primary targets, falling back to
+// SCAN_SUBNETS for backwards compatibility
+func nmapTargetList(cfg *config.Config) []string {
+	targets := cfg.Targets.EnabledPrimary()
+	if len(targets) == 0 {
+		if scanSubnets := os.Getenv("SCAN_SUBNETS"); scanSubnets != "" {
+			targets = strings.Split(scanSubnets, ",")
+		}
+	}
+	return targets
+}
+
+// nmapSettings is the nmap adapter's reloadable configuration
+func nmapSettings(cfg *config.Config, targets []string) map[string]any {
+	return map[string]any{
+		"targets":         targets,
+		"target_profiles": cfg.Targets.PrimaryProfiles(),
+	}
+}
+
+// adapterReloader re-reads the config file and applies it to the registry
+type adapterReloader struct {
+	path     string
+	registry *adapter.Registry
+}
+
+// Reload implements handler.Reloader
+func (l *adapterReloader) Reload(ctx context.Context) (*adapter.ReloadResult, error) {
+	if l.path == "" {
+		return nil, fmt.Errorf("no config file loaded at startup")
+	}
+	cfg, _, err := config.LoadFromPath(l.path)
+	if err != nil {
+		return nil, err
+	}
+	return l.registry.Reload(desiredAdapterConfigs(cfg, l.registry.Configs()))
+}
+
+// desiredAdapterConfigs derives adapter configuration from cfg, keeping the
+// priorities and intervals of current that the config file does not set
+func desiredAdapterConfigs(cfg *config.Config, current map[string]adapter.AdapterConfig) map[string]adapter.AdapterConfig {
+	mode := cfg.EffectiveMode()
+	behavior := cfg.EffectiveBehavior()
+
+	desired := make(map[string]adapter.AdapterConfig, len(adapterCapabilities))
+	for name, capability := range adapterCapabilities {
+		ac := current[name]
+		ac.Enabled = cfg.Capabilities.IsEnabled(capability, mode)
+		desired[name] = ac
+	}
+
+	sshprobe := desired["sshprobe"]
+	sshprobe.Enabled = sshprobe.Enabled || os.Getenv("ENABLE_SSH_PROBE") == "true"
+	desired["sshprobe"] = sshprobe
+
+	lldp := desired["lldp"]
+	lldp.Enabled = lldp.Enabled && os.Getenv("KUBERNETES_SERVICE_HOST") == ""
+	desired["lldp"] = lldp
+
+	verifier := desired["verifier"]
+	verifier.PollInterval = behavior.VerifyInterval.String()
+	desired["verifier"] = verifier
+
+	targets := nmapTargetList(cfg)
+	nmap := desired["nmap"]
+	nmap.Enabled = nmap.Enabled && len(targets) > 0
+	nmap.PollInterval = behavior.ScanInterval.String()
+	nmap.Settings = nmapSettings(cfg, targets)
+	desired["nmap"] = nmap
+
+	return desired
+}
+
 // scannerService wraps the scanner adapter and saves discovered hosts
 type scannerService struct {
-	scanner  *adapter.ScannerAdapter
-	repo     *sqlite.Repository
-	eventBus *service.EventBus
+	scanner   *adapter.ScannerAdapter
+	repo      *sqlite.Repository
+	eventBus  *service.EventBus
+	reconcile *service.ReconcileService
+	tracer    *adapter.TracerouteAdapter // nil when traceroute discovery is disabled
+	metrics   *adapter.SyncMetrics
+}
+
+// CheckScanRange rejects ranges over the scan limit before they are queued
+func (s *scannerService) CheckScanRange(cidr string, maxHosts int) error {
+	return s.scanner.CheckScanRange(cidr, maxHosts)
 }
 
 // ScanSubnet scans a CIDR range and saves discovered hosts
-func (s *scannerService) ScanSubnet(ctx context.Context, cidr string) error {
+func (s *scannerService) ScanSubnet(ctx context.Context, cidr string, opts adapter.ScanOptions) error {
 	log.Printf("scannerService: Starting scan of %s", cidr)
-	fragment, err := s.scanner.ScanSubnet(ctx, cidr)
+	start := time.Now()
+	fragment, err := s.scanner.ScanSubnetWithOptions(ctx, cidr, opts)
+	s.metrics.Record(s.scanner.Name(), start, err)
 	if err != nil {
 		log.Printf("scannerService: Scan error: %v", err)
 		return err
@@ -438,30 +940,57 @@ func (s *scannerService) ScanSubnet(ctx context.Context, cidr string) error {
 
 	log.Printf("scannerService: Received fragment with %d nodes", len(fragment.Nodes))
 
-	// Save discovered nodes to repository
+	// Record the scanned range even when no host answered
+	if err := s.repo.EnsureSubnet(ctx, cidr); err != nil {
+		log.Printf("scannerService: Failed to record subnet %s: %v", cidr, err)
+	}
+
+	// Save discovered nodes to repository; new nodes are enriched once all
+	// are saved so slow enrichment steps don't serialize the host loop
 	created := 0
 	updated := 0
+	var fresh []domain.Node
 	for _, node := range fragment.Nodes {
 		// Check if node already exists
 		existing, _ := s.repo.GetNode(ctx, node.ID)
-		if existing != nil {
+		if existing != nil && existing.DeletedAt != nil {
+			// Seen again after a soft delete: bring it back with its history
+			if err := s.repo.RestoreNode(ctx, node.ID); err != nil {
+				log.Printf("Failed to revive discovered node %s: %v", node.ID, err)
+				continue
+			}
+			if err := s.repo.UpdateNodeVerification(ctx, node.ID, node.Status, node.LastVerified, node.LastSeen, node.Discovered); err != nil {
+				log.Printf("Failed to update discovered node %s: %v", node.ID, err)
+			}
+			s.publishRevived(ctx, node.ID)
+			created++
+		} else if existing != nil {
 			// Update existing node with discovered data
 			if err := s.repo.UpdateNodeVerification(ctx, node.ID, node.Status, node.LastVerified, node.LastSeen, node.Discovered); err != nil {
 				log.Printf("Failed to update discovered node %s: %v", node.ID, err)
 			} else {
 				updated++
 			}
+		} else if merged, err := s.reconcile.MergeByMAC(ctx, s.scanner.Name(), &node); err != nil {
+			log.Printf("Failed to merge discovered node %s by MAC: %v", node.ID, err)
+		} else if merged {
+			updated++
 		} else {
-			// Create new node
 			if err := s.repo.CreateNode(ctx, &node); err != nil {
 				log.Printf("Failed to create discovered node %s: %v", node.ID, err)
 			} else {
 				created++
+				fresh = append(fresh, node)
 			}
 		}
 	}
 
 	log.Printf("scannerService: Created %d nodes, updated %d nodes", created, updated)
+	s.reconcile.EnrichCreated(ctx, s.scanner.Name(), fresh)
+
+	if s.tracer != nil {
+		s.traceSubnet(ctx, cidr)
+	}
 
 	// Broadcast graph update
 	s.eventBus.Publish(service.Event{
@@ -472,6 +1001,73 @@ func (s *scannerService) ScanSubnet(ctx context.Context, cidr string) error {
 	return nil
 }
 
+// traceSubnet records routers on the paths to a subnet. Existing nodes are
+// left untouched; edges are only added when both endpoints are known.
+func (s *scannerService) traceSubnet(ctx context.Context, cidr string) {
+	fragment, err := s.tracer.TraceSubnet(ctx, cidr)
+	if err != nil {
+		log.Printf("scannerService: Traceroute error: %v", err)
+	}
+	if fragment == nil {
+		return
+	}
+
+	routers := 0
+	for _, node := range fragment.Nodes {
+		existing, _ := s.repo.GetNode(ctx, node.ID)
+		if existing != nil && existing.DeletedAt != nil {
+			// A deleted router on the path is back in use
+			if err := s.repo.RestoreNode(ctx, node.ID); err != nil {
+				log.Printf("Failed to revive router node %s: %v", node.ID, err)
+				continue
+			}
+			s.publishRevived(ctx, node.ID)
+			routers++
+			continue
+		}
+		if existing != nil {
+			continue
+		}
+		if err := s.repo.CreateNode(ctx, &node); err != nil {
+			log.Printf("Failed to create router node %s: %v", node.ID, err)
+			continue
+		}
+		routers++
+	}
+
+	links := 0
+	for _, edge := range fragment.Edges {
+		if existing, _ := s.repo.GetEdge(ctx, edge.ID); existing != nil {
+			continue
+		}
+		from, _ := s.repo.GetNode(ctx, edge.FromID)
+		to, _ := s.repo.GetNode(ctx, edge.ToID)
+		if from == nil || to == nil || from.DeletedAt != nil || to.DeletedAt != nil {
+			continue
+		}
+		if err := s.repo.CreateEdge(ctx, &edge); err != nil {
+			log.Printf("Failed to create route edge %s: %v", edge.ID, err)
+			continue
+		}
+		links++
+	}
+
+	log.Printf("scannerService: Traceroute to %s added %d routers, %d route edges", cidr, routers, links)
+}
+
+// publishRevived announces a node brought back from a soft delete as created
+func (s *scannerService) publishRevived(ctx context.Context, id string) {
+	node, err := s.repo.GetNode(ctx, id)
+	if err != nil || node == nil {
+		log.Printf("Failed to load revived node %s: %v", id, err)
+		return
+	}
+	s.eventBus.Publish(service.Event{
+		Type:    service.EventNodeCreated,
+		Payload: node,
+	})
+}
+
 // bootstrapService wraps the bootstrap adapter and saves discovered nodes
 type bootstrapService struct {
 	bootstrap *adapter.BootstrapAdapter
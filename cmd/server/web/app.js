@@ -8,6 +8,40 @@
     let eventSource = null;
     let iconCache = {};
 
+    // API token for servers with auth enabled, asked for on the first 401
+    const tokenKey = 'specularium-api-token';
+    const nativeFetch = window.fetch.bind(window);
+
+    // Wraps fetch to send the stored token on API requests; on a 401 it asks
+    // for a token and retries once
+    async function fetch(url, options = {}) {
+        const isAPI = typeof url === 'string' && url.startsWith('/api/');
+        const send = (token) => {
+            if (!isAPI || !token) return nativeFetch(url, options);
+            const headers = new Headers(options.headers || {});
+            headers.set('Authorization', `Bearer ${token}`);
+            return nativeFetch(url, { ...options, headers });
+        };
+
+        const sent = localStorage.getItem(tokenKey);
+        const response = await send(sent);
+        if (response.status !== 401 || !isAPI) {
+            return response;
+        }
+        // Another request may already have asked for a new token
+        const current = localStorage.getItem(tokenKey);
+        if (current && current !== sent) {
+            return send(current);
+        }
+        const entered = window.prompt('This server requires an API token');
+        if (!entered || !entered.trim()) {
+            return response;
+        }
+        localStorage.setItem(tokenKey, entered.trim());
+        connectSSE();
+        return send(entered.trim());
+    }
+
     // Zoom configuration
     const zoomConfig = {
         step: 0.2,          // Zoom step multiplier
@@ -43,7 +77,8 @@
         unverified: theme.gray,
         verifying: theme.yellow,
         unreachable: theme.red,
-        degraded: theme.orange
+        degraded: theme.orange,
+        stale: theme.gray
     };
 
     // Truth status colors
@@ -95,6 +130,11 @@
             size: 18,
             satellite: true  // Indicates this node orbits its parent
         },
+        subnet: {
+            icon: '/icons/switch.svg',
+            color: theme.gray,
+            size: 24
+        },
         unknown: {
             icon: '/icons/unknown.svg',
             color: theme.greenDim,
@@ -1216,7 +1256,8 @@
             unverified: '[?]',
             verifying: '[...]',
             unreachable: '[X]',
-            degraded: '[!]'
+            degraded: '[!]',
+            stale: '[~]'
         }[status] || '[?]';
         text += `Status: ${statusIcon} ${status.toUpperCase()}\n`;
 
@@ -1550,7 +1591,9 @@
             eventSource.close();
         }
 
-        eventSource = new EventSource('/events');
+        // EventSource cannot send headers, so the token goes in the query
+        const token = localStorage.getItem(tokenKey);
+        eventSource = new EventSource(token ? `/events?access_token=${encodeURIComponent(token)}` : '/events');
 
         eventSource.onopen = () => {
             console.log('SSE connected');
@@ -2105,12 +2148,24 @@
                 else loadGraph();
                 break;
 
+            case 'node-merged':
+                if (event.payload && event.payload.merged_id) removeNode(event.payload.merged_id);
+                if (event.payload && event.payload.node) addNode(event.payload.node);
+                else loadGraph();
+                break;
+
+            case 'node-stale':
+                if (event.payload && event.payload.node) addNode(event.payload.node);
+                else loadGraph();
+                break;
+
             case 'node-deleted':
                 if (event.payload && event.payload.id) removeNode(event.payload.id);
                 else loadGraph();
                 break;
 
             case 'edge-created':
+            case 'edge-updated':
                 if (event.payload) addEdge(event.payload);
                 else loadGraph();
                 break;
@@ -2120,8 +2175,13 @@
                 else loadGraph();
                 break;
 
+            case 'reconcile-batch':
+                applyReconcileBatch(event.payload);
+                break;
+
             case 'graph-updated':
             case 'import-completed':
+            case 'resync':
                 loadGraph();
                 break;
 
@@ -2167,11 +2227,38 @@
                 break;
 
             case 'discrepancy-resolved':
+            case 'discrepancy-dismissed':
                 loadDiscrepancies();
                 break;
         }
     }
 
+    // Patch the nodes and edges named in a debounced reconcile batch,
+    // falling back to a full reload for very large batches
+    async function applyReconcileBatch(batch) {
+        const nodeIds = (batch && batch.node_ids) || [];
+        const edgeIds = (batch && batch.edge_ids) || [];
+        if (!batch || nodeIds.length + edgeIds.length > 200) {
+            loadGraph();
+            return;
+        }
+
+        try {
+            for (const id of nodeIds) {
+                const response = await fetch(`/api/nodes/${encodeURIComponent(id)}`);
+                if (response.ok) addNode(await response.json());
+                else if (response.status === 404) removeNode(id);
+            }
+            for (const id of edgeIds) {
+                const response = await fetch(`/api/edges/${encodeURIComponent(id)}`);
+                if (response.ok) addEdge(await response.json());
+            }
+        } catch (error) {
+            console.error('Failed to apply reconcile batch:', error);
+            loadGraph();
+        }
+    }
+
     // Update status display
     function updateStatus(status) {
         elements.status.textContent = `> STATUS: ${status}`;
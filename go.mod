@@ -7,6 +7,7 @@ toolchain go1.24.11
 require (
 	github.com/Ullaakut/nmap/v3 v3.0.4
 	golang.org/x/crypto v0.31.0
+	golang.org/x/net v0.33.0
 	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/sqlite v1.40.1
 )
@@ -0,0 +1,306 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// procARPPath is the Linux IPv4 neighbor table
+const procARPPath = "/proc/net/arp"
+
+// arpEntry is one resolved neighbor
+type arpEntry struct {
+	IP     string
+	MAC    string
+	Device string
+}
+
+// ARPConfig holds configuration for the ARP adapter
+type ARPConfig struct {
+	// Subnets are swept before reading the neighbor table so the kernel
+	// resolves every address. Empty reads the table as-is.
+	Subnets []string
+	// SettleTime is how long to wait for ARP replies after a sweep
+	SettleTime time.Duration
+	// MaxHosts caps addresses swept per subnet (0 = scanner default)
+	MaxHosts int
+}
+
+// DefaultARPConfig returns sensible defaults
+func DefaultARPConfig() ARPConfig {
+	return ARPConfig{
+		SettleTime: 2 * time.Second,
+	}
+}
+
+// ARPAdapter discovers layer-2 neighbors from the kernel ARP table. Devices
+// that ignore TCP probes and have no DNS record still answer ARP, so this
+// finds hosts other adapters miss and attaches MAC addresses and vendors to
+// nodes already in the graph.
+type ARPAdapter struct {
+	config    ARPConfig
+	nodes     NodeLister
+	publisher EventPublisher
+	mu        sync.Mutex
+	running   bool
+
+	// readTable and sweep are swappable for tests
+	readTable func() ([]byte, error)
+	sweep     func(ctx context.Context, ips []string)
+}
+
+// NewARPAdapter creates a new ARP adapter.
+// Nodes is used to merge results into existing nodes and may be nil.
+func NewARPAdapter(nodes NodeLister, config ARPConfig) *ARPAdapter {
+	if config.SettleTime == 0 {
+		config.SettleTime = 2 * time.Second
+	}
+
+	return &ARPAdapter{
+		config:    config,
+		nodes:     nodes,
+		readTable: func() ([]byte, error) { return os.ReadFile(procARPPath) },
+		sweep:     udpSweep,
+	}
+}
+
+// SetEventPublisher sets the event publisher for progress updates
+func (a *ARPAdapter) SetEventPublisher(pub EventPublisher) {
+	a.publisher = pub
+}
+
+// publishProgress emits a discovery event
+func (a *ARPAdapter) publishProgress(eventType string, payload interface{}) {
+	if a.publisher != nil {
+		a.publisher.PublishDiscoveryEvent(eventType, payload)
+	}
+}
+
+// Name returns the adapter identifier
+func (a *ARPAdapter) Name() string {
+	return "arp"
+}
+
+// Type returns the adapter type
+func (a *ARPAdapter) Type() AdapterType {
+	return AdapterTypeOneShot
+}
+
+// Priority returns the adapter priority
+func (a *ARPAdapter) Priority() int {
+	return 45 // MACs are reliable, but ARP carries nothing beyond IP and MAC
+}
+
+// Start initializes the adapter
+func (a *ARPAdapter) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = true
+	log.Printf("ARP adapter started (subnets=%v)", a.config.Subnets)
+	return nil
+}
+
+// Stop shuts down the adapter
+func (a *ARPAdapter) Stop() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = false
+	log.Printf("ARP adapter stopped")
+	return nil
+}
+
+// Sync sweeps the configured subnets, reads the neighbor table and returns
+// a node per resolved neighbor. Neighbors already in the graph, by IP or
+// MAC, are merged into their existing node.
+func (a *ARPAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	a.publishProgress("discovery-started", map[string]interface{}{
+		"message": "Reading ARP neighbor table",
+		"phase":   "arp_sweep",
+	})
+
+	if ips := a.sweepTargets(); len(ips) > 0 {
+		log.Printf("ARP: sweeping %d addresses", len(ips))
+		a.sweep(ctx, ips)
+		select {
+		case <-time.After(a.config.SettleTime):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	data, err := a.readTable()
+	if err != nil {
+		return nil, fmt.Errorf("read ARP table: %w", err)
+	}
+	entries := parseProcARP(data)
+
+	byIP, byMAC := a.existingNodes(ctx)
+	now := time.Now()
+
+	fragment := domain.NewGraphFragment()
+	for i, entry := range entries {
+		existing := byIP[entry.IP]
+		if existing == nil {
+			existing = byMAC[entry.MAC]
+		}
+		node := arpNode(entry, existing, now)
+		fragment.AddNode(node)
+
+		a.publishProgress("discovery-progress", map[string]interface{}{
+			"ip":      entry.IP,
+			"mac":     entry.MAC,
+			"current": i + 1,
+			"total":   len(entries),
+			"message": fmt.Sprintf("ARP: %s is at %s", entry.IP, entry.MAC),
+			"phase":   "arp_sweep",
+		})
+	}
+
+	a.publishProgress("discovery-complete", map[string]interface{}{
+		"discovered": len(entries),
+		"message":    fmt.Sprintf("ARP sweep complete: %d neighbors", len(entries)),
+	})
+	log.Printf("ARP: %d neighbors resolved", len(entries))
+
+	return fragment, nil
+}
+
+// sweepTargets expands the configured subnets to IPv4 addresses.
+// ARP is IPv4 only, so IPv6 subnets are skipped.
+func (a *ARPAdapter) sweepTargets() []string {
+	var ips []string
+	for _, subnet := range a.config.Subnets {
+		expanded, err := expandCIDR(subnet, a.config.MaxHosts)
+		if err != nil {
+			log.Printf("ARP: skipping subnet %s: %v", subnet, err)
+			continue
+		}
+		for _, ip := range expanded {
+			if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
+
+// existingNodes indexes current nodes by their ip property and MAC address
+func (a *ARPAdapter) existingNodes(ctx context.Context) (byIP, byMAC map[string]*domain.Node) {
+	byIP = make(map[string]*domain.Node)
+	byMAC = make(map[string]*domain.Node)
+	if a.nodes == nil {
+		return byIP, byMAC
+	}
+	nodes, err := a.nodes.ListNodes(ctx, "", "")
+	if err != nil {
+		log.Printf("ARP: failed to list nodes: %v", err)
+		return byIP, byMAC
+	}
+	for i := range nodes {
+		if ip := nodes[i].GetPropertyString("ip"); ip != "" {
+			byIP[ip] = &nodes[i]
+		}
+		if mac := nodes[i].MACAddress(); mac != "" {
+			byMAC[mac] = &nodes[i]
+		}
+	}
+	return byIP, byMAC
+}
+
+// arpNode builds the node for a neighbor, merging into an existing node if
+// given. New nodes are keyed by MAC since that is what ARP vouches for.
+func arpNode(entry arpEntry, existing *domain.Node, now time.Time) domain.Node {
+	var node domain.Node
+	if existing != nil {
+		// Carry existing state forward since reconcile replaces it
+		node = *existing
+		node.Discovered = make(map[string]any, len(existing.Discovered)+3)
+		for k, v := range existing.Discovered {
+			node.Discovered[k] = v
+		}
+	} else {
+		node = domain.Node{
+			ID:         "mac-" + strings.ReplaceAll(entry.MAC, ":", ""),
+			Type:       domain.NodeTypeUnknown,
+			Label:      entry.IP,
+			Source:     "arp",
+			Status:     domain.NodeStatusVerified,
+			Properties: map[string]any{"ip": entry.IP},
+			Discovered: make(map[string]any),
+		}
+		node.LastVerified = &now
+	}
+	node.LastSeen = &now
+
+	node.Discovered["mac_address"] = entry.MAC
+	vendor := LookupMACVendor(entry.MAC)
+	setMACVendor(&node, vendor)
+	// ARP sees no ports, so a new node's only type hint is its vendor
+	if existing == nil && vendor != "" {
+		node.Type = domain.ClassifyNodeTypeWithVendor(nil, vendor, nil, now).Type
+	}
+	if entry.Device != "" {
+		node.Discovered["arp_interface"] = entry.Device
+	}
+	return node
+}
+
+// parseProcARP parses /proc/net/arp, skipping the header and incomplete
+// entries:
+//
+//	IP address       HW type     Flags       HW address            Mask     Device
+//	192.168.1.1      0x1         0x2         aa:bb:cc:dd:ee:ff     *        eth0
+func parseProcARP(data []byte) []arpEntry {
+	var entries []arpEntry
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		// Flags 0x0 means the address never resolved
+		if fields[2] == "0x0" {
+			continue
+		}
+		mac := domain.NormalizeMAC(fields[3])
+		if mac == "" || mac == "00:00:00:00:00:00" || seen[fields[0]] {
+			continue
+		}
+		seen[fields[0]] = true
+
+		entry := arpEntry{IP: fields[0], MAC: mac}
+		if len(fields) >= 6 {
+			entry.Device = fields[5]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// udpSweep sends a single UDP datagram to the discard port of each address.
+// Nothing needs to listen: sending forces the kernel to resolve the address
+// over ARP, populating the neighbor table without raw socket privileges.
+func udpSweep(ctx context.Context, ips []string) {
+	for _, ip := range ips {
+		if ctx.Err() != nil {
+			return
+		}
+		conn, err := net.Dial("udp4", net.JoinHostPort(ip, "9"))
+		if err != nil {
+			continue
+		}
+		conn.Write([]byte{0})
+		conn.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+func TestLookupMACVendor(t *testing.T) {
+	tests := []struct {
+		mac  string
+		want string
+	}{
+		{"b8:27:eb:12:34:56", "Raspberry Pi"},
+		{"B8-27-EB-12-34-56", "Raspberry Pi"},
+		{"00:50:56:AB:CD:EF", "VMware"},
+		{"00:11:32:00:00:01", "Synology"},
+		{"12:34:56:78:9a:bc", ""},
+		{"not-a-mac", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := LookupMACVendor(tt.mac); got != tt.want {
+			t.Errorf("LookupMACVendor(%q) = %q, want %q", tt.mac, got, tt.want)
+		}
+	}
+}
+
+const procARPFixture = `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         24:a4:3c:01:02:03     *        eth0
+192.168.1.50     0x1         0x2         B8:27:EB:AA:BB:CC     *        eth0
+192.168.1.77     0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+
+func TestParseProcARP(t *testing.T) {
+	entries := parseProcARP([]byte(procARPFixture))
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (incomplete entry skipped): %+v", len(entries), entries)
+	}
+	if entries[1].IP != "192.168.1.50" || entries[1].MAC != "b8:27:eb:aa:bb:cc" || entries[1].Device != "eth0" {
+		t.Errorf("entry = %+v", entries[1])
+	}
+}
+
+func TestARPAdapterSyncMergesByIP(t *testing.T) {
+	gateway := domain.Node{
+		ID:         "192-168-1-1",
+		Type:       domain.NodeTypeRouter,
+		Label:      "gateway",
+		Properties: map[string]any{"ip": "192.168.1.1"},
+		Discovered: map[string]any{"reverse_dns": "gw.lan"},
+	}
+	a := NewARPAdapter(&fakeNodeLister{nodes: []domain.Node{gateway}}, DefaultARPConfig())
+	a.readTable = func() ([]byte, error) { return []byte(procARPFixture), nil }
+
+	fragment, err := a.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(fragment.Nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(fragment.Nodes))
+	}
+
+	merged := fragment.Nodes[0]
+	if merged.ID != gateway.ID || merged.Type != domain.NodeTypeRouter {
+		t.Errorf("gateway not merged into existing node: %+v", merged)
+	}
+	if merged.Discovered["mac_vendor"] != "Ubiquiti" || merged.Discovered["reverse_dns"] != "gw.lan" {
+		t.Errorf("merged discovered = %v", merged.Discovered)
+	}
+
+	silent := fragment.Nodes[1]
+	if silent.ID != "mac-b827ebaabbcc" || silent.GetPropertyString("ip") != "192.168.1.50" || silent.Source != "arp" {
+		t.Errorf("new neighbor node = %+v", silent)
+	}
+}
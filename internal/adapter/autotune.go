@@ -0,0 +1,113 @@
+package adapter
+
+import (
+	"sync"
+	"time"
+)
+
+// Concurrency tuning parameters. The controller adjusts its limit once per
+// window of completed probes so a single slow host cannot swing it.
+const (
+	autotuneWindow = 8
+	// autotuneBackoffRate is the timeout rate at which the limit is halved
+	autotuneBackoffRate = 0.25
+)
+
+// ConcurrencyController adapts how many probes run at once between a
+// minimum and maximum. After every window of completed probes it halves the
+// limit when timeouts spike, steps it down by one when the average latency
+// exceeds the target, and steps it up by one when the window was clean. A
+// nil controller imposes no limit.
+type ConcurrencyController struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	min      int
+	max      int
+	limit    int
+	inFlight int
+	target   time.Duration
+
+	// Current window
+	samples  int
+	timeouts int
+	latency  time.Duration
+}
+
+// NewConcurrencyController creates a controller starting midway between
+// minLimit and maxLimit. A window whose average latency exceeds target
+// counts as slow.
+func NewConcurrencyController(minLimit, maxLimit int, target time.Duration) *ConcurrencyController {
+	if minLimit < 1 {
+		minLimit = 1
+	}
+	if maxLimit < minLimit {
+		maxLimit = minLimit
+	}
+	c := &ConcurrencyController{
+		min:    minLimit,
+		max:    maxLimit,
+		limit:  (minLimit + maxLimit) / 2,
+		target: target,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Limit returns the current concurrency limit
+func (c *ConcurrencyController) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.limit
+}
+
+// Acquire blocks until fewer probes than the current limit are in flight
+func (c *ConcurrencyController) Acquire() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for c.inFlight >= c.limit {
+		c.cond.Wait()
+	}
+	c.inFlight++
+	c.mu.Unlock()
+}
+
+// Release ends a probe started with Acquire and records its latency and
+// whether it timed out
+func (c *ConcurrencyController) Release(latency time.Duration, timedOut bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.inFlight--
+	c.observeLocked(latency, timedOut)
+	c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// observeLocked adds a sample and adjusts the limit when the window fills
+func (c *ConcurrencyController) observeLocked(latency time.Duration, timedOut bool) {
+	c.samples++
+	c.latency += latency
+	if timedOut {
+		c.timeouts++
+	}
+	if c.samples < autotuneWindow {
+		return
+	}
+
+	timeoutRate := float64(c.timeouts) / float64(c.samples)
+	average := c.latency / time.Duration(c.samples)
+	switch {
+	case timeoutRate >= autotuneBackoffRate:
+		c.limit /= 2
+	case c.target > 0 && average > c.target:
+		c.limit--
+	case c.timeouts == 0:
+		c.limit++
+	}
+	c.limit = max(c.min, min(c.max, c.limit))
+
+	c.samples, c.timeouts, c.latency = 0, 0, 0
+}
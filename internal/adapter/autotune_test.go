@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"testing"
+	"time"
+)
+
+// feedWindow releases one window of probes, timeouts of which timed out
+func feedWindow(c *ConcurrencyController, latency time.Duration, timeouts int) {
+	for i := 0; i < autotuneWindow; i++ {
+		c.Acquire()
+		if i < timeouts {
+			c.Release(time.Second, true)
+		} else {
+			c.Release(latency, false)
+		}
+	}
+}
+
+func TestConcurrencyControllerBacksOffOnTimeouts(t *testing.T) {
+	c := NewConcurrencyController(2, 20, 100*time.Millisecond)
+	if got := c.Limit(); got != 11 {
+		t.Fatalf("initial limit = %d, want 11", got)
+	}
+
+	// Clean, fast windows climb toward the maximum
+	for i := 0; i < 3; i++ {
+		feedWindow(c, 10*time.Millisecond, 0)
+	}
+	if got := c.Limit(); got != 14 {
+		t.Fatalf("limit after clean windows = %d, want 14", got)
+	}
+
+	// Rising timeout rates drive the limit down to the floor
+	previous := c.Limit()
+	for _, timeouts := range []int{1, 2, 4, 6, 8} {
+		feedWindow(c, 10*time.Millisecond, timeouts)
+		got := c.Limit()
+		if got > previous {
+			t.Errorf("limit rose from %d to %d at %d/%d timeouts", previous, got, timeouts, autotuneWindow)
+		}
+		previous = got
+	}
+	if previous != 2 {
+		t.Errorf("limit after timeout spike = %d, want the minimum 2", previous)
+	}
+
+	// Slow but successful probes step down without halving
+	c = NewConcurrencyController(2, 20, 100*time.Millisecond)
+	feedWindow(c, 500*time.Millisecond, 0)
+	if got := c.Limit(); got != 10 {
+		t.Errorf("limit after slow window = %d, want 10", got)
+	}
+
+	// The limit never exceeds the maximum
+	for i := 0; i < 30; i++ {
+		feedWindow(c, time.Millisecond, 0)
+	}
+	if got := c.Limit(); got != 20 {
+		t.Errorf("limit after sustained clean windows = %d, want the maximum 20", got)
+	}
+}
+
+func TestConcurrencyControllerAcquireBlocksAtLimit(t *testing.T) {
+	c := NewConcurrencyController(1, 1, 0)
+	c.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		c.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Acquire() did not block at the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Release(time.Millisecond, false)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second Acquire() did not proceed after Release()")
+	}
+}
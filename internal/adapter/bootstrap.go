@@ -24,6 +24,7 @@ type BootstrapAdapter struct {
 	env         domain.EnvironmentInfo
 	resources   *config.ResourceInfo
 	permissions *config.PermissionInfo
+	instance    config.InstanceConfig // Identity of the self node
 }
 
 // NewBootstrapAdapter creates a new bootstrap adapter
@@ -36,6 +37,11 @@ func (b *BootstrapAdapter) SetEventPublisher(pub EventPublisher) {
 	b.publisher = pub
 }
 
+// SetInstance sets the identity used for the self node
+func (b *BootstrapAdapter) SetInstance(instance config.InstanceConfig) {
+	b.instance = instance
+}
+
 // Name returns the adapter identifier
 func (b *BootstrapAdapter) Name() string {
 	return "bootstrap"
@@ -545,12 +551,12 @@ func (b *BootstrapAdapter) detectLocalSubnet(podIP string) string {
 
 // createSelfNode creates a node representing Specularium itself
 func (b *BootstrapAdapter) createSelfNode(now time.Time) domain.Node {
-	nodeID := "specularium"
-	label := "specularium"
-
 	properties := map[string]any{
-		"role":     "observer",
-		"hostname": b.env.Hostname,
+		"hostname":      b.env.Hostname,
+		"instance_name": b.instance.EffectiveName(),
+	}
+	if b.instance.UUID != "" {
+		properties["instance_uuid"] = b.instance.UUID
 	}
 
 	discovered := map[string]any{
@@ -575,9 +581,10 @@ func (b *BootstrapAdapter) createSelfNode(now time.Time) domain.Node {
 	}
 
 	node := domain.Node{
-		ID:         nodeID,
+		ID:         b.instance.SelfNodeID(),
 		Type:       domain.NodeTypeServer, // Specularium is a server/service
-		Label:      label,
+		Label:      b.instance.SelfLabel(),
+		Role:       b.instance.SelfRole(),
 		Source:     "bootstrap",
 		Status:     domain.NodeStatusVerified,
 		Properties: properties,
@@ -609,11 +616,11 @@ func (b *BootstrapAdapter) discoverK8sInfrastructure(now time.Time) []domain.Nod
 			ID:     "k8s-api",
 			Type:   domain.NodeTypeServer,
 			Label:  "kubernetes-api",
+			Role:   domain.RoleControlPlane,
 			Source: "bootstrap",
 			Status: domain.NodeStatusVerified,
 			Properties: map[string]any{
 				"ip":        b.env.KubernetesAPIIP,
-				"role":      "k8s-control-plane",
 				"segmentum": k8sServiceSegmentum,
 			},
 			Discovered: map[string]any{
@@ -631,11 +638,11 @@ func (b *BootstrapAdapter) discoverK8sInfrastructure(now time.Time) []domain.Nod
 			ID:     "k8s-dns",
 			Type:   domain.NodeTypeServer,
 			Label:  "coredns",
+			Role:   domain.RoleDNS,
 			Source: "bootstrap",
 			Status: domain.NodeStatusVerified,
 			Properties: map[string]any{
 				"ip":        b.env.ClusterDNS,
-				"role":      "k8s-dns",
 				"segmentum": k8sServiceSegmentum,
 			},
 			Discovered: map[string]any{
@@ -653,11 +660,9 @@ func (b *BootstrapAdapter) discoverK8sInfrastructure(now time.Time) []domain.Nod
 			ID:     fmt.Sprintf("k8s-node-%s", strings.ToLower(b.env.NodeName)),
 			Type:   domain.NodeTypeServer,
 			Label:  b.env.NodeName,
+			Role:   domain.RoleWorker,
 			Source: "bootstrap",
 			Status: domain.NodeStatusUnverified, // Need to verify via API
-			Properties: map[string]any{
-				"role": "k8s-node",
-			},
 		}
 		hostNode.LastSeen = &now
 		nodes = append(nodes, hostNode)
@@ -685,11 +690,11 @@ func (b *BootstrapAdapter) discoverNetworkInfrastructure(now time.Time) []domain
 			ID:     strings.ReplaceAll(b.env.DefaultGateway, ".", "-"),
 			Type:   domain.NodeTypeRouter,
 			Label:  "gateway",
+			Role:   domain.RoleGateway,
 			Source: "bootstrap",
 			Status: domain.NodeStatusUnverified,
 			Properties: map[string]any{
 				"ip":        b.env.DefaultGateway,
-				"role":      "gateway",
 				"segmentum": podNetworkSegmentum,
 			},
 		}
@@ -719,11 +724,11 @@ func (b *BootstrapAdapter) discoverNetworkInfrastructure(now time.Time) []domain
 			ID:     strings.ReplaceAll(dns, ".", "-"),
 			Type:   domain.NodeTypeServer,
 			Label:  fmt.Sprintf("dns-%s", dns),
+			Role:   domain.RoleDNS,
 			Source: "bootstrap",
 			Status: domain.NodeStatusUnverified,
 			Properties: map[string]any{
 				"ip":        dns,
-				"role":      "dns",
 				"segmentum": dnsSegmentum,
 			},
 		}
@@ -747,18 +752,16 @@ func (b *BootstrapAdapter) createInfrastructureEdges(selfID string, nodes []doma
 		edgeType := domain.EdgeTypeEthernet
 		props := map[string]any{}
 
-		if role, ok := node.Properties["role"].(string); ok {
-			switch role {
-			case "k8s-control-plane", "k8s-dns":
-				edgeType = domain.EdgeTypeEthernet
-				props["connection"] = "cluster-network"
-			case "gateway":
-				edgeType = domain.EdgeTypeEthernet
-				props["connection"] = "default-route"
-			case "dns":
-				edgeType = domain.EdgeTypeEthernet
-				props["connection"] = "dns-resolver"
-			}
+		switch {
+		case node.Role == domain.RoleControlPlane, node.ID == "k8s-dns":
+			edgeType = domain.EdgeTypeEthernet
+			props["connection"] = "cluster-network"
+		case node.Role == domain.RoleGateway:
+			edgeType = domain.EdgeTypeEthernet
+			props["connection"] = "default-route"
+		case node.Role == domain.RoleDNS:
+			edgeType = domain.EdgeTypeEthernet
+			props["connection"] = "dns-resolver"
 		}
 
 		edge := domain.Edge{
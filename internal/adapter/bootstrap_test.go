@@ -0,0 +1,41 @@
+package adapter
+
+import (
+	"testing"
+	"time"
+
+	"specularium/internal/config"
+	"specularium/internal/domain"
+)
+
+func TestBootstrapSelfNodeIdentity(t *testing.T) {
+	now := time.Now()
+
+	b := NewBootstrapAdapter()
+	b.SetInstance(config.InstanceConfig{Name: "Lab East", UUID: "0b7e4f0e-8a51-4c3c-9a57-2f1d6f1d2f10"})
+	node := b.createSelfNode(now)
+
+	if node.ID != "specularium-lab-east" {
+		t.Errorf("ID = %q, want specularium-lab-east", node.ID)
+	}
+	if node.Label != "Lab East" || node.Role != domain.RoleObserver {
+		t.Errorf("label/role = %q/%q, want Lab East/observer", node.Label, node.Role)
+	}
+	if got := node.GetPropertyString("instance_uuid"); got != "0b7e4f0e-8a51-4c3c-9a57-2f1d6f1d2f10" {
+		t.Errorf("instance_uuid = %q", got)
+	}
+
+	other := NewBootstrapAdapter()
+	other.SetInstance(config.InstanceConfig{Name: "lab-west"})
+	if otherID := other.createSelfNode(now).ID; otherID == node.ID {
+		t.Errorf("two instances share self node ID %q", otherID)
+	}
+
+	// Explicit overrides win over the name
+	custom := NewBootstrapAdapter()
+	custom.SetInstance(config.InstanceConfig{Name: "lab-east", NodeID: "observer-1", Label: "Observer", Role: "Worker"})
+	node = custom.createSelfNode(now)
+	if node.ID != "observer-1" || node.Label != "Observer" || node.Role != domain.RoleWorker {
+		t.Errorf("overrides = %q/%q/%q", node.ID, node.Label, node.Role)
+	}
+}
@@ -11,6 +11,7 @@ import (
 // SecretResolver provides access to secrets for adapters
 type SecretResolver interface {
 	GetSecret(ctx context.Context, id string) (*domain.Secret, error)
+	GetByName(ctx context.Context, name string) (*domain.Secret, error)
 	GetSecretValue(ctx context.Context, id, key string) (string, error)
 	ListSecrets(ctx context.Context, secretType string, source string) ([]domain.SecretSummary, error)
 }
@@ -18,16 +19,62 @@ type SecretResolver interface {
 // CapabilityManager provides capability-based access to discovery features
 // It wraps secrets and provides a clean interface for adapters to use
 type CapabilityManager struct {
-	secrets SecretResolver
+	secrets     SecretResolver
+	secretNames map[string]string // capability name -> preferred secret name
 }
 
 // NewCapabilityManager creates a new capability manager
 func NewCapabilityManager(secrets SecretResolver) *CapabilityManager {
 	return &CapabilityManager{
-		secrets: secrets,
+		secrets:     secrets,
+		secretNames: make(map[string]string),
 	}
 }
 
+// SetSecretName makes capability (dns, ssh, snmpv2 or snmpv3) try the
+// secret called name before any other secret of its type. An empty name
+// clears the preference.
+func (c *CapabilityManager) SetSecretName(capability, name string) {
+	if name == "" {
+		delete(c.secretNames, capability)
+		return
+	}
+	c.secretNames[capability] = name
+}
+
+// candidates lists the secrets of secretType for capability, with the
+// secret named for it first. A named secret that is missing or of another
+// type is logged and skipped, leaving the secrets in their usual order.
+func (c *CapabilityManager) candidates(ctx context.Context, capability string, secretType domain.SecretType) ([]domain.SecretSummary, error) {
+	secrets, err := c.secrets.ListSecrets(ctx, string(secretType), "")
+	if err != nil {
+		return nil, err
+	}
+	name := c.secretNames[capability]
+	if name == "" {
+		return secrets, nil
+	}
+
+	named, err := c.secrets.GetByName(ctx, name)
+	switch {
+	case err != nil:
+		log.Printf("Failed to get %s secret %q: %v", capability, name, err)
+	case named == nil:
+		log.Printf("Secret %q for %s not found, using any %s secret", name, capability, secretType)
+	case named.Type != secretType:
+		log.Printf("Secret %q for %s is a %s secret, not %s", name, capability, named.Type, secretType)
+	default:
+		ordered := []domain.SecretSummary{named.ToSummary()}
+		for _, summary := range secrets {
+			if summary.ID != named.ID {
+				ordered = append(ordered, summary)
+			}
+		}
+		return ordered, nil
+	}
+	return secrets, nil
+}
+
 // DNSCapability provides DNS resolution capabilities
 type DNSCapability struct {
 	Server string
@@ -63,7 +110,7 @@ type APICapability struct {
 // GetDNSCapability returns DNS capability from configured secrets
 func (c *CapabilityManager) GetDNSCapability(ctx context.Context) (*DNSCapability, error) {
 	// Look for DNS secrets
-	secrets, err := c.secrets.ListSecrets(ctx, string(domain.SecretTypeDNS), "")
+	secrets, err := c.candidates(ctx, "dns", domain.SecretTypeDNS)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list DNS secrets: %w", err)
 	}
@@ -100,7 +147,7 @@ func (c *CapabilityManager) GetDNSCapability(ctx context.Context) (*DNSCapabilit
 // GetSSHCapability returns SSH capability from configured secrets
 func (c *CapabilityManager) GetSSHCapability(ctx context.Context) (*SSHCapability, error) {
 	// Look for SSH key secrets
-	secrets, err := c.secrets.ListSecrets(ctx, string(domain.SecretTypeSSHKey), "")
+	secrets, err := c.candidates(ctx, "ssh", domain.SecretTypeSSHKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list SSH secrets: %w", err)
 	}
@@ -136,7 +183,7 @@ func (c *CapabilityManager) GetSSHCapability(ctx context.Context) (*SSHCapabilit
 // GetSNMPv2Capability returns SNMPv2c capability from configured secrets
 func (c *CapabilityManager) GetSNMPv2Capability(ctx context.Context) (*SNMPv2Capability, error) {
 	// Look for SNMP community secrets
-	secrets, err := c.secrets.ListSecrets(ctx, string(domain.SecretTypeSNMPCommunity), "")
+	secrets, err := c.candidates(ctx, "snmpv2", domain.SecretTypeSNMPCommunity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list SNMP secrets: %w", err)
 	}
@@ -169,7 +216,7 @@ func (c *CapabilityManager) GetSNMPv2Capability(ctx context.Context) (*SNMPv2Cap
 // GetSNMPv3Capability returns SNMPv3 capability from configured secrets
 func (c *CapabilityManager) GetSNMPv3Capability(ctx context.Context) (*SNMPv3Capability, error) {
 	// Look for SNMPv3 secrets
-	secrets, err := c.secrets.ListSecrets(ctx, string(domain.SecretTypeSNMPv3), "")
+	secrets, err := c.candidates(ctx, "snmpv3", domain.SecretTypeSNMPv3)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list SNMPv3 secrets: %w", err)
 	}
@@ -278,3 +325,122 @@ func (c *CapabilityManager) GetAllCapabilities(ctx context.Context) map[string]b
 
 	return caps
 }
+
+// capabilityRequirement maps a capability to the secret types that unlock it
+type capabilityRequirement struct {
+	name        string
+	description string
+	secretTypes []domain.SecretType
+	usable      func(c *CapabilityManager, ctx context.Context) bool
+}
+
+// capabilityRequirements lists the secret-backed capabilities in display order
+var capabilityRequirements = []capabilityRequirement{
+	{
+		name:        "dns",
+		description: "PTR lookups against a specific DNS server",
+		secretTypes: []domain.SecretType{domain.SecretTypeDNS},
+		usable: func(c *CapabilityManager, ctx context.Context) bool {
+			dns, _ := c.GetDNSCapability(ctx)
+			return dns != nil
+		},
+	},
+	{
+		name:        "ssh",
+		description: "SSH-based fact gathering",
+		secretTypes: []domain.SecretType{domain.SecretTypeSSHKey, domain.SecretTypeSSHPassword},
+		usable: func(c *CapabilityManager, ctx context.Context) bool {
+			ssh, _ := c.GetSSHCapability(ctx)
+			return ssh != nil || c.hasSecretWith(ctx, domain.SecretTypeSSHPassword, "username", "password")
+		},
+	},
+	{
+		name:        "snmpv2",
+		description: "SNMPv2c network device discovery",
+		secretTypes: []domain.SecretType{domain.SecretTypeSNMPCommunity},
+		usable: func(c *CapabilityManager, ctx context.Context) bool {
+			snmp, _ := c.GetSNMPv2Capability(ctx)
+			return snmp != nil
+		},
+	},
+	{
+		name:        "snmpv3",
+		description: "SNMPv3 network device discovery",
+		secretTypes: []domain.SecretType{domain.SecretTypeSNMPv3},
+		usable: func(c *CapabilityManager, ctx context.Context) bool {
+			snmp, _ := c.GetSNMPv3Capability(ctx)
+			return snmp != nil
+		},
+	},
+	{
+		name:        "api",
+		description: "API integrations with external services",
+		secretTypes: []domain.SecretType{domain.SecretTypeAPIToken},
+		usable: func(c *CapabilityManager, ctx context.Context) bool {
+			api, _ := c.GetAPICapability(ctx, "")
+			return api != nil
+		},
+	},
+}
+
+// GetCapabilityRequirements reports, per capability, which secret types it needs,
+// which matching secrets exist, and whether the capability is usable
+func (c *CapabilityManager) GetCapabilityRequirements(ctx context.Context) []domain.CapabilityRequirement {
+	reqs := make([]domain.CapabilityRequirement, 0, len(capabilityRequirements))
+
+	for _, cr := range capabilityRequirements {
+		req := domain.CapabilityRequirement{
+			Capability:  cr.name,
+			Description: cr.description,
+			SecretTypes: cr.secretTypes,
+			SecretIDs:   []string{},
+		}
+
+		for _, secretType := range cr.secretTypes {
+			secrets, err := c.secrets.ListSecrets(ctx, string(secretType), "")
+			if err != nil {
+				log.Printf("Failed to list %s secrets: %v", secretType, err)
+				continue
+			}
+			for _, summary := range secrets {
+				req.SecretIDs = append(req.SecretIDs, summary.ID)
+			}
+		}
+
+		req.HasSecret = len(req.SecretIDs) > 0
+		if req.HasSecret {
+			req.Usable = cr.usable(c, ctx)
+		}
+
+		reqs = append(reqs, req)
+	}
+
+	return reqs
+}
+
+// hasSecretWith returns true if any secret of the given type has all the given keys set
+func (c *CapabilityManager) hasSecretWith(ctx context.Context, secretType domain.SecretType, keys ...string) bool {
+	secrets, err := c.secrets.ListSecrets(ctx, string(secretType), "")
+	if err != nil {
+		return false
+	}
+
+	for _, summary := range secrets {
+		secret, err := c.secrets.GetSecret(ctx, summary.ID)
+		if err != nil || secret == nil {
+			continue
+		}
+		complete := true
+		for _, key := range keys {
+			if secret.Data[key] == "" {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			return true
+		}
+	}
+
+	return false
+}
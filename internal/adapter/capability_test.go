@@ -0,0 +1,140 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+// fakeSecretResolver serves secrets from memory
+type fakeSecretResolver struct {
+	secrets []*domain.Secret
+}
+
+func (f *fakeSecretResolver) GetSecret(ctx context.Context, id string) (*domain.Secret, error) {
+	for _, s := range f.secrets {
+		if s.ID == id {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("secret %s not found", id)
+}
+
+func (f *fakeSecretResolver) GetByName(ctx context.Context, name string) (*domain.Secret, error) {
+	for _, s := range f.secrets {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeSecretResolver) GetSecretValue(ctx context.Context, id, key string) (string, error) {
+	secret, err := f.GetSecret(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return secret.Data[key], nil
+}
+
+func (f *fakeSecretResolver) ListSecrets(ctx context.Context, secretType string, source string) ([]domain.SecretSummary, error) {
+	var summaries []domain.SecretSummary
+	for _, s := range f.secrets {
+		if secretType != "" && string(s.Type) != secretType {
+			continue
+		}
+		summaries = append(summaries, s.ToSummary())
+	}
+	return summaries, nil
+}
+
+func TestGetCapabilityRequirements(t *testing.T) {
+	resolver := &fakeSecretResolver{
+		secrets: []*domain.Secret{
+			{ID: "dns.home", Type: domain.SecretTypeDNS, Data: map[string]string{"server": "10.0.0.53"}},
+			{ID: "ssh.ops", Type: domain.SecretTypeSSHPassword, Data: map[string]string{"username": "ops", "password": "hunter2"}},
+			// Present but missing the community string
+			{ID: "snmp.empty", Type: domain.SecretTypeSNMPCommunity, Data: map[string]string{}},
+		},
+	}
+	mgr := NewCapabilityManager(resolver)
+
+	reqs := mgr.GetCapabilityRequirements(context.Background())
+	byName := make(map[string]domain.CapabilityRequirement)
+	for _, req := range reqs {
+		byName[req.Capability] = req
+	}
+
+	tests := []struct {
+		capability string
+		hasSecret  bool
+		usable     bool
+		secretIDs  int
+	}{
+		{"dns", true, true, 1},
+		{"ssh", true, true, 1},
+		{"snmpv2", true, false, 1},
+		{"snmpv3", false, false, 0},
+		{"api", false, false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.capability, func(t *testing.T) {
+			req, ok := byName[tt.capability]
+			if !ok {
+				t.Fatalf("requirement for %s not reported", tt.capability)
+			}
+			if len(req.SecretTypes) == 0 {
+				t.Error("SecretTypes should not be empty")
+			}
+			if req.HasSecret != tt.hasSecret {
+				t.Errorf("HasSecret = %v, want %v", req.HasSecret, tt.hasSecret)
+			}
+			if req.Usable != tt.usable {
+				t.Errorf("Usable = %v, want %v", req.Usable, tt.usable)
+			}
+			if len(req.SecretIDs) != tt.secretIDs {
+				t.Errorf("SecretIDs = %v, want %d entries", req.SecretIDs, tt.secretIDs)
+			}
+		})
+	}
+}
+
+func TestGetSSHCapabilityPrefersNamedSecret(t *testing.T) {
+	resolver := &fakeSecretResolver{
+		secrets: []*domain.Secret{
+			{ID: "ssh.ops", Name: "ops", Type: domain.SecretTypeSSHKey, Data: map[string]string{"username": "ops", "key_path": "/keys/ops"}},
+			{ID: "ssh.backup", Name: "backup", Type: domain.SecretTypeSSHKey, Data: map[string]string{"username": "backup", "key_path": "/keys/backup"}},
+			{ID: "ssh.lab", Name: "lab", Type: domain.SecretTypeSSHKey, Data: map[string]string{"username": "lab", "key_path": "/keys/lab"}},
+			{ID: "dns.backup", Name: "dns", Type: domain.SecretTypeDNS, Data: map[string]string{"server": "10.0.0.53"}},
+		},
+	}
+	mgr := NewCapabilityManager(resolver)
+	ctx := context.Background()
+
+	tests := []struct {
+		secretName string
+		wantUser   string
+	}{
+		{"", "ops"}, // no preference: first secret of the type
+		{"backup", "backup"},
+		{"lab", "lab"},
+		{"missing", "ops"}, // unknown name falls back
+		{"dns", "ops"},     // wrong type falls back
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.secretName, func(t *testing.T) {
+			mgr.SetSecretName("ssh", tt.secretName)
+			cap, err := mgr.GetSSHCapability(ctx)
+			if err != nil {
+				t.Fatalf("GetSSHCapability failed: %v", err)
+			}
+			if cap.Username != tt.wantUser {
+				t.Errorf("Username = %q, want %q", cap.Username, tt.wantUser)
+			}
+		})
+	}
+}
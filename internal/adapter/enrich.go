@@ -0,0 +1,371 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// Enrichment step names, usable in config to choose and order the pipeline
+const (
+	EnrichPTR        = "ptr"
+	EnrichBanner     = "banner"
+	EnrichTLSCert    = "tls_cert"
+	EnrichHTTPTitle  = "http_title"
+	EnrichForwardDNS = "forward_dns"
+)
+
+// DefaultEnrichmentSteps is the first-contact order used when none is configured.
+// Cheap, authoritative lookups run first so later steps can be skipped.
+var DefaultEnrichmentSteps = []string{
+	EnrichPTR,
+	EnrichBanner,
+	EnrichTLSCert,
+	EnrichHTTPTitle,
+	EnrichForwardDNS,
+}
+
+// Enricher is a single first-contact enrichment step run against a new node
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error
+}
+
+// EnricherConfig holds settings shared by the built-in enrichers
+type EnricherConfig struct {
+	// DNSServer is an optional DNS server for PTR and forward lookups
+	DNSServer string
+	// Timeout bounds each network operation
+	Timeout time.Duration
+	// Capabilities provides the DNS server from secrets when DNSServer is unset
+	Capabilities *CapabilityManager
+}
+
+// DefaultEnricherConfig returns sensible enricher defaults
+func DefaultEnricherConfig() EnricherConfig {
+	return EnricherConfig{
+		Timeout: 2 * time.Second,
+	}
+}
+
+// NewEnricher creates a built-in enricher by step name
+func NewEnricher(name string, cfg EnricherConfig) (Enricher, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultEnricherConfig().Timeout
+	}
+	switch name {
+	case EnrichPTR:
+		return &ptrEnricher{cfg: cfg}, nil
+	case EnrichBanner:
+		return &bannerEnricher{cfg: cfg}, nil
+	case EnrichTLSCert:
+		return &tlsCertEnricher{cfg: cfg}, nil
+	case EnrichHTTPTitle:
+		return &httpTitleEnricher{cfg: cfg}, nil
+	case EnrichForwardDNS:
+		return &forwardDNSEnricher{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown enrichment step: %s", name)
+	}
+}
+
+// ptrEnricher resolves the node address to a hostname via reverse DNS
+type ptrEnricher struct {
+	cfg EnricherConfig
+}
+
+func (e *ptrEnricher) Name() string { return EnrichPTR }
+
+func (e *ptrEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	// Reuse a PTR answer the discovering adapter already obtained
+	if name, ok := node.GetDiscovered("reverse_dns"); ok {
+		if hostname, _ := name.(string); hostname != "" {
+			inference.AddCandidate(hostname, domain.SourcePTR, time.Now())
+			return nil
+		}
+	}
+
+	ip := enrichNodeIP(node)
+	if ip == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	names, err := enrichResolver(e.cfg).LookupAddr(ctx, ip)
+	if err != nil || len(names) == 0 {
+		return nil
+	}
+	hostname := strings.TrimSuffix(names[0], ".")
+	node.SetDiscovered("reverse_dns", hostname)
+	inference.AddCandidate(hostname, domain.SourcePTR, time.Now())
+	return nil
+}
+
+// bannerEnricher reads SSH and SMTP greetings for hostname hints
+type bannerEnricher struct {
+	cfg EnricherConfig
+}
+
+func (e *bannerEnricher) Name() string { return EnrichBanner }
+
+func (e *bannerEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	ip := enrichNodeIP(node)
+	if ip == "" {
+		return nil
+	}
+
+	banners := make(map[string]string)
+	for _, port := range enrichOpenPorts(node) {
+		var source domain.ConfidenceSource
+		var extract func(string) string
+		switch port {
+		case 22:
+			source, extract = domain.SourceSSHBanner, extractHostnameFromSSHBanner
+		case 25, 587:
+			source, extract = domain.SourceSMTPBanner, extractHostnameFromSMTPBanner
+		default:
+			continue
+		}
+
+		banner := e.readBanner(ctx, ip, port)
+		if banner == "" {
+			continue
+		}
+		banners[strconv.Itoa(port)] = banner
+		if hostname := extract(banner); hostname != "" {
+			inference.AddCandidate(hostname, source, time.Now())
+		}
+	}
+
+	if len(banners) > 0 {
+		node.SetDiscovered("banners", banners)
+	}
+	return nil
+}
+
+// readBanner connects to a port and reads the first line the service sends
+func (e *bannerEnricher) readBanner(ctx context.Context, ip string, port int) string {
+	d := net.Dialer{Timeout: e.cfg.Timeout}
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(e.cfg.Timeout))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && line == "" {
+		return ""
+	}
+	return strings.TrimSpace(line)
+}
+
+// tlsCertEnricher takes hostnames from the certificate presented on TLS ports
+type tlsCertEnricher struct {
+	cfg EnricherConfig
+}
+
+func (e *tlsCertEnricher) Name() string { return EnrichTLSCert }
+
+func (e *tlsCertEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	ip := enrichNodeIP(node)
+	if ip == "" {
+		return nil
+	}
+
+	for _, port := range enrichOpenPorts(node) {
+		if port != 443 && port != 8443 {
+			continue
+		}
+
+		dialer := &tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: e.cfg.Timeout},
+			// Identification only: the certificate is read, never trusted
+			Config: &tls.Config{InsecureSkipVerify: true},
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, strconv.Itoa(port)))
+		if err != nil {
+			continue
+		}
+		certs := conn.(*tls.Conn).ConnectionState().PeerCertificates
+		conn.Close()
+		if len(certs) == 0 {
+			continue
+		}
+
+		leaf := certs[0]
+		names := leaf.DNSNames
+		if len(names) == 0 && leaf.Subject.CommonName != "" {
+			names = []string{leaf.Subject.CommonName}
+		}
+
+		var recorded []string
+		for _, name := range names {
+			// Wildcards name a domain, not this host
+			if strings.HasPrefix(name, "*.") || !isValidHostname(name) {
+				continue
+			}
+			recorded = append(recorded, strings.ToLower(name))
+		}
+		if len(recorded) == 0 {
+			continue
+		}
+
+		node.SetDiscovered("tls_names", recorded)
+		inference.AddCandidate(recorded[0], domain.SourceTLSCert, time.Now())
+		return nil
+	}
+	return nil
+}
+
+// titleRe matches the contents of an HTML title element
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// httpTitleEnricher fetches the root page of plain HTTP ports and records its title
+type httpTitleEnricher struct {
+	cfg EnricherConfig
+}
+
+func (e *httpTitleEnricher) Name() string { return EnrichHTTPTitle }
+
+func (e *httpTitleEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	ip := enrichNodeIP(node)
+	if ip == "" {
+		return nil
+	}
+
+	for _, port := range enrichOpenPorts(node) {
+		if port != 80 && port != 8080 {
+			continue
+		}
+
+		title := e.fetchTitle(ctx, ip, port)
+		if title == "" {
+			continue
+		}
+		node.SetDiscovered("http_title", title)
+
+		// Titles are only a hostname hint when they look like one
+		if candidate := strings.ToLower(strings.Fields(title)[0]); isValidHostname(candidate) && strings.Contains(candidate, ".") {
+			inference.AddCandidate(candidate, domain.SourceHTTPTitle, time.Now())
+		}
+		return nil
+	}
+	return nil
+}
+
+// fetchTitle issues a minimal HTTP GET and extracts the page title
+func (e *httpTitleEnricher) fetchTitle(ctx context.Context, ip string, port int) string {
+	d := net.Dialer{Timeout: e.cfg.Timeout}
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(e.cfg.Timeout))
+	fmt.Fprintf(conn, "GET / HTTP/1.0\r\nHost: %s\r\nUser-Agent: specularium\r\n\r\n", addr)
+
+	// Titles live near the top of the document
+	body, _ := io.ReadAll(io.LimitReader(conn, 16*1024))
+	m := titleRe.FindSubmatch(body)
+	if len(m) < 2 {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+	if len(title) > 100 {
+		title = title[:100] + "..."
+	}
+	return title
+}
+
+// forwardDNSEnricher confirms the best candidate resolves back to the node
+type forwardDNSEnricher struct {
+	cfg EnricherConfig
+}
+
+func (e *forwardDNSEnricher) Name() string { return EnrichForwardDNS }
+
+func (e *forwardDNSEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	ip := enrichNodeIP(node)
+	hostname := inference.GetBestHostname()
+	if ip == "" || hostname == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.cfg.Timeout)
+	defer cancel()
+
+	addrs, err := enrichResolver(e.cfg).LookupHost(ctx, hostname)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if addr == ip {
+			inference.AddCandidate(hostname, domain.SourceForwardDNS, time.Now())
+			return nil
+		}
+	}
+	return nil
+}
+
+// enrichResolver returns a resolver honoring the configured or capability DNS server
+func enrichResolver(cfg EnricherConfig) *net.Resolver {
+	dnsServer := cfg.DNSServer
+	if dnsServer == "" && cfg.Capabilities != nil {
+		if dnsCap, err := cfg.Capabilities.GetDNSCapability(context.Background()); err == nil && dnsCap != nil {
+			dnsServer = dnsCap.Server
+		}
+	}
+	if dnsServer == "" {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			d := net.Dialer{Timeout: cfg.Timeout}
+			return d.DialContext(ctx, "udp", net.JoinHostPort(dnsServer, "53"))
+		},
+	}
+}
+
+// enrichNodeIP returns the node's IP property
+func enrichNodeIP(node *domain.Node) string {
+	ip, _ := node.Properties["ip"].(string)
+	return ip
+}
+
+// enrichOpenPorts returns the open ports recorded by discovery
+func enrichOpenPorts(node *domain.Node) []int {
+	raw, ok := node.GetDiscovered("open_ports")
+	if !ok {
+		return nil
+	}
+	switch v := raw.(type) {
+	case []int:
+		return v
+	case []any:
+		// Decoded from JSON
+		ports := make([]int, 0, len(v))
+		for _, p := range v {
+			if f, ok := p.(float64); ok {
+				ports = append(ports, int(f))
+			}
+		}
+		return ports
+	}
+	return nil
+}
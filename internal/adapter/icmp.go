@@ -0,0 +1,204 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// ICMP protocol numbers for icmp.ParseMessage
+const (
+	protocolICMP     = 1
+	protocolICMPIPv6 = 58
+)
+
+// pingLatencyPattern matches the round trip in ping output ("time=X.XX ms")
+var pingLatencyPattern = regexp.MustCompile(`time[=<](\d+\.?\d*)\s*ms`)
+
+// icmpProber sends one echo request to ip and reports whether a reply
+// arrived within timeout and the round trip time
+type icmpProber func(ctx context.Context, ip string, timeout time.Duration) (bool, time.Duration)
+
+// selectICMPProber returns the native prober when enableNative is set and
+// probeNative finds a usable ICMP socket type, and the ping binary prober
+// otherwise. The chosen name is returned for logging.
+func selectICMPProber(enableNative bool, probeNative func() (string, error)) (icmpProber, string) {
+	if !enableNative {
+		return binaryPing, "ping binary"
+	}
+	network, err := probeNative()
+	if err != nil {
+		return binaryPing, "ping binary (native ICMP unavailable: " + err.Error() + ")"
+	}
+	native := &nativeICMP{network: network}
+	return native.ping, "native ICMP (" + network + ")"
+}
+
+// probeNativeICMP reports the IPv4 ICMP socket type this process may open:
+// an unprivileged datagram socket (net.ipv4.ping_group_range) or a raw
+// socket (root or CAP_NET_RAW)
+func probeNativeICMP() (string, error) {
+	var errs []error
+	for _, network := range []string{"udp4", "ip4:icmp"} {
+		conn, err := icmp.ListenPacket(network, "0.0.0.0")
+		if err == nil {
+			conn.Close()
+			return network, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", network, err))
+	}
+	return "", errors.Join(errs...)
+}
+
+// nativeICMP pings with golang.org/x/net/icmp over the socket type found by
+// probeNativeICMP, using the matching IPv6 socket for IPv6 targets
+type nativeICMP struct {
+	network string
+	seq     atomic.Uint32
+}
+
+// ping sends one echo request. Socket errors, such as IPv6 sockets being
+// unavailable where IPv4 ones were, fall back to the ping binary.
+func (n *nativeICMP) ping(ctx context.Context, ip string, timeout time.Duration) (bool, time.Duration) {
+	ok, latency, err := n.echo(ctx, ip, timeout)
+	if err != nil {
+		return binaryPing(ctx, ip, timeout)
+	}
+	return ok, latency
+}
+
+// echo sends an echo request and waits for the matching reply. A timeout is
+// reported as no reply rather than an error.
+func (n *nativeICMP) echo(ctx context.Context, ip string, timeout time.Duration) (bool, time.Duration, error) {
+	target := net.ParseIP(ip)
+	if target == nil {
+		return false, 0, fmt.Errorf("invalid address %q", ip)
+	}
+
+	privileged := n.network == "ip4:icmp"
+	network, listen, proto := n.network, "0.0.0.0", protocolICMP
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if target.To4() == nil {
+		network, listen, proto = "udp6", "::", protocolICMPIPv6
+		if privileged {
+			network = "ip6:ipv6-icmp"
+		}
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+
+	conn, err := icmp.ListenPacket(network, listen)
+	if err != nil {
+		return false, 0, err
+	}
+	defer conn.Close()
+
+	var dst net.Addr = &net.IPAddr{IP: target}
+	if !privileged {
+		dst = &net.UDPAddr{IP: target}
+	}
+
+	// Datagram sockets have their ID rewritten by the kernel and only see
+	// their own replies; raw sockets see every reply and must match the ID
+	id := os.Getpid() & 0xffff
+	seq := int(n.seq.Add(1) & 0xffff)
+	request, err := (&icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: id, Seq: seq, Data: []byte("specularium")},
+	}).Marshal(nil)
+	if err != nil {
+		return false, 0, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return false, 0, err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	start := time.Now()
+	if _, err := conn.WriteTo(request, dst); err != nil {
+		return false, 0, err
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		size, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return false, 0, nil
+			}
+			return false, 0, err
+		}
+		latency := time.Since(start)
+
+		reply, err := icmp.ParseMessage(proto, buf[:size])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (privileged && echo.ID != id) {
+			continue
+		}
+		if !sameHost(peer, target) {
+			continue
+		}
+		return true, latency, nil
+	}
+}
+
+// sameHost reports whether addr is ip
+func sameHost(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
+
+// binaryPing performs an ICMP ping using the system ping command
+func binaryPing(ctx context.Context, ip string, timeout time.Duration) (bool, time.Duration) {
+	// Use system ping command with 1 packet and timeout
+	timeoutSec := int(timeout.Seconds())
+	if timeoutSec < 1 {
+		timeoutSec = 1
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+	defer cancel()
+
+	// Linux ping: -c count, -W timeout in seconds
+	cmd := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(timeoutSec), ip)
+	output, err := cmd.Output()
+	if err != nil {
+		return false, 0
+	}
+
+	// Parse latency from output: "time=X.XX ms"
+	matches := pingLatencyPattern.FindSubmatch(output)
+	if len(matches) >= 2 {
+		if latencyMs, err := strconv.ParseFloat(string(matches[1]), 64); err == nil {
+			return true, time.Duration(latencyMs * float64(time.Millisecond))
+		}
+	}
+
+	// Ping succeeded but couldn't parse latency
+	return true, 0
+}
@@ -0,0 +1,52 @@
+package adapter
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelectICMPProber(t *testing.T) {
+	isBinary := func(p icmpProber) bool {
+		return reflect.ValueOf(p).Pointer() == reflect.ValueOf(binaryPing).Pointer()
+	}
+
+	t.Run("native disabled uses binary without probing", func(t *testing.T) {
+		probed := false
+		prober, name := selectICMPProber(false, func() (string, error) {
+			probed = true
+			return "udp4", nil
+		})
+		if probed {
+			t.Error("native ICMP was probed while disabled")
+		}
+		if !isBinary(prober) || name != "ping binary" {
+			t.Errorf("selected %q, want the ping binary", name)
+		}
+	})
+
+	t.Run("native unavailable falls back to binary", func(t *testing.T) {
+		prober, name := selectICMPProber(true, func() (string, error) {
+			return "", errors.New("operation not permitted")
+		})
+		if !isBinary(prober) {
+			t.Errorf("selected %q, want the ping binary", name)
+		}
+		if !strings.Contains(name, "operation not permitted") {
+			t.Errorf("name = %q, want the native failure reason", name)
+		}
+	})
+
+	t.Run("native available is used", func(t *testing.T) {
+		prober, name := selectICMPProber(true, func() (string, error) {
+			return "ip4:icmp", nil
+		})
+		if isBinary(prober) {
+			t.Error("selected the ping binary although native ICMP is available")
+		}
+		if name != "native ICMP (ip4:icmp)" {
+			t.Errorf("name = %q, want native ICMP (ip4:icmp)", name)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrDiscoveryQueueFull is returned when no more operations can be queued
+var ErrDiscoveryQueueFull = errors.New("discovery queue is full")
+
+// Operation states
+const (
+	OperationRunning = "running"
+	OperationQueued  = "queued"
+)
+
+// Operation describes a discovery operation holding or waiting for a slot
+type Operation struct {
+	ID        uint64     `json:"id"`
+	Kind      string     `json:"kind"` // adapter name, or "scan" for subnet scans
+	Target    string     `json:"target,omitempty"`
+	State     string     `json:"state"`
+	Position  int        `json:"position,omitempty"` // 1-based queue position while queued
+	QueuedAt  time.Time  `json:"queued_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+// OperationStatus is a snapshot of the limiter
+type OperationStatus struct {
+	MaxConcurrent int         `json:"max_concurrent"`
+	MaxQueued     int         `json:"max_queued"`
+	Running       []Operation `json:"running"`
+	Queued        []Operation `json:"queued"`
+}
+
+// OperationLimiter bounds how many discovery operations (scans, syncs,
+// verification) run at once across the whole process. Waiters are served
+// in FIFO order. A nil limiter imposes no limit.
+type OperationLimiter struct {
+	mu            sync.Mutex
+	maxConcurrent int
+	maxQueued     int
+	nextID        uint64
+	running       []*Operation
+	queue         []*pendingOperation
+}
+
+// pendingOperation is a queued operation and the channel that admits it
+type pendingOperation struct {
+	op    *Operation
+	ready chan struct{}
+}
+
+// NewOperationLimiter creates a limiter running at most maxConcurrent
+// operations, with up to maxQueued submitted operations waiting
+func NewOperationLimiter(maxConcurrent, maxQueued int) *OperationLimiter {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &OperationLimiter{maxConcurrent: maxConcurrent, maxQueued: maxQueued}
+}
+
+// Acquire blocks until a slot is free or ctx is done, and returns a func
+// releasing the slot. Background syncs use this and are not subject to the
+// queue limit.
+func (l *OperationLimiter) Acquire(ctx context.Context, kind, target string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	p := l.enqueueLocked(kind, target)
+	l.mu.Unlock()
+
+	select {
+	case <-p.ready:
+		return func() { l.release(p.op) }, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		select {
+		case <-p.ready:
+			// Admitted while cancelling; hand the slot on
+			l.releaseLocked(p.op)
+		default:
+			l.removeQueuedLocked(p)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// Submit runs fn in the background once a slot is free. It returns the
+// operation as submitted: running, or queued with its position. If the
+// queue is full nothing is started and ErrDiscoveryQueueFull is returned.
+func (l *OperationLimiter) Submit(kind, target string, fn func(ctx context.Context)) (Operation, error) {
+	if l == nil {
+		go fn(context.Background())
+		now := time.Now()
+		return Operation{Kind: kind, Target: target, State: OperationRunning, QueuedAt: now, StartedAt: &now}, nil
+	}
+
+	l.mu.Lock()
+	if len(l.running) >= l.maxConcurrent && len(l.queue) >= l.maxQueued {
+		l.mu.Unlock()
+		return Operation{}, ErrDiscoveryQueueFull
+	}
+	p := l.enqueueLocked(kind, target)
+	snapshot := l.snapshotLocked(p.op)
+	l.mu.Unlock()
+
+	go func() {
+		<-p.ready
+		defer l.release(p.op)
+		fn(context.Background())
+	}()
+
+	return snapshot, nil
+}
+
+// Status returns the running and queued operations
+func (l *OperationLimiter) Status() OperationStatus {
+	status := OperationStatus{Running: []Operation{}, Queued: []Operation{}}
+	if l == nil {
+		return status
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status.MaxConcurrent = l.maxConcurrent
+	status.MaxQueued = l.maxQueued
+	for _, op := range l.running {
+		status.Running = append(status.Running, *op)
+	}
+	for _, p := range l.queue {
+		status.Queued = append(status.Queued, l.snapshotLocked(p.op))
+	}
+	return status
+}
+
+// enqueueLocked adds an operation, admitting it at once if a slot is free
+func (l *OperationLimiter) enqueueLocked(kind, target string) *pendingOperation {
+	l.nextID++
+	p := &pendingOperation{
+		op:    &Operation{ID: l.nextID, Kind: kind, Target: target, State: OperationQueued, QueuedAt: time.Now()},
+		ready: make(chan struct{}),
+	}
+	l.queue = append(l.queue, p)
+	l.admitLocked()
+	return p
+}
+
+// admitLocked moves queued operations into free slots in FIFO order
+func (l *OperationLimiter) admitLocked() {
+	for len(l.running) < l.maxConcurrent && len(l.queue) > 0 {
+		p := l.queue[0]
+		l.queue = l.queue[1:]
+		now := time.Now()
+		p.op.State = OperationRunning
+		p.op.StartedAt = &now
+		l.running = append(l.running, p.op)
+		close(p.ready)
+	}
+}
+
+func (l *OperationLimiter) release(op *Operation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked(op)
+}
+
+func (l *OperationLimiter) releaseLocked(op *Operation) {
+	for i, running := range l.running {
+		if running == op {
+			l.running = append(l.running[:i], l.running[i+1:]...)
+			break
+		}
+	}
+	l.admitLocked()
+}
+
+func (l *OperationLimiter) removeQueuedLocked(p *pendingOperation) {
+	for i, queued := range l.queue {
+		if queued == p {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// snapshotLocked copies an operation, filling in its queue position
+func (l *OperationLimiter) snapshotLocked(op *Operation) Operation {
+	snapshot := *op
+	for i, p := range l.queue {
+		if p.op == op {
+			snapshot.Position = i + 1
+			break
+		}
+	}
+	return snapshot
+}
@@ -0,0 +1,86 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperationLimiterQueuesAndRejects(t *testing.T) {
+	l := NewOperationLimiter(1, 1)
+
+	unblock := make(chan struct{})
+	started := make(chan string, 2)
+	work := func(name string) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			started <- name
+			<-unblock
+		}
+	}
+
+	first, err := l.Submit("scan", "10.0.0.0/24", work("first"))
+	if err != nil || first.State != OperationRunning {
+		t.Fatalf("first Submit() = %+v, %v, want running", first, err)
+	}
+	second, err := l.Submit("scan", "10.0.1.0/24", work("second"))
+	if err != nil || second.State != OperationQueued || second.Position != 1 {
+		t.Fatalf("second Submit() = %+v, %v, want queued at position 1", second, err)
+	}
+	if _, err := l.Submit("scan", "10.0.2.0/24", work("third")); !errors.Is(err, ErrDiscoveryQueueFull) {
+		t.Fatalf("third Submit() error = %v, want ErrDiscoveryQueueFull", err)
+	}
+
+	if got := <-started; got != "first" {
+		t.Fatalf("started %s, want first", got)
+	}
+	status := l.Status()
+	if len(status.Running) != 1 || len(status.Queued) != 1 || status.Queued[0].Target != "10.0.1.0/24" {
+		t.Errorf("status = %+v, want one running and the second queued", status)
+	}
+
+	// Finishing the first admits the second
+	unblock <- struct{}{}
+	select {
+	case got := <-started:
+		if got != "second" {
+			t.Errorf("started %s, want second", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("queued operation was not started")
+	}
+	close(unblock)
+}
+
+func TestOperationLimiterAcquireHonorsContext(t *testing.T) {
+	l := NewOperationLimiter(1, 0)
+
+	release, err := l.Acquire(context.Background(), "verifier", "")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, "nmap", ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("blocked Acquire() error = %v, want deadline exceeded", err)
+	}
+	if status := l.Status(); len(status.Queued) != 0 {
+		t.Errorf("cancelled waiter still queued: %+v", status.Queued)
+	}
+
+	release()
+	release2, err := l.Acquire(context.Background(), "nmap", "")
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	release2()
+
+	// A nil limiter never blocks
+	var unlimited *OperationLimiter
+	done, err := unlimited.Acquire(context.Background(), "scan", "")
+	if err != nil {
+		t.Fatalf("nil Acquire() error = %v", err)
+	}
+	done()
+}
@@ -0,0 +1,506 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// LLDPConfig holds configuration for local LLDP neighbor discovery
+type LLDPConfig struct {
+	// OriginID is the node neighbors are linked from (this Specularium instance)
+	OriginID string
+	// Timeout bounds a single lldpctl run
+	Timeout time.Duration
+}
+
+// DefaultLLDPConfig returns sensible defaults
+func DefaultLLDPConfig() LLDPConfig {
+	return LLDPConfig{
+		OriginID: "specularium",
+		Timeout:  10 * time.Second,
+	}
+}
+
+// localLLDPNeighbor is a neighbor heard on one of this host's interfaces
+type localLLDPNeighbor struct {
+	LocalPort    string
+	ChassisID    string
+	ChassisMAC   string
+	SysName      string
+	SysDescr     string
+	MgmtIP       string
+	PortID       string
+	PortDescr    string
+	Capabilities []string // enabled capabilities, e.g. "Bridge", "Router"
+	SpeedMbps    int      // from the negotiated MAU type, 0 if unknown
+	Duplex       string   // full or half, "" if unknown
+	VLANID       int      // port VLAN ID, 0 if not advertised
+}
+
+// LLDPAdapter reads the neighbors lldpd has heard on this host's own
+// interfaces and links the self node to the switches it is plugged into.
+// Unlike SNMP it needs no credentials, only a local lldpd, so it is useful
+// on bare metal and in containers with host networking. Without lldpd or
+// any neighbors it returns an empty fragment.
+type LLDPAdapter struct {
+	config  LLDPConfig
+	nodes   NodeLister
+	mu      sync.Mutex
+	running bool
+
+	// readNeighbors returns lldpctl JSON output; swappable for tests
+	readNeighbors func(ctx context.Context) ([]byte, error)
+}
+
+// NewLLDPAdapter creates a new LLDP adapter.
+// Nodes is used to match neighbors to existing nodes and may be nil.
+func NewLLDPAdapter(nodes NodeLister, config LLDPConfig) *LLDPAdapter {
+	if config.OriginID == "" {
+		config.OriginID = "specularium"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	a := &LLDPAdapter{config: config, nodes: nodes}
+	a.readNeighbors = a.runLLDPCtl
+	return a
+}
+
+// Name returns the adapter identifier
+func (a *LLDPAdapter) Name() string {
+	return "lldp"
+}
+
+// Type returns the adapter type
+func (a *LLDPAdapter) Type() AdapterType {
+	return AdapterTypePolling
+}
+
+// Priority returns the adapter priority
+func (a *LLDPAdapter) Priority() int {
+	return 65 // Switches announce themselves, so identity and port are reliable
+}
+
+// Start initializes the adapter
+func (a *LLDPAdapter) Start(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = true
+	log.Printf("LLDP adapter started (origin=%s)", a.config.OriginID)
+	return nil
+}
+
+// Stop shuts down the adapter
+func (a *LLDPAdapter) Stop() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.running = false
+	log.Printf("LLDP adapter stopped")
+	return nil
+}
+
+// errLLDPUnavailable is returned when lldpctl is not installed
+var errLLDPUnavailable = errors.New("lldpctl binary not found")
+
+// Sync returns a node per LLDP neighbor and an edge from the self node to
+// each, carrying the local and remote ports and the chassis ID. Neighbors
+// already in the graph, by management IP or MAC, reuse their node.
+func (a *LLDPAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	fragment := domain.NewGraphFragment()
+
+	data, err := a.readNeighbors(ctx)
+	if errors.Is(err, errLLDPUnavailable) {
+		return fragment, nil
+	}
+	if err != nil {
+		// lldpd not running (no socket) is the common case off bare metal
+		log.Printf("LLDP: no neighbor data: %v", err)
+		return fragment, nil
+	}
+
+	neighbors, err := parseLLDPCtlJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse lldpctl output: %w", err)
+	}
+	if len(neighbors) == 0 {
+		return fragment, nil
+	}
+
+	byIP, byMAC := a.existingNodes(ctx)
+	now := time.Now()
+	seenNodes := make(map[string]bool)
+	seenEdges := make(map[string]bool)
+
+	for _, n := range neighbors {
+		existing := byIP[n.MgmtIP]
+		if existing == nil {
+			existing = byMAC[n.ChassisMAC]
+		}
+		node := lldpNode(n, existing, now)
+		if node.ID == a.config.OriginID {
+			continue
+		}
+		if !seenNodes[node.ID] {
+			seenNodes[node.ID] = true
+			fragment.AddNode(node)
+		}
+
+		edge := domain.NewEdge(a.config.OriginID, node.ID, domain.EdgeTypeEthernet)
+		if seenEdges[edge.ID] {
+			continue
+		}
+		seenEdges[edge.ID] = true
+		edge.SetProperty("source", "lldp")
+		edge.SetProperty("local_port", n.LocalPort)
+		edge.SetProperty("remote_port", n.PortID)
+		if n.PortDescr != "" {
+			edge.SetProperty("remote_port_descr", n.PortDescr)
+		}
+		edge.SetProperty("chassis_id", n.ChassisID)
+		if n.SpeedMbps > 0 {
+			edge.SetSpeedMbps(n.SpeedMbps)
+		}
+		if n.Duplex != "" {
+			edge.SetDuplex(n.Duplex)
+		}
+		if n.VLANID > 0 {
+			edge.SetVLANID(n.VLANID)
+		}
+		fragment.AddEdge(*edge)
+	}
+
+	log.Printf("LLDP: %d neighbors on local interfaces", len(neighbors))
+	return fragment, nil
+}
+
+// existingNodes indexes current nodes by their ip property and MAC address
+func (a *LLDPAdapter) existingNodes(ctx context.Context) (byIP, byMAC map[string]*domain.Node) {
+	byIP = make(map[string]*domain.Node)
+	byMAC = make(map[string]*domain.Node)
+	if a.nodes == nil {
+		return byIP, byMAC
+	}
+	nodes, err := a.nodes.ListNodes(ctx, "", "")
+	if err != nil {
+		log.Printf("LLDP: failed to list nodes: %v", err)
+		return byIP, byMAC
+	}
+	for i := range nodes {
+		if ip := nodes[i].GetPropertyString("ip"); ip != "" {
+			byIP[ip] = &nodes[i]
+		}
+		if mac := nodes[i].MACAddress(); mac != "" {
+			byMAC[mac] = &nodes[i]
+		}
+	}
+	return byIP, byMAC
+}
+
+// lldpNode builds the node for a neighbor, merging into an existing node if
+// given. New nodes are keyed by chassis MAC when there is one, matching
+// the IDs the ARP adapter gives MAC-only hosts.
+func lldpNode(n localLLDPNeighbor, existing *domain.Node, now time.Time) domain.Node {
+	var node domain.Node
+	if existing != nil {
+		// Carry existing state forward since reconcile replaces it
+		node = *existing
+		node.Discovered = make(map[string]any, len(existing.Discovered)+4)
+		for k, v := range existing.Discovered {
+			node.Discovered[k] = v
+		}
+	} else {
+		id := "lldp-" + slugify(n.ChassisID)
+		if n.ChassisMAC != "" {
+			id = "mac-" + strings.ReplaceAll(n.ChassisMAC, ":", "")
+		}
+		label := n.SysName
+		if label == "" {
+			label = n.ChassisID
+		}
+		node = domain.Node{
+			ID:         id,
+			Type:       lldpNodeType(n.Capabilities),
+			Label:      label,
+			Source:     "lldp",
+			Status:     domain.NodeStatusVerified,
+			Properties: make(map[string]any),
+			Discovered: make(map[string]any),
+		}
+		if n.MgmtIP != "" {
+			node.Properties["ip"] = n.MgmtIP
+		}
+		node.LastVerified = &now
+	}
+	node.LastSeen = &now
+
+	node.Discovered["lldp_chassis_id"] = n.ChassisID
+	if n.ChassisMAC != "" {
+		node.Discovered["mac_address"] = n.ChassisMAC
+		setMACVendor(&node, LookupMACVendor(n.ChassisMAC))
+	}
+	if n.SysName != "" {
+		node.Discovered["lldp_system_name"] = n.SysName
+	}
+	if n.SysDescr != "" {
+		node.Discovered["lldp_system_description"] = n.SysDescr
+	}
+	if len(n.Capabilities) > 0 {
+		node.Discovered["lldp_capabilities"] = n.Capabilities
+	}
+	return node
+}
+
+// lldpNodeType infers a node type from the neighbor's enabled capabilities.
+// Neighbors are assumed to be switches unless they only route, serve WLAN
+// or are end stations such as phones.
+func lldpNodeType(capabilities []string) domain.NodeType {
+	has := make(map[string]bool, len(capabilities))
+	for _, c := range capabilities {
+		has[strings.ToLower(c)] = true
+	}
+	switch {
+	case has["bridge"]:
+		return domain.NodeTypeSwitch
+	case has["router"]:
+		return domain.NodeTypeRouter
+	case has["wlan"]:
+		return domain.NodeTypeAccessPoint
+	case has["station"], has["tel"]:
+		return domain.NodeTypeServer
+	}
+	return domain.NodeTypeSwitch
+}
+
+// slugify lowercases s and replaces anything but letters and digits with '-'
+func slugify(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// runLLDPCtl queries the local lldpd for its neighbors
+func (a *LLDPAdapter) runLLDPCtl(ctx context.Context) ([]byte, error) {
+	if _, err := exec.LookPath("lldpctl"); err != nil {
+		return nil, errLLDPUnavailable
+	}
+	ctx, cancel := context.WithTimeout(ctx, a.config.Timeout)
+	defer cancel()
+	return exec.CommandContext(ctx, "lldpctl", "-f", "json").Output()
+}
+
+// parseLLDPCtlJSON parses `lldpctl -f json` output. lldpd collapses
+// single-element lists into objects, so "interface" is either an object
+// keyed by interface name or a list of such objects, and a chassis is
+// either keyed by system name or given directly.
+func parseLLDPCtlJSON(data []byte) ([]localLLDPNeighbor, error) {
+	var doc struct {
+		LLDP struct {
+			Interface json.RawMessage `json:"interface"`
+		} `json:"lldp"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.LLDP.Interface) == 0 {
+		return nil, nil
+	}
+
+	var groups []map[string]lldpctlInterface
+	if err := unmarshalOneOrMany(doc.LLDP.Interface, &groups); err != nil {
+		return nil, fmt.Errorf("interface: %w", err)
+	}
+
+	var neighbors []localLLDPNeighbor
+	for _, group := range groups {
+		names := make([]string, 0, len(group))
+		for name := range group {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			n, ok, err := group[name].neighbor(name)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", name, err)
+			}
+			if ok {
+				neighbors = append(neighbors, n)
+			}
+		}
+	}
+	return neighbors, nil
+}
+
+// lldpctlInterface is one local interface entry in lldpctl JSON
+type lldpctlInterface struct {
+	Chassis json.RawMessage `json:"chassis"`
+	Port    struct {
+		ID              lldpctlID `json:"id"`
+		Descr           string    `json:"descr"`
+		AutoNegotiation struct {
+			Current string `json:"current"`
+		} `json:"auto-negotiation"`
+	} `json:"port"`
+	VLAN json.RawMessage `json:"vlan"`
+}
+
+// lldpctlVLAN is one VLAN advertised on a port
+type lldpctlVLAN struct {
+	ID   string `json:"vlan-id"`
+	PVID bool   `json:"pvid"`
+}
+
+// mauTypePattern matches the speed prefix of an 802.3 MAU type such as
+// "1000BaseTFD" or "10GigBaseLR"
+var mauTypePattern = regexp.MustCompile(`(?i)^(\d+)(gig)?base`)
+
+// parseMAUType reads the link speed and duplex from an lldpctl MAU type
+// description, e.g. "1000BaseTFD - Four-pair Category 5 UTP, full duplex mode"
+func parseMAUType(mau string) (speedMbps int, duplex string) {
+	if m := mauTypePattern.FindStringSubmatch(mau); m != nil {
+		speedMbps, _ = strconv.Atoi(m[1])
+		if m[2] != "" {
+			speedMbps *= 1000
+		}
+	}
+	code, _, _ := strings.Cut(mau, " ")
+	lower := strings.ToLower(mau)
+	switch {
+	case strings.Contains(lower, "full duplex") || strings.HasSuffix(code, "FD"):
+		duplex = domain.DuplexFull
+	case strings.Contains(lower, "half duplex") || strings.HasSuffix(code, "HD"):
+		duplex = domain.DuplexHalf
+	}
+	return speedMbps, duplex
+}
+
+// lldpctlID is a typed identifier such as {"type": "mac", "value": "..."}
+type lldpctlID struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// lldpctlChassis describes the remote system
+type lldpctlChassis struct {
+	ID         lldpctlID       `json:"id"`
+	Descr      string          `json:"descr"`
+	MgmtIP     json.RawMessage `json:"mgmt-ip"`
+	Capability json.RawMessage `json:"capability"`
+}
+
+// neighbor converts the entry for local port name
+func (i lldpctlInterface) neighbor(name string) (localLLDPNeighbor, bool, error) {
+	n := localLLDPNeighbor{
+		LocalPort: name,
+		PortID:    i.Port.ID.Value,
+		PortDescr: i.Port.Descr,
+	}
+	if len(i.Chassis) == 0 {
+		return n, false, nil
+	}
+	n.SpeedMbps, n.Duplex = parseMAUType(i.Port.AutoNegotiation.Current)
+
+	// Prefer the port VLAN ID; otherwise take a lone advertised VLAN
+	var vlans []lldpctlVLAN
+	if len(i.VLAN) > 0 {
+		if err := unmarshalOneOrMany(i.VLAN, &vlans); err != nil {
+			return n, false, fmt.Errorf("vlan: %w", err)
+		}
+	}
+	for _, vlan := range vlans {
+		if vlan.PVID || len(vlans) == 1 {
+			n.VLANID, _ = strconv.Atoi(vlan.ID)
+			break
+		}
+	}
+
+	// Either {"id": ...} or {"<sysname>": {"id": ...}}
+	var chassis lldpctlChassis
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(i.Chassis, &probe); err != nil {
+		return n, false, fmt.Errorf("chassis: %w", err)
+	}
+	if _, direct := probe["id"]; direct {
+		if err := json.Unmarshal(i.Chassis, &chassis); err != nil {
+			return n, false, fmt.Errorf("chassis: %w", err)
+		}
+	} else {
+		for sysName, raw := range probe {
+			if err := json.Unmarshal(raw, &chassis); err != nil {
+				return n, false, fmt.Errorf("chassis %s: %w", sysName, err)
+			}
+			n.SysName = sysName
+			break
+		}
+	}
+
+	n.ChassisID = chassis.ID.Value
+	if chassis.ID.Type == "mac" {
+		n.ChassisMAC = domain.NormalizeMAC(chassis.ID.Value)
+	}
+	n.SysDescr = chassis.Descr
+
+	var mgmtIPs []string
+	if len(chassis.MgmtIP) > 0 {
+		if err := unmarshalOneOrMany(chassis.MgmtIP, &mgmtIPs); err != nil {
+			return n, false, fmt.Errorf("mgmt-ip: %w", err)
+		}
+	}
+	for _, ip := range mgmtIPs {
+		// Prefer IPv4, which is what the rest of the graph keys on
+		if n.MgmtIP == "" || (!strings.Contains(ip, ":") && strings.Contains(n.MgmtIP, ":")) {
+			n.MgmtIP = ip
+		}
+	}
+
+	var caps []struct {
+		Type    string `json:"type"`
+		Enabled bool   `json:"enabled"`
+	}
+	if len(chassis.Capability) > 0 {
+		if err := unmarshalOneOrMany(chassis.Capability, &caps); err != nil {
+			return n, false, fmt.Errorf("capability: %w", err)
+		}
+	}
+	for _, c := range caps {
+		if c.Enabled {
+			n.Capabilities = append(n.Capabilities, c.Type)
+		}
+	}
+
+	return n, n.ChassisID != "", nil
+}
+
+// unmarshalOneOrMany decodes raw into the slice at dst, accepting a bare
+// element as a one-element list
+func unmarshalOneOrMany[T any](raw json.RawMessage, dst *[]T) error {
+	trimmed := strings.TrimSpace(string(raw))
+	if strings.HasPrefix(trimmed, "[") {
+		return json.Unmarshal(raw, dst)
+	}
+	var one T
+	if err := json.Unmarshal(raw, &one); err != nil {
+		return err
+	}
+	*dst = []T{one}
+	return nil
+}
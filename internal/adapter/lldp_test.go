@@ -0,0 +1,156 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+// lldpctlFixture is `lldpctl -f json` output from lldpd 1.0 with two
+// neighbors: a switch keyed by system name and a phone with no system name
+const lldpctlFixture = `{
+  "lldp": {
+    "interface": [
+      {
+        "eno1": {
+          "via": "LLDP",
+          "rid": "1",
+          "age": "0 day, 02:14:07",
+          "chassis": {
+            "core-sw1": {
+              "id": {"type": "mac", "value": "24:A4:3C:10:20:30"},
+              "descr": "UniFi Switch 24 POE, 6.5.59",
+              "mgmt-ip": ["192.168.1.2", "fe80::26a4:3cff:fe10:2030"],
+              "capability": [
+                {"type": "Bridge", "enabled": true},
+                {"type": "Router", "enabled": false}
+              ]
+            }
+          },
+          "port": {
+            "id": {"type": "local", "value": "Port 12"},
+            "descr": "Port 12",
+            "ttl": "120",
+            "auto-negotiation": {
+              "supported": true,
+              "enabled": true,
+              "current": "1000BaseTFD - Four-pair Category 5 UTP, full duplex mode"
+            }
+          },
+          "vlan": {"vlan-id": "20", "pvid": true, "value": "iot"}
+        }
+      },
+      {
+        "eno2": {
+          "via": "LLDP",
+          "rid": "2",
+          "age": "0 day, 00:03:41",
+          "chassis": {
+            "id": {"type": "local", "value": "SEP001122334455"},
+            "capability": {"type": "Tel", "enabled": true}
+          },
+          "port": {
+            "id": {"type": "ifname", "value": "Port 1"}
+          }
+        }
+      }
+    ]
+  }
+}`
+
+func TestParseLLDPCtlJSON(t *testing.T) {
+	neighbors, err := parseLLDPCtlJSON([]byte(lldpctlFixture))
+	if err != nil {
+		t.Fatalf("parseLLDPCtlJSON() error = %v", err)
+	}
+	if len(neighbors) != 2 {
+		t.Fatalf("got %d neighbors, want 2: %+v", len(neighbors), neighbors)
+	}
+
+	sw := neighbors[0]
+	if sw.LocalPort != "eno1" || sw.SysName != "core-sw1" || sw.PortID != "Port 12" {
+		t.Errorf("switch neighbor = %+v", sw)
+	}
+	if sw.ChassisMAC != "24:a4:3c:10:20:30" || sw.MgmtIP != "192.168.1.2" {
+		t.Errorf("switch chassis MAC/IP = %q/%q", sw.ChassisMAC, sw.MgmtIP)
+	}
+	if len(sw.Capabilities) != 1 || sw.Capabilities[0] != "Bridge" {
+		t.Errorf("switch capabilities = %v, want [Bridge]", sw.Capabilities)
+	}
+	if sw.SpeedMbps != 1000 || sw.Duplex != domain.DuplexFull || sw.VLANID != 20 {
+		t.Errorf("switch link = %d Mbit/s %q duplex vlan %d, want 1000 full 20", sw.SpeedMbps, sw.Duplex, sw.VLANID)
+	}
+
+	phone := neighbors[1]
+	if phone.ChassisID != "SEP001122334455" || phone.ChassisMAC != "" || phone.SysName != "" {
+		t.Errorf("phone neighbor = %+v", phone)
+	}
+
+	t.Run("single interface object", func(t *testing.T) {
+		single := `{"lldp": {"interface": {"eth0": {"chassis": {"id": {"type": "mac", "value": "aa:bb:cc:00:00:01"}}, "port": {"id": {"type": "ifname", "value": "ge-0/0/1"}}}}}}`
+		neighbors, err := parseLLDPCtlJSON([]byte(single))
+		if err != nil {
+			t.Fatalf("parseLLDPCtlJSON() error = %v", err)
+		}
+		if len(neighbors) != 1 || neighbors[0].PortID != "ge-0/0/1" {
+			t.Errorf("neighbors = %+v", neighbors)
+		}
+	})
+
+	t.Run("no neighbors", func(t *testing.T) {
+		neighbors, err := parseLLDPCtlJSON([]byte(`{"lldp": {}}`))
+		if err != nil || len(neighbors) != 0 {
+			t.Errorf("got %v, %v; want no neighbors", neighbors, err)
+		}
+	})
+}
+
+func TestLLDPAdapterSync(t *testing.T) {
+	known := domain.Node{
+		ID:         "192-168-1-2",
+		Type:       domain.NodeTypeSwitch,
+		Label:      "core",
+		Properties: map[string]any{"ip": "192.168.1.2"},
+	}
+	a := NewLLDPAdapter(&fakeNodeLister{nodes: []domain.Node{known}}, DefaultLLDPConfig())
+	a.readNeighbors = func(ctx context.Context) ([]byte, error) { return []byte(lldpctlFixture), nil }
+
+	fragment, err := a.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(fragment.Nodes) != 2 || len(fragment.Edges) != 2 {
+		t.Fatalf("got %d nodes and %d edges, want 2 and 2", len(fragment.Nodes), len(fragment.Edges))
+	}
+
+	sw := fragment.Nodes[0]
+	if sw.ID != known.ID || sw.Discovered["mac_address"] != "24:a4:3c:10:20:30" {
+		t.Errorf("switch not merged into existing node: %+v", sw)
+	}
+
+	edge := fragment.Edges[0]
+	if edge.FromID != "specularium" || edge.ToID != known.ID {
+		t.Errorf("edge = %s -> %s", edge.FromID, edge.ToID)
+	}
+	if edge.Properties["local_port"] != "eno1" || edge.Properties["remote_port"] != "Port 12" ||
+		edge.Properties["chassis_id"] != "24:A4:3C:10:20:30" || edge.Properties["source"] != "lldp" {
+		t.Errorf("edge properties = %v", edge.Properties)
+	}
+	if speed, _ := edge.SpeedMbps(); speed != 1000 || edge.Duplex() != domain.DuplexFull {
+		t.Errorf("edge link = %d Mbit/s %q duplex, want 1000 full", speed, edge.Duplex())
+	}
+
+	phone := fragment.Nodes[1]
+	if phone.ID != "lldp-sep001122334455" || phone.Type != domain.NodeTypeServer || phone.Label != "SEP001122334455" {
+		t.Errorf("phone node = %+v", phone)
+	}
+
+	t.Run("falls back silently without lldpd", func(t *testing.T) {
+		a.readNeighbors = func(ctx context.Context) ([]byte, error) { return nil, errLLDPUnavailable }
+		fragment, err := a.Sync(context.Background())
+		if err != nil || len(fragment.Nodes) != 0 {
+			t.Errorf("got %d nodes, %v; want an empty fragment", len(fragment.Nodes), err)
+		}
+	})
+}
@@ -0,0 +1,418 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// mdnsAddress is the IPv4 mDNS multicast group
+const mdnsAddress = "224.0.0.251:5353"
+
+// mdnsServiceEnumeration lists every service type advertised on the link
+const mdnsServiceEnumeration = "_services._dns-sd._udp.local"
+
+// DefaultMDNSServiceTypes are browsed even if a responder skips enumeration
+var DefaultMDNSServiceTypes = []string{
+	"_http._tcp.local",
+	"_https._tcp.local",
+	"_ssh._tcp.local",
+	"_smb._tcp.local",
+	"_ipp._tcp.local",
+	"_printer._tcp.local",
+	"_googlecast._tcp.local",
+	"_airplay._tcp.local",
+	"_raop._tcp.local",
+	"_homekit._tcp.local",
+	"_workstation._tcp.local",
+}
+
+// MDNSService is a single advertised service instance
+type MDNSService struct {
+	Type     string   `json:"type"`
+	Instance string   `json:"instance"`
+	Port     int      `json:"port"`
+	TXT      []string `json:"txt,omitempty"`
+}
+
+// mdnsHost collects everything advertised for one address
+type mdnsHost struct {
+	IP       string
+	Hostname string
+	Services []MDNSService
+}
+
+// MDNSConfig holds configuration for the mDNS adapter
+type MDNSConfig struct {
+	// ServiceTypes are browsed in addition to enumerated ones
+	ServiceTypes []string
+	// Timeout is how long to collect responses per browse round
+	Timeout time.Duration
+}
+
+// DefaultMDNSConfig returns sensible defaults
+func DefaultMDNSConfig() MDNSConfig {
+	return MDNSConfig{
+		ServiceTypes: DefaultMDNSServiceTypes,
+		Timeout:      3 * time.Second,
+	}
+}
+
+// MDNSAdapter browses DNS-SD service types over multicast DNS and reports
+// the hosts advertising them with their services and friendly hostnames.
+type MDNSAdapter struct {
+	config    MDNSConfig
+	nodes     NodeLister
+	publisher EventPublisher
+	mu        sync.Mutex
+	running   bool
+
+	// query is swappable for tests; it returns raw response packets
+	query func(ctx context.Context, names []string, timeout time.Duration) ([][]byte, error)
+}
+
+// NewMDNSAdapter creates a new mDNS adapter.
+// Nodes is used to merge results into existing nodes and may be nil.
+func NewMDNSAdapter(nodes NodeLister, config MDNSConfig) *MDNSAdapter {
+	if len(config.ServiceTypes) == 0 {
+		config.ServiceTypes = DefaultMDNSServiceTypes
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 3 * time.Second
+	}
+
+	return &MDNSAdapter{
+		config: config,
+		nodes:  nodes,
+		query:  multicastQuery,
+	}
+}
+
+// SetEventPublisher sets the event publisher for progress updates
+func (m *MDNSAdapter) SetEventPublisher(pub EventPublisher) {
+	m.publisher = pub
+}
+
+// publishProgress emits a discovery event
+func (m *MDNSAdapter) publishProgress(eventType string, payload interface{}) {
+	if m.publisher != nil {
+		m.publisher.PublishDiscoveryEvent(eventType, payload)
+	}
+}
+
+// Name returns the adapter identifier
+func (m *MDNSAdapter) Name() string {
+	return "mdns"
+}
+
+// Type returns the adapter type
+func (m *MDNSAdapter) Type() AdapterType {
+	return AdapterTypeOneShot
+}
+
+// Priority returns the adapter priority
+func (m *MDNSAdapter) Priority() int {
+	return 40 // Self-advertised names are useful but unauthenticated
+}
+
+// Start initializes the adapter
+func (m *MDNSAdapter) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = true
+	log.Printf("mDNS adapter started (types=%d, timeout=%s)", len(m.config.ServiceTypes), m.config.Timeout)
+	return nil
+}
+
+// Stop shuts down the adapter
+func (m *MDNSAdapter) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running = false
+	log.Printf("mDNS adapter stopped")
+	return nil
+}
+
+// Sync browses the local link and returns a node per advertising host.
+// Hosts already in the graph are merged into their existing node.
+func (m *MDNSAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	m.publishProgress("discovery-started", map[string]interface{}{
+		"message": "Browsing mDNS services",
+		"phase":   "mdns_browse",
+	})
+
+	// First round: known types plus the enumeration meta-query
+	names := append([]string{mdnsServiceEnumeration}, m.config.ServiceTypes...)
+	packets, err := m.query(ctx, names, m.config.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("mDNS query failed: %w", err)
+	}
+	records := parseMDNSPackets(packets)
+
+	// Second round: any types only learned through enumeration
+	queried := make(map[string]bool, len(names))
+	for _, n := range names {
+		queried[strings.ToLower(n)] = true
+	}
+	var extra []string
+	for _, rec := range records {
+		if rec.Type == dnsTypePTR && strings.EqualFold(rec.Name, mdnsServiceEnumeration) && !queried[strings.ToLower(rec.Target)] {
+			queried[strings.ToLower(rec.Target)] = true
+			extra = append(extra, rec.Target)
+		}
+	}
+	if len(extra) > 0 {
+		more, err := m.query(ctx, extra, m.config.Timeout)
+		if err != nil {
+			log.Printf("mDNS: follow-up query failed: %v", err)
+		}
+		records = append(records, parseMDNSPackets(more)...)
+	}
+
+	hosts := buildMDNSHosts(records)
+	existing := m.existingByIP(ctx)
+	now := time.Now()
+
+	fragment := domain.NewGraphFragment()
+	for i, host := range hosts {
+		fragment.AddNode(mdnsNode(host, existing[host.IP], now))
+
+		m.publishProgress("discovery-progress", map[string]interface{}{
+			"ip":       host.IP,
+			"hostname": host.Hostname,
+			"services": host.Services,
+			"current":  i + 1,
+			"total":    len(hosts),
+			"message":  fmt.Sprintf("mDNS: %s advertises %d services", host.Hostname, len(host.Services)),
+			"phase":    "mdns_browse",
+		})
+	}
+
+	m.publishProgress("discovery-complete", map[string]interface{}{
+		"discovered": len(hosts),
+		"message":    fmt.Sprintf("mDNS browse complete: %d hosts", len(hosts)),
+	})
+	log.Printf("mDNS: browse complete, %d hosts advertising services", len(hosts))
+
+	return fragment, nil
+}
+
+// existingByIP indexes current nodes by their ip property
+func (m *MDNSAdapter) existingByIP(ctx context.Context) map[string]*domain.Node {
+	index := make(map[string]*domain.Node)
+	if m.nodes == nil {
+		return index
+	}
+	nodes, err := m.nodes.ListNodes(ctx, "", "")
+	if err != nil {
+		log.Printf("mDNS: failed to list nodes: %v", err)
+		return index
+	}
+	for i := range nodes {
+		if ip := nodes[i].GetPropertyString("ip"); ip != "" {
+			index[ip] = &nodes[i]
+		}
+	}
+	return index
+}
+
+// mdnsNode builds the node for a host, merging into an existing node if given
+func mdnsNode(host mdnsHost, existing *domain.Node, now time.Time) domain.Node {
+	var node domain.Node
+	if existing != nil {
+		// Carry existing state forward since reconcile replaces it
+		node = *existing
+		node.Discovered = make(map[string]any, len(existing.Discovered)+3)
+		for k, v := range existing.Discovered {
+			node.Discovered[k] = v
+		}
+	} else {
+		node = domain.Node{
+			ID:         sanitizeIP(host.IP),
+			Type:       domain.NodeTypeUnknown,
+			Label:      host.IP,
+			Source:     "mdns",
+			Status:     domain.NodeStatusVerified,
+			Properties: map[string]any{"ip": host.IP},
+			Discovered: make(map[string]any),
+		}
+		node.LastVerified = &now
+	}
+	node.LastSeen = &now
+
+	node.Discovered["mdns_services"] = host.Services
+	if host.Hostname != "" {
+		node.Discovered["mdns_hostname"] = host.Hostname
+
+		inference := existingHostnameInference(node.Discovered["hostname_inference"])
+		inference.AddCandidate(host.Hostname, domain.SourceMDNS, now)
+		node.Discovered["hostname_inference"] = inference
+
+		if existing == nil {
+			node.Label = domain.ExtractShortName(host.Hostname)
+		}
+	}
+	return node
+}
+
+// existingHostnameInference decodes a stored inference, which may be a
+// struct or a JSON-decoded map
+func existingHostnameInference(raw any) domain.HostnameInference {
+	var inference domain.HostnameInference
+	switch v := raw.(type) {
+	case domain.HostnameInference:
+		return v
+	case *domain.HostnameInference:
+		if v != nil {
+			return *v
+		}
+	case map[string]any:
+		if data, err := json.Marshal(v); err == nil {
+			json.Unmarshal(data, &inference)
+		}
+	}
+	return inference
+}
+
+// parseMDNSPackets parses every packet, skipping malformed ones
+func parseMDNSPackets(packets [][]byte) []dnsRecord {
+	var records []dnsRecord
+	for _, p := range packets {
+		recs, err := parseDNSMessage(p)
+		if err != nil {
+			log.Printf("mDNS: skipping malformed response: %v", err)
+			continue
+		}
+		records = append(records, recs...)
+	}
+	return records
+}
+
+// buildMDNSHosts joins PTR -> SRV -> A records into per-host service lists.
+// Instances without a resolvable IPv4 address are dropped.
+func buildMDNSHosts(records []dnsRecord) []mdnsHost {
+	srv := make(map[string]dnsRecord)
+	txt := make(map[string][]string)
+	addrs := make(map[string]string)
+	instances := make(map[string]string) // instance -> service type
+
+	for _, rec := range records {
+		name := strings.ToLower(rec.Name)
+		switch rec.Type {
+		case dnsTypePTR:
+			if !strings.EqualFold(rec.Name, mdnsServiceEnumeration) {
+				instances[strings.ToLower(rec.Target)] = rec.Name
+			}
+		case dnsTypeSRV:
+			srv[name] = rec
+			// SRV implies the instance even if its PTR was not returned
+			if _, ok := instances[name]; !ok {
+				if idx := strings.Index(rec.Name, "._"); idx > 0 {
+					instances[name] = rec.Name[idx+1:]
+				}
+			}
+		case dnsTypeTXT:
+			txt[name] = rec.Text
+		case dnsTypeA:
+			if ip4 := rec.IP.To4(); ip4 != nil {
+				addrs[name] = ip4.String()
+			}
+		}
+	}
+
+	byIP := make(map[string]*mdnsHost)
+	for instance, serviceType := range instances {
+		s, ok := srv[instance]
+		if !ok {
+			continue
+		}
+		hostname := strings.TrimSuffix(strings.ToLower(s.Target), ".")
+		ip, ok := addrs[hostname]
+		if !ok {
+			continue
+		}
+
+		host, ok := byIP[ip]
+		if !ok {
+			host = &mdnsHost{IP: ip, Hostname: strings.TrimSuffix(hostname, ".local")}
+			byIP[ip] = host
+		}
+		host.Services = append(host.Services, MDNSService{
+			Type:     strings.TrimSuffix(serviceType, ".local"),
+			Instance: mdnsInstanceName(s.Name, serviceType),
+			Port:     int(s.Port),
+			TXT:      txt[instance],
+		})
+	}
+
+	hosts := make([]mdnsHost, 0, len(byIP))
+	for _, host := range byIP {
+		sort.Slice(host.Services, func(i, j int) bool {
+			if host.Services[i].Type != host.Services[j].Type {
+				return host.Services[i].Type < host.Services[j].Type
+			}
+			return host.Services[i].Instance < host.Services[j].Instance
+		})
+		hosts = append(hosts, *host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].IP < hosts[j].IP })
+	return hosts
+}
+
+// mdnsInstanceName strips the service type suffix from an instance name
+func mdnsInstanceName(instance, serviceType string) string {
+	suffix := "." + serviceType
+	if len(instance) > len(suffix) && strings.EqualFold(instance[len(instance)-len(suffix):], suffix) {
+		return instance[:len(instance)-len(suffix)]
+	}
+	return instance
+}
+
+// multicastQuery sends one query for all names and collects responses until
+// timeout. Querying from an ephemeral port makes responders answer unicast
+// (RFC 6762 section 6.7), so no multicast group membership is needed.
+func multicastQuery(ctx context.Context, names []string, timeout time.Duration) ([][]byte, error) {
+	packet, err := encodeMDNSQuery(names)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("open socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP(packet, group); err != nil {
+		return nil, fmt.Errorf("send query: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+
+	var packets [][]byte
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// Deadline reached: return what was collected
+			return packets, nil
+		}
+		packets = append(packets, append([]byte(nil), buf[:n]...))
+	}
+}
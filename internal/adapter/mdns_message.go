@@ -0,0 +1,176 @@
+package adapter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Minimal DNS wire format support for mDNS browsing: PTR queries out,
+// PTR/SRV/TXT/A/AAAA records back. Name compression is handled on read.
+
+// DNS record types used by DNS-SD
+const (
+	dnsTypeA    uint16 = 1
+	dnsTypePTR  uint16 = 12
+	dnsTypeTXT  uint16 = 16
+	dnsTypeAAAA uint16 = 28
+	dnsTypeSRV  uint16 = 33
+
+	dnsClassIN uint16 = 1
+)
+
+// dnsRecord is a parsed resource record. Only the fields relevant to
+// the record type are set.
+type dnsRecord struct {
+	Name   string
+	Type   uint16
+	Target string   // PTR and SRV
+	Port   uint16   // SRV
+	Text   []string // TXT
+	IP     net.IP   // A and AAAA
+}
+
+// encodeMDNSQuery builds a query packet asking for PTR records of each name
+func encodeMDNSQuery(names []string) ([]byte, error) {
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[4:], uint16(len(names))) // QDCOUNT
+
+	for _, name := range names {
+		encoded, err := encodeDNSName(name)
+		if err != nil {
+			return nil, err
+		}
+		msg = append(msg, encoded...)
+		msg = binary.BigEndian.AppendUint16(msg, dnsTypePTR)
+		msg = binary.BigEndian.AppendUint16(msg, dnsClassIN)
+	}
+	return msg, nil
+}
+
+// encodeDNSName encodes a dotted name as DNS labels
+func encodeDNSName(name string) ([]byte, error) {
+	var out []byte
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid DNS name %q", name)
+		}
+		out = append(out, byte(len(label)))
+		out = append(out, label...)
+	}
+	return append(out, 0), nil
+}
+
+// parseDNSMessage returns every resource record in a response packet
+func parseDNSMessage(msg []byte) ([]dnsRecord, error) {
+	if len(msg) < 12 {
+		return nil, fmt.Errorf("DNS message too short")
+	}
+	if msg[2]&0x80 == 0 {
+		return nil, fmt.Errorf("not a DNS response")
+	}
+
+	qdcount := int(binary.BigEndian.Uint16(msg[4:]))
+	rrcount := int(binary.BigEndian.Uint16(msg[6:])) +
+		int(binary.BigEndian.Uint16(msg[8:])) +
+		int(binary.BigEndian.Uint16(msg[10:]))
+
+	offset := 12
+	for i := 0; i < qdcount; i++ {
+		_, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4 // type, class
+	}
+
+	records := make([]dnsRecord, 0, rrcount)
+	for i := 0; i < rrcount; i++ {
+		name, next, err := readDNSName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(msg) {
+			return nil, fmt.Errorf("truncated resource record")
+		}
+		rrType := binary.BigEndian.Uint16(msg[next:])
+		rdlen := int(binary.BigEndian.Uint16(msg[next+8:]))
+		rdata := next + 10
+		if rdata+rdlen > len(msg) {
+			return nil, fmt.Errorf("truncated record data")
+		}
+		offset = rdata + rdlen
+
+		rec := dnsRecord{Name: name, Type: rrType}
+		switch rrType {
+		case dnsTypePTR:
+			if rec.Target, _, err = readDNSName(msg, rdata); err != nil {
+				return nil, err
+			}
+		case dnsTypeSRV:
+			if rdlen < 7 {
+				return nil, fmt.Errorf("short SRV record")
+			}
+			rec.Port = binary.BigEndian.Uint16(msg[rdata+4:])
+			if rec.Target, _, err = readDNSName(msg, rdata+6); err != nil {
+				return nil, err
+			}
+		case dnsTypeTXT:
+			for p := rdata; p < rdata+rdlen; {
+				n := int(msg[p])
+				if p+1+n > rdata+rdlen {
+					break
+				}
+				if n > 0 {
+					rec.Text = append(rec.Text, string(msg[p+1:p+1+n]))
+				}
+				p += 1 + n
+			}
+		case dnsTypeA, dnsTypeAAAA:
+			rec.IP = net.IP(append([]byte(nil), msg[rdata:rdata+rdlen]...))
+		default:
+			continue
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// readDNSName reads a possibly compressed name at offset, returning the
+// dotted name and the offset just past it in the original position
+func readDNSName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, fmt.Errorf("truncated DNS name")
+		}
+		n := int(msg[offset])
+		switch {
+		case n == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case n&0xc0 == 0xc0:
+			if offset+1 >= len(msg) {
+				return "", 0, fmt.Errorf("truncated DNS name pointer")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			jumps++
+			if jumps > 16 {
+				return "", 0, fmt.Errorf("DNS name pointer loop")
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+		default:
+			if offset+1+n > len(msg) {
+				return "", 0, fmt.Errorf("truncated DNS label")
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+n]))
+			offset += 1 + n
+		}
+	}
+}
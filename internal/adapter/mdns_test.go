@@ -0,0 +1,127 @@
+package adapter
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// capturedMDNSResponse is a Synology-style answer to a _http._tcp.local
+// browse: PTR answer plus SRV, TXT, A and AAAA additionals, using name
+// compression throughout.
+const capturedMDNSResponse = "000084000000000100000004055f68747470045f746370056c6f63616c00000c" +
+	"00010000119400120f4c6976696e6720526f6f6d204e4153c00cc02800218001" +
+	"00000078000c000000001388036e6173c017c028001080010000119400170670" +
+	"6174683d2f0f76656e646f723d53796e6f6c6f6779c04c000180010000007800" +
+	"04c0a80114c04c001c8001000000780010fe80000000000000021132fffe1122" +
+	"33"
+
+func TestParseDNSMessageCapturedResponse(t *testing.T) {
+	packet, err := hex.DecodeString(capturedMDNSResponse)
+	if err != nil {
+		t.Fatalf("bad fixture: %v", err)
+	}
+
+	records, err := parseDNSMessage(packet)
+	if err != nil {
+		t.Fatalf("parseDNSMessage() error = %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("got %d records, want 5", len(records))
+	}
+
+	ptr := records[0]
+	if ptr.Type != dnsTypePTR || ptr.Name != "_http._tcp.local" || ptr.Target != "Living Room NAS._http._tcp.local" {
+		t.Errorf("PTR = %+v", ptr)
+	}
+	srv := records[1]
+	if srv.Type != dnsTypeSRV || srv.Port != 5000 || srv.Target != "nas.local" {
+		t.Errorf("SRV = %+v", srv)
+	}
+	if txt := records[2]; len(txt.Text) != 2 || txt.Text[1] != "vendor=Synology" {
+		t.Errorf("TXT = %+v", txt)
+	}
+	if a := records[3]; a.Name != "nas.local" || a.IP.String() != "192.168.1.20" {
+		t.Errorf("A = %+v", a)
+	}
+
+	hosts := buildMDNSHosts(records)
+	if len(hosts) != 1 {
+		t.Fatalf("got %d hosts, want 1", len(hosts))
+	}
+	host := hosts[0]
+	if host.IP != "192.168.1.20" || host.Hostname != "nas" {
+		t.Errorf("host = %s (%s), want 192.168.1.20 (nas)", host.IP, host.Hostname)
+	}
+	if len(host.Services) != 1 {
+		t.Fatalf("got %d services, want 1", len(host.Services))
+	}
+	svc := host.Services[0]
+	if svc.Type != "_http._tcp" || svc.Instance != "Living Room NAS" || svc.Port != 5000 {
+		t.Errorf("service = %+v", svc)
+	}
+}
+
+func TestParseDNSMessageRejectsMalformed(t *testing.T) {
+	packet, _ := hex.DecodeString(capturedMDNSResponse)
+
+	if _, err := parseDNSMessage(packet[:40]); err == nil {
+		t.Error("expected error for truncated packet")
+	}
+
+	query, _ := encodeMDNSQuery([]string{"_ssh._tcp.local"})
+	if _, err := parseDNSMessage(query); err == nil {
+		t.Error("expected error for a query packet")
+	}
+
+	// Pointer that refers to itself
+	loop := append([]byte{0, 0, 0x84, 0, 0, 1, 0, 0, 0, 0, 0, 0}, 0xc0, 12)
+	if _, err := parseDNSMessage(loop); err == nil {
+		t.Error("expected error for compression loop")
+	}
+}
+
+func TestMDNSSyncMergesExistingNodes(t *testing.T) {
+	packet, _ := hex.DecodeString(capturedMDNSResponse)
+
+	existing := *domain.NewNode("192-168-1-20", domain.NodeTypeServer, "storage")
+	existing.SetProperty("ip", "192.168.1.20")
+	existing.Status = domain.NodeStatusVerified
+	existing.SetDiscovered("open_ports", []int{22, 5000})
+
+	m := NewMDNSAdapter(&fakeNodeLister{nodes: []domain.Node{existing}}, DefaultMDNSConfig())
+	var queries int
+	m.query = func(ctx context.Context, names []string, timeout time.Duration) ([][]byte, error) {
+		queries++
+		return [][]byte{packet}, nil
+	}
+
+	fragment, err := m.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if queries != 1 {
+		t.Errorf("got %d query rounds, want 1 (no new types enumerated)", queries)
+	}
+	if len(fragment.Nodes) != 1 {
+		t.Fatalf("got %d nodes, want 1", len(fragment.Nodes))
+	}
+
+	node := fragment.Nodes[0]
+	if node.ID != "192-168-1-20" || node.Label != "storage" {
+		t.Errorf("node = %s (%s), want existing node with label kept", node.ID, node.Label)
+	}
+	if _, ok := node.Discovered["open_ports"]; !ok {
+		t.Error("existing discoveries should be preserved")
+	}
+	if node.Discovered["mdns_hostname"] != "nas" {
+		t.Errorf("mdns_hostname = %v, want nas", node.Discovered["mdns_hostname"])
+	}
+	inference, ok := node.Discovered["hostname_inference"].(domain.HostnameInference)
+	if !ok || inference.Best == nil || inference.Best.Source != domain.SourceMDNS {
+		t.Errorf("hostname_inference = %#v, want mdns candidate", node.Discovered["hostname_inference"])
+	}
+}
@@ -2,9 +2,13 @@ package adapter
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,13 +24,51 @@ type NmapAdapter struct {
 	interval          time.Duration
 	timeout           time.Duration
 	portRange         string
+	udpPorts          []int // scanned with -sU when running as root
 	serviceDetection  bool
 	osDetection       bool
 	skipHostDiscovery bool
+	typeWeights       []domain.PortTypeWeight
+	portProfiles      PortProfiles
+	targetProfiles    map[string]string // target -> port profile name
 	publisher         EventPublisher
 	mu                sync.Mutex
 	running           bool
 	lastScanTime      time.Time
+
+	// runner executes a scan; replaced in tests
+	runner nmapRunner
+
+	// Per-target result cache, enabled when cacheTTL > 0
+	cacheTTL time.Duration
+	cache    map[string]nmapCacheEntry
+}
+
+// nmapRunner runs an nmap scan with the given options
+type nmapRunner func(ctx context.Context, opts ...nmap.Option) (*nmap.Run, error)
+
+// isRoot reports whether the process may run raw-socket scans such as
+// -sU; replaced in tests
+var isRoot = func() bool { return os.Geteuid() == 0 }
+
+// nmapCacheEntry is the last scan result for a target
+type nmapCacheEntry struct {
+	result    *nmap.Run
+	portsHash string
+	scannedAt time.Time
+}
+
+type forceRescanKey struct{}
+
+// WithForceRescan returns a context that makes NmapAdapter.Sync scan every
+// target and process the results, ignoring any cached result
+func WithForceRescan(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceRescanKey{}, true)
+}
+
+func forceRescan(ctx context.Context) bool {
+	force, _ := ctx.Value(forceRescanKey{}).(bool)
+	return force
 }
 
 // NewNmapAdapter creates a new nmap-based scanning adapter
@@ -40,6 +82,8 @@ func NewNmapAdapter(targets []string, opts ...NmapOption) *NmapAdapter {
 		portRange:        "22,25,53,80,443,445,3389,5432,5900,6443,8080,8443,9090,9100",
 		serviceDetection: true,
 		osDetection:      false, // Requires root
+		runner:           runNmap,
+		cache:            make(map[string]nmapCacheEntry),
 	}
 
 	// Apply options
@@ -90,6 +134,9 @@ func (n *NmapAdapter) Start(ctx context.Context) error {
 	n.running = true
 	log.Printf("Nmap adapter started (targets=%v, port_range=%s, service_detection=%v, os_detection=%v)",
 		n.targets, n.portRange, n.serviceDetection, n.osDetection)
+	if len(n.udpPorts) > 0 && !isRoot() {
+		log.Printf("Nmap: UDP scan of ports %s needs root, scanning TCP only", formatPortList(n.udpPorts))
+	}
 	return nil
 }
 
@@ -110,31 +157,33 @@ func (n *NmapAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
 		return nil, fmt.Errorf("adapter not running")
 	}
 	n.lastScanTime = time.Now()
+	targets := n.targets
 	n.mu.Unlock()
 
-	if len(n.targets) == 0 {
+	if len(targets) == 0 {
 		log.Printf("Nmap: no targets configured")
 		return nil, nil
 	}
 
-	log.Printf("Nmap: starting scan of %d targets: %v", len(n.targets), n.targets)
+	log.Printf("Nmap: starting scan of %d targets: %v", len(targets), targets)
 	n.publishProgress("discovery-started", map[string]interface{}{
-		"total":   len(n.targets),
-		"message": fmt.Sprintf("Starting nmap scan of %d targets", len(n.targets)),
+		"total":   len(targets),
+		"message": fmt.Sprintf("Starting nmap scan of %d targets", len(targets)),
 		"phase":   "nmap_scan",
 	})
 
 	fragment := domain.NewGraphFragment()
+	force := forceRescan(ctx)
 
-	for _, target := range n.targets {
-		if err := n.scanTarget(ctx, target, fragment); err != nil {
+	for _, target := range targets {
+		if err := n.scanTarget(ctx, target, fragment, force); err != nil {
 			log.Printf("Nmap: error scanning %s: %v", target, err)
 			continue
 		}
 	}
 
 	n.publishProgress("discovery-complete", map[string]interface{}{
-		"total":      len(n.targets),
+		"total":      len(targets),
 		"discovered": len(fragment.Nodes),
 		"message":    fmt.Sprintf("Nmap scan complete: %d hosts discovered", len(fragment.Nodes)),
 	})
@@ -143,6 +192,41 @@ func (n *NmapAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
 	return fragment, nil
 }
 
+// Targets returns the CIDR ranges and IPs scanned on each sync
+func (n *NmapAdapter) Targets() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.targets...)
+}
+
+// Reload replaces the scan targets and their port profiles while the
+// adapter runs; the next sync scans the new set. The "targets" setting is
+// a []string and "target_profiles" a map[string]string; either may be
+// omitted to keep the current value.
+func (n *NmapAdapter) Reload(settings map[string]any) error {
+	rawTargets, setTargets := settings["targets"]
+	targets, ok := rawTargets.([]string)
+	if setTargets && !ok {
+		return fmt.Errorf("invalid targets setting: %T", rawTargets)
+	}
+	rawProfiles, setProfiles := settings["target_profiles"]
+	profiles, ok := rawProfiles.(map[string]string)
+	if setProfiles && !ok {
+		return fmt.Errorf("invalid target_profiles setting: %T", rawProfiles)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if setTargets {
+		n.targets = append([]string(nil), targets...)
+	}
+	if setProfiles {
+		n.targetProfiles = profiles
+	}
+	log.Printf("Nmap: targets reloaded: %v", n.targets)
+	return nil
+}
+
 // isNmapAvailable checks if nmap binary exists
 func (n *NmapAdapter) isNmapAvailable(ctx context.Context) bool {
 	scanner, err := nmap.NewScanner(
@@ -159,12 +243,29 @@ func (n *NmapAdapter) isNmapAvailable(ctx context.Context) bool {
 	return err == nil
 }
 
-// scanTarget performs nmap scan on a single target
-func (n *NmapAdapter) scanTarget(ctx context.Context, target string, fragment *domain.GraphFragment) error {
+// scanTarget performs nmap scan on a single target. With the result cache
+// enabled, a target scanned within the TTL is skipped, and a rescan whose
+// open ports match the cached result adds nothing to the fragment. force
+// bypasses both checks.
+func (n *NmapAdapter) scanTarget(ctx context.Context, target string, fragment *domain.GraphFragment, force bool) error {
+	if !force {
+		if entry, ok := n.cachedResult(target); ok && time.Since(entry.scannedAt) < n.cacheTTL {
+			log.Printf("Nmap: skipping %s, scanned %s ago", target, time.Since(entry.scannedAt).Round(time.Second))
+			return nil
+		}
+	}
+
 	// Build nmap options
-	opts := []nmap.Option{
-		nmap.WithTargets(target),
-		nmap.WithPorts(n.portRange),
+	opts := []nmap.Option{nmap.WithTargets(target)}
+	if len(n.udpPorts) > 0 && isRoot() {
+		// -sU alone drops the TCP scan, so request a SYN scan alongside it
+		opts = append(opts,
+			nmap.WithPorts("T:"+n.portsFor(target)+",U:"+formatPortList(n.udpPorts)),
+			nmap.WithSYNScan(),
+			nmap.WithUDPScan(),
+		)
+	} else {
+		opts = append(opts, nmap.WithPorts(n.portsFor(target)))
 	}
 
 	// Add service detection if enabled
@@ -182,25 +283,106 @@ func (n *NmapAdapter) scanTarget(ctx context.Context, target string, fragment *d
 		opts = append(opts, nmap.WithSkipHostDiscovery())
 	}
 
-	// Create scanner
+	// Run scan
+	log.Printf("Nmap: scanning target %s", target)
+	result, err := n.runner(ctx, opts...)
+	if err != nil {
+		return err
+	}
+
+	if !n.cacheResult(target, result) && !force {
+		log.Printf("Nmap: open ports on %s unchanged since last scan", target)
+		return nil
+	}
+
+	// Process results
+	return n.processResults(result, fragment)
+}
+
+// portsFor returns the port list to scan on target: its profile's ports
+// when it has a known one, otherwise the adapter's port range
+func (n *NmapAdapter) portsFor(target string) string {
+	n.mu.Lock()
+	profile := n.targetProfiles[target]
+	n.mu.Unlock()
+	if ports, ok := n.portProfiles.Resolve(profile); ok {
+		return formatPortList(ports)
+	}
+	if profile != "" {
+		log.Printf("Nmap: unknown port profile %q for %s, using %s", profile, target, n.portRange)
+	}
+	return n.portRange
+}
+
+// runNmap runs a real nmap scan
+func runNmap(ctx context.Context, opts ...nmap.Option) (*nmap.Run, error) {
 	scanner, err := nmap.NewScanner(ctx, opts...)
 	if err != nil {
-		return fmt.Errorf("failed to create scanner: %w", err)
+		return nil, fmt.Errorf("failed to create scanner: %w", err)
 	}
 
-	// Run scan
-	log.Printf("Nmap: scanning target %s", target)
 	result, warnings, err := scanner.Run()
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		return nil, fmt.Errorf("scan failed: %w", err)
 	}
 
 	if warnings != nil && len(*warnings) > 0 {
-		log.Printf("Nmap: warnings for %s: %v", target, *warnings)
+		log.Printf("Nmap: warnings: %v", *warnings)
 	}
+	return result, nil
+}
 
-	// Process results
-	return n.processResults(result, fragment)
+// cachedResult returns the cached result for a target, if caching is enabled
+func (n *NmapAdapter) cachedResult(target string) (nmapCacheEntry, bool) {
+	if n.cacheTTL <= 0 {
+		return nmapCacheEntry{}, false
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	entry, ok := n.cache[target]
+	return entry, ok
+}
+
+// cacheResult stores a scan result and reports whether its open ports
+// differ from the previously cached result. Without caching every result
+// counts as changed.
+func (n *NmapAdapter) cacheResult(target string, result *nmap.Run) bool {
+	if n.cacheTTL <= 0 {
+		return true
+	}
+
+	hash := openPortsHash(result)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	previous, ok := n.cache[target]
+	n.cache[target] = nmapCacheEntry{result: result, portsHash: hash, scannedAt: time.Now()}
+	return !ok || previous.portsHash != hash
+}
+
+// openPortsHash summarizes the open ports of every up host in a result
+func openPortsHash(result *nmap.Run) string {
+	if result == nil {
+		return ""
+	}
+
+	hosts := make([]string, 0, len(result.Hosts))
+	for _, host := range result.Hosts {
+		if host.Status.State != "up" || len(host.Addresses) == 0 {
+			continue
+		}
+		ports := make([]string, 0, len(host.Ports))
+		for _, port := range host.Ports {
+			if port.State.State == "open" {
+				ports = append(ports, fmt.Sprintf("%d/%s", port.ID, port.Protocol))
+			}
+		}
+		sort.Strings(ports)
+		hosts = append(hosts, host.Addresses[0].Addr+"="+strings.Join(ports, ","))
+	}
+	sort.Strings(hosts)
+
+	sum := sha256.Sum256([]byte(strings.Join(hosts, ";")))
+	return hex.EncodeToString(sum[:])
 }
 
 // processResults converts nmap scan results to graph fragment with evidence
@@ -293,9 +475,21 @@ func (n *NmapAdapter) processResults(result *nmap.Run, fragment *domain.GraphFra
 
 // createNodeFromHost creates a node from nmap host results
 func (n *NmapAdapter) createNodeFromHost(host nmap.Host, ip, nodeID string, now time.Time) domain.Node {
+	var mac, vendor string
+	for _, addr := range host.Addresses {
+		if addr.AddrType == "mac" {
+			mac = strings.ToUpper(addr.Addr)
+			vendor = addr.Vendor
+			if vendor == "" {
+				vendor = LookupMACVendor(mac)
+			}
+		}
+	}
+
+	classification := n.classifyNodeType(host.Ports, vendor, now)
 	node := domain.Node{
 		ID:         nodeID,
-		Type:       n.inferNodeType(host.Ports),
+		Type:       classification.Type,
 		Label:      ip,
 		Source:     "nmap",
 		Status:     domain.NodeStatusVerified,
@@ -306,6 +500,7 @@ func (n *NmapAdapter) createNodeFromHost(host nmap.Host, ip, nodeID string, now
 		LastVerified: &now,
 		LastSeen:     &now,
 	}
+	node.SetDiscovered("type_classification", classification)
 
 	// Add hostname from nmap results
 	if len(host.Hostnames) > 0 {
@@ -323,13 +518,9 @@ func (n *NmapAdapter) createNodeFromHost(host nmap.Host, ip, nodeID string, now
 	}
 
 	// Add MAC address if available
-	for _, addr := range host.Addresses {
-		if addr.AddrType == "mac" {
-			node.SetDiscovered("mac_address", strings.ToUpper(addr.Addr))
-			if addr.Vendor != "" {
-				node.SetDiscovered("mac_vendor", addr.Vendor)
-			}
-		}
+	if mac != "" {
+		node.SetDiscovered("mac_address", mac)
+		setMACVendor(&node, vendor)
 	}
 
 	return node
@@ -340,6 +531,25 @@ func (n *NmapAdapter) createEvidenceFromPorts(ports []nmap.Port, now time.Time)
 	var evidence []domain.Evidence
 
 	for _, port := range ports {
+		key := portKey(port)
+
+		// UDP ports that never answered are open|filtered: possibly a
+		// service, possibly a firewall dropping the probe
+		if port.Protocol == "udp" && port.State.State == "open|filtered" {
+			evidence = append(evidence, domain.Evidence{
+				Source:     domain.EvidenceSourcePortScan,
+				Property:   "service:" + key,
+				Value:      port.State.State,
+				Confidence: 0.2,
+				ObservedAt: now,
+				Raw: map[string]any{
+					"port":     port.ID,
+					"protocol": port.Protocol,
+					"state":    port.State.State,
+				},
+			})
+			continue
+		}
 		if port.State.State != "open" {
 			continue
 		}
@@ -347,7 +557,7 @@ func (n *NmapAdapter) createEvidenceFromPorts(ports []nmap.Port, now time.Time)
 		// Evidence from port being open
 		portEvidence := domain.Evidence{
 			Source:     domain.EvidenceSourcePortScan,
-			Property:   fmt.Sprintf("service:%d", port.ID),
+			Property:   "service:" + key,
 			Value:      port.State.State,
 			Confidence: 0.5, // Port open = moderate confidence
 			ObservedAt: now,
@@ -363,7 +573,7 @@ func (n *NmapAdapter) createEvidenceFromPorts(ports []nmap.Port, now time.Time)
 		if port.Service.Name != "" {
 			serviceEvidence := domain.Evidence{
 				Source:     domain.EvidenceSourceBanner,
-				Property:   fmt.Sprintf("service:%d:name", port.ID),
+				Property:   "service:" + key + ":name",
 				Value:      port.Service.Name,
 				Confidence: 0.7, // Service detection = higher confidence
 				ObservedAt: now,
@@ -381,7 +591,7 @@ func (n *NmapAdapter) createEvidenceFromPorts(ports []nmap.Port, now time.Time)
 		if port.Service.Product != "" {
 			versionEvidence := domain.Evidence{
 				Source:     domain.EvidenceSourceBanner,
-				Property:   fmt.Sprintf("service:%d:product", port.ID),
+				Property:   "service:" + key + ":product",
 				Value:      fmt.Sprintf("%s %s", port.Service.Product, port.Service.Version),
 				Confidence: 0.8, // Version info = high confidence
 				ObservedAt: now,
@@ -417,8 +627,9 @@ func (n *NmapAdapter) createPortDetails(ports []nmap.Port) []PortInfo {
 		}
 
 		info := PortInfo{
-			Port:    int(port.ID),
-			Service: serviceName,
+			Port:     int(port.ID),
+			Protocol: port.Protocol,
+			Service:  serviceName,
 		}
 
 		// Build banner from service info
@@ -439,11 +650,23 @@ func (n *NmapAdapter) createPortDetails(ports []nmap.Port) []PortInfo {
 	return details
 }
 
-// getOpenPorts extracts list of open port numbers
+// portKey names a port in evidence properties: the bare number for TCP,
+// as before UDP scanning existed, and "53/udp" for UDP
+func portKey(port nmap.Port) string {
+	if port.Protocol == "udp" {
+		return fmt.Sprintf("%d/udp", port.ID)
+	}
+	return strconv.Itoa(int(port.ID))
+}
+
+// getOpenPorts extracts list of open port numbers; a port open over both
+// TCP and UDP is listed once
 func (n *NmapAdapter) getOpenPorts(ports []nmap.Port) []int {
 	var openPorts []int
+	seen := make(map[int]bool)
 	for _, port := range ports {
-		if port.State.State == "open" {
+		if port.State.State == "open" && !seen[int(port.ID)] {
+			seen[int(port.ID)] = true
 			openPorts = append(openPorts, int(port.ID))
 		}
 	}
@@ -480,56 +703,29 @@ func (n *NmapAdapter) extractOSInfo(os nmap.OS) map[string]any {
 	return info
 }
 
-// inferNodeType guesses node type from open ports
-func (n *NmapAdapter) inferNodeType(ports []nmap.Port) domain.NodeType {
-	portSet := make(map[uint16]bool)
-	for _, p := range ports {
-		if p.State.State == "open" {
-			portSet[p.ID] = true
-		}
-	}
-
-	// Router indicators
-	if portSet[53] && (portSet[80] || portSet[443]) {
-		return domain.NodeTypeRouter
-	}
-
-	// Kubernetes node
-	if portSet[6443] || portSet[10250] {
-		return domain.NodeTypeServer
-	}
-
-	// Windows machine
-	if portSet[3389] || portSet[445] {
-		return domain.NodeTypeServer
-	}
+// classifyNodeType infers node type from weighted open-port evidence and
+// the MAC vendor, if known
+func (n *NmapAdapter) classifyNodeType(ports []nmap.Port, vendor string, now time.Time) domain.TypeClassification {
+	open := n.getOpenPorts(ports)
 
-	// Linux server (SSH + web)
-	if portSet[22] && (portSet[80] || portSet[443]) {
-		return domain.NodeTypeServer
+	weights := n.typeWeights
+	if weights == nil {
+		weights = domain.DefaultPortTypeWeights
 	}
-
-	// Just SSH
-	if portSet[22] {
-		return domain.NodeTypeServer
-	}
-
-	// Web only
-	if portSet[80] || portSet[443] || portSet[8080] {
-		return domain.NodeTypeServer
-	}
-
-	return domain.NodeTypeUnknown
+	return domain.ClassifyNodeTypeWithVendor(open, vendor, weights, now)
 }
 
 // sanitizeIP converts an IP address to a valid node ID
 func sanitizeIP(ip string) string {
-	// Parse IP to validate
+	// Drop any IPv6 zone, then parse to validate and canonicalize
+	if i := strings.IndexByte(ip, '%'); i >= 0 {
+		ip = ip[:i]
+	}
 	parsed := net.ParseIP(ip)
 	if parsed != nil {
 		ip = parsed.String()
 	}
-	return strings.ReplaceAll(ip, ".", "-")
+	return strings.NewReplacer(".", "-", ":", "-").Replace(ip)
 }
 
 // expandTargets expands CIDR notation targets (helper for configuration)
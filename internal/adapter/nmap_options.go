@@ -1,6 +1,10 @@
 package adapter
 
-import "time"
+import (
+	"time"
+
+	"specularium/internal/domain"
+)
 
 // NmapOption is a functional option for configuring NmapAdapter
 type NmapOption func(*NmapAdapter)
@@ -12,6 +16,15 @@ func WithInterval(d time.Duration) NmapOption {
 	}
 }
 
+// WithResultCacheTTL caches each target's last scan for ttl. Targets scanned
+// within the TTL are skipped, and rescans whose open ports are unchanged are
+// not reconciled. Zero disables the cache.
+func WithResultCacheTTL(ttl time.Duration) NmapOption {
+	return func(n *NmapAdapter) {
+		n.cacheTTL = ttl
+	}
+}
+
 // WithTimeout sets the timeout for the entire nmap scan
 func WithTimeout(d time.Duration) NmapOption {
 	return func(n *NmapAdapter) {
@@ -30,6 +43,30 @@ func WithPortRange(ports string) NmapOption {
 	}
 }
 
+// WithPortProfiles scans targets with the ports of their named profile.
+// targetProfiles maps a target to a profile in profiles; other targets, and
+// targets naming an unknown profile, use the port range.
+func WithPortProfiles(profiles PortProfiles, targetProfiles map[string]string) NmapOption {
+	return func(n *NmapAdapter) {
+		n.portProfiles = profiles
+		n.targetProfiles = targetProfiles
+	}
+}
+
+// WithUDPScan also scans the given UDP ports (-sU), e.g. 53, 123 and 161
+// for DNS, NTP and SNMP. UDP scanning needs root, so it is skipped when
+// the process is unprivileged; TCP ports are scanned either way.
+func WithUDPScan(ports []int) NmapOption {
+	return func(n *NmapAdapter) {
+		n.udpPorts = nil
+		for _, port := range ports {
+			if port >= 1 && port <= 65535 {
+				n.udpPorts = append(n.udpPorts, port)
+			}
+		}
+	}
+}
+
 // WithServiceDetection enables or disables service version detection (-sV)
 func WithServiceDetection(enabled bool) NmapOption {
 	return func(n *NmapAdapter) {
@@ -105,3 +142,10 @@ func WithAggressiveScan() NmapOption {
 		n.timeout = 30 * time.Minute
 	}
 }
+
+// WithTypeWeights sets the port-to-node-type evidence weights used for classification
+func WithTypeWeights(weights []domain.PortTypeWeight) NmapOption {
+	return func(n *NmapAdapter) {
+		n.typeWeights = weights
+	}
+}
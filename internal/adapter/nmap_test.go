@@ -443,9 +443,9 @@ func TestNmapAdapter_NodeTypeInference(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			nodeType := adapter.inferNodeType(tt.ports)
-			if nodeType != tt.wantType {
-				t.Errorf("expected type %s, got %s", tt.wantType, nodeType)
+			classification := adapter.classifyNodeType(tt.ports, "", time.Now())
+			if classification.Type != tt.wantType {
+				t.Errorf("expected type %s, got %s", tt.wantType, classification.Type)
 			}
 		})
 	}
@@ -536,6 +536,9 @@ func TestSanitizeIP(t *testing.T) {
 	}{
 		{"IPv4", "192.168.1.1", "192-168-1-1"},
 		{"IPv4 with zeros", "10.0.0.1", "10-0-0-1"},
+		{"IPv6", "2001:db8::1", "2001-db8--1"},
+		{"IPv6 canonicalized", "2001:DB8:0:0::10", "2001-db8--10"},
+		{"IPv6 with zone", "fe80::1%eth0", "fe80--1"},
 		{"malformed IP passthrough", "test-host", "test-host"},
 	}
 
@@ -633,3 +636,122 @@ func TestNmapAdapter_OSDetection(t *testing.T) {
 		t.Errorf("expected family 'Linux', got %v", osInfo["family"])
 	}
 }
+
+// TestNmapAdapter_ResultCache tests that cached results skip rescans and
+// unchanged rescans add nothing to the fragment
+func TestNmapAdapter_ResultCache(t *testing.T) {
+	openPorts := []uint16{22}
+	calls := 0
+	adapter := NewNmapAdapter([]string{"192.168.1.0/24"}, WithResultCacheTTL(time.Hour))
+	adapter.running = true
+	adapter.runner = func(ctx context.Context, opts ...nmap.Option) (*nmap.Run, error) {
+		calls++
+		host := nmap.Host{
+			Addresses: []nmap.Address{{Addr: "192.168.1.10", AddrType: "ipv4"}},
+			Status:    nmap.Status{State: "up"},
+		}
+		for _, port := range openPorts {
+			host.Ports = append(host.Ports, nmap.Port{ID: port, Protocol: "tcp", State: nmap.State{State: "open"}})
+		}
+		return &nmap.Run{Hosts: []nmap.Host{host}}, nil
+	}
+
+	sync := func(ctx context.Context) int {
+		t.Helper()
+		fragment, err := adapter.Sync(ctx)
+		if err != nil {
+			t.Fatalf("Sync() error = %v", err)
+		}
+		return len(fragment.Nodes)
+	}
+
+	if got := sync(context.Background()); calls != 1 || got != 1 {
+		t.Fatalf("first sync: %d scans, %d nodes; want 1 scan, 1 node", calls, got)
+	}
+
+	if got := sync(context.Background()); calls != 1 || got != 0 {
+		t.Errorf("sync within TTL: %d scans, %d nodes; want scan skipped", calls, got)
+	}
+
+	if got := sync(WithForceRescan(context.Background())); calls != 2 || got != 1 {
+		t.Errorf("forced sync: %d scans, %d nodes; want 2 scans, 1 node", calls, got)
+	}
+
+	// Expire the cache: an unchanged rescan adds nothing, a changed one does
+	expire := func() {
+		entry := adapter.cache["192.168.1.0/24"]
+		entry.scannedAt = time.Now().Add(-2 * time.Hour)
+		adapter.cache["192.168.1.0/24"] = entry
+	}
+	expire()
+	if got := sync(context.Background()); calls != 3 || got != 0 {
+		t.Errorf("unchanged rescan: %d scans, %d nodes; want 3 scans, 0 nodes", calls, got)
+	}
+
+	expire()
+	openPorts = []uint16{22, 443}
+	if got := sync(context.Background()); calls != 4 || got != 1 {
+		t.Errorf("changed rescan: %d scans, %d nodes; want 4 scans, 1 node", calls, got)
+	}
+}
+
+// TestNmapAdapter_UDPScan tests that UDP results merge into the TCP node
+// with a protocol marker, and open|filtered ports yield weak evidence
+func TestNmapAdapter_UDPScan(t *testing.T) {
+	defer func(prev func() bool) { isRoot = prev }(isRoot)
+	isRoot = func() bool { return true }
+
+	adapter := NewNmapAdapter([]string{"192.168.1.53"}, WithUDPScan([]int{53, 123, 161}))
+	adapter.running = true
+	adapter.runner = func(ctx context.Context, opts ...nmap.Option) (*nmap.Run, error) {
+		return &nmap.Run{Hosts: []nmap.Host{{
+			Addresses: []nmap.Address{{Addr: "192.168.1.53", AddrType: "ipv4"}},
+			Status:    nmap.Status{State: "up"},
+			Ports: []nmap.Port{
+				{ID: 53, Protocol: "tcp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "domain"}},
+				{ID: 53, Protocol: "udp", State: nmap.State{State: "open"}, Service: nmap.Service{Name: "domain"}},
+				{ID: 123, Protocol: "udp", State: nmap.State{State: "open|filtered"}},
+				{ID: 161, Protocol: "udp", State: nmap.State{State: "closed"}},
+			},
+		}}}, nil
+	}
+
+	fragment, err := adapter.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(fragment.Nodes) != 1 {
+		t.Fatalf("expected 1 node, got %d", len(fragment.Nodes))
+	}
+	node := fragment.Nodes[0]
+
+	openPorts, _ := node.GetDiscovered("open_ports")
+	if ports, _ := openPorts.([]int); len(ports) != 1 || ports[0] != 53 {
+		t.Errorf("open_ports = %v, want [53]", openPorts)
+	}
+
+	services, _ := node.GetDiscovered("services")
+	details, _ := services.([]PortInfo)
+	if len(details) != 2 || details[0].Protocol != "tcp" || details[1].Protocol != "udp" {
+		t.Errorf("services = %+v, want tcp and udp entries for port 53", details)
+	}
+
+	raw, _ := node.GetDiscovered("nmap_evidence")
+	evidence, _ := raw.([]domain.Evidence)
+	byProperty := make(map[string]domain.Evidence)
+	for _, ev := range evidence {
+		byProperty[ev.Property] = ev
+	}
+	if ev, ok := byProperty["service:53"]; !ok || ev.Confidence != 0.5 {
+		t.Errorf("service:53 evidence = %+v, want open TCP evidence", ev)
+	}
+	if ev, ok := byProperty["service:53/udp"]; !ok || ev.Confidence != 0.5 {
+		t.Errorf("service:53/udp evidence = %+v, want open UDP evidence", ev)
+	}
+	if ev, ok := byProperty["service:123/udp"]; !ok || ev.Value != "open|filtered" || ev.Confidence >= 0.5 {
+		t.Errorf("service:123/udp evidence = %+v, want low-confidence open|filtered evidence", ev)
+	}
+	if _, ok := byProperty["service:161/udp"]; ok {
+		t.Error("closed UDP port should not produce evidence")
+	}
+}
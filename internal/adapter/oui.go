@@ -0,0 +1,56 @@
+package adapter
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"sync"
+
+	"specularium/internal/domain"
+)
+
+//go:embed oui.txt
+var ouiTable string
+
+var (
+	ouiOnce    sync.Once
+	ouiVendors map[string]string
+)
+
+// loadOUITable parses the embedded "PREFIX<tab>Vendor" table
+func loadOUITable() {
+	ouiVendors = make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(ouiTable))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		prefix, vendor, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		ouiVendors[strings.ToLower(prefix)] = strings.TrimSpace(vendor)
+	}
+}
+
+// LookupMACVendor returns the vendor for a MAC address's OUI prefix, or ""
+// if the address is malformed or the prefix is not in the bundled table
+func LookupMACVendor(mac string) string {
+	normalized := domain.NormalizeMAC(mac)
+	if normalized == "" {
+		return ""
+	}
+	ouiOnce.Do(loadOUITable)
+	return ouiVendors[normalized[:8]]
+}
+
+// setMACVendor records a node's MAC vendor as reported, plus the normalized
+// vendor used for type hints
+func setMACVendor(node *domain.Node, vendor string) {
+	if vendor == "" {
+		return
+	}
+	node.SetDiscovered("mac_vendor", vendor)
+	node.SetDiscovered("vendor", domain.NormalizeVendor(vendor))
+}
@@ -0,0 +1,56 @@
+package adapter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PortProfiles maps profile names to the TCP ports scanned for them, so
+// environments such as IoT or storage networks can be scanned for the
+// services they actually run, e.g. "iot": [80, 554, 1883]
+type PortProfiles map[string][]int
+
+// Validate checks that every profile lists at least one port in 1-65535
+func (p PortProfiles) Validate() error {
+	for name, ports := range p {
+		if len(ports) == 0 {
+			return fmt.Errorf("port profile %s has no ports", name)
+		}
+		for _, port := range ports {
+			if port < 1 || port > 65535 {
+				return fmt.Errorf("port profile %s: invalid port %d", name, port)
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve returns the sorted, de-duplicated ports of the named profile.
+// ok is false when name is empty or no such profile exists.
+func (p PortProfiles) Resolve(name string) (ports []int, ok bool) {
+	listed, ok := p[name]
+	if name == "" || !ok {
+		return nil, false
+	}
+
+	seen := make(map[int]bool, len(listed))
+	for _, port := range listed {
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return ports, true
+}
+
+// formatPortList renders ports in nmap's comma-separated form
+func formatPortList(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ",")
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"specularium/internal/domain"
+	"specularium/internal/metrics"
 )
 
 // ReconcileFunc is called when an adapter produces a fragment to be merged
@@ -23,9 +27,33 @@ type Registry struct {
 	configs         map[string]AdapterConfig
 	reconcile       ReconcileFunc
 	discoveryEvent  DiscoveryEventFunc
+	metrics         *SyncMetrics
+	limiter         *OperationLimiter
+	started         bool
 	ctx             context.Context
 	cancel          context.CancelFunc
 	wg              sync.WaitGroup
+	loops           map[string]*pollLoop
+	reloadMu        sync.Mutex
+
+	// Run state per adapter, under its own lock so syncs can record
+	// results while r.mu is held
+	statusMu sync.Mutex
+	status   map[string]*adapterStatus
+}
+
+// adapterStatus is the run state of one adapter
+type adapterStatus struct {
+	running   bool
+	lastRun   time.Time
+	lastError string
+}
+
+// pollLoop is the running polling goroutine of one adapter
+type pollLoop struct {
+	cancel   context.CancelFunc
+	done     chan struct{}
+	interval atomic.Int64 // time.Duration; a change applies on the next tick
 }
 
 // NewRegistry creates a new adapter registry
@@ -33,6 +61,8 @@ func NewRegistry(reconcile ReconcileFunc) *Registry {
 	return &Registry{
 		adapters:  make(map[string]Adapter),
 		configs:   make(map[string]AdapterConfig),
+		loops:     make(map[string]*pollLoop),
+		status:    make(map[string]*adapterStatus),
 		reconcile: reconcile,
 	}
 }
@@ -44,6 +74,22 @@ func (r *Registry) SetDiscoveryEventHandler(handler DiscoveryEventFunc) {
 	r.discoveryEvent = handler
 }
 
+// SetMetrics records sync counts and durations for every adapter.
+// Must be called before Start.
+func (r *Registry) SetMetrics(m *SyncMetrics) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = m
+}
+
+// SetLimiter bounds how many syncs run at once, shared with other
+// discovery operations using the same limiter. Must be called before Start.
+func (r *Registry) SetLimiter(l *OperationLimiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limiter = l
+}
+
 // PublishDiscoveryEvent implements EventPublisher interface
 func (r *Registry) PublishDiscoveryEvent(eventType string, payload interface{}) {
 	r.mu.RLock()
@@ -95,8 +141,10 @@ func (r *Registry) Start(ctx context.Context) error {
 		// Initialize adapter
 		if err := adapter.Start(r.ctx); err != nil {
 			log.Printf("Failed to start adapter %s: %v", name, err)
+			r.setRunning(name, false, err)
 			continue
 		}
+		r.setRunning(name, true, nil)
 
 		// Start polling loop for polling adapters
 		if adapter.Type() == AdapterTypePolling {
@@ -104,26 +152,37 @@ func (r *Registry) Start(ctx context.Context) error {
 		}
 	}
 
+	r.started = true
 	return nil
 }
 
+// Started reports whether Start has run and Stop has not since been called
+func (r *Registry) Started() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.started
+}
+
 // Stop gracefully shuts down all adapters
 func (r *Registry) Stop() error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	r.started = false
 	if r.cancel != nil {
 		r.cancel()
 	}
 
 	// Wait for all polling loops to finish
 	r.wg.Wait()
+	clear(r.loops)
 
 	// Stop all adapters
 	for name, adapter := range r.adapters {
 		if err := adapter.Stop(); err != nil {
 			log.Printf("Error stopping adapter %s: %v", name, err)
 		}
+		r.setRunning(name, false, nil)
 	}
 
 	return nil
@@ -147,6 +206,35 @@ func (r *Registry) TriggerSync(ctx context.Context, name string) error {
 	return r.runSync(ctx, name, adapter)
 }
 
+// NodeSyncer is implemented by adapters that can sync a chosen set of nodes
+type NodeSyncer interface {
+	SyncNodes(ctx context.Context, ids []string) (*domain.GraphFragment, error)
+}
+
+// TriggerNodeSync syncs only the given nodes through the named adapter and
+// reconciles the result
+func (r *Registry) TriggerNodeSync(ctx context.Context, name string, ids []string) error {
+	r.mu.RLock()
+	adapter, exists := r.adapters[name]
+	config := r.configs[name]
+	r.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("adapter %s not found", name)
+	}
+	if !config.Enabled {
+		return fmt.Errorf("adapter %s is disabled", name)
+	}
+	syncer, ok := adapter.(NodeSyncer)
+	if !ok {
+		return fmt.Errorf("adapter %s cannot sync selected nodes", name)
+	}
+
+	return r.sync(ctx, name, func(ctx context.Context) (*domain.GraphFragment, error) {
+		return syncer.SyncNodes(ctx, ids)
+	})
+}
+
 // TriggerSyncAll manually triggers sync for all enabled adapters
 func (r *Registry) TriggerSyncAll(ctx context.Context) error {
 	r.mu.RLock()
@@ -170,48 +258,103 @@ func (r *Registry) TriggerSyncAll(ctx context.Context) error {
 	return nil
 }
 
-// ListAdapters returns information about registered adapters
+// ListAdapters returns information about registered adapters, sorted by name
 func (r *Registry) ListAdapters() []AdapterInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
 
-	var infos []AdapterInfo
+	infos := make([]AdapterInfo, 0, len(r.adapters))
 	for name, adapter := range r.adapters {
 		config := r.configs[name]
-		infos = append(infos, AdapterInfo{
+		info := AdapterInfo{
 			Name:         name,
 			Type:         adapter.Type(),
 			Priority:     config.Priority,
 			Enabled:      config.Enabled,
 			PollInterval: config.PollInterval,
-		})
+		}
+		if status, ok := r.status[name]; ok {
+			info.Running = status.running
+			info.LastError = status.lastError
+			if !status.lastRun.IsZero() {
+				lastRun := status.lastRun
+				info.LastRun = &lastRun
+			}
+		}
+		infos = append(infos, info)
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
 	return infos
 }
 
-// AdapterInfo provides read-only information about an adapter
+// AdapterInfo provides read-only information about an adapter. LastError
+// holds the error of the last failed start or sync, e.g. "nmap binary not
+// found in PATH", and is cleared by the next success.
 type AdapterInfo struct {
 	Name         string      `json:"name"`
 	Type         AdapterType `json:"type"`
 	Priority     int         `json:"priority"`
 	Enabled      bool        `json:"enabled"`
 	PollInterval string      `json:"poll_interval,omitempty"`
+	Running      bool        `json:"running"`
+	LastRun      *time.Time  `json:"last_run,omitempty"`
+	LastError    string      `json:"last_error,omitempty"`
 }
 
-// startPollingLoop starts a goroutine that polls the adapter on schedule
-func (r *Registry) startPollingLoop(name string, adapter Adapter, config AdapterConfig) {
-	interval, err := time.ParseDuration(config.PollInterval)
+// adapterState returns the status entry for an adapter, creating it.
+// Must be called with r.statusMu held.
+func (r *Registry) adapterState(name string) *adapterStatus {
+	status, ok := r.status[name]
+	if !ok {
+		status = &adapterStatus{}
+		r.status[name] = status
+	}
+	return status
+}
+
+// setRunning records an adapter starting or stopping; err is a failed start
+func (r *Registry) setRunning(name string, running bool, err error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	status := r.adapterState(name)
+	status.running = running
+	if err != nil {
+		status.lastError = err.Error()
+	} else if running {
+		status.lastError = ""
+	}
+}
+
+// recordSync records the time and outcome of a sync
+func (r *Registry) recordSync(name string, start time.Time, err error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+	status := r.adapterState(name)
+	status.lastRun = start
+	status.lastError = ""
 	if err != nil {
-		log.Printf("Invalid poll interval for %s: %v, using 1m default", name, err)
-		interval = time.Minute
+		status.lastError = err.Error()
 	}
+}
+
+// startPollingLoop starts a goroutine that polls the adapter on schedule.
+// Must be called with r.mu held.
+func (r *Registry) startPollingLoop(name string, adapter Adapter, config AdapterConfig) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	loop := &pollLoop{cancel: cancel, done: make(chan struct{})}
+	interval := parsePollInterval(name, config.PollInterval)
+	loop.interval.Store(int64(interval))
+	r.loops[name] = loop
 
 	r.wg.Add(1)
 	go func() {
 		defer r.wg.Done()
+		defer close(loop.done)
 
 		// Run initial sync
-		if err := r.runSync(r.ctx, name, adapter); err != nil {
+		if err := r.runSync(ctx, name, adapter); err != nil {
 			log.Printf("Initial sync failed for %s: %v", name, err)
 		}
 
@@ -220,13 +363,18 @@ func (r *Registry) startPollingLoop(name string, adapter Adapter, config Adapter
 
 		for {
 			select {
-			case <-r.ctx.Done():
+			case <-ctx.Done():
 				log.Printf("Stopping polling loop for %s", name)
 				return
 			case <-ticker.C:
-				if err := r.runSync(r.ctx, name, adapter); err != nil {
+				if err := r.runSync(ctx, name, adapter); err != nil {
 					log.Printf("Sync failed for %s: %v", name, err)
 				}
+				if next := time.Duration(loop.interval.Load()); next != interval {
+					interval = next
+					ticker.Reset(interval)
+					log.Printf("Polling interval for %s is now %s", name, interval)
+				}
 			}
 		}
 	}()
@@ -234,11 +382,209 @@ func (r *Registry) startPollingLoop(name string, adapter Adapter, config Adapter
 	log.Printf("Started polling loop for %s (interval=%s)", name, interval)
 }
 
+// parsePollInterval parses an adapter's poll interval, falling back to 1m
+func parsePollInterval(name, s string) time.Duration {
+	interval, err := time.ParseDuration(s)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid poll interval for %s: %q, using 1m default", name, s)
+		return time.Minute
+	}
+	return interval
+}
+
+// Reloadable is implemented by adapters whose settings can be changed
+// while they run
+type Reloadable interface {
+	Reload(settings map[string]any) error
+}
+
+// ReloadResult lists, by adapter name, what a Reload changed
+type ReloadResult struct {
+	Started      []string `json:"started"`
+	Stopped      []string `json:"stopped"`
+	Reconfigured []string `json:"reconfigured"`
+	Unchanged    []string `json:"unchanged"`
+	// RestartRequired names configured adapters that were never
+	// registered, so only a server restart can bring them up
+	RestartRequired []string `json:"restart_required,omitempty"`
+}
+
+// Configs returns a copy of every registered adapter's configuration
+func (r *Registry) Configs() map[string]AdapterConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	configs := make(map[string]AdapterConfig, len(r.configs))
+	for name, config := range r.configs {
+		configs[name] = config
+	}
+	return configs
+}
+
+// Reload applies new adapter configuration without restarting the
+// registry. Adapters that became disabled are stopped, newly enabled ones
+// are started, and running adapters get new settings through Reloadable and
+// new poll intervals on their next tick. Adapters whose configuration is
+// unchanged keep running untouched; registered adapters missing from
+// configs are disabled.
+func (r *Registry) Reload(configs map[string]AdapterConfig) (*ReloadResult, error) {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	type change struct {
+		name     string
+		adapter  Adapter
+		old, new AdapterConfig
+		loop     *pollLoop
+	}
+
+	result := &ReloadResult{}
+	var changes []change
+
+	r.mu.Lock()
+	running := r.started
+	for name, config := range configs {
+		if _, ok := r.adapters[name]; !ok && config.Enabled {
+			result.RestartRequired = append(result.RestartRequired, name)
+		}
+	}
+	for name, adapter := range r.adapters {
+		next, ok := configs[name]
+		if !ok {
+			next = r.configs[name]
+			next.Enabled = false
+		}
+		changes = append(changes, change{
+			name:    name,
+			adapter: adapter,
+			old:     r.configs[name],
+			new:     next,
+			loop:    r.loops[name],
+		})
+		r.configs[name] = next
+		if running && !next.Enabled {
+			delete(r.loops, name)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Strings(result.RestartRequired)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].name < changes[j].name })
+
+	var errs []error
+	for _, c := range changes {
+		switch {
+		case c.old.Enabled && !c.new.Enabled:
+			result.Stopped = append(result.Stopped, c.name)
+			if !running {
+				continue
+			}
+			if c.loop != nil {
+				c.loop.cancel()
+				<-c.loop.done
+			}
+			if err := c.adapter.Stop(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: stop: %w", c.name, err))
+			}
+			r.setRunning(c.name, false, nil)
+
+		case !c.old.Enabled && c.new.Enabled:
+			result.Started = append(result.Started, c.name)
+			if !running {
+				continue
+			}
+			if err := r.reloadSettings(c.adapter, c.old, c.new); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+				continue
+			}
+			if err := c.adapter.Start(r.ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: start: %w", c.name, err))
+				r.setRunning(c.name, false, err)
+				continue
+			}
+			r.setRunning(c.name, true, nil)
+			if c.adapter.Type() == AdapterTypePolling {
+				r.mu.Lock()
+				r.startPollingLoop(c.name, c.adapter, c.new)
+				r.mu.Unlock()
+			}
+
+		case !c.new.Enabled:
+			result.Unchanged = append(result.Unchanged, c.name)
+
+		default:
+			settingsChanged := !reflect.DeepEqual(c.old.Settings, c.new.Settings)
+			intervalChanged := c.old.PollInterval != c.new.PollInterval
+			if !settingsChanged && !intervalChanged {
+				result.Unchanged = append(result.Unchanged, c.name)
+				continue
+			}
+			result.Reconfigured = append(result.Reconfigured, c.name)
+			if settingsChanged {
+				if err := r.reloadSettings(c.adapter, c.old, c.new); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+				}
+			}
+			if intervalChanged && running && c.loop != nil {
+				c.loop.interval.Store(int64(parsePollInterval(c.name, c.new.PollInterval)))
+			}
+		}
+	}
+
+	for _, name := range result.Started {
+		log.Printf("Reload: started adapter %s", name)
+	}
+	for _, name := range result.Stopped {
+		log.Printf("Reload: stopped adapter %s", name)
+	}
+	for _, name := range result.Reconfigured {
+		log.Printf("Reload: reconfigured adapter %s", name)
+	}
+
+	if len(errs) > 0 {
+		return result, fmt.Errorf("reload errors: %v", errs)
+	}
+	return result, nil
+}
+
+// reloadSettings hands changed settings to an adapter that supports it
+func (r *Registry) reloadSettings(adapter Adapter, old, next AdapterConfig) error {
+	if reflect.DeepEqual(old.Settings, next.Settings) {
+		return nil
+	}
+	reloadable, ok := adapter.(Reloadable)
+	if !ok {
+		log.Printf("Adapter %s does not support reloading settings; restart to apply them", adapter.Name())
+		return nil
+	}
+	if err := reloadable.Reload(next.Settings); err != nil {
+		return fmt.Errorf("reload settings: %w", err)
+	}
+	return nil
+}
+
 // runSync executes a sync operation and reconciles the result
 func (r *Registry) runSync(ctx context.Context, name string, adapter Adapter) error {
+	return r.sync(ctx, name, adapter.Sync)
+}
+
+// sync runs fn in a discovery slot and reconciles the fragment it returns
+func (r *Registry) sync(ctx context.Context, name string, fn func(context.Context) (*domain.GraphFragment, error)) (err error) {
+	release, err := r.limiter.Acquire(ctx, name, "")
+	if err != nil {
+		return fmt.Errorf("waiting for discovery slot: %w", err)
+	}
+	defer release()
+
 	log.Printf("Running sync for adapter: %s", name)
 
-	fragment, err := adapter.Sync(ctx)
+	start := time.Now()
+	defer func() {
+		r.metrics.Record(name, start, err)
+		r.recordSync(name, start, err)
+	}()
+
+	fragment, err := fn(ctx)
 	if err != nil {
 		return fmt.Errorf("sync failed: %w", err)
 	}
@@ -258,3 +604,38 @@ func (r *Registry) runSync(ctx context.Context, name string, adapter Adapter) er
 
 	return nil
 }
+
+// SyncMetrics records adapter sync outcomes in a metrics registry
+type SyncMetrics struct {
+	syncs       *metrics.Counter
+	duration    *metrics.Summary
+	lastSuccess *metrics.Gauge
+}
+
+// NewSyncMetrics registers the sync metric families
+func NewSyncMetrics(reg *metrics.Registry) *SyncMetrics {
+	return &SyncMetrics{
+		syncs: reg.NewCounter("specularium_adapter_syncs_total",
+			"Adapter sync runs by outcome.", "adapter", "result"),
+		duration: reg.NewSummary("specularium_scan_duration_seconds",
+			"Time spent in discovery syncs and scans.", "adapter"),
+		lastSuccess: reg.NewGauge("specularium_adapter_last_success_timestamp_seconds",
+			"Unix time of the last successful sync, for stall alerts.", "adapter"),
+	}
+}
+
+// Record notes a sync for adapter that began at start and ended with err.
+// It is a no-op on a nil receiver.
+func (m *SyncMetrics) Record(adapter string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	now := time.Now()
+	m.duration.Observe(now.Sub(start).Seconds(), adapter)
+	if err != nil {
+		m.syncs.Inc(adapter, "error")
+		return
+	}
+	m.syncs.Inc(adapter, "success")
+	m.lastSuccess.Set(float64(now.Unix()), adapter)
+}
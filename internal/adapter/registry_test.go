@@ -0,0 +1,160 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	nmap "github.com/Ullaakut/nmap/v3"
+	"specularium/internal/domain"
+)
+
+// countingAdapter is a polling adapter that counts Start and Stop calls
+type countingAdapter struct {
+	name          string
+	starts, stops atomic.Int32
+}
+
+func (a *countingAdapter) Name() string                    { return a.name }
+func (a *countingAdapter) Type() AdapterType               { return AdapterTypePolling }
+func (a *countingAdapter) Priority() int                   { return 10 }
+func (a *countingAdapter) Start(ctx context.Context) error { a.starts.Add(1); return nil }
+func (a *countingAdapter) Stop() error                     { a.stops.Add(1); return nil }
+func (a *countingAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	return nil, nil
+}
+
+func TestRegistryReload(t *testing.T) {
+	registry := NewRegistry(func(ctx context.Context, source string, fragment *domain.GraphFragment) error {
+		return nil
+	})
+
+	nmapAdapter := NewNmapAdapter([]string{"192.168.1.0/24"})
+	nmapAdapter.runner = func(ctx context.Context, opts ...nmap.Option) (*nmap.Run, error) {
+		return &nmap.Run{}, nil
+	}
+	other := &countingAdapter{name: "other"}
+
+	nmapConfig := AdapterConfig{Enabled: true, Priority: 80, PollInterval: "1h",
+		Settings: map[string]any{"targets": []string{"192.168.1.0/24"}}}
+	otherConfig := AdapterConfig{Enabled: true, Priority: 10, PollInterval: "1h"}
+	if err := registry.Register(nmapAdapter, nmapConfig); err != nil {
+		t.Fatalf("Register nmap: %v", err)
+	}
+	if err := registry.Register(other, otherConfig); err != nil {
+		t.Fatalf("Register other: %v", err)
+	}
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer registry.Stop()
+
+	t.Run("swap nmap targets", func(t *testing.T) {
+		swapped := nmapConfig
+		swapped.Settings = map[string]any{"targets": []string{"10.0.0.0/24", "10.0.1.5"}}
+
+		result, err := registry.Reload(map[string]AdapterConfig{"nmap": swapped, "other": otherConfig})
+		if err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if got, want := nmapAdapter.Targets(), []string{"10.0.0.0/24", "10.0.1.5"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("nmap targets = %v, want %v", got, want)
+		}
+		if !reflect.DeepEqual(result.Reconfigured, []string{"nmap"}) || !reflect.DeepEqual(result.Unchanged, []string{"other"}) {
+			t.Errorf("result = %+v, want nmap reconfigured and other unchanged", result)
+		}
+		if other.starts.Load() != 1 || other.stops.Load() != 0 {
+			t.Errorf("other adapter starts=%d stops=%d, want 1 and 0", other.starts.Load(), other.stops.Load())
+		}
+	})
+
+	t.Run("disable and unknown adapters", func(t *testing.T) {
+		disabled := otherConfig
+		disabled.Enabled = false
+
+		result, err := registry.Reload(map[string]AdapterConfig{
+			"nmap":  registry.Configs()["nmap"],
+			"other": disabled,
+			"snmp":  {Enabled: true},
+		})
+		if err != nil {
+			t.Fatalf("Reload: %v", err)
+		}
+		if !reflect.DeepEqual(result.Stopped, []string{"other"}) || other.stops.Load() != 1 {
+			t.Errorf("result = %+v, stops = %d, want other stopped once", result, other.stops.Load())
+		}
+		if !reflect.DeepEqual(result.RestartRequired, []string{"snmp"}) {
+			t.Errorf("restart required = %v, want [snmp]", result.RestartRequired)
+		}
+	})
+}
+
+// failingAdapter is a one-shot adapter whose start or sync fails
+type failingAdapter struct {
+	name              string
+	startErr, syncErr error
+}
+
+func (a *failingAdapter) Name() string                    { return a.name }
+func (a *failingAdapter) Type() AdapterType               { return AdapterTypeOneShot }
+func (a *failingAdapter) Priority() int                   { return 10 }
+func (a *failingAdapter) Start(ctx context.Context) error { return a.startErr }
+func (a *failingAdapter) Stop() error                     { return nil }
+func (a *failingAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	return nil, a.syncErr
+}
+
+func TestRegistryListAdaptersStatus(t *testing.T) {
+	registry := NewRegistry(func(ctx context.Context, source string, fragment *domain.GraphFragment) error {
+		return nil
+	})
+
+	noNmap := &failingAdapter{name: "nmap", startErr: errors.New("nmap binary not found in PATH")}
+	flaky := &failingAdapter{name: "snmp", syncErr: errors.New("community rejected")}
+	config := AdapterConfig{Enabled: true, Priority: 10}
+	for _, a := range []Adapter{noNmap, flaky} {
+		if err := registry.Register(a, config); err != nil {
+			t.Fatalf("Register %s: %v", a.Name(), err)
+		}
+	}
+	if err := registry.Register(&failingAdapter{name: "off"}, AdapterConfig{}); err != nil {
+		t.Fatalf("Register off: %v", err)
+	}
+	if err := registry.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer registry.Stop()
+
+	if err := registry.TriggerSync(context.Background(), "snmp"); err == nil {
+		t.Fatal("TriggerSync(snmp) should fail")
+	}
+
+	infos := make(map[string]AdapterInfo)
+	for _, info := range registry.ListAdapters() {
+		infos[info.Name] = info
+	}
+
+	if got := infos["nmap"]; got.Running || got.LastError != "nmap binary not found in PATH" || got.LastRun != nil {
+		t.Errorf("nmap = %+v, want not running with its start error", got)
+	}
+	if got := infos["snmp"]; !got.Running || !strings.Contains(got.LastError, "community rejected") || got.LastRun == nil {
+		t.Errorf("snmp = %+v, want running with last sync error and time", got)
+	}
+	if got := infos["off"]; got.Enabled || got.Running || got.LastError != "" {
+		t.Errorf("off = %+v, want disabled and idle", got)
+	}
+
+	// A successful sync clears the error
+	flaky.syncErr = nil
+	if err := registry.TriggerSync(context.Background(), "snmp"); err != nil {
+		t.Fatalf("TriggerSync(snmp): %v", err)
+	}
+	for _, info := range registry.ListAdapters() {
+		if info.Name == "snmp" && info.LastError != "" {
+			t.Errorf("snmp last_error = %q after a successful sync, want empty", info.LastError)
+		}
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/binary"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +25,10 @@ type ScannerConfig struct {
 	Timeout time.Duration
 	// MaxConcurrent limits parallel probe operations
 	MaxConcurrent int
+	// MaxHosts caps how many addresses a single scan may expand to.
+	// Larger ranges, including most IPv6 prefixes, are rejected (0 = 1024,
+	// at most MaxScanHostsCeiling).
+	MaxHosts int
 	// BannerTimeout for reading service banners
 	BannerTimeout time.Duration
 	// DNSServer is an optional DNS server to use for PTR lookups
@@ -31,6 +36,11 @@ type ScannerConfig struct {
 	DNSServer string
 	// CapabilityManager provides access to secrets for enhanced discovery
 	Capabilities *CapabilityManager
+	// TypeWeights drive evidence-weighted node type inference (nil = domain defaults)
+	TypeWeights []domain.PortTypeWeight
+	// PortProfiles are named port lists a scan can use in place of
+	// DiscoveryPorts and ScanPorts
+	PortProfiles PortProfiles
 }
 
 // DefaultScannerConfig returns sensible defaults for homelab scanning
@@ -46,10 +56,27 @@ func DefaultScannerConfig() ScannerConfig {
 		},
 		Timeout:       1 * time.Second,
 		MaxConcurrent: 200,
+		MaxHosts:      defaultMaxScanHosts,
 		BannerTimeout: 1 * time.Second,
 	}
 }
 
+// defaultMaxScanHosts is the scan size safety limit when none is configured
+const defaultMaxScanHosts = 1024
+
+// MaxScanHostsCeiling is the absolute scan size limit (a /16). Neither
+// the configured limit nor a per-scan override can exceed it.
+const MaxScanHostsCeiling = 65536
+
+// ScanOptions adjust a single subnet scan
+type ScanOptions struct {
+	// Profile names a port profile (see EffectivePorts)
+	Profile string
+	// MaxHosts overrides ScannerConfig.MaxHosts for this scan, up to
+	// MaxScanHostsCeiling (0 = configured limit)
+	MaxHosts int
+}
+
 // DiscoveredHost represents a host found during scanning
 type DiscoveredHost struct {
 	IP          string
@@ -63,6 +90,7 @@ type DiscoveredHost struct {
 type ScannerAdapter struct {
 	config    ScannerConfig
 	publisher EventPublisher
+	logger    *slog.Logger
 	mu        sync.Mutex
 	scanning  bool
 }
@@ -71,9 +99,15 @@ type ScannerAdapter struct {
 func NewScannerAdapter(config ScannerConfig) *ScannerAdapter {
 	return &ScannerAdapter{
 		config: config,
+		logger: slog.Default(),
 	}
 }
 
+// SetLogger sets the logger used for scan activity
+func (s *ScannerAdapter) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
 // SetEventPublisher sets the event publisher for progress updates
 func (s *ScannerAdapter) SetEventPublisher(pub EventPublisher) {
 	s.publisher = pub
@@ -103,14 +137,15 @@ func (s *ScannerAdapter) Priority() int {
 
 // Start initializes the adapter
 func (s *ScannerAdapter) Start(ctx context.Context) error {
-	log.Printf("Scanner adapter started (discovery_ports=%v, max_concurrent=%d)",
-		s.config.DiscoveryPorts, s.config.MaxConcurrent)
+	s.logger.Info("scanner adapter started",
+		"discovery_ports", s.config.DiscoveryPorts,
+		"max_concurrent", s.config.MaxConcurrent)
 	return nil
 }
 
 // Stop shuts down the adapter
 func (s *ScannerAdapter) Stop() error {
-	log.Printf("Scanner adapter stopped")
+	s.logger.Info("scanner adapter stopped")
 	return nil
 }
 
@@ -119,8 +154,72 @@ func (s *ScannerAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error
 	return nil, nil
 }
 
-// ScanSubnet scans a CIDR range and returns discovered hosts as a graph fragment
+// EffectivePorts returns the ports used for host discovery and service
+// detection with the named profile. A profile's ports serve for both, so
+// hosts exposing only those services are still found. An empty or unknown
+// profile falls back to DiscoveryPorts and ScanPorts.
+func (s *ScannerAdapter) EffectivePorts(profile string) (discovery, scan []int) {
+	if ports, ok := s.config.PortProfiles.Resolve(profile); ok {
+		return ports, ports
+	}
+	if profile != "" {
+		s.logger.Warn("unknown port profile, using default ports", "profile", profile)
+	}
+	return s.config.DiscoveryPorts, s.config.ScanPorts
+}
+
+// ScanSubnet scans a CIDR range with the default ports and returns
+// discovered hosts as a graph fragment
 func (s *ScannerAdapter) ScanSubnet(ctx context.Context, cidr string) (*domain.GraphFragment, error) {
+	return s.ScanSubnetWithOptions(ctx, cidr, ScanOptions{})
+}
+
+// ScanLimit returns the host limit for a scan with the given override:
+// the override when set, otherwise the configured limit. An override above
+// MaxScanHostsCeiling is an error; a configured limit above it is clamped.
+func (s *ScannerAdapter) ScanLimit(override int) (int, error) {
+	if override < 0 {
+		return 0, fmt.Errorf("invalid max_hosts %d", override)
+	}
+	if override > MaxScanHostsCeiling {
+		return 0, fmt.Errorf("invalid max_hosts %d: exceeds the absolute ceiling of %d addresses", override, MaxScanHostsCeiling)
+	}
+	if override > 0 {
+		return override, nil
+	}
+
+	limit := s.config.MaxHosts
+	if limit <= 0 {
+		limit = defaultMaxScanHosts
+	}
+	return min(limit, MaxScanHostsCeiling), nil
+}
+
+// CheckScanRange reports whether cidr is a valid target within the limit
+// a scan with the given max_hosts override would use. Hostnames are
+// resolved, so a name that does not resolve is rejected up front.
+func (s *ScannerAdapter) CheckScanRange(cidr string, maxHosts int) error {
+	limit, err := s.ScanLimit(maxHosts)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout*2)
+	defer cancel()
+	if _, _, err := resolveTarget(ctx, cidr, limit); err != nil {
+		return fmt.Errorf("invalid scan target: %w", err)
+	}
+	return nil
+}
+
+// ScanSubnetWithOptions scans a CIDR range, address or hostname using the
+// ports of the chosen port profile and the chosen host limit. The nodes
+// found for a hostname carry it as their hostname property.
+func (s *ScannerAdapter) ScanSubnetWithOptions(ctx context.Context, cidr string, opts ScanOptions) (*domain.GraphFragment, error) {
+	limit, err := s.ScanLimit(opts.MaxHosts)
+	if err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	if s.scanning {
 		s.mu.Unlock()
@@ -135,13 +234,16 @@ func (s *ScannerAdapter) ScanSubnet(ctx context.Context, cidr string) (*domain.G
 		s.mu.Unlock()
 	}()
 
-	// Parse CIDR
-	ips, err := expandCIDR(cidr)
+	ips, hostname, err := resolveTarget(ctx, cidr, limit)
 	if err != nil {
-		return nil, fmt.Errorf("invalid CIDR: %w", err)
+		return nil, fmt.Errorf("invalid scan target: %w", err)
 	}
 
-	log.Printf("Starting subnet scan: %s (%d IPs), publisher=%v", cidr, len(ips), s.publisher != nil)
+	discoveryPorts, scanPorts := s.EffectivePorts(opts.Profile)
+
+	start := time.Now()
+	logger := s.logger.With("cidr", cidr)
+	logger.Info("starting subnet scan", "ips", len(ips), "profile", opts.Profile, "max_hosts", limit)
 
 	s.publishProgress("discovery-started", map[string]interface{}{
 		"total":   len(ips),
@@ -150,12 +252,18 @@ func (s *ScannerAdapter) ScanSubnet(ctx context.Context, cidr string) (*domain.G
 	})
 
 	// Phase 1: Host discovery - probe common ports to find live hosts
-	log.Printf("Phase 1: Discovering hosts on %d IPs with ports %v", len(ips), s.config.DiscoveryPorts)
-	liveHosts := s.discoverHosts(ctx, ips)
-	log.Printf("Phase 1 complete: Found %d live hosts", len(liveHosts))
+	phaseStart := time.Now()
+	liveHosts := s.discoverHosts(ctx, ips, discoveryPorts)
+	logger.Debug("host discovery complete",
+		"ports", discoveryPorts,
+		"live_hosts", len(liveHosts),
+		"duration", time.Since(phaseStart))
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan of %s stopped: %w", cidr, err)
+	}
 
 	if len(liveHosts) == 0 {
-		log.Printf("No live hosts found in %s", cidr)
+		logger.Info("no live hosts found", "duration", time.Since(start))
 		s.publishProgress("discovery-complete", map[string]interface{}{
 			"total":      len(ips),
 			"discovered": 0,
@@ -170,14 +278,29 @@ func (s *ScannerAdapter) ScanSubnet(ctx context.Context, cidr string) (*domain.G
 	})
 
 	// Phase 2: Service detection on live hosts
-	log.Printf("Phase 2: Scanning services on %d hosts", len(liveHosts))
-	hosts := s.scanHosts(ctx, liveHosts)
-	log.Printf("Phase 2 complete: Scanned %d hosts", len(hosts))
+	phaseStart = time.Now()
+	hosts := s.scanHosts(ctx, liveHosts, scanPorts)
+	logger.Debug("service scan complete", "hosts", len(hosts), "duration", time.Since(phaseStart))
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("scan of %s stopped: %w", cidr, err)
+	}
+
+	// A requested name groups the addresses it resolved to as one device
+	if hostname != "" {
+		for i := range hosts {
+			hosts[i].Hostname = hostname
+		}
+	}
 
 	// Phase 3: Convert to graph fragment
-	log.Printf("Phase 3: Converting %d hosts to graph fragment", len(hosts))
 	fragment := s.hostsToFragment(hosts, cidr)
-	log.Printf("Phase 3 complete: Created fragment with %d nodes", len(fragment.Nodes))
+	if hostname != "" {
+		for i := range fragment.Nodes {
+			if fragment.Nodes[i].ParentID == "" {
+				fragment.Nodes[i].SetProperty("hostname", hostname)
+			}
+		}
+	}
 
 	s.publishProgress("discovery-complete", map[string]interface{}{
 		"total":      len(ips),
@@ -185,12 +308,15 @@ func (s *ScannerAdapter) ScanSubnet(ctx context.Context, cidr string) (*domain.G
 		"message":    fmt.Sprintf("Discovered %d hosts with services", len(hosts)),
 	})
 
-	log.Printf("Scan complete: returning fragment with %d nodes", len(fragment.Nodes))
+	logger.Info("subnet scan complete",
+		"hosts", len(hosts),
+		"nodes", len(fragment.Nodes),
+		"duration", time.Since(start))
 	return fragment, nil
 }
 
 // discoverHosts finds live hosts by probing discovery ports
-func (s *ScannerAdapter) discoverHosts(ctx context.Context, ips []string) []string {
+func (s *ScannerAdapter) discoverHosts(ctx context.Context, ips []string, ports []int) []string {
 	liveHosts := make(map[string]bool)
 	var mu sync.Mutex
 
@@ -199,7 +325,7 @@ func (s *ScannerAdapter) discoverHosts(ctx context.Context, ips []string) []stri
 		ip   string
 		port int
 	}
-	jobs := make(chan probeJob, len(ips)*len(s.config.DiscoveryPorts))
+	jobs := make(chan probeJob, len(ips)*len(ports))
 
 	// Start worker pool
 	var wg sync.WaitGroup
@@ -233,7 +359,7 @@ func (s *ScannerAdapter) discoverHosts(ctx context.Context, ips []string) []stri
 
 	// Queue all probe jobs
 	for _, ip := range ips {
-		for _, port := range s.config.DiscoveryPorts {
+		for _, port := range ports {
 			jobs <- probeJob{ip: ip, port: port}
 		}
 	}
@@ -252,7 +378,7 @@ func (s *ScannerAdapter) discoverHosts(ctx context.Context, ips []string) []stri
 }
 
 // scanHosts performs detailed scanning on discovered hosts
-func (s *ScannerAdapter) scanHosts(ctx context.Context, ips []string) []DiscoveredHost {
+func (s *ScannerAdapter) scanHosts(ctx context.Context, ips []string, ports []int) []DiscoveredHost {
 	hosts := make([]DiscoveredHost, 0, len(ips))
 	var mu sync.Mutex
 
@@ -271,7 +397,7 @@ func (s *ScannerAdapter) scanHosts(ctx context.Context, ips []string) []Discover
 			case <-ctx.Done():
 				return
 			default:
-				host := s.scanHost(ctx, ip)
+				host := s.scanHost(ctx, ip, ports)
 				mu.Lock()
 				hosts = append(hosts, host)
 				mu.Unlock()
@@ -300,19 +426,19 @@ func (s *ScannerAdapter) scanHosts(ctx context.Context, ips []string) []Discover
 	return hosts
 }
 
-// scanHost performs a detailed scan of a single host
-func (s *ScannerAdapter) scanHost(ctx context.Context, ip string) DiscoveredHost {
+// scanHost performs a detailed scan of ports on a single host
+func (s *ScannerAdapter) scanHost(ctx context.Context, ip string, ports []int) DiscoveredHost {
 	host := DiscoveredHost{
 		IP: ip,
 	}
 
 	// Reverse DNS lookup
-	host.Hostname = s.reverseDNS(ip)
+	host.Hostname = s.reverseDNS(ctx, ip)
 
 	// Try to get MAC from ARP cache
 	host.MACAddress = s.arpLookup(ip)
 
-	// Scan all configured ports
+	// Scan the requested ports
 	var openPorts []int
 	var portDetails []PortInfo
 
@@ -321,10 +447,10 @@ func (s *ScannerAdapter) scanHost(ctx context.Context, ip string) DiscoveredHost
 		open   bool
 		detail PortInfo
 	}
-	results := make(chan portResult, len(s.config.ScanPorts))
+	results := make(chan portResult, len(ports))
 
 	var wg sync.WaitGroup
-	for _, port := range s.config.ScanPorts {
+	for _, port := range ports {
 		wg.Add(1)
 		go func(p int) {
 			defer wg.Done()
@@ -368,11 +494,11 @@ func (s *ScannerAdapter) scanHost(ctx context.Context, ip string) DiscoveredHost
 	return host
 }
 
-// probePort attempts to connect to a TCP port
+// probePort attempts to connect to a TCP port over tcp4 or tcp6
 func (s *ScannerAdapter) probePort(ctx context.Context, ip string, port int) bool {
-	addr := fmt.Sprintf("%s:%d", ip, port)
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
 	dialer := net.Dialer{Timeout: s.config.Timeout}
-	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	conn, err := dialer.DialContext(ctx, tcpNetwork(ip), addr)
 	if err != nil {
 		return false
 	}
@@ -382,23 +508,23 @@ func (s *ScannerAdapter) probePort(ctx context.Context, ip string, port int) boo
 
 // reverseDNS performs a reverse DNS lookup
 // Priority: 1) Static DNSServer config, 2) DNS capability from secrets, 3) System resolver
-func (s *ScannerAdapter) reverseDNS(ip string) string {
+func (s *ScannerAdapter) reverseDNS(ctx context.Context, ip string) string {
 	dnsServer := s.config.DNSServer
 
 	// If no static DNS configured, try to get from capabilities
 	if dnsServer == "" && s.config.Capabilities != nil {
-		if dnsCap, err := s.config.Capabilities.GetDNSCapability(context.Background()); err == nil && dnsCap != nil {
+		if dnsCap, err := s.config.Capabilities.GetDNSCapability(ctx); err == nil && dnsCap != nil {
 			dnsServer = dnsCap.Server
 		}
 	}
 
 	if dnsServer != "" {
 		// Use custom DNS server for PTR lookup
-		return s.reverseDNSCustom(ip, dnsServer)
+		return s.reverseDNSCustom(ctx, ip, dnsServer)
 	}
 
 	// Fall back to system resolver
-	names, err := net.LookupAddr(ip)
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
 	if err != nil || len(names) == 0 {
 		return ""
 	}
@@ -410,24 +536,24 @@ func (s *ScannerAdapter) reverseDNS(ip string) string {
 }
 
 // reverseDNSCustom performs PTR lookup against a specific DNS server
-func (s *ScannerAdapter) reverseDNSCustom(ip, dnsServer string) string {
+func (s *ScannerAdapter) reverseDNSCustom(ctx context.Context, ip, dnsServer string) string {
 	// Create a custom resolver
 	resolver := &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
 			d := net.Dialer{Timeout: s.config.Timeout}
 			// Always connect to the configured DNS server
-			return d.DialContext(ctx, "udp", dnsServer+":53")
+			return d.DialContext(ctx, "udp", net.JoinHostPort(dnsServer, "53"))
 		},
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), s.config.Timeout*2)
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout*2)
 	defer cancel()
 
 	// Use LookupAddr with our custom resolver
 	names, err := resolver.LookupAddr(ctx, ip)
 	if err != nil || len(names) == 0 {
-		log.Printf("PTR lookup for %s via %s failed: %v", ip, dnsServer, err)
+		s.logger.Debug("PTR lookup failed", "ip", ip, "dns_server", dnsServer, "error", err)
 		return ""
 	}
 
@@ -435,7 +561,7 @@ func (s *ScannerAdapter) reverseDNSCustom(ip, dnsServer string) string {
 	if len(hostname) > 0 && hostname[len(hostname)-1] == '.' {
 		hostname = hostname[:len(hostname)-1]
 	}
-	log.Printf("PTR lookup for %s via %s: %s", ip, dnsServer, hostname)
+	s.logger.Debug("PTR lookup", "ip", ip, "dns_server", dnsServer, "hostname", hostname)
 	return hostname
 }
 
@@ -450,8 +576,8 @@ func (s *ScannerAdapter) arpLookup(ip string) string {
 
 // grabBanner attempts to read a service banner
 func (s *ScannerAdapter) grabBanner(ip string, port int) string {
-	addr := fmt.Sprintf("%s:%d", ip, port)
-	conn, err := net.DialTimeout("tcp", addr, s.config.Timeout)
+	addr := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, err := net.DialTimeout(tcpNetwork(ip), addr, s.config.Timeout)
 	if err != nil {
 		return ""
 	}
@@ -461,7 +587,11 @@ func (s *ScannerAdapter) grabBanner(ip string, port int) string {
 
 	// For HTTP, send a request
 	if port == 80 || port == 8080 {
-		fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", ip)
+		host := ip
+		if strings.Contains(ip, ":") {
+			host = "[" + ip + "]"
+		}
+		fmt.Fprintf(conn, "HEAD / HTTP/1.0\r\nHost: %s\r\n\r\n", host)
 	}
 
 	// Read response
@@ -531,10 +661,11 @@ func (s *ScannerAdapter) hostsToFragment(hosts []DiscoveredHost, segmentum strin
 // segmentum is the CIDR range this host was discovered in (for visual grouping)
 func (s *ScannerAdapter) createStandaloneNode(host DiscoveredHost, segmentum string, now time.Time) domain.Node {
 	// Generate node ID from IP (sanitized)
-	nodeID := strings.ReplaceAll(host.IP, ".", "-")
+	nodeID := sanitizeIP(host.IP)
 
-	// Determine node type based on open ports
-	nodeType := inferNodeType(host.OpenPorts)
+	// Determine node type from weighted port evidence and the MAC vendor
+	vendor := LookupMACVendor(host.MACAddress)
+	classification := s.classifyNodeType(host.OpenPorts, vendor, now)
 
 	// Use hostname as label if available, otherwise IP
 	label := host.Hostname
@@ -552,7 +683,7 @@ func (s *ScannerAdapter) createStandaloneNode(host DiscoveredHost, segmentum str
 
 	node := domain.Node{
 		ID:     nodeID,
-		Type:   nodeType,
+		Type:   classification.Type,
 		Label:  label,
 		Source: "scanner",
 		Status: domain.NodeStatusVerified,
@@ -561,14 +692,16 @@ func (s *ScannerAdapter) createStandaloneNode(host DiscoveredHost, segmentum str
 			"segmentum": segmentum, // CIDR for visual fabric grouping
 		},
 		Discovered: map[string]any{
-			"open_ports":  host.OpenPorts,
-			"services":    host.PortDetails,
-			"reverse_dns": host.Hostname,
+			"open_ports":          host.OpenPorts,
+			"services":            host.PortDetails,
+			"reverse_dns":         host.Hostname,
+			"type_classification": classification,
 		},
 	}
 
 	if host.MACAddress != "" {
 		node.Discovered["mac_address"] = host.MACAddress
+		setMACVendor(&node, vendor)
 	}
 
 	node.LastVerified = &now
@@ -589,15 +722,19 @@ func (s *ScannerAdapter) createHostWithInterfaces(fragment *domain.GraphFragment
 
 	// Determine parent node type from combined port analysis
 	allPorts := []int{}
+	vendor := ""
 	for _, h := range hosts {
 		allPorts = append(allPorts, h.OpenPorts...)
+		if vendor == "" {
+			vendor = LookupMACVendor(h.MACAddress)
+		}
 	}
-	parentType := inferNodeType(allPorts)
+	classification := s.classifyNodeType(allPorts, vendor, now)
 
 	// Create parent node
 	parentNode := domain.Node{
 		ID:     shortName,
-		Type:   parentType,
+		Type:   classification.Type,
 		Label:  shortName,
 		Source: "scanner",
 		Status: domain.NodeStatusVerified,
@@ -606,8 +743,9 @@ func (s *ScannerAdapter) createHostWithInterfaces(fragment *domain.GraphFragment
 			"segmentum": segmentum, // CIDR for visual fabric grouping
 		},
 		Discovered: map[string]any{
-			"interface_count": len(hosts),
-			"reverse_dns":     hostname,
+			"interface_count":     len(hosts),
+			"reverse_dns":         hostname,
+			"type_classification": classification,
 		},
 	}
 	parentNode.LastVerified = &now
@@ -652,63 +790,30 @@ func (s *ScannerAdapter) createHostWithInterfaces(fragment *domain.GraphFragment
 		fragment.AddNode(interfaceNode)
 	}
 
-	log.Printf("Created parent node %s with %d interfaces (IPs: %v)",
-		shortName, len(hosts), func() []string {
-			ips := make([]string, len(hosts))
-			for i, h := range hosts {
-				ips[i] = h.IP
-			}
-			return ips
-		}())
-}
-
-// inferNodeType guesses the device type based on open ports
-func inferNodeType(ports []int) domain.NodeType {
-	portSet := make(map[int]bool)
-	for _, p := range ports {
-		portSet[p] = true
-	}
-
-	// Router indicators
-	if portSet[53] && (portSet[80] || portSet[443]) {
-		return domain.NodeTypeRouter
-	}
-
-	// Network switch/AP (SNMP, web interface)
-	if portSet[161] || (portSet[80] && !portSet[22] && !portSet[443]) {
-		return domain.NodeTypeSwitch
-	}
-
-	// Windows machine
-	if portSet[3389] || portSet[445] {
-		return domain.NodeTypeServer
-	}
-
-	// Linux server (SSH + web)
-	if portSet[22] && (portSet[80] || portSet[443]) {
-		return domain.NodeTypeServer
-	}
-
-	// VNC suggests desktop/VM
-	if portSet[5900] {
-		return domain.NodeTypeVM
+	ips := make([]string, len(hosts))
+	for i, h := range hosts {
+		ips[i] = h.IP
 	}
+	s.logger.Debug("created parent node", "node_id", shortName, "interfaces", len(hosts), "ips", ips)
+}
 
-	// Just SSH - likely a server
-	if portSet[22] {
-		return domain.NodeTypeServer
+// classifyNodeType infers the device type from weighted open-port evidence
+// and the MAC vendor, if known
+func (s *ScannerAdapter) classifyNodeType(ports []int, vendor string, now time.Time) domain.TypeClassification {
+	weights := s.config.TypeWeights
+	if weights == nil {
+		weights = domain.DefaultPortTypeWeights
 	}
+	return domain.ClassifyNodeTypeWithVendor(ports, vendor, weights, now)
+}
 
-	// Web only
-	if portSet[80] || portSet[443] || portSet[8080] {
-		return domain.NodeTypeServer
+// expandCIDR converts a CIDR notation to a list of IPs, refusing ranges
+// with more than maxHosts addresses (0 = default limit)
+func expandCIDR(cidr string, maxHosts int) ([]string, error) {
+	if maxHosts <= 0 {
+		maxHosts = defaultMaxScanHosts
 	}
 
-	return domain.NodeTypeUnknown
-}
-
-// expandCIDR converts a CIDR notation to a list of IPs
-func expandCIDR(cidr string) ([]string, error) {
 	_, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
 		// Try parsing as single IP
@@ -719,14 +824,14 @@ func expandCIDR(cidr string) ([]string, error) {
 		return nil, err
 	}
 
-	var ips []string
-
 	// Get the network and broadcast addresses
 	ip := ipNet.IP.To4()
 	if ip == nil {
-		return nil, fmt.Errorf("only IPv4 supported")
+		return expandCIDR6(ipNet, maxHosts)
 	}
 
+	var ips []string
+
 	mask := ipNet.Mask
 
 	// Calculate range
@@ -744,9 +849,9 @@ func expandCIDR(cidr string) ([]string, error) {
 		lastIP--
 	}
 
-	// Safety limit - don't scan more than 1024 IPs
-	if lastIP-firstIP > 1024 {
-		return nil, fmt.Errorf("CIDR range too large (max 1024 IPs)")
+	// Safety limit
+	if count := uint64(lastIP-firstIP) + 1; count > uint64(maxHosts) {
+		return nil, fmt.Errorf("CIDR range %s has %d addresses, over the scan limit of %d", cidr, count, maxHosts)
 	}
 
 	for i := firstIP; i <= lastIP; i++ {
@@ -757,3 +862,78 @@ func expandCIDR(cidr string) ([]string, error) {
 
 	return ips, nil
 }
+
+// resolveTarget lists the addresses to scan for target: a CIDR range or
+// address is expanded with expandCIDR, and anything else is looked up as a
+// hostname (A and AAAA records). The hostname is returned for a looked up
+// target and empty otherwise.
+func resolveTarget(ctx context.Context, target string, maxHosts int) ([]string, string, error) {
+	if strings.Contains(target, "/") || net.ParseIP(target) != nil {
+		ips, err := expandCIDR(target, maxHosts)
+		return ips, "", err
+	}
+
+	hostname := strings.TrimSuffix(strings.TrimSpace(target), ".")
+	if hostname == "" || strings.ContainsAny(hostname, " \t:") {
+		return nil, "", fmt.Errorf("%q is not a CIDR, address or hostname", target)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, hostname)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot resolve host %s: %w", hostname, err)
+	}
+
+	seen := make(map[string]bool, len(addrs))
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := addr.IP.String()
+		if !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, "", fmt.Errorf("cannot resolve host %s: no addresses", hostname)
+	}
+	sort.Strings(ips)
+	if maxHosts > 0 && len(ips) > maxHosts {
+		ips = ips[:maxHosts]
+	}
+	return ips, hostname, nil
+}
+
+// expandCIDR6 lists the addresses of a small IPv6 prefix. The all-zeros
+// subnet-router anycast address is skipped except for /127 and /128.
+func expandCIDR6(ipNet *net.IPNet, maxHosts int) ([]string, error) {
+	ones, bits := ipNet.Mask.Size()
+	hostBits := bits - ones
+	if hostBits >= 63 || uint64(1)<<uint(hostBits) > uint64(maxHosts) {
+		return nil, fmt.Errorf("IPv6 prefix /%d is over the scan limit of %d addresses", ones, maxHosts)
+	}
+
+	base := ipNet.IP.To16()
+	high := binary.BigEndian.Uint64(base[:8])
+	low := binary.BigEndian.Uint64(base[8:])
+
+	first := uint64(0)
+	if hostBits > 1 {
+		first = 1
+	}
+	count := uint64(1) << uint(hostBits)
+
+	ips := make([]string, 0, count-first)
+	for i := first; i < count; i++ {
+		addr := make(net.IP, net.IPv6len)
+		binary.BigEndian.PutUint64(addr[:8], high)
+		binary.BigEndian.PutUint64(addr[8:], low+i)
+		ips = append(ips, addr.String())
+	}
+	return ips, nil
+}
+
+// tcpNetwork returns the dial network matching an address family
+func tcpNetwork(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "tcp6"
+	}
+	return "tcp4"
+}
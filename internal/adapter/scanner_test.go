@@ -0,0 +1,215 @@
+package adapter
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+func TestExpandCIDR(t *testing.T) {
+	tests := []struct {
+		name      string
+		cidr      string
+		maxHosts  int
+		wantCount int
+		wantFirst string
+		wantLast  string
+		wantError bool
+	}{
+		{"IPv4 /24 skips network and broadcast", "192.168.1.0/24", 0, 254, "192.168.1.1", "192.168.1.254", false},
+		{"IPv4 /30", "10.0.0.0/30", 0, 4, "10.0.0.0", "10.0.0.3", false},
+		{"IPv4 over default cap", "10.0.0.0/21", 0, 0, "", "", true},
+		{"IPv4 raised cap", "10.0.0.0/21", 4096, 2046, "10.0.0.1", "10.0.7.254", false},
+		{"IPv4 lowered cap", "192.168.1.0/24", 100, 0, "", "", true},
+		{"single IPv6 address", "2001:db8::5", 0, 1, "2001:db8::5", "2001:db8::5", false},
+		{"IPv6 /120 skips anycast", "2001:db8::/120", 0, 255, "2001:db8::1", "2001:db8::ff", false},
+		{"IPv6 /127", "2001:db8::/127", 0, 2, "2001:db8::", "2001:db8::1", false},
+		{"IPv6 /64 rejected", "2001:db8::/64", 0, 0, "", "", true},
+		{"IPv6 over configured cap", "2001:db8::/120", 128, 0, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ips, err := expandCIDR(tt.cidr, tt.maxHosts)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("expandCIDR(%s) error = %v, wantError %v", tt.cidr, err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if len(ips) != tt.wantCount {
+				t.Fatalf("expandCIDR(%s) got %d IPs, want %d", tt.cidr, len(ips), tt.wantCount)
+			}
+			if ips[0] != tt.wantFirst || ips[len(ips)-1] != tt.wantLast {
+				t.Errorf("expandCIDR(%s) range = %s..%s, want %s..%s", tt.cidr, ips[0], ips[len(ips)-1], tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestTCPNetwork(t *testing.T) {
+	if got := tcpNetwork("192.168.1.1"); got != "tcp4" {
+		t.Errorf("tcpNetwork(v4) = %s, want tcp4", got)
+	}
+	if got := tcpNetwork("2001:db8::1"); got != "tcp6" {
+		t.Errorf("tcpNetwork(v6) = %s, want tcp6", got)
+	}
+}
+
+func TestScannerEffectivePorts(t *testing.T) {
+	config := DefaultScannerConfig()
+	config.PortProfiles = PortProfiles{
+		"iot":     {1883, 80, 554, 80},
+		"storage": {111, 2049, 3260},
+	}
+	if err := config.PortProfiles.Validate(); err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+	scanner := NewScannerAdapter(config)
+
+	discovery, scan := scanner.EffectivePorts("iot")
+	want := []int{80, 554, 1883}
+	if !reflect.DeepEqual(discovery, want) || !reflect.DeepEqual(scan, want) {
+		t.Errorf("EffectivePorts(iot) = %v, %v; want %v for both", discovery, scan, want)
+	}
+
+	for _, profile := range []string{"", "unknown"} {
+		discovery, scan := scanner.EffectivePorts(profile)
+		if !reflect.DeepEqual(discovery, config.DiscoveryPorts) || !reflect.DeepEqual(scan, config.ScanPorts) {
+			t.Errorf("EffectivePorts(%q) = %v, %v; want the defaults", profile, discovery, scan)
+		}
+	}
+
+	for _, bad := range []PortProfiles{{"empty": {}}, {"zero": {0}}, {"high": {22, 70000}}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Validate(%v) = nil, want an error", bad)
+		}
+	}
+
+	nmap := NewNmapAdapter([]string{"10.0.0.0/24", "10.0.1.0/24"},
+		WithPortRange("22,80"),
+		WithPortProfiles(config.PortProfiles, map[string]string{"10.0.0.0/24": "storage", "10.0.1.0/24": "missing"}))
+	if got := nmap.portsFor("10.0.0.0/24"); got != "111,2049,3260" {
+		t.Errorf("nmap ports for storage target = %q", got)
+	}
+	if got := nmap.portsFor("10.0.1.0/24"); got != "22,80" {
+		t.Errorf("nmap ports for unknown profile = %q, want the port range", got)
+	}
+}
+
+func TestScannerVendorTypeHint(t *testing.T) {
+	scanner := NewScannerAdapter(DefaultScannerConfig())
+	host := DiscoveredHost{IP: "10.0.0.2", OpenPorts: []int{161, 445}}
+
+	// SNMP and SMB tie between switch and server without a vendor
+	if node := scanner.createStandaloneNode(host, "10.0.0.0/24", time.Now()); node.Type != domain.NodeTypeServer {
+		t.Errorf("ports alone typed as %s, want the server tie-break", node.Type)
+	}
+
+	host.MACAddress = "00:00:0C:12:34:56"
+	node := scanner.createStandaloneNode(host, "10.0.0.0/24", time.Now())
+	if node.Type != domain.NodeTypeSwitch {
+		t.Errorf("Cisco OUI with SNMP typed as %s, want switch", node.Type)
+	}
+	if node.Discovered["mac_vendor"] != "Cisco" || node.Discovered["vendor"] != "Cisco" {
+		t.Errorf("vendor = %v / %v, want Cisco", node.Discovered["mac_vendor"], node.Discovered["vendor"])
+	}
+}
+
+func TestScannerScanLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured int
+		override   int
+		cidr       string
+		wantLimit  int
+		wantError  string
+	}{
+		{"default cap allows /22", 0, 0, "10.0.0.0/22", 1024, ""},
+		{"default cap rejects /21", 0, 0, "10.0.0.0/21", 1024, "2046 addresses, over the scan limit of 1024"},
+		{"raised cap allows /21", 0, 2048, "10.0.0.0/21", 2048, ""},
+		{"configured cap is lowered", 256, 0, "10.0.0.0/23", 256, "over the scan limit of 256"},
+		{"configured cap is clamped to the ceiling", 1 << 20, 0, "10.0.0.0/24", MaxScanHostsCeiling, ""},
+		{"override above the ceiling", 0, MaxScanHostsCeiling + 1, "10.0.0.0/24", 0, "absolute ceiling of 65536"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultScannerConfig()
+			config.MaxHosts = tt.configured
+			s := NewScannerAdapter(config)
+
+			limit, err := s.ScanLimit(tt.override)
+			if err == nil && limit != tt.wantLimit {
+				t.Errorf("ScanLimit(%d) = %d, want %d", tt.override, limit, tt.wantLimit)
+			}
+
+			err = s.CheckScanRange(tt.cidr, tt.override)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("CheckScanRange(%s) error = %v", tt.cidr, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("CheckScanRange(%s) error = %v, want it to mention %q", tt.cidr, err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestScannerScanHostnameTarget(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	config := DefaultScannerConfig()
+	config.DiscoveryPorts = []int{port}
+	config.ScanPorts = []int{port}
+	config.Timeout = 200 * time.Millisecond
+	config.BannerTimeout = 50 * time.Millisecond
+	s := NewScannerAdapter(config)
+	ctx := context.Background()
+
+	ips, hostname, err := resolveTarget(ctx, "localhost", 0)
+	if err != nil {
+		t.Fatalf("resolveTarget(localhost) error = %v", err)
+	}
+	if hostname != "localhost" || !slices.Contains(ips, "127.0.0.1") {
+		t.Fatalf("resolveTarget(localhost) = %v, %q; want 127.0.0.1 for localhost", ips, hostname)
+	}
+
+	fragment, err := s.ScanSubnetWithOptions(ctx, "localhost", ScanOptions{})
+	if err != nil {
+		t.Fatalf("ScanSubnetWithOptions(localhost) error = %v", err)
+	}
+	if fragment == nil || len(fragment.Nodes) == 0 {
+		t.Fatal("scan of localhost found no nodes")
+	}
+	node := fragment.Nodes[0]
+	if node.GetPropertyString("hostname") != "localhost" || node.Label != "localhost" {
+		t.Errorf("node hostname = %q, label = %q; want localhost", node.GetPropertyString("hostname"), node.Label)
+	}
+
+	if err := s.CheckScanRange("no-such-host.invalid", 0); err == nil || !strings.Contains(err.Error(), "cannot resolve host") {
+		t.Errorf("CheckScanRange(unresolvable) error = %v, want a resolution error", err)
+	}
+}
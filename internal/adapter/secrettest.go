@@ -0,0 +1,139 @@
+package adapter
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// sysDescrOID is SNMPv2-MIB::sysDescr; a GetNext on it returns sysDescr.0
+const sysDescrOID = "1.3.6.1.2.1.1.1"
+
+// SecretTester performs a single lightweight check that a secret works
+// against a target: SSH authentication, an SNMP sysDescr read, or a DNS
+// lookup through the secret's server.
+type SecretTester struct {
+	timeout time.Duration
+}
+
+// NewSecretTester creates a secret tester. A zero timeout uses 10s.
+func NewSecretTester(timeout time.Duration) *SecretTester {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &SecretTester{timeout: timeout}
+}
+
+// TestSecret validates secret against target and returns a short
+// description of what succeeded. For SSH and SNMP the target is
+// host[:port]; for DNS it is the name to resolve.
+func (t *SecretTester) TestSecret(ctx context.Context, secret *domain.Secret, target string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	switch secret.Type {
+	case domain.SecretTypeSSHKey, domain.SecretTypeSSHPassword:
+		return t.testSSH(ctx, secret, target)
+	case domain.SecretTypeSNMPCommunity:
+		return t.testSNMP(ctx, secret, target)
+	case domain.SecretTypeDNS:
+		return t.testDNS(ctx, secret, target)
+	default:
+		return "", fmt.Errorf("secret type %s does not support testing", secret.Type)
+	}
+}
+
+// testSSH authenticates and closes the connection without running commands
+func (t *SecretTester) testSSH(ctx context.Context, secret *domain.Secret, target string) (string, error) {
+	host, port, err := splitTarget(target, 22)
+	if err != nil {
+		return "", err
+	}
+
+	probe := &SSHProbeAdapter{timeout: t.timeout}
+	client, _, err := probe.connect(ctx, host, port, secret)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return fmt.Sprintf("authenticated as %s (%s)", client.User(), client.ServerVersion()), nil
+}
+
+// testSNMP reads sysDescr.0 with the secret's community
+func (t *SecretTester) testSNMP(ctx context.Context, secret *domain.Secret, target string) (string, error) {
+	community := secret.Data["community"]
+	if community == "" {
+		return "", fmt.Errorf("community not found in SNMP secret")
+	}
+	host, port, err := splitTarget(target, 161)
+	if err != nil {
+		return "", err
+	}
+
+	client := &snmpClient{
+		address:   net.JoinHostPort(host, strconv.Itoa(port)),
+		community: community,
+		timeout:   t.timeout / 2,
+		retries:   1,
+	}
+	conn, err := net.Dial("udp", client.address)
+	if err != nil {
+		return "", fmt.Errorf("dial %s: %w", client.address, err)
+	}
+	defer conn.Close()
+
+	vb, err := client.getNext(conn, sysDescrOID)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(vb.OID, sysDescrOID+".") {
+		return "", fmt.Errorf("agent returned no sysDescr")
+	}
+	return "sysDescr: " + strings.TrimSpace(string(vb.Bytes())), nil
+}
+
+// testDNS resolves target through the secret's server
+func (t *SecretTester) testDNS(ctx context.Context, secret *domain.Secret, target string) (string, error) {
+	server := secret.Data["server"]
+	if server == "" {
+		return "", fmt.Errorf("server not found in DNS secret")
+	}
+	port := secret.Data["port"]
+	if port == "" {
+		port = "53"
+	}
+	addr := net.JoinHostPort(server, port)
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			d := net.Dialer{Timeout: t.timeout}
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+	addrs, err := resolver.LookupHost(ctx, target)
+	if err != nil {
+		return "", fmt.Errorf("lookup %s via %s: %w", target, addr, err)
+	}
+	return fmt.Sprintf("%s resolved to %s via %s", target, strings.Join(addrs, ", "), addr), nil
+}
+
+// splitTarget parses host[:port], applying defaultPort when none is given
+func splitTarget(target string, defaultPort int) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		// No port (or a bare IPv6 address)
+		return strings.Trim(target, "[]"), defaultPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in target %q", target)
+	}
+	return host, port, nil
+}
@@ -0,0 +1,100 @@
+package adapter
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// fakeSNMPAgent answers every request on a local UDP port with sysDescr.0,
+// echoing the request ID, as long as the community matches
+func fakeSNMPAgent(t *testing.T, community, sysDescr string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, msg, _, _ := berRead(buf[:n])
+			_, _, msg, _ = berRead(msg) // version
+			_, gotCommunity, msg, _ := berRead(msg)
+			if string(gotCommunity) != community {
+				continue // agents silently drop bad communities
+			}
+			_, pdu, _, _ := berRead(msg)
+			_, reqID, _, _ := berRead(pdu)
+
+			oid, _ := encodeOID(sysDescrOID + ".0")
+			varBind := berTLV(berSequence, append(berTLV(berOID, oid), berTLV(berOctetString, []byte(sysDescr))...))
+			resp := berTLV(berInteger, reqID)
+			resp = append(resp, berTLV(berInteger, encodeBERInt(0))...)
+			resp = append(resp, berTLV(berInteger, encodeBERInt(0))...)
+			resp = append(resp, berTLV(berSequence, varBind)...)
+			out := berTLV(berInteger, encodeBERInt(snmpVersion2c))
+			out = append(out, berTLV(berOctetString, gotCommunity)...)
+			out = append(out, berTLV(snmpGetResponse, resp)...)
+			conn.WriteTo(berTLV(berSequence, out), addr)
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestSecretTesterSNMP(t *testing.T) {
+	agent := fakeSNMPAgent(t, "lab-ro", "Cisco IOS 15.2")
+	tester := NewSecretTester(400 * time.Millisecond)
+
+	good := &domain.Secret{Type: domain.SecretTypeSNMPCommunity, Data: map[string]string{"community": "lab-ro"}}
+	msg, err := tester.TestSecret(context.Background(), good, agent)
+	if err != nil {
+		t.Fatalf("TestSecret() error = %v", err)
+	}
+	if !strings.Contains(msg, "Cisco IOS 15.2") {
+		t.Errorf("message = %q, want sysDescr", msg)
+	}
+
+	bad := &domain.Secret{Type: domain.SecretTypeSNMPCommunity, Data: map[string]string{"community": "public"}}
+	if _, err := tester.TestSecret(context.Background(), bad, agent); err == nil {
+		t.Error("expected timeout for wrong community")
+	}
+}
+
+func TestSecretTesterUnsupportedType(t *testing.T) {
+	secret := &domain.Secret{Type: domain.SecretTypeGeneric, Data: map[string]string{"value": "x"}}
+	if _, err := NewSecretTester(0).TestSecret(context.Background(), secret, "host"); err == nil {
+		t.Error("expected error for generic secret")
+	}
+}
+
+func TestSplitTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		host    string
+		port    int
+		wantErr bool
+	}{
+		{"10.0.0.1", "10.0.0.1", 22, false},
+		{"10.0.0.1:2222", "10.0.0.1", 2222, false},
+		{"[fe80::1]:22", "fe80::1", 22, false},
+		{"fe80::1", "fe80::1", 22, false},
+		{"host:notaport", "", 0, true},
+	}
+	for _, tt := range tests {
+		host, port, err := splitTarget(tt.target, 22)
+		if (err != nil) != tt.wantErr || host != tt.host || port != tt.port {
+			t.Errorf("splitTarget(%q) = %q, %d, %v", tt.target, host, port, err)
+		}
+	}
+}
@@ -0,0 +1,544 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// MIB columns walked by the SNMP adapter
+const (
+	oidIfDescr       = "1.3.6.1.2.1.2.2.1.2"
+	oidIfType        = "1.3.6.1.2.1.2.2.1.3"
+	oidIfSpeed       = "1.3.6.1.2.1.2.2.1.5"
+	oidIfPhysAddress = "1.3.6.1.2.1.2.2.1.6"
+	oidIfOperStatus  = "1.3.6.1.2.1.2.2.1.8"
+	oidIfName        = "1.3.6.1.2.1.31.1.1.1.1"
+
+	// Q-BRIDGE-MIB dot1qPvid: the untagged VLAN of each bridge port
+	oidDot1qPvid = "1.3.6.1.2.1.17.7.1.4.5.1.1"
+
+	oidLldpRemChassisIDSubtype = "1.0.8802.1.1.2.1.4.1.1.4"
+	oidLldpRemChassisID        = "1.0.8802.1.1.2.1.4.1.1.5"
+	oidLldpRemPortIDSubtype    = "1.0.8802.1.1.2.1.4.1.1.6"
+	oidLldpRemPortID           = "1.0.8802.1.1.2.1.4.1.1.7"
+	oidLldpRemSysName          = "1.0.8802.1.1.2.1.4.1.1.9"
+)
+
+// ifTypeSoftwareLoopback is skipped when building interface nodes
+const ifTypeSoftwareLoopback = 24
+
+// LLDP chassis/port ID subtypes that carry a MAC address
+const (
+	lldpChassisSubtypeMAC = 4
+	lldpPortSubtypeMAC    = 3
+)
+
+// ifOperStatusNames maps IF-MIB ifOperStatus values to names
+var ifOperStatusNames = map[int64]string{
+	1: "up",
+	2: "down",
+	3: "testing",
+	4: "unknown",
+	5: "dormant",
+	6: "notPresent",
+	7: "lowerLayerDown",
+}
+
+// snmpIDUnsafe matches characters that don't belong in a node ID
+var snmpIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NodeLister lists nodes from the graph
+type NodeLister interface {
+	ListNodes(ctx context.Context, nodeType, source string) ([]domain.Node, error)
+}
+
+// SNMPConfig holds configuration for the SNMP adapter
+type SNMPConfig struct {
+	// Port is the agent UDP port
+	Port int
+	// Timeout for each SNMP request
+	Timeout time.Duration
+	// Retries per request before the agent is considered unresponsive
+	Retries int
+	// CapabilityManager provides the community string
+	Capabilities *CapabilityManager
+}
+
+// DefaultSNMPConfig returns sensible defaults
+func DefaultSNMPConfig() SNMPConfig {
+	return SNMPConfig{
+		Port:    161,
+		Timeout: 3 * time.Second,
+		Retries: 1,
+	}
+}
+
+// SNMPAdapter polls switches and routers over SNMPv2c. It walks IF-MIB to
+// build interface child nodes and LLDP-MIB to link those interfaces to
+// neighbors already in the graph.
+type SNMPAdapter struct {
+	config    SNMPConfig
+	nodes     NodeLister
+	publisher EventPublisher
+	mu        sync.Mutex
+	running   bool
+
+	// walk is swappable for tests
+	walk func(ctx context.Context, target, community, root string) ([]snmpVarBind, error)
+}
+
+// NewSNMPAdapter creates a new SNMP adapter
+func NewSNMPAdapter(nodes NodeLister, config SNMPConfig) *SNMPAdapter {
+	if config.Port == 0 {
+		config.Port = 161
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 3 * time.Second
+	}
+
+	s := &SNMPAdapter{
+		config: config,
+		nodes:  nodes,
+	}
+	s.walk = s.systemWalk
+	return s
+}
+
+// SetEventPublisher sets the event publisher for progress updates
+func (s *SNMPAdapter) SetEventPublisher(pub EventPublisher) {
+	s.publisher = pub
+}
+
+// publishProgress emits a discovery progress event
+func (s *SNMPAdapter) publishProgress(payload interface{}) {
+	if s.publisher != nil {
+		s.publisher.PublishDiscoveryEvent("discovery-progress", payload)
+	}
+}
+
+// Name returns the adapter identifier
+func (s *SNMPAdapter) Name() string {
+	return "snmp"
+}
+
+// Type returns the adapter type
+func (s *SNMPAdapter) Type() AdapterType {
+	return AdapterTypePolling
+}
+
+// Priority returns the adapter priority
+func (s *SNMPAdapter) Priority() int {
+	return 70 // Device-reported topology is more reliable than inference
+}
+
+// Start initializes the adapter
+func (s *SNMPAdapter) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+	log.Printf("SNMP adapter started (port=%d, timeout=%s)", s.config.Port, s.config.Timeout)
+	return nil
+}
+
+// Stop shuts down the adapter
+func (s *SNMPAdapter) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = false
+	log.Printf("SNMP adapter stopped")
+	return nil
+}
+
+// Sync polls every SNMP-capable node and returns interface nodes, neighbor
+// edges and updated device discoveries. An unresponsive agent marks its
+// node degraded instead of failing the sync.
+func (s *SNMPAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	if s.config.Capabilities == nil {
+		return nil, nil
+	}
+	snmp, err := s.config.Capabilities.GetSNMPv2Capability(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SNMP capability: %w", err)
+	}
+	if snmp == nil {
+		log.Printf("SNMP: No community secret configured, skipping")
+		return nil, nil
+	}
+
+	nodes, err := s.nodes.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	macIndex := buildMACIndex(nodes)
+	fragment := domain.NewGraphFragment()
+
+	for _, node := range nodes {
+		if !isSNMPTarget(node) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return fragment, err
+		}
+		s.pollDevice(ctx, node, snmp.Community, macIndex, fragment)
+	}
+
+	return fragment, nil
+}
+
+// isSNMPTarget reports whether a node should be polled
+func isSNMPTarget(node domain.Node) bool {
+	if node.GetPropertyString("ip") == "" {
+		return false
+	}
+	if node.Type == domain.NodeTypeSwitch || node.Type == domain.NodeTypeRouter {
+		return true
+	}
+	return node.GetCapability(domain.CapabilitySNMP) != nil
+}
+
+// pollDevice walks one agent and adds its results to the fragment
+func (s *SNMPAdapter) pollDevice(ctx context.Context, device domain.Node, community string, macIndex map[string]string, fragment *domain.GraphFragment) {
+	ip := device.GetPropertyString("ip")
+	now := time.Now()
+
+	// Carry existing discoveries forward since reconcile replaces the map
+	updated := device
+	updated.Discovered = make(map[string]any, len(device.Discovered)+3)
+	for k, v := range device.Discovered {
+		updated.Discovered[k] = v
+	}
+
+	ifaces, err := s.walkInterfaces(ctx, ip, community)
+	if err != nil {
+		log.Printf("SNMP: poll of %s (%s) failed: %v", device.ID, ip, err)
+		if errors.Is(err, errSNMPTimeout) {
+			updated.Status = domain.NodeStatusDegraded
+			updated.SetDiscovered("snmp_error", err.Error())
+			fragment.AddNode(updated)
+		}
+		return
+	}
+
+	ifaceIDs := make(map[int64]string, len(ifaces))
+	ifaceSpeeds := make(map[int64]int64, len(ifaces))
+	for i, iface := range ifaces {
+		ifaceNode := snmpInterfaceNode(device.ID, iface, now)
+		ifaceIDs[iface.Index] = ifaceNode.ID
+		ifaceSpeeds[iface.Index] = iface.Speed
+		fragment.AddNode(ifaceNode)
+
+		s.publishProgress(map[string]interface{}{
+			"node_id":     device.ID,
+			"interface":   iface.Name,
+			"oper_status": iface.OperStatus,
+			"current":     i + 1,
+			"total":       len(ifaces),
+			"message":     fmt.Sprintf("SNMP %s: interface %s is %s", device.Label, iface.Name, iface.OperStatus),
+			"phase":       "snmp_poll",
+		})
+	}
+
+	// LLDP is optional on many agents, so a failure here keeps the interfaces
+	neighbors, err := s.walkNeighbors(ctx, ip, community)
+	if err != nil {
+		log.Printf("SNMP: LLDP walk of %s failed: %v", device.ID, err)
+	}
+
+	neighborInfo := make([]map[string]any, 0, len(neighbors))
+	for _, n := range neighbors {
+		info := map[string]any{
+			"local_port":  n.LocalPort,
+			"chassis_id":  n.ChassisID,
+			"port_id":     n.PortID,
+			"system_name": n.SysName,
+		}
+
+		localID, ok := ifaceIDs[n.LocalPort]
+		if !ok {
+			localID = device.ID
+		}
+		remoteID := macIndex[n.PortMAC]
+		if remoteID == "" {
+			remoteID = macIndex[n.ChassisMAC]
+		}
+		if remoteID != "" && remoteID != device.ID {
+			info["node_id"] = remoteID
+			edge := domain.NewEdge(localID, remoteID, domain.EdgeTypeEthernet)
+			edge.SetProperty("source", "lldp")
+			edge.SetProperty("remote_port", n.PortID)
+			// ifSpeed is in bit/s; agents report 0 when unknown
+			if mbps := ifaceSpeeds[n.LocalPort] / 1_000_000; mbps > 0 {
+				edge.SetSpeedMbps(int(mbps))
+			}
+			fragment.AddEdge(*edge)
+		}
+		neighborInfo = append(neighborInfo, info)
+	}
+
+	// Clear a degraded status left by an earlier SNMP timeout
+	if _, hadErr := updated.Discovered["snmp_error"]; hadErr && updated.Status == domain.NodeStatusDegraded {
+		updated.Status = domain.NodeStatusVerified
+	}
+	delete(updated.Discovered, "snmp_error")
+	updated.SetDiscovered("snmp_interfaces", len(ifaces))
+	updated.SetDiscovered("lldp_neighbors", neighborInfo)
+	updated.LastSeen = &now
+	fragment.AddNode(updated)
+}
+
+// snmpInterface is a row from ifTable/ifXTable
+type snmpInterface struct {
+	Index      int64
+	Name       string
+	Descr      string
+	Speed      int64
+	MAC        string
+	OperStatus string
+	PVID       int64 // port VLAN ID, 0 when the agent has no Q-BRIDGE-MIB
+}
+
+// lldpNeighbor is a row from lldpRemTable
+type lldpNeighbor struct {
+	LocalPort  int64
+	ChassisID  string
+	ChassisMAC string
+	PortID     string
+	PortMAC    string
+	SysName    string
+}
+
+// walkInterfaces walks IF-MIB and returns non-loopback interfaces in ifIndex order.
+// Port VLAN IDs come from Q-BRIDGE-MIB, assuming bridge port numbers match
+// ifIndex as they do on most agents.
+func (s *SNMPAdapter) walkInterfaces(ctx context.Context, ip, community string) ([]snmpInterface, error) {
+	descr, err := s.walk(ctx, ip, community, oidIfDescr)
+	if err != nil {
+		return nil, err
+	}
+
+	byIndex := make(map[int64]*snmpInterface)
+	var order []int64
+	for _, vb := range descr {
+		idx := oidIndex(vb.OID, oidIfDescr)
+		if idx < 0 {
+			continue
+		}
+		byIndex[idx] = &snmpInterface{Index: idx, Descr: string(vb.Bytes()), OperStatus: "unknown"}
+		order = append(order, idx)
+	}
+
+	columns := []string{oidIfType, oidIfSpeed, oidIfPhysAddress, oidIfOperStatus, oidIfName, oidDot1qPvid}
+	types := make(map[int64]int64)
+	for _, column := range columns {
+		vbs, err := s.walk(ctx, ip, community, column)
+		if err != nil {
+			// ifXTable is missing on some older agents, and Q-BRIDGE-MIB on
+			// anything that is not a VLAN-aware switch
+			if (column == oidIfName || column == oidDot1qPvid) && !errors.Is(err, errSNMPTimeout) {
+				continue
+			}
+			return nil, err
+		}
+		for _, vb := range vbs {
+			iface, ok := byIndex[oidIndex(vb.OID, column)]
+			if !ok {
+				continue
+			}
+			switch column {
+			case oidIfType:
+				types[iface.Index] = vb.Int()
+			case oidIfSpeed:
+				iface.Speed = vb.Int()
+			case oidIfPhysAddress:
+				iface.MAC = formatMAC(vb.Bytes())
+			case oidIfOperStatus:
+				if name, ok := ifOperStatusNames[vb.Int()]; ok {
+					iface.OperStatus = name
+				}
+			case oidIfName:
+				iface.Name = string(vb.Bytes())
+			case oidDot1qPvid:
+				iface.PVID = vb.Int()
+			}
+		}
+	}
+
+	result := make([]snmpInterface, 0, len(order))
+	for _, idx := range order {
+		if types[idx] == ifTypeSoftwareLoopback {
+			continue
+		}
+		iface := byIndex[idx]
+		if iface.Name == "" {
+			iface.Name = iface.Descr
+		}
+		if iface.Name == "" {
+			iface.Name = fmt.Sprintf("if%d", idx)
+		}
+		result = append(result, *iface)
+	}
+	return result, nil
+}
+
+// walkNeighbors walks the LLDP remote systems table.
+// The local port number is assumed to match ifIndex, as it does on most agents.
+func (s *SNMPAdapter) walkNeighbors(ctx context.Context, ip, community string) ([]lldpNeighbor, error) {
+	columns := []string{oidLldpRemChassisIDSubtype, oidLldpRemChassisID, oidLldpRemPortIDSubtype, oidLldpRemPortID, oidLldpRemSysName}
+
+	// Rows are keyed by the timeMark.localPort.remIndex suffix
+	rows := make(map[string]*lldpNeighbor)
+	var order []string
+	subtypes := make(map[string][2]int64)
+
+	for _, column := range columns {
+		vbs, err := s.walk(ctx, ip, community, column)
+		if err != nil {
+			return nil, err
+		}
+		for _, vb := range vbs {
+			key := strings.TrimPrefix(vb.OID, column+".")
+			parts := strings.Split(key, ".")
+			if len(parts) != 3 {
+				continue
+			}
+			row, ok := rows[key]
+			if !ok {
+				localPort, _ := strconv.ParseInt(parts[1], 10, 64)
+				row = &lldpNeighbor{LocalPort: localPort}
+				rows[key] = row
+				order = append(order, key)
+			}
+
+			st := subtypes[key]
+			switch column {
+			case oidLldpRemChassisIDSubtype:
+				st[0] = vb.Int()
+			case oidLldpRemChassisID:
+				if st[0] == lldpChassisSubtypeMAC {
+					row.ChassisMAC = formatMAC(vb.Bytes())
+					row.ChassisID = row.ChassisMAC
+				} else {
+					row.ChassisID = string(vb.Bytes())
+				}
+			case oidLldpRemPortIDSubtype:
+				st[1] = vb.Int()
+			case oidLldpRemPortID:
+				if st[1] == lldpPortSubtypeMAC {
+					row.PortMAC = formatMAC(vb.Bytes())
+					row.PortID = row.PortMAC
+				} else {
+					row.PortID = string(vb.Bytes())
+				}
+			case oidLldpRemSysName:
+				row.SysName = string(vb.Bytes())
+			}
+			subtypes[key] = st
+		}
+	}
+
+	neighbors := make([]lldpNeighbor, 0, len(order))
+	for _, key := range order {
+		neighbors = append(neighbors, *rows[key])
+	}
+	return neighbors, nil
+}
+
+// systemWalk walks a subtree on the agent at target over UDP
+func (s *SNMPAdapter) systemWalk(ctx context.Context, target, community, root string) ([]snmpVarBind, error) {
+	client := &snmpClient{
+		address:   net.JoinHostPort(target, strconv.Itoa(s.config.Port)),
+		community: community,
+		timeout:   s.config.Timeout,
+		retries:   s.config.Retries,
+	}
+	return client.walk(ctx, root)
+}
+
+// snmpInterfaceNode builds the child node for an interface
+func snmpInterfaceNode(parentID string, iface snmpInterface, now time.Time) domain.Node {
+	status := domain.NodeStatusVerified
+	if iface.OperStatus != "up" {
+		status = domain.NodeStatusUnreachable
+	}
+
+	node := domain.Node{
+		ID:       fmt.Sprintf("%s:%s", parentID, snmpIDUnsafe.ReplaceAllString(iface.Name, "-")),
+		Type:     domain.NodeTypeInterface,
+		Label:    iface.Name,
+		ParentID: parentID,
+		Source:   "snmp",
+		Status:   status,
+		Properties: map[string]any{
+			"interface_name": iface.Name,
+			"if_index":       iface.Index,
+		},
+		Discovered: map[string]any{
+			"oper_status": iface.OperStatus,
+			"speed_bps":   iface.Speed,
+		},
+		LastVerified: &now,
+		LastSeen:     &now,
+	}
+	if iface.Descr != "" && iface.Descr != iface.Name {
+		node.Discovered["description"] = iface.Descr
+	}
+	if iface.MAC != "" {
+		node.Discovered["mac_address"] = iface.MAC
+	}
+	if iface.PVID > 0 {
+		node.Discovered[domain.LinkVLANProperty] = iface.PVID
+	}
+	return node
+}
+
+// buildMACIndex maps normalized MAC addresses to node IDs.
+// Interface nodes win over their parents so edges land on the exact port.
+func buildMACIndex(nodes []domain.Node) map[string]string {
+	index := make(map[string]string)
+	for _, node := range nodes {
+		for _, raw := range []any{node.Discovered["mac_address"], node.Properties["mac"]} {
+			s, _ := raw.(string)
+			mac := domain.NormalizeMAC(s)
+			if mac == "" {
+				continue
+			}
+			if _, taken := index[mac]; taken && node.Type != domain.NodeTypeInterface {
+				continue
+			}
+			index[mac] = node.ID
+		}
+	}
+	return index
+}
+
+// formatMAC formats raw 6-byte MAC octets, or returns "" for other lengths
+func formatMAC(b []byte) string {
+	if len(b) != 6 {
+		return ""
+	}
+	return net.HardwareAddr(b).String()
+}
+
+// oidIndex returns the single-arc index of oid under column, or -1
+func oidIndex(oid, column string) int64 {
+	suffix := strings.TrimPrefix(oid, column+".")
+	if suffix == oid || strings.Contains(suffix, ".") {
+		return -1
+	}
+	idx, err := strconv.ParseInt(suffix, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return idx
+}
@@ -0,0 +1,367 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal SNMPv2c client: just enough BER to issue GetNext requests and
+// walk a subtree. Only community-based v2c is supported.
+
+// BER/SNMP type tags
+const (
+	berInteger     byte = 0x02
+	berOctetString byte = 0x04
+	berNull        byte = 0x05
+	berOID         byte = 0x06
+	berSequence    byte = 0x30
+
+	snmpIPAddress byte = 0x40
+	snmpCounter32 byte = 0x41
+	snmpGauge32   byte = 0x42
+	snmpTimeTicks byte = 0x43
+	snmpCounter64 byte = 0x46
+
+	snmpNoSuchObject   byte = 0x80
+	snmpNoSuchInstance byte = 0x81
+	snmpEndOfMibView   byte = 0x82
+
+	snmpGetNextRequest byte = 0xa1
+	snmpGetResponse    byte = 0xa2
+
+	snmpVersion2c = 1
+)
+
+// errSNMPTimeout is returned when an agent does not answer in time
+var errSNMPTimeout = errors.New("snmp request timed out")
+
+// snmpVarBind is a single OID/value pair from a response.
+// Value is int64 for integer types, []byte for strings and addresses,
+// and a dotted string for OIDs.
+type snmpVarBind struct {
+	OID   string
+	Type  byte
+	Value any
+}
+
+// Int returns the value as an integer, or 0 for non-numeric types
+func (v snmpVarBind) Int() int64 {
+	n, _ := v.Value.(int64)
+	return n
+}
+
+// Bytes returns the raw value for string-like types
+func (v snmpVarBind) Bytes() []byte {
+	b, _ := v.Value.([]byte)
+	return b
+}
+
+// snmpClient walks MIB subtrees on a single agent
+type snmpClient struct {
+	address   string
+	community string
+	timeout   time.Duration
+	retries   int
+}
+
+// walk returns every varbind below root using repeated GetNext requests
+func (c *snmpClient) walk(ctx context.Context, root string) ([]snmpVarBind, error) {
+	conn, err := net.Dial("udp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", c.address, err)
+	}
+	defer conn.Close()
+
+	var results []snmpVarBind
+	current := root
+	prefix := root + "."
+	for {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		vb, err := c.getNext(conn, current)
+		if err != nil {
+			return results, err
+		}
+		if vb.Type == snmpEndOfMibView || !strings.HasPrefix(vb.OID, prefix) {
+			return results, nil
+		}
+		if vb.OID == current {
+			return results, fmt.Errorf("agent returned non-increasing OID %s", vb.OID)
+		}
+		results = append(results, vb)
+		current = vb.OID
+	}
+}
+
+// getNext sends a single GetNext request, retrying on timeout
+func (c *snmpClient) getNext(conn net.Conn, oid string) (snmpVarBind, error) {
+	requestID := rand.Int31()
+	packet, err := encodeSNMPRequest(snmpGetNextRequest, c.community, requestID, oid)
+	if err != nil {
+		return snmpVarBind{}, err
+	}
+
+	buf := make([]byte, 65535)
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if _, err := conn.Write(packet); err != nil {
+			return snmpVarBind{}, fmt.Errorf("send request: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(c.timeout))
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() {
+					break // retry
+				}
+				return snmpVarBind{}, fmt.Errorf("read response: %w", err)
+			}
+
+			id, vbs, err := decodeSNMPResponse(buf[:n])
+			if err != nil {
+				return snmpVarBind{}, err
+			}
+			if id != requestID {
+				continue // stale response from an earlier retry
+			}
+			if len(vbs) == 0 {
+				return snmpVarBind{}, fmt.Errorf("empty response for %s", oid)
+			}
+			return vbs[0], nil
+		}
+	}
+
+	return snmpVarBind{}, errSNMPTimeout
+}
+
+// encodeSNMPRequest builds a v2c request PDU for a single OID
+func encodeSNMPRequest(pduType byte, community string, requestID int32, oid string) ([]byte, error) {
+	oidBytes, err := encodeOID(oid)
+	if err != nil {
+		return nil, err
+	}
+
+	varBind := berTLV(berSequence, append(berTLV(berOID, oidBytes), berTLV(berNull, nil)...))
+	varBinds := berTLV(berSequence, varBind)
+
+	pdu := berTLV(berInteger, encodeBERInt(int64(requestID)))
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...) // error-status
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...) // error-index
+	pdu = append(pdu, varBinds...)
+
+	msg := berTLV(berInteger, encodeBERInt(snmpVersion2c))
+	msg = append(msg, berTLV(berOctetString, []byte(community))...)
+	msg = append(msg, berTLV(pduType, pdu)...)
+	return berTLV(berSequence, msg), nil
+}
+
+// decodeSNMPResponse parses a GetResponse and returns its request ID and varbinds
+func decodeSNMPResponse(data []byte) (int32, []snmpVarBind, error) {
+	tag, msg, _, err := berRead(data)
+	if err != nil || tag != berSequence {
+		return 0, nil, fmt.Errorf("malformed SNMP message")
+	}
+
+	// version, community
+	for i := 0; i < 2; i++ {
+		if _, _, msg, err = berRead(msg); err != nil {
+			return 0, nil, fmt.Errorf("malformed SNMP header: %w", err)
+		}
+	}
+
+	tag, pdu, _, err := berRead(msg)
+	if err != nil || tag != snmpGetResponse {
+		return 0, nil, fmt.Errorf("unexpected PDU type 0x%02x", tag)
+	}
+
+	var fields [3]int64
+	for i := range fields {
+		var content []byte
+		if tag, content, pdu, err = berRead(pdu); err != nil || tag != berInteger {
+			return 0, nil, fmt.Errorf("malformed PDU header")
+		}
+		fields[i] = decodeBERInt(content)
+	}
+	if fields[1] != 0 {
+		return int32(fields[0]), nil, fmt.Errorf("agent returned error-status %d", fields[1])
+	}
+
+	tag, list, _, err := berRead(pdu)
+	if err != nil || tag != berSequence {
+		return 0, nil, fmt.Errorf("malformed varbind list")
+	}
+
+	var vbs []snmpVarBind
+	for len(list) > 0 {
+		var entry []byte
+		if tag, entry, list, err = berRead(list); err != nil || tag != berSequence {
+			return 0, nil, fmt.Errorf("malformed varbind")
+		}
+
+		tag, oidBytes, rest, err := berRead(entry)
+		if err != nil || tag != berOID {
+			return 0, nil, fmt.Errorf("malformed varbind OID")
+		}
+		valueTag, value, _, err := berRead(rest)
+		if err != nil {
+			return 0, nil, fmt.Errorf("malformed varbind value: %w", err)
+		}
+
+		vb := snmpVarBind{OID: decodeOID(oidBytes), Type: valueTag}
+		switch valueTag {
+		case berInteger:
+			vb.Value = decodeBERInt(value)
+		case snmpCounter32, snmpGauge32, snmpTimeTicks, snmpCounter64:
+			vb.Value = decodeBERUint(value)
+		case berOID:
+			vb.Value = decodeOID(value)
+		case berOctetString, snmpIPAddress:
+			vb.Value = value
+		}
+		vbs = append(vbs, vb)
+	}
+
+	return int32(fields[0]), vbs, nil
+}
+
+// berTLV encodes a tag-length-value triple
+func berTLV(tag byte, content []byte) []byte {
+	out := []byte{tag}
+	n := len(content)
+	if n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var lenBytes []byte
+		for n > 0 {
+			lenBytes = append([]byte{byte(n)}, lenBytes...)
+			n >>= 8
+		}
+		out = append(out, 0x80|byte(len(lenBytes)))
+		out = append(out, lenBytes...)
+	}
+	return append(out, content...)
+}
+
+// berRead splits the first TLV off data, returning its tag, content and the remainder
+func berRead(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated BER element")
+	}
+	tag := data[0]
+	length := int(data[1])
+	offset := 2
+	if length&0x80 != 0 {
+		n := length & 0x7f
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return 0, nil, nil, fmt.Errorf("invalid BER length")
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		offset += n
+	}
+	if length < 0 || len(data) < offset+length {
+		return 0, nil, nil, fmt.Errorf("truncated BER element")
+	}
+	return tag, data[offset : offset+length], data[offset+length:], nil
+}
+
+// encodeBERInt encodes a signed integer in minimal two's complement form
+func encodeBERInt(v int64) []byte {
+	out := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		out = append([]byte{byte(v)}, out...)
+	}
+	return out
+}
+
+// decodeBERInt decodes a signed two's complement integer
+func decodeBERInt(b []byte) int64 {
+	var v int64
+	for i, c := range b {
+		if i == 0 && c&0x80 != 0 {
+			v = -1
+		}
+		v = v<<8 | int64(c)
+	}
+	return v
+}
+
+// decodeBERUint decodes an unsigned integer (counters, gauges, timeticks)
+func decodeBERUint(b []byte) int64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return int64(v)
+}
+
+// encodeOID encodes a dotted OID string
+func encodeOID(oid string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("invalid OID %q", oid)
+	}
+	arcs := make([]uint64, len(parts))
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid OID %q", oid)
+		}
+		arcs[i] = n
+	}
+
+	out := encodeBase128(arcs[0]*40 + arcs[1])
+	for _, arc := range arcs[2:] {
+		out = append(out, encodeBase128(arc)...)
+	}
+	return out, nil
+}
+
+// encodeBase128 encodes an OID arc with continuation bits
+func encodeBase128(v uint64) []byte {
+	out := []byte{byte(v & 0x7f)}
+	for v >>= 7; v > 0; v >>= 7 {
+		out = append([]byte{byte(v&0x7f) | 0x80}, out...)
+	}
+	return out
+}
+
+// decodeOID decodes BER OID content into dotted form
+func decodeOID(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var arcs []string
+	var v uint64
+	first := true
+	for _, c := range b {
+		v = v<<7 | uint64(c&0x7f)
+		if c&0x80 != 0 {
+			continue
+		}
+		if first {
+			x := v / 40
+			if x > 2 {
+				x = 2
+			}
+			arcs = append(arcs, strconv.FormatUint(x, 10), strconv.FormatUint(v-40*x, 10))
+			first = false
+		} else {
+			arcs = append(arcs, strconv.FormatUint(v, 10))
+		}
+		v = 0
+	}
+	return strings.Join(arcs, ".")
+}
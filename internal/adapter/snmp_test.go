@@ -0,0 +1,185 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+// fakeNodeLister serves a fixed node list
+type fakeNodeLister struct {
+	nodes []domain.Node
+}
+
+func (f *fakeNodeLister) ListNodes(ctx context.Context, nodeType, source string) ([]domain.Node, error) {
+	return f.nodes, nil
+}
+
+func TestOIDRoundTrip(t *testing.T) {
+	for _, oid := range []string{"1.3.6.1.2.1.2.2.1.2", "1.0.8802.1.1.2.1.4.1.1.5", "1.3.6.1.4.1.311.21.2"} {
+		b, err := encodeOID(oid)
+		if err != nil {
+			t.Fatalf("encodeOID(%s) error = %v", oid, err)
+		}
+		if got := decodeOID(b); got != oid {
+			t.Errorf("round trip of %s = %s", oid, got)
+		}
+	}
+
+	if _, err := encodeOID("not.an.oid"); err == nil {
+		t.Error("expected error for invalid OID")
+	}
+}
+
+func TestDecodeSNMPResponse(t *testing.T) {
+	oid, _ := encodeOID("1.3.6.1.2.1.2.2.1.2.3")
+	varBind := berTLV(berSequence, append(berTLV(berOID, oid), berTLV(berOctetString, []byte("GigabitEthernet0/3"))...))
+
+	pdu := berTLV(berInteger, encodeBERInt(300))
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...)
+	pdu = append(pdu, berTLV(berInteger, encodeBERInt(0))...)
+	pdu = append(pdu, berTLV(berSequence, varBind)...)
+
+	msg := berTLV(berInteger, encodeBERInt(snmpVersion2c))
+	msg = append(msg, berTLV(berOctetString, []byte("public"))...)
+	msg = append(msg, berTLV(snmpGetResponse, pdu)...)
+
+	id, vbs, err := decodeSNMPResponse(berTLV(berSequence, msg))
+	if err != nil {
+		t.Fatalf("decodeSNMPResponse() error = %v", err)
+	}
+	if id != 300 {
+		t.Errorf("request id = %d, want 300", id)
+	}
+	if len(vbs) != 1 || vbs[0].OID != "1.3.6.1.2.1.2.2.1.2.3" || string(vbs[0].Bytes()) != "GigabitEthernet0/3" {
+		t.Errorf("varbinds = %+v", vbs)
+	}
+
+	if _, _, err := decodeSNMPResponse([]byte{0x30, 0x05, 0x02}); err == nil {
+		t.Error("expected error for truncated message")
+	}
+}
+
+func TestBERIntRoundTrip(t *testing.T) {
+	for _, v := range []int64{0, 1, 127, 128, 255, 256, -1, -128, -129, 2147483647} {
+		if got := decodeBERInt(encodeBERInt(v)); got != v {
+			t.Errorf("round trip of %d = %d", v, got)
+		}
+	}
+}
+
+func newTestSNMPAdapter(nodes []domain.Node) *SNMPAdapter {
+	resolver := &fakeSecretResolver{
+		secrets: []*domain.Secret{
+			{ID: "snmp.lab", Type: domain.SecretTypeSNMPCommunity, Data: map[string]string{"community": "lab"}},
+		},
+	}
+	cfg := DefaultSNMPConfig()
+	cfg.Capabilities = NewCapabilityManager(resolver)
+	return NewSNMPAdapter(&fakeNodeLister{nodes: nodes}, cfg)
+}
+
+func TestSNMPSyncBuildsInterfacesAndNeighbors(t *testing.T) {
+	sw := *domain.NewNode("core-sw", domain.NodeTypeSwitch, "core-sw")
+	sw.SetProperty("ip", "10.0.0.2")
+	nas := *domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	nas.SetDiscovered("mac_address", "AA:BB:CC:00:00:01")
+
+	s := newTestSNMPAdapter([]domain.Node{sw, nas})
+	str := func(oid, v string) snmpVarBind { return snmpVarBind{OID: oid, Type: berOctetString, Value: []byte(v)} }
+	num := func(oid string, v int64) snmpVarBind { return snmpVarBind{OID: oid, Type: berInteger, Value: v} }
+	s.walk = func(ctx context.Context, target, community, root string) ([]snmpVarBind, error) {
+		if target != "10.0.0.2" || community != "lab" {
+			t.Errorf("walk(%s, %s), want 10.0.0.2 with community lab", target, community)
+		}
+		switch root {
+		case oidIfDescr:
+			return []snmpVarBind{str(root+".1", "lo"), str(root+".2", "GigabitEthernet0/1")}, nil
+		case oidIfType:
+			return []snmpVarBind{num(root+".1", ifTypeSoftwareLoopback), num(root+".2", 6)}, nil
+		case oidIfOperStatus:
+			return []snmpVarBind{num(root+".1", 1), num(root+".2", 1)}, nil
+		case oidIfSpeed:
+			return []snmpVarBind{{OID: root + ".2", Type: snmpGauge32, Value: int64(1_000_000_000)}}, nil
+		case oidIfName:
+			return []snmpVarBind{str(root+".2", "Gi0/1")}, nil
+		case oidLldpRemChassisIDSubtype:
+			return []snmpVarBind{num(root+".0.2.1", lldpChassisSubtypeMAC)}, nil
+		case oidLldpRemChassisID:
+			return []snmpVarBind{{OID: root + ".0.2.1", Type: berOctetString, Value: []byte{0xaa, 0xbb, 0xcc, 0, 0, 1}}}, nil
+		case oidLldpRemSysName:
+			return []snmpVarBind{str(root+".0.2.1", "nas")}, nil
+		}
+		return nil, nil
+	}
+
+	var progress int
+	s.SetEventPublisher(publisherFunc(func(eventType string, payload interface{}) { progress++ }))
+
+	fragment, err := s.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+
+	// One interface (loopback skipped) plus the updated switch
+	if len(fragment.Nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2: %+v", len(fragment.Nodes), fragment.Nodes)
+	}
+	iface := fragment.Nodes[0]
+	if iface.ID != "core-sw:Gi0-1" || iface.ParentID != "core-sw" || iface.Type != domain.NodeTypeInterface || iface.Label != "Gi0/1" {
+		t.Errorf("interface node = %s (%s, parent %s, label %s)", iface.ID, iface.Type, iface.ParentID, iface.Label)
+	}
+	if progress != 1 {
+		t.Errorf("got %d progress events, want 1 per interface", progress)
+	}
+
+	if len(fragment.Edges) != 1 {
+		t.Fatalf("got %d edges, want 1", len(fragment.Edges))
+	}
+	if e := fragment.Edges[0]; e.FromID != "core-sw:Gi0-1" || e.ToID != "nas" {
+		t.Errorf("edge = %s -> %s, want core-sw:Gi0-1 -> nas", e.FromID, e.ToID)
+	}
+	if speed, _ := fragment.Edges[0].SpeedMbps(); speed != 1000 {
+		t.Errorf("edge speed_mbps = %d, want 1000 from ifSpeed", speed)
+	}
+
+	device := fragment.Nodes[1]
+	if device.Discovered["snmp_interfaces"] != 1 {
+		t.Errorf("snmp_interfaces = %v, want 1", device.Discovered["snmp_interfaces"])
+	}
+}
+
+func TestSNMPSyncTimeoutMarksDegraded(t *testing.T) {
+	router := *domain.NewNode("gw", domain.NodeTypeRouter, "gw")
+	router.SetProperty("ip", "10.0.0.1")
+	router.Status = domain.NodeStatusVerified
+	router.SetDiscovered("reverse_dns", "gw.lan")
+
+	s := newTestSNMPAdapter([]domain.Node{router})
+	s.walk = func(ctx context.Context, target, community, root string) ([]snmpVarBind, error) {
+		return nil, errSNMPTimeout
+	}
+
+	fragment, err := s.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() should not fail on agent timeout: %v", err)
+	}
+	if len(fragment.Nodes) != 1 {
+		t.Fatalf("got %d nodes, want 1", len(fragment.Nodes))
+	}
+	node := fragment.Nodes[0]
+	if node.Status != domain.NodeStatusDegraded {
+		t.Errorf("status = %s, want degraded", node.Status)
+	}
+	if node.Discovered["reverse_dns"] != "gw.lan" {
+		t.Error("existing discoveries should be preserved")
+	}
+}
+
+// publisherFunc adapts a function to EventPublisher
+type publisherFunc func(eventType string, payload interface{})
+
+func (f publisherFunc) PublishDiscoveryEvent(eventType string, payload interface{}) {
+	f(eventType, payload)
+}
@@ -7,9 +7,26 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/crypto/ssh"
 	"specularium/internal/domain"
 )
 
+// Discovered properties describing a node's SSH host key
+const (
+	// SSHHostKeyProperty holds the SHA256 fingerprint seen on the last probe.
+	// Setting it as truth pins the expected key.
+	SSHHostKeyProperty = "ssh_host_key"
+	// SSHHostKeyFirstSeenProperty holds the first fingerprint ever seen
+	SSHHostKeyFirstSeenProperty = "ssh_host_key_first_seen"
+	// SSHHostKeyChangedProperty is true when the last fingerprint differs
+	// from the pinned truth or, without truth, from the first one seen
+	SSHHostKeyChangedProperty = "ssh_host_key_changed"
+)
+
+// SSHSecretProperty names the secret to try first when probing a node,
+// taking precedence over SSHProbeConfig.SecretName
+const SSHSecretProperty = "ssh_secret"
+
 // SSHProbeAdapter performs SSH-based fact gathering on discovered hosts
 // It uses stored SSH credentials to connect and run lightweight commands
 type SSHProbeAdapter struct {
@@ -18,8 +35,12 @@ type SSHProbeAdapter struct {
 	interval  time.Duration
 	timeout   time.Duration
 	commands  []FactCommand
-	mu        sync.Mutex
-	running   bool
+	// trackHostKeys compares host key fingerprints across probes
+	trackHostKeys bool
+	// secretName is the secret tried first unless a node names its own
+	secretName string
+	mu         sync.Mutex
+	running    bool
 }
 
 // SSHProbeConfig holds configuration for the SSH probe adapter
@@ -34,6 +55,11 @@ type SSHProbeConfig struct {
 	MaxConcurrent int
 	// Commands to run for fact gathering
 	Commands []FactCommand
+	// TrackHostKeys flags nodes whose host key fingerprint changes between
+	// probes (a reinstalled or replaced host, or a man in the middle)
+	TrackHostKeys bool
+	// SecretName is the SSH secret tried before the others on every host
+	SecretName string
 }
 
 // DefaultSSHProbeConfig returns sensible defaults
@@ -44,6 +70,7 @@ func DefaultSSHProbeConfig() SSHProbeConfig {
 		CommandTimeout:    30 * time.Second,
 		MaxConcurrent:     5,
 		Commands:          DefaultFactCommands,
+		TrackHostKeys:     true,
 	}
 }
 
@@ -66,10 +93,12 @@ func NewSSHProbeAdapter(secrets SecretResolver, config SSHProbeConfig) *SSHProbe
 	}
 
 	return &SSHProbeAdapter{
-		secrets:  secrets,
-		interval: config.Interval,
-		timeout:  config.ConnectionTimeout,
-		commands: config.Commands,
+		secrets:       secrets,
+		interval:      config.Interval,
+		timeout:       config.ConnectionTimeout,
+		commands:      config.Commands,
+		trackHostKeys: config.TrackHostKeys,
+		secretName:    config.SecretName,
 	}
 }
 
@@ -136,8 +165,6 @@ func (s *SSHProbeAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 // ProbeNode probes a single node via SSH and returns gathered evidence
 // This is the main entry point for SSH probing a specific node
 func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*domain.GraphFragment, error) {
-	fragment := domain.NewGraphFragment()
-
 	// Check if node has port 22 open
 	openPorts, ok := node.GetDiscovered("open_ports")
 	if !ok {
@@ -167,6 +194,40 @@ func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*dom
 		return nil, nil
 	}
 
+	return s.probeHost(ctx, node, ip, 22)
+}
+
+// preferSecret moves the secret named for node, or failing that the one
+// named in the config, to the front of secrets
+func (s *SSHProbeAdapter) preferSecret(ctx context.Context, node domain.Node, secrets []*domain.Secret) []*domain.Secret {
+	name := node.GetPropertyString(SSHSecretProperty)
+	if name == "" {
+		name = s.secretName
+	}
+	if name == "" {
+		return secrets
+	}
+
+	named, err := s.secrets.GetByName(ctx, name)
+	if err != nil || named == nil {
+		log.Printf("SSH probe: Secret %q for %s not usable (%v), trying all secrets", name, node.ID, err)
+		return secrets
+	}
+	for i, secret := range secrets {
+		if secret.ID == named.ID {
+			ordered := append([]*domain.Secret{secret}, secrets[:i]...)
+			return append(ordered, secrets[i+1:]...)
+		}
+	}
+	log.Printf("SSH probe: Secret %q for %s is not an SSH secret, trying all secrets", name, node.ID)
+	return secrets
+}
+
+// probeHost connects to ip:port with each SSH secret in turn and returns
+// the node updated with the facts gathered by the first that works
+func (s *SSHProbeAdapter) probeHost(ctx context.Context, node domain.Node, ip string, port int) (*domain.GraphFragment, error) {
+	fragment := domain.NewGraphFragment()
+
 	// Get SSH credentials
 	sshSecrets, err := s.getSSHSecrets(ctx)
 	if err != nil {
@@ -177,6 +238,7 @@ func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*dom
 		log.Printf("SSH probe: No SSH secrets configured")
 		return nil, nil
 	}
+	sshSecrets = s.preferSecret(ctx, node, sshSecrets)
 
 	// Try each SSH credential until one works
 	var lastErr error
@@ -184,7 +246,7 @@ func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*dom
 		log.Printf("SSH probe: Attempting connection to %s (%s) with secret %s",
 			node.ID, ip, secret.ID)
 
-		evidence, capabilities, err := s.probeWithSecret(ctx, ip, secret)
+		evidence, capabilities, err := s.probeWithSecret(ctx, ip, port, secret)
 		if err != nil {
 			log.Printf("SSH probe: Failed to connect to %s with secret %s: %v",
 				ip, secret.ID, err)
@@ -215,6 +277,11 @@ func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*dom
 			updatedNode.Discovered["capabilities"] = capabilities
 		}
 
+		if s.trackHostKeys && trackHostKey(&updatedNode) {
+			log.Printf("SSH probe: WARNING host key for %s (%s) changed to %v (was %v)",
+				node.ID, ip, updatedNode.Discovered[SSHHostKeyProperty], expectedHostKey(&updatedNode))
+		}
+
 		fragment.AddNode(updatedNode)
 
 		log.Printf("SSH probe: Successfully gathered %d facts and %d capabilities from %s",
@@ -242,9 +309,9 @@ func (s *SSHProbeAdapter) ProbeNode(ctx context.Context, node domain.Node) (*dom
 }
 
 // probeWithSecret attempts to connect and gather facts using a specific secret
-func (s *SSHProbeAdapter) probeWithSecret(ctx context.Context, ip string, secret *domain.Secret) ([]domain.Evidence, []domain.Capability, error) {
+func (s *SSHProbeAdapter) probeWithSecret(ctx context.Context, ip string, port int, secret *domain.Secret) ([]domain.Evidence, []domain.Capability, error) {
 	// Connect to host
-	client, err := s.connect(ctx, ip, 22, secret)
+	client, hostKey, err := s.connect(ctx, ip, port, secret)
 	if err != nil {
 		return nil, nil, fmt.Errorf("connection failed: %w", err)
 	}
@@ -254,6 +321,22 @@ func (s *SSHProbeAdapter) probeWithSecret(ctx context.Context, ip string, secret
 	evidence := []domain.Evidence{}
 	capabilities := []domain.Capability{}
 
+	// Record the host key as identity evidence
+	if hostKey != nil {
+		evidence = append(evidence, domain.Evidence{
+			ID:         fmt.Sprintf("%s-%s-%d", ip, SSHHostKeyProperty, now.Unix()),
+			Source:     domain.EvidenceSourceSSHProbe,
+			Property:   SSHHostKeyProperty,
+			Value:      ssh.FingerprintSHA256(hostKey),
+			Confidence: 1.0,
+			ObservedAt: now,
+			SecretRef:  secret.ID,
+			Raw: map[string]any{
+				"key_type": hostKey.Type(),
+			},
+		})
+	}
+
 	// Run fact commands
 	for _, factCmd := range s.commands {
 		output, err := s.runCommand(client, factCmd.Command)
@@ -297,7 +380,7 @@ func (s *SSHProbeAdapter) probeWithSecret(ctx context.Context, ip string, secret
 		Confidence: 1.0,
 		Status:     "confirmed",
 		Properties: map[string]any{
-			"port":      22,
+			"port":       port,
 			"accessible": true,
 			"secret_ref": secret.ID,
 		},
@@ -316,6 +399,36 @@ func (s *SSHProbeAdapter) probeWithSecret(ctx context.Context, ip string, secret
 	return evidence, capabilities, nil
 }
 
+// trackHostKey compares the node's freshly discovered host key fingerprint
+// with the one expected for it and records the outcome in
+// ssh_host_key_changed. The first fingerprint seen is kept in
+// ssh_host_key_first_seen. Returns true when the key changed.
+func trackHostKey(node *domain.Node) bool {
+	current, ok := node.Discovered[SSHHostKeyProperty].(string)
+	if !ok || current == "" {
+		return false
+	}
+	if _, ok := node.Discovered[SSHHostKeyFirstSeenProperty]; !ok {
+		node.Discovered[SSHHostKeyFirstSeenProperty] = current
+	}
+
+	changed := expectedHostKey(node) != current
+	node.Discovered[SSHHostKeyChangedProperty] = changed
+	return changed
+}
+
+// expectedHostKey returns the fingerprint a node should present: the
+// operator's ssh_host_key truth if set, otherwise the first one seen
+func expectedHostKey(node *domain.Node) string {
+	if node.Truth.HasProperty(SSHHostKeyProperty) {
+		if fp, ok := node.Truth.Properties[SSHHostKeyProperty].(string); ok {
+			return fp
+		}
+	}
+	fp, _ := node.Discovered[SSHHostKeyFirstSeenProperty].(string)
+	return fp
+}
+
 // detectCapabilities analyzes evidence to detect node capabilities
 func (s *SSHProbeAdapter) detectCapabilities(evidence []domain.Evidence, secretRef string, now time.Time) []domain.Capability {
 	capabilities := []domain.Capability{}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strconv"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -11,19 +12,28 @@ import (
 )
 
 // connect establishes an SSH connection using the provided secret
-// Supports both key-based and password authentication
-func (s *SSHProbeAdapter) connect(ctx context.Context, host string, port int, secret *domain.Secret) (*ssh.Client, error) {
+// Supports both key-based and password authentication. The host key the
+// server presented is returned rather than verified; callers decide what
+// to do with it.
+func (s *SSHProbeAdapter) connect(ctx context.Context, host string, port int, secret *domain.Secret) (*ssh.Client, ssh.PublicKey, error) {
 	// Build SSH client config based on secret type
 	config, err := s.buildSSHConfig(secret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build SSH config: %w", err)
+		return nil, nil, fmt.Errorf("failed to build SSH config: %w", err)
 	}
 
 	// Apply timeout
 	config.Timeout = s.timeout
 
+	// Capture the host key during the handshake
+	var hostKey ssh.PublicKey
+	config.HostKeyCallback = func(_ string, _ net.Addr, key ssh.PublicKey) error {
+		hostKey = key
+		return nil
+	}
+
 	// Create address
-	addr := fmt.Sprintf("%s:%d", host, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
 
 	// Create dialer with context support
 	dialer := &net.Dialer{
@@ -33,20 +43,20 @@ func (s *SSHProbeAdapter) connect(ctx context.Context, host string, port int, se
 	// Dial with context
 	conn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to dial: %w", err)
+		return nil, nil, fmt.Errorf("failed to dial: %w", err)
 	}
 
 	// Create SSH connection from net.Conn
 	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
 	if err != nil {
 		conn.Close()
-		return nil, fmt.Errorf("failed to establish SSH connection: %w", err)
+		return nil, nil, fmt.Errorf("failed to establish SSH connection: %w", err)
 	}
 
 	// Create SSH client
 	client := ssh.NewClient(sshConn, chans, reqs)
 
-	return client, nil
+	return client, hostKey, nil
 }
 
 // buildSSHConfig creates an SSH client config from a secret
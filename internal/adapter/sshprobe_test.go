@@ -1,7 +1,16 @@
 package adapter
 
 import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"strconv"
 	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"specularium/internal/domain"
 )
 
 // TestParseOSRelease tests parsing of /etc/os-release output
@@ -464,3 +473,118 @@ func TestEvidenceGeneration(t *testing.T) {
 		t.Error("Evidence should have a secret reference")
 	}
 }
+
+// fakeSSHServer accepts password logins for probe/hunter2 on a local port,
+// presenting hostKey, and refuses every session
+func fakeSSHServer(t *testing.T, hostKey ssh.Signer) (string, int) {
+	t.Helper()
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pass []byte) (*ssh.Permissions, error) {
+			if c.User() == "probe" && string(pass) == "hunter2" {
+				return nil, nil
+			}
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				_, chans, reqs, err := ssh.NewServerConn(conn, config)
+				if err != nil {
+					conn.Close()
+					return
+				}
+				go ssh.DiscardRequests(reqs)
+				for ch := range chans {
+					ch.Reject(ssh.Prohibited, "no sessions")
+				}
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return host, p
+}
+
+func newHostKey(t *testing.T) ssh.Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatalf("signer: %v", err)
+	}
+	return signer
+}
+
+// TestSSHProbeHostKey checks fingerprint collection and change detection
+func TestSSHProbeHostKey(t *testing.T) {
+	ctx := context.Background()
+	secret := &domain.Secret{
+		ID:   "ssh.lab",
+		Type: domain.SecretTypeSSHPassword,
+		Data: map[string]string{"username": "probe", "password": "hunter2"},
+	}
+	probe := NewSSHProbeAdapter(&fakeSecretResolver{secrets: []*domain.Secret{secret}}, SSHProbeConfig{
+		ConnectionTimeout: 2 * time.Second,
+		TrackHostKeys:     true,
+	})
+
+	probeOnce := func(node domain.Node, key ssh.Signer) domain.Node {
+		t.Helper()
+		host, port := fakeSSHServer(t, key)
+		fragment, err := probe.probeHost(ctx, node, host, port)
+		if err != nil || fragment == nil || len(fragment.Nodes) != 1 {
+			t.Fatalf("probeHost = %v, %v; want one node", fragment, err)
+		}
+		return fragment.Nodes[0]
+	}
+
+	original, replacement := newHostKey(t), newHostKey(t)
+	originalFP := ssh.FingerprintSHA256(original.PublicKey())
+	replacementFP := ssh.FingerprintSHA256(replacement.PublicKey())
+
+	node := probeOnce(*domain.NewNode("lab", domain.NodeTypeServer, "lab"), original)
+	if got := node.Discovered[SSHHostKeyProperty]; got != originalFP {
+		t.Errorf("ssh_host_key = %v, want %s", got, originalFP)
+	}
+	if got := node.Discovered[SSHHostKeyFirstSeenProperty]; got != originalFP {
+		t.Errorf("ssh_host_key_first_seen = %v, want %s", got, originalFP)
+	}
+	if node.Discovered[SSHHostKeyChangedProperty] != false {
+		t.Error("first probe should not report a changed key")
+	}
+
+	node = probeOnce(node, replacement)
+	if got := node.Discovered[SSHHostKeyProperty]; got != replacementFP {
+		t.Errorf("ssh_host_key = %v, want %s", got, replacementFP)
+	}
+	if got := node.Discovered[SSHHostKeyFirstSeenProperty]; got != originalFP {
+		t.Errorf("ssh_host_key_first_seen = %v, want it kept at %s", got, originalFP)
+	}
+	if node.Discovered[SSHHostKeyChangedProperty] != true {
+		t.Error("changed host key was not flagged")
+	}
+
+	// Pinning the new key as truth accepts it
+	node.Truth = &domain.NodeTruth{Properties: map[string]any{SSHHostKeyProperty: replacementFP}}
+	node = probeOnce(node, replacement)
+	if node.Discovered[SSHHostKeyChangedProperty] != false {
+		t.Error("key matching ssh_host_key truth should not be flagged")
+	}
+}
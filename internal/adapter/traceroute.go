@@ -0,0 +1,489 @@
+package adapter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// TracerouteConfig holds configuration for traceroute-based path discovery
+type TracerouteConfig struct {
+	// MaxHops bounds each trace
+	MaxHops int
+	// Timeout bounds a single trace
+	Timeout time.Duration
+	// TargetsPerSubnet is how many representative hosts are traced per subnet
+	TargetsPerSubnet int
+	// OriginID is the node paths start from (this Specularium instance)
+	OriginID string
+	// Targets are node IDs traced on each sync. Empty traces every node
+	// with an IP, up to MaxTargets.
+	Targets []string
+	// MaxTargets caps how many nodes a sync traces
+	MaxTargets int
+}
+
+// DefaultTracerouteConfig returns sensible defaults
+func DefaultTracerouteConfig() TracerouteConfig {
+	return TracerouteConfig{
+		MaxHops:          15,
+		Timeout:          30 * time.Second,
+		TargetsPerSubnet: 2,
+		OriginID:         "specularium",
+		MaxTargets:       32,
+	}
+}
+
+// errTracerouteUnavailable is returned when neither traceroute nor mtr is installed
+var errTracerouteUnavailable = errors.New("traceroute and mtr binaries not found")
+
+// TracerouteAdapter discovers the routed path between this host and the
+// rest of the network. As a polling adapter it traces known nodes; the
+// scanner also uses it to trace representative hosts of scanned subnets.
+type TracerouteAdapter struct {
+	config    TracerouteConfig
+	nodes     NodeLister
+	publisher EventPublisher
+	mu        sync.Mutex
+	running   bool
+
+	// trace runs a single traceroute and returns hop IPs ("" for silent hops)
+	trace func(ctx context.Context, target string) ([]string, error)
+}
+
+// NewTracerouteAdapter creates a new traceroute adapter using the system traceroute
+func NewTracerouteAdapter(config TracerouteConfig) *TracerouteAdapter {
+	t := &TracerouteAdapter{config: config}
+	t.trace = t.systemTraceroute
+	return t
+}
+
+// SetNodeLister sets the source of nodes traced by Sync
+func (t *TracerouteAdapter) SetNodeLister(nodes NodeLister) {
+	t.nodes = nodes
+}
+
+// SetEventPublisher sets the event publisher for progress updates
+func (t *TracerouteAdapter) SetEventPublisher(pub EventPublisher) {
+	t.publisher = pub
+}
+
+// publishProgress emits a discovery-progress event
+func (t *TracerouteAdapter) publishProgress(payload interface{}) {
+	if t.publisher != nil {
+		t.publisher.PublishDiscoveryEvent("discovery-progress", payload)
+	}
+}
+
+// Name returns the adapter identifier
+func (t *TracerouteAdapter) Name() string {
+	return "traceroute"
+}
+
+// Type returns the adapter type
+func (t *TracerouteAdapter) Type() AdapterType {
+	return AdapterTypePolling
+}
+
+// Priority returns the adapter priority
+func (t *TracerouteAdapter) Priority() int {
+	return 60 // Observed paths, but hops may hide behind silent routers
+}
+
+// Start initializes the adapter
+func (t *TracerouteAdapter) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = true
+	log.Printf("Traceroute adapter started (max hops=%d, max targets=%d)", t.config.MaxHops, t.config.MaxTargets)
+	return nil
+}
+
+// Stop shuts down the adapter
+func (t *TracerouteAdapter) Stop() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running = false
+	log.Printf("Traceroute adapter stopped")
+	return nil
+}
+
+// Available reports whether traceroute or mtr is installed
+func (t *TracerouteAdapter) Available() bool {
+	for _, bin := range []string{"traceroute", "mtr"} {
+		if _, err := exec.LookPath(bin); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Sync traces the path to each target node and returns the intermediate
+// hops as router nodes with ordered route edges. Hops that match a known
+// node's IP reuse that node instead of creating a new one.
+func (t *TracerouteAdapter) Sync(ctx context.Context) (*domain.GraphFragment, error) {
+	fragment := domain.NewGraphFragment()
+	if t.nodes == nil {
+		return fragment, nil
+	}
+
+	nodes, err := t.nodes.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+	known := buildIPIndex(nodes)
+	targets := t.selectTargets(nodes)
+
+	now := time.Now()
+	seenNodes := make(map[string]bool)
+	seenEdges := make(map[string]bool)
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			return fragment, ctx.Err()
+		}
+
+		ip := target.GetPropertyString("ip")
+		hops, err := t.trace(ctx, ip)
+		if errors.Is(err, errTracerouteUnavailable) {
+			log.Printf("Traceroute: %v, skipping path discovery", err)
+			return fragment, nil
+		}
+		if err != nil {
+			log.Printf("Traceroute to %s (%s) failed: %v", target.ID, ip, err)
+			continue
+		}
+
+		for i, hop := range hops {
+			message := fmt.Sprintf("Traceroute %s: hop %d no reply", target.Label, i+1)
+			if hop != "" {
+				message = fmt.Sprintf("Traceroute %s: hop %d is %s", target.Label, i+1, hop)
+			}
+			t.publishProgress(map[string]interface{}{
+				"node_id": target.ID,
+				"hop":     i + 1,
+				"ip":      hop,
+				"message": message,
+				"phase":   "traceroute",
+			})
+		}
+
+		pathNodes, pathEdges := buildRoutePath(t.config.OriginID, ip, hops, known, now)
+		for _, n := range pathNodes {
+			if !seenNodes[n.ID] {
+				seenNodes[n.ID] = true
+				fragment.AddNode(n)
+			}
+		}
+		for _, e := range pathEdges {
+			if !seenEdges[e.ID] {
+				seenEdges[e.ID] = true
+				fragment.AddEdge(e)
+			}
+		}
+	}
+
+	return fragment, nil
+}
+
+// selectTargets returns the configured target nodes, or every top-level
+// node with an IP other than the origin, capped at MaxTargets
+func (t *TracerouteAdapter) selectTargets(nodes []domain.Node) []domain.Node {
+	wanted := make(map[string]bool, len(t.config.Targets))
+	for _, id := range t.config.Targets {
+		wanted[id] = true
+	}
+
+	var targets []domain.Node
+	for _, node := range nodes {
+		if node.GetPropertyString("ip") == "" || node.ID == t.config.OriginID {
+			continue
+		}
+		if len(wanted) > 0 {
+			if !wanted[node.ID] {
+				continue
+			}
+		} else if node.ParentID != "" {
+			continue
+		}
+		targets = append(targets, node)
+		if t.config.MaxTargets > 0 && len(targets) >= t.config.MaxTargets {
+			break
+		}
+	}
+	return targets
+}
+
+// buildIPIndex maps IPs to node IDs. Interface nodes win over their
+// parents so a hop lands on the exact router interface.
+func buildIPIndex(nodes []domain.Node) map[string]string {
+	index := make(map[string]string)
+	for _, node := range nodes {
+		ip := node.GetPropertyString("ip")
+		if ip == "" {
+			continue
+		}
+		if _, taken := index[ip]; taken && node.Type != domain.NodeTypeInterface {
+			continue
+		}
+		index[ip] = node.ID
+	}
+	return index
+}
+
+// TraceSubnet traces representative targets in a subnet and returns router
+// nodes for private intermediate hops with route edges along each path.
+// Edges may reference the origin or target nodes, which are not included.
+func (t *TracerouteAdapter) TraceSubnet(ctx context.Context, cidr string) (*domain.GraphFragment, error) {
+	targets, err := representativeTargets(cidr, t.config.TargetsPerSubnet)
+	if err != nil {
+		return nil, err
+	}
+
+	fragment := domain.NewGraphFragment()
+	now := time.Now()
+	seenNodes := make(map[string]bool)
+	seenEdges := make(map[string]bool)
+
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			return fragment, ctx.Err()
+		}
+
+		hops, err := t.trace(ctx, target)
+		if err != nil {
+			log.Printf("Traceroute to %s failed: %v", target, err)
+			continue
+		}
+
+		nodes, edges := buildRoutePath(t.config.OriginID, target, hops, nil, now)
+		for _, n := range nodes {
+			if !seenNodes[n.ID] {
+				seenNodes[n.ID] = true
+				fragment.AddNode(n)
+			}
+		}
+		for _, e := range edges {
+			if !seenEdges[e.ID] {
+				seenEdges[e.ID] = true
+				fragment.AddEdge(e)
+			}
+		}
+	}
+
+	return fragment, nil
+}
+
+// buildRoutePath converts one traced path into router nodes and route edges.
+// Hops found in known (IP to node ID) reuse the existing node. Other public
+// and silent hops are collapsed: the hops on either side are linked directly
+// and the edge records how many hops were skipped. Each edge carries the
+// hop number of its far end. The final hop is only linked when the trace
+// actually reached the target.
+func buildRoutePath(originID, target string, hops []string, known map[string]string, now time.Time) ([]domain.Node, []domain.Edge) {
+	var nodes []domain.Node
+	var edges []domain.Edge
+
+	reached := len(hops) > 0 && hops[len(hops)-1] == target
+	if reached {
+		hops = hops[:len(hops)-1]
+	}
+
+	prev := originID
+	skipped := 0
+	link := func(to string, hop int) {
+		if prev == "" || prev == to {
+			return
+		}
+		edge := domain.NewEdge(prev, to, domain.EdgeTypeRoute)
+		edge.SetProperty("discovered_by", "traceroute")
+		edge.SetProperty("hop", hop)
+		if skipped > 0 {
+			edge.SetProperty("collapsed_hops", skipped)
+		}
+		edges = append(edges, *edge)
+	}
+
+	for i, hop := range hops {
+		if id, ok := known[hop]; ok {
+			link(id, i+1)
+			prev = id
+			skipped = 0
+			continue
+		}
+
+		ip := net.ParseIP(hop)
+		if ip == nil || !ip.IsPrivate() {
+			skipped++
+			continue
+		}
+
+		id := sanitizeIP(hop)
+		node := domain.Node{
+			ID:     id,
+			Type:   domain.NodeTypeRouter,
+			Label:  hop,
+			Role:   domain.RoleRouter,
+			Source: "traceroute",
+			Status: domain.NodeStatusVerified,
+			Properties: map[string]any{
+				"ip": hop,
+			},
+			Discovered: map[string]any{
+				"hop_distance": i + 1,
+			},
+		}
+		node.LastSeen = &now
+		nodes = append(nodes, node)
+
+		link(id, i+1)
+		prev = id
+		skipped = 0
+	}
+
+	if reached {
+		id, ok := known[target]
+		if !ok {
+			id = sanitizeIP(target)
+		}
+		link(id, len(hops)+1)
+	}
+
+	return nodes, edges
+}
+
+// representativeTargets picks up to n addresses spread across a subnet:
+// the first usable host, then points further into the range.
+func representativeTargets(cidr string, n int) ([]string, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		// Allow single hosts
+		if parsed := net.ParseIP(cidr); parsed != nil {
+			return []string{parsed.String()}, nil
+		}
+		return nil, fmt.Errorf("invalid CIDR: %w", err)
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("traceroute targets only support IPv4 subnets: %s", cidr)
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	ones, bits := ipnet.Mask.Size()
+	size := uint32(1) << uint(bits-ones)
+	base := binary.BigEndian.Uint32(ipnet.IP.To4())
+
+	// Usable hosts exclude network and broadcast addresses except for /31 and /32
+	first, last := uint32(1), size-2
+	if size <= 2 {
+		first, last = 0, size-1
+	}
+	usable := last - first + 1
+
+	if uint32(n) > usable {
+		n = int(usable)
+	}
+
+	targets := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		offset := first + uint32(i)*(usable/uint32(n))
+		addr := make(net.IP, 4)
+		binary.BigEndian.PutUint32(addr, base+offset)
+		targets = append(targets, addr.String())
+	}
+	return targets, nil
+}
+
+// systemTraceroute runs the system traceroute command, falling back to mtr
+func (t *TracerouteAdapter) systemTraceroute(ctx context.Context, target string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancel()
+
+	maxHops := strconv.Itoa(t.config.MaxHops)
+	var cmd *exec.Cmd
+	switch {
+	case hasBinary("traceroute"):
+		// -n numeric, -q 1 probe per hop, -w 1 second wait per probe
+		cmd = exec.CommandContext(ctx, "traceroute", "-n", "-q", "1", "-w", "1", "-m", maxHops, target)
+	case hasBinary("mtr"):
+		// --report with a single cycle prints one line per hop
+		cmd = exec.CommandContext(ctx, "mtr", "-n", "-r", "-c", "1", "-m", maxHops, target)
+	default:
+		return nil, errTracerouteUnavailable
+	}
+
+	output, err := cmd.Output()
+	if err != nil && len(output) == 0 {
+		return nil, err
+	}
+	return parseTracerouteOutput(string(output)), nil
+}
+
+// hasBinary reports whether name is on PATH
+func hasBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// parseTracerouteOutput extracts hop addresses from traceroute output. It
+// accepts Linux and busybox traceroute (numeric or "name (ip)" form) and
+// mtr --report lines ("1.|-- 10.0.0.1 ..."). Silent hops ("*" or "???")
+// are returned as empty strings to keep hop positions.
+func parseTracerouteOutput(output string) []string {
+	var hops []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		// Hop lines start with the hop number; skip headers.
+		// mtr writes "1.|--" before the host.
+		num := fields[0]
+		if i := strings.Index(num, ".|"); i > 0 {
+			num = num[:i]
+		}
+		n, err := strconv.Atoi(num)
+		if err != nil || n < 1 {
+			continue
+		}
+
+		// Keep positions if the output skipped a hop number
+		for len(hops) < n-1 {
+			hops = append(hops, "")
+		}
+		if len(hops) >= n {
+			continue // extra probe lines for a hop already seen
+		}
+
+		hops = append(hops, hopAddress(fields[1:]))
+	}
+	return hops
+}
+
+// hopAddress returns the IP in the fields after a hop number, or ""
+func hopAddress(fields []string) string {
+	if net.ParseIP(fields[0]) != nil {
+		return fields[0]
+	}
+	// Resolved form: "gateway (192.168.1.1)"
+	if len(fields) > 1 {
+		if ip := strings.Trim(fields[1], "()"); net.ParseIP(ip) != nil {
+			return ip
+		}
+	}
+	return ""
+}
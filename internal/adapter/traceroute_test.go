@@ -0,0 +1,234 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+func TestParseTracerouteOutput(t *testing.T) {
+	output := `traceroute to 10.20.0.1 (10.20.0.1), 15 hops max, 60 byte packets
+ 1  192.168.1.1  0.512 ms
+ 2  *
+ 3  203.0.113.9  4.100 ms
+ 4  10.20.0.1  6.250 ms
+`
+	hops := parseTracerouteOutput(output)
+	want := []string{"192.168.1.1", "", "203.0.113.9", "10.20.0.1"}
+	if len(hops) != len(want) {
+		t.Fatalf("hops = %v, want %v", hops, want)
+	}
+	for i := range want {
+		if hops[i] != want[i] {
+			t.Errorf("hop %d = %q, want %q", i+1, hops[i], want[i])
+		}
+	}
+}
+
+func TestRepresentativeTargets(t *testing.T) {
+	tests := []struct {
+		cidr string
+		n    int
+		want []string
+	}{
+		{"192.168.1.0/24", 2, []string{"192.168.1.1", "192.168.1.128"}},
+		{"10.0.0.0/30", 5, []string{"10.0.0.1", "10.0.0.2"}},
+		{"10.0.0.7/32", 2, []string{"10.0.0.7"}},
+		{"10.0.0.9", 2, []string{"10.0.0.9"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			got, err := representativeTargets(tt.cidr, tt.n)
+			if err != nil {
+				t.Fatalf("representativeTargets() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("representativeTargets() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("target %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := representativeTargets("fd00::/64", 2); err == nil {
+		t.Error("expected error for IPv6 subnet")
+	}
+}
+
+func TestTraceSubnetBuildsRouterPath(t *testing.T) {
+	tr := NewTracerouteAdapter(DefaultTracerouteConfig())
+	tr.config.TargetsPerSubnet = 1
+	tr.trace = func(ctx context.Context, target string) ([]string, error) {
+		// LAN gateway, silent hop, public transit, remote site router, target
+		return []string{"192.168.1.1", "", "203.0.113.9", "10.20.0.254", target}, nil
+	}
+
+	fragment, err := tr.TraceSubnet(context.Background(), "10.20.0.0/24")
+	if err != nil {
+		t.Fatalf("TraceSubnet() error = %v", err)
+	}
+
+	if len(fragment.Nodes) != 2 {
+		t.Fatalf("got %d router nodes, want 2 (public and silent hops skipped)", len(fragment.Nodes))
+	}
+	for _, n := range fragment.Nodes {
+		if n.Type != domain.NodeTypeRouter || n.Role != domain.RoleRouter {
+			t.Errorf("node %s = %s/%v, want router role", n.ID, n.Type, n.Role)
+		}
+	}
+
+	wantEdges := []struct {
+		from, to  string
+		collapsed int
+	}{
+		{"specularium", "192-168-1-1", 0},
+		{"192-168-1-1", "10-20-0-254", 2},
+		{"10-20-0-254", "10-20-0-1", 0},
+	}
+	if len(fragment.Edges) != len(wantEdges) {
+		t.Fatalf("got %d edges, want %d", len(fragment.Edges), len(wantEdges))
+	}
+	for i, want := range wantEdges {
+		e := fragment.Edges[i]
+		if e.FromID != want.from || e.ToID != want.to || e.Type != domain.EdgeTypeRoute {
+			t.Errorf("edge %d = %s->%s (%s), want %s->%s route", i, e.FromID, e.ToID, e.Type, want.from, want.to)
+		}
+		collapsed, _ := e.Properties["collapsed_hops"].(int)
+		if collapsed != want.collapsed {
+			t.Errorf("edge %d collapsed_hops = %d, want %d", i, collapsed, want.collapsed)
+		}
+	}
+}
+
+func TestBuildRoutePathUnreachedTarget(t *testing.T) {
+	nodes, edges := buildRoutePath("specularium", "10.9.9.9", []string{"192.168.1.1", ""}, nil, time.Now())
+	if len(nodes) != 1 {
+		t.Errorf("got %d nodes, want 1", len(nodes))
+	}
+	for _, e := range edges {
+		if e.ToID == "10-9-9-9" {
+			t.Error("unreached target should not be linked")
+		}
+	}
+}
+
+func TestParseTracerouteOutputFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{
+			name: "busybox",
+			output: `traceroute to 10.20.0.1 (10.20.0.1), 30 hops max, 38 byte packets
+ 1  gateway (192.168.1.1)  0.398 ms
+ 2  *
+ 3  10.20.0.1 (10.20.0.1)  5.112 ms
+`,
+			want: []string{"192.168.1.1", "", "10.20.0.1"},
+		},
+		{
+			name: "mtr report",
+			output: `Start: 2026-10-15T10:00:00+0000
+HOST: specularium                 Loss%   Snt   Last   Avg  Best  Wrst StDev
+  1.|-- 192.168.1.1                0.0%     1    0.4   0.4   0.4   0.4   0.0
+  2.|-- ???                       100.0     1    0.0   0.0   0.0   0.0   0.0
+  3.|-- 10.20.0.1                  0.0%     1    5.1   5.1   5.1   5.1   0.0
+`,
+			want: []string{"192.168.1.1", "", "10.20.0.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hops := parseTracerouteOutput(tt.output)
+			if len(hops) != len(tt.want) {
+				t.Fatalf("hops = %v, want %v", hops, tt.want)
+			}
+			for i := range tt.want {
+				if hops[i] != tt.want[i] {
+					t.Errorf("hop %d = %q, want %q", i+1, hops[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTracerouteSyncReusesKnownNodes(t *testing.T) {
+	gw := *domain.NewNode("gw", domain.NodeTypeRouter, "gw")
+	gw.SetProperty("ip", "192.168.1.1")
+	nas := *domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	nas.SetProperty("ip", "10.20.0.5")
+
+	tr := NewTracerouteAdapter(DefaultTracerouteConfig())
+	tr.SetNodeLister(&fakeNodeLister{nodes: []domain.Node{gw, nas}})
+	var traced []string
+	tr.trace = func(ctx context.Context, target string) ([]string, error) {
+		traced = append(traced, target)
+		if target != "10.20.0.5" {
+			return []string{target}, nil
+		}
+		return []string{"192.168.1.1", "10.20.0.254", target}, nil
+	}
+	var progress int
+	tr.SetEventPublisher(publisherFunc(func(eventType string, payload interface{}) { progress++ }))
+
+	fragment, err := tr.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() error = %v", err)
+	}
+	if len(traced) != 2 {
+		t.Errorf("traced %v, want both nodes", traced)
+	}
+	if progress != 4 {
+		t.Errorf("got %d progress events, want one per hop", progress)
+	}
+
+	// Only the unknown remote router becomes a new node
+	if len(fragment.Nodes) != 1 || fragment.Nodes[0].ID != "10-20-0-254" {
+		t.Fatalf("nodes = %+v, want only 10-20-0-254", fragment.Nodes)
+	}
+
+	wantEdges := []struct {
+		from, to string
+		hop      int
+	}{
+		{"specularium", "gw", 1},
+		{"gw", "10-20-0-254", 2},
+		{"10-20-0-254", "nas", 3},
+	}
+	if len(fragment.Edges) != len(wantEdges) {
+		t.Fatalf("got %d edges, want %d: %+v", len(fragment.Edges), len(wantEdges), fragment.Edges)
+	}
+	for i, want := range wantEdges {
+		e := fragment.Edges[i]
+		if e.FromID != want.from || e.ToID != want.to || e.Properties["hop"] != want.hop {
+			t.Errorf("edge %d = %s->%s hop %v, want %s->%s hop %d", i, e.FromID, e.ToID, e.Properties["hop"], want.from, want.to, want.hop)
+		}
+	}
+}
+
+func TestTracerouteSyncWithoutBinary(t *testing.T) {
+	node := *domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	node.SetProperty("ip", "10.20.0.5")
+
+	tr := NewTracerouteAdapter(DefaultTracerouteConfig())
+	tr.SetNodeLister(&fakeNodeLister{nodes: []domain.Node{node}})
+	tr.trace = func(ctx context.Context, target string) ([]string, error) {
+		return nil, errTracerouteUnavailable
+	}
+
+	fragment, err := tr.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync() should not fail when traceroute is missing: %v", err)
+	}
+	if len(fragment.Nodes) != 0 || len(fragment.Edges) != 0 {
+		t.Errorf("fragment = %+v, want empty", fragment)
+	}
+}
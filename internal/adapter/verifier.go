@@ -4,10 +4,9 @@ import (
 	"bufio"
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os/exec"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -41,22 +40,35 @@ var wellKnownPorts = map[int]string{
 	9100:  "node-exporter",
 }
 
+// AddressFamily identifies an IP address family for probing
+type AddressFamily = domain.AddressFamily
+
+const (
+	AddressFamilyIPv4 = domain.AddressFamilyIPv4
+	AddressFamilyIPv6 = domain.AddressFamilyIPv6
+)
+
 // NodeFetcher retrieves nodes that need verification
 type NodeFetcher interface {
 	// GetNodesForVerification returns nodes that need to be verified
 	GetNodesForVerification(ctx context.Context) ([]domain.Node, error)
+	// GetNodesByIDs returns the existing nodes among ids
+	GetNodesByIDs(ctx context.Context, ids []string) ([]domain.Node, error)
 }
 
 // PortInfo contains details about an open port
 type PortInfo struct {
-	Port    int    `json:"port"`
-	Service string `json:"service"`
-	Banner  string `json:"banner,omitempty"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol,omitempty"` // "tcp" or "udp"; empty means tcp
+	Service  string `json:"service"`
+	Banner   string `json:"banner,omitempty"`
 }
 
 // ProbeResult contains the results of probing a single node
 type ProbeResult struct {
 	NodeID       string
+	Address      string
+	Family       AddressFamily
 	Status       domain.NodeStatus
 	PingSuccess  bool
 	PingLatency  time.Duration
@@ -69,6 +81,8 @@ type ProbeResult struct {
 	Hostname     string // Reverse DNS
 	Error        string
 	VerifiedAt   time.Time
+	// FamilyReachable records TCP reachability per address family (dual-stack probing)
+	FamilyReachable map[AddressFamily]bool
 }
 
 // VerifierConfig holds configuration for the verifier adapter
@@ -83,16 +97,32 @@ type VerifierConfig struct {
 	CommonPorts []int
 	// MaxConcurrent limits parallel probe operations
 	MaxConcurrent int
+	// MinConcurrent enables adaptive concurrency when above zero and below
+	// MaxConcurrent: each sync tunes parallelism between the two from
+	// observed ping latency and timeouts
+	MinConcurrent int
+	// TargetLatency is the average ping latency above which adaptive
+	// concurrency backs off (0 = a quarter of PingTimeout)
+	TargetLatency time.Duration
 	// VerifyInterval determines how often to re-verify already-verified nodes
 	VerifyInterval time.Duration
-	// EnableICMP enables ICMP ping (requires ping binary)
+	// EnableICMP enables ICMP ping (requires ping binary unless native
+	// ICMP is enabled and permitted)
 	EnableICMP bool
+	// EnableNativeICMP sends echoes over ICMP sockets instead of running
+	// ping, falling back to the binary when neither unprivileged nor raw
+	// ICMP sockets may be opened
+	EnableNativeICMP bool
 	// EnableBannerGrab enables reading service banners
 	EnableBannerGrab bool
 	// EnableARPLookup enables MAC address discovery
 	EnableARPLookup bool
 	// DNSServer is an optional DNS server to use for PTR lookups
 	DNSServer string
+	// PreferredFamily selects which address to probe when a node has both ip and ipv6
+	PreferredFamily AddressFamily
+	// ProbeBothFamilies also pings the non-preferred address and records per-family reachability
+	ProbeBothFamilies bool
 	// CapabilityManager provides access to secrets for enhanced discovery
 	Capabilities *CapabilityManager
 }
@@ -109,6 +139,7 @@ func DefaultVerifierConfig() VerifierConfig {
 		EnableICMP:       true,
 		EnableBannerGrab: true,
 		EnableARPLookup:  true,
+		PreferredFamily:  AddressFamilyIPv4,
 	}
 }
 
@@ -117,8 +148,12 @@ type VerifierAdapter struct {
 	config    VerifierConfig
 	fetcher   NodeFetcher
 	publisher EventPublisher
+	logger    *slog.Logger
 	mu        sync.Mutex
 	running   bool
+
+	icmpOnce  sync.Once
+	icmpProbe icmpProber
 }
 
 // NewVerifierAdapter creates a new verifier adapter
@@ -126,9 +161,15 @@ func NewVerifierAdapter(fetcher NodeFetcher, config VerifierConfig) *VerifierAda
 	return &VerifierAdapter{
 		config:  config,
 		fetcher: fetcher,
+		logger:  slog.Default(),
 	}
 }
 
+// SetLogger sets the logger used for verification activity
+func (v *VerifierAdapter) SetLogger(logger *slog.Logger) {
+	v.logger = logger
+}
+
 // SetEventPublisher sets the event publisher for progress updates
 func (v *VerifierAdapter) SetEventPublisher(pub EventPublisher) {
 	v.publisher = pub
@@ -161,8 +202,10 @@ func (v *VerifierAdapter) Start(ctx context.Context) error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	v.running = true
-	log.Printf("Verifier adapter started (timeout=%s, ports=%v, concurrency=%d)",
-		v.config.PingTimeout, v.config.CommonPorts, v.config.MaxConcurrent)
+	v.logger.Info("verifier adapter started",
+		"timeout", v.config.PingTimeout,
+		"ports", v.config.CommonPorts,
+		"concurrency", v.config.MaxConcurrent)
 	return nil
 }
 
@@ -171,7 +214,7 @@ func (v *VerifierAdapter) Stop() error {
 	v.mu.Lock()
 	defer v.mu.Unlock()
 	v.running = false
-	log.Printf("Verifier adapter stopped")
+	v.logger.Info("verifier adapter stopped")
 	return nil
 }
 
@@ -181,7 +224,21 @@ func (v *VerifierAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
 	}
+	return v.verifyNodes(ctx, nodes)
+}
+
+// SyncNodes probes only the given nodes, regardless of when they were last
+// verified or any unreachable backoff
+func (v *VerifierAdapter) SyncNodes(ctx context.Context, ids []string) (*domain.GraphFragment, error) {
+	nodes, err := v.fetcher.GetNodesByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+	return v.verifyNodes(ctx, nodes)
+}
 
+// verifyNodes probes nodes in parallel and returns their updated status
+func (v *VerifierAdapter) verifyNodes(ctx context.Context, nodes []domain.Node) (*domain.GraphFragment, error) {
 	if len(nodes) == 0 {
 		// Emit complete event with zero nodes message
 		if v.publisher != nil {
@@ -196,7 +253,7 @@ func (v *VerifierAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 		return nil, nil
 	}
 
-	log.Printf("Verifying %d nodes", len(nodes))
+	v.logger.Info("verifying nodes", "count", len(nodes))
 
 	// Emit discovery started event
 	if v.publisher != nil {
@@ -210,7 +267,9 @@ func (v *VerifierAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 	workCh := make(chan domain.Node, len(nodes))
 	resultCh := make(chan ProbeResult, len(nodes))
 
-	// Start worker pool
+	// Start worker pool; with adaptive concurrency the controller decides
+	// how many of the workers probe at once
+	tuner := v.concurrencyController()
 	var wg sync.WaitGroup
 	for i := 0; i < v.config.MaxConcurrent; i++ {
 		wg.Add(1)
@@ -221,12 +280,14 @@ func (v *VerifierAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 				case <-ctx.Done():
 					return
 				default:
+					tuner.Acquire()
 					result := v.probeNode(ctx, node)
+					tuner.Release(probeLatency(result, v.config.PingTimeout))
 					// Emit progress event for each node
 					v.publishProgress(map[string]interface{}{
 						"node_id":  result.NodeID,
 						"status":   string(result.Status),
-						"ip":       node.GetPropertyString("ip"),
+						"ip":       result.Address,
 						"icmp":     result.ICMPSuccess,
 						"ping":     result.PingSuccess,
 						"latency":  result.PingLatency.Milliseconds(),
@@ -274,6 +335,11 @@ func (v *VerifierAdapter) Sync(ctx context.Context) (*domain.GraphFragment, erro
 		}
 	}
 
+	// Probes cut short by cancellation would report live nodes unreachable
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("verification stopped: %w", err)
+	}
+
 	// Emit discovery complete event
 	if v.publisher != nil {
 		v.publisher.PublishDiscoveryEvent("discovery-complete", map[string]interface{}{
@@ -295,21 +361,24 @@ func (v *VerifierAdapter) probeNode(ctx context.Context, node domain.Node) Probe
 		VerifiedAt: time.Now(),
 	}
 
-	// Get IP address
-	ip := node.GetPropertyString("ip")
+	// Pick the address to probe based on family preference
+	ip, family := v.selectAddress(node)
 	if ip == "" {
 		result.Status = domain.NodeStatusUnreachable
 		result.Error = "no IP address"
 		return result
 	}
+	result.Address = ip
+	result.Family = family
+	settings := v.settingsFor(node)
 
 	// ICMP ping (if enabled)
-	if v.config.EnableICMP {
-		result.ICMPSuccess, result.ICMPLatency = v.icmpPing(ctx, ip)
+	if settings.icmp {
+		result.ICMPSuccess, result.ICMPLatency = v.icmpPing(ctx, ip, settings.pingTimeout)
 	}
 
 	// TCP ping (more reliable than ICMP which often requires root)
-	pingSuccess, latency := v.tcpPing(ctx, ip)
+	pingSuccess, latency := v.tcpPing(ctx, ip, settings)
 	result.PingSuccess = pingSuccess
 	result.PingLatency = latency
 
@@ -319,13 +388,21 @@ func (v *VerifierAdapter) probeNode(ctx context.Context, node domain.Node) Probe
 		result.PingLatency = result.ICMPLatency
 	}
 
+	// Dual-stack: record reachability of the other family as well
+	if v.config.ProbeBothFamilies {
+		result.FamilyReachable = map[AddressFamily]bool{family: result.PingSuccess}
+		if otherIP, otherFamily := nodeAddress(node, otherFamily(family)); otherIP != "" {
+			result.FamilyReachable[otherFamily], _ = v.tcpPing(ctx, otherIP, settings)
+		}
+	}
+
 	// Port probes with service identification
 	if result.PingSuccess {
-		result.OpenPorts, result.ClosedPorts, result.PortDetails = v.probePortsWithDetails(ctx, ip)
+		result.OpenPorts, result.ClosedPorts, result.PortDetails = v.probePortsWithDetails(ctx, ip, settings)
 	}
 
 	// Reverse DNS lookup
-	result.Hostname = v.reverseDNS(ip)
+	result.Hostname = v.reverseDNS(ctx, ip)
 
 	// ARP lookup for MAC address (if enabled)
 	if v.config.EnableARPLookup {
@@ -344,22 +421,139 @@ func (v *VerifierAdapter) probeNode(ctx context.Context, node domain.Node) Probe
 		result.Status = domain.NodeStatusUnreachable
 	}
 
-	log.Printf("Verified %s (%s): status=%s, icmp=%v, tcp=%v (%s), mac=%s, ports=%v",
-		node.ID, ip, result.Status, result.ICMPSuccess, result.PingSuccess, result.PingLatency, result.MACAddress, result.OpenPorts)
+	v.logger.Debug("verified node",
+		"node_id", node.ID,
+		"ip", ip,
+		"status", result.Status,
+		"icmp", result.ICMPSuccess,
+		"tcp", result.PingSuccess,
+		"latency", result.PingLatency,
+		"mac", result.MACAddress,
+		"ports", result.OpenPorts,
+		"duration", time.Since(result.VerifiedAt))
 
 	return result
 }
 
-// tcpPing attempts a TCP connection to common ports to check reachability
-func (v *VerifierAdapter) tcpPing(ctx context.Context, ip string) (bool, time.Duration) {
-	// Try common ports for TCP ping
-	ports := []int{22, 80, 443, 53}
+// concurrencyController returns the adaptive concurrency controller for a
+// sync, or nil when concurrency is fixed at MaxConcurrent
+func (v *VerifierAdapter) concurrencyController() *ConcurrencyController {
+	if v.config.MinConcurrent <= 0 || v.config.MinConcurrent >= v.config.MaxConcurrent {
+		return nil
+	}
+	target := v.config.TargetLatency
+	if target <= 0 {
+		target = v.config.PingTimeout / 4
+	}
+	return NewConcurrencyController(v.config.MinConcurrent, v.config.MaxConcurrent, target)
+}
+
+// probeLatency reports a probe's ping latency and whether the ping timed
+// out. An unreachable node counts as waiting the full ping timeout; a node
+// without an address was never pinged.
+func probeLatency(result ProbeResult, pingTimeout time.Duration) (time.Duration, bool) {
+	switch {
+	case result.PingSuccess:
+		return result.PingLatency, false
+	case result.Address == "":
+		return 0, false
+	}
+	return pingTimeout, true
+}
+
+// probeSettings are the ports and timeouts used to probe one node
+type probeSettings struct {
+	pingPorts   []int
+	ports       []int
+	pingTimeout time.Duration
+	portTimeout time.Duration
+	icmp        bool
+}
+
+// settingsFor returns the adapter defaults with the node's probe_config
+// applied. An invalid probe_config is logged and ignored.
+func (v *VerifierAdapter) settingsFor(node domain.Node) probeSettings {
+	settings := probeSettings{
+		pingPorts:   []int{22, 80, 443, 53},
+		ports:       v.config.CommonPorts,
+		pingTimeout: v.config.PingTimeout,
+		portTimeout: v.config.PortTimeout,
+		icmp:        v.config.EnableICMP,
+	}
+
+	override, err := node.ProbeConfig()
+	if err != nil {
+		v.logger.Warn("ignoring probe_config", "node_id", node.ID, "error", err)
+		return settings
+	}
+	if override == nil {
+		return settings
+	}
+	if len(override.Ports) > 0 {
+		settings.pingPorts = override.Ports
+		settings.ports = override.Ports
+	}
+	if override.Timeout > 0 {
+		settings.pingTimeout = override.Timeout
+		settings.portTimeout = override.Timeout
+	}
+	if override.ICMP != nil {
+		settings.icmp = *override.ICMP
+	}
+	return settings
+}
 
-	for _, port := range ports {
-		addr := fmt.Sprintf("%s:%d", ip, port)
+// selectAddress returns the address to probe and its family.
+// The preferred family is used when present, falling back to the other family.
+func (v *VerifierAdapter) selectAddress(node domain.Node) (string, AddressFamily) {
+	preferred := v.config.PreferredFamily
+	if preferred != AddressFamilyIPv6 {
+		preferred = AddressFamilyIPv4
+	}
+
+	if ip, family := nodeAddress(node, preferred); ip != "" {
+		return ip, family
+	}
+	return nodeAddress(node, otherFamily(preferred))
+}
+
+// nodeAddress returns the node's address for a family.
+// IPv4 comes from the ip property; IPv6 from ipv6, or from ip if it holds a v6 address.
+func nodeAddress(node domain.Node, family AddressFamily) (string, AddressFamily) {
+	ip := node.GetPropertyString("ip")
+	ipIsV6 := ip != "" && strings.Contains(ip, ":")
+
+	switch family {
+	case AddressFamilyIPv6:
+		if v6 := node.GetPropertyString("ipv6"); v6 != "" {
+			return v6, AddressFamilyIPv6
+		}
+		if ipIsV6 {
+			return ip, AddressFamilyIPv6
+		}
+	default:
+		if ip != "" && !ipIsV6 {
+			return ip, AddressFamilyIPv4
+		}
+	}
+	return "", family
+}
+
+// otherFamily returns the opposite address family
+func otherFamily(family AddressFamily) AddressFamily {
+	if family == AddressFamilyIPv6 {
+		return AddressFamilyIPv4
+	}
+	return AddressFamilyIPv6
+}
+
+// tcpPing attempts a TCP connection to the ping ports to check reachability
+func (v *VerifierAdapter) tcpPing(ctx context.Context, ip string, settings probeSettings) (bool, time.Duration) {
+	for _, port := range settings.pingPorts {
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
 		start := time.Now()
 
-		dialer := net.Dialer{Timeout: v.config.PingTimeout}
+		dialer := net.Dialer{Timeout: settings.pingTimeout}
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err == nil {
 			conn.Close()
@@ -381,7 +575,7 @@ func (v *VerifierAdapter) tcpPing(ctx context.Context, ip string) (bool, time.Du
 // probePorts checks which common ports are open
 func (v *VerifierAdapter) probePorts(ctx context.Context, ip string) (open, closed []int) {
 	for _, port := range v.config.CommonPorts {
-		addr := fmt.Sprintf("%s:%d", ip, port)
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
 
 		dialer := net.Dialer{Timeout: v.config.PortTimeout}
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
@@ -397,23 +591,23 @@ func (v *VerifierAdapter) probePorts(ctx context.Context, ip string) (open, clos
 
 // reverseDNS performs a reverse DNS lookup
 // Priority: 1) Static DNSServer config, 2) DNS capability from secrets, 3) System resolver
-func (v *VerifierAdapter) reverseDNS(ip string) string {
+func (v *VerifierAdapter) reverseDNS(ctx context.Context, ip string) string {
 	dnsServer := v.config.DNSServer
 
 	// If no static DNS configured, try to get from capabilities
 	if dnsServer == "" && v.config.Capabilities != nil {
-		if dnsCap, err := v.config.Capabilities.GetDNSCapability(context.Background()); err == nil && dnsCap != nil {
+		if dnsCap, err := v.config.Capabilities.GetDNSCapability(ctx); err == nil && dnsCap != nil {
 			dnsServer = dnsCap.Server
 		}
 	}
 
 	if dnsServer != "" {
 		// Use custom DNS server for PTR lookup
-		return v.reverseDNSCustom(ip, dnsServer)
+		return v.reverseDNSCustom(ctx, ip, dnsServer)
 	}
 
 	// Fall back to system resolver
-	names, err := net.LookupAddr(ip)
+	names, err := net.DefaultResolver.LookupAddr(ctx, ip)
 	if err != nil || len(names) == 0 {
 		return ""
 	}
@@ -426,17 +620,17 @@ func (v *VerifierAdapter) reverseDNS(ip string) string {
 }
 
 // reverseDNSCustom performs PTR lookup against a specific DNS server
-func (v *VerifierAdapter) reverseDNSCustom(ip, dnsServer string) string {
+func (v *VerifierAdapter) reverseDNSCustom(ctx context.Context, ip, dnsServer string) string {
 	// Create a custom resolver
 	resolver := &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
 			d := net.Dialer{Timeout: v.config.PingTimeout}
-			return d.DialContext(ctx, "udp", dnsServer+":53")
+			return d.DialContext(ctx, "udp", net.JoinHostPort(dnsServer, "53"))
 		},
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), v.config.PingTimeout*2)
+	ctx, cancel := context.WithTimeout(ctx, v.config.PingTimeout*2)
 	defer cancel()
 
 	names, err := resolver.LookupAddr(ctx, ip)
@@ -451,35 +645,16 @@ func (v *VerifierAdapter) reverseDNSCustom(ip, dnsServer string) string {
 	return hostname
 }
 
-// icmpPing performs an ICMP ping using the system ping command
-func (v *VerifierAdapter) icmpPing(ctx context.Context, ip string) (bool, time.Duration) {
-	// Use system ping command with 1 packet and timeout
-	timeoutSec := int(v.config.PingTimeout.Seconds())
-	if timeoutSec < 1 {
-		timeoutSec = 1
-	}
-
-	ctx, cancel := context.WithTimeout(ctx, v.config.PingTimeout+time.Second)
-	defer cancel()
-
-	// Linux ping: -c count, -W timeout in seconds
-	cmd := exec.CommandContext(ctx, "ping", "-c", "1", "-W", strconv.Itoa(timeoutSec), ip)
-	output, err := cmd.Output()
-	if err != nil {
-		return false, 0
-	}
-
-	// Parse latency from output: "time=X.XX ms"
-	latencyRe := regexp.MustCompile(`time[=<](\d+\.?\d*)\s*ms`)
-	matches := latencyRe.FindSubmatch(output)
-	if len(matches) >= 2 {
-		if latencyMs, err := strconv.ParseFloat(string(matches[1]), 64); err == nil {
-			return true, time.Duration(latencyMs * float64(time.Millisecond))
-		}
-	}
-
-	// Ping succeeded but couldn't parse latency
-	return true, 0
+// icmpPing sends one ICMP echo with the prober chosen on first use:
+// native sockets when EnableNativeICMP is set and permitted, the ping
+// binary otherwise
+func (v *VerifierAdapter) icmpPing(ctx context.Context, ip string, timeout time.Duration) (bool, time.Duration) {
+	v.icmpOnce.Do(func() {
+		var name string
+		v.icmpProbe, name = selectICMPProber(v.config.EnableNativeICMP, probeNativeICMP)
+		v.logger.Info("ICMP prober selected", "prober", name)
+	})
+	return v.icmpProbe(ctx, ip, timeout)
 }
 
 // arpLookup retrieves the MAC address for an IP from the ARP cache
@@ -517,11 +692,11 @@ func (v *VerifierAdapter) arpLookup(ip string) string {
 }
 
 // probePortsWithDetails checks ports and identifies services
-func (v *VerifierAdapter) probePortsWithDetails(ctx context.Context, ip string) (open, closed []int, details []PortInfo) {
-	for _, port := range v.config.CommonPorts {
-		addr := fmt.Sprintf("%s:%d", ip, port)
+func (v *VerifierAdapter) probePortsWithDetails(ctx context.Context, ip string, settings probeSettings) (open, closed []int, details []PortInfo) {
+	for _, port := range settings.ports {
+		addr := net.JoinHostPort(ip, strconv.Itoa(port))
 
-		dialer := net.Dialer{Timeout: v.config.PortTimeout}
+		dialer := net.Dialer{Timeout: settings.portTimeout}
 		conn, err := dialer.DialContext(ctx, "tcp", addr)
 		if err != nil {
 			closed = append(closed, port)
@@ -682,6 +857,19 @@ func (v *VerifierAdapter) resultToNode(result ProbeResult) domain.Node {
 		node.SetDiscovered("last_error", result.Error)
 	}
 
+	if result.Address != "" {
+		node.SetDiscovered("probed_address", result.Address)
+		node.SetDiscovered("probed_family", string(result.Family))
+	}
+
+	if len(result.FamilyReachable) > 0 {
+		reachability := make(map[string]bool, len(result.FamilyReachable))
+		for family, ok := range result.FamilyReachable {
+			reachability[string(family)] = ok
+		}
+		node.SetDiscovered("reachability_by_family", reachability)
+	}
+
 	// Build hostname inference from all available sources
 	inference := v.buildHostnameInference(result, now)
 	if len(inference.Candidates) > 0 {
@@ -0,0 +1,164 @@
+package adapter
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+func TestVerifierSelectAddress(t *testing.T) {
+	dualStack := domain.Node{ID: "dual", Properties: map[string]any{"ip": "192.168.1.10", "ipv6": "fd00::10"}}
+	v4Only := domain.Node{ID: "v4", Properties: map[string]any{"ip": "192.168.1.11"}}
+	v6InIP := domain.Node{ID: "v6", Properties: map[string]any{"ip": "fd00::12"}}
+	noAddr := domain.Node{ID: "none", Properties: map[string]any{}}
+
+	tests := []struct {
+		name       string
+		preferred  AddressFamily
+		node       domain.Node
+		wantIP     string
+		wantFamily AddressFamily
+	}{
+		{"dual-stack prefers ipv4", AddressFamilyIPv4, dualStack, "192.168.1.10", AddressFamilyIPv4},
+		{"dual-stack prefers ipv6", AddressFamilyIPv6, dualStack, "fd00::10", AddressFamilyIPv6},
+		{"unset preference defaults to ipv4", "", dualStack, "192.168.1.10", AddressFamilyIPv4},
+		{"ipv6 preference falls back to ipv4", AddressFamilyIPv6, v4Only, "192.168.1.11", AddressFamilyIPv4},
+		{"ipv4 preference falls back to ipv6 in ip property", AddressFamilyIPv4, v6InIP, "fd00::12", AddressFamilyIPv6},
+		{"no address", AddressFamilyIPv4, noAddr, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifierAdapter(nil, VerifierConfig{PreferredFamily: tt.preferred})
+			ip, family := v.selectAddress(tt.node)
+			if ip != tt.wantIP {
+				t.Errorf("selectAddress() ip = %q, want %q", ip, tt.wantIP)
+			}
+			if ip != "" && family != tt.wantFamily {
+				t.Errorf("selectAddress() family = %q, want %q", family, tt.wantFamily)
+			}
+		})
+	}
+}
+
+func TestVerifierResultFamilyReachability(t *testing.T) {
+	v := NewVerifierAdapter(nil, DefaultVerifierConfig())
+	node := v.resultToNode(ProbeResult{
+		NodeID:          "dual",
+		Address:         "fd00::10",
+		Family:          AddressFamilyIPv6,
+		Status:          domain.NodeStatusVerified,
+		PingSuccess:     true,
+		FamilyReachable: map[AddressFamily]bool{AddressFamilyIPv6: true, AddressFamilyIPv4: false},
+	})
+
+	if got, _ := node.GetDiscovered("probed_family"); got != "ipv6" {
+		t.Errorf("probed_family = %v, want ipv6", got)
+	}
+	reach, ok := node.GetDiscovered("reachability_by_family")
+	if !ok {
+		t.Fatal("reachability_by_family not recorded")
+	}
+	m, ok := reach.(map[string]bool)
+	if !ok || !m["ipv6"] || m["ipv4"] {
+		t.Errorf("reachability_by_family = %v, want ipv6 reachable and ipv4 not", reach)
+	}
+}
+
+// fakeNodeFetcher serves nodes from memory and records targeted lookups
+type fakeNodeFetcher struct {
+	nodes     []domain.Node
+	requested []string
+}
+
+func (f *fakeNodeFetcher) GetNodesForVerification(ctx context.Context) ([]domain.Node, error) {
+	return f.nodes, nil
+}
+
+func (f *fakeNodeFetcher) GetNodesByIDs(ctx context.Context, ids []string) ([]domain.Node, error) {
+	f.requested = append(f.requested, ids...)
+	var nodes []domain.Node
+	for _, n := range f.nodes {
+		for _, id := range ids {
+			if n.ID == id {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+func TestVerifierSyncNodes(t *testing.T) {
+	// Nodes without addresses resolve immediately as unreachable
+	fetcher := &fakeNodeFetcher{nodes: []domain.Node{{ID: "a"}, {ID: "b"}, {ID: "c"}}}
+	v := NewVerifierAdapter(fetcher, DefaultVerifierConfig())
+
+	fragment, err := v.SyncNodes(context.Background(), []string{"a", "c"})
+	if err != nil {
+		t.Fatalf("SyncNodes() error = %v", err)
+	}
+	got := map[string]bool{}
+	for _, n := range fragment.Nodes {
+		got[n.ID] = true
+	}
+	if len(got) != 2 || !got["a"] || !got["c"] {
+		t.Errorf("SyncNodes() verified %v, want only a and c", got)
+	}
+}
+
+func TestVerifierProbeConfigOverride(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// The global defaults only probe a port nothing listens on
+	config := DefaultVerifierConfig()
+	config.CommonPorts = []int{1}
+	config.EnableICMP = false
+	config.EnableARPLookup = false
+	config.EnableBannerGrab = false
+	v := NewVerifierAdapter(nil, config)
+
+	tests := []struct {
+		name        string
+		probeConfig any
+		wantStatus  domain.NodeStatus
+		wantPorts   []int
+	}{
+		{"global defaults miss the port", nil, domain.NodeStatusDegraded, nil},
+		{"custom port only", map[string]any{"ports": []any{float64(port)}, "timeout": "1s", "icmp": false}, domain.NodeStatusVerified, []int{port}},
+		{"invalid config is ignored", map[string]any{"ports": []any{float64(70000)}}, domain.NodeStatusDegraded, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := domain.Node{ID: "odd-ports", Properties: map[string]any{"ip": "127.0.0.1"}}
+			if tt.probeConfig != nil {
+				node.Properties[domain.ProbeConfigProperty] = tt.probeConfig
+			}
+
+			result := v.probeNode(context.Background(), node)
+			if result.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", result.Status, tt.wantStatus)
+			}
+			if !slices.Equal(result.OpenPorts, tt.wantPorts) {
+				t.Errorf("open ports = %v, want %v", result.OpenPorts, tt.wantPorts)
+			}
+		})
+	}
+}
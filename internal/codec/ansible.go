@@ -100,6 +100,7 @@ func (c *AnsibleCodec) Parse(r io.Reader) (*domain.GraphFragment, error) {
 		}
 	}
 
+	normalizeFragment(fragment)
 	return fragment, nil
 }
 
@@ -0,0 +1,216 @@
+package codec
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"specularium/internal/domain"
+)
+
+// csvColumns are the recognized columns, in export order
+var csvColumns = []string{"id", "label", "type", "ip", "source", "tags"}
+
+// CSVRowError describes a CSV row that could not be parsed into a node
+type CSVRowError struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// CSVResult is a parsed CSV inventory
+type CSVResult struct {
+	Fragment       *domain.GraphFragment
+	Errors         []CSVRowError
+	IgnoredColumns []string
+}
+
+// CSVCodec handles spreadsheet-style node inventories with one node per
+// row. Only nodes are carried; CSV has no natural place for edges.
+type CSVCodec struct{}
+
+// NewCSVCodec creates a new CSV codec
+func NewCSVCodec() *CSVCodec {
+	return &CSVCodec{}
+}
+
+// Format returns the codec format identifier
+func (c *CSVCodec) Format() string {
+	return "csv"
+}
+
+// Parse imports nodes from CSV, failing on the first bad row
+func (c *CSVCodec) Parse(r io.Reader) (*domain.GraphFragment, error) {
+	result, err := c.ParseRows(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		e := result.Errors[0]
+		return nil, fmt.Errorf("row %d: %s", e.Row, e.Error)
+	}
+	return result.Fragment, nil
+}
+
+// ParseRows imports nodes from CSV, collecting bad rows instead of failing.
+// The first row is a header naming the columns; id is required, label
+// defaults to the id and type to unknown. Tags are separated by semicolons,
+// commas or spaces. Unrecognized columns are reported and ignored.
+func (c *CSVCodec) ParseRows(r io.Reader) (*CSVResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	result := &CSVResult{
+		Fragment: domain.NewGraphFragment(),
+		Errors:   []CSVRowError{},
+	}
+
+	cols := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isCSVColumn(name) {
+			if name != "" {
+				result.IgnoredColumns = append(result.IgnoredColumns, name)
+			}
+			continue
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		cols[name] = i
+	}
+	if _, ok := cols["id"]; !ok {
+		return nil, fmt.Errorf("CSV must include an id column")
+	}
+
+	seen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("failed to read CSV: %w", err)
+			}
+			result.Errors = append(result.Errors, CSVRowError{Row: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		row, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		node, err := csvNode(field)
+		if err != nil {
+			result.Errors = append(result.Errors, CSVRowError{Row: row, ID: field("id"), Error: err.Error()})
+			continue
+		}
+		if first, dup := seen[node.ID]; dup {
+			result.Errors = append(result.Errors, CSVRowError{
+				Row: row, ID: node.ID, Error: fmt.Sprintf("duplicate id, first seen on row %d", first),
+			})
+			continue
+		}
+		seen[node.ID] = row
+		result.Fragment.AddNode(node)
+	}
+
+	normalizeFragment(result.Fragment)
+	return result, nil
+}
+
+// csvNode builds a node from a row's recognized fields
+func csvNode(field func(string) string) (domain.Node, error) {
+	node := domain.Node{
+		ID:         field("id"),
+		Label:      field("label"),
+		Type:       domain.NodeType(strings.ToLower(field("type"))),
+		Source:     field("source"),
+		Properties: make(map[string]any),
+	}
+	if node.ID == "" {
+		return node, fmt.Errorf("id is required")
+	}
+	if node.Label == "" {
+		node.Label = node.ID
+	}
+	if node.Type == "" {
+		node.Type = domain.NodeTypeUnknown
+	}
+	if node.Source == "" {
+		node.Source = "csv"
+	}
+
+	if ip := field("ip"); ip != "" {
+		if net.ParseIP(ip) == nil {
+			return node, fmt.Errorf("invalid ip %q", ip)
+		}
+		node.Properties["ip"] = ip
+	}
+
+	for _, tag := range strings.FieldsFunc(field("tags"), isTagSeparator) {
+		normalized, err := domain.NormalizeTag(tag)
+		if err != nil {
+			return node, fmt.Errorf("invalid tag: %w", err)
+		}
+		node.Tags = append(node.Tags, normalized)
+	}
+
+	return node, nil
+}
+
+// Export writes nodes as CSV, sorted by ID. Edges are not exported.
+func (c *CSVCodec) Export(fragment *domain.GraphFragment, w io.Writer) error {
+	nodes := make([]domain.Node, len(fragment.Nodes))
+	copy(nodes, fragment.Nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		record := []string{
+			node.ID,
+			node.Label,
+			string(node.Type),
+			node.GetPropertyString("ip"),
+			node.Source,
+			strings.Join(node.Tags, ";"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func isCSVColumn(name string) bool {
+	for _, col := range csvColumns {
+		if col == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isTagSeparator(r rune) bool {
+	return r == ';' || r == ',' || r == ' '
+}
@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const csvInventory = `ID,Label,Type,IP,Source,Tags,Rack
+nas,"Storage, main",server,192.168.1.20,,prod;storage,r1
+
+printer,,,192.168.1.30,spreadsheet,,r2
+,orphan,server,,,,
+bad-ip,Bad,server,300.1.1.1,,,
+nas,Duplicate,server,,,,
+`
+
+func TestCSVCodecParseRows(t *testing.T) {
+	result, err := NewCSVCodec().ParseRows(strings.NewReader(csvInventory))
+	if err != nil {
+		t.Fatalf("ParseRows failed: %v", err)
+	}
+
+	if len(result.Fragment.Nodes) != 2 {
+		t.Fatalf("got %d nodes, want 2", len(result.Fragment.Nodes))
+	}
+	nas := result.Fragment.Nodes[0]
+	if nas.Label != "Storage, main" || nas.Source != "csv" || nas.GetPropertyString("ip") != "192.168.1.20" {
+		t.Errorf("nas = %+v", nas)
+	}
+	if !reflect.DeepEqual(nas.Tags, []string{"prod", "storage"}) {
+		t.Errorf("nas tags = %v", nas.Tags)
+	}
+	printer := result.Fragment.Nodes[1]
+	if printer.Label != "printer" || printer.Type != "unknown" || printer.Source != "spreadsheet" {
+		t.Errorf("printer defaults not applied: %+v", printer)
+	}
+
+	// Rows are file line numbers; the blank line is skipped but still counted
+	rows := make([]int, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		rows = append(rows, e.Row)
+	}
+	if !reflect.DeepEqual(rows, []int{5, 6, 7}) {
+		t.Errorf("error rows = %v (%+v)", rows, result.Errors)
+	}
+	if !reflect.DeepEqual(result.IgnoredColumns, []string{"rack"}) {
+		t.Errorf("ignored columns = %v", result.IgnoredColumns)
+	}
+
+	if _, err := NewCSVCodec().Parse(strings.NewReader(csvInventory)); err == nil {
+		t.Error("Parse should fail on the first bad row")
+	}
+	if _, err := NewCSVCodec().ParseRows(strings.NewReader("label,ip\nx,1.2.3.4\n")); err == nil {
+		t.Error("expected error for missing id column")
+	}
+}
+
+func TestCSVCodecRoundTrip(t *testing.T) {
+	parsed, err := NewCSVCodec().Parse(strings.NewReader("id,label,tags\nweb,\"web \"\"one\"\"\",a;b\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewCSVCodec().Export(parsed, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	want := "id,label,type,ip,source,tags\nweb,\"web \"\"one\"\"\",unknown,,csv,a;b\n"
+	if buf.String() != want {
+		t.Errorf("Export = %q, want %q", buf.String(), want)
+	}
+}
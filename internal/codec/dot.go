@@ -0,0 +1,113 @@
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"specularium/internal/domain"
+)
+
+// dotNodeColors maps node types to Graphviz fill colors
+var dotNodeColors = map[domain.NodeType]string{
+	domain.NodeTypeServer:      "#8ecae6",
+	domain.NodeTypeSwitch:      "#ffb703",
+	domain.NodeTypeRouter:      "#fb8500",
+	domain.NodeTypeAccessPoint: "#ffd166",
+	domain.NodeTypeVM:          "#b8e0d2",
+	domain.NodeTypeVIP:         "#cdb4db",
+	domain.NodeTypeContainer:   "#a8dadc",
+	domain.NodeTypeInterface:   "#e9ecef",
+	domain.NodeTypeSelf:        "#90be6d",
+}
+
+// dotEdgeStyles maps edge types to Graphviz edge styles
+var dotEdgeStyles = map[domain.EdgeType]string{
+	domain.EdgeTypeVirtual:     "dashed",
+	domain.EdgeTypeAggregation: "bold",
+	domain.EdgeTypeVLAN:        "dotted",
+	domain.EdgeTypeRoute:       "dashed",
+}
+
+// DOTCodec handles Graphviz DOT export
+type DOTCodec struct{}
+
+// NewDOTCodec creates a new DOT codec
+func NewDOTCodec() *DOTCodec {
+	return &DOTCodec{}
+}
+
+// Format returns the codec format identifier
+func (c *DOTCodec) Format() string {
+	return "dot"
+}
+
+// Export writes the fragment as a Graphviz digraph.
+// Nodes and edges are sorted so output is stable across exports.
+func (c *DOTCodec) Export(fragment *domain.GraphFragment, w io.Writer) error {
+	nodes := make([]domain.Node, len(fragment.Nodes))
+	copy(nodes, fragment.Nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	edges := make([]domain.Edge, len(fragment.Edges))
+	copy(edges, fragment.Edges)
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.FromID != b.FromID {
+			return a.FromID < b.FromID
+		}
+		if a.ToID != b.ToID {
+			return a.ToID < b.ToID
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph specularium {")
+	fmt.Fprintln(bw, `  node [shape=box, style="rounded,filled", fontname="Helvetica"];`)
+	fmt.Fprintln(bw, `  edge [fontname="Helvetica", fontsize=10];`)
+
+	for _, node := range nodes {
+		color, ok := dotNodeColors[node.Type]
+		if !ok {
+			color = "#ffffff"
+		}
+		label := node.Label
+		if label == "" {
+			label = node.ID
+		}
+		fmt.Fprintf(bw, "  %s [label=%s, fillcolor=%s];\n",
+			dotQuote(node.ID), dotQuote(label), dotQuote(color))
+	}
+
+	for _, edge := range edges {
+		attrs := []string{"label=" + dotQuote(string(edge.Type))}
+		if style, ok := dotEdgeStyles[edge.Type]; ok {
+			attrs = append(attrs, "style="+style)
+		}
+		fmt.Fprintf(bw, "  %s -> %s [%s];\n",
+			dotQuote(edge.FromID), dotQuote(edge.ToID), strings.Join(attrs, ", "))
+	}
+
+	fmt.Fprintln(bw, "}")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write DOT: %w", err)
+	}
+	return nil
+}
+
+// dotQuote returns s as a double-quoted DOT string with special characters escaped
+func dotQuote(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\r", "",
+		"\n", `\n`,
+	)
+	return `"` + r.Replace(s) + `"`
+}
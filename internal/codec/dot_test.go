@@ -0,0 +1,61 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+func TestDOTCodecExport(t *testing.T) {
+	build := func(reverse bool) *domain.GraphFragment {
+		fragment := domain.NewGraphFragment()
+		nodes := []*domain.Node{
+			domain.NewNode("web1", domain.NodeTypeServer, `web "primary"`),
+			domain.NewNode("core", domain.NodeTypeRouter, "core\\rtr"),
+		}
+		edges := []*domain.Edge{
+			domain.NewEdge("core", "web1", domain.EdgeTypeVirtual),
+			domain.NewEdge("core", "web1", domain.EdgeTypeAggregation),
+		}
+		if reverse {
+			nodes[0], nodes[1] = nodes[1], nodes[0]
+			edges[0], edges[1] = edges[1], edges[0]
+		}
+		for _, n := range nodes {
+			fragment.AddNode(*n)
+		}
+		for _, e := range edges {
+			fragment.AddEdge(*e)
+		}
+		return fragment
+	}
+
+	var a, b bytes.Buffer
+	if err := NewDOTCodec().Export(build(false), &a); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if err := NewDOTCodec().Export(build(true), &b); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if a.String() != b.String() {
+		t.Errorf("output depends on input order:\n%s\nvs\n%s", a.String(), b.String())
+	}
+
+	out := a.String()
+	for _, want := range []string{
+		"digraph specularium {",
+		`"web1" [label="web \"primary\"", fillcolor="#8ecae6"];`,
+		`"core" [label="core\\rtr", fillcolor="#fb8500"];`,
+		`"core" -> "web1" [label="aggregation", style=bold];`,
+		`"core" -> "web1" [label="virtual", style=dashed];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, `"core" [`) > strings.Index(out, `"web1" [`) {
+		t.Error("nodes should be sorted by ID")
+	}
+}
@@ -0,0 +1,189 @@
+package codec
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"specularium/internal/domain"
+)
+
+// graphMLNamespace is the GraphML XML namespace understood by yEd and Gephi
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+// GraphMLCodec handles GraphML export
+type GraphMLCodec struct {
+	// positions populate x/y data so layouts round-trip
+	positions map[string]domain.NodePosition
+}
+
+// NewGraphMLCodec creates a new GraphML codec. Positions may be nil.
+func NewGraphMLCodec(positions map[string]domain.NodePosition) *GraphMLCodec {
+	return &GraphMLCodec{positions: positions}
+}
+
+// Format returns the codec format identifier
+func (c *GraphMLCodec) Format() string {
+	return "graphml"
+}
+
+// graphMLDocument is the root GraphML element
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Export writes the fragment as a GraphML document
+func (c *GraphMLCodec) Export(fragment *domain.GraphFragment, w io.Writer) error {
+	doc := graphMLDocument{
+		XMLNS: graphMLNamespace,
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", AttrName: "label", AttrType: "string"},
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "x", For: "node", AttrName: "x", AttrType: "double"},
+			{ID: "y", For: "node", AttrName: "y", AttrType: "double"},
+			{ID: "edge_type", For: "edge", AttrName: "type", AttrType: "string"},
+		},
+		Graph: graphMLGraph{ID: "specularium", EdgeDefault: "directed"},
+	}
+
+	for _, node := range fragment.Nodes {
+		gn := graphMLNode{
+			ID: node.ID,
+			Data: []graphMLData{
+				{Key: "label", Value: node.Label},
+				{Key: "type", Value: string(node.Type)},
+			},
+		}
+		if pos, ok := c.positions[node.ID]; ok {
+			gn.Data = append(gn.Data,
+				graphMLData{Key: "x", Value: strconv.FormatFloat(pos.X, 'f', -1, 64)},
+				graphMLData{Key: "y", Value: strconv.FormatFloat(pos.Y, 'f', -1, 64)},
+			)
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gn)
+	}
+
+	// Edge properties become data keys, declared once per distinct name.
+	// Key IDs are numbered ep_<n> in name order so arbitrary property names
+	// stay valid and never collide with the built-in keys; attr.name keeps
+	// the original name.
+	flats := make([]map[string]string, len(fragment.Edges))
+	propKeys := make(map[string]string)
+	for i, edge := range fragment.Edges {
+		flats[i] = make(map[string]string)
+		flattenProperties("", edge.Properties, flats[i])
+		for name := range flats[i] {
+			propKeys[name] = ""
+		}
+	}
+	names := make([]string, 0, len(propKeys))
+	for name := range propKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for n, name := range names {
+		propKeys[name] = "ep_" + strconv.Itoa(n)
+		doc.Keys = append(doc.Keys, graphMLKey{
+			ID: propKeys[name], For: "edge", AttrName: name, AttrType: "string",
+		})
+	}
+
+	for i, edge := range fragment.Edges {
+		ge := graphMLEdge{
+			ID:     edge.ID,
+			Source: edge.FromID,
+			Target: edge.ToID,
+			Data:   []graphMLData{{Key: "edge_type", Value: string(edge.Type)}},
+		}
+		for _, name := range sortedKeys(flats[i]) {
+			ge.Data = append(ge.Data, graphMLData{Key: propKeys[name], Value: flats[i][name]})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, ge)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode GraphML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// flattenProperties flattens nested maps into dot-separated keys.
+// Scalars are formatted directly; other values are JSON encoded.
+func flattenProperties(prefix string, props map[string]any, out map[string]string) {
+	for k, v := range props {
+		name := k
+		if prefix != "" {
+			name = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case map[string]any:
+			flattenProperties(name, val, out)
+		case string:
+			out[name] = val
+		case nil:
+			out[name] = ""
+		case bool, int, int64, float64:
+			out[name] = fmt.Sprint(val)
+		default:
+			data, err := json.Marshal(val)
+			if err != nil {
+				out[name] = fmt.Sprint(val)
+				continue
+			}
+			out[name] = string(data)
+		}
+	}
+}
+
+// sortedKeys returns map keys in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+func TestGraphMLCodecExport(t *testing.T) {
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*domain.NewNode("router", domain.NodeTypeRouter, "Core <Router> & Co"))
+	fragment.AddNode(*domain.NewNode("web1", domain.NodeTypeServer, "web1"))
+	fragment.AddNode(*domain.NewNode("web2", domain.NodeTypeServer, "web2"))
+
+	e1 := domain.NewEdge("router", "web1", domain.EdgeTypeEthernet)
+	e1.SetProperty("speed", "1G")
+	e1.SetProperty("link", map[string]any{"mtu": 9000})
+	e1.SetProperty("type", "fiber")          // would collide with the edge type key
+	e1.SetProperty("rack port #3", "uplink") // not a valid key ID as is
+	fragment.AddEdge(*e1)
+	fragment.AddEdge(*domain.NewEdge("router", "web2", domain.EdgeTypeVirtual))
+
+	positions := map[string]domain.NodePosition{
+		"router": {NodeID: "router", X: 12.5, Y: -40},
+	}
+
+	var buf bytes.Buffer
+	if err := NewGraphMLCodec(positions).Export(fragment, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var doc graphMLDocument
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not valid XML: %v\n%s", err, buf.String())
+	}
+
+	if len(doc.Graph.Nodes) != 3 {
+		t.Errorf("got %d nodes, want 3", len(doc.Graph.Nodes))
+	}
+	if len(doc.Graph.Edges) != 2 {
+		t.Errorf("got %d edges, want 2", len(doc.Graph.Edges))
+	}
+
+	data := func(entries []graphMLData, key string) string {
+		for _, d := range entries {
+			if d.Key == key {
+				return d.Value
+			}
+		}
+		return ""
+	}
+
+	router := doc.Graph.Nodes[0]
+	if got := data(router.Data, "label"); got != "Core <Router> & Co" {
+		t.Errorf("label = %q, want escaped label to round-trip", got)
+	}
+	if data(router.Data, "x") != "12.5" || data(router.Data, "y") != "-40" {
+		t.Errorf("position = (%s, %s), want (12.5, -40)", data(router.Data, "x"), data(router.Data, "y"))
+	}
+	if data(doc.Graph.Nodes[1].Data, "x") != "" {
+		t.Error("nodes without a saved position should have no x data")
+	}
+
+	// Edge property keys are looked up by their original name
+	keyIDs := make(map[string]string)
+	declared := make(map[string]bool)
+	for _, k := range doc.Keys {
+		if declared[k.ID] {
+			t.Errorf("key %q declared twice", k.ID)
+		}
+		declared[k.ID] = true
+		if k.For == "edge" {
+			keyIDs[k.AttrName] = k.ID
+		}
+	}
+	for _, want := range []string{"label", "type", "x", "y", "edge_type"} {
+		if !declared[want] {
+			t.Errorf("key %q not declared", want)
+		}
+	}
+
+	edge := doc.Graph.Edges[0]
+	if data(edge.Data, "edge_type") != "ethernet" || data(edge.Data, keyIDs["speed"]) != "1G" {
+		t.Errorf("edge data = %+v, want type and speed", edge.Data)
+	}
+	if data(edge.Data, keyIDs["link.mtu"]) != "9000" {
+		t.Errorf("nested property not flattened: %+v", edge.Data)
+	}
+	if id := keyIDs["type"]; id == "edge_type" || data(edge.Data, id) != "fiber" {
+		t.Errorf("type property keyed as %q, want its own key holding fiber", id)
+	}
+	if id := keyIDs["rack port #3"]; id != "ep_1" || data(edge.Data, id) != "uplink" {
+		t.Errorf("rack port #3 keyed as %q, want ep_1", id)
+	}
+}
@@ -9,13 +9,21 @@ import (
 )
 
 // JSONCodec handles JSON import/export
-type JSONCodec struct{}
+type JSONCodec struct {
+	compact bool
+}
 
-// NewJSONCodec creates a new JSON codec
+// NewJSONCodec creates a new JSON codec that exports indented JSON
 func NewJSONCodec() *JSONCodec {
 	return &JSONCodec{}
 }
 
+// NewCompactJSONCodec creates a JSON codec that exports without
+// indentation
+func NewCompactJSONCodec() *JSONCodec {
+	return &JSONCodec{compact: true}
+}
+
 // Format returns the codec format identifier
 func (c *JSONCodec) Format() string {
 	return "json"
@@ -29,13 +37,16 @@ func (c *JSONCodec) Parse(r io.Reader) (*domain.GraphFragment, error) {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
+	normalizeFragment(&fragment)
 	return &fragment, nil
 }
 
-// Export exports graph data to JSON
+// Export streams graph data to w as JSON
 func (c *JSONCodec) Export(fragment *domain.GraphFragment, w io.Writer) error {
 	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
+	if !c.compact {
+		encoder.SetIndent("", "  ")
+	}
 
 	if err := encoder.Encode(fragment); err != nil {
 		return fmt.Errorf("failed to encode JSON: %w", err)
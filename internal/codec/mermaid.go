@@ -0,0 +1,163 @@
+package codec
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"specularium/internal/domain"
+)
+
+// DefaultMermaidMaxNodes caps Mermaid exports; renderers become unreadable
+// (and slow) well before the graph gets large
+const DefaultMermaidMaxNodes = 150
+
+// mermaidShapes maps node types to flowchart shape delimiters
+var mermaidShapes = map[domain.NodeType][2]string{
+	domain.NodeTypeServer:      {"[", "]"},
+	domain.NodeTypeSwitch:      {"[[", "]]"},
+	domain.NodeTypeRouter:      {"{{", "}}"},
+	domain.NodeTypeAccessPoint: {">", "]"},
+	domain.NodeTypeVM:          {"(", ")"},
+	domain.NodeTypeVIP:         {"((", "))"},
+	domain.NodeTypeContainer:   {"([", "])"},
+	domain.NodeTypeInterface:   {"[/", "/]"},
+	domain.NodeTypeSelf:        {"[/", `\]`},
+	domain.NodeTypeSubnet:      {"{", "}"},
+}
+
+// mermaidArrows maps edge types to link styles, mirroring the DOT export
+var mermaidArrows = map[domain.EdgeType]string{
+	domain.EdgeTypeVirtual:     "-.->",
+	domain.EdgeTypeRoute:       "-.->",
+	domain.EdgeTypeAggregation: "==>",
+}
+
+// MermaidCodec handles Mermaid flowchart export for Markdown documents
+type MermaidCodec struct {
+	maxNodes int
+}
+
+// NewMermaidCodec creates a Mermaid codec that includes at most maxNodes
+// nodes. Zero or less uses DefaultMermaidMaxNodes.
+func NewMermaidCodec(maxNodes int) *MermaidCodec {
+	if maxNodes <= 0 {
+		maxNodes = DefaultMermaidMaxNodes
+	}
+	return &MermaidCodec{maxNodes: maxNodes}
+}
+
+// Format returns the codec format identifier
+func (c *MermaidCodec) Format() string {
+	return "mermaid"
+}
+
+// Export writes the fragment as a left-to-right Mermaid flowchart.
+// Nodes and edges are sorted so output is stable across exports. When the
+// graph exceeds the node cap, the first nodes by ID are kept, edges to the
+// rest are dropped, and a comment records how many were left out.
+func (c *MermaidCodec) Export(fragment *domain.GraphFragment, w io.Writer) error {
+	nodes := make([]domain.Node, len(fragment.Nodes))
+	copy(nodes, fragment.Nodes)
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	omitted := 0
+	if len(nodes) > c.maxNodes {
+		omitted = len(nodes) - c.maxNodes
+		nodes = nodes[:c.maxNodes]
+	}
+
+	ids := make(map[string]string, len(nodes))
+	used := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		ids[node.ID] = mermaidID(node.ID, used)
+	}
+
+	edges := make([]domain.Edge, 0, len(fragment.Edges))
+	for _, edge := range fragment.Edges {
+		if ids[edge.FromID] != "" && ids[edge.ToID] != "" {
+			edges = append(edges, edge)
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		a, b := edges[i], edges[j]
+		if a.FromID != b.FromID {
+			return a.FromID < b.FromID
+		}
+		if a.ToID != b.ToID {
+			return a.ToID < b.ToID
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.ID < b.ID
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "graph LR")
+	if omitted > 0 {
+		fmt.Fprintf(bw, "  %%%% Showing %d of %d nodes; raise max_nodes to include the rest\n",
+			len(nodes), len(nodes)+omitted)
+	}
+
+	for _, node := range nodes {
+		shape, ok := mermaidShapes[node.Type]
+		if !ok {
+			shape = [2]string{"[", "]"}
+		}
+		label := node.Label
+		if label == "" {
+			label = node.ID
+		}
+		fmt.Fprintf(bw, "  %s%s%s%s\n", ids[node.ID], shape[0], mermaidQuote(label), shape[1])
+	}
+
+	for _, edge := range edges {
+		arrow, ok := mermaidArrows[edge.Type]
+		if !ok {
+			arrow = "-->"
+		}
+		fmt.Fprintf(bw, "  %s %s|%s| %s\n",
+			ids[edge.FromID], arrow, mermaidQuote(string(edge.Type)), ids[edge.ToID])
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write Mermaid: %w", err)
+	}
+	return nil
+}
+
+// mermaidID turns a node ID into a token Mermaid accepts: letters, digits
+// and underscores, prefixed so IDs such as "end" or "10.0.0.1" don't clash
+// with keywords or start with a digit. Collisions get a numeric suffix.
+func mermaidID(id string, used map[string]bool) string {
+	var b strings.Builder
+	b.WriteString("n_")
+	for _, r := range id {
+		if r < 128 && (r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+
+	token := b.String()
+	for i := 2; used[token]; i++ {
+		token = fmt.Sprintf("%s_%d", b.String(), i)
+	}
+	used[token] = true
+	return token
+}
+
+// mermaidQuote returns s as a double-quoted Mermaid label, using entity
+// codes for characters that would end the label early
+func mermaidQuote(s string) string {
+	r := strings.NewReplacer(
+		`"`, "#quot;",
+		"\r", "",
+		"\n", " ",
+	)
+	return `"` + r.Replace(s) + `"`
+}
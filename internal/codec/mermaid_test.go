@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+var (
+	mermaidNodeLine = regexp.MustCompile(`^  n_\w+[\[\(\{>]`)
+	mermaidEdgeLine = regexp.MustCompile(`^  n_\w+ (-->|-\.->|==>)\|"[^"]*"\| n_\w+$`)
+)
+
+func TestMermaidCodecExport(t *testing.T) {
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*domain.NewNode("10.0.0.1", domain.NodeTypeRouter, "core"))
+	fragment.AddNode(*domain.NewNode("end", domain.NodeTypeSwitch, `sw "1"`))
+	fragment.AddNode(*domain.NewNode("web-1", domain.NodeTypeServer, "web"))
+	fragment.AddEdge(*domain.NewEdge("10.0.0.1", "end", domain.EdgeTypeEthernet))
+	fragment.AddEdge(*domain.NewEdge("end", "web-1", domain.EdgeTypeVirtual))
+
+	var buf bytes.Buffer
+	if err := NewMermaidCodec(0).Export(fragment, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if lines[0] != "graph LR" {
+		t.Errorf("first line = %q, want graph LR", lines[0])
+	}
+	nodeLines, edgeLines := 0, 0
+	for _, line := range lines[1:] {
+		switch {
+		case mermaidEdgeLine.MatchString(line):
+			edgeLines++
+		case mermaidNodeLine.MatchString(line):
+			nodeLines++
+		default:
+			t.Errorf("unexpected line %q", line)
+		}
+	}
+	if nodeLines != 3 || edgeLines != 2 {
+		t.Errorf("got %d node and %d edge lines, want 3 and 2:\n%s", nodeLines, edgeLines, out)
+	}
+
+	for _, want := range []string{
+		`n_10_0_0_1{{"core"}}`,
+		`n_end[["sw #quot;1#quot;"]]`,
+		`n_web_1["web"]`,
+		`n_10_0_0_1 -->|"ethernet"| n_end`,
+		`n_end -.->|"virtual"| n_web_1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMermaidCodecTruncates(t *testing.T) {
+	fragment := domain.NewGraphFragment()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		fragment.AddNode(*domain.NewNode(id, domain.NodeTypeServer, id))
+	}
+	fragment.AddEdge(*domain.NewEdge("a", "b", domain.EdgeTypeEthernet))
+	fragment.AddEdge(*domain.NewEdge("b", "d", domain.EdgeTypeEthernet))
+
+	var buf bytes.Buffer
+	if err := NewMermaidCodec(2).Export(fragment, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.Contains(out, "%% Showing 2 of 4 nodes") {
+		t.Errorf("missing truncation note:\n%s", out)
+	}
+	if strings.Contains(out, "n_c") || strings.Contains(out, "n_d") {
+		t.Errorf("nodes past the cap should be left out:\n%s", out)
+	}
+	if !strings.Contains(out, "n_a -->") || strings.Contains(out, "n_b -->") {
+		t.Errorf("only edges between kept nodes should remain:\n%s", out)
+	}
+}
+
+func TestMermaidID(t *testing.T) {
+	used := map[string]bool{}
+	if got := mermaidID("host.lan", used); got != "n_host_lan" {
+		t.Errorf("mermaidID(host.lan) = %q", got)
+	}
+	if got := mermaidID("host-lan", used); got != "n_host_lan_2" {
+		t.Errorf("colliding ID = %q, want n_host_lan_2", got)
+	}
+}
@@ -0,0 +1,160 @@
+package codec
+
+import (
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"specularium/internal/domain"
+)
+
+// propertyKind is the type a well-known property is stored as
+type propertyKind int
+
+const (
+	kindAddress propertyKind = iota // canonical IP address string
+	kindPorts                       // []int of port numbers
+	kindInt                         // int
+	kindBool                        // bool
+)
+
+// propertyKinds lists the properties coerced on import. Anything else is
+// passed through as parsed.
+var propertyKinds = map[string]propertyKind{
+	"ip":                     kindAddress,
+	"ipv6":                   kindAddress,
+	"ports":                  kindPorts,
+	"open_ports":             kindPorts,
+	domain.LinkSpeedProperty: kindInt,
+	domain.LinkMTUProperty:   kindInt,
+	domain.LinkVLANProperty:  kindInt,
+	"dhcp":                   kindBool,
+	"virtual":                kindBool,
+	"managed":                kindBool,
+}
+
+// normalizeFragment coerces well-known node and edge properties to
+// consistent types, so a port list reads the same whether it was written
+// as numbers, floats or strings
+func normalizeFragment(fragment *domain.GraphFragment) {
+	for i := range fragment.Nodes {
+		normalizeProperties(fragment.Nodes[i].Properties)
+	}
+	for i := range fragment.Edges {
+		normalizeProperties(fragment.Edges[i].Properties)
+	}
+}
+
+// normalizeProperties rewrites the well-known properties in props in
+// place. Values that cannot be coerced are left as they are for
+// validation to report.
+func normalizeProperties(props map[string]any) {
+	for key, value := range props {
+		kind, ok := propertyKinds[key]
+		if !ok {
+			continue
+		}
+
+		var coerced any
+		switch kind {
+		case kindAddress:
+			coerced, ok = coerceAddress(value)
+		case kindPorts:
+			coerced, ok = coercePorts(value)
+		case kindInt:
+			coerced, ok = coerceInt(value)
+		case kindBool:
+			coerced, ok = coerceBool(value)
+		}
+		if ok {
+			props[key] = coerced
+		}
+	}
+}
+
+// coerceAddress canonicalizes an IP address, unmapping IPv4-mapped IPv6
+func coerceAddress(value any) (string, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return "", false
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(s))
+	if err != nil {
+		return "", false
+	}
+	return addr.Unmap().String(), true
+}
+
+// coercePorts reads a list of ports, a single port or a comma-separated
+// string into []int. Every port must be between 1 and 65535.
+func coercePorts(value any) ([]int, bool) {
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	case []int:
+		items = make([]any, len(v))
+		for i, port := range v {
+			items[i] = port
+		}
+	case string:
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				items = append(items, field)
+			}
+		}
+	default:
+		items = []any{v}
+	}
+
+	ports := make([]int, 0, len(items))
+	for _, item := range items {
+		port, ok := coerceInt(item)
+		if !ok || port < 1 || port > 65535 {
+			return nil, false
+		}
+		ports = append(ports, port)
+	}
+	return ports, true
+}
+
+// coerceInt reads a whole number stored as an int, a float or a string
+func coerceInt(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// coerceBool reads a bool stored as a bool, a yes/no or true/false string,
+// or 0 and 1
+func coerceBool(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "on", "1":
+			return true, true
+		case "false", "no", "off", "0":
+			return false, true
+		}
+	case int, int64, float64:
+		if n, ok := coerceInt(v); ok && (n == 0 || n == 1) {
+			return n == 1, true
+		}
+	}
+	return false, false
+}
@@ -0,0 +1,94 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+const looseJSONImport = `{
+  "nodes": [{
+    "id": "nas",
+    "type": "server",
+    "label": "nas",
+    "properties": {
+      "ip": " ::ffff:192.168.1.20 ",
+      "ports": [22.0, "80", 443],
+      "managed": "yes",
+      "note": "007"
+    }
+  }],
+  "edges": [{
+    "from_id": "nas",
+    "to_id": "sw",
+    "type": "ethernet",
+    "properties": {"speed_mbps": 1000.0, "vlan_id": "20"}
+  }]
+}`
+
+func TestImportNormalizesProperties(t *testing.T) {
+	fragment, err := NewJSONCodec().Parse(strings.NewReader(looseJSONImport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	assertNormalized(t, "json import", fragment)
+
+	// The normalized types survive an export and re-import in either format
+	for _, c := range []interface {
+		Importer
+		Exporter
+	}{NewJSONCodec(), NewYAMLCodec()} {
+		var buf bytes.Buffer
+		if err := c.Export(fragment, &buf); err != nil {
+			t.Fatalf("%s export failed: %v", c.Format(), err)
+		}
+		reparsed, err := c.Parse(&buf)
+		if err != nil {
+			t.Fatalf("%s re-import failed: %v", c.Format(), err)
+		}
+		assertNormalized(t, c.Format()+" round trip", reparsed)
+	}
+}
+
+func assertNormalized(t *testing.T, stage string, fragment *domain.GraphFragment) {
+	t.Helper()
+	props := fragment.Nodes[0].Properties
+	if props["ip"] != "192.168.1.20" {
+		t.Errorf("%s: ip = %#v, want 192.168.1.20", stage, props["ip"])
+	}
+	if !reflect.DeepEqual(props["ports"], []int{22, 80, 443}) {
+		t.Errorf("%s: ports = %#v, want []int{22, 80, 443}", stage, props["ports"])
+	}
+	if props["managed"] != true {
+		t.Errorf("%s: managed = %#v, want true", stage, props["managed"])
+	}
+	if props["note"] != "007" {
+		t.Errorf("%s: unknown property note = %#v, want it untouched", stage, props["note"])
+	}
+
+	edge := fragment.Edges[0].Properties
+	if edge["speed_mbps"] != 1000 || edge["vlan_id"] != 20 {
+		t.Errorf("%s: edge properties = %#v, want int speed_mbps and vlan_id", stage, edge)
+	}
+}
+
+func TestNormalizePropertiesLeavesInvalidValues(t *testing.T) {
+	props := map[string]any{
+		"ip":    "nas.lan",
+		"ports": []any{22.0, 70000.0},
+		"mtu":   1500.5,
+	}
+	normalizeProperties(props)
+	if props["ip"] != "nas.lan" {
+		t.Errorf("ip = %#v, want hostname untouched", props["ip"])
+	}
+	if !reflect.DeepEqual(props["ports"], []any{22.0, 70000.0}) {
+		t.Errorf("ports = %#v, want out-of-range list untouched", props["ports"])
+	}
+	if props["mtu"] != 1500.5 {
+		t.Errorf("mtu = %#v, want fractional value untouched", props["mtu"])
+	}
+}
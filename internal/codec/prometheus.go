@@ -0,0 +1,145 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"specularium/internal/domain"
+)
+
+// PrometheusSDSource is the node source and discovered-value provenance
+// for Prometheus service discovery imports
+const PrometheusSDSource = "prometheus"
+
+// PrometheusSDCodec imports Prometheus file_sd target files:
+//
+//	[{"targets": ["10.0.0.5:9100"], "labels": {"job": "node"}}]
+//
+// Each target host becomes one node; targets differing only by port are
+// folded together and their ports kept in the discovered "ports" list.
+type PrometheusSDCodec struct{}
+
+// NewPrometheusSDCodec creates a new Prometheus service discovery codec
+func NewPrometheusSDCodec() *PrometheusSDCodec {
+	return &PrometheusSDCodec{}
+}
+
+// Format returns the codec format identifier
+func (c *PrometheusSDCodec) Format() string {
+	return "prometheus-sd"
+}
+
+// prometheusTargetGroup is one entry of a file_sd file
+type prometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// Parse imports nodes from a file_sd JSON document. Labels become node
+// properties, with later groups overriding earlier ones for a shared host;
+// internal "__" labels are skipped.
+func (c *PrometheusSDCodec) Parse(r io.Reader) (*domain.GraphFragment, error) {
+	var groups []prometheusTargetGroup
+	if err := json.NewDecoder(r).Decode(&groups); err != nil {
+		return nil, fmt.Errorf("failed to parse Prometheus service discovery file: %w", err)
+	}
+
+	nodes := make(map[string]*domain.Node)
+	ports := make(map[string]map[int]bool)
+	var order []string
+
+	for i, group := range groups {
+		for _, target := range group.Targets {
+			host, port, err := splitPrometheusTarget(target)
+			if err != nil {
+				return nil, fmt.Errorf("group %d: %w", i, err)
+			}
+
+			id := prometheusNodeID(host)
+			node, ok := nodes[id]
+			if !ok {
+				node = prometheusNode(id, host)
+				nodes[id] = node
+				ports[id] = make(map[int]bool)
+				order = append(order, id)
+			}
+			if port > 0 {
+				ports[id][port] = true
+			}
+			for key, value := range group.Labels {
+				if strings.HasPrefix(key, "__") {
+					continue
+				}
+				node.SetProperty(key, value)
+			}
+		}
+	}
+
+	fragment := domain.NewGraphFragment()
+	for _, id := range order {
+		node := nodes[id]
+		if len(ports[id]) > 0 {
+			list := make([]int, 0, len(ports[id]))
+			for port := range ports[id] {
+				list = append(list, port)
+			}
+			sort.Ints(list)
+			node.SetDiscoveredFrom(PrometheusSDSource, "ports", list, 0)
+		}
+		fragment.AddNode(*node)
+	}
+	normalizeFragment(fragment)
+	return fragment, nil
+}
+
+// splitPrometheusTarget splits "host:port", "[v6]:port" or a bare host
+func splitPrometheusTarget(target string) (string, int, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", 0, fmt.Errorf("empty target")
+	}
+
+	host, rawPort, err := net.SplitHostPort(target)
+	if err != nil {
+		// No port; a bare IPv6 address also lands here
+		return strings.Trim(target, "[]"), 0, nil
+	}
+	port, err := strconv.Atoi(rawPort)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in target %q", target)
+	}
+	if host == "" {
+		return "", 0, fmt.Errorf("missing host in target %q", target)
+	}
+	return host, port, nil
+}
+
+// prometheusNodeID derives a node ID from a target host the way the
+// scanner does for IPs, e.g. 10.0.0.5 -> 10-0-0-5
+func prometheusNodeID(host string) string {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		host = addr.WithZone("").String()
+	}
+	return strings.NewReplacer(".", "-", ":", "-").Replace(strings.ToLower(host))
+}
+
+// prometheusNode creates the node for a target host
+func prometheusNode(id, host string) *domain.Node {
+	node := domain.NewNode(id, domain.NodeTypeUnknown, host)
+	node.Source = PrometheusSDSource
+	if addr, err := netip.ParseAddr(host); err == nil {
+		node.SetProperty("ip", addr.WithZone("").String())
+	} else {
+		node.SetProperty("hostname", host)
+		if short, _, found := strings.Cut(host, "."); found && short != "" {
+			node.Label = short
+		}
+	}
+	return node
+}
@@ -87,6 +87,7 @@ func (c *YAMLCodec) Parse(r io.Reader) (*domain.GraphFragment, error) {
 		fragment.AddEdge(edge)
 	}
 
+	normalizeFragment(fragment)
 	return fragment, nil
 }
 
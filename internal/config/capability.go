@@ -13,6 +13,7 @@ type CapabilityConfig struct {
 	Enabled    bool    `yaml:"enabled"`
 	MinMode    Mode    `yaml:"min_mode,omitempty"`    // Minimum mode required
 	BinaryPath *string `yaml:"binary_path,omitempty"` // Path to external binary (plugins)
+	Secret     string  `yaml:"secret,omitempty"`      // Name of the secret to prefer (plugins)
 }
 
 // CoreCapabilities defines the built-in capabilities
@@ -25,10 +26,14 @@ type CoreCapabilities struct {
 
 // PluginCapabilities defines optional capabilities
 type PluginCapabilities struct {
-	Scanner  CapabilityConfig `yaml:"scanner"`
-	Nmap     CapabilityConfig `yaml:"nmap"`
-	SSHProbe CapabilityConfig `yaml:"ssh_probe"`
-	SNMP     CapabilityConfig `yaml:"snmp"`
+	Scanner    CapabilityConfig `yaml:"scanner"`
+	Nmap       CapabilityConfig `yaml:"nmap"`
+	SSHProbe   CapabilityConfig `yaml:"ssh_probe"`
+	SNMP       CapabilityConfig `yaml:"snmp"`
+	Traceroute CapabilityConfig `yaml:"traceroute"`
+	MDNS       CapabilityConfig `yaml:"mdns"`
+	ARP        CapabilityConfig `yaml:"arp"`
+	LLDP       CapabilityConfig `yaml:"lldp"`
 }
 
 // CapabilitiesConfig holds all capability settings
@@ -60,9 +65,25 @@ func DefaultCapabilities() CapabilitiesConfig {
 				MinMode: ModeDiscovery,
 			},
 			SNMP: CapabilityConfig{
-				Enabled: false, // Future capability
+				Enabled: false, // Requires community secret
 				MinMode: ModeDiscovery,
 			},
+			Traceroute: CapabilityConfig{
+				Enabled: false, // Requires traceroute binary
+				MinMode: ModeDiscovery,
+			},
+			MDNS: CapabilityConfig{
+				Enabled: false, // Sends multicast queries on the local link
+				MinMode: ModeDiscovery,
+			},
+			ARP: CapabilityConfig{
+				Enabled: false, // Sweeps local subnets to populate the ARP table
+				MinMode: ModeDiscovery,
+			},
+			LLDP: CapabilityConfig{
+				Enabled: false, // Requires a local lldpd
+				MinMode: ModeMonitor,
+			},
 		},
 	}
 }
@@ -142,9 +163,41 @@ func (c *CapabilitiesConfig) ListCapabilities() []CapabilityInfo {
 			Name:        "snmp",
 			Type:        CapabilityTypePlugin,
 			Enabled:     c.Plugins.SNMP.Enabled,
-			Available:   false, // Future
+			Available:   true, // Pure Go SNMPv2c client
 			MinMode:     c.Plugins.SNMP.MinMode,
-			Description: "SNMP discovery (future)",
+			Description: "SNMP interface and LLDP neighbor discovery",
+		},
+		{
+			Name:        "traceroute",
+			Type:        CapabilityTypePlugin,
+			Enabled:     c.Plugins.Traceroute.Enabled,
+			Available:   true, // Binary checked at runtime
+			MinMode:     c.Plugins.Traceroute.MinMode,
+			Description: "Router and path discovery via traceroute or mtr",
+		},
+		{
+			Name:        "mdns",
+			Type:        CapabilityTypePlugin,
+			Enabled:     c.Plugins.MDNS.Enabled,
+			Available:   true, // Pure Go DNS-SD browser
+			MinMode:     c.Plugins.MDNS.MinMode,
+			Description: "mDNS/Bonjour service and hostname discovery",
+		},
+		{
+			Name:        "arp",
+			Type:        CapabilityTypePlugin,
+			Enabled:     c.Plugins.ARP.Enabled,
+			Available:   true, // Reads /proc/net/arp; Linux only
+			MinMode:     c.Plugins.ARP.MinMode,
+			Description: "Layer-2 neighbor discovery with MAC vendor lookup",
+		},
+		{
+			Name:        "lldp",
+			Type:        CapabilityTypePlugin,
+			Enabled:     c.Plugins.LLDP.Enabled,
+			Available:   true, // lldpctl checked at runtime
+			MinMode:     c.Plugins.LLDP.MinMode,
+			Description: "Directly attached switches from the local lldpd",
 		},
 	}
 }
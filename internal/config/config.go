@@ -12,6 +12,7 @@
 package config
 
 import (
+	"crypto/rand"
 	"fmt"
 	"os"
 	"time"
@@ -46,6 +47,9 @@ func LoadFromPath(path string) (*Config, string, error) {
 	}
 
 	cfg.applyDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, path, fmt.Errorf("invalid config: %w", err)
+	}
 
 	return &cfg, path, nil
 }
@@ -93,6 +97,44 @@ func (c *Config) applyDefaults() {
 	c.Capabilities.Core.SSEEvents.Enabled = true
 }
 
+// validate rejects settings that cannot be applied safely
+func (c *Config) validate() error {
+	if c.Behavior != nil && c.Behavior.PreferredFamily != nil {
+		if err := domain.ValidateAddressFamily(*c.Behavior.PreferredFamily); err != nil {
+			return fmt.Errorf("behavior.preferred_family: %w", err)
+		}
+	}
+	return c.CORS.Validate()
+}
+
+// AdoptLegacySelfNode pins the self node ID to LegacySelfNodeID when none is
+// configured and exists reports a node under that ID, so installs from
+// before named instances keep their self node and its edges. It reports
+// whether the config changed and should be saved.
+func (c *Config) AdoptLegacySelfNode(exists func(id string) bool) bool {
+	if c.Instance.NodeID != "" || c.Instance.SelfNodeID() == LegacySelfNodeID || !exists(LegacySelfNodeID) {
+		return false
+	}
+	c.Instance.NodeID = LegacySelfNodeID
+	return true
+}
+
+// EnsureInstanceUUID assigns a random instance UUID if none is set and
+// reports whether one was generated, so the caller can persist it
+func (c *Config) EnsureInstanceUUID() bool {
+	if c.Instance.UUID != "" {
+		return false
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return false
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	c.Instance.UUID = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	return true
+}
+
 // EffectiveMode returns the mode to use (override > recommendation > default)
 func (c *Config) EffectiveMode() Mode {
 	// Explicit override takes precedence
@@ -130,13 +172,47 @@ func (c *Config) EffectiveBehavior() BehaviorProfile {
 	if c.Behavior.MaxConcurrentProbes != nil {
 		base.MaxConcurrentProbes = *c.Behavior.MaxConcurrentProbes
 	}
+	if c.Behavior.MinConcurrentProbes != nil {
+		base.MinConcurrentProbes = *c.Behavior.MinConcurrentProbes
+	}
 	if c.Behavior.MaxConcurrentScans != nil {
 		base.MaxConcurrentScans = *c.Behavior.MaxConcurrentScans
 	}
+	if c.Behavior.PreferredFamily != nil {
+		base.PreferredFamily = *c.Behavior.PreferredFamily
+	}
+	if c.Behavior.ProbeBothFamilies != nil {
+		base.ProbeBothFamilies = *c.Behavior.ProbeBothFamilies
+	}
+	if c.Behavior.NativeICMP != nil {
+		base.NativeICMP = *c.Behavior.NativeICMP
+	}
+	if c.Behavior.EvidenceHalfLife != nil {
+		base.EvidenceHalfLife = c.Behavior.EvidenceHalfLife.Duration()
+	}
+	if len(c.Behavior.VerifyBackoff) > 0 {
+		base.VerifyBackoff = make([]time.Duration, len(c.Behavior.VerifyBackoff))
+		for i, d := range c.Behavior.VerifyBackoff {
+			base.VerifyBackoff[i] = d.Duration()
+		}
+	}
+	if c.Behavior.MaxScanHosts != nil {
+		base.MaxScanHosts = *c.Behavior.MaxScanHosts
+	}
 
 	return base
 }
 
+// EffectiveMaxScanHosts returns the subnet scan size limit: the behavior's
+// max_scan_hosts, capped lower when the mode is below discovery
+func (c *Config) EffectiveMaxScanHosts() int {
+	limit := c.EffectiveBehavior().MaxScanHosts
+	if !c.EffectiveMode().Allows(ModeDiscovery) {
+		limit = min(limit, lowResourceMaxScanHosts)
+	}
+	return limit
+}
+
 // NeedsBootstrap returns true if bootstrap should run
 func (c *Config) NeedsBootstrap() bool {
 	return c.Bootstrap == nil
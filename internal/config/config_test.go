@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 )
@@ -168,6 +169,40 @@ func TestEffectiveBehavior(t *testing.T) {
 	}
 }
 
+func TestEffectiveMaxScanHosts(t *testing.T) {
+	discovery := ModeDiscovery
+	monitor := ModeMonitor
+	raised := 4096
+
+	tests := []struct {
+		name     string
+		mode     *Mode
+		posture  Posture
+		override *int
+		want     int
+	}{
+		{"balanced discovery", &discovery, PostureBalanced, nil, 1024},
+		{"aggressive discovery", &discovery, PostureAggressive, nil, 4096},
+		{"override raises the cap", &discovery, PostureBalanced, &raised, 4096},
+		{"monitor mode caps lower", &monitor, PostureBalanced, nil, 256},
+		{"monitor mode caps overrides", &monitor, PostureBalanced, &raised, 256},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Mode = tt.mode
+			cfg.Posture = tt.posture
+			if tt.override != nil {
+				cfg.Behavior = &BehaviorOverride{MaxScanHosts: tt.override}
+			}
+			if got := cfg.EffectiveMaxScanHosts(); got != tt.want {
+				t.Errorf("EffectiveMaxScanHosts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestModeExceedsRecommendation(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -232,7 +267,7 @@ func TestSaveAndLoad(t *testing.T) {
 	cfg.Posture = PostureAggressive
 	mode := ModeDiscovery
 	cfg.Mode = &mode
-	cfg.Targets.Primary = []string{"192.168.1.0/24"}
+	cfg.Targets.Primary = []ScanTarget{{CIDR: "192.168.1.0/24", Enabled: true}}
 
 	if err := cfg.Save(configPath); err != nil {
 		t.Fatalf("Save() error: %v", err)
@@ -254,11 +289,130 @@ func TestSaveAndLoad(t *testing.T) {
 	if loaded.Mode == nil || *loaded.Mode != ModeDiscovery {
 		t.Error("Mode should be discovery")
 	}
-	if len(loaded.Targets.Primary) != 1 || loaded.Targets.Primary[0] != "192.168.1.0/24" {
+	if len(loaded.Targets.Primary) != 1 || loaded.Targets.Primary[0].CIDR != "192.168.1.0/24" {
 		t.Errorf("Targets.Primary = %v, want [192.168.1.0/24]", loaded.Targets.Primary)
 	}
 }
 
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+	}{
+		{"credentials with wildcard origin", "cors:\n  allowed_origins: [\"*\"]\n  allow_credentials: true\n"},
+		{"unknown preferred family", "behavior:\n  preferred_family: ipv5\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+			if _, _, err := LoadFromPath(path); err == nil {
+				t.Error("LoadFromPath() succeeded, want a validation error")
+			}
+		})
+	}
+}
+
+func TestAdoptLegacySelfNode(t *testing.T) {
+	exists := func(ids ...string) func(string) bool {
+		return func(id string) bool { return slices.Contains(ids, id) }
+	}
+
+	tests := []struct {
+		name     string
+		instance InstanceConfig
+		exists   func(string) bool
+		want     string
+		changed  bool
+	}{
+		{"legacy node kept", InstanceConfig{Name: "lab"}, exists(LegacySelfNodeID), LegacySelfNodeID, true},
+		{"fresh install gets a named ID", InstanceConfig{Name: "lab"}, exists(), "specularium-lab", false},
+		{"explicit ID wins", InstanceConfig{Name: "lab", NodeID: "observer-1"}, exists(LegacySelfNodeID), "observer-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Instance = tt.instance
+			if changed := cfg.AdoptLegacySelfNode(tt.exists); changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if got := cfg.Instance.SelfNodeID(); got != tt.want {
+				t.Errorf("SelfNodeID() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTargetYAML(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	data := `
+targets:
+  primary:
+    - 192.168.1.0/24
+    - cidr: 10.0.0.0/24
+      label: lab
+      note: rack two
+    - cidr: 172.16.0.0/24
+      enabled: false
+      note: muted during rewiring
+`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	cfg, _, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromPath() error: %v", err)
+	}
+
+	want := []ScanTarget{
+		{CIDR: "192.168.1.0/24", Enabled: true},
+		{CIDR: "10.0.0.0/24", Label: "lab", Enabled: true, Note: "rack two"},
+		{CIDR: "172.16.0.0/24", Enabled: false, Note: "muted during rewiring"},
+	}
+	if len(cfg.Targets.Primary) != len(want) {
+		t.Fatalf("Targets.Primary has %d entries, want %d", len(cfg.Targets.Primary), len(want))
+	}
+	for i, target := range cfg.Targets.Primary {
+		if target != want[i] {
+			t.Errorf("Targets.Primary[%d] = %+v, want %+v", i, target, want[i])
+		}
+	}
+
+	enabled := cfg.Targets.EnabledPrimary()
+	if len(enabled) != 2 || enabled[0] != "192.168.1.0/24" || enabled[1] != "10.0.0.0/24" {
+		t.Errorf("EnabledPrimary() = %v, want [192.168.1.0/24 10.0.0.0/24]", enabled)
+	}
+
+	// Round trip preserves annotations and disabled state
+	if err := cfg.Save(configPath); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	reloaded, _, err := LoadFromPath(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromPath() error: %v", err)
+	}
+	for i, target := range reloaded.Targets.Primary {
+		if target != want[i] {
+			t.Errorf("reloaded Targets.Primary[%d] = %+v, want %+v", i, target, want[i])
+		}
+	}
+
+	// Expanded entries require a CIDR
+	if err := os.WriteFile(configPath, []byte("targets:\n  primary:\n    - label: broken\n"), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if _, _, err := LoadFromPath(configPath); err == nil {
+		t.Error("LoadFromPath() should reject target without cidr")
+	}
+}
+
 func TestFindConfigPath(t *testing.T) {
 	// Create temp directory with config
 	tmpDir := t.TempDir()
@@ -72,16 +72,27 @@ func ParsePosture(s string) Posture {
 
 // BehaviorProfile defines timing and concurrency settings
 type BehaviorProfile struct {
-	VerifyInterval      time.Duration `yaml:"verify_interval"`
-	ScanInterval        time.Duration `yaml:"scan_interval"`
-	ProbeTimeout        time.Duration `yaml:"probe_timeout"`
-	MaxConcurrentProbes int           `yaml:"max_concurrent_probes"`
-	MaxConcurrentScans  int           `yaml:"max_concurrent_scans"`
-	MaxRetries          int           `yaml:"max_retries"`
-	RateLimitPerHost    int           `yaml:"rate_limit_per_host"` // probes per minute
-	JitterPercent       int           `yaml:"jitter_percent"`      // timing variance
+	VerifyInterval      time.Duration   `yaml:"verify_interval"`
+	ScanInterval        time.Duration   `yaml:"scan_interval"`
+	ProbeTimeout        time.Duration   `yaml:"probe_timeout"`
+	MaxConcurrentProbes int             `yaml:"max_concurrent_probes"`
+	MinConcurrentProbes int             `yaml:"min_concurrent_probes"` // adaptive concurrency floor, 0 keeps concurrency fixed
+	MaxConcurrentScans  int             `yaml:"max_concurrent_scans"`
+	MaxRetries          int             `yaml:"max_retries"`
+	RateLimitPerHost    int             `yaml:"rate_limit_per_host"`      // probes per minute
+	JitterPercent       int             `yaml:"jitter_percent"`           // timing variance
+	PreferredFamily     string          `yaml:"preferred_family"`         // ipv4 (default) or ipv6
+	ProbeBothFamilies   bool            `yaml:"probe_both_families"`      // probe both addresses on dual-stack nodes
+	NativeICMP          bool            `yaml:"native_icmp"`              // ping over ICMP sockets, falling back to the ping binary
+	EvidenceHalfLife    time.Duration   `yaml:"evidence_half_life"`       // evidence confidence half-life, 0 disables decay
+	VerifyBackoff       []time.Duration `yaml:"verify_backoff,omitempty"` // re-verify delays for unreachable nodes, nil uses the default
+	MaxScanHosts        int             `yaml:"max_scan_hosts"`           // largest subnet scan, in addresses
 }
 
+// lowResourceMaxScanHosts caps subnet scans below discovery mode, where
+// the host was not judged fit for full scanning
+const lowResourceMaxScanHosts = 256
+
 // PostureProfiles maps postures to their default behavior profiles
 var PostureProfiles = map[Posture]BehaviorProfile{
 	PostureStealth: {
@@ -93,6 +104,7 @@ var PostureProfiles = map[Posture]BehaviorProfile{
 		MaxRetries:          0,
 		RateLimitPerHost:    1,
 		JitterPercent:       30,
+		MaxScanHosts:        256,
 	},
 	PostureCautious: {
 		VerifyInterval:      30 * time.Minute,
@@ -103,6 +115,7 @@ var PostureProfiles = map[Posture]BehaviorProfile{
 		MaxRetries:          1,
 		RateLimitPerHost:    5,
 		JitterPercent:       20,
+		MaxScanHosts:        512,
 	},
 	PostureBalanced: {
 		VerifyInterval:      5 * time.Minute,
@@ -113,6 +126,7 @@ var PostureProfiles = map[Posture]BehaviorProfile{
 		MaxRetries:          2,
 		RateLimitPerHost:    10,
 		JitterPercent:       10,
+		MaxScanHosts:        1024,
 	},
 	PostureAggressive: {
 		VerifyInterval:      30 * time.Second,
@@ -123,6 +137,7 @@ var PostureProfiles = map[Posture]BehaviorProfile{
 		MaxRetries:          3,
 		RateLimitPerHost:    60,
 		JitterPercent:       0,
+		MaxScanHosts:        4096,
 	},
 }
 
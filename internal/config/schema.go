@@ -1,20 +1,35 @@
 package config
 
 import (
+	"fmt"
+	"os"
+	"strings"
 	"time"
+
+	"specularium/internal/domain"
 )
 
 // Config is the root configuration structure
 type Config struct {
 	Version      int                `yaml:"version"`
+	Instance     InstanceConfig     `yaml:"instance,omitempty"`
 	Bootstrap    *BootstrapResult   `yaml:"bootstrap,omitempty"`
-	Mode         *Mode              `yaml:"mode"`    // nil = use bootstrap recommendation
+	Mode         *Mode              `yaml:"mode"` // nil = use bootstrap recommendation
 	Posture      Posture            `yaml:"posture"`
 	Behavior     *BehaviorOverride  `yaml:"behavior,omitempty"`
 	Database     DatabaseConfig     `yaml:"database"`
 	Capabilities CapabilitiesConfig `yaml:"capabilities"`
 	Targets      TargetConfig       `yaml:"targets"`
 	Secrets      SecretsConfig      `yaml:"secrets"`
+	Enrichment   EnrichmentConfig   `yaml:"enrichment,omitempty"`
+	Events       EventsConfig       `yaml:"events,omitempty"`
+	Discovery    DiscoveryConfig    `yaml:"discovery,omitempty"`
+	Reconcile    ReconcileConfig    `yaml:"reconcile,omitempty"`
+	Notify       NotifyConfig       `yaml:"notify,omitempty"`
+	Auth         AuthConfig         `yaml:"auth,omitempty"`
+	Stale        StaleConfig        `yaml:"stale,omitempty"`
+	Logging      LoggingConfig      `yaml:"logging,omitempty"`
+	CORS         CORSConfig         `yaml:"cors,omitempty"`
 }
 
 // BootstrapResult stores self-discovery findings (written by bootstrap)
@@ -74,22 +89,125 @@ type ModeRecommendation struct {
 
 // BehaviorOverride allows overriding posture defaults
 type BehaviorOverride struct {
-	VerifyInterval      *Duration `yaml:"verify_interval,omitempty"`
-	ScanInterval        *Duration `yaml:"scan_interval,omitempty"`
-	ProbeTimeout        *Duration `yaml:"probe_timeout,omitempty"`
-	MaxConcurrentProbes *int      `yaml:"max_concurrent_probes,omitempty"`
-	MaxConcurrentScans  *int      `yaml:"max_concurrent_scans,omitempty"`
+	VerifyInterval      *Duration  `yaml:"verify_interval,omitempty"`
+	ScanInterval        *Duration  `yaml:"scan_interval,omitempty"`
+	ProbeTimeout        *Duration  `yaml:"probe_timeout,omitempty"`
+	MaxConcurrentProbes *int       `yaml:"max_concurrent_probes,omitempty"`
+	MinConcurrentProbes *int       `yaml:"min_concurrent_probes,omitempty"` // Adaptive probe concurrency floor (0 = fixed)
+	MaxConcurrentScans  *int       `yaml:"max_concurrent_scans,omitempty"`
+	PreferredFamily     *string    `yaml:"preferred_family,omitempty"`    // ipv4 or ipv6
+	ProbeBothFamilies   *bool      `yaml:"probe_both_families,omitempty"` // Record per-family reachability
+	NativeICMP          *bool      `yaml:"native_icmp,omitempty"`         // Ping over ICMP sockets instead of the ping binary
+	EvidenceHalfLife    *Duration  `yaml:"evidence_half_life,omitempty"`  // Capability confidence decay (0 = off)
+	VerifyBackoff       []Duration `yaml:"verify_backoff,omitempty"`      // Re-verify delays after consecutive unreachable results
+	MaxScanHosts        *int       `yaml:"max_scan_hosts,omitempty"`      // Largest subnet scan, in addresses
 }
 
 // DatabaseConfig holds database settings
 type DatabaseConfig struct {
 	Path string `yaml:"path"`
+	// Connection pool tuning; zero values use the repository defaults
+	// (unbounded connections, 5s busy timeout)
+	MaxOpenConns int      `yaml:"max_open_conns,omitempty"`
+	MaxIdleConns int      `yaml:"max_idle_conns,omitempty"`
+	BusyTimeout  Duration `yaml:"busy_timeout,omitempty"`
 }
 
 // TargetConfig holds discovery targets
 type TargetConfig struct {
-	Primary   []string `yaml:"primary,omitempty"`   // Main monitored networks
-	Discovery []string `yaml:"discovery,omitempty"` // Additional discovery targets
+	Primary   []ScanTarget `yaml:"primary,omitempty"`   // Main monitored networks
+	Discovery []string     `yaml:"discovery,omitempty"` // Additional discovery targets
+}
+
+// ScanTarget is an annotated scan target entry.
+// Accepts either a plain CIDR string or the expanded form:
+//
+//	primary:
+//	  - 192.168.1.0/24
+//	  - cidr: 10.0.0.0/24
+//	    label: lab
+//	    enabled: false
+//	    note: muted while the rack is rewired
+type ScanTarget struct {
+	CIDR    string `yaml:"cidr" json:"cidr"`
+	Label   string `yaml:"label,omitempty" json:"label,omitempty"`
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	Note    string `yaml:"note,omitempty" json:"note,omitempty"`
+	// Profile selects a discovery port profile for nmap scans of this target
+	Profile string `yaml:"profile,omitempty" json:"profile,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+// Plain strings are treated as enabled targets; entries default to enabled
+func (t *ScanTarget) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var cidr string
+	if err := unmarshal(&cidr); err == nil {
+		*t = ScanTarget{CIDR: cidr, Enabled: true}
+		return nil
+	}
+
+	var entry struct {
+		CIDR    string `yaml:"cidr"`
+		Label   string `yaml:"label"`
+		Enabled *bool  `yaml:"enabled"`
+		Note    string `yaml:"note"`
+		Profile string `yaml:"profile"`
+	}
+	if err := unmarshal(&entry); err != nil {
+		return err
+	}
+	if entry.CIDR == "" {
+		return fmt.Errorf("scan target missing cidr")
+	}
+
+	*t = ScanTarget{
+		CIDR:    entry.CIDR,
+		Label:   entry.Label,
+		Enabled: entry.Enabled == nil || *entry.Enabled,
+		Note:    entry.Note,
+		Profile: entry.Profile,
+	}
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler
+// Unannotated enabled targets are written back as plain strings
+func (t ScanTarget) MarshalYAML() (interface{}, error) {
+	if t.Enabled && t.Label == "" && t.Note == "" && t.Profile == "" {
+		return t.CIDR, nil
+	}
+	type plain ScanTarget
+	return plain(t), nil
+}
+
+// EnabledPrimary returns the CIDRs of enabled primary targets
+func (t *TargetConfig) EnabledPrimary() []string {
+	var cidrs []string
+	for _, target := range t.Primary {
+		if target.Enabled {
+			cidrs = append(cidrs, target.CIDR)
+		}
+	}
+	return cidrs
+}
+
+// PrimaryProfiles maps enabled primary targets that select a port profile
+// to the profile name
+func (t *TargetConfig) PrimaryProfiles() map[string]string {
+	profiles := make(map[string]string)
+	for _, target := range t.Primary {
+		if target.Enabled && target.Profile != "" {
+			profiles[target.CIDR] = target.Profile
+		}
+	}
+	return profiles
+}
+
+// ListTargets returns all primary targets, including disabled ones
+func (t *TargetConfig) ListTargets() []ScanTarget {
+	targets := make([]ScanTarget, len(t.Primary))
+	copy(targets, t.Primary)
+	return targets
 }
 
 // SecretsConfig holds references to secrets (paths, not values)
@@ -98,6 +216,191 @@ type SecretsConfig struct {
 	DNSServer  *string `yaml:"dns_server,omitempty"`
 }
 
+// EnrichmentConfig controls the first-contact pipeline run on newly discovered nodes
+type EnrichmentConfig struct {
+	// Steps in execution order: ptr, banner, tls_cert, http_title, forward_dns.
+	// Empty uses the built-in order.
+	Steps []string `yaml:"steps,omitempty"`
+	// StopConfidence ends the pipeline once a hostname candidate reaches it (0 = default)
+	StopConfidence float64 `yaml:"stop_confidence,omitempty"`
+	// Disabled turns first-contact enrichment off
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// DefaultEventHistorySize is the number of events retained for replay
+// when events.history_size is unset
+const DefaultEventHistorySize = 500
+
+// DefaultEventBufferSize is the SSE forwarding channel capacity when
+// events.buffer_size is unset
+const DefaultEventBufferSize = 100
+
+// EventsConfig controls the event bus
+type EventsConfig struct {
+	// HistorySize is how many recent events are kept for
+	// /api/events/history and SSE resumption (0 = default, negative disables)
+	HistorySize int `yaml:"history_size,omitempty"`
+	// BufferSize is the capacity of the channel forwarding events to SSE
+	// clients (0 = default)
+	BufferSize int `yaml:"buffer_size,omitempty"`
+	// Overflow is what publishing does when a subscriber's buffer is full:
+	// drop-newest (default), drop-oldest or block
+	Overflow string `yaml:"overflow,omitempty"`
+}
+
+// EffectiveBufferSize returns the configured buffer size with the default
+// applied
+func (e EventsConfig) EffectiveBufferSize() int {
+	if e.BufferSize <= 0 {
+		return DefaultEventBufferSize
+	}
+	return e.BufferSize
+}
+
+// EffectiveHistorySize returns the configured history size with the
+// default applied
+func (e EventsConfig) EffectiveHistorySize() int {
+	switch {
+	case e.HistorySize < 0:
+		return 0
+	case e.HistorySize == 0:
+		return DefaultEventHistorySize
+	}
+	return e.HistorySize
+}
+
+// Discovery concurrency defaults applied when discovery settings are unset
+const (
+	DefaultMaxConcurrentDiscovery = 2
+	DefaultMaxQueuedDiscovery     = 8
+	// DefaultOperationTimeout bounds a requested scan, verification or
+	// bootstrap so a hung host cannot hold a discovery slot forever
+	DefaultOperationTimeout = 30 * time.Minute
+)
+
+// DiscoveryConfig limits concurrent discovery work across all adapters
+type DiscoveryConfig struct {
+	// MaxConcurrent caps scans, syncs and verification running at once (0 = default)
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+	// MaxQueued caps requested operations waiting for a slot before
+	// requests are rejected (0 = default, negative disables queueing)
+	MaxQueued int `yaml:"max_queued,omitempty"`
+	// PortProfiles names port lists that a scan request or primary target
+	// can select instead of the default ports, e.g. iot: [80, 554, 1883]
+	PortProfiles map[string][]int `yaml:"port_profiles,omitempty"`
+	// OperationTimeout is the deadline for scans, verification and
+	// bootstrap started from the API (0 = default, negative = none)
+	OperationTimeout Duration `yaml:"operation_timeout,omitempty"`
+	// TypeWeights replaces the port evidence rules used to infer node types
+	// in nmap and scanner results (empty = domain.DefaultPortTypeWeights)
+	TypeWeights []domain.PortTypeWeight `yaml:"type_weights,omitempty"`
+}
+
+// EffectiveMaxConcurrent returns the concurrency limit with the default applied
+func (d DiscoveryConfig) EffectiveMaxConcurrent() int {
+	if d.MaxConcurrent <= 0 {
+		return DefaultMaxConcurrentDiscovery
+	}
+	return d.MaxConcurrent
+}
+
+// EffectiveMaxQueued returns the queue limit with the default applied
+func (d DiscoveryConfig) EffectiveMaxQueued() int {
+	switch {
+	case d.MaxQueued < 0:
+		return 0
+	case d.MaxQueued == 0:
+		return DefaultMaxQueuedDiscovery
+	}
+	return d.MaxQueued
+}
+
+// EffectiveOperationTimeout returns the API operation deadline with the
+// default applied, or 0 for no deadline
+func (d DiscoveryConfig) EffectiveOperationTimeout() time.Duration {
+	switch {
+	case d.OperationTimeout < 0:
+		return 0
+	case d.OperationTimeout == 0:
+		return DefaultOperationTimeout
+	}
+	return d.OperationTimeout.Duration()
+}
+
+// ReconcileConfig controls how discoveries from different sources combine
+type ReconcileConfig struct {
+	// SourcePriority overrides the rank of discovery sources by adapter
+	// name. A discovered property set by a higher-ranked source is not
+	// overwritten by a lower one; unlisted sources keep their defaults.
+	SourcePriority map[string]int `yaml:"source_priority,omitempty"`
+	// Debounce coalesces the node and edge events reconcile raises within
+	// this window into one reconcile-batch event (0 = publish each change)
+	Debounce Duration `yaml:"debounce,omitempty"`
+	// Allow limits the discovered properties that count as changes and are
+	// checked against truth to those matching these glob patterns (empty =
+	// all)
+	Allow []string `yaml:"allow,omitempty"`
+	// Deny excludes discovered properties matching these glob patterns;
+	// unset keeps the default (latencies and timestamps), [] denies none
+	Deny []string `yaml:"deny,omitempty"`
+}
+
+// NotifyConfig sends selected events to external services
+type NotifyConfig struct {
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+	// Timeout bounds each webhook request (0 = default)
+	Timeout Duration `yaml:"timeout,omitempty"`
+	// Retries is how many times a failed delivery is retried (0 = default,
+	// negative disables retries)
+	Retries int `yaml:"retries,omitempty"`
+}
+
+// WebhookConfig is a URL posted to when selected events occur
+type WebhookConfig struct {
+	URL string `yaml:"url"`
+	// Events lists event types to send (empty = node-unreachable and
+	// discrepancy-created)
+	Events []string `yaml:"events,omitempty"`
+	// Payload is a Go template for the request body; empty sends the event JSON
+	Payload string `yaml:"payload,omitempty"`
+	// Headers are added to every request
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Stale sweep defaults applied when stale settings are unset
+const (
+	DefaultStaleAfter         = 7 * 24 * time.Hour
+	DefaultStaleSweepInterval = 15 * time.Minute
+)
+
+// StaleConfig controls marking nodes that have not been seen for a while
+type StaleConfig struct {
+	// After marks nodes stale once last_seen is older than this (0 = default)
+	After Duration `yaml:"after,omitempty"`
+	// DeleteAfter soft-deletes nodes once last_seen is older than this (0 = never)
+	DeleteAfter Duration `yaml:"delete_after,omitempty"`
+	// Interval between sweeps (0 = default)
+	Interval Duration `yaml:"interval,omitempty"`
+	// Disabled turns the sweep off
+	Disabled bool `yaml:"disabled,omitempty"`
+}
+
+// EffectiveAfter returns the stale threshold with the default applied
+func (s StaleConfig) EffectiveAfter() time.Duration {
+	if s.After <= 0 {
+		return DefaultStaleAfter
+	}
+	return time.Duration(s.After)
+}
+
+// EffectiveInterval returns the sweep interval with the default applied
+func (s StaleConfig) EffectiveInterval() time.Duration {
+	if s.Interval <= 0 {
+		return DefaultStaleSweepInterval
+	}
+	return time.Duration(s.Interval)
+}
+
 // Duration wraps time.Duration for YAML unmarshaling
 type Duration time.Duration
 
@@ -124,3 +427,143 @@ func (d Duration) MarshalYAML() (interface{}, error) {
 func (d Duration) Duration() time.Duration {
 	return time.Duration(d)
 }
+
+// InstanceConfig identifies this Specularium instance. Instances observing
+// overlapping networks need distinct names so their self nodes don't collide.
+type InstanceConfig struct {
+	// Name identifies the instance (empty = hostname)
+	Name string `yaml:"name,omitempty"`
+	// UUID is generated on first run and kept in the config file
+	UUID string `yaml:"uuid,omitempty"`
+	// NodeID overrides the self node ID (empty = "specularium-" + name)
+	NodeID string `yaml:"node_id,omitempty"`
+	// Label overrides the self node label (empty = name)
+	Label string `yaml:"label,omitempty"`
+	// Role overrides the self node role (empty = observer)
+	Role string `yaml:"role,omitempty"`
+}
+
+// EffectiveName returns the instance name, falling back to the hostname
+func (i InstanceConfig) EffectiveName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		return hostname
+	}
+	return "specularium"
+}
+
+// LegacySelfNodeID is the self node ID used before instances were named
+const LegacySelfNodeID = "specularium"
+
+// SelfNodeID returns the ID of the node representing this instance
+func (i InstanceConfig) SelfNodeID() string {
+	if i.NodeID != "" {
+		return i.NodeID
+	}
+	slug := slugify(i.EffectiveName())
+	if slug == "" {
+		return LegacySelfNodeID
+	}
+	return "specularium-" + slug
+}
+
+// SelfLabel returns the label of the node representing this instance
+func (i InstanceConfig) SelfLabel() string {
+	if i.Label != "" {
+		return i.Label
+	}
+	return i.EffectiveName()
+}
+
+// SelfRole returns the role of the node representing this instance. An
+// unrecognized role falls back to observer.
+func (i InstanceConfig) SelfRole() domain.NodeRole {
+	if role, err := domain.NormalizeRole(i.Role); err == nil && role != "" {
+		return role
+	}
+	return domain.RoleObserver
+}
+
+// slugify lowercases s and replaces anything but letters and digits with '-'
+func slugify(s string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+// AuthConfig controls bearer-token authentication of the API. Tokens are
+// api_token secrets; the web UI's static files are always served.
+type AuthConfig struct {
+	// Enabled requires a valid token on /api and /events requests
+	Enabled bool `yaml:"enabled,omitempty"`
+	// ReadAnonymous lets GET and HEAD requests through without a token,
+	// except on the secrets API
+	ReadAnonymous bool `yaml:"read_anonymous,omitempty"`
+}
+
+// LoggingConfig controls server log output. The LOG_LEVEL environment
+// variable overrides Level.
+type LoggingConfig struct {
+	// Level is debug, info, warn or error (empty = info)
+	Level string `yaml:"level,omitempty"`
+	// Format is text or json (empty = text)
+	Format string `yaml:"format,omitempty"`
+}
+
+// Default CORS methods and headers applied when cors settings are unset
+var (
+	DefaultCORSMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	DefaultCORSHeaders = []string{"Content-Type", "Authorization", "Idempotency-Key"}
+)
+
+// CORSConfig controls cross-origin access to the API
+type CORSConfig struct {
+	// AllowedOrigins lists origins whose requests are allowed; empty
+	// allows any origin with a wildcard
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	// AllowCredentials lets allowed origins send cookies and auth headers.
+	// Only honored with an explicit allowlist; "*" in the list is rejected.
+	AllowCredentials bool `yaml:"allow_credentials,omitempty"`
+	// AllowedMethods is sent on preflight responses (empty = default)
+	AllowedMethods []string `yaml:"allowed_methods,omitempty"`
+	// AllowedHeaders is sent on preflight responses (empty = default)
+	AllowedHeaders []string `yaml:"allowed_headers,omitempty"`
+}
+
+// Validate rejects credentials for a wildcard origin, which would let any
+// site make credentialed requests
+func (c CORSConfig) Validate() error {
+	if !c.AllowCredentials {
+		return nil
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			return fmt.Errorf("cors.allow_credentials cannot be combined with allowed origin \"*\"")
+		}
+	}
+	return nil
+}
+
+// EffectiveMethods returns the allowed methods with the default applied
+func (c CORSConfig) EffectiveMethods() []string {
+	if len(c.AllowedMethods) == 0 {
+		return DefaultCORSMethods
+	}
+	return c.AllowedMethods
+}
+
+// EffectiveHeaders returns the allowed headers with the default applied
+func (c CORSConfig) EffectiveHeaders() []string {
+	if len(c.AllowedHeaders) == 0 {
+		return DefaultCORSHeaders
+	}
+	return c.AllowedHeaders
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"math"
 	"time"
 )
 
@@ -16,6 +17,7 @@ const (
 	CapabilityDHCP       CapabilityType = "dhcp"
 	CapabilitySMB        CapabilityType = "smb"
 	CapabilityNFS        CapabilityType = "nfs"
+	CapabilitySNMP       CapabilityType = "snmp"
 )
 
 // EvidenceSource identifies how evidence was gathered
@@ -32,6 +34,7 @@ const (
 	EvidenceSourceDNS          EvidenceSource = "dns"            // DNS query result
 	EvidenceSourceCorrelation  EvidenceSource = "correlation"    // Inferred from other evidence
 	EvidenceSourceOperator     EvidenceSource = "operator"       // Operator assertion
+	EvidenceSourceOUI          EvidenceSource = "oui"            // MAC address vendor prefix
 )
 
 // Evidence represents a single piece of discovered information
@@ -83,40 +86,77 @@ func (c *Capability) ConfidenceStatus() string {
 	}
 }
 
+// Summary returns a copy of the capability without its evidence list and
+// with Status derived from the current confidence
+func (c *Capability) Summary() *Capability {
+	summary := *c
+	summary.Evidence = nil
+	summary.Status = c.ConfidenceStatus()
+	return &summary
+}
+
 // AddEvidence adds evidence and recalculates aggregate confidence
 func (c *Capability) AddEvidence(e Evidence) {
 	c.Evidence = append(c.Evidence, e)
 	c.recalculateConfidence()
 }
 
-// recalculateConfidence computes aggregate confidence from all evidence
-// Uses a "max + bonus" approach: highest evidence confidence + small bonus for corroborating evidence
+// RefreshConfidence recalculates confidence so aged evidence decays even when
+// no new evidence has arrived. A zero halfLife disables decay.
+func (c *Capability) RefreshConfidence(now time.Time, halfLife time.Duration) {
+	c.Confidence = AggregateConfidence(DecayEvidence(c.Evidence, halfLife, now))
+	c.Status = c.ConfidenceStatus()
+}
+
+// recalculateConfidence computes aggregate confidence from all evidence;
+// decay is applied when capabilities are read via RefreshConfidence
 func (c *Capability) recalculateConfidence() {
-	if len(c.Evidence) == 0 {
-		c.Confidence = 0
-		c.Status = "speculative"
-		return
+	c.RefreshConfidence(time.Now(), 0)
+}
+
+// DecayEvidence returns copies of evidence with confidence halved for every
+// halfLife elapsed since observation. Operator assertions never decay.
+func DecayEvidence(evidence []Evidence, halfLife time.Duration, now time.Time) []Evidence {
+	if halfLife <= 0 {
+		return evidence
 	}
 
+	decayed := make([]Evidence, len(evidence))
+	for i, e := range evidence {
+		decayed[i] = e
+		age := now.Sub(e.ObservedAt)
+		if e.Source == EvidenceSourceOperator || e.ObservedAt.IsZero() || age <= 0 {
+			continue
+		}
+		decayed[i].Confidence = e.Confidence * math.Pow(0.5, float64(age)/float64(halfLife))
+	}
+	return decayed
+}
+
+// AggregateConfidence combines evidence into a single confidence score
+// Uses a "max + bonus" approach: highest evidence confidence + small bonus for corroborating evidence
+func AggregateConfidence(evidence []Evidence) float64 {
 	// Find max confidence
 	maxConf := 0.0
-	for _, e := range c.Evidence {
+	for _, e := range evidence {
 		if e.Confidence > maxConf {
 			maxConf = e.Confidence
 		}
 	}
+	if maxConf == 0 {
+		return 0
+	}
 
 	// Add small bonus for corroborating evidence (diminishing returns)
 	bonus := 0.0
-	for _, e := range c.Evidence {
+	for _, e := range evidence {
 		if e.Confidence < maxConf {
 			// Each corroborating piece adds up to 5% of remaining gap to 1.0
 			bonus += (1.0 - maxConf) * 0.05 * (e.Confidence / maxConf)
 		}
 	}
 
-	c.Confidence = min(1.0, maxConf+bonus)
-	c.Status = c.ConfidenceStatus()
+	return min(1.0, maxConf+bonus)
 }
 
 // KubernetesCapability holds K8s-specific capability details
@@ -493,3 +493,66 @@ func TestCapability_RecalculateConfidence(t *testing.T) {
 		}
 	})
 }
+
+func TestCapability_ConfidenceDecay(t *testing.T) {
+	now := time.Now()
+	halfLife := 24 * time.Hour
+
+	tests := []struct {
+		name       string
+		age        time.Duration
+		wantStatus string
+	}{
+		{"fresh evidence stays confirmed", 0, "confirmed"},
+		{"one half-life drops to probable", halfLife, "probable"},
+		{"two half-lives drop to speculative", 2 * halfLife, "speculative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cap := &Capability{
+				Type: CapabilitySSH,
+				Evidence: []Evidence{{
+					Source:     EvidenceSourceSSHProbe,
+					Confidence: 0.9,
+					ObservedAt: now.Add(-tt.age),
+				}},
+			}
+			cap.RefreshConfidence(now, halfLife)
+
+			if cap.Status != tt.wantStatus {
+				t.Errorf("status = %s (confidence %.3f), want %s", cap.Status, cap.Confidence, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("re-observation restores confidence", func(t *testing.T) {
+		cap := &Capability{Type: CapabilityDocker}
+		cap.Evidence = []Evidence{{Source: EvidenceSourceDockerAPI, Confidence: 0.95, ObservedAt: now.Add(-3 * halfLife)}}
+		cap.RefreshConfidence(now, halfLife)
+		if cap.Status != "speculative" {
+			t.Fatalf("stale status = %s, want speculative", cap.Status)
+		}
+
+		cap.AddEvidence(Evidence{Source: EvidenceSourceDockerAPI, Confidence: 0.95, ObservedAt: time.Now()})
+		if cap.Status != "confirmed" {
+			t.Errorf("status after new evidence = %s, want confirmed", cap.Status)
+		}
+	})
+
+	t.Run("operator evidence does not decay", func(t *testing.T) {
+		cap := &Capability{Type: CapabilityDNS}
+		cap.Evidence = []Evidence{{Source: EvidenceSourceOperator, Confidence: 1.0, ObservedAt: now.Add(-10 * halfLife)}}
+		cap.RefreshConfidence(now, halfLife)
+		if cap.Confidence != 1.0 {
+			t.Errorf("operator confidence = %.3f, want 1.0", cap.Confidence)
+		}
+	})
+
+	t.Run("zero half-life disables decay", func(t *testing.T) {
+		evidence := []Evidence{{Source: EvidenceSourceBanner, Confidence: 0.7, ObservedAt: now.Add(-1000 * time.Hour)}}
+		if got := DecayEvidence(evidence, 0, now)[0].Confidence; got != 0.7 {
+			t.Errorf("confidence = %.3f, want 0.7", got)
+		}
+	})
+}
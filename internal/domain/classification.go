@@ -0,0 +1,272 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PortTypeWeight is a rule contributing weighted evidence toward a node type.
+// The rule fires when every port in Ports is open and none in Absent are.
+type PortTypeWeight struct {
+	Ports  []int    `json:"ports" yaml:"ports"`
+	Absent []int    `json:"absent,omitempty" yaml:"absent,omitempty"`
+	Type   NodeType `json:"type" yaml:"type"`
+	Weight float64  `json:"weight" yaml:"weight"` // 0.0-1.0 confidence contributed
+	Reason string   `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Validate checks that a rule requires at least one valid port, names a
+// known type and carries a weight in 0.0-1.0
+func (w PortTypeWeight) Validate() error {
+	if len(w.Ports) == 0 {
+		return fmt.Errorf("invalid type weight for %s: no ports", w.Type)
+	}
+	for _, p := range append(slices.Clone(w.Ports), w.Absent...) {
+		if p < 1 || p > 65535 {
+			return fmt.Errorf("invalid type weight for %s: port %d", w.Type, p)
+		}
+	}
+	if !w.Type.IsKnown() {
+		return fmt.Errorf("invalid type weight: unknown node type %q", w.Type)
+	}
+	if w.Weight < 0 || w.Weight > 1 {
+		return fmt.Errorf("invalid type weight for %s: weight %g must be between 0 and 1", w.Type, w.Weight)
+	}
+	return nil
+}
+
+// DefaultPortTypeWeights expresses how strongly open ports imply each node type
+var DefaultPortTypeWeights = []PortTypeWeight{
+	// Routers: DNS alone is weak, DNS plus a web UI is typical of a gateway
+	{Ports: []int{53}, Type: NodeTypeRouter, Weight: 0.30, Reason: "dns"},
+	{Ports: []int{53, 80}, Type: NodeTypeRouter, Weight: 0.60, Reason: "dns with web ui"},
+	{Ports: []int{53, 443}, Type: NodeTypeRouter, Weight: 0.60, Reason: "dns with web ui"},
+
+	// Switches/APs: SNMP, or a bare web UI without remote shell. The web UI
+	// rule is weaker than plain http so it only wins with corroborating
+	// evidence such as a network vendor.
+	{Ports: []int{161}, Type: NodeTypeSwitch, Weight: 0.60, Reason: "snmp"},
+	{Ports: []int{80}, Absent: []int{22, 443}, Type: NodeTypeSwitch, Weight: 0.20, Reason: "web ui only"},
+
+	// Kubernetes nodes
+	{Ports: []int{6443}, Type: NodeTypeServer, Weight: 0.90, Reason: "kubernetes api"},
+	{Ports: []int{10250}, Type: NodeTypeServer, Weight: 0.85, Reason: "kubelet"},
+
+	// Windows hosts
+	{Ports: []int{3389}, Type: NodeTypeServer, Weight: 0.70, Reason: "rdp"},
+	{Ports: []int{445}, Type: NodeTypeServer, Weight: 0.60, Reason: "smb"},
+
+	// Linux hosts
+	{Ports: []int{22}, Type: NodeTypeServer, Weight: 0.40, Reason: "ssh"},
+	{Ports: []int{22, 80}, Type: NodeTypeServer, Weight: 0.50, Reason: "ssh with web"},
+	{Ports: []int{22, 443}, Type: NodeTypeServer, Weight: 0.50, Reason: "ssh with web"},
+
+	// Desktops/VMs
+	{Ports: []int{5900}, Type: NodeTypeVM, Weight: 0.45, Reason: "vnc"},
+
+	// Generic web services
+	{Ports: []int{80}, Type: NodeTypeServer, Weight: 0.30, Reason: "http"},
+	{Ports: []int{443}, Type: NodeTypeServer, Weight: 0.30, Reason: "https"},
+	{Ports: []int{8080}, Type: NodeTypeServer, Weight: 0.30, Reason: "http-alt"},
+}
+
+// VendorTypeWeight is a rule contributing evidence toward a node type when
+// a node's normalized MAC vendor matches. Vendors hint at a type but are
+// weaker than a matching port profile.
+type VendorTypeWeight struct {
+	Vendor string   `json:"vendor" yaml:"vendor"`
+	Type   NodeType `json:"type" yaml:"type"`
+	Weight float64  `json:"weight" yaml:"weight"`
+	Reason string   `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// DefaultVendorTypeWeights expresses how strongly a MAC vendor implies each
+// node type. Vendors are matched after NormalizeVendor.
+var DefaultVendorTypeWeights = []VendorTypeWeight{
+	// Network gear
+	{Vendor: "Cisco", Type: NodeTypeSwitch, Weight: 0.45, Reason: "network vendor"},
+	{Vendor: "Arista Networks", Type: NodeTypeSwitch, Weight: 0.55, Reason: "network vendor"},
+	{Vendor: "Juniper Networks", Type: NodeTypeRouter, Weight: 0.45, Reason: "network vendor"},
+	{Vendor: "MikroTik", Type: NodeTypeRouter, Weight: 0.50, Reason: "network vendor"},
+	{Vendor: "PC Engines", Type: NodeTypeRouter, Weight: 0.40, Reason: "router board"},
+	{Vendor: "Fortinet", Type: NodeTypeRouter, Weight: 0.50, Reason: "firewall vendor"},
+	{Vendor: "Palo Alto Networks", Type: NodeTypeRouter, Weight: 0.50, Reason: "firewall vendor"},
+	{Vendor: "Ubiquiti", Type: NodeTypeAccessPoint, Weight: 0.40, Reason: "wireless vendor"},
+	{Vendor: "Ubiquiti", Type: NodeTypeSwitch, Weight: 0.35, Reason: "network vendor"},
+	{Vendor: "TP-Link", Type: NodeTypeAccessPoint, Weight: 0.30, Reason: "wireless vendor"},
+
+	// Servers and storage
+	{Vendor: "Raspberry Pi", Type: NodeTypeServer, Weight: 0.45, Reason: "single-board computer"},
+	{Vendor: "Supermicro", Type: NodeTypeServer, Weight: 0.50, Reason: "server vendor"},
+	{Vendor: "Dell", Type: NodeTypeServer, Weight: 0.25, Reason: "server vendor"},
+	{Vendor: "Synology", Type: NodeTypeServer, Weight: 0.45, Reason: "nas vendor"},
+	{Vendor: "QNAP", Type: NodeTypeServer, Weight: 0.45, Reason: "nas vendor"},
+
+	// Virtual NICs
+	{Vendor: "VMware", Type: NodeTypeVM, Weight: 0.60, Reason: "virtual nic"},
+	{Vendor: "QEMU/KVM", Type: NodeTypeVM, Weight: 0.60, Reason: "virtual nic"},
+	{Vendor: "VirtualBox", Type: NodeTypeVM, Weight: 0.60, Reason: "virtual nic"},
+	{Vendor: "Xen", Type: NodeTypeVM, Weight: 0.60, Reason: "virtual nic"},
+	{Vendor: "Microsoft Hyper-V", Type: NodeTypeVM, Weight: 0.60, Reason: "virtual nic"},
+}
+
+// vendorAliases maps lowercase fragments of vendor names, as reported by
+// nmap or the IEEE registry, to the canonical names used by the bundled OUI
+// table and DefaultVendorTypeWeights. Earlier entries win.
+var vendorAliases = []struct{ match, vendor string }{
+	{"raspberry pi", "Raspberry Pi"},
+	{"ubiquiti", "Ubiquiti"},
+	{"linksys", "Linksys"},
+	{"cisco", "Cisco"},
+	{"arista", "Arista Networks"},
+	{"juniper", "Juniper Networks"},
+	{"mikrotik", "MikroTik"},
+	{"routerboard", "MikroTik"},
+	{"pc engines", "PC Engines"},
+	{"fortinet", "Fortinet"},
+	{"palo alto", "Palo Alto Networks"},
+	{"tp-link", "TP-Link"},
+	{"tp link", "TP-Link"},
+	{"super micro", "Supermicro"},
+	{"supermicro", "Supermicro"},
+	{"dell", "Dell"},
+	{"synology", "Synology"},
+	{"qnap", "QNAP"},
+	{"vmware", "VMware"},
+	{"qemu", "QEMU/KVM"},
+	{"virtualbox", "VirtualBox"},
+	{"pcs systemtechnik", "VirtualBox"},
+	{"xensource", "Xen"},
+	{"hyper-v", "Microsoft Hyper-V"},
+}
+
+// corporateSuffixes are trailing words dropped from unrecognized vendors
+var corporateSuffixes = map[string]bool{
+	"inc": true, "corp": true, "corporation": true, "co": true, "ltd": true,
+	"limited": true, "llc": true, "gmbh": true, "ag": true, "sa": true,
+}
+
+// NormalizeVendor maps a MAC vendor string to a canonical name, e.g.
+// "Raspberry Pi Trading Ltd" -> "Raspberry Pi". Unrecognized vendors are
+// returned with corporate suffixes removed.
+func NormalizeVendor(raw string) string {
+	lower := strings.ToLower(strings.TrimSpace(raw))
+	if lower == "" {
+		return ""
+	}
+	for _, alias := range vendorAliases {
+		if strings.Contains(lower, alias.match) {
+			return alias.vendor
+		}
+	}
+
+	words := strings.Fields(raw)
+	for len(words) > 1 && corporateSuffixes[strings.ToLower(strings.Trim(words[len(words)-1], ",."))] {
+		words = words[:len(words)-1]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ",")
+}
+
+// TypeClassification is the outcome of evidence-weighted node type inference
+type TypeClassification struct {
+	Type       NodeType             `json:"type"`
+	Confidence float64              `json:"confidence"`
+	Candidates map[NodeType]float64 `json:"candidates,omitempty"`
+	Evidence   []Evidence           `json:"evidence,omitempty"`
+}
+
+// ClassifyNodeType aggregates weighted port evidence per candidate type and
+// picks the type with the highest confidence. Returns unknown with zero
+// confidence when no rule matches.
+func ClassifyNodeType(ports []int, weights []PortTypeWeight, now time.Time) TypeClassification {
+	return ClassifyNodeTypeWithVendor(ports, "", weights, now)
+}
+
+// ClassifyNodeTypeWithVendor is ClassifyNodeType with the node's MAC vendor
+// as additional evidence from DefaultVendorTypeWeights. A vendor hint
+// breaks ties between port profiles and types nodes with no open ports.
+func ClassifyNodeTypeWithVendor(ports []int, vendor string, weights []PortTypeWeight, now time.Time) TypeClassification {
+	open := make(map[int]bool, len(ports))
+	for _, p := range ports {
+		open[p] = true
+	}
+
+	byType := make(map[NodeType][]Evidence)
+	for _, w := range weights {
+		if !rulePortsMatch(w, open) {
+			continue
+		}
+		byType[w.Type] = append(byType[w.Type], Evidence{
+			ID:         fmt.Sprintf("type-%s-%v", w.Type, w.Ports),
+			Source:     EvidenceSourcePortScan,
+			Property:   "node_type",
+			Value:      string(w.Type),
+			Confidence: w.Weight,
+			ObservedAt: now,
+			Raw:        map[string]any{"ports": w.Ports, "reason": w.Reason},
+		})
+	}
+
+	if vendor = NormalizeVendor(vendor); vendor != "" {
+		for _, w := range DefaultVendorTypeWeights {
+			if w.Vendor != vendor {
+				continue
+			}
+			byType[w.Type] = append(byType[w.Type], Evidence{
+				ID:         fmt.Sprintf("type-%s-vendor", w.Type),
+				Source:     EvidenceSourceOUI,
+				Property:   "node_type",
+				Value:      string(w.Type),
+				Confidence: w.Weight,
+				ObservedAt: now,
+				Raw:        map[string]any{"vendor": vendor, "reason": w.Reason},
+			})
+		}
+	}
+
+	result := TypeClassification{Type: NodeTypeUnknown}
+	if len(byType) == 0 {
+		return result
+	}
+
+	// Iterate in a stable order so ties resolve deterministically
+	types := make([]NodeType, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	result.Candidates = make(map[NodeType]float64, len(types))
+	for _, t := range types {
+		conf := AggregateConfidence(byType[t])
+		result.Candidates[t] = conf
+		if conf > result.Confidence {
+			result.Type = t
+			result.Confidence = conf
+		}
+	}
+	result.Evidence = byType[result.Type]
+
+	return result
+}
+
+// rulePortsMatch reports whether a rule's required ports are open and excluded ports are not
+func rulePortsMatch(w PortTypeWeight, open map[int]bool) bool {
+	if len(w.Ports) == 0 {
+		return false
+	}
+	for _, p := range w.Ports {
+		if !open[p] {
+			return false
+		}
+	}
+	for _, p := range w.Absent {
+		if open[p] {
+			return false
+		}
+	}
+	return true
+}
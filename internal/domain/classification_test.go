@@ -0,0 +1,148 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClassifyNodeType(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    []int
+		wantType NodeType
+	}{
+		{"router (dns + http)", []int{53, 80}, NodeTypeRouter},
+		{"switch (snmp)", []int{161}, NodeTypeSwitch},
+		{"web ui only", []int{80}, NodeTypeServer},
+		{"kubernetes node", []int{6443, 22}, NodeTypeServer},
+		{"windows server", []int{3389, 445}, NodeTypeServer},
+		{"linux server (ssh + http)", []int{22, 80}, NodeTypeServer},
+		{"vnc desktop", []int{5900, 22}, NodeTypeVM},
+		{"web only", []int{443}, NodeTypeServer},
+		{"no evidence", []int{}, NodeTypeUnknown},
+		{"unrecognized ports", []int{12345}, NodeTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyNodeType(tt.ports, DefaultPortTypeWeights, time.Now())
+			if got.Type != tt.wantType {
+				t.Errorf("ClassifyNodeType(%v).Type = %s, want %s (candidates %v)",
+					tt.ports, got.Type, tt.wantType, got.Candidates)
+			}
+		})
+	}
+}
+
+func TestClassifyNodeType_Confidence(t *testing.T) {
+	now := time.Now()
+
+	strong := ClassifyNodeType([]int{6443}, DefaultPortTypeWeights, now)
+	weak := ClassifyNodeType([]int{22, 80}, DefaultPortTypeWeights, now)
+
+	if strong.Confidence <= weak.Confidence {
+		t.Errorf("kubernetes api confidence %.2f should exceed ssh+web confidence %.2f",
+			strong.Confidence, weak.Confidence)
+	}
+	if len(weak.Evidence) != 3 {
+		t.Errorf("ssh+web should carry 3 pieces of server evidence, got %d", len(weak.Evidence))
+	}
+	// Corroborating evidence lifts confidence above the strongest single rule
+	if weak.Confidence <= 0.50 {
+		t.Errorf("corroborated confidence = %.3f, want > 0.50", weak.Confidence)
+	}
+
+	none := ClassifyNodeType(nil, DefaultPortTypeWeights, now)
+	if none.Confidence != 0 || none.Candidates != nil {
+		t.Errorf("empty classification = %+v, want zero confidence and no candidates", none)
+	}
+}
+
+func TestClassifyNodeTypeWithVendor(t *testing.T) {
+	now := time.Now()
+	snmpAndSMB := []int{161, 445}
+
+	// SNMP points at a switch, SMB at a server, equally strongly
+	portsOnly := ClassifyNodeType(snmpAndSMB, DefaultPortTypeWeights, now)
+	if portsOnly.Candidates[NodeTypeSwitch] != portsOnly.Candidates[NodeTypeServer] {
+		t.Fatalf("ports alone candidates = %v, want switch and server tied", portsOnly.Candidates)
+	}
+
+	cisco := ClassifyNodeTypeWithVendor(snmpAndSMB, "Cisco Systems, Inc", DefaultPortTypeWeights, now)
+	if cisco.Type != NodeTypeSwitch {
+		t.Errorf("Cisco with SNMP = %s (candidates %v), want switch", cisco.Type, cisco.Candidates)
+	}
+	if last := cisco.Evidence[len(cisco.Evidence)-1]; last.Source != EvidenceSourceOUI || last.Raw["vendor"] != "Cisco" {
+		t.Errorf("vendor evidence = %+v, want oui evidence for Cisco", last)
+	}
+
+	// A bare web UI is only a switch when the vendor corroborates it
+	if got := ClassifyNodeTypeWithVendor([]int{80}, "Cisco Systems, Inc", DefaultPortTypeWeights, now).Type; got != NodeTypeSwitch {
+		t.Errorf("web ui only on Cisco classified as %s, want switch", got)
+	}
+
+	// Vendor alone types a node with no open ports; strong ports still win
+	if got := ClassifyNodeTypeWithVendor(nil, "Raspberry Pi Trading Ltd", DefaultPortTypeWeights, now).Type; got != NodeTypeServer {
+		t.Errorf("Raspberry Pi without ports = %s, want server", got)
+	}
+	if got := ClassifyNodeTypeWithVendor([]int{6443}, "VMware, Inc.", DefaultPortTypeWeights, now).Type; got != NodeTypeServer {
+		t.Errorf("VMware NIC with kubernetes api = %s, want server", got)
+	}
+	if got := ClassifyNodeTypeWithVendor(nil, "Acme Widgets", DefaultPortTypeWeights, now).Type; got != NodeTypeUnknown {
+		t.Errorf("unknown vendor = %s, want unknown", got)
+	}
+}
+
+func TestPortTypeWeightValidate(t *testing.T) {
+	for _, w := range DefaultPortTypeWeights {
+		if err := w.Validate(); err != nil {
+			t.Errorf("default rule %v: %v", w.Ports, err)
+		}
+	}
+
+	invalid := []PortTypeWeight{
+		{Type: NodeTypeServer, Weight: 0.5},
+		{Ports: []int{70000}, Type: NodeTypeServer, Weight: 0.5},
+		{Ports: []int{80}, Absent: []int{0}, Type: NodeTypeServer, Weight: 0.5},
+		{Ports: []int{80}, Type: "toaster", Weight: 0.5},
+		{Ports: []int{80}, Type: NodeTypeServer, Weight: 1.5},
+	}
+	for _, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%+v accepted, want error", w)
+		}
+	}
+}
+
+func TestNormalizeVendor(t *testing.T) {
+	tests := map[string]string{
+		"Raspberry Pi Trading Ltd":       "Raspberry Pi",
+		"Ubiquiti Networks Inc.":         "Ubiquiti",
+		"Cisco-Linksys, LLC":             "Linksys",
+		"Super Micro Computer":           "Supermicro",
+		"PCS Systemtechnik GmbH":         "VirtualBox",
+		"Hewlett Packard Enterprise Co.": "Hewlett Packard Enterprise",
+		"Acme Widgets, Inc.":             "Acme Widgets",
+		"  ":                             "",
+	}
+	for raw, want := range tests {
+		if got := NormalizeVendor(raw); got != want {
+			t.Errorf("NormalizeVendor(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestClassifyNodeType_CustomWeights(t *testing.T) {
+	weights := []PortTypeWeight{
+		{Ports: []int{22}, Type: NodeTypeServer, Weight: 0.4},
+		{Ports: []int{8006}, Type: NodeTypeVM, Weight: 0.9, Reason: "proxmox"},
+	}
+
+	got := ClassifyNodeType([]int{22, 8006}, weights, time.Now())
+	if got.Type != NodeTypeVM {
+		t.Errorf("Type = %s, want %s", got.Type, NodeTypeVM)
+	}
+	if got.Candidates[NodeTypeServer] != 0.4 {
+		t.Errorf("server candidate = %v, want 0.4", got.Candidates[NodeTypeServer])
+	}
+}
@@ -13,8 +13,22 @@ const (
 	EdgeTypeVLAN        EdgeType = "vlan"
 	EdgeTypeVirtual     EdgeType = "virtual"
 	EdgeTypeAggregation EdgeType = "aggregation"
+	EdgeTypeRoute       EdgeType = "route" // Layer 3 path observed via traceroute
 )
 
+// knownEdgeTypes lists every valid EdgeType, including relationship types
+var knownEdgeTypes = map[EdgeType]bool{
+	EdgeTypeEthernet: true, EdgeTypeVLAN: true, EdgeTypeVirtual: true,
+	EdgeTypeAggregation: true, EdgeTypeRoute: true,
+	EdgeTypeHostedBy: true, EdgeTypeRunsOn: true, EdgeTypeBackedBy: true,
+	EdgeTypeMemberOf: true, EdgeTypeManages: true,
+}
+
+// IsKnown reports whether t is one of the defined edge types
+func (t EdgeType) IsKnown() bool {
+	return knownEdgeTypes[t]
+}
+
 // Edge represents a connection between two nodes
 type Edge struct {
 	ID         string         `json:"id"`
@@ -65,3 +79,12 @@ func (e *Edge) GetProperty(key string) (any, bool) {
 	val, ok := e.Properties[key]
 	return val, ok
 }
+
+// EdgeFilter narrows an edge listing. Empty fields match everything; all
+// set fields must match.
+type EdgeFilter struct {
+	Type         EdgeType
+	FromID       string
+	ToID         string
+	MinSpeedMbps int // edges without a speed_mbps never match
+}
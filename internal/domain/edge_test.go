@@ -154,3 +154,88 @@ func TestEdgeTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestEdgeLinkAttributes(t *testing.T) {
+	edge := NewEdge("sw1", "sw2", EdgeTypeEthernet)
+	if _, ok := edge.SpeedMbps(); ok {
+		t.Error("expected no speed on a new edge")
+	}
+
+	if err := edge.SetSpeedMbps(10000); err != nil {
+		t.Fatalf("SetSpeedMbps() error = %v", err)
+	}
+	if err := edge.SetDuplex("Full"); err != nil {
+		t.Fatalf("SetDuplex() error = %v", err)
+	}
+	if err := edge.SetMTU(9000); err != nil {
+		t.Fatalf("SetMTU() error = %v", err)
+	}
+	if err := edge.SetVLANID(20); err != nil {
+		t.Fatalf("SetVLANID() error = %v", err)
+	}
+	if speed, _ := edge.SpeedMbps(); speed != 10000 {
+		t.Errorf("SpeedMbps() = %d, want 10000", speed)
+	}
+	if edge.Duplex() != DuplexFull {
+		t.Errorf("Duplex() = %q, want full", edge.Duplex())
+	}
+	if mtu, _ := edge.MTU(); mtu != 9000 {
+		t.Errorf("MTU() = %d, want 9000", mtu)
+	}
+	if vlan, _ := edge.VLANID(); vlan != 20 {
+		t.Errorf("VLANID() = %d, want 20", vlan)
+	}
+
+	// Setters reject out-of-range values and leave the property untouched
+	if err := edge.SetSpeedMbps(0); err == nil {
+		t.Error("expected error for zero speed")
+	}
+	if err := edge.SetDuplex("auto"); err == nil {
+		t.Error("expected error for unknown duplex")
+	}
+	if err := edge.SetMTU(40); err == nil {
+		t.Error("expected error for MTU below 68")
+	}
+	if err := edge.SetVLANID(4095); err == nil {
+		t.Error("expected error for VLAN ID 4095")
+	}
+	if vlan, _ := edge.VLANID(); vlan != 20 {
+		t.Errorf("VLANID() = %d after rejected set, want 20", vlan)
+	}
+
+	// JSON-decoded numbers arrive as float64
+	decoded := &Edge{Properties: map[string]any{"speed_mbps": float64(1000), "mtu": float64(1500.5)}}
+	if speed, ok := decoded.SpeedMbps(); !ok || speed != 1000 {
+		t.Errorf("SpeedMbps() = %d, %v, want 1000", speed, ok)
+	}
+	if _, ok := decoded.MTU(); ok {
+		t.Error("expected a fractional MTU to be rejected")
+	}
+}
+
+func TestEdgeValidateLinkProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		props   map[string]any
+		wantErr bool
+	}{
+		{"no link attributes", map[string]any{"remote_port": "ge-0/0/1"}, false},
+		{"valid attributes", map[string]any{"speed_mbps": float64(1000), "duplex": "half", "mtu": 1500, "vlan_id": float64(1)}, false},
+		{"removal in a partial update", map[string]any{"speed_mbps": nil}, false},
+		{"speed as string", map[string]any{"speed_mbps": "1G"}, true},
+		{"negative speed", map[string]any{"speed_mbps": float64(-10)}, true},
+		{"bad duplex", map[string]any{"duplex": "auto"}, true},
+		{"vlan out of range", map[string]any{"vlan_id": float64(0)}, true},
+		{"mtu too large", map[string]any{"mtu": float64(70000)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			edge := &Edge{Properties: tt.props}
+			err := edge.ValidateLinkProperties()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateLinkProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
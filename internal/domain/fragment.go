@@ -4,6 +4,8 @@ package domain
 type GraphFragment struct {
 	Nodes []Node `json:"nodes"`
 	Edges []Edge `json:"edges"`
+	// Positions is only filled for snapshots; imports ignore it
+	Positions []NodePosition `json:"positions,omitempty"`
 }
 
 // NewGraphFragment creates an empty graph fragment
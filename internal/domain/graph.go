@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
 // Graph represents the complete network topology with positions
 type Graph struct {
 	Nodes     []Node                  `json:"nodes"`
@@ -7,6 +13,125 @@ type Graph struct {
 	Positions map[string]NodePosition `json:"positions,omitempty"`
 }
 
+// GraphStats summarizes the graph without listing it
+type GraphStats struct {
+	Nodes             int                `json:"nodes"`
+	Edges             int                `json:"edges"`
+	NodesByType       map[NodeType]int   `json:"nodes_by_type"`
+	EdgesByType       map[EdgeType]int   `json:"edges_by_type"`
+	NodesByStatus     map[NodeStatus]int `json:"nodes_by_status"`
+	NodesWithTruth    int                `json:"nodes_with_truth"`
+	OpenDiscrepancies int                `json:"open_discrepancies"`
+}
+
+// Neighbor is a node one hop away together with the edge reaching it
+type Neighbor struct {
+	Node Node `json:"node"`
+	Edge Edge `json:"edge"`
+}
+
+// GraphDiff lists what applying a fragment as a replace import would change
+type GraphDiff struct {
+	NodesAdded   []Node         `json:"nodes_added"`
+	NodesRemoved []Node         `json:"nodes_removed"`
+	NodesChanged []ObjectChange `json:"nodes_changed"`
+	EdgesAdded   []Edge         `json:"edges_added"`
+	EdgesRemoved []Edge         `json:"edges_removed"`
+	EdgesChanged []ObjectChange `json:"edges_changed"`
+}
+
+// IsEmpty returns true if the fragment matches the graph
+func (d *GraphDiff) IsEmpty() bool {
+	return len(d.NodesAdded) == 0 && len(d.NodesRemoved) == 0 && len(d.NodesChanged) == 0 &&
+		len(d.EdgesAdded) == 0 && len(d.EdgesRemoved) == 0 && len(d.EdgesChanged) == 0
+}
+
+// ObjectChange lists the fields that differ on a node or edge present on
+// both sides of a diff
+type ObjectChange struct {
+	ID     string        `json:"id"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is one differing field. Properties are named
+// "properties.<key>"; a nil side means the key is absent.
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue any    `json:"old_value"`
+	NewValue any    `json:"new_value"`
+}
+
+// MergeOperation records the state of nodes before they were merged into
+// interfaces of a parent, so the merge can be undone
+type MergeOperation struct {
+	ID            string         `json:"id"`
+	ParentID      string         `json:"parent_id"`
+	ParentCreated bool           `json:"parent_created"` // parent was created by the merge
+	InterfaceIDs  []string       `json:"interface_ids"`
+	Nodes         []Node         `json:"nodes"`     // pre-merge nodes
+	Edges         []Edge         `json:"edges"`     // pre-merge edges touching those nodes
+	Positions     []NodePosition `json:"positions"` // pre-merge positions
+	CreatedAt     time.Time      `json:"created_at"`
+	UndoneAt      *time.Time     `json:"undone_at,omitempty"`
+}
+
+// ExportFilter narrows an export to matching nodes. Empty fields match
+// everything; all set fields must match. Edges are kept only when both
+// endpoints match.
+type ExportFilter struct {
+	Type   NodeType // node type
+	Source string   // node source
+	Tags   []string // every tag must be present
+	CIDR   string   // properties.ip must fall within this prefix
+}
+
+// IsEmpty returns true if the filter matches every node
+func (f ExportFilter) IsEmpty() bool {
+	return f.Type == "" && f.Source == "" && len(f.Tags) == 0 && f.CIDR == ""
+}
+
+// Normalize validates the filter, lowercasing tags and canonicalizing the CIDR
+func (f *ExportFilter) Normalize() error {
+	for i, tag := range f.Tags {
+		normalized, err := NormalizeTag(tag)
+		if err != nil {
+			return fmt.Errorf("invalid tag: %w", err)
+		}
+		f.Tags[i] = normalized
+	}
+	if f.CIDR != "" {
+		prefix, err := netip.ParsePrefix(f.CIDR)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q: %w", f.CIDR, err)
+		}
+		f.CIDR = prefix.Masked().String()
+	}
+	return nil
+}
+
+// ClearOptions controls a graph clear
+type ClearOptions struct {
+	DryRun    bool // report what would be deleted without deleting
+	KeepTruth bool // keep nodes carrying operator truth, with their positions
+}
+
+// ClearResult counts what a graph clear removed, or would remove
+type ClearResult struct {
+	DryRun        bool `json:"dry_run"`
+	Nodes         int  `json:"nodes"`
+	Edges         int  `json:"edges"`
+	Positions     int  `json:"positions"`
+	Discrepancies int  `json:"discrepancies"`
+	KeptNodes     int  `json:"kept_nodes"`
+}
+
+// VacuumResult reports the database size, including its write-ahead log,
+// around a vacuum. Sizes are zero for in-memory databases.
+type VacuumResult struct {
+	BeforeBytes int64 `json:"before_bytes"`
+	AfterBytes  int64 `json:"after_bytes"`
+}
+
 // NewGraph creates an empty graph
 func NewGraph() *Graph {
 	return &Graph{
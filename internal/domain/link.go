@@ -0,0 +1,181 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Link attribute properties on physical edges
+const (
+	LinkSpeedProperty  = "speed_mbps"
+	LinkDuplexProperty = "duplex"
+	LinkMTUProperty    = "mtu"
+	LinkVLANProperty   = "vlan_id"
+	// LinkVLANsProperty lists every VLAN a trunk carries. On an interface
+	// node it lists the VLANs the port is a member of, alongside or instead
+	// of a single vlan_id.
+	LinkVLANsProperty = "vlans"
+)
+
+// Duplex values for the duplex link property
+const (
+	DuplexFull = "full"
+	DuplexHalf = "half"
+)
+
+// Link attribute bounds
+const (
+	minLinkMTU = 68 // IPv4 minimum
+	maxLinkMTU = 65535
+	minVLANID  = 1
+	maxVLANID  = 4094
+)
+
+// SpeedMbps returns the link speed in Mbit/s, or false when unset or not
+// a whole number
+func (e *Edge) SpeedMbps() (int, bool) {
+	return e.intProperty(LinkSpeedProperty)
+}
+
+// SetSpeedMbps sets the link speed in Mbit/s
+func (e *Edge) SetSpeedMbps(mbps int) error {
+	if mbps <= 0 {
+		return fmt.Errorf("invalid %s %d: must be positive", LinkSpeedProperty, mbps)
+	}
+	e.SetProperty(LinkSpeedProperty, mbps)
+	return nil
+}
+
+// Duplex returns the link duplex mode, or "" when unset
+func (e *Edge) Duplex() string {
+	s, _ := e.Properties[LinkDuplexProperty].(string)
+	return s
+}
+
+// SetDuplex sets the link duplex mode to full or half
+func (e *Edge) SetDuplex(duplex string) error {
+	duplex = strings.ToLower(strings.TrimSpace(duplex))
+	if duplex != DuplexFull && duplex != DuplexHalf {
+		return fmt.Errorf("invalid %s %q: must be full or half", LinkDuplexProperty, duplex)
+	}
+	e.SetProperty(LinkDuplexProperty, duplex)
+	return nil
+}
+
+// MTU returns the link MTU in bytes, or false when unset or not a whole
+// number
+func (e *Edge) MTU() (int, bool) {
+	return e.intProperty(LinkMTUProperty)
+}
+
+// SetMTU sets the link MTU in bytes
+func (e *Edge) SetMTU(mtu int) error {
+	if mtu < minLinkMTU || mtu > maxLinkMTU {
+		return fmt.Errorf("invalid %s %d: must be between %d and %d", LinkMTUProperty, mtu, minLinkMTU, maxLinkMTU)
+	}
+	e.SetProperty(LinkMTUProperty, mtu)
+	return nil
+}
+
+// VLANID returns the link's 802.1Q VLAN ID, or false when unset or not a
+// whole number
+func (e *Edge) VLANID() (int, bool) {
+	return e.intProperty(LinkVLANProperty)
+}
+
+// SetVLANID sets the link's 802.1Q VLAN ID
+func (e *Edge) SetVLANID(id int) error {
+	if id < minVLANID || id > maxVLANID {
+		return fmt.Errorf("invalid %s %d: must be between %d and %d", LinkVLANProperty, id, minVLANID, maxVLANID)
+	}
+	e.SetProperty(LinkVLANProperty, id)
+	return nil
+}
+
+// ValidateLinkProperties checks any link attributes present in the edge's
+// properties. Values decoded from JSON arrive as float64 and are accepted
+// when whole; a nil value (a removal in a partial update) is skipped.
+func (e *Edge) ValidateLinkProperties() error {
+	check := Edge{}
+	for _, key := range []string{LinkSpeedProperty, LinkMTUProperty, LinkVLANProperty} {
+		raw, ok := e.Properties[key]
+		if !ok || raw == nil {
+			continue
+		}
+		n, ok := e.intProperty(key)
+		if !ok {
+			return fmt.Errorf("invalid %s %v: must be a whole number", key, raw)
+		}
+		var err error
+		switch key {
+		case LinkSpeedProperty:
+			err = check.SetSpeedMbps(n)
+		case LinkMTUProperty:
+			err = check.SetMTU(n)
+		case LinkVLANProperty:
+			err = check.SetVLANID(n)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if raw, ok := e.Properties[LinkDuplexProperty]; ok && raw != nil {
+		s, _ := raw.(string)
+		if err := check.SetDuplex(s); err != nil {
+			return fmt.Errorf("invalid %s %v: must be full or half", LinkDuplexProperty, raw)
+		}
+	}
+	return nil
+}
+
+// VLANs returns the distinct VLAN IDs, in order, that a node's vlan_id
+// and vlans properties and discovered values name. Values outside 1-4094
+// are ignored.
+func (n *Node) VLANs() []int {
+	var ids []int
+	add := func(v any) {
+		if id, ok := wholeNumber(v); ok && id >= minVLANID && id <= maxVLANID {
+			ids = append(ids, id)
+		}
+	}
+	for _, values := range []map[string]any{n.Properties, n.Discovered} {
+		add(values[LinkVLANProperty])
+		switch list := values[LinkVLANsProperty].(type) {
+		case []int:
+			for _, v := range list {
+				add(v)
+			}
+		case []any:
+			for _, v := range list {
+				add(v)
+			}
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// intProperty reads a whole-number property stored as an int or as the
+// float64 JSON decoding produces
+func (e *Edge) intProperty(key string) (int, bool) {
+	return wholeNumber(e.Properties[key])
+}
+
+// wholeNumber reads a whole number stored as an int or as the float64
+// JSON decoding produces
+func wholeNumber(value any) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		if v != math.Trunc(v) || math.IsInf(v, 0) {
+			return 0, false
+		}
+		return int(v), true
+	}
+	return 0, false
+}
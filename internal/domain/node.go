@@ -1,6 +1,10 @@
 package domain
 
 import (
+	"cmp"
+	"fmt"
+	"net"
+	"slices"
 	"strings"
 	"time"
 )
@@ -18,9 +22,23 @@ const (
 	NodeTypeContainer   NodeType = "container"
 	NodeTypeInterface   NodeType = "interface" // Network interface, USB port, radio, etc. (child of parent node)
 	NodeTypeSelf        NodeType = "self"      // This Specularium instance
+	NodeTypeSubnet      NodeType = "subnet"    // Synthetic hub for nodes sharing a segmentum
 	NodeTypeUnknown     NodeType = "unknown"
 )
 
+// knownNodeTypes lists every valid NodeType
+var knownNodeTypes = map[NodeType]bool{
+	NodeTypeServer: true, NodeTypeSwitch: true, NodeTypeRouter: true,
+	NodeTypeAccessPoint: true, NodeTypeVM: true, NodeTypeVIP: true,
+	NodeTypeContainer: true, NodeTypeInterface: true, NodeTypeSelf: true,
+	NodeTypeSubnet: true, NodeTypeUnknown: true,
+}
+
+// IsKnown reports whether t is one of the defined node types
+func (t NodeType) IsKnown() bool {
+	return knownNodeTypes[t]
+}
+
 // NodeStatus represents the verification status of a node
 type NodeStatus string
 
@@ -30,13 +48,110 @@ const (
 	NodeStatusVerified    NodeStatus = "verified"    // Successfully contacted
 	NodeStatusUnreachable NodeStatus = "unreachable" // Failed to contact
 	NodeStatusDegraded    NodeStatus = "degraded"    // Partially reachable (some probes failed)
+	NodeStatusStale       NodeStatus = "stale"       // Not seen within the stale TTL
 )
 
+// NodeRole is the job a node does on the network, independent of its
+// hardware type (a server can be a DNS resolver, a router a gateway)
+type NodeRole string
+
+const (
+	RoleGateway      NodeRole = "gateway"       // Default route out of a network
+	RoleRouter       NodeRole = "router"        // Forwards traffic between networks
+	RoleSwitch       NodeRole = "switch"        // Layer 2 switching
+	RoleAccessPoint  NodeRole = "access-point"  // Wireless access
+	RoleFirewall     NodeRole = "firewall"      // Packet filtering
+	RoleDNS          NodeRole = "dns"           // Name resolution
+	RoleDHCP         NodeRole = "dhcp"          // Address assignment
+	RoleLoadBalancer NodeRole = "load-balancer" // Ingress or load balancing
+	RoleControlPlane NodeRole = "control-plane" // Cluster control plane (e.g. Kubernetes API)
+	RoleWorker       NodeRole = "worker"        // Cluster worker node
+	RoleHypervisor   NodeRole = "hypervisor"    // Hosts VMs
+	RoleStorage      NodeRole = "storage"       // NAS, SAN or object storage
+	RoleClient       NodeRole = "client"        // End-user device
+	RoleObserver     NodeRole = "observer"      // This Specularium instance
+)
+
+// knownRoles lists every valid NodeRole
+var knownRoles = map[NodeRole]bool{
+	RoleGateway: true, RoleRouter: true, RoleSwitch: true, RoleAccessPoint: true,
+	RoleFirewall: true, RoleDNS: true, RoleDHCP: true, RoleLoadBalancer: true,
+	RoleControlPlane: true, RoleWorker: true, RoleHypervisor: true,
+	RoleStorage: true, RoleClient: true, RoleObserver: true,
+}
+
+// roleAliases maps role spellings used by adapters and imports onto the
+// known roles
+var roleAliases = map[string]NodeRole{
+	"default-gateway":   RoleGateway,
+	"k8s-control-plane": RoleControlPlane,
+	"k8s-master":        RoleControlPlane,
+	"master":            RoleControlPlane,
+	"controlplane":      RoleControlPlane,
+	"k8s-dns":           RoleDNS,
+	"coredns":           RoleDNS,
+	"resolver":          RoleDNS,
+	"dns-server":        RoleDNS,
+	"k8s-node":          RoleWorker,
+	"k8s-worker":        RoleWorker,
+	"node":              RoleWorker,
+	"ap":                RoleAccessPoint,
+	"wap":               RoleAccessPoint,
+	"loadbalancer":      RoleLoadBalancer,
+	"lb":                RoleLoadBalancer,
+	"ingress":           RoleLoadBalancer,
+	"nas":               RoleStorage,
+	"self":              RoleObserver,
+}
+
+// NormalizeRole maps a role or a known alias (e.g. "k8s-control-plane") to
+// its NodeRole. Case is ignored and spaces or underscores read as '-'. An
+// empty string is no role.
+func NormalizeRole(s string) (NodeRole, error) {
+	key := strings.ToLower(strings.TrimSpace(s))
+	key = strings.NewReplacer("_", "-", " ", "-").Replace(key)
+	if key == "" {
+		return "", nil
+	}
+	if role, ok := roleAliases[key]; ok {
+		return role, nil
+	}
+	if role := NodeRole(key); knownRoles[role] {
+		return role, nil
+	}
+	return "", fmt.Errorf("unknown role %q", s)
+}
+
+// PromoteRole normalizes Role and moves a recognized properties.role into
+// it, so callers that only set the property still get a first-class role.
+// A recognized property wins over the existing Role since it is the newer
+// value. Unrecognized property values are left in properties.
+func (n *Node) PromoteRole() error {
+	if n.Role != "" {
+		role, err := NormalizeRole(string(n.Role))
+		if err != nil {
+			return fmt.Errorf("invalid role: %w", err)
+		}
+		n.Role = role
+	}
+
+	raw, ok := n.Properties["role"].(string)
+	if !ok {
+		return nil
+	}
+	if role, err := NormalizeRole(raw); err == nil && role != "" {
+		n.Role = role
+		delete(n.Properties, "role")
+	}
+	return nil
+}
+
 // Node represents a network entity in the graph
 type Node struct {
 	ID         string         `json:"id"`
 	Type       NodeType       `json:"type"`
 	Label      string         `json:"label"`
+	Role       NodeRole       `json:"role,omitempty"`
 	ParentID   string         `json:"parent_id,omitempty"` // Parent node ID for interface/satellite nodes
 	Properties map[string]any `json:"properties,omitempty"`
 	Source     string         `json:"source,omitempty"`
@@ -47,19 +162,79 @@ type Node struct {
 	Status       NodeStatus `json:"status"`
 	LastVerified *time.Time `json:"last_verified,omitempty"`
 	LastSeen     *time.Time `json:"last_seen,omitempty"`
+	// FirstSeen is when the node was first observed. It survives a
+	// soft delete and re-create and only ever moves earlier.
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+
+	// Verification backoff: consecutive unreachable results, and the time
+	// before which the node is not re-verified
+	VerifyFailures int        `json:"verify_failures,omitempty"`
+	BackoffUntil   *time.Time `json:"verify_backoff_until,omitempty"`
 
 	// Discovered properties (auto-populated by adapters)
 	Discovered map[string]any `json:"discovered,omitempty"`
 
+	// DiscoveredSources records which source set each discovered property,
+	// so lower-priority sources cannot overwrite it
+	DiscoveredSources map[string]DiscoveredSource `json:"discovered_sources,omitempty"`
+
 	// Operator Truth fields
 	Truth          *NodeTruth  `json:"truth,omitempty"`
 	TruthStatus    TruthStatus `json:"truth_status,omitempty"`
 	HasDiscrepancy bool        `json:"has_discrepancy,omitempty"`
 
+	// HasImage is true when an operator has attached an icon or photo
+	HasImage bool `json:"has_image,omitempty"`
+
+	// Protected marks a curated node: it cannot be deleted without force,
+	// and discovery does not overwrite its properties or label
+	Protected bool `json:"protected,omitempty"`
+
+	// Tags group nodes by purpose (e.g. "prod", "iot"). They are stored
+	// separately and managed via the node tag endpoints, not node updates.
+	Tags []string `json:"tags,omitempty"`
+
+	// DeletedAt is set when the node has been soft-deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
 	// Capabilities detected for this node (K8s, Docker, SSH, etc.)
 	Capabilities map[CapabilityType]*Capability `json:"capabilities,omitempty"`
 }
 
+// MaxNodeImageSize is the largest image accepted for a node (2 MiB)
+const MaxNodeImageSize = 2 << 20
+
+// NodeImageTypes lists the content types accepted for node images.
+// SVG is excluded since it can carry script when served inline.
+var NodeImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// NodeImage is an operator-supplied icon or photo attached to a node
+type NodeImage struct {
+	NodeID      string    `json:"node_id"`
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"-"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NodeHistoryEntry records a single property or discovered value change.
+// Property is prefixed with the map it belongs to, e.g. "properties.ip" or
+// "discovered.reverse_dns". A nil OldValue means the key was added; a nil
+// NewValue means it was removed.
+type NodeHistoryEntry struct {
+	ID        int64     `json:"id"`
+	NodeID    string    `json:"node_id"`
+	Property  string    `json:"property"`
+	OldValue  any       `json:"old_value"`
+	NewValue  any       `json:"new_value"`
+	Source    string    `json:"source,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
 // IsInterface returns true if this node is a child interface node
 func (n *Node) IsInterface() bool {
 	return n.ParentID != ""
@@ -88,6 +263,27 @@ func (n *Node) SetDiscovered(key string, value any) {
 	n.Discovered[key] = value
 }
 
+// DiscoveredSource is the provenance of a single discovered property
+type DiscoveredSource struct {
+	Source   string `json:"source"`
+	Priority int    `json:"priority"`
+}
+
+// SetDiscoveredFrom sets a discovered property on behalf of source and
+// records its provenance. It refuses, returning false, when a source of
+// higher priority already set the key; equal priority overwrites.
+func (n *Node) SetDiscoveredFrom(source, key string, value any, priority int) bool {
+	if prev, ok := n.DiscoveredSources[key]; ok && prev.Priority > priority {
+		return false
+	}
+	n.SetDiscovered(key, value)
+	if n.DiscoveredSources == nil {
+		n.DiscoveredSources = make(map[string]DiscoveredSource)
+	}
+	n.DiscoveredSources[key] = DiscoveredSource{Source: source, Priority: priority}
+	return true
+}
+
 // GetDiscovered gets a discovered property value
 func (n *Node) GetDiscovered(key string) (any, bool) {
 	if n.Discovered == nil {
@@ -126,6 +322,75 @@ func (n *Node) GetPropertyString(key string) string {
 	return ""
 }
 
+// MACAddress returns the node's discovered MAC address, normalized, or ""
+func (n *Node) MACAddress() string {
+	s, _ := n.Discovered["mac_address"].(string)
+	return NormalizeMAC(s)
+}
+
+// NormalizeMAC returns a lowercase colon-separated MAC, or "" if s isn't one
+func NormalizeMAC(s string) string {
+	hw, err := net.ParseMAC(strings.TrimSpace(s))
+	if err != nil || len(hw) != 6 {
+		return ""
+	}
+	return hw.String()
+}
+
+// NodeFilter narrows a node listing. Empty fields match everything; all
+// set fields must match.
+type NodeFilter struct {
+	Type   NodeType
+	Source string
+	Role   NodeRole
+	Tags   []string // every tag must be present
+	// Sort orders the result by one of NodeSortFields; a leading '-'
+	// sorts descending. Empty keeps the default order.
+	Sort string
+}
+
+// NodeSortFields lists the fields a node listing can be sorted by
+var NodeSortFields = map[string]bool{
+	"first_seen": true,
+	"last_seen":  true,
+	"created_at": true,
+	"label":      true,
+}
+
+// SortField splits Sort into its field and direction, rejecting unknown
+// fields. An empty Sort returns an empty field.
+func (f NodeFilter) SortField() (field string, desc bool, err error) {
+	field, desc = strings.CutPrefix(f.Sort, "-")
+	if field == "" {
+		return "", false, nil
+	}
+	if !NodeSortFields[field] {
+		return "", false, fmt.Errorf("invalid sort field %q", field)
+	}
+	return field, desc, nil
+}
+
+// MaxTagLength is the longest tag accepted on a node
+const MaxTagLength = 64
+
+// NormalizeTag trims and lowercases a tag, rejecting empty or overlong tags
+// and any characters other than letters, digits, '-', '_', '.' and ':'
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("tag is empty")
+	}
+	if len(tag) > MaxTagLength {
+		return "", fmt.Errorf("tag %q exceeds %d characters", tag, MaxTagLength)
+	}
+	for _, c := range tag {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && !strings.ContainsRune("-_.:", c) {
+			return "", fmt.Errorf("tag %q contains invalid character %q", tag, c)
+		}
+	}
+	return tag, nil
+}
+
 // ConfidenceSource identifies where a discovered value came from
 type ConfidenceSource string
 
@@ -136,6 +401,8 @@ const (
 	SourceSMTPBanner    ConfidenceSource = "smtp_banner"    // SMTP EHLO/HELO hostname
 	SourceSSHBanner     ConfidenceSource = "ssh_banner"     // SSH server identification
 	SourceHTTPHeader    ConfidenceSource = "http_header"    // HTTP Server header
+	SourceTLSCert       ConfidenceSource = "tls_cert"       // TLS certificate subject/SAN
+	SourceHTTPTitle     ConfidenceSource = "http_title"     // HTML page title
 	SourceMDNS          ConfidenceSource = "mdns"           // Multicast DNS
 	SourceNetBIOS       ConfidenceSource = "netbios"        // NetBIOS name
 	SourceSNMP          ConfidenceSource = "snmp"           // SNMP sysName
@@ -154,7 +421,9 @@ var ConfidenceScores = map[ConfidenceSource]float64{
 	SourceMDNS:          0.80, // Local discovery
 	SourceNetBIOS:       0.75, // Windows naming
 	SourceSSHBanner:     0.70, // Often contains hints
+	SourceTLSCert:       0.80, // Certificates usually name the host
 	SourceHTTPHeader:    0.60, // Sometimes hostname in headers
+	SourceHTTPTitle:     0.40, // Titles often name the device, not the host
 	SourceImport:        0.50, // Imported data, unverified
 	SourceIPDerived:     0.10, // Just the IP, placeholder
 	SourceUnknown:       0.05, // Unknown origin
@@ -192,15 +461,9 @@ func (h *HostnameInference) AddCandidate(hostname string, source ConfidenceSourc
 	hostname = strings.TrimSpace(hostname)
 	hostname = strings.ToLower(hostname)
 
-	// Get confidence for this source
-	confidence := ConfidenceScores[source]
-	if confidence == 0 {
-		confidence = ConfidenceScores[SourceUnknown]
-	}
-
 	candidate := HostnameCandidate{
 		Hostname:   hostname,
-		Confidence: confidence,
+		Confidence: SourceConfidence(source),
 		Source:     source,
 		ObservedAt: observedAt,
 	}
@@ -220,7 +483,7 @@ func (h *HostnameInference) AddCandidate(hostname string, source ConfidenceSourc
 	h.updateBest()
 }
 
-// updateBest selects the highest confidence candidate as best
+// updateBest selects the highest ranked candidate as best
 func (h *HostnameInference) updateBest() {
 	if len(h.Candidates) == 0 {
 		h.Best = nil
@@ -229,13 +492,45 @@ func (h *HostnameInference) updateBest() {
 
 	best := &h.Candidates[0]
 	for i := 1; i < len(h.Candidates); i++ {
-		if h.Candidates[i].Confidence > best.Confidence {
+		if compareCandidates(h.Candidates[i], *best) < 0 {
 			best = &h.Candidates[i]
 		}
 	}
 	h.Best = best
 }
 
+// Ranked returns a copy of the candidates, best first. The first entry is
+// always the Best selection.
+func (h *HostnameInference) Ranked() []HostnameCandidate {
+	ranked := slices.Clone(h.Candidates)
+	slices.SortStableFunc(ranked, compareCandidates)
+	return ranked
+}
+
+// compareCandidates orders candidates by confidence, breaking ties by
+// source confidence, then the most recent observation, then hostname
+func compareCandidates(a, b HostnameCandidate) int {
+	if c := cmp.Compare(b.Confidence, a.Confidence); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(SourceConfidence(b.Source), SourceConfidence(a.Source)); c != 0 {
+		return c
+	}
+	if c := b.ObservedAt.Compare(a.ObservedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Hostname, b.Hostname)
+}
+
+// SourceConfidence returns the default confidence for a source, falling
+// back to the unknown source's confidence
+func SourceConfidence(source ConfidenceSource) float64 {
+	if confidence, ok := ConfidenceScores[source]; ok {
+		return confidence
+	}
+	return ConfidenceScores[SourceUnknown]
+}
+
 // GetBestHostname returns the highest confidence hostname, or empty string
 func (h *HostnameInference) GetBestHostname() string {
 	if h.Best == nil {
@@ -264,6 +559,31 @@ func ExtractShortName(fqdn string) string {
 	return fqdn
 }
 
+// RefreshCapabilities recalculates capability confidence to account for
+// evidence age. With decay disabled (zero halfLife) only the status is brought
+// in line with the stored confidence.
+func (n *Node) RefreshCapabilities(now time.Time, halfLife time.Duration) {
+	for _, cap := range n.Capabilities {
+		switch {
+		case cap == nil:
+		case halfLife > 0:
+			cap.RefreshConfidence(now, halfLife)
+		default:
+			cap.Status = cap.ConfidenceStatus()
+		}
+	}
+}
+
+// SummarizeCapabilities replaces each capability with its Summary, dropping
+// evidence lists from nodes sent in bulk
+func (n *Node) SummarizeCapabilities() {
+	for capType, cap := range n.Capabilities {
+		if cap != nil {
+			n.Capabilities[capType] = cap.Summary()
+		}
+	}
+}
+
 // AddEvidence adds evidence to a capability and creates the capability if needed
 func (n *Node) AddEvidence(capType CapabilityType, evidence Evidence) {
 	if n.Capabilities == nil {
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"testing"
 	"time"
 )
@@ -214,6 +215,43 @@ func TestHostnameInference(t *testing.T) {
 		}
 	})
 
+	t.Run("Ranked follows source confidence", func(t *testing.T) {
+		inference := &HostnameInference{}
+		now := time.Now()
+
+		sources := []ConfidenceSource{SourceHTTPTitle, SourceSMTPBanner, SourceIPDerived, SourcePTR, SourceTLSCert}
+		for i, source := range sources {
+			inference.AddCandidate(fmt.Sprintf("host%d", i), source, now)
+		}
+
+		ranked := inference.Ranked()
+		if len(ranked) != len(sources) {
+			t.Fatalf("expected %d candidates, got %d", len(sources), len(ranked))
+		}
+		for i := 1; i < len(ranked); i++ {
+			if ConfidenceScores[ranked[i-1].Source] < ConfidenceScores[ranked[i].Source] {
+				t.Errorf("%s ranked above %s", ranked[i-1].Source, ranked[i].Source)
+			}
+		}
+		if ranked[0] != *inference.Best {
+			t.Errorf("expected Best %+v to rank first, got %+v", *inference.Best, ranked[0])
+		}
+		if inference.Candidates[0].Source != SourceHTTPTitle {
+			t.Error("Ranked should not reorder Candidates")
+		}
+	})
+
+	t.Run("ties prefer the most recent observation", func(t *testing.T) {
+		inference := &HostnameInference{}
+		now := time.Now()
+
+		inference.AddCandidate("old", SourceSMTPBanner, now.Add(-time.Hour))
+		inference.AddCandidate("new", SourceSNMP, now)
+		if inference.GetBestHostname() != "new" {
+			t.Errorf("expected 'new', got %s", inference.GetBestHostname())
+		}
+	})
+
 	t.Run("GetBestHostname returns empty for no candidates", func(t *testing.T) {
 		inference := &HostnameInference{}
 		hostname := inference.GetBestHostname()
@@ -282,3 +320,92 @@ func TestConfidenceScores(t *testing.T) {
 		}
 	})
 }
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      string
+		wantError bool
+	}{
+		{" Prod ", "prod", false},
+		{"env:lab-2", "env:lab-2", false},
+		{"", "", true},
+		{"has space", "", true},
+		{"a,b", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeTag(tt.input)
+		if (err != nil) != tt.wantError {
+			t.Errorf("NormalizeTag(%q) error = %v, wantError %v", tt.input, err, tt.wantError)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRole(t *testing.T) {
+	tests := []struct {
+		input     string
+		want      NodeRole
+		wantError bool
+	}{
+		{"gateway", RoleGateway, false},
+		{"k8s-control-plane", RoleControlPlane, false},
+		{"K8S_Control_Plane", RoleControlPlane, false},
+		{"k8s-dns", RoleDNS, false},
+		{"k8s-node", RoleWorker, false},
+		{" Load Balancer ", RoleLoadBalancer, false},
+		{"", "", false},
+		{"toaster", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeRole(tt.input)
+		if (err != nil) != tt.wantError {
+			t.Errorf("NormalizeRole(%q) error = %v, wantError %v", tt.input, err, tt.wantError)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeRole(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNodePromoteRole(t *testing.T) {
+	t.Run("recognized property moves to role", func(t *testing.T) {
+		node := NewNode("api", NodeTypeServer, "api")
+		node.SetProperty("role", "k8s-control-plane")
+		if err := node.PromoteRole(); err != nil {
+			t.Fatalf("PromoteRole() error = %v", err)
+		}
+		if node.Role != RoleControlPlane {
+			t.Errorf("Role = %q, want %q", node.Role, RoleControlPlane)
+		}
+		if _, ok := node.Properties["role"]; ok {
+			t.Error("expected properties.role to be removed")
+		}
+	})
+
+	t.Run("unrecognized property is kept", func(t *testing.T) {
+		node := NewNode("web", NodeTypeServer, "web")
+		node.Role = RoleStorage
+		node.SetProperty("role", "webserver")
+		if err := node.PromoteRole(); err != nil {
+			t.Fatalf("PromoteRole() error = %v", err)
+		}
+		if node.Role != RoleStorage || node.GetPropertyString("role") != "webserver" {
+			t.Errorf("got role %q / property %q", node.Role, node.GetPropertyString("role"))
+		}
+	})
+
+	t.Run("invalid role field is rejected", func(t *testing.T) {
+		node := NewNode("x", NodeTypeServer, "x")
+		node.Role = "toaster"
+		if err := node.PromoteRole(); err == nil {
+			t.Error("expected error for unknown role")
+		}
+	})
+}
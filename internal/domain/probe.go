@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// AddressFamily identifies an IP address family for probing
+type AddressFamily string
+
+const (
+	AddressFamilyIPv4 AddressFamily = "ipv4"
+	AddressFamilyIPv6 AddressFamily = "ipv6"
+)
+
+// ValidateAddressFamily accepts ipv4, ipv6 or empty (the default)
+func ValidateAddressFamily(family string) error {
+	switch AddressFamily(family) {
+	case "", AddressFamilyIPv4, AddressFamilyIPv6:
+		return nil
+	}
+	return fmt.Errorf("address family %q must be %s or %s", family, AddressFamilyIPv4, AddressFamilyIPv6)
+}
+
+// ProbeConfigProperty is the node property holding a ProbeConfig
+const ProbeConfigProperty = "probe_config"
+
+// maxProbeTimeout caps a per-node timeout so one node cannot stall a
+// verification worker
+const maxProbeTimeout = time.Minute
+
+// ProbeConfig overrides the verifier's probe defaults for a single node,
+// for hosts that only answer on unusual ports, e.g.
+// {"ports": [8443], "timeout": "5s", "icmp": false}
+type ProbeConfig struct {
+	// Ports replaces the common ports for both the TCP ping and port probes
+	Ports []int `json:"ports,omitempty"`
+	// Timeout replaces the ping and per-port timeouts
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// ICMP turns the ICMP ping on or off; nil keeps the default
+	ICMP *bool `json:"icmp,omitempty"`
+}
+
+// ParseProbeConfig reads a probe_config property value. The timeout is a
+// duration string ("5s") or a number of seconds.
+func ParseProbeConfig(raw any) (*ProbeConfig, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("probe_config: %w", err)
+	}
+
+	var fields struct {
+		Ports   []int           `json:"ports"`
+		Timeout json.RawMessage `json:"timeout"`
+		ICMP    *bool           `json:"icmp"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("probe_config: %w", err)
+	}
+
+	cfg := &ProbeConfig{Ports: fields.Ports, ICMP: fields.ICMP}
+	for _, port := range cfg.Ports {
+		if port < 1 || port > 65535 {
+			return nil, fmt.Errorf("probe_config: invalid port %d", port)
+		}
+	}
+
+	if len(fields.Timeout) > 0 && string(fields.Timeout) != "null" {
+		var s string
+		var seconds float64
+		switch {
+		case json.Unmarshal(fields.Timeout, &s) == nil:
+			if cfg.Timeout, err = time.ParseDuration(s); err != nil {
+				return nil, fmt.Errorf("probe_config: invalid timeout %q", s)
+			}
+		case json.Unmarshal(fields.Timeout, &seconds) == nil:
+			cfg.Timeout = time.Duration(seconds * float64(time.Second))
+		default:
+			return nil, fmt.Errorf("probe_config: invalid timeout %s", fields.Timeout)
+		}
+		if cfg.Timeout <= 0 || cfg.Timeout > maxProbeTimeout {
+			return nil, fmt.Errorf("probe_config: timeout must be between 0 and %s", maxProbeTimeout)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ProbeConfig returns the node's probe override, or nil when it has none
+func (n *Node) ProbeConfig() (*ProbeConfig, error) {
+	raw, ok := n.Properties[ProbeConfigProperty]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	return ParseProbeConfig(raw)
+}
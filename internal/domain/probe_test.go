@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseProbeConfig(t *testing.T) {
+	off := false
+	tests := []struct {
+		name    string
+		raw     any
+		want    ProbeConfig
+		wantErr bool
+	}{
+		{"duration string", map[string]any{"ports": []any{float64(8443)}, "timeout": "5s", "icmp": false},
+			ProbeConfig{Ports: []int{8443}, Timeout: 5 * time.Second, ICMP: &off}, false},
+		{"timeout in seconds", map[string]any{"timeout": float64(2)}, ProbeConfig{Timeout: 2 * time.Second}, false},
+		{"port out of range", map[string]any{"ports": []any{float64(0)}}, ProbeConfig{}, true},
+		{"timeout too long", map[string]any{"timeout": "5m"}, ProbeConfig{}, true},
+		{"unknown field", map[string]any{"port": float64(22)}, ProbeConfig{}, true},
+		{"not an object", "8443", ProbeConfig{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProbeConfig(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseProbeConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(got.Ports) != len(tt.want.Ports) || (len(got.Ports) > 0 && got.Ports[0] != tt.want.Ports[0]) {
+				t.Errorf("Ports = %v, want %v", got.Ports, tt.want.Ports)
+			}
+			if got.Timeout != tt.want.Timeout {
+				t.Errorf("Timeout = %v, want %v", got.Timeout, tt.want.Timeout)
+			}
+			if (got.ICMP == nil) != (tt.want.ICMP == nil) || (got.ICMP != nil && *got.ICMP != *tt.want.ICMP) {
+				t.Errorf("ICMP = %v, want %v", got.ICMP, tt.want.ICMP)
+			}
+		})
+	}
+}
@@ -45,9 +45,13 @@ type Secret struct {
 
 	// Data holds the secret values (key-value pairs)
 	// For mounted secrets, this is populated from files
-	// For operator secrets, this is stored encrypted in SQLite
+	// For operator secrets, this is stored encrypted in SQLite when a key is configured
 	Data map[string]string `json:"data,omitempty"`
 
+	// SealedData is the encrypted form of Data as stored at rest. When set,
+	// repositories persist it in place of Data and mark the row encrypted.
+	SealedData string `json:"-"`
+
 	// Metadata holds non-sensitive configuration
 	Metadata map[string]string `json:"metadata,omitempty"`
 
@@ -71,6 +75,34 @@ type Secret struct {
 
 	// StatusMessage provides details about the status
 	StatusMessage string `json:"status_message,omitempty"`
+
+	// ExpiresAt is when the credential stops working, if known
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// RotationInterval is how often the secret should be rotated, as a Go
+	// duration string (e.g. "2160h"); empty means no rotation schedule
+	RotationInterval string `json:"rotation_interval,omitempty"`
+}
+
+// RotationDueAt returns when the secret is due for rotation: the earlier of
+// ExpiresAt and the rotation interval counted from LastUsedAt, or CreatedAt
+// if the secret has never been used. It returns nil when neither is set or
+// the interval does not parse.
+func (s *Secret) RotationDueAt() *time.Time {
+	var due *time.Time
+	if interval, err := time.ParseDuration(s.RotationInterval); err == nil && interval > 0 {
+		base := s.CreatedAt
+		if s.LastUsedAt != nil {
+			base = *s.LastUsedAt
+		}
+		t := base.Add(interval)
+		due = &t
+	}
+	if s.ExpiresAt != nil && (due == nil || s.ExpiresAt.Before(*due)) {
+		t := *s.ExpiresAt
+		due = &t
+	}
+	return due
 }
 
 // SecretStatus indicates the operational state of a secret
@@ -83,6 +115,27 @@ const (
 	SecretStatusExpired  SecretStatus = "expired"  // Token/cert expired
 )
 
+// IsTestable reports whether secrets of this type can be validated
+// against a target with a lightweight live check
+func (t SecretType) IsTestable() bool {
+	switch t {
+	case SecretTypeSSHKey, SecretTypeSSHPassword, SecretTypeSNMPCommunity, SecretTypeDNS:
+		return true
+	}
+	return false
+}
+
+// SecretTestResult is the outcome of validating a secret against a target
+type SecretTestResult struct {
+	SecretID   string     `json:"secret_id"`
+	Type       SecretType `json:"type"`
+	Target     string     `json:"target"`
+	Success    bool       `json:"success"`
+	Message    string     `json:"message"`
+	DurationMs int64      `json:"duration_ms"`
+	TestedAt   time.Time  `json:"tested_at"`
+}
+
 // SecretRef is a reference to a secret by ID, used in capability configs
 type SecretRef struct {
 	ID  string `json:"id" yaml:"id"`
@@ -104,6 +157,10 @@ type SecretSummary struct {
 	UsageCount    int               `json:"usage_count"`
 	Status        SecretStatus      `json:"status"`
 	StatusMessage string            `json:"status_message,omitempty"`
+	ExpiresAt     *time.Time        `json:"expires_at,omitempty"`
+	// RotationInterval is a Go duration string; RotationDueAt is derived
+	RotationInterval string     `json:"rotation_interval,omitempty"`
+	RotationDueAt    *time.Time `json:"rotation_due_at,omitempty"`
 	// DataKeys lists the keys in Data without exposing values
 	DataKeys []string `json:"data_keys"`
 }
@@ -116,20 +173,23 @@ func (s *Secret) ToSummary() SecretSummary {
 	}
 
 	return SecretSummary{
-		ID:            s.ID,
-		Name:          s.Name,
-		Type:          s.Type,
-		Source:        s.Source,
-		Description:   s.Description,
-		Metadata:      s.Metadata,
-		Immutable:     s.Immutable,
-		CreatedAt:     s.CreatedAt,
-		UpdatedAt:     s.UpdatedAt,
-		LastUsedAt:    s.LastUsedAt,
-		UsageCount:    s.UsageCount,
-		Status:        s.Status,
-		StatusMessage: s.StatusMessage,
-		DataKeys:      keys,
+		ID:               s.ID,
+		Name:             s.Name,
+		Type:             s.Type,
+		Source:           s.Source,
+		Description:      s.Description,
+		Metadata:         s.Metadata,
+		Immutable:        s.Immutable,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+		LastUsedAt:       s.LastUsedAt,
+		UsageCount:       s.UsageCount,
+		Status:           s.Status,
+		StatusMessage:    s.StatusMessage,
+		ExpiresAt:        s.ExpiresAt,
+		RotationInterval: s.RotationInterval,
+		RotationDueAt:    s.RotationDueAt(),
+		DataKeys:         keys,
 	}
 }
 
@@ -153,6 +213,19 @@ type SecretFieldInfo struct {
 	Default     string `json:"default,omitempty"`
 }
 
+// CapabilityRequirement describes which secrets a discovery capability needs
+type CapabilityRequirement struct {
+	Capability  string       `json:"capability"`
+	Description string       `json:"description"`
+	SecretTypes []SecretType `json:"secret_types"`
+	// SecretIDs lists existing secrets of a matching type
+	SecretIDs []string `json:"secret_ids"`
+	// HasSecret is true when at least one matching secret exists
+	HasSecret bool `json:"has_secret"`
+	// Usable is true when a matching secret carries the fields the capability needs
+	Usable bool `json:"usable"`
+}
+
 // GetSecretTypeInfos returns metadata for all secret types
 func GetSecretTypeInfos() []SecretTypeInfo {
 	return []SecretTypeInfo{
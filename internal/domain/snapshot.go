@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// Snapshot is a saved copy of the graph that can be restored later
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	NodeCount int       `json:"node_count"`
+	EdgeCount int       `json:"edge_count"`
+
+	// Graph holds the nodes (with truth), edges and positions. It is only
+	// loaded when restoring, not when listing snapshots.
+	Graph *GraphFragment `json:"-"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Subnet is a network segment nodes belong to through their segmentum
+// property. Subnets are created automatically the first time a node
+// references a new segmentum and carry operator metadata.
+type Subnet struct {
+	CIDR        string    `json:"cidr"`
+	VLANID      int       `json:"vlan_id,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Gateway     string    `json:"gateway,omitempty"`
+	MemberCount int       `json:"member_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// NormalizeCIDR parses a CIDR and returns it with host bits cleared,
+// e.g. 192.168.1.7/24 -> 192.168.1.0/24
+func NormalizeCIDR(s string) (string, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid CIDR %q", s)
+	}
+	return prefix.Masked().String(), nil
+}
+
+// Validate normalizes the CIDR and checks the VLAN ID (0 for none, or
+// 1-4094) and that the gateway, if set, is an address inside the subnet
+func (s *Subnet) Validate() error {
+	cidr, err := NormalizeCIDR(s.CIDR)
+	if err != nil {
+		return err
+	}
+	s.CIDR = cidr
+
+	if s.VLANID < 0 || s.VLANID > 4094 {
+		return fmt.Errorf("invalid vlan_id %d: must be between 1 and 4094", s.VLANID)
+	}
+	if s.Gateway != "" {
+		gw, err := netip.ParseAddr(s.Gateway)
+		if err != nil {
+			return fmt.Errorf("invalid gateway %q", s.Gateway)
+		}
+		if !netip.MustParsePrefix(cidr).Contains(gw) {
+			return fmt.Errorf("invalid gateway %s: not in %s", s.Gateway, cidr)
+		}
+	}
+	return nil
+}
@@ -53,7 +53,7 @@ type Discrepancy struct {
 	Source      string     `json:"source"` // verifier, scanner, etc.
 	DetectedAt  time.Time  `json:"detected_at"`
 	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
-	Resolution  string     `json:"resolution,omitempty"` // "updated_truth", "fixed_reality", "dismissed"
+	Resolution  string     `json:"resolution,omitempty"` // "updated_truth", "fixed_reality", "dismissed", "auto_matched"
 }
 
 // IsResolved returns true if the discrepancy has been resolved
@@ -68,6 +68,7 @@ const (
 	ResolutionUpdatedTruth DiscrepancyResolution = "updated_truth" // Operator updated truth to match reality
 	ResolutionFixedReality DiscrepancyResolution = "fixed_reality" // Reality was fixed to match truth
 	ResolutionDismissed    DiscrepancyResolution = "dismissed"     // Discrepancy was dismissed/ignored
+	ResolutionAutoMatched  DiscrepancyResolution = "auto_matched"  // Discovered value drifted back to match truth
 )
 
 // ExistenceAssertion defines the expected existence state of a node
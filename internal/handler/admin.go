@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"specularium/internal/adapter"
+	"specularium/internal/domain"
+)
+
+// Reloader re-reads configuration and applies adapter changes in place
+type Reloader interface {
+	Reload(ctx context.Context) (*adapter.ReloadResult, error)
+}
+
+// Vacuumer compacts the database
+type Vacuumer interface {
+	Vacuum(ctx context.Context) (*domain.VacuumResult, error)
+}
+
+// AdminHandler serves operator endpoints that change the running server
+type AdminHandler struct {
+	reloader    Reloader
+	vacuumer    Vacuumer
+	authEnabled bool
+}
+
+// NewAdminHandler creates a new admin handler. Its endpoints refuse every
+// request unless API authentication is enabled.
+func NewAdminHandler(reloader Reloader, authEnabled bool) *AdminHandler {
+	return &AdminHandler{reloader: reloader, authEnabled: authEnabled}
+}
+
+// Reload re-reads the config file and starts, stops or reconfigures
+// adapters to match it without restarting the server
+func (h *AdminHandler) Reload(w http.ResponseWriter, r *http.Request) {
+	if !h.authEnabled {
+		h.writeJSON(w, map[string]string{"error": "admin endpoints require auth.enabled"}, http.StatusForbidden)
+		return
+	}
+
+	result, err := h.reloader.Reload(r.Context())
+	if err != nil {
+		h.writeJSON(w, map[string]any{"error": err.Error(), "result": result}, http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// SetVacuumer sets the database compacted by Vacuum
+func (h *AdminHandler) SetVacuumer(v Vacuumer) {
+	h.vacuumer = v
+}
+
+// Vacuum compacts the database and reports its size before and after
+func (h *AdminHandler) Vacuum(w http.ResponseWriter, r *http.Request) {
+	if !h.authEnabled {
+		h.writeJSON(w, map[string]string{"error": "admin endpoints require auth.enabled"}, http.StatusForbidden)
+		return
+	}
+	if h.vacuumer == nil {
+		h.writeJSON(w, map[string]string{"error": "vacuum not configured"}, http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := h.vacuumer.Vacuum(r.Context())
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "already running") {
+			status = http.StatusConflict
+		} else {
+			log.Printf("Failed to vacuum database: %v", err)
+		}
+		h.writeJSON(w, map[string]string{"error": err.Error()}, status)
+		return
+	}
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// writeJSON writes a JSON response
+func (h *AdminHandler) writeJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
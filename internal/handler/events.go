@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"specularium/internal/service"
+)
+
+// EventsHandler serves the event bus history
+type EventsHandler struct {
+	bus *service.EventBus
+}
+
+// NewEventsHandler creates a new events handler
+func NewEventsHandler(bus *service.EventBus) *EventsHandler {
+	return &EventsHandler{bus: bus}
+}
+
+// GetHistory returns recently published events, oldest first, so clients
+// can backfill before subscribing to /events
+func (h *EventsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			h.writeError(w, "Invalid limit", "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	events := h.bus.History(limit)
+	h.writeJSON(w, map[string]any{
+		"events": events,
+		"count":  len(events),
+	}, http.StatusOK)
+}
+
+// writeJSON writes a JSON response
+func (h *EventsHandler) writeJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
+// writeError writes an error response
+func (h *EventsHandler) writeError(w http.ResponseWriter, message, details string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: message, Details: details}); err != nil {
+		log.Printf("Failed to encode error response: %v", err)
+	}
+}
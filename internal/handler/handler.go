@@ -1,16 +1,21 @@
 package handler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"specularium/internal/adapter"
+	"specularium/internal/config"
 	"specularium/internal/domain"
 	"specularium/internal/service"
 )
@@ -20,9 +25,21 @@ type DiscoveryTrigger interface {
 	TriggerSyncAll(ctx context.Context) error
 }
 
-// SubnetScanner allows scanning network subnets for hosts
+// AdapterLister reports the registered adapters and their run state
+type AdapterLister interface {
+	ListAdapters() []adapter.AdapterInfo
+}
+
+// SubnetScanner allows scanning network subnets for hosts. opts.Profile
+// names a configured port profile; empty or unknown uses the default ports.
 type SubnetScanner interface {
-	ScanSubnet(ctx context.Context, cidr string) error
+	ScanSubnet(ctx context.Context, cidr string, opts adapter.ScanOptions) error
+}
+
+// ScanRangeChecker is implemented by scanners that can reject an oversized
+// or malformed range before the scan is queued
+type ScanRangeChecker interface {
+	CheckScanRange(cidr string, maxHosts int) error
 }
 
 // Bootstrapper performs initial self-discovery
@@ -33,12 +50,37 @@ type Bootstrapper interface {
 	GetScanTargets() domain.ScanTargets
 }
 
+// NodeVerifier re-verifies a chosen set of nodes
+type NodeVerifier interface {
+	VerifyNodes(ctx context.Context, ids []string) error
+}
+
+// DiscoveryLimiter bounds concurrent discovery operations
+type DiscoveryLimiter interface {
+	Submit(kind, target string, fn func(ctx context.Context)) (adapter.Operation, error)
+	Status() adapter.OperationStatus
+}
+
+// TargetLister provides the configured scan targets
+type TargetLister interface {
+	ListTargets() []config.ScanTarget
+}
+
 // GraphHandler handles graph API requests
 type GraphHandler struct {
 	svc          *service.GraphService
 	discovery    DiscoveryTrigger
+	adapters     AdapterLister
 	scanner      SubnetScanner
 	bootstrapper Bootstrapper
+	targets      TargetLister
+	limiter      DiscoveryLimiter
+	verifier     NodeVerifier
+
+	// Background operations started by requests derive their context
+	// from baseCtx and stop after operationTimeout
+	baseCtx          context.Context
+	operationTimeout time.Duration
 }
 
 // NewGraphHandler creates a new graph handler
@@ -51,6 +93,11 @@ func (h *GraphHandler) SetDiscoveryTrigger(d DiscoveryTrigger) {
 	h.discovery = d
 }
 
+// SetAdapterLister sets the source of adapter status (adapter registry)
+func (h *GraphHandler) SetAdapterLister(l AdapterLister) {
+	h.adapters = l
+}
+
 // SetSubnetScanner sets the subnet scanner
 func (h *GraphHandler) SetSubnetScanner(s SubnetScanner) {
 	h.scanner = s
@@ -61,6 +108,46 @@ func (h *GraphHandler) SetBootstrapper(b Bootstrapper) {
 	h.bootstrapper = b
 }
 
+// SetTargetLister sets the source of configured scan targets
+func (h *GraphHandler) SetTargetLister(t TargetLister) {
+	h.targets = t
+}
+
+// SetNodeVerifier sets the verifier used for targeted re-verification
+func (h *GraphHandler) SetNodeVerifier(v NodeVerifier) {
+	h.verifier = v
+}
+
+// SetDiscoveryLimiter sets the limiter that queues requested scans
+func (h *GraphHandler) SetDiscoveryLimiter(l DiscoveryLimiter) {
+	h.limiter = l
+}
+
+// SetOperationContext sets the context background operations run under,
+// cancelled on shutdown, and the deadline each operation gets (0 = none)
+func (h *GraphHandler) SetOperationContext(ctx context.Context, timeout time.Duration) {
+	h.baseCtx = ctx
+	h.operationTimeout = timeout
+}
+
+// operationContext derives the context for a background operation from
+// parent. It is cancelled when the operation timeout elapses or the
+// handler's base context is done.
+func (h *GraphHandler) operationContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if h.operationTimeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, h.operationTimeout)
+	}
+	if h.baseCtx == nil {
+		return ctx, cancel
+	}
+	stop := context.AfterFunc(h.baseCtx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
 // Error response structure
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -69,29 +156,242 @@ type ErrorResponse struct {
 
 // GetGraph returns the complete graph
 func (h *GraphHandler) GetGraph(w http.ResponseWriter, r *http.Request) {
-	graph, err := h.svc.GetGraph(r.Context())
+	query := r.URL.Query()
+
+	// ?collapse_subnets=true (or a member threshold) rolls dense subnets up
+	// into one edge each; repeated ?expand= keeps those subnets expanded
+	threshold := 0
+	if raw := query.Get("collapse_subnets"); raw != "" && raw != "false" {
+		threshold = service.DefaultCollapseThreshold
+		if raw != "true" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				h.writeError(w, "Invalid collapse_subnets", "collapse_subnets must be true, false or a positive member count", http.StatusBadRequest)
+				return
+			}
+			threshold = n
+		}
+	}
+
+	// Repeated ?tag= narrows the graph to nodes carrying every tag and the
+	// edges between them
+	filter := domain.ExportFilter{Tags: query["tag"]}
+	if err := filter.Normalize(); err != nil {
+		h.writeError(w, "Invalid tag", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := h.svc.GetSubgraph(r.Context(), filter)
 	if err != nil {
 		log.Printf("Failed to get graph: %v", err)
 		h.writeError(w, "Failed to get graph", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	if threshold > 0 {
+		expand := make(map[string]bool)
+		for _, id := range query["expand"] {
+			expand[id] = true
+		}
+		graph = service.CollapseSubnetEdges(graph, threshold, expand)
+	}
+
 	h.writeJSON(w, graph, http.StatusOK)
 }
 
-// ListNodes returns all nodes
+// GetStats returns node and edge counts without the full graph
+func (h *GraphHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.svc.GetStats(r.Context())
+	if err != nil {
+		log.Printf("Failed to get graph stats: %v", err)
+		h.writeError(w, "Failed to get graph stats", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, stats, http.StatusOK)
+}
+
+// DiffGraph reports what importing the request body with the replace
+// strategy would change, without applying it. ?format= selects the parser
+// (yaml, json, ansible or csv; default yaml).
+func (h *GraphHandler) DiffGraph(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, "Failed to read request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fragment, err := service.ParseImport(r.URL.Query().Get("format"), data)
+	if err != nil {
+		h.writeError(w, "Invalid import", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	diff, err := h.svc.DiffFragment(r.Context(), fragment)
+	if err != nil {
+		log.Printf("Failed to diff graph: %v", err)
+		h.writeError(w, "Failed to diff graph", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, diff, http.StatusOK)
+}
+
+// ValidateImport parses an import and reports structural problems without
+// writing anything. Parse failures are reported as issues with a 200.
+func (h *GraphHandler) ValidateImport(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, "Failed to read request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.svc.ValidateImport(r.Context(), r.URL.Query().Get("format"), data)
+	if err != nil {
+		if strings.Contains(err.Error(), "unsupported import format") {
+			h.writeError(w, "Invalid format", err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to validate import: %v", err)
+		h.writeError(w, "Failed to validate import", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// InferEdges links nodes sharing a segmentum to a synthetic subnet node
+func (h *GraphHandler) InferEdges(w http.ResponseWriter, r *http.Request) {
+	result, err := h.svc.InferSubnetEdges(r.Context())
+	if err != nil {
+		log.Printf("Failed to infer subnet edges: %v", err)
+		h.writeError(w, "Failed to infer edges", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// ListOrphans reports edgeless nodes and edges with missing endpoints
+func (h *GraphHandler) ListOrphans(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.FindOrphans(r.Context())
+	if err != nil {
+		log.Printf("Failed to find orphans: %v", err)
+		h.writeError(w, "Failed to find orphans", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, report, http.StatusOK)
+}
+
+// GetConnectivity reports the nodes reachable from the self node and the
+// islands cut off from it
+func (h *GraphHandler) GetConnectivity(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svc.AnalyzeConnectivity(r.Context())
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Self node not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to analyze connectivity: %v", err)
+		h.writeError(w, "Failed to analyze connectivity", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, report, http.StatusOK)
+}
+
+// ListNodes returns all nodes, filtered by ?type=, ?source= and ?role=.
+// Repeated ?tag= parameters only match nodes carrying every tag.
 func (h *GraphHandler) ListNodes(w http.ResponseWriter, r *http.Request) {
-	nodeType := r.URL.Query().Get("type")
-	source := r.URL.Query().Get("source")
+	query := r.URL.Query()
 
-	nodes, err := h.svc.ListNodes(r.Context(), nodeType, source)
+	// ?updated_since= lists recently changed nodes instead
+	if raw := query.Get("updated_since"); raw != "" {
+		since, err := parseSince(raw, time.Now())
+		if err != nil {
+			h.writeError(w, "Invalid updated_since", err.Error(), http.StatusBadRequest)
+			return
+		}
+		nodes, err := h.svc.GetNodesUpdatedSince(r.Context(), since)
+		if err != nil {
+			log.Printf("Failed to list updated nodes: %v", err)
+			h.writeError(w, "Failed to list nodes", err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.writeProjected(w, r, nodes, nodeViews)
+		return
+	}
+
+	filter := domain.NodeFilter{
+		Type:   domain.NodeType(query.Get("type")),
+		Source: query.Get("source"),
+		Role:   domain.NodeRole(query.Get("role")),
+		Tags:   query["tag"],
+		Sort:   query.Get("sort"),
+	}
+
+	nodes, err := h.svc.ListNodes(r.Context(), filter)
 	if err != nil {
+		if strings.Contains(err.Error(), "invalid tag") {
+			h.writeError(w, "Invalid tag", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid sort") {
+			h.writeError(w, "Invalid sort", err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid role") {
+			h.writeError(w, "Invalid role", err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("Failed to list nodes: %v", err)
 		h.writeError(w, "Failed to list nodes", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.writeJSON(w, nodes, http.StatusOK)
+	h.writeProjected(w, r, nodes, nodeViews)
+}
+
+// parseSince reads an RFC3339 timestamp, or a duration such as "15m"
+// meaning that long before now
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is not an RFC3339 timestamp or a positive duration", raw)
+	}
+	return now.Add(-d), nil
+}
+
+// SearchNodes returns nodes matching ?q= in label, ID, properties or discovered data
+func (h *GraphHandler) SearchNodes(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		h.writeError(w, "Invalid query", "Search query q is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			h.writeError(w, "Invalid limit", "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	nodes, err := h.svc.SearchNodes(r.Context(), query, limit)
+	if err != nil {
+		log.Printf("Failed to search nodes: %v", err)
+		h.writeError(w, "Failed to search nodes", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeProjected(w, r, nodes, nodeViews)
 }
 
 // GetNode returns a single node
@@ -113,7 +413,7 @@ func (h *GraphHandler) GetNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeJSON(w, node, http.StatusOK)
+	h.writeProjected(w, r, node, nodeViews)
 }
 
 // CreateNode creates a new node
@@ -133,6 +433,52 @@ func (h *GraphHandler) CreateNode(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, node, http.StatusCreated)
 }
 
+// CreateNodes creates a batch of nodes in one transaction. Failed nodes
+// are reported per ID; with ?atomic=true any failure rolls back the batch.
+func (h *GraphHandler) CreateNodes(w http.ResponseWriter, r *http.Request) {
+	var nodes []*domain.Node
+	if err := json.NewDecoder(r.Body).Decode(&nodes); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(nodes) == 0 {
+		h.writeError(w, "Invalid request body", "at least one node is required", http.StatusBadRequest)
+		return
+	}
+	for i, node := range nodes {
+		if node == nil {
+			h.writeError(w, "Invalid request body", fmt.Sprintf("node %d is null", i), http.StatusBadRequest)
+			return
+		}
+	}
+	atomic := r.URL.Query().Get("atomic") == "true"
+
+	results, err := h.svc.CreateNodes(r.Context(), nodes, atomic)
+	if err != nil {
+		log.Printf("Failed to create nodes: %v", err)
+		h.writeError(w, "Failed to create nodes", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	created := 0
+	for _, res := range results {
+		if res.Created {
+			created++
+		}
+	}
+
+	status := http.StatusOK
+	if atomic && created == 0 {
+		status = http.StatusConflict
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"results": results,
+		"created": created,
+		"failed":  len(results) - created,
+		"atomic":  atomic,
+	}, status)
+}
+
 // UpdateNode updates an existing node
 func (h *GraphHandler) UpdateNode(w http.ResponseWriter, r *http.Request) {
 	id := extractPathParam(r.URL.Path, "/api/nodes/")
@@ -175,33 +521,390 @@ func (h *GraphHandler) DeleteNode(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.DeleteNode(r.Context(), id); err != nil {
+	// Soft-delete by default; ?hard=true removes the node permanently and
+	// ?force=true deletes a protected node
+	hard := r.URL.Query().Get("hard") == "true"
+	force := r.URL.Query().Get("force") == "true"
+
+	result, err := h.svc.DeleteNode(r.Context(), id, hard, force)
+	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
 			return
 		}
+		if strings.Contains(err.Error(), "is protected") {
+			h.writeError(w, "Node is protected", err.Error(), http.StatusConflict)
+			return
+		}
 		log.Printf("Failed to delete node: %v", err)
 		h.writeError(w, "Failed to delete node", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// RestoreNode brings back a soft-deleted node
+func (h *GraphHandler) RestoreNode(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	node, err := h.svc.RestoreNode(r.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to restore node: %v", err)
+		h.writeError(w, "Failed to restore node", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, node, http.StatusOK)
+}
+
+// PutNodeImage attaches an icon or photo to a node.
+// Accepts a raw image body or a multipart form with an "image" field.
+func (h *GraphHandler) PutNodeImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	// Allow slack for multipart framing; the service enforces the exact limit
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxNodeImageSize+64*1024)
+
+	var src io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			h.writeError(w, "Invalid upload", err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		src = file
+	}
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		h.writeError(w, "Image too large", err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Trust the bytes, not the declared Content-Type
+	contentType := http.DetectContentType(data)
+
+	if err := h.svc.SetNodeImage(r.Context(), id, contentType, data); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "exceeds") {
+			h.writeError(w, "Image too large", err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.writeError(w, "Invalid image", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"node_id":      id,
+		"content_type": contentType,
+		"size":         len(data),
+	}, http.StatusOK)
+}
+
+// GetNodeImage serves the image attached to a node
+func (h *GraphHandler) GetNodeImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	img, err := h.svc.GetNodeImage(r.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to get node image: %v", err)
+		h.writeError(w, "Failed to get node image", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", img.ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", img.UpdatedAt, bytes.NewReader(img.Data))
+}
+
+// DeleteNodeImage removes the image attached to a node
+func (h *GraphHandler) DeleteNodeImage(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteNodeImage(r.Context(), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete node image: %v", err)
+		h.writeError(w, "Failed to delete node image", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ListEdges returns all edges
+// nodeTagsRequest is the body of POST /api/nodes/{id}/tags
+type nodeTagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// nodeTagsResponse reports a node's tags after a change
+type nodeTagsResponse struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+// AddNodeTags attaches the tags in the request body to a node
+func (h *GraphHandler) AddNodeTags(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req nodeTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tags, err := h.svc.AddTags(r.Context(), id, req.Tags)
+	if err != nil {
+		h.writeTagError(w, "Failed to add tags", err)
+		return
+	}
+
+	h.writeJSON(w, nodeTagsResponse{ID: id, Tags: tags}, http.StatusOK)
+}
+
+// applyTagRequest is the body of POST /api/tags/{tag}/apply
+type applyTagRequest struct {
+	Type      domain.NodeType `json:"type"`
+	Source    string          `json:"source"`
+	Segmentum string          `json:"segmentum"` // CIDR matched against node IPs
+}
+
+// ApplyTag attaches the path tag to every node matching the type, source
+// and segmentum criteria in the request body
+func (h *GraphHandler) ApplyTag(w http.ResponseWriter, r *http.Request) {
+	var req applyTagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter := domain.ExportFilter{
+		Type:   req.Type,
+		Source: req.Source,
+		CIDR:   strings.TrimSpace(req.Segmentum),
+	}
+	result, err := h.svc.ApplyTag(r.Context(), r.PathValue("tag"), filter)
+	if err != nil {
+		h.writeTagError(w, "Failed to apply tag", err)
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
+// RemoveNodeTags detaches the tags named by repeated ?tag= parameters
+func (h *GraphHandler) RemoveNodeTags(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := h.svc.RemoveTags(r.Context(), id, r.URL.Query()["tag"])
+	if err != nil {
+		h.writeTagError(w, "Failed to remove tags", err)
+		return
+	}
+
+	h.writeJSON(w, nodeTagsResponse{ID: id, Tags: tags}, http.StatusOK)
+}
+
+// GetNodeHistory returns recorded property changes for a node, newest first.
+// ?limit= bounds the number of entries returned.
+func (h *GraphHandler) GetNodeHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			h.writeError(w, "Invalid limit", "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	history, err := h.svc.GetNodeHistory(r.Context(), id, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to get node history: %v", err)
+		h.writeError(w, "Failed to get node history", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, map[string]any{
+		"node_id": id,
+		"history": history,
+		"count":   len(history),
+	}, http.StatusOK)
+}
+
+// GetNodeCapabilities returns a node's capabilities with the evidence
+// behind each confidence score
+func (h *GraphHandler) GetNodeCapabilities(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	capabilities, err := h.svc.GetNodeCapabilities(r.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to get node capabilities: %v", err)
+		h.writeError(w, "Failed to get node capabilities", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, map[string]any{
+		"node_id":      id,
+		"capabilities": capabilities,
+	}, http.StatusOK)
+}
+
+// GetNodeNeighbors returns the subgraph around a node.
+// ?depth= sets how many hops to walk (default 1, capped at 3).
+func (h *GraphHandler) GetNodeNeighbors(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid node ID", "Node ID is required", http.StatusBadRequest)
+		return
+	}
+
+	depth := 1
+	if raw := r.URL.Query().Get("depth"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			h.writeError(w, "Invalid depth", "depth must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		depth = min(n, service.MaxNeighborDepth)
+	}
+
+	fragment, err := h.svc.GetNeighborhood(r.Context(), id, depth)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to get node neighbors: %v", err)
+		h.writeError(w, "Failed to get node neighbors", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, map[string]any{
+		"node_id": id,
+		"depth":   depth,
+		"nodes":   fragment.Nodes,
+		"edges":   fragment.Edges,
+	}, http.StatusOK)
+}
+
+// writeTagError maps tag operation errors to status codes
+func (h *GraphHandler) writeTagError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+	case strings.Contains(err.Error(), "invalid tag"), strings.Contains(err.Error(), "tag is required"),
+		strings.Contains(err.Error(), "invalid request"), strings.Contains(err.Error(), "invalid CIDR"):
+		h.writeError(w, "Invalid tags", err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s: %v", message, err)
+		h.writeError(w, message, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ListEdges returns all edges, filtered by type, from_id, to_id and
+// min_speed (Mbit/s)
 func (h *GraphHandler) ListEdges(w http.ResponseWriter, r *http.Request) {
-	edgeType := r.URL.Query().Get("type")
-	fromID := r.URL.Query().Get("from_id")
-	toID := r.URL.Query().Get("to_id")
+	query := r.URL.Query()
+	filter := domain.EdgeFilter{
+		Type:   domain.EdgeType(query.Get("type")),
+		FromID: query.Get("from_id"),
+		ToID:   query.Get("to_id"),
+	}
+	if raw := query.Get("min_speed"); raw != "" {
+		speed, err := strconv.Atoi(raw)
+		if err != nil || speed < 0 {
+			h.writeError(w, "Invalid min_speed", "min_speed must be a non-negative number of Mbit/s", http.StatusBadRequest)
+			return
+		}
+		filter.MinSpeedMbps = speed
+	}
 
-	edges, err := h.svc.ListEdges(r.Context(), edgeType, fromID, toID)
+	edges, err := h.svc.ListEdges(r.Context(), filter)
 	if err != nil {
 		log.Printf("Failed to list edges: %v", err)
 		h.writeError(w, "Failed to list edges", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.writeJSON(w, edges, http.StatusOK)
+	h.writeProjected(w, r, edges, edgeViews)
+}
+
+// GetEdgesBetween returns the edges connecting two nodes in either
+// direction, or an empty array when they are not connected
+func (h *GraphHandler) GetEdgesBetween(w http.ResponseWriter, r *http.Request) {
+	fromID := r.URL.Query().Get("from")
+	toID := r.URL.Query().Get("to")
+	if fromID == "" || toID == "" {
+		h.writeError(w, "Invalid request", "from and to are required", http.StatusBadRequest)
+		return
+	}
+
+	edges, err := h.svc.GetEdgesBetween(r.Context(), fromID, toID)
+	if err != nil {
+		log.Printf("Failed to get edges between nodes: %v", err)
+		h.writeError(w, "Failed to get edges", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeProjected(w, r, edges, edgeViews)
 }
 
 // GetEdge returns a single edge
@@ -223,7 +926,7 @@ func (h *GraphHandler) GetEdge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.writeJSON(w, edge, http.StatusOK)
+	h.writeProjected(w, r, edge, edgeViews)
 }
 
 // CreateEdge creates a new edge
@@ -243,6 +946,36 @@ func (h *GraphHandler) CreateEdge(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, edge, http.StatusCreated)
 }
 
+// SuggestEdgeType suggests an edge type for linking two nodes without
+// creating the edge
+func (h *GraphHandler) SuggestEdgeType(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		FromID string `json:"from_id"`
+		ToID   string `json:"to_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := h.svc.SuggestEdgeType(r.Context(), req.FromID, req.ToID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		if strings.Contains(err.Error(), "invalid") {
+			h.writeError(w, "Invalid request", err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Failed to suggest edge type: %v", err)
+		h.writeError(w, "Failed to suggest edge type", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, suggestion, http.StatusOK)
+}
+
 // UpdateEdge updates an existing edge
 func (h *GraphHandler) UpdateEdge(w http.ResponseWriter, r *http.Request) {
 	id := extractPathParam(r.URL.Path, "/api/edges/")
@@ -270,34 +1003,129 @@ func (h *GraphHandler) UpdateEdge(w http.ResponseWriter, r *http.Request) {
 	// Return updated edge
 	edge, err := h.svc.GetEdge(r.Context(), id)
 	if err != nil {
-		log.Printf("Failed to fetch updated edge: %v", err)
-		w.WriteHeader(http.StatusNoContent)
+		log.Printf("Failed to fetch updated edge: %v", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h.writeJSON(w, edge, http.StatusOK)
+}
+
+// DeleteEdge deletes an edge
+func (h *GraphHandler) DeleteEdge(w http.ResponseWriter, r *http.Request) {
+	id := extractPathParam(r.URL.Path, "/api/edges/")
+	if id == "" {
+		h.writeError(w, "Invalid edge ID", "Edge ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteEdge(r.Context(), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete edge: %v", err)
+		h.writeError(w, "Failed to delete edge", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSubnets returns all subnets with their member counts
+func (h *GraphHandler) ListSubnets(w http.ResponseWriter, r *http.Request) {
+	subnets, err := h.svc.ListSubnets(r.Context())
+	if err != nil {
+		log.Printf("Failed to list subnets: %v", err)
+		h.writeError(w, "Failed to list subnets", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, subnets, http.StatusOK)
+}
+
+// ListVLANs returns the VLANs carried by interface nodes with their members
+func (h *GraphHandler) ListVLANs(w http.ResponseWriter, r *http.Request) {
+	vlans, err := h.svc.ListVLANs(r.Context())
+	if err != nil {
+		log.Printf("Failed to list VLANs: %v", err)
+		h.writeError(w, "Failed to list VLANs", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, vlans, http.StatusOK)
+}
+
+// GetSubnet returns a single subnet. The CIDR is the rest of the path,
+// e.g. /api/subnets/192.168.1.0/24
+func (h *GraphHandler) GetSubnet(w http.ResponseWriter, r *http.Request) {
+	subnet, err := h.svc.GetSubnet(r.Context(), r.PathValue("cidr"))
+	if err != nil {
+		h.writeSubnetError(w, "Failed to get subnet", err)
+		return
+	}
+
+	h.writeJSON(w, subnet, http.StatusOK)
+}
+
+// CreateSubnet registers a subnet with its VLAN, description and gateway
+func (h *GraphHandler) CreateSubnet(w http.ResponseWriter, r *http.Request) {
+	var subnet domain.Subnet
+	if err := json.NewDecoder(r.Body).Decode(&subnet); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.svc.CreateSubnet(r.Context(), &subnet)
+	if err != nil {
+		h.writeSubnetError(w, "Failed to create subnet", err)
 		return
 	}
-	h.writeJSON(w, edge, http.StatusOK)
+
+	h.writeJSON(w, created, http.StatusCreated)
 }
 
-// DeleteEdge deletes an edge
-func (h *GraphHandler) DeleteEdge(w http.ResponseWriter, r *http.Request) {
-	id := extractPathParam(r.URL.Path, "/api/edges/")
-	if id == "" {
-		h.writeError(w, "Invalid edge ID", "Edge ID is required", http.StatusBadRequest)
+// UpdateSubnet replaces a subnet's VLAN, description and gateway
+func (h *GraphHandler) UpdateSubnet(w http.ResponseWriter, r *http.Request) {
+	var subnet domain.Subnet
+	if err := json.NewDecoder(r.Body).Decode(&subnet); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := h.svc.DeleteEdge(r.Context(), id); err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
-			return
-		}
-		log.Printf("Failed to delete edge: %v", err)
-		h.writeError(w, "Failed to delete edge", err.Error(), http.StatusInternalServerError)
+	updated, err := h.svc.UpdateSubnet(r.Context(), r.PathValue("cidr"), &subnet)
+	if err != nil {
+		h.writeSubnetError(w, "Failed to update subnet", err)
+		return
+	}
+
+	h.writeJSON(w, updated, http.StatusOK)
+}
+
+// DeleteSubnet removes a subnet that no node references any more
+func (h *GraphHandler) DeleteSubnet(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.DeleteSubnet(r.Context(), r.PathValue("cidr")); err != nil {
+		h.writeSubnetError(w, "Failed to delete subnet", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// writeSubnetError maps subnet service errors to status codes
+func (h *GraphHandler) writeSubnetError(w http.ResponseWriter, message string, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		h.writeError(w, "Not found", msg, http.StatusNotFound)
+	case strings.Contains(msg, "invalid"):
+		h.writeError(w, "Invalid subnet", msg, http.StatusBadRequest)
+	case strings.Contains(msg, "already exists"), strings.Contains(msg, "member nodes"):
+		h.writeError(w, message, msg, http.StatusConflict)
+	default:
+		log.Printf("%s: %v", message, err)
+		h.writeError(w, message, msg, http.StatusInternalServerError)
+	}
+}
+
 // GetPositions returns all node positions
 func (h *GraphHandler) GetPositions(w http.ResponseWriter, r *http.Request) {
 	positions, err := h.svc.GetAllPositions(r.Context())
@@ -318,13 +1146,17 @@ func (h *GraphHandler) SavePositions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.svc.SavePositions(r.Context(), positions); err != nil {
+	saved, err := h.svc.SavePositions(r.Context(), positions)
+	if err != nil {
 		log.Printf("Failed to save positions: %v", err)
 		h.writeError(w, "Failed to save positions", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	h.writeJSON(w, map[string]int{"saved": len(positions)}, http.StatusOK)
+	h.writeJSON(w, map[string]int{
+		"saved":     saved,
+		"unchanged": len(positions) - saved,
+	}, http.StatusOK)
 }
 
 // UpdatePosition updates a single node position
@@ -352,6 +1184,60 @@ func (h *GraphHandler) UpdatePosition(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, pos, http.StatusOK)
 }
 
+// CreateSnapshot saves the current graph. The body may set {"label": "..."}.
+func (h *GraphHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Label string `json:"label"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	snap, err := h.svc.CreateSnapshot(r.Context(), req.Label)
+	if err != nil {
+		log.Printf("Failed to create snapshot: %v", err)
+		h.writeError(w, "Failed to create snapshot", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, snap, http.StatusCreated)
+}
+
+// ListSnapshots returns saved snapshots, newest first
+func (h *GraphHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.svc.ListSnapshots(r.Context())
+	if err != nil {
+		log.Printf("Failed to list snapshots: %v", err)
+		h.writeError(w, "Failed to list snapshots", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, snapshots, http.StatusOK)
+}
+
+// RestoreSnapshot replaces the live graph with a saved snapshot
+func (h *GraphHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid snapshot ID", "Snapshot ID is required", http.StatusBadRequest)
+		return
+	}
+
+	snap, err := h.svc.RestoreSnapshot(r.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to restore snapshot: %v", err)
+		h.writeError(w, "Failed to restore snapshot", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, snap, http.StatusOK)
+}
+
 // ImportYAML imports graph data from YAML
 func (h *GraphHandler) ImportYAML(w http.ResponseWriter, r *http.Request) {
 	strategy := r.URL.Query().Get("strategy")
@@ -375,6 +1261,29 @@ func (h *GraphHandler) ImportYAML(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, result, http.StatusOK)
 }
 
+// ImportCSV imports nodes from a CSV inventory. Bad rows are reported and
+// skipped; with ?atomic=true any bad row aborts the import with 409.
+func (h *GraphHandler) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	strategy := r.URL.Query().Get("strategy")
+	if strategy == "" {
+		strategy = "merge"
+	}
+	atomic := r.URL.Query().Get("atomic") == "true"
+
+	result, err := h.svc.ImportCSV(r.Context(), r.Body, strategy, atomic)
+	if err != nil {
+		log.Printf("Failed to import CSV: %v", err)
+		h.writeError(w, "Failed to import CSV", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	if result.RolledBack {
+		status = http.StatusConflict
+	}
+	h.writeJSON(w, result, status)
+}
+
 // ImportAnsibleInventory imports graph data from Ansible inventory
 func (h *GraphHandler) ImportAnsibleInventory(w http.ResponseWriter, r *http.Request) {
 	strategy := r.URL.Query().Get("strategy")
@@ -398,9 +1307,38 @@ func (h *GraphHandler) ImportAnsibleInventory(w http.ResponseWriter, r *http.Req
 	h.writeJSON(w, result, http.StatusOK)
 }
 
+// ImportPrometheusSD imports target hosts from a Prometheus file_sd JSON file
+func (h *GraphHandler) ImportPrometheusSD(w http.ResponseWriter, r *http.Request) {
+	strategy := r.URL.Query().Get("strategy")
+	if strategy == "" {
+		strategy = "merge"
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, "Failed to read request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.svc.ImportPrometheusSD(r.Context(), data, strategy)
+	if err != nil {
+		log.Printf("Failed to import Prometheus service discovery file: %v", err)
+		h.writeError(w, "Failed to import Prometheus service discovery file", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
 // ScanRequest represents a subnet scan request
 type ScanRequest struct {
+	// CIDR is a range, a single address or a hostname to resolve
 	CIDR string `json:"cidr"`
+	// Profile selects a configured port profile (optional)
+	Profile string `json:"profile,omitempty"`
+	// MaxHosts raises or lowers the scan size limit for this scan, up to
+	// adapter.MaxScanHostsCeiling (optional)
+	MaxHosts int `json:"max_hosts,omitempty"`
 }
 
 // ImportScan handles network scan requests
@@ -417,23 +1355,79 @@ func (h *GraphHandler) ImportScan(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.CIDR == "" {
-		h.writeError(w, "CIDR required", "Please provide a CIDR range to scan (e.g., 192.168.0.0/24)", http.StatusBadRequest)
+		h.writeError(w, "CIDR required", "Please provide a CIDR range, address or hostname to scan (e.g., 192.168.0.0/24)", http.StatusBadRequest)
 		return
 	}
 
-	// Run scan in background and return immediately
-	go func() {
-		if err := h.scanner.ScanSubnet(context.Background(), req.CIDR); err != nil {
+	if checker, ok := h.scanner.(ScanRangeChecker); ok {
+		if err := checker.CheckScanRange(req.CIDR, req.MaxHosts); err != nil {
+			h.writeError(w, "Invalid scan range", err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The deadline starts when the scan runs, not while it is queued
+	opts := adapter.ScanOptions{Profile: req.Profile, MaxHosts: req.MaxHosts}
+	scan := func(ctx context.Context) {
+		ctx, cancel := h.operationContext(ctx)
+		defer cancel()
+		if err := h.scanner.ScanSubnet(ctx, req.CIDR, opts); err != nil {
 			log.Printf("Subnet scan failed: %v", err)
 		}
-	}()
+	}
+
+	if h.limiter == nil {
+		// Run scan in background and return immediately
+		go scan(context.Background())
+		h.writeJSON(w, map[string]string{
+			"status": "scan_started",
+			"cidr":   req.CIDR,
+		}, http.StatusAccepted)
+		return
+	}
+
+	op, err := h.limiter.Submit("scan", req.CIDR, scan)
+	if errors.Is(err, adapter.ErrDiscoveryQueueFull) {
+		w.Header().Set("Retry-After", "30")
+		h.writeError(w, "Too many discovery operations", err.Error(), http.StatusTooManyRequests)
+		return
+	}
+	if err != nil {
+		h.writeError(w, "Failed to start scan", err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	h.writeJSON(w, map[string]string{
-		"status": "scan_started",
-		"cidr":   req.CIDR,
+	status := "scan_started"
+	if op.State == adapter.OperationQueued {
+		status = "scan_queued"
+	}
+	h.writeJSON(w, map[string]interface{}{
+		"status":    status,
+		"cidr":      req.CIDR,
+		"operation": op,
+		"position":  op.Position,
 	}, http.StatusAccepted)
 }
 
+// GetDiscoveryStatus returns running and queued discovery operations
+func (h *GraphHandler) GetDiscoveryStatus(w http.ResponseWriter, r *http.Request) {
+	if h.limiter == nil {
+		h.writeError(w, "Discovery limiter not configured", "No discovery limiter is registered", http.StatusServiceUnavailable)
+		return
+	}
+	h.writeJSON(w, h.limiter.Status(), http.StatusOK)
+}
+
+// ListAdapters returns each registered adapter with whether it is running,
+// when it last synced and its last start or sync error
+func (h *GraphHandler) ListAdapters(w http.ResponseWriter, r *http.Request) {
+	if h.adapters == nil {
+		h.writeJSON(w, []adapter.AdapterInfo{}, http.StatusOK)
+		return
+	}
+	h.writeJSON(w, h.adapters.ListAdapters(), http.StatusOK)
+}
+
 // Bootstrap triggers self-discovery from the current deployment environment
 func (h *GraphHandler) Bootstrap(w http.ResponseWriter, r *http.Request) {
 	if h.bootstrapper == nil {
@@ -443,7 +1437,9 @@ func (h *GraphHandler) Bootstrap(w http.ResponseWriter, r *http.Request) {
 
 	// Run bootstrap in background and return immediately
 	go func() {
-		if err := h.bootstrapper.Bootstrap(context.Background()); err != nil {
+		ctx, cancel := h.operationContext(context.Background())
+		defer cancel()
+		if err := h.bootstrapper.Bootstrap(ctx); err != nil {
 			log.Printf("Bootstrap failed: %v", err)
 		}
 	}()
@@ -475,20 +1471,44 @@ func (h *GraphHandler) GetEnvironment(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+// GetTargets returns the configured scan targets, including disabled entries
+func (h *GraphHandler) GetTargets(w http.ResponseWriter, r *http.Request) {
+	targets := []config.ScanTarget{}
+	if h.targets != nil {
+		targets = append(targets, h.targets.ListTargets()...)
+	}
+
+	h.writeJSON(w, targets, http.StatusOK)
+}
+
 // ClearGraph removes all nodes, edges, and positions
-// After clearing, it automatically re-runs bootstrap to rediscover infrastructure
+// After clearing, it automatically re-runs bootstrap to rediscover infrastructure.
+// ?dry_run=true only reports the counts that would be deleted, and
+// ?keep_truth=true keeps nodes carrying operator truth.
 func (h *GraphHandler) ClearGraph(w http.ResponseWriter, r *http.Request) {
-	if err := h.svc.ClearGraph(r.Context()); err != nil {
+	opts := domain.ClearOptions{
+		DryRun:    r.URL.Query().Get("dry_run") == "true",
+		KeepTruth: r.URL.Query().Get("keep_truth") == "true",
+	}
+
+	result, err := h.svc.ClearGraph(r.Context(), opts)
+	if err != nil {
 		log.Printf("Failed to clear graph: %v", err)
 		h.writeError(w, "Failed to clear graph", err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if opts.DryRun {
+		h.writeJSON(w, result, http.StatusOK)
+		return
+	}
 
 	// Auto-trigger bootstrap after clear to rediscover infrastructure
 	if h.bootstrapper != nil {
 		go func() {
+			ctx, cancel := h.operationContext(context.Background())
+			defer cancel()
 			log.Printf("Auto-triggering bootstrap after graph clear...")
-			if err := h.bootstrapper.Bootstrap(context.Background()); err != nil {
+			if err := h.bootstrapper.Bootstrap(ctx); err != nil {
 				log.Printf("Post-clear bootstrap failed: %v", err)
 			}
 		}()
@@ -497,14 +1517,20 @@ func (h *GraphHandler) ClearGraph(w http.ResponseWriter, r *http.Request) {
 	// Also trigger discovery adapters (nmap, verifier, etc.)
 	if h.discovery != nil {
 		go func() {
+			ctx, cancel := h.operationContext(context.Background())
+			defer cancel()
 			log.Printf("Auto-triggering discovery adapters after graph clear...")
-			if err := h.discovery.TriggerSyncAll(context.Background()); err != nil {
+			if err := h.discovery.TriggerSyncAll(ctx); err != nil {
 				log.Printf("Post-clear discovery failed: %v", err)
 			}
 		}()
 	}
 
-	h.writeJSON(w, map[string]string{"status": "cleared", "bootstrap": "triggered"}, http.StatusOK)
+	h.writeJSON(w, map[string]any{
+		"status":    "cleared",
+		"bootstrap": "triggered",
+		"deleted":   result,
+	}, http.StatusOK)
 }
 
 // RegisterClient creates or updates a node for the browser client
@@ -580,12 +1606,12 @@ func (h *GraphHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
 		ID:     nodeID,
 		Type:   domain.NodeTypeServer, // Generic server type for client devices
 		Label:  label,
+		Role:   domain.RoleClient,
 		Source: "client",
 		Status: domain.NodeStatusVerified, // We know it's alive - it's talking to us!
 		Properties: map[string]any{
 			"ip":        clientIP,
 			"segmentum": segmentum,
-			"role":      "client",
 		},
 		Discovered: map[string]any{
 			"last_browser_visit": now.Format(time.RFC3339),
@@ -659,9 +1685,17 @@ func (h *GraphHandler) TriggerDiscovery(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// ?force=true rescans targets even if a cached nmap result is still fresh
+	ctx := context.Background()
+	if r.URL.Query().Get("force") == "true" {
+		ctx = adapter.WithForceRescan(ctx)
+	}
+
 	// Run discovery in background and return immediately
 	go func() {
-		if err := h.discovery.TriggerSyncAll(context.Background()); err != nil {
+		ctx, cancel := h.operationContext(ctx)
+		defer cancel()
+		if err := h.discovery.TriggerSyncAll(ctx); err != nil {
 			log.Printf("Discovery sync failed: %v", err)
 		}
 	}()
@@ -669,44 +1703,234 @@ func (h *GraphHandler) TriggerDiscovery(w http.ResponseWriter, r *http.Request)
 	h.writeJSON(w, map[string]string{"status": "discovery_triggered"}, http.StatusAccepted)
 }
 
-// ExportJSON exports the graph as JSON
-func (h *GraphHandler) ExportJSON(w http.ResponseWriter, r *http.Request) {
-	data, err := h.svc.ExportJSON(r.Context())
+// VerifyNodes re-verifies the nodes listed in {"node_ids": [...]}, or
+// without a body every node inside ?segmentum= (a CIDR), immediately and
+// in the background
+func (h *GraphHandler) VerifyNodes(w http.ResponseWriter, r *http.Request) {
+	if h.verifier == nil {
+		h.writeError(w, "Verification not configured", "The verifier adapter is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		NodeIDs []string `json:"node_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids, err := h.svc.SelectNodesForVerification(r.Context(), req.NodeIDs, strings.TrimSpace(r.URL.Query().Get("segmentum")))
 	if err != nil {
-		log.Printf("Failed to export JSON: %v", err)
-		h.writeError(w, "Failed to export JSON", err.Error(), http.StatusInternalServerError)
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "invalid"):
+			h.writeError(w, "Invalid request", err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Failed to select nodes for verification: %v", err)
+			h.writeError(w, "Failed to select nodes", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if len(ids) > 0 {
+		go func() {
+			ctx, cancel := h.operationContext(context.Background())
+			defer cancel()
+			if err := h.verifier.VerifyNodes(ctx, ids); err != nil {
+				log.Printf("Targeted verification failed: %v", err)
+			}
+		}()
+	}
+
+	h.writeJSON(w, map[string]interface{}{
+		"status":   "verification_queued",
+		"queued":   len(ids),
+		"node_ids": ids,
+	}, http.StatusAccepted)
+}
+
+// parseExportFilter reads ?type=, ?tag= (repeatable) and ?segmentum=
+// (a CIDR matched against node IPs), writing a 400 if they are invalid
+func (h *GraphHandler) parseExportFilter(w http.ResponseWriter, r *http.Request) (domain.ExportFilter, bool) {
+	q := r.URL.Query()
+	filter := domain.ExportFilter{
+		Type: domain.NodeType(q.Get("type")),
+		Tags: q["tag"],
+		CIDR: strings.TrimSpace(q.Get("segmentum")),
+	}
+	if err := filter.Normalize(); err != nil {
+		h.writeError(w, "Invalid export filter", err.Error(), http.StatusBadRequest)
+		return filter, false
+	}
+	return filter, true
+}
+
+// loadExport loads the fragment to export, writing a 500 if that fails.
+// Export handlers load before setting download headers so failures still
+// get a proper error response.
+func (h *GraphHandler) loadExport(w http.ResponseWriter, r *http.Request, filter domain.ExportFilter) (*domain.GraphFragment, bool) {
+	fragment, err := h.svc.ExportFragment(r.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to load graph for export: %v", err)
+		h.writeError(w, "Failed to export graph", err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return fragment, true
+}
+
+// ExportJSON streams the graph as JSON, compact unless ?pretty=true
+func (h *GraphHandler) ExportJSON(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseExportFilter(w, r)
+	if !ok {
+		return
+	}
+	fragment, ok := h.loadExport(w, r, filter)
+	if !ok {
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Disposition", "attachment; filename=graph.json")
-	w.Write(data)
+
+	if err := h.svc.ExportJSON(w, fragment, r.URL.Query().Get("pretty") == "true"); err != nil {
+		log.Printf("Failed to export JSON: %v", err)
+		// Can't write error response once streaming has started
+		return
+	}
 }
 
 // ExportYAML exports the graph as YAML
 func (h *GraphHandler) ExportYAML(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseExportFilter(w, r)
+	if !ok {
+		return
+	}
+	fragment, ok := h.loadExport(w, r, filter)
+	if !ok {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/x-yaml")
 	w.Header().Set("Content-Disposition", "attachment; filename=graph.yml")
 
-	if err := h.svc.ExportYAML(r.Context(), w); err != nil {
+	if err := h.svc.ExportYAML(w, fragment); err != nil {
 		log.Printf("Failed to export YAML: %v", err)
 		// Can't write error response as we already set headers
 		return
 	}
 }
 
+// ExportCSV exports nodes as a CSV inventory
+func (h *GraphHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseExportFilter(w, r)
+	if !ok {
+		return
+	}
+	fragment, ok := h.loadExport(w, r, filter)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=inventory.csv")
+
+	if err := h.svc.ExportCSV(w, fragment); err != nil {
+		log.Printf("Failed to export CSV: %v", err)
+		// Can't write error response as we already set headers
+		return
+	}
+}
+
 // ExportAnsibleInventory exports the graph as Ansible inventory
 func (h *GraphHandler) ExportAnsibleInventory(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseExportFilter(w, r)
+	if !ok {
+		return
+	}
+	fragment, ok := h.loadExport(w, r, filter)
+	if !ok {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/x-yaml")
 	w.Header().Set("Content-Disposition", "attachment; filename=inventory.yml")
 
-	if err := h.svc.ExportAnsibleInventory(r.Context(), w); err != nil {
+	if err := h.svc.ExportAnsibleInventory(w, fragment); err != nil {
 		log.Printf("Failed to export Ansible inventory: %v", err)
 		// Can't write error response as we already set headers
 		return
 	}
 }
 
+// ExportGraphML exports the graph as GraphML for yEd, Gephi and similar tools
+func (h *GraphHandler) ExportGraphML(w http.ResponseWriter, r *http.Request) {
+	fragment, ok := h.loadExport(w, r, domain.ExportFilter{})
+	if !ok {
+		return
+	}
+	positions, err := h.svc.GetAllPositions(r.Context())
+	if err != nil {
+		log.Printf("Failed to load positions for export: %v", err)
+		h.writeError(w, "Failed to export graph", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/graphml+xml")
+	w.Header().Set("Content-Disposition", "attachment; filename=graph.graphml")
+
+	if err := h.svc.ExportGraphML(w, fragment, positions); err != nil {
+		log.Printf("Failed to export GraphML: %v", err)
+		// Can't write error response as we already set headers
+		return
+	}
+}
+
+// ExportDOT exports the graph as Graphviz DOT for rendering with dot
+func (h *GraphHandler) ExportDOT(w http.ResponseWriter, r *http.Request) {
+	fragment, ok := h.loadExport(w, r, domain.ExportFilter{})
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vnd.graphviz")
+	w.Header().Set("Content-Disposition", "attachment; filename=graph.dot")
+
+	if err := h.svc.ExportDOT(w, fragment); err != nil {
+		log.Printf("Failed to export DOT: %v", err)
+		// Can't write error response as we already set headers
+		return
+	}
+}
+
+// ExportMermaid exports the graph as a Mermaid flowchart for Markdown docs.
+// ?max_nodes= caps the node count; larger graphs are truncated with a note.
+func (h *GraphHandler) ExportMermaid(w http.ResponseWriter, r *http.Request) {
+	maxNodes := 0
+	if raw := r.URL.Query().Get("max_nodes"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			h.writeError(w, "Invalid max_nodes", "max_nodes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		maxNodes = n
+	}
+	fragment, ok := h.loadExport(w, r, domain.ExportFilter{})
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=graph.mmd")
+
+	if err := h.svc.ExportMermaid(w, fragment, maxNodes); err != nil {
+		log.Printf("Failed to export Mermaid: %v", err)
+		// Can't write error response as we already set headers
+		return
+	}
+}
+
 // Helper methods
 
 func (h *GraphHandler) writeJSON(w http.ResponseWriter, data interface{}, statusCode int) {
@@ -744,11 +1968,20 @@ type MergeRequest struct {
 
 // MergeResponse is returned after a successful merge
 type MergeResponse struct {
+	MergeID        string   `json:"merge_id"` // pass to POST /api/nodes/merge/{merge_id}/undo
 	ParentID       string   `json:"parent_id"`
 	InterfaceCount int      `json:"interface_count"`
 	InterfaceIDs   []string `json:"interface_ids"`
 }
 
+// UnmergeResponse is returned after a merge is undone
+type UnmergeResponse struct {
+	MergeID       string   `json:"merge_id"`
+	ParentID      string   `json:"parent_id"`
+	ParentRemoved bool     `json:"parent_removed"`
+	RestoredIDs   []string `json:"restored_ids"`
+}
+
 // MergeNodes merges multiple nodes into a parent with interface children
 func (h *GraphHandler) MergeNodes(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -777,7 +2010,7 @@ func (h *GraphHandler) MergeNodes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service to perform merge
-	interfaceIDs, err := h.svc.MergeNodesAsInterfaces(r.Context(), req.NodeIDs, req.ParentID, domain.NodeType(req.ParentType))
+	op, err := h.svc.MergeNodesAsInterfaces(r.Context(), req.NodeIDs, req.ParentID, domain.NodeType(req.ParentType))
 	if err != nil {
 		log.Printf("Failed to merge nodes: %v", err)
 		h.writeError(w, "Failed to merge nodes", err.Error(), http.StatusInternalServerError)
@@ -785,8 +2018,80 @@ func (h *GraphHandler) MergeNodes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.writeJSON(w, MergeResponse{
-		ParentID:       req.ParentID,
-		InterfaceCount: len(interfaceIDs),
-		InterfaceIDs:   interfaceIDs,
+		MergeID:        op.ID,
+		ParentID:       op.ParentID,
+		InterfaceCount: len(op.InterfaceIDs),
+		InterfaceIDs:   op.InterfaceIDs,
+	}, http.StatusOK)
+}
+
+// UnmergeNodes undoes a merge, restoring the original standalone nodes
+func (h *GraphHandler) UnmergeNodes(w http.ResponseWriter, r *http.Request) {
+	mergeID := r.PathValue("merge_id")
+	if mergeID == "" {
+		h.writeError(w, "Invalid merge ID", "Merge ID is required", http.StatusBadRequest)
+		return
+	}
+
+	op, err := h.svc.UnmergeNodes(r.Context(), mergeID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "already"):
+			h.writeError(w, "Cannot undo merge", err.Error(), http.StatusConflict)
+		default:
+			log.Printf("Failed to unmerge nodes: %v", err)
+			h.writeError(w, "Failed to unmerge nodes", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	restored := make([]string, len(op.Nodes))
+	for i, node := range op.Nodes {
+		restored[i] = node.ID
+	}
+	h.writeJSON(w, UnmergeResponse{
+		MergeID:       op.ID,
+		ParentID:      op.ParentID,
+		ParentRemoved: op.ParentCreated,
+		RestoredIDs:   restored,
 	}, http.StatusOK)
 }
+
+// mergeByIdentityRequest names the node to keep and the node folded into it
+type mergeByIdentityRequest struct {
+	SurvivorID string `json:"survivor_id"`
+	MergedID   string `json:"merged_id"`
+}
+
+// MergeNodesByIdentity folds one node into another that is the same
+// device, keeping the survivor's ID and truth
+func (h *GraphHandler) MergeNodesByIdentity(w http.ResponseWriter, r *http.Request) {
+	var req mergeByIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ?force=true merges away a protected node, as it deletes one
+	force := r.URL.Query().Get("force") == "true"
+
+	node, err := h.svc.MergeNodesByIdentity(r.Context(), req.SurvivorID, req.MergedID, force)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "is protected"):
+			h.writeError(w, "Node is protected", err.Error(), http.StatusConflict)
+		case strings.Contains(err.Error(), "not found"):
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "invalid request"):
+			h.writeError(w, "Invalid request", err.Error(), http.StatusBadRequest)
+		default:
+			log.Printf("Failed to merge nodes by identity: %v", err)
+			h.writeError(w, "Failed to merge nodes", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, node, http.StatusOK)
+}
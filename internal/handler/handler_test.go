@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"specularium/internal/adapter"
+	"specularium/internal/domain"
+	"specularium/internal/repository/sqlite"
+	"specularium/internal/service"
+)
+
+func newTestGraphService(t *testing.T) *service.GraphService {
+	t.Helper()
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	return service.NewGraphService(repo, service.NewEventBus())
+}
+
+func TestGraphHandlerDeleteNodeReportsEdges(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestGraphService(t)
+	for _, id := range []string{"sw", "a", "b"} {
+		if err := svc.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	for _, to := range []string{"a", "b"} {
+		if err := svc.CreateEdge(ctx, domain.NewEdge("sw", to, domain.EdgeTypeEthernet)); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewGraphHandler(svc).DeleteNode(rec, httptest.NewRequest(http.MethodDelete, "/api/nodes/sw?hard=true", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	var result service.NodeDeleteResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if result.ID != "sw" || !result.Hard || result.EdgesDeleted != 2 {
+		t.Errorf("result = %+v, want sw hard with 2 edges", result)
+	}
+}
+
+// hangingScanner blocks until its context is done, like a scan stuck on
+// an unresponsive host, and reports why it stopped
+type hangingScanner struct {
+	stopped chan error
+}
+
+func (s *hangingScanner) ScanSubnet(ctx context.Context, cidr string, opts adapter.ScanOptions) error {
+	<-ctx.Done()
+	s.stopped <- ctx.Err()
+	return ctx.Err()
+}
+
+func TestGraphHandlerScanStopsAtOperationTimeout(t *testing.T) {
+	scanner := &hangingScanner{stopped: make(chan error, 1)}
+	h := NewGraphHandler(newTestGraphService(t))
+	h.SetSubnetScanner(scanner)
+	h.SetOperationContext(context.Background(), 50*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	h.ImportScan(rec, httptest.NewRequest(http.MethodPost, "/api/import/scan", strings.NewReader(`{"cidr": "10.0.0.0/30"}`)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case err := <-scanner.stopped:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("scan stopped with %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("scan goroutine still running after its deadline")
+	}
+
+	// Shutdown cancels operations that have no deadline
+	base, shutdown := context.WithCancel(context.Background())
+	h.SetOperationContext(base, 0)
+	h.ImportScan(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/import/scan", strings.NewReader(`{"cidr": "10.0.0.0/30"}`)))
+	shutdown()
+
+	select {
+	case err := <-scanner.stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("scan stopped with %v, want context.Canceled", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("scan goroutine still running after shutdown")
+	}
+}
+
+func TestGraphHandlerApplyTagAndTaggedGraph(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestGraphService(t)
+	nodes := []*domain.Node{
+		domain.NewNode("web", domain.NodeTypeServer, "web"),
+		domain.NewNode("db", domain.NodeTypeServer, "db"),
+		domain.NewNode("rtr", domain.NodeTypeRouter, "rtr"),
+	}
+	for _, node := range nodes {
+		if err := svc.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", node.ID, err)
+		}
+	}
+	for _, edge := range []*domain.Edge{
+		domain.NewEdge("web", "db", domain.EdgeTypeEthernet),
+		domain.NewEdge("rtr", "web", domain.EdgeTypeEthernet),
+	} {
+		if err := svc.CreateEdge(ctx, edge); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+	h := NewGraphHandler(svc)
+
+	apply := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/tags/Prod/apply", strings.NewReader(body))
+		req.SetPathValue("tag", "Prod")
+		rec := httptest.NewRecorder()
+		h.ApplyTag(rec, req)
+		return rec
+	}
+
+	rec := apply(`{"type": "server"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var result service.TagApplyResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if result.Tag != "prod" || result.Matched != 2 || strings.Join(result.Tagged, ",") != "db,web" {
+		t.Errorf("result = %+v, want prod matching and tagging db,web", result)
+	}
+
+	// Reapplying matches the same nodes but tags nothing new
+	rec = apply(`{"type": "server"}`)
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if result.Matched != 2 || len(result.Tagged) != 0 {
+		t.Errorf("reapply result = %+v, want 2 matched and none tagged", result)
+	}
+
+	if rec := apply(`{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("apply without criteria status = %d, want 400", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.GetGraph(rec, httptest.NewRequest(http.MethodGet, "/api/graph?tag=prod", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("graph status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+	var graph domain.Graph
+	if err := json.NewDecoder(rec.Body).Decode(&graph); err != nil {
+		t.Fatalf("decode graph: %v", err)
+	}
+	if len(graph.Nodes) != 2 {
+		t.Errorf("tagged graph has %d nodes, want 2", len(graph.Nodes))
+	}
+	if len(graph.Edges) != 1 || graph.Edges[0].FromID != "web" || graph.Edges[0].ToID != "db" {
+		t.Errorf("tagged graph edges = %+v, want only web -> db", graph.Edges)
+	}
+}
+
+func TestGraphHandlerExportJSON(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestGraphService(t)
+	for i := 0; i < 25; i++ {
+		id := fmt.Sprintf("host-%02d", i)
+		if err := svc.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	h := NewGraphHandler(svc)
+
+	export := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ExportJSON(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want 200: %s", url, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Disposition"); got != "attachment; filename=graph.json" {
+			t.Errorf("GET %s Content-Disposition = %q", url, got)
+		}
+		return rec
+	}
+
+	compact := export("/api/export/json").Body.String()
+	pretty := export("/api/export/json?pretty=true").Body.String()
+	if strings.Contains(compact, "\n  ") {
+		t.Error("default export is indented, want compact")
+	}
+	if !strings.Contains(pretty, "\n  \"nodes\": [\n    {") {
+		t.Errorf("pretty export is not indented: %.80q", pretty)
+	}
+
+	// The streamed export carries the same nodes as the buffered graph
+	graph, err := svc.GetGraph(ctx)
+	if err != nil {
+		t.Fatalf("GetGraph failed: %v", err)
+	}
+	for name, body := range map[string]string{"compact": compact, "pretty": pretty} {
+		var fragment domain.GraphFragment
+		if err := json.Unmarshal([]byte(body), &fragment); err != nil {
+			t.Fatalf("decode %s export: %v", name, err)
+		}
+		if len(fragment.Nodes) != len(graph.Nodes) {
+			t.Errorf("%s export has %d nodes, buffered graph has %d", name, len(fragment.Nodes), len(graph.Nodes))
+		}
+	}
+}
+
+func TestGraphHandlerExportLoadError(t *testing.T) {
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test repository: %v", err)
+	}
+	h := NewGraphHandler(service.NewGraphService(repo, service.NewEventBus()))
+	repo.Close()
+
+	for name, export := range map[string]http.HandlerFunc{
+		"json":    h.ExportJSON,
+		"yaml":    h.ExportYAML,
+		"csv":     h.ExportCSV,
+		"ansible": h.ExportAnsibleInventory,
+		"graphml": h.ExportGraphML,
+		"dot":     h.ExportDOT,
+		"mermaid": h.ExportMermaid,
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			export(rec, httptest.NewRequest(http.MethodGet, "/api/export/"+name, nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Fatalf("status = %d, want 500", rec.Code)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got != "" {
+				t.Errorf("Content-Disposition = %q on a failed export, want none", got)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error == "" {
+				t.Errorf("body is not an error response: %v %q", err, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds how long a readiness check waits on the database
+const readyTimeout = 2 * time.Second
+
+// Pinger checks that a backing store is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// StartChecker reports whether a component has finished starting
+type StartChecker interface {
+	Started() bool
+}
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	db       Pinger
+	adapters StartChecker
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(db Pinger, adapters StartChecker) *HealthHandler {
+	return &HealthHandler{db: db, adapters: adapters}
+}
+
+// Healthz reports that the process is alive. It never checks dependencies,
+// so a slow database cannot get the pod restarted.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+// Readyz reports whether the server can take traffic: the database must
+// answer a ping and the adapter registry must have started.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	if err := h.db.Ping(ctx); err != nil {
+		h.writeJSON(w, map[string]string{
+			"status": "unavailable",
+			"reason": "database: " + err.Error(),
+		}, http.StatusServiceUnavailable)
+		return
+	}
+	if h.adapters != nil && !h.adapters.Started() {
+		h.writeJSON(w, map[string]string{
+			"status": "unavailable",
+			"reason": "adapter registry not started",
+		}, http.StatusServiceUnavailable)
+		return
+	}
+
+	h.writeJSON(w, map[string]string{"status": "ready"}, http.StatusOK)
+}
+
+// writeJSON writes a JSON response
+func (h *HealthHandler) writeJSON(w http.ResponseWriter, data interface{}, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Printf("Failed to encode JSON response: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"specularium/internal/repository/sqlite"
+)
+
+type fakeStartChecker bool
+
+func (f fakeStartChecker) Started() bool { return bool(f) }
+
+func TestHealthHandler(t *testing.T) {
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	serve := func(h *HealthHandler, path string) *httptest.ResponseRecorder {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /healthz", h.Healthz)
+		mux.HandleFunc("GET /readyz", h.Readyz)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	if rec := serve(NewHealthHandler(repo, fakeStartChecker(true)), "/readyz"); rec.Code != http.StatusOK {
+		t.Errorf("readyz = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+
+	rec := serve(NewHealthHandler(repo, fakeStartChecker(false)), "/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before start = %d, want 503", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "adapter registry") {
+		t.Errorf("readyz reason = %s, want adapter registry", rec.Body.String())
+	}
+
+	repo.Close()
+
+	rec = serve(NewHealthHandler(repo, fakeStartChecker(true)), "/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz with closed db = %d, want 503", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "database") {
+		t.Errorf("readyz reason = %s, want database", rec.Body.String())
+	}
+
+	if rec := serve(NewHealthHandler(repo, fakeStartChecker(false)), "/healthz"); rec.Code != http.StatusOK {
+		t.Errorf("healthz = %d, want 200 regardless of dependencies", rec.Code)
+	}
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader lets clients retry a discovery trigger without
+// starting a second operation
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long a key's response is replayed
+const DefaultIdempotencyTTL = 10 * time.Minute
+
+// IdempotencyCache remembers accepted (202) responses by Idempotency-Key
+// so a repeated request gets the original response instead of launching
+// another background operation. Keys are scoped to method and path and
+// held in memory only.
+type IdempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+// idempotencyEntry is a key's in-flight or recorded response
+type idempotencyEntry struct {
+	done    chan struct{} // closed once the first request completes
+	stored  bool          // the first request was accepted and is replayable
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// NewIdempotencyCache creates a cache replaying responses for ttl. A zero
+// ttl uses DefaultIdempotencyTTL.
+func NewIdempotencyCache(ttl time.Duration) *IdempotencyCache {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Wrap makes next idempotent for requests carrying an Idempotency-Key.
+// Concurrent requests with the same key wait for the first to finish.
+// Only 202 responses are kept; after any other status the key is released
+// so the request can be retried.
+func (c *IdempotencyCache) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		cacheKey := r.Method + " " + r.URL.Path + " " + key
+
+		for {
+			entry, owner := c.reserve(cacheKey)
+			if owner {
+				rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+				next(rec, r)
+				c.finish(cacheKey, entry, rec)
+				return
+			}
+
+			select {
+			case <-entry.done:
+			case <-r.Context().Done():
+				return
+			}
+			if entry.stored {
+				replay(w, entry)
+				return
+			}
+			// The first request was not accepted; run this one instead
+		}
+	}
+}
+
+// reserve returns the live entry for key, creating one owned by the
+// caller if there is none
+func (c *IdempotencyCache) reserve(key string) (*idempotencyEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, e := range c.entries {
+		if e.stored && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if entry, ok := c.entries[key]; ok {
+		return entry, false
+	}
+	entry := &idempotencyEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	return entry, true
+}
+
+// finish records an accepted response or releases the key
+func (c *IdempotencyCache) finish(key string, entry *idempotencyEntry, rec *recordingWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rec.status == http.StatusAccepted {
+		entry.stored = true
+		entry.status = rec.status
+		entry.header = rec.Header().Clone()
+		entry.body = rec.body.Bytes()
+		entry.expires = c.now().Add(c.ttl)
+	} else {
+		delete(c.entries, key)
+	}
+	close(entry.done)
+}
+
+// replay writes a recorded response, marking it as a replay
+func replay(w http.ResponseWriter, entry *idempotencyEntry) {
+	for k, v := range entry.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(entry.status)
+	w.Write(entry.body)
+}
+
+// recordingWriter passes a response through while keeping a copy of it
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
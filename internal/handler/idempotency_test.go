@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"specularium/internal/adapter"
+)
+
+// countingScanner counts ScanSubnet calls
+type countingScanner struct {
+	calls atomic.Int32
+	wg    sync.WaitGroup
+}
+
+func (s *countingScanner) ScanSubnet(ctx context.Context, cidr string, opts adapter.ScanOptions) error {
+	defer s.wg.Done()
+	s.calls.Add(1)
+	return nil
+}
+
+func TestIdempotencyCacheScan(t *testing.T) {
+	scanner := &countingScanner{}
+	h := NewGraphHandler(nil)
+	h.SetSubnetScanner(scanner)
+
+	cache := NewIdempotencyCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	scan := cache.Wrap(h.ImportScan)
+
+	post := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/import/scan", strings.NewReader(`{"cidr":"192.168.1.0/24"}`))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		scan(rec, req)
+		return rec
+	}
+
+	scanner.wg.Add(1)
+	first := post("click-1")
+	second := post("click-1")
+	scanner.wg.Wait()
+
+	if first.Code != http.StatusAccepted || second.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 twice, got %d and %d", first.Code, second.Code)
+	}
+	if got := scanner.calls.Load(); got != 1 {
+		t.Errorf("expected 1 scan for a repeated key, got %d", got)
+	}
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("expected replayed body %q, got %q", first.Body.String(), second.Body.String())
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("expected replay to be marked")
+	}
+
+	// A new key starts a new scan
+	scanner.wg.Add(1)
+	post("click-2")
+	scanner.wg.Wait()
+	if got := scanner.calls.Load(); got != 2 {
+		t.Errorf("expected 2 scans after a new key, got %d", got)
+	}
+
+	// The key expires after the TTL
+	now = now.Add(2 * time.Minute)
+	scanner.wg.Add(1)
+	if rec := post("click-1"); rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("expired key should not replay")
+	}
+	scanner.wg.Wait()
+	if got := scanner.calls.Load(); got != 3 {
+		t.Errorf("expected 3 scans after expiry, got %d", got)
+	}
+
+	// Rejected requests do not hold the key
+	req := httptest.NewRequest(http.MethodPost, "/api/import/scan", strings.NewReader(`{}`))
+	req.Header.Set(IdempotencyKeyHeader, "click-3")
+	rec := httptest.NewRecorder()
+	scan(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a CIDR, got %d", rec.Code)
+	}
+	scanner.wg.Add(1)
+	if rec := post("click-3"); rec.Code != http.StatusAccepted || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expected a fresh 202 after a rejected attempt, got %d", rec.Code)
+	}
+	scanner.wg.Wait()
+	if got := scanner.calls.Load(); got != 4 {
+		t.Errorf("expected 4 scans, got %d", got)
+	}
+}
@@ -1,44 +1,85 @@
 package handler
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"specularium/internal/config"
 )
 
-// Logger wraps an http.Handler and logs requests
-func Logger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// Logger logs each request with its method, path, status and duration.
+// Server errors log at warn level, everything else at debug level except
+// mutating requests, which log at info.
+func Logger(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		// Create a response writer wrapper to capture status code
-		wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			// Create a response writer wrapper to capture status code
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		next.ServeHTTP(wrapped, r)
+			next.ServeHTTP(wrapped, r)
 
-		log.Printf("%s %s %d %s",
-			r.Method,
-			r.URL.Path,
-			wrapped.statusCode,
-			time.Since(start).Round(time.Millisecond),
-		)
-	})
+			level := slog.LevelDebug
+			switch {
+			case wrapped.statusCode >= http.StatusInternalServerError:
+				level = slog.LevelWarn
+			case r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions:
+				level = slog.LevelInfo
+			}
+			logger.Log(r.Context(), level, "request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", wrapped.statusCode,
+				"duration", time.Since(start).Round(time.Millisecond),
+			)
+		})
+	}
 }
 
-// CORS adds CORS headers to responses
-func CORS(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// CORS adds CORS headers to responses. With no allowed origins configured
+// every origin gets a wildcard; otherwise the request Origin is echoed only
+// when it is on the allowlist, and omitted for anyone else. Credentials are
+// only allowed for origins listed by name, never through "*". OPTIONS
+// preflight requests are answered directly.
+func CORS(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	methods := strings.Join(cfg.EffectiveMethods(), ", ")
+	headers := strings.Join(cfg.EffectiveHeaders(), ", ")
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		allowed[strings.ToLower(strings.TrimRight(origin, "/"))] = true
+	}
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(allowed) == 0 {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Add("Vary", "Origin")
+				origin := r.Header.Get("Origin")
+				named := allowed[strings.ToLower(origin)]
+				if origin != "" && (allowed["*"] || named) {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					if cfg.AllowCredentials && named {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+				}
+			}
+			w.Header().Set("Access-Control-Allow-Methods", methods)
+			w.Header().Set("Access-Control-Allow-Headers", headers)
 
-		next.ServeHTTP(w, r)
-	})
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // Recover recovers from panics and returns a 500 error
@@ -46,7 +87,7 @@ func Recover(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("Panic recovered: %v", err)
+				slog.Error("panic recovered", "method", r.Method, "path", r.URL.Path, "panic", err)
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			}
 		}()
@@ -55,6 +96,87 @@ func Recover(next http.Handler) http.Handler {
 	})
 }
 
+// TokenValidator checks bearer tokens presented to the API
+type TokenValidator interface {
+	ValidateAPIToken(ctx context.Context, token string) (bool, error)
+}
+
+// Auth requires "Authorization: Bearer <token>" on API and event stream
+// requests, answering 401 otherwise. Browsers cannot set headers on an
+// EventSource, so the event stream also accepts ?access_token=<token>.
+// Static UI files are always served.
+// With readAnonymous, GET and HEAD requests need no token, except on the
+// secrets API, whose reads can disclose the API tokens themselves.
+func Auth(tokens TokenValidator, readAnonymous bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !requiresAuth(r, readAnonymous) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, "Missing bearer token")
+				return
+			}
+			valid, err := tokens.ValidateAPIToken(r.Context(), token)
+			if err != nil {
+				slog.Error("failed to validate API token", "error", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !valid {
+				unauthorized(w, "Invalid bearer token")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requiresAuth reports whether a request must carry a token
+func requiresAuth(r *http.Request, readAnonymous bool) bool {
+	if r.Method == http.MethodOptions {
+		return false
+	}
+	if readAnonymous && (r.Method == http.MethodGet || r.Method == http.MethodHead) && !isSecretsPath(r.URL.Path) {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/events"
+}
+
+// isSecretsPath reports whether a path is on the secrets API
+func isSecretsPath(path string) bool {
+	return path == "/api/secrets" || strings.HasPrefix(path, "/api/secrets/")
+}
+
+// bearerToken extracts the token from an Authorization header, or from the
+// access_token query parameter on the event stream
+func bearerToken(r *http.Request) (string, bool) {
+	if r.Header.Get("Authorization") == "" && r.URL.Path == "/events" {
+		token := r.URL.Query().Get("access_token")
+		return token, token != ""
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized writes a 401 with a bearer challenge
+func unauthorized(w http.ResponseWriter, details string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="specularium"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	if err := json.NewEncoder(w).Encode(ErrorResponse{Error: "Unauthorized", Details: details}); err != nil {
+		slog.Error("failed to encode error response", "error", err)
+	}
+}
+
 // Chain applies a list of middlewares to a handler
 func Chain(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for i := len(middlewares) - 1; i >= 0; i-- {
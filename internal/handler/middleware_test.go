@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"specularium/internal/config"
+)
+
+// staticTokens accepts a fixed set of tokens
+type staticTokens map[string]bool
+
+func (s staticTokens) ValidateAPIToken(ctx context.Context, token string) (bool, error) {
+	return s[token], nil
+}
+
+func TestAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	tokens := staticTokens{"s3cret": true}
+
+	tests := []struct {
+		name          string
+		readAnonymous bool
+		method        string
+		path          string
+		header        string
+		want          int
+	}{
+		{"valid token", false, http.MethodDelete, "/api/graph", "Bearer s3cret", http.StatusNoContent},
+		{"scheme is case-insensitive", false, http.MethodDelete, "/api/graph", "bearer s3cret", http.StatusNoContent},
+		{"missing token", false, http.MethodDelete, "/api/graph", "", http.StatusUnauthorized},
+		{"wrong token", false, http.MethodDelete, "/api/graph", "Bearer nope", http.StatusUnauthorized},
+		{"non-bearer scheme", false, http.MethodDelete, "/api/graph", "Basic s3cret", http.StatusUnauthorized},
+		{"reads need a token by default", false, http.MethodGet, "/api/graph", "", http.StatusUnauthorized},
+		{"event stream is protected", false, http.MethodGet, "/events", "", http.StatusUnauthorized},
+		{"event stream takes a query token", false, http.MethodGet, "/events?types=node-created&access_token=s3cret", "", http.StatusNoContent},
+		{"event stream rejects a wrong query token", false, http.MethodGet, "/events?access_token=nope", "", http.StatusUnauthorized},
+		{"query token only for the event stream", false, http.MethodGet, "/api/graph?access_token=s3cret", "", http.StatusUnauthorized},
+		{"anonymous read allowed", true, http.MethodGet, "/api/graph", "", http.StatusNoContent},
+		{"anonymous write refused", true, http.MethodDelete, "/api/graph", "", http.StatusUnauthorized},
+		{"anonymous secret read refused", true, http.MethodGet, "/api/secrets/abc?include_data=true", "", http.StatusUnauthorized},
+		{"anonymous secret list refused", true, http.MethodGet, "/api/secrets", "", http.StatusUnauthorized},
+		{"secret read with token", true, http.MethodGet, "/api/secrets/abc", "Bearer s3cret", http.StatusNoContent},
+		{"static files are public", false, http.MethodGet, "/app.js", "", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			Auth(tokens, tt.readAnonymous)(ok).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	restricted := config.CORSConfig{
+		AllowedOrigins:   []string{"https://ops.example.com/"},
+		AllowCredentials: true,
+		AllowedMethods:   []string{"GET", "POST"},
+	}
+
+	tests := []struct {
+		name        string
+		cfg         config.CORSConfig
+		method      string
+		origin      string
+		wantOrigin  string
+		wantCreds   string
+		wantHandled bool
+	}{
+		{"wildcard by default", config.CORSConfig{}, http.MethodGet, "https://any.example.com", "*", "", true},
+		{"allowed origin is echoed", restricted, http.MethodGet, "https://OPS.example.com", "https://OPS.example.com", "true", true},
+		{"disallowed origin is omitted", restricted, http.MethodGet, "https://evil.example.com", "", "", true},
+		{"preflight is answered directly", restricted, http.MethodOptions, "https://ops.example.com", "https://ops.example.com", "true", false},
+		{"wildcard entry never gets credentials", config.CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, http.MethodGet, "https://any.example.com", "https://any.example.com", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handled = true
+				ok.ServeHTTP(w, r)
+			})
+			req := httptest.NewRequest(tt.method, "/api/graph", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+
+			CORS(tt.cfg)(next).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+			if handled != tt.wantHandled {
+				t.Errorf("next handler called = %v, want %v", handled, tt.wantHandled)
+			}
+		})
+	}
+
+	t.Run("preflight lists configured methods", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/api/graph", nil)
+		req.Header.Set("Origin", "https://ops.example.com")
+		rec := httptest.NewRecorder()
+		CORS(restricted)(ok).ServeHTTP(rec, req)
+
+		if got := rec.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+			t.Errorf("Allow-Methods = %q, want %q", got, "GET, POST")
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+			t.Errorf("Allow-Headers = %q, want default headers", got)
+		}
+		if got := rec.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("Vary = %q, want Origin", got)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// nodeViews are the preset projections accepted by ?view= on node endpoints.
+// A nil field list means the full entity.
+var nodeViews = map[string][]string{
+	"minimal": {"id", "label", "type", "status"},
+	"full":    nil,
+}
+
+// edgeViews are the preset projections accepted by ?view= on edge endpoints
+var edgeViews = map[string][]string{
+	"minimal": {"id", "from_id", "to_id", "type"},
+	"full":    nil,
+}
+
+// parseProjection reads ?fields= or ?view= and returns the top-level JSON
+// fields to keep. Explicit fields take precedence over a view. Returns nil
+// when the full entity should be returned.
+func parseProjection(r *http.Request, views map[string][]string) ([]string, error) {
+	q := r.URL.Query()
+
+	if raw := q.Get("fields"); raw != "" {
+		var fields []string
+		for _, f := range strings.Split(raw, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("fields must name at least one field")
+		}
+		return fields, nil
+	}
+
+	if view := q.Get("view"); view != "" {
+		fields, ok := views[view]
+		if !ok {
+			return nil, fmt.Errorf("unknown view: %s", view)
+		}
+		return fields, nil
+	}
+
+	return nil, nil
+}
+
+// project serializes data and keeps only the given top-level fields of each
+// object. The id field is always kept so projected entities stay addressable.
+func project(data interface{}, fields []string) (interface{}, error) {
+	keep := map[string]bool{"id": true}
+	for _, f := range fields {
+		keep[f] = true
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	trim := func(obj map[string]json.RawMessage) map[string]json.RawMessage {
+		for k := range obj {
+			if !keep[k] {
+				delete(obj, k)
+			}
+		}
+		return obj
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		var list []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &list); err != nil {
+			return nil, err
+		}
+		for i := range list {
+			list[i] = trim(list[i])
+		}
+		return list, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, err
+	}
+	return trim(obj), nil
+}
+
+// writeProjected writes data trimmed to the fields requested by the client
+func (h *GraphHandler) writeProjected(w http.ResponseWriter, r *http.Request, data interface{}, views map[string][]string) {
+	fields, err := parseProjection(r, views)
+	if err != nil {
+		h.writeError(w, "Invalid projection", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if fields != nil {
+		if data, err = project(data, fields); err != nil {
+			h.writeError(w, "Failed to project response", err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.writeJSON(w, data, http.StatusOK)
+}
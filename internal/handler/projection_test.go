@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"specularium/internal/domain"
+)
+
+func TestGraphHandlerProjection(t *testing.T) {
+	ctx := context.Background()
+	svc := newTestGraphService(t)
+	for _, id := range []string{"nas", "sw"} {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		node.SetProperty("ip", "10.0.0.1")
+		if err := svc.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	if err := svc.CreateEdge(ctx, domain.NewEdge("sw", "nas", domain.EdgeTypeEthernet)); err != nil {
+		t.Fatalf("CreateEdge failed: %v", err)
+	}
+	h := NewGraphHandler(svc)
+
+	// get serves url and returns the status with the decoded body's keys,
+	// one key set per entity
+	get := func(handler http.HandlerFunc, url string) (int, [][]string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusOK {
+			return rec.Code, nil
+		}
+
+		var list []map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+			var obj map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &obj); err != nil {
+				t.Fatalf("GET %s: decode body: %v", url, err)
+			}
+			list = []map[string]any{obj}
+		}
+		keys := make([][]string, len(list))
+		for i, entity := range list {
+			keys[i] = slices.Sorted(maps.Keys(entity))
+		}
+		return rec.Code, keys
+	}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		url      string
+		wantCode int
+		wantKeys []string // every returned entity has exactly these keys
+		wantLen  int
+	}{
+		{"field subset", h.GetNode, "/api/nodes/nas?fields=label,properties", http.StatusOK, []string{"id", "label", "properties"}, 1},
+		{"minimal view", h.GetNode, "/api/nodes/nas?view=minimal", http.StatusOK, []string{"id", "label", "status", "type"}, 1},
+		{"fields win over view", h.GetNode, "/api/nodes/nas?view=minimal&fields=type", http.StatusOK, []string{"id", "type"}, 1},
+		{"unknown field keeps only id", h.GetNode, "/api/nodes/nas?fields=bogus", http.StatusOK, []string{"id"}, 1},
+		{"unknown view", h.GetNode, "/api/nodes/nas?view=bogus", http.StatusBadRequest, nil, 0},
+		{"empty field list", h.GetNode, "/api/nodes/nas?fields=,", http.StatusBadRequest, nil, 0},
+		{"node list", h.ListNodes, "/api/nodes?fields=label", http.StatusOK, []string{"id", "label"}, 2},
+		{"edge list minimal view", h.ListEdges, "/api/edges?view=minimal", http.StatusOK, []string{"from_id", "id", "to_id", "type"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, keys := get(tt.handler, tt.url)
+			if code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", code, tt.wantCode)
+			}
+			if len(keys) != tt.wantLen {
+				t.Fatalf("got %d entities, want %d", len(keys), tt.wantLen)
+			}
+			for _, got := range keys {
+				if !slices.Equal(got, tt.wantKeys) {
+					t.Errorf("keys = %v, want %v", got, tt.wantKeys)
+				}
+			}
+		})
+	}
+
+	// Without a projection the full entity is returned
+	if _, keys := get(h.GetNode, "/api/nodes/nas"); len(keys) != 1 || len(keys[0]) <= 4 {
+		t.Errorf("unprojected node keys = %v, want the full entity", keys)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"specularium/internal/domain"
 )
@@ -16,14 +17,21 @@ type SecretsService interface {
 	ListSecrets(ctx context.Context, secretType string, source string) ([]domain.SecretSummary, error)
 	CreateSecret(ctx context.Context, secret *domain.Secret) error
 	UpdateSecret(ctx context.Context, secret *domain.Secret) error
-	DeleteSecret(ctx context.Context, id string) error
+	DeleteSecret(ctx context.Context, id string, force bool) error
 	GetSecretTypes() []domain.SecretTypeInfo
 	LoadMountedSecrets() error
+	TestSecret(ctx context.Context, id, target string) (*domain.SecretTestResult, error)
+	ListExpiringSecrets(ctx context.Context, now time.Time, within time.Duration) ([]domain.SecretSummary, error)
 }
 
+// defaultExpiryWindow is how far ahead /api/secrets/expiring looks when
+// ?within= is not given
+const defaultExpiryWindow = 7 * 24 * time.Hour
+
 // CapabilityChecker checks what discovery capabilities are available
 type CapabilityChecker interface {
 	GetAllCapabilities(ctx context.Context) map[string]bool
+	GetCapabilityRequirements(ctx context.Context) []domain.CapabilityRequirement
 }
 
 // SecretsHandler handles secrets API requests
@@ -54,6 +62,19 @@ func (h *SecretsHandler) GetCapabilities(w http.ResponseWriter, r *http.Request)
 	h.writeJSON(w, caps, http.StatusOK)
 }
 
+// GetCapabilityRequirements returns the secrets each capability needs and
+// whether matching secrets are present
+// GET /api/capabilities/requirements
+func (h *SecretsHandler) GetCapabilityRequirements(w http.ResponseWriter, r *http.Request) {
+	if h.capabilities == nil {
+		h.writeJSON(w, []domain.CapabilityRequirement{}, http.StatusOK)
+		return
+	}
+
+	reqs := h.capabilities.GetCapabilityRequirements(r.Context())
+	h.writeJSON(w, reqs, http.StatusOK)
+}
+
 // ListSecrets returns all secrets (summaries only)
 // GET /api/secrets?type=ssh_key&source=operator
 func (h *SecretsHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
@@ -70,6 +91,30 @@ func (h *SecretsHandler) ListSecrets(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, secrets, http.StatusOK)
 }
 
+// ListExpiringSecrets returns secrets due for rotation within a window,
+// including overdue ones
+// GET /api/secrets/expiring?within=72h
+func (h *SecretsHandler) ListExpiringSecrets(w http.ResponseWriter, r *http.Request) {
+	within := defaultExpiryWindow
+	if raw := r.URL.Query().Get("within"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < 0 {
+			h.writeError(w, "Invalid within", "within must be a non-negative duration such as 72h", http.StatusBadRequest)
+			return
+		}
+		within = d
+	}
+
+	secrets, err := h.svc.ListExpiringSecrets(r.Context(), time.Now(), within)
+	if err != nil {
+		log.Printf("Failed to list expiring secrets: %v", err)
+		h.writeError(w, "Failed to list expiring secrets", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, secrets, http.StatusOK)
+}
+
 // GetSecret returns a single secret summary (no sensitive data)
 // GET /api/secrets/{id}
 func (h *SecretsHandler) GetSecret(w http.ResponseWriter, r *http.Request) {
@@ -110,6 +155,9 @@ type CreateSecretRequest struct {
 	Description string            `json:"description,omitempty"`
 	Data        map[string]string `json:"data"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	// RotationInterval is a Go duration string such as "2160h"
+	RotationInterval string `json:"rotation_interval,omitempty"`
 }
 
 // CreateSecret creates a new operator secret
@@ -122,12 +170,14 @@ func (h *SecretsHandler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 	}
 
 	secret := &domain.Secret{
-		ID:          req.ID,
-		Name:        req.Name,
-		Type:        req.Type,
-		Description: req.Description,
-		Data:        req.Data,
-		Metadata:    req.Metadata,
+		ID:               req.ID,
+		Name:             req.Name,
+		Type:             req.Type,
+		Description:      req.Description,
+		Data:             req.Data,
+		Metadata:         req.Metadata,
+		ExpiresAt:        req.ExpiresAt,
+		RotationInterval: req.RotationInterval,
 	}
 
 	if err := h.svc.CreateSecret(r.Context(), secret); err != nil {
@@ -135,6 +185,10 @@ func (h *SecretsHandler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, "Conflict", err.Error(), http.StatusConflict)
 			return
 		}
+		if strings.Contains(err.Error(), "invalid") {
+			h.writeError(w, "Invalid secret", err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("Failed to create secret: %v", err)
 		h.writeError(w, "Failed to create secret", err.Error(), http.StatusInternalServerError)
 		return
@@ -143,12 +197,57 @@ func (h *SecretsHandler) CreateSecret(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, secret.ToSummary(), http.StatusCreated)
 }
 
+// TestSecretRequest names the target a secret is tested against
+type TestSecretRequest struct {
+	// Target is host[:port] for SSH and SNMP secrets, or a name to resolve for DNS
+	Target string `json:"target"`
+}
+
+// TestSecret validates a secret against a target and updates its status.
+// A failed check still returns 200 with success=false.
+// POST /api/secrets/{id}/test
+func (h *SecretsHandler) TestSecret(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Invalid secret ID", "Secret ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req TestSecretRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.svc.TestSecret(r.Context(), id, req.Target)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			h.writeError(w, "Secret not found", err.Error(), http.StatusNotFound)
+		case strings.Contains(err.Error(), "does not support testing"),
+			strings.Contains(err.Error(), "target is required"):
+			h.writeError(w, "Cannot test secret", err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "not available"):
+			h.writeError(w, "Secret testing unavailable", err.Error(), http.StatusServiceUnavailable)
+		default:
+			log.Printf("Failed to test secret: %v", err)
+			h.writeError(w, "Failed to test secret", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
 // UpdateSecretRequest is the request body for updating a secret
 type UpdateSecretRequest struct {
 	Name        string            `json:"name,omitempty"`
 	Description string            `json:"description,omitempty"`
 	Data        map[string]string `json:"data,omitempty"`
 	Metadata    map[string]string `json:"metadata,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	// RotationInterval replaces the rotation schedule; "0" clears it
+	RotationInterval string `json:"rotation_interval,omitempty"`
 }
 
 // UpdateSecret updates an existing operator secret
@@ -195,8 +294,22 @@ func (h *SecretsHandler) UpdateSecret(w http.ResponseWriter, r *http.Request) {
 	if req.Metadata != nil {
 		existing.Metadata = req.Metadata
 	}
+	if req.ExpiresAt != nil {
+		existing.ExpiresAt = req.ExpiresAt
+	}
+	switch req.RotationInterval {
+	case "":
+	case "0":
+		existing.RotationInterval = ""
+	default:
+		existing.RotationInterval = req.RotationInterval
+	}
 
 	if err := h.svc.UpdateSecret(r.Context(), existing); err != nil {
+		if strings.Contains(err.Error(), "invalid") {
+			h.writeError(w, "Invalid secret", err.Error(), http.StatusBadRequest)
+			return
+		}
 		log.Printf("Failed to update secret: %v", err)
 		h.writeError(w, "Failed to update secret", err.Error(), http.StatusInternalServerError)
 		return
@@ -205,7 +318,8 @@ func (h *SecretsHandler) UpdateSecret(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, existing.ToSummary(), http.StatusOK)
 }
 
-// DeleteSecret deletes an operator secret
+// DeleteSecret deletes an operator secret. ?force=true also deletes a
+// stored mounted secret whose mount no longer exists.
 // DELETE /api/secrets/{id}
 func (h *SecretsHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 	id := extractSecretID(r.URL.Path)
@@ -213,6 +327,7 @@ func (h *SecretsHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "Invalid secret ID", "Secret ID is required", http.StatusBadRequest)
 		return
 	}
+	force := r.URL.Query().Get("force") == "true"
 
 	// Check if secret exists and is deletable
 	existing, err := h.svc.GetSecret(r.Context(), id)
@@ -225,12 +340,17 @@ func (h *SecretsHandler) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, "Secret not found", "No secret with ID: "+id, http.StatusNotFound)
 		return
 	}
-	if existing.Immutable {
+	if existing.Immutable && !force {
 		h.writeError(w, "Immutable secret", "Cannot delete mounted secrets", http.StatusForbidden)
 		return
 	}
 
-	if err := h.svc.DeleteSecret(r.Context(), id); err != nil {
+	if err := h.svc.DeleteSecret(r.Context(), id, force); err != nil {
+		if strings.Contains(err.Error(), "cannot delete mounted secret") ||
+			strings.Contains(err.Error(), "cannot verify mounts") {
+			h.writeError(w, "Immutable secret", err.Error(), http.StatusForbidden)
+			return
+		}
 		log.Printf("Failed to delete secret: %v", err)
 		h.writeError(w, "Failed to delete secret", err.Error(), http.StatusInternalServerError)
 		return
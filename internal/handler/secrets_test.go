@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"specularium/internal/domain"
+	"specularium/internal/repository/sqlite"
+	"specularium/internal/service"
+)
+
+func TestSecretsHandlerTestSecret(t *testing.T) {
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test repository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	svc := service.NewSecretsService(repo, service.NewEventBus())
+	svc.SetMountedPaths(nil)
+	if err := svc.CreateSecret(context.Background(), &domain.Secret{
+		ID:   "generic.note",
+		Name: "Note",
+		Type: domain.SecretTypeGeneric,
+		Data: map[string]string{"value": "hello"},
+	}); err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/secrets/{id}/test", NewSecretsHandler(svc).TestSecret)
+
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"unsupported type", "generic.note", http.StatusBadRequest},
+		{"unknown secret", "missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/secrets/"+tt.id+"/test", strings.NewReader(`{"target": "10.0.0.1"}`))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
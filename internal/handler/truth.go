@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 
 	"specularium/internal/domain"
 	"specularium/internal/service"
@@ -103,6 +104,113 @@ func (h *TruthHandler) ClearNodeTruth(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, map[string]string{"status": "ok", "node_id": nodeID}, http.StatusOK)
 }
 
+// PinHostnameRequest represents the request body for pinning a hostname candidate
+type PinHostnameRequest struct {
+	Hostname string `json:"hostname"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// GetHostnameCandidates returns a node's hostname candidates and the selected one
+func (h *TruthHandler) GetHostnameCandidates(w http.ResponseWriter, r *http.Request) {
+	nodeID := r.PathValue("id")
+	if nodeID == "" {
+		h.writeError(w, "Node ID is required", "", http.StatusBadRequest)
+		return
+	}
+
+	candidates, err := h.svc.GetHostnameCandidates(r.Context(), nodeID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to get hostname candidates for node %s: %v", nodeID, err)
+		h.writeError(w, "Failed to get hostname candidates", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, candidates, http.StatusOK)
+}
+
+// GetReconciliation compares a node's truth against its discovered values
+func (h *TruthHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	nodeID := r.PathValue("id")
+	if nodeID == "" {
+		h.writeError(w, "Node ID is required", "", http.StatusBadRequest)
+		return
+	}
+
+	reconciliation, err := h.svc.GetReconciliation(r.Context(), nodeID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to reconcile truth for node %s: %v", nodeID, err)
+		h.writeError(w, "Failed to reconcile truth", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, reconciliation, http.StatusOK)
+}
+
+// PinHostnameCandidate asserts a hostname candidate as operator truth
+func (h *TruthHandler) PinHostnameCandidate(w http.ResponseWriter, r *http.Request) {
+	nodeID := r.PathValue("id")
+	if nodeID == "" {
+		h.writeError(w, "Node ID is required", "", http.StatusBadRequest)
+		return
+	}
+
+	var req PinHostnameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, "Invalid request body", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Hostname == "" {
+		h.writeError(w, "Hostname is required", "", http.StatusBadRequest)
+		return
+	}
+
+	operator := req.Operator
+	if operator == "" {
+		operator = "operator" // Default operator name
+	}
+
+	if err := h.svc.PinHostnameCandidate(r.Context(), nodeID, req.Hostname, operator); err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not a candidate"):
+			h.writeError(w, "Invalid hostname", err.Error(), http.StatusBadRequest)
+		case strings.Contains(err.Error(), "not found"):
+			h.writeError(w, "Not found", err.Error(), http.StatusNotFound)
+		default:
+			log.Printf("Failed to pin hostname for node %s: %v", nodeID, err)
+			h.writeError(w, "Failed to pin hostname", err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.writeJSON(w, map[string]string{"status": "ok", "node_id": nodeID, "hostname": strings.ToLower(strings.TrimSpace(req.Hostname))}, http.StatusOK)
+}
+
+// ImportTruthCSV asserts operator truth for many nodes from a CSV body
+// POST /api/import/truth-csv?operator=name
+func (h *TruthHandler) ImportTruthCSV(w http.ResponseWriter, r *http.Request) {
+	operator := r.URL.Query().Get("operator")
+	if operator == "" {
+		operator = "operator" // Default operator name
+	}
+
+	result, err := h.svc.ImportTruthCSV(r.Context(), r.Body, operator)
+	if err != nil {
+		log.Printf("Failed to import truth CSV: %v", err)
+		h.writeError(w, "Failed to import truth CSV", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, result, http.StatusOK)
+}
+
 // ListDiscrepancies returns all unresolved discrepancies
 func (h *TruthHandler) ListDiscrepancies(w http.ResponseWriter, r *http.Request) {
 	discrepancies, err := h.svc.GetUnresolvedDiscrepancies(r.Context())
@@ -115,6 +223,18 @@ func (h *TruthHandler) ListDiscrepancies(w http.ResponseWriter, r *http.Request)
 	h.writeJSON(w, discrepancies, http.StatusOK)
 }
 
+// ExportDiscrepanciesCSV streams unresolved discrepancies as a CSV download
+func (h *TruthHandler) ExportDiscrepanciesCSV(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=discrepancies.csv")
+
+	if err := h.svc.ExportDiscrepanciesCSV(r.Context(), w); err != nil {
+		log.Printf("Failed to export discrepancies: %v", err)
+		// Can't write error response as we already set headers
+		return
+	}
+}
+
 // GetDiscrepancy returns a single discrepancy by ID
 func (h *TruthHandler) GetDiscrepancy(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
@@ -171,6 +291,27 @@ func (h *TruthHandler) ResolveDiscrepancy(w http.ResponseWriter, r *http.Request
 	h.writeJSON(w, map[string]string{"status": "ok", "discrepancy_id": id, "resolution": req.Resolution}, http.StatusOK)
 }
 
+// DeleteDiscrepancy removes a false-positive discrepancy
+func (h *TruthHandler) DeleteDiscrepancy(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		h.writeError(w, "Discrepancy ID is required", "", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteDiscrepancy(r.Context(), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			h.writeError(w, "Discrepancy not found", err.Error(), http.StatusNotFound)
+			return
+		}
+		log.Printf("Failed to delete discrepancy %s: %v", id, err)
+		h.writeError(w, "Failed to delete discrepancy", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // GetNodeDiscrepancies returns all discrepancies for a specific node
 func (h *TruthHandler) GetNodeDiscrepancies(w http.ResponseWriter, r *http.Request) {
 	nodeID := r.PathValue("id")
@@ -3,20 +3,73 @@ package hub
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// Sequenced is implemented by events that carry a sequence number. The
+// number is sent as the SSE id: field so clients can resume with
+// Last-Event-ID.
+type Sequenced interface {
+	SequenceID() uint64
+}
+
+// Typed is implemented by events that carry a type name. Clients that
+// connect with ?types= only receive events whose type is listed.
+type Typed interface {
+	EventTypeName() string
+}
+
+// ReplayFunc returns the events published after seq, oldest first.
+// ok is false when that range is no longer available.
+type ReplayFunc func(seq uint64) (events []interface{}, ok bool)
+
+// resyncMessage tells a resuming client that the missed events are gone
+// and it should refetch the graph
+const resyncMessage = "data: {\"type\":\"resync\"}\n\n"
+
+// message is a formatted SSE message with the sequence number and type
+// it carries
+type message struct {
+	seq  uint64
+	typ  string
+	data []byte
+}
+
 // Client represents a connected SSE client
 type Client struct {
 	id     string
-	events chan []byte
+	types  map[string]bool // subscribed event types; nil means all
+	events chan message
 	done   chan struct{}
 	closed bool // Protected by Hub mutex when checking
 }
 
+// wants reports whether the client subscribed to messages of msg's type.
+// Untyped messages go to every client.
+func (c *Client) wants(msg message) bool {
+	return c.types == nil || msg.typ == "" || c.types[msg.typ]
+}
+
+// parseTypes reads a comma-separated ?types= list; empty means all types
+func parseTypes(raw string) map[string]bool {
+	var types map[string]bool
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			if types == nil {
+				types = make(map[string]bool)
+			}
+			types[t] = true
+		}
+	}
+	return types
+}
+
 // Hub manages SSE client connections
 type Hub struct {
 	mu         sync.RWMutex
@@ -24,6 +77,7 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan interface{}
+	replay     ReplayFunc
 }
 
 // New creates a new Hub
@@ -36,6 +90,31 @@ func New() *Hub {
 	}
 }
 
+// SetReplay enables Last-Event-ID resumption using the given history
+// source. Must be called before serving clients.
+func (h *Hub) SetReplay(replay ReplayFunc) {
+	h.replay = replay
+}
+
+// formatMessage encodes an event as an SSE message, with an id: field
+// when the event is sequenced
+func formatMessage(event interface{}) (message, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return message{}, err
+	}
+
+	msg := message{data: []byte(fmt.Sprintf("data: %s\n\n", data))}
+	if seq, ok := event.(Sequenced); ok && seq.SequenceID() > 0 {
+		msg.seq = seq.SequenceID()
+		msg.data = []byte(fmt.Sprintf("id: %d\n%s", msg.seq, msg.data))
+	}
+	if typed, ok := event.(Typed); ok {
+		msg.typ = typed.EventTypeName()
+	}
+	return msg, nil
+}
+
 // Run starts the hub's event loop
 func (h *Hub) Run() {
 	for {
@@ -58,22 +137,21 @@ func (h *Hub) Run() {
 			log.Printf("SSE client disconnected: %s (total: %d)", client.id, len(h.clients))
 
 		case event := <-h.broadcast:
-			data, err := json.Marshal(event)
+			msg, err := formatMessage(event)
 			if err != nil {
 				log.Printf("Failed to marshal event: %v", err)
 				continue
 			}
 
-			msg := fmt.Sprintf("data: %s\n\n", data)
-
 			h.mu.RLock()
 			for client := range h.clients {
 				// Skip clients marked as closed (defensive check)
-				if client.closed {
+				// and those not subscribed to this type
+				if client.closed || !client.wants(msg) {
 					continue
 				}
 				select {
-				case client.events <- []byte(msg):
+				case client.events <- msg:
 				case <-client.done:
 					// Client is being unregistered, skip
 				default:
@@ -115,13 +193,13 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
 
-	// Create client
+	// Create client, subscribed to ?types= (comma-separated) or everything
 	client := &Client{
 		id:     fmt.Sprintf("%d", time.Now().UnixNano()),
-		events: make(chan []byte, 64),
+		types:  parseTypes(r.URL.Query().Get("types")),
+		events: make(chan message, 64),
 		done:   make(chan struct{}),
 	}
 
@@ -135,6 +213,14 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Send initial connection message
 	fmt.Fprintf(w, ": connected\n\n")
+
+	// Replay missed events for a resuming client. The client is already
+	// registered, so anything published meanwhile is queued; lastSeq
+	// drops the overlap.
+	lastSeq, err := h.resume(w, client, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		return
+	}
 	flusher.Flush()
 
 	// Keep-alive ticker
@@ -148,7 +234,10 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			if _, err := w.Write(msg); err != nil {
+			if msg.seq > 0 && msg.seq <= lastSeq {
+				continue // already sent during replay
+			}
+			if _, err := w.Write(msg.data); err != nil {
 				return
 			}
 			flusher.Flush()
@@ -165,3 +254,43 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 }
+
+// resume writes the events client missed since lastEventID, or a resync
+// message when they are no longer available. It returns the highest
+// sequence number replayed.
+func (h *Hub) resume(w http.ResponseWriter, client *Client, lastEventID string) (uint64, error) {
+	if lastEventID == "" || h.replay == nil {
+		return 0, nil
+	}
+
+	seq, err := strconv.ParseUint(lastEventID, 10, 64)
+	if err != nil {
+		_, err := io.WriteString(w, resyncMessage)
+		return 0, err
+	}
+
+	events, ok := h.replay(seq)
+	if !ok {
+		_, err := io.WriteString(w, resyncMessage)
+		return 0, err
+	}
+
+	lastSeq := seq
+	for _, event := range events {
+		msg, err := formatMessage(event)
+		if err != nil {
+			log.Printf("Failed to marshal replayed event: %v", err)
+			continue
+		}
+		if msg.seq > lastSeq {
+			lastSeq = msg.seq
+		}
+		if !client.wants(msg) {
+			continue
+		}
+		if _, err := w.Write(msg.data); err != nil {
+			return 0, err
+		}
+	}
+	return lastSeq, nil
+}
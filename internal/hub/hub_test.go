@@ -0,0 +1,217 @@
+package hub
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEvent is a minimal sequenced event
+type testEvent struct {
+	Seq  uint64 `json:"seq"`
+	Type string `json:"type"`
+}
+
+func (e testEvent) SequenceID() uint64    { return e.Seq }
+func (e testEvent) EventTypeName() string { return e.Type }
+
+// testBus records published events and serves them for replay, keeping
+// only the newest size events like the real event bus
+type testBus struct {
+	mu     sync.Mutex
+	hub    *Hub
+	seq    uint64
+	events []testEvent
+	size   int
+}
+
+func (b *testBus) publish(eventType string) {
+	b.mu.Lock()
+	b.seq++
+	e := testEvent{Seq: b.seq, Type: eventType}
+	b.events = append(b.events, e)
+	if len(b.events) > b.size {
+		b.events = b.events[1:]
+	}
+	b.mu.Unlock()
+	b.hub.Broadcast(e)
+}
+
+func (b *testBus) since(seq uint64) ([]interface{}, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if seq == b.seq {
+		return nil, true
+	}
+	if seq > b.seq || len(b.events) == 0 || b.events[0].Seq > seq+1 {
+		return nil, false
+	}
+	var out []interface{}
+	for _, e := range b.events {
+		if e.Seq > seq {
+			out = append(out, e)
+		}
+	}
+	return out, true
+}
+
+// sseMessage is one parsed SSE message
+type sseMessage struct {
+	id   string
+	data string
+}
+
+// connect opens an SSE stream, sending lastEventID if set, and waits for
+// the hub to register the client
+func connect(t *testing.T, ctx context.Context, h *Hub, url, lastEventID string) *bufio.Reader {
+	t.Helper()
+	before := h.ClientCount()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	for deadline := time.Now().Add(2 * time.Second); h.ClientCount() == before; {
+		if time.Now().After(deadline) {
+			t.Fatal("client was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readMessage returns the next message on the stream, skipping comments
+func readMessage(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if msg.data != "" {
+				return msg
+			}
+		case strings.HasPrefix(line, "id: "):
+			msg.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			msg.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func newTestHub(t *testing.T, size int) (*Hub, *testBus, string) {
+	t.Helper()
+	h := New()
+	bus := &testBus{hub: h, size: size}
+	h.SetReplay(bus.since)
+	go h.Run()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return h, bus, srv.URL
+}
+
+func TestHubResumesFromLastEventID(t *testing.T) {
+	h, bus, url := newTestHub(t, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	first, cancelFirst := context.WithCancel(ctx)
+	stream := connect(t, first, h, url, "")
+	for _, typ := range []string{"node-created", "node-updated", "edge-created"} {
+		bus.publish(typ)
+	}
+	var last sseMessage
+	for i := 0; i < 3; i++ {
+		last = readMessage(t, stream)
+	}
+	if last.id != "3" {
+		t.Fatalf("last id = %q, want 3", last.id)
+	}
+
+	// Drop the connection and publish while the client is away
+	cancelFirst()
+	for h.ClientCount() != 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	bus.publish("node-deleted")
+	bus.publish("edge-deleted")
+
+	stream = connect(t, ctx, h, url, last.id)
+	bus.publish("graph-updated")
+
+	for _, want := range []string{"4", "5", "6"} {
+		if msg := readMessage(t, stream); msg.id != want {
+			t.Errorf("got id %q (%s), want %s", msg.id, msg.data, want)
+		}
+	}
+}
+
+func TestHubResyncsWhenHistoryExpired(t *testing.T) {
+	h, bus, url := newTestHub(t, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for i := 0; i < 5; i++ {
+		bus.publish("node-updated")
+	}
+
+	stream := connect(t, ctx, h, url, "1")
+	if msg := readMessage(t, stream); msg.id != "" || !strings.Contains(msg.data, `"resync"`) {
+		t.Errorf("got %+v, want resync message", msg)
+	}
+
+	// Live streaming continues after the resync
+	bus.publish("node-created")
+	if msg := readMessage(t, stream); msg.id != "6" {
+		t.Errorf("got id %q, want 6", msg.id)
+	}
+}
+
+func TestHubFiltersByType(t *testing.T) {
+	h, bus, url := newTestHub(t, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filtered := connect(t, ctx, h, url+"?types=discrepancy-created", "")
+	everything := connect(t, ctx, h, url, "")
+
+	bus.publish("discovery-progress")
+	bus.publish("discovery-progress")
+	bus.publish("discrepancy-created")
+	bus.publish("discovery-progress")
+	bus.publish("discrepancy-created")
+
+	for _, want := range []string{"3", "5"} {
+		msg := readMessage(t, filtered)
+		if msg.id != want || !strings.Contains(msg.data, "discrepancy-created") {
+			t.Errorf("filtered client got id %q (%s), want discrepancy-created %s", msg.id, msg.data, want)
+		}
+	}
+	if msg := readMessage(t, everything); msg.id != "1" {
+		t.Errorf("unfiltered client got id %q, want 1", msg.id)
+	}
+
+	t.Run("replay honors the filter", func(t *testing.T) {
+		stream := connect(t, ctx, h, url+"?types=discrepancy-created", "1")
+		if msg := readMessage(t, stream); msg.id != "3" {
+			t.Errorf("got id %q, want 3", msg.id)
+		}
+		if msg := readMessage(t, stream); msg.id != "5" {
+			t.Errorf("got id %q, want 5", msg.id)
+		}
+	})
+}
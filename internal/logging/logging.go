@@ -0,0 +1,53 @@
+// Package logging builds the leveled slog logger shared by the server.
+//
+// The level and output format come from the logging config section, with
+// the LOG_LEVEL environment variable taking precedence over the level.
+// Installing the logger as the slog default also routes the standard log
+// package through it at info level, so older log.Printf call sites keep
+// working while hot paths log structured fields.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// EnvLevel is the environment variable that overrides the configured level
+const EnvLevel = "LOG_LEVEL"
+
+// Output formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel parses debug, info, warn (or warning) and error, case
+// insensitively. An empty string is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// New creates a logger writing to w at level. Format is "text" (the
+// default when empty) or "json".
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(format) {
+	case "", FormatText:
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q", format)
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"info", slog.LevelInfo, false},
+		{"DEBUG", slog.LevelDebug, false},
+		{"warning", slog.LevelWarn, false},
+		{" error ", slog.LevelError, false},
+		{"verbose", slog.LevelInfo, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, slog.LevelInfo, FormatJSON)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Debug("hidden")
+	logger.Info("verified node", "node_id", "nas", "ip", "192.168.1.20")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line below debug level, got %d: %q", len(lines), buf.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("expected JSON output: %v", err)
+	}
+	if record["msg"] != "verified node" || record["node_id"] != "nas" || record["ip"] != "192.168.1.20" {
+		t.Errorf("unexpected record: %v", record)
+	}
+
+	buf.Reset()
+	logger, err = New(&buf, slog.LevelDebug, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	logger.Debug("probe", "node_id", "nas")
+	if !strings.Contains(buf.String(), "level=DEBUG") || !strings.Contains(buf.String(), "node_id=nas") {
+		t.Errorf("expected text output, got %q", buf.String())
+	}
+
+	if _, err := New(&buf, slog.LevelInfo, "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,257 @@
+// Package metrics is a minimal Prometheus text exposition registry.
+//
+// It supports counters, gauges and summaries (sum and count only) with
+// optional labels, plus gauge functions evaluated at scrape time. It
+// avoids pulling in the full Prometheus client for a handful of series.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metric families and renders them in registration order
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+	names    map[string]bool
+}
+
+// family is a named metric that can render itself
+type family interface {
+	write(ctx context.Context, w io.Writer) error
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(name string, f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.names[name] = true
+	r.families = append(r.families, f)
+}
+
+// GaugeFunc registers a gauge whose value is read at scrape time. If fn
+// returns an error the sample is omitted from that scrape.
+func (r *Registry) GaugeFunc(name, help string, fn func(ctx context.Context) (float64, error)) {
+	r.register(name, &gaugeFunc{name: name, help: help, fn: fn})
+}
+
+// NewCounter registers a counter with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newVec(name, help, "counter", labels)}
+	r.register(name, c)
+	return c
+}
+
+// NewGauge registers a settable gauge with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newVec(name, help, "gauge", labels)}
+	r.register(name, g)
+	return g
+}
+
+// NewSummary registers a summary exposing _sum and _count series
+func (r *Registry) NewSummary(name, help string, labels ...string) *Summary {
+	s := &Summary{newVec(name, help, "summary", labels)}
+	r.register(name, s)
+	return s
+}
+
+// Write renders every family in the text exposition format
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	for _, f := range families {
+		if err := f.write(ctx, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP serves the registry for Prometheus scrapes
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := r.Write(req.Context(), w); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+	}
+}
+
+// Counter is a monotonically increasing value per label set
+type Counter struct{ *vec }
+
+// Inc adds one for the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (which must not be negative) for the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	c.update(labelValues, func(s *sample) { s.value += v })
+}
+
+// Gauge is a value that can go up and down per label set
+type Gauge struct{ *vec }
+
+// Set sets the value for the given label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.update(labelValues, func(s *sample) { s.value = v })
+}
+
+// Summary tracks the sum and count of observations per label set
+type Summary struct{ *vec }
+
+// Observe records one observation for the given label values
+func (s *Summary) Observe(v float64, labelValues ...string) {
+	s.update(labelValues, func(smp *sample) {
+		smp.value += v
+		smp.count++
+	})
+}
+
+// sample is the state of one label set
+type sample struct {
+	labelValues []string
+	value       float64
+	count       uint64
+}
+
+// vec stores samples keyed by label values
+type vec struct {
+	name, help, kind string
+	labels           []string
+
+	mu      sync.Mutex
+	samples map[string]*sample
+}
+
+func newVec(name, help, kind string, labels []string) *vec {
+	return &vec{name: name, help: help, kind: kind, labels: labels, samples: make(map[string]*sample)}
+}
+
+func (v *vec) update(labelValues []string, fn func(*sample)) {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.samples[key]
+	if !ok {
+		s = &sample{labelValues: append([]string(nil), labelValues...)}
+		v.samples[key] = s
+	}
+	fn(s)
+}
+
+func (v *vec) write(ctx context.Context, w io.Writer) error {
+	v.mu.Lock()
+	keys := make([]string, 0, len(v.samples))
+	for k := range v.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	samples := make([]sample, len(keys))
+	for i, k := range keys {
+		samples[i] = *v.samples[k]
+	}
+	v.mu.Unlock()
+
+	if err := writeHeader(w, v.name, v.help, v.kind); err != nil {
+		return err
+	}
+	if len(v.labels) == 0 && len(samples) == 0 && v.kind != "summary" {
+		// Unlabelled metrics start at zero rather than being absent
+		samples = append(samples, sample{})
+	}
+
+	for _, s := range samples {
+		labels := formatLabels(v.labels, s.labelValues)
+		var err error
+		if v.kind == "summary" {
+			_, err = fmt.Fprintf(w, "%s_sum%s %s\n%s_count%s %d\n", v.name, labels, formatValue(s.value), v.name, labels, s.count)
+		} else {
+			_, err = fmt.Fprintf(w, "%s%s %s\n", v.name, labels, formatValue(s.value))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gaugeFunc is a gauge read at scrape time
+type gaugeFunc struct {
+	name, help string
+	fn         func(ctx context.Context) (float64, error)
+}
+
+func (g *gaugeFunc) write(ctx context.Context, w io.Writer) error {
+	value, err := g.fn(ctx)
+	if err != nil {
+		log.Printf("Failed to collect metric %s: %v", g.name, err)
+		return nil
+	}
+	if err := writeHeader(w, g.name, g.help, "gauge"); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", g.name, formatValue(value))
+	return err
+}
+
+func writeHeader(w io.Writer, name, help, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, kind)
+	return err
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	reg := NewRegistry()
+	reg.GaugeFunc("test_nodes_total", "Nodes in the graph.", func(ctx context.Context) (float64, error) {
+		return 42, nil
+	})
+	reg.GaugeFunc("test_broken", "Fails to collect.", func(ctx context.Context) (float64, error) {
+		return 0, errors.New("database locked")
+	})
+	syncs := reg.NewCounter("test_syncs_total", "Sync runs.", "adapter", "result")
+	syncs.Inc("verifier", "success")
+	syncs.Inc("verifier", "success")
+	syncs.Inc("nmap", "error")
+	duration := reg.NewSummary("test_duration_seconds", "Sync time.", "adapter")
+	duration.Observe(1.5, "verifier")
+	duration.Observe(0.5, "verifier")
+	reg.NewCounter("test_idle_total", "Never incremented.")
+	reg.NewGauge("test_label", "Escaping.", "name").Set(1, "a \"quoted\"\nname")
+
+	var b strings.Builder
+	if err := reg.Write(context.Background(), &b); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	want := `# HELP test_nodes_total Nodes in the graph.
+# TYPE test_nodes_total gauge
+test_nodes_total 42
+# HELP test_syncs_total Sync runs.
+# TYPE test_syncs_total counter
+test_syncs_total{adapter="nmap",result="error"} 1
+test_syncs_total{adapter="verifier",result="success"} 2
+# HELP test_duration_seconds Sync time.
+# TYPE test_duration_seconds summary
+test_duration_seconds_sum{adapter="verifier"} 2
+test_duration_seconds_count{adapter="verifier"} 2
+# HELP test_idle_total Never incremented.
+# TYPE test_idle_total counter
+test_idle_total 0
+# HELP test_label Escaping.
+# TYPE test_label gauge
+test_label{name="a \"quoted\"\nname"} 1
+`
+	if got := b.String(); got != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistryRejectsDuplicates(t *testing.T) {
+	reg := NewRegistry()
+	reg.NewCounter("dup_total", "First.")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate registration")
+		}
+	}()
+	reg.NewGauge("dup_total", "Second.")
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"database/sql/driver"
+	"net/netip"
+
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// Custom SQL functions, registered once for every connection the driver opens
+func init() {
+	sqlitedriver.MustRegisterFunction("ip_in_cidr", &sqlitedriver.FunctionImpl{
+		NArgs:         2,
+		Deterministic: true,
+		Scalar:        ipInCIDR,
+	})
+}
+
+// ipInCIDR implements ip_in_cidr(ip, cidr), returning 1 if ip parses and
+// falls within cidr and 0 otherwise (including for NULL or malformed input)
+func ipInCIDR(_ *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+	ip, _ := args[0].(string)
+	cidr, _ := args[1].(string)
+	if ip == "" || cidr == "" {
+		return int64(0), nil
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return int64(0), nil
+	}
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return int64(0), nil
+	}
+	if prefix.Contains(addr.WithZone("").Unmap()) {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
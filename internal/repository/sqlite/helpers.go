@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"specularium/internal/domain"
@@ -42,6 +44,14 @@ func stringToNull(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+// intToNull converts an int to sql.NullInt64, treating 0 as NULL
+func intToNull(i int) sql.NullInt64 {
+	if i == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(i), Valid: true}
+}
+
 // timePtrToNull safely converts *time.Time to sql.NullTime
 func timePtrToNull(t *time.Time) sql.NullTime {
 	if t == nil {
@@ -123,13 +133,24 @@ type nodeRow struct {
 	CapabilitiesJSON sql.NullString
 	CreatedAt        time.Time
 	UpdatedAt        time.Time
+	HasImage         bool
+	DeletedAt        sql.NullTime
+	TagsList         sql.NullString
+	VerifyFailures   sql.NullInt64
+	BackoffUntil     sql.NullTime
+	Role             sql.NullString
+	SourcesJSON      sql.NullString
+	Protected        sql.NullInt64
+	FirstSeen        sql.NullTime
 }
 
 // scanArgs returns pointers to all fields for sql.Scan()
 // MUST match nodeColumns order exactly:
 // id, type, label, parent_id, properties, source, status,
 // last_verified, last_seen, discovered, truth, truth_status,
-// has_discrepancy, capabilities, created_at, updated_at
+// has_discrepancy, capabilities, created_at, updated_at, has_image,
+// deleted_at, tags, verify_failures, verify_backoff_until, role,
+// discovered_sources, protected, first_seen
 func (r *nodeRow) scanArgs() []interface{} {
 	return []interface{}{
 		&r.ID,               // 1
@@ -148,6 +169,15 @@ func (r *nodeRow) scanArgs() []interface{} {
 		&r.CapabilitiesJSON, // 14
 		&r.CreatedAt,        // 15
 		&r.UpdatedAt,        // 16
+		&r.HasImage,         // 17
+		&r.DeletedAt,        // 18
+		&r.TagsList,         // 19
+		&r.VerifyFailures,   // 20
+		&r.BackoffUntil,     // 21
+		&r.Role,             // 22
+		&r.SourcesJSON,      // 23
+		&r.Protected,        // 24
+		&r.FirstSeen,        // 25
 	}
 }
 
@@ -157,13 +187,20 @@ func (r *nodeRow) toDomain() (*domain.Node, error) {
 		ID:             r.ID,
 		Type:           domain.NodeType(r.Type),
 		Label:          r.Label,
+		Role:           domain.NodeRole(nullToString(r.Role)),
 		ParentID:       nullToString(r.ParentID),
 		Source:         nullToString(r.Source),
 		Status:         domain.NodeStatus(nullToString(r.Status)),
 		TruthStatus:    domain.TruthStatus(nullToString(r.TruthStatus)),
 		HasDiscrepancy: nullToBool(r.HasDiscrepancy),
+		HasImage:       r.HasImage,
+		Protected:      nullToBool(r.Protected),
 		LastVerified:   nullToTimePtr(r.LastVerified),
 		LastSeen:       nullToTimePtr(r.LastSeen),
+		FirstSeen:      nullToTimePtr(r.FirstSeen),
+		DeletedAt:      nullToTimePtr(r.DeletedAt),
+		VerifyFailures: int(r.VerifyFailures.Int64),
+		BackoffUntil:   nullToTimePtr(r.BackoffUntil),
 		CreatedAt:      r.CreatedAt,
 		UpdatedAt:      r.UpdatedAt,
 	}
@@ -193,13 +230,29 @@ func (r *nodeRow) toDomain() (*domain.Node, error) {
 		return nil, fmt.Errorf("unmarshal capabilities: %w", err)
 	}
 
+	if err := unmarshalJSONField(r.SourcesJSON, &node.DiscoveredSources); err != nil {
+		return nil, fmt.Errorf("unmarshal discovered sources: %w", err)
+	}
+
+	// Tags are aggregated comma-separated; NormalizeTag never allows commas
+	if r.TagsList.Valid && r.TagsList.String != "" {
+		node.Tags = strings.Split(r.TagsList.String, ",")
+		sort.Strings(node.Tags)
+	}
+
 	return node, nil
 }
 
-// nodeColumns returns the SELECT column list for node queries
+// nodeColumns returns the SELECT column list for node queries.
+// has_image and tags are derived from node_images and node_tags rather
+// than stored on the node.
 const nodeColumns = `id, type, label, parent_id, properties, source, status,
 	last_verified, last_seen, discovered, truth, truth_status,
-	has_discrepancy, capabilities, created_at, updated_at`
+	has_discrepancy, capabilities, created_at, updated_at,
+	EXISTS(SELECT 1 FROM node_images WHERE node_images.node_id = nodes.id) AS has_image,
+	deleted_at,
+	(SELECT group_concat(tag, ',') FROM node_tags WHERE node_tags.node_id = nodes.id) AS tags,
+	verify_failures, verify_backoff_until, role, discovered_sources, protected, first_seen`
 
 // ============================================================================
 // Edge Row Scanner
@@ -312,7 +365,8 @@ const discrepancyColumns = `id, node_id, property_key, truth_value, actual_value
 
 // nodeInsertArgs prepares arguments for node INSERT/UPSERT
 // Returns: id, type, label, parent_id, properties, source, status,
-//          last_verified, last_seen, discovered, capabilities, created_at, updated_at
+//          last_verified, last_seen, discovered, capabilities, created_at, updated_at,
+//          deleted_at, mac_address, role, discovered_sources, first_seen
 func nodeInsertArgs(node *domain.Node) ([]interface{}, error) {
 	propsJSON, err := marshalToNull(node.Properties)
 	if err != nil {
@@ -329,6 +383,13 @@ func nodeInsertArgs(node *domain.Node) ([]interface{}, error) {
 		return nil, fmt.Errorf("marshal capabilities: %w", err)
 	}
 
+	var sourcesJSON sql.NullString
+	if len(node.DiscoveredSources) > 0 {
+		if sourcesJSON, err = marshalToNull(node.DiscoveredSources); err != nil {
+			return nil, fmt.Errorf("marshal discovered sources: %w", err)
+		}
+	}
+
 	return []interface{}{
 		node.ID,
 		string(node.Type),
@@ -343,15 +404,34 @@ func nodeInsertArgs(node *domain.Node) ([]interface{}, error) {
 		capabilitiesJSON,
 		node.CreatedAt,
 		node.UpdatedAt,
+		timePtrToNull(node.DeletedAt),
+		stringToNull(node.MACAddress()),
+		stringToNull(string(node.Role)),
+		sourcesJSON,
+		timePtrToNull(nodeFirstSeen(node)),
 	}, nil
 }
 
+// nodeFirstSeen returns the first observation time to store for node: its
+// FirstSeen, else its LastSeen. Nodes never observed have none.
+func nodeFirstSeen(node *domain.Node) *time.Time {
+	if node.FirstSeen != nil {
+		return node.FirstSeen
+	}
+	return node.LastSeen
+}
+
+// keepEarliestFirstSeen is the upsert assignment that keeps the earlier of
+// the stored and incoming first_seen. SQLite's multi-argument min() is NULL
+// if either side is, so COALESCE then takes whichever is set.
+const keepEarliestFirstSeen = `first_seen = COALESCE(min(nodes.first_seen, excluded.first_seen), nodes.first_seen, excluded.first_seen)`
+
 // ============================================================================
 // Edge Write Helpers
 // ============================================================================
 
 // edgeInsertArgs prepares arguments for edge INSERT/UPSERT
-// Returns: id, from_id, to_id, type, properties
+// Returns: id, from_id, to_id, type, properties, speed_mbps
 func edgeInsertArgs(edge *domain.Edge) ([]interface{}, error) {
 	propsJSON, err := marshalToNull(edge.Properties)
 	if err != nil {
@@ -364,5 +444,84 @@ func edgeInsertArgs(edge *domain.Edge) ([]interface{}, error) {
 		edge.ToID,
 		string(edge.Type),
 		propsJSON,
+		edgeSpeed(edge),
 	}, nil
 }
+
+// edgeSpeed returns the speed_mbps column value extracted from properties
+func edgeSpeed(edge *domain.Edge) sql.NullInt64 {
+	speed, ok := edge.SpeedMbps()
+	if !ok {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(speed), Valid: true}
+}
+
+// ============================================================================
+// Node History Helpers
+// ============================================================================
+
+// historyIgnoredKeys are discovered values that change on nearly every probe
+// and would drown out meaningful history
+var historyIgnoredKeys = map[string]bool{
+	"discovered.ping_latency_ms":    true,
+	"discovered.icmp_latency_ms":    true,
+	"discovered.hostname_inference": true,
+}
+
+// nodeValueChange is a pending node_history row
+type nodeValueChange struct {
+	Property string
+	OldValue sql.NullString
+	NewValue sql.NullString
+}
+
+// diffNodeValues compares two property maps by their JSON encoding and
+// returns one change per added, removed, or modified key, sorted by key.
+// Keys are prefixed, e.g. "discovered.reverse_dns".
+func diffNodeValues(prefix string, oldValues, newValues map[string]any) ([]nodeValueChange, error) {
+	keys := make(map[string]bool, len(oldValues)+len(newValues))
+	for k := range oldValues {
+		keys[k] = true
+	}
+	for k := range newValues {
+		keys[k] = true
+	}
+
+	var changes []nodeValueChange
+	for k := range keys {
+		property := prefix + "." + k
+		if historyIgnoredKeys[property] {
+			continue
+		}
+
+		oldJSON, err := marshalHistoryValue(oldValues, k)
+		if err != nil {
+			return nil, err
+		}
+		newJSON, err := marshalHistoryValue(newValues, k)
+		if err != nil {
+			return nil, err
+		}
+		if oldJSON == newJSON {
+			continue
+		}
+		changes = append(changes, nodeValueChange{Property: property, OldValue: oldJSON, NewValue: newJSON})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Property < changes[j].Property })
+	return changes, nil
+}
+
+// marshalHistoryValue encodes values[key], or NULL if the key is absent
+func marshalHistoryValue(values map[string]any, key string) (sql.NullString, error) {
+	v, ok := values[key]
+	if !ok {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("marshal %s: %w", key, err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"specularium/internal/domain"
@@ -12,21 +15,79 @@ import (
 	_ "modernc.org/sqlite" // Pure-Go SQLite driver (no CGO)
 )
 
+// DefaultVerifyBackoff is the re-verify delay after each consecutive
+// unreachable result; the last step repeats once reached
+var DefaultVerifyBackoff = []time.Duration{5 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour}
+
 // Repository implements repository operations using SQLite
 type Repository struct {
-	db *sql.DB
+	db            *sql.DB
+	path          string
+	verifyBackoff []time.Duration
+	vacuumMu      sync.Mutex
+}
+
+// Options tunes the connection pool and lock waiting
+type Options struct {
+	// MaxOpenConns caps open connections (default 0, unbounded). Setting 1
+	// serializes all access through one connection, which rules out lock
+	// contention between connections, but then any open rows cursor stalls
+	// every other query until it is closed.
+	MaxOpenConns int
+	// MaxIdleConns is how many idle connections are kept (default
+	// MaxOpenConns when capped, otherwise the database/sql default)
+	MaxIdleConns int
+	// BusyTimeout is how long a statement waits for another connection's
+	// lock before failing with "database is locked" (default 5s)
+	BusyTimeout time.Duration
+}
+
+// DefaultOptions returns the options New uses
+func DefaultOptions() Options {
+	return Options{
+		BusyTimeout: 5 * time.Second,
+	}
 }
 
-// New creates a new SQLite repository
+// New creates a new SQLite repository with DefaultOptions
 func New(dbPath string) (*Repository, error) {
+	return NewWithOptions(dbPath, DefaultOptions())
+}
+
+// NewWithOptions creates a new SQLite repository. Every connection is
+// opened with:
+//
+//   - journal_mode(WAL): readers don't block the writer and vice versa
+//   - busy_timeout(ms): wait up to opts.BusyTimeout for a lock
+//   - _txlock=immediate: transactions take the write lock at BEGIN, so a
+//     waiting transaction uses the busy timeout instead of failing when it
+//     later tries to upgrade a read lock
+//
+// Zero options fall back to DefaultOptions.
+func NewWithOptions(dbPath string, opts Options) (*Repository, error) {
+	defaults := DefaultOptions()
+	if opts.MaxOpenConns > 0 && (opts.MaxIdleConns <= 0 || opts.MaxIdleConns > opts.MaxOpenConns) {
+		opts.MaxIdleConns = opts.MaxOpenConns
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = defaults.BusyTimeout
+	}
+
 	// Pure-Go driver uses "sqlite" and _pragma=name(value) syntax
-	dsn := dbPath + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+	dsn := fmt.Sprintf("%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(%d)&_txlock=immediate",
+		dbPath, opts.BusyTimeout.Milliseconds())
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
 
-	repo := &Repository{db: db}
+	repo := &Repository{db: db, path: dbPath, verifyBackoff: DefaultVerifyBackoff}
 	if err := repo.migrate(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
@@ -80,11 +141,37 @@ func (r *Repository) migrate() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS node_images (
+		node_id TEXT PRIMARY KEY REFERENCES nodes(id) ON DELETE CASCADE,
+		content_type TEXT NOT NULL,
+		data BLOB NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS node_tags (
+		node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+		tag TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (node_id, tag)
+	);
+
+	CREATE TABLE IF NOT EXISTS node_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		node_id TEXT NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+		property TEXT NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		source TEXT,
+		changed_at DATETIME NOT NULL
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_nodes_type ON nodes(type);
 	CREATE INDEX IF NOT EXISTS idx_nodes_source ON nodes(source);
 	CREATE INDEX IF NOT EXISTS idx_edges_from ON edges(from_id);
 	CREATE INDEX IF NOT EXISTS idx_edges_to ON edges(to_id);
 	CREATE INDEX IF NOT EXISTS idx_discrepancies_node ON discrepancies(node_id);
+	CREATE INDEX IF NOT EXISTS idx_node_tags_tag ON node_tags(tag);
+	CREATE INDEX IF NOT EXISTS idx_node_history_node ON node_history(node_id, changed_at);
 	`
 
 	if _, err := r.db.Exec(schema); err != nil {
@@ -108,11 +195,55 @@ func (r *Repository) migrate() error {
 	// Capabilities column for Evidence Model
 	r.addColumnIfNotExists("nodes", "capabilities", "TEXT")
 
+	// Soft-delete marker; NULL for live nodes
+	r.addColumnIfNotExists("nodes", "deleted_at", "DATETIME")
+
+	// Verification backoff for persistently unreachable nodes
+	r.addColumnIfNotExists("nodes", "verify_failures", "INTEGER DEFAULT 0")
+	r.addColumnIfNotExists("nodes", "verify_backoff_until", "DATETIME")
+
+	// MAC address extracted from discovered for deduplication lookups
+	if !r.columnExists("nodes", "mac_address") {
+		r.addColumnIfNotExists("nodes", "mac_address", "TEXT")
+		r.backfillMACAddresses()
+	}
+
+	// Role promoted out of properties into a filterable column
+	if !r.columnExists("nodes", "role") {
+		r.addColumnIfNotExists("nodes", "role", "TEXT")
+		r.backfillRoles()
+	}
+
+	// Which source set each discovered property, for source priority
+	r.addColumnIfNotExists("nodes", "discovered_sources", "TEXT")
+
+	// Operator protection against deletion and discovery overwrites
+	r.addColumnIfNotExists("nodes", "protected", "INTEGER DEFAULT 0")
+
+	// First observation time, kept across soft delete and re-create.
+	// Existing observed nodes take their row creation time.
+	if !r.columnExists("nodes", "first_seen") {
+		r.addColumnIfNotExists("nodes", "first_seen", "DATETIME")
+		r.db.Exec(`UPDATE nodes SET first_seen = created_at WHERE last_seen IS NOT NULL`)
+	}
+
+	// Link speed extracted from properties for speed filtering
+	if !r.columnExists("edges", "speed_mbps") {
+		r.addColumnIfNotExists("edges", "speed_mbps", "INTEGER")
+		r.backfillEdgeSpeeds()
+	}
+
 	// Create indexes if not exists
 	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_status ON nodes(status)`)
 	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_parent ON nodes(parent_id)`)
 	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_truth_status ON nodes(truth_status)`)
 	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_discrepancies_unresolved ON discrepancies(node_id) WHERE resolved_at IS NULL`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_deleted ON nodes(deleted_at) WHERE deleted_at IS NOT NULL`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_updated ON nodes(updated_at)`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_first_seen ON nodes(first_seen)`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_mac ON nodes(mac_address) WHERE mac_address IS NOT NULL`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_edges_speed ON edges(speed_mbps) WHERE speed_mbps IS NOT NULL`)
+	r.db.Exec(`CREATE INDEX IF NOT EXISTS idx_nodes_role ON nodes(role) WHERE role IS NOT NULL`)
 
 	// Secrets table for operator-created secrets
 	secretsSchema := `
@@ -134,19 +265,63 @@ func (r *Repository) migrate() error {
 	);
 	CREATE INDEX IF NOT EXISTS idx_secrets_type ON secrets(type);
 	CREATE INDEX IF NOT EXISTS idx_secrets_source ON secrets(source);
+	CREATE INDEX IF NOT EXISTS idx_secrets_name ON secrets(name);
 	`
 	r.db.Exec(secretsSchema)
 
+	// Merge operations kept so merges can be undone
+	r.db.Exec(`
+	CREATE TABLE IF NOT EXISTS merge_operations (
+		id TEXT PRIMARY KEY,
+		parent_id TEXT NOT NULL,
+		data TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		undone_at DATETIME
+	)`)
+
+	// Graph snapshots for manual rollback
+	r.db.Exec(`
+	CREATE TABLE IF NOT EXISTS snapshots (
+		id TEXT PRIMARY KEY,
+		label TEXT,
+		node_count INTEGER NOT NULL,
+		edge_count INTEGER NOT NULL,
+		data TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	)`)
+
+	// Subnets referenced by node segmentum properties, with operator metadata
+	r.db.Exec(`
+	CREATE TABLE IF NOT EXISTS subnets (
+		cidr TEXT PRIMARY KEY,
+		vlan_id INTEGER,
+		description TEXT,
+		gateway TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	)`)
+	r.backfillSubnets()
+
+	// Marks rows whose data column holds sealed (encrypted) data
+	r.addColumnIfNotExists("secrets", "encrypted", "INTEGER DEFAULT 0")
+
+	// Rotation tracking for secrets
+	r.addColumnIfNotExists("secrets", "expires_at", "DATETIME")
+	r.addColumnIfNotExists("secrets", "rotation_interval", "TEXT")
+
 	return nil
 }
 
-// addColumnIfNotExists adds a column to a table if it doesn't already exist
-func (r *Repository) addColumnIfNotExists(table, column, colType string) {
-	// Check if column exists by querying table info
+// columnExists reports whether a table has the named column
+func (r *Repository) columnExists(table, column string) bool {
 	var count int
 	err := r.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&count)
-	if err != nil || count > 0 {
-		// Column exists or error checking - skip
+	return err == nil && count > 0
+}
+
+// addColumnIfNotExists adds a column to a table if it doesn't already exist
+func (r *Repository) addColumnIfNotExists(table, column, colType string) {
+	if r.columnExists(table, column) {
 		return
 	}
 
@@ -155,6 +330,105 @@ func (r *Repository) addColumnIfNotExists(table, column, colType string) {
 	r.db.Exec(query)
 }
 
+// backfillMACAddresses fills mac_address from the discovered JSON of
+// nodes written before the column existed
+func (r *Repository) backfillMACAddresses() {
+	rows, err := r.db.Query(`SELECT id, discovered FROM nodes WHERE discovered LIKE '%mac_address%'`)
+	if err != nil {
+		return
+	}
+	macs := make(map[string]string)
+	for rows.Next() {
+		var id string
+		var discovered sql.NullString
+		if err := rows.Scan(&id, &discovered); err != nil {
+			continue
+		}
+		var fields map[string]any
+		if err := unmarshalJSONField(discovered, &fields); err != nil {
+			continue
+		}
+		raw, _ := fields["mac_address"].(string)
+		if mac := domain.NormalizeMAC(raw); mac != "" {
+			macs[id] = mac
+		}
+	}
+	rows.Close()
+
+	for id, mac := range macs {
+		r.db.Exec(`UPDATE nodes SET mac_address = ? WHERE id = ?`, mac, id)
+	}
+}
+
+// backfillEdgeSpeeds fills speed_mbps from the properties of edges
+// written before the column existed
+func (r *Repository) backfillEdgeSpeeds() {
+	rows, err := r.db.Query(`SELECT id, properties FROM edges WHERE json_extract(properties, '$.speed_mbps') IS NOT NULL`)
+	if err != nil {
+		return
+	}
+	speeds := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var propsJSON sql.NullString
+		if err := rows.Scan(&id, &propsJSON); err != nil {
+			continue
+		}
+		edge := domain.Edge{ID: id}
+		if err := unmarshalJSONField(propsJSON, &edge.Properties); err != nil {
+			continue
+		}
+		if speed, ok := edge.SpeedMbps(); ok {
+			speeds[id] = speed
+		}
+	}
+	rows.Close()
+
+	for id, speed := range speeds {
+		r.db.Exec(`UPDATE edges SET speed_mbps = ? WHERE id = ?`, speed, id)
+	}
+}
+
+// backfillRoles moves recognized properties.role values of nodes written
+// before the role column existed into it. Unrecognized values stay in
+// properties.
+func (r *Repository) backfillRoles() {
+	rows, err := r.db.Query(`SELECT id, properties FROM nodes WHERE json_extract(properties, '$.role') IS NOT NULL`)
+	if err != nil {
+		return
+	}
+	type promoted struct {
+		role  domain.NodeRole
+		props sql.NullString
+	}
+	updates := make(map[string]promoted)
+	for rows.Next() {
+		var id string
+		var propsJSON sql.NullString
+		if err := rows.Scan(&id, &propsJSON); err != nil {
+			continue
+		}
+		node := domain.Node{ID: id}
+		if err := unmarshalJSONField(propsJSON, &node.Properties); err != nil {
+			continue
+		}
+		node.PromoteRole()
+		if node.Role == "" {
+			continue
+		}
+		props, err := marshalToNull(node.Properties)
+		if err != nil {
+			continue
+		}
+		updates[id] = promoted{role: node.Role, props: props}
+	}
+	rows.Close()
+
+	for id, p := range updates {
+		r.db.Exec(`UPDATE nodes SET role = ?, properties = ? WHERE id = ?`, string(p.role), p.props, id)
+	}
+}
+
 // GetGraph returns the complete graph with nodes, edges, and positions
 func (r *Repository) GetGraph(ctx context.Context) (*domain.Graph, error) {
 	graph := domain.NewGraph()
@@ -183,7 +457,8 @@ func (r *Repository) GetGraph(ctx context.Context) (*domain.Graph, error) {
 	return graph, nil
 }
 
-// GetNode retrieves a single node by ID
+// GetNode retrieves a single node by ID.
+// Soft-deleted nodes are still returned, with DeletedAt set.
 func (r *Repository) GetNode(ctx context.Context, id string) (*domain.Node, error) {
 	var row nodeRow
 	row.ID = id
@@ -202,18 +477,177 @@ func (r *Repository) GetNode(ctx context.Context, id string) (*domain.Node, erro
 	return row.toDomain()
 }
 
-// ListNodes returns all nodes, optionally filtered by type or source
+// CountNodesByStatus returns the number of live nodes in each status
+func (r *Repository) CountNodesByStatus(ctx context.Context) (map[domain.NodeStatus]int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT COALESCE(status, ''), COUNT(*) FROM nodes
+		WHERE deleted_at IS NULL
+		GROUP BY status
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count nodes: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[domain.NodeStatus]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("scan node count: %w", err)
+		}
+		counts[domain.NodeStatus(status)] += count
+	}
+	return counts, rows.Err()
+}
+
+// GetGraphStats counts live nodes and edges by type and status, nodes with
+// operator truth and open discrepancies, using aggregate queries only
+func (r *Repository) GetGraphStats(ctx context.Context) (*domain.GraphStats, error) {
+	stats := &domain.GraphStats{
+		NodesByType:   make(map[domain.NodeType]int),
+		EdgesByType:   make(map[domain.EdgeType]int),
+		NodesByStatus: make(map[domain.NodeStatus]int),
+	}
+
+	byType, err := r.countGroups(ctx, `
+		SELECT type, COUNT(*) FROM nodes
+		WHERE deleted_at IS NULL
+		GROUP BY type
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count nodes by type: %w", err)
+	}
+	for t, n := range byType {
+		stats.NodesByType[domain.NodeType(t)] = n
+		stats.Nodes += n
+	}
+
+	edgesByType, err := r.countGroups(ctx, `
+		SELECT type, COUNT(*) FROM edges
+		WHERE from_id NOT IN (SELECT id FROM nodes WHERE deleted_at IS NOT NULL)
+		  AND to_id NOT IN (SELECT id FROM nodes WHERE deleted_at IS NOT NULL)
+		GROUP BY type
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count edges by type: %w", err)
+	}
+	for t, n := range edgesByType {
+		stats.EdgesByType[domain.EdgeType(t)] = n
+		stats.Edges += n
+	}
+
+	byStatus, err := r.CountNodesByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for status, n := range byStatus {
+		// Nodes without a stored status read back as unverified
+		if status == "" {
+			status = domain.NodeStatusUnverified
+		}
+		stats.NodesByStatus[status] += n
+	}
+
+	err = r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM nodes
+		WHERE deleted_at IS NULL AND truth IS NOT NULL AND truth != ''
+	`).Scan(&stats.NodesWithTruth)
+	if err != nil {
+		return nil, fmt.Errorf("count nodes with truth: %w", err)
+	}
+
+	if stats.OpenDiscrepancies, err = r.CountUnresolvedDiscrepancies(ctx); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// countGroups runs a two-column "key, COUNT(*)" query into a map
+func (r *Repository) countGroups(ctx context.Context, query string, args ...interface{}) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var key string
+		var count int
+		if err := rows.Scan(&key, &count); err != nil {
+			return nil, err
+		}
+		counts[key] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetNodeByMAC returns the live node whose discovered MAC address matches
+// mac, preferring top-level nodes over interfaces. Returns nil if none.
+func (r *Repository) GetNodeByMAC(ctx context.Context, mac string) (*domain.Node, error) {
+	mac = domain.NormalizeMAC(mac)
+	if mac == "" {
+		return nil, nil
+	}
+
+	var row nodeRow
+	err := r.db.QueryRowContext(ctx, `
+		SELECT `+nodeColumns+` FROM nodes
+		WHERE mac_address = ? AND deleted_at IS NULL
+		ORDER BY parent_id IS NOT NULL, created_at
+		LIMIT 1
+	`, mac).Scan(row.scanArgs()...)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query node by mac: %w", err)
+	}
+
+	return row.toDomain()
+}
+
+// ListNodes returns all live nodes, optionally filtered by type or source
 func (r *Repository) ListNodes(ctx context.Context, nodeType, source string) ([]domain.Node, error) {
-	query := "SELECT " + nodeColumns + " FROM nodes WHERE 1=1"
+	return r.FilterNodes(ctx, domain.NodeFilter{Type: domain.NodeType(nodeType), Source: source})
+}
+
+// FilterNodes returns the live nodes matching filter
+func (r *Repository) FilterNodes(ctx context.Context, filter domain.NodeFilter) ([]domain.Node, error) {
+	query := "SELECT " + nodeColumns + " FROM nodes WHERE deleted_at IS NULL"
 	args := make([]interface{}, 0)
 
-	if nodeType != "" {
+	if filter.Type != "" {
 		query += " AND type = ?"
-		args = append(args, nodeType)
+		args = append(args, string(filter.Type))
 	}
-	if source != "" {
+	if filter.Source != "" {
 		query += " AND source = ?"
-		args = append(args, source)
+		args = append(args, filter.Source)
+	}
+	if filter.Role != "" {
+		query += " AND role = ?"
+		args = append(args, string(filter.Role))
+	}
+	for _, tag := range filter.Tags {
+		query += " AND EXISTS(SELECT 1 FROM node_tags WHERE node_tags.node_id = nodes.id AND node_tags.tag = ?)"
+		args = append(args, tag)
+	}
+
+	field, desc, err := filter.SortField()
+	if err != nil {
+		return nil, err
+	}
+	if field != "" {
+		// The field is checked against domain.NodeSortFields; unset values sort last
+		direction := "ASC"
+		if desc {
+			direction = "DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %[1]s IS NULL, %[1]s %[2]s, id", field, direction)
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -225,6 +659,41 @@ func (r *Repository) ListNodes(ctx context.Context, nodeType, source string) ([]
 	return scanNodeRows(rows)
 }
 
+// SearchNodes finds nodes whose label, ID, properties or discovered data
+// contain the query, case-insensitively. Label matches rank first (exact,
+// then prefix, then substring), followed by ID, properties and discovered.
+func (r *Repository) SearchNodes(ctx context.Context, query string, limit int) ([]domain.Node, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	contains := "%" + escaped + "%"
+	prefix := escaped + "%"
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+nodeColumns+` FROM nodes
+		WHERE deleted_at IS NULL
+			AND (label LIKE ?1 ESCAPE '\'
+				OR id LIKE ?1 ESCAPE '\'
+				OR properties LIKE ?1 ESCAPE '\'
+				OR discovered LIKE ?1 ESCAPE '\')
+		ORDER BY
+			CASE
+				WHEN lower(label) = lower(?2) THEN 0
+				WHEN label LIKE ?3 ESCAPE '\' THEN 1
+				WHEN label LIKE ?1 ESCAPE '\' THEN 2
+				WHEN id LIKE ?1 ESCAPE '\' THEN 3
+				WHEN properties LIKE ?1 ESCAPE '\' THEN 4
+				ELSE 5
+			END,
+			label
+		LIMIT ?4`,
+		contains, query, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search nodes: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNodeRows(rows)
+}
+
 // scanNodeRows scans multiple node rows into a slice
 func scanNodeRows(rows *sql.Rows) ([]domain.Node, error) {
 	nodes := make([]domain.Node, 0)
@@ -242,20 +711,140 @@ func scanNodeRows(rows *sql.Rows) ([]domain.Node, error) {
 	return nodes, rows.Err()
 }
 
-// CreateNode creates a new node
+// CreateNode creates a new node.
+// Creating a node whose ID was soft-deleted replaces and revives it.
 func (r *Repository) CreateNode(ctx context.Context, node *domain.Node) error {
 	// Check if node already exists
 	existing, err := r.GetNode(ctx, node.ID)
 	if err != nil {
 		return err
 	}
-	if existing != nil {
+	if existing != nil && existing.DeletedAt == nil {
 		return fmt.Errorf("node %s already exists", node.ID)
 	}
 
 	return r.UpsertNode(ctx, node)
 }
 
+// CreateNodes inserts nodes in a single transaction using one prepared
+// statement. It returns one error per node (nil on success). A node fails
+// if its ID is already taken by a live node; soft-deleted IDs are revived
+// as in CreateNode. Successes are committed unless atomic is set, in which
+// case any failure rolls back the whole batch.
+func (r *Repository) CreateNodes(ctx context.Context, nodes []*domain.Node, atomic bool) ([]error, error) {
+	errs := make([]error, len(nodes))
+	if len(nodes) == 0 {
+		return errs, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO nodes (id, type, label, parent_id, properties, source, status, last_verified, last_seen, discovered, capabilities, created_at, updated_at, deleted_at, mac_address, role, discovered_sources, first_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			type = excluded.type,
+			label = excluded.label,
+			parent_id = excluded.parent_id,
+			properties = excluded.properties,
+			source = excluded.source,
+			status = excluded.status,
+			last_verified = excluded.last_verified,
+			last_seen = excluded.last_seen,
+			discovered = excluded.discovered,
+			capabilities = excluded.capabilities,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			deleted_at = NULL,
+			mac_address = excluded.mac_address,
+			role = excluded.role,
+			discovered_sources = excluded.discovered_sources,
+			`+keepEarliestFirstSeen+`
+		WHERE nodes.deleted_at IS NOT NULL
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	failed := false
+	for i, node := range nodes {
+		if node.CreatedAt.IsZero() {
+			node.CreatedAt = now
+		}
+		node.UpdatedAt = now
+		node.DeletedAt = nil
+		if node.Status == "" {
+			node.Status = domain.NodeStatusUnverified
+		}
+		if err := node.PromoteRole(); err != nil {
+			errs[i] = err
+			failed = true
+			continue
+		}
+
+		args, err := nodeInsertArgs(node)
+		if err != nil {
+			errs[i] = fmt.Errorf("prepare node args: %w", err)
+			failed = true
+			continue
+		}
+
+		res, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			errs[i] = fmt.Errorf("insert node %s: %w", node.ID, err)
+			failed = true
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			errs[i] = fmt.Errorf("node %s already exists", node.ID)
+			failed = true
+			continue
+		}
+		if err := ensureNodeSubnet(ctx, tx, node); err != nil {
+			return nil, err
+		}
+	}
+
+	if atomic && failed {
+		return errs, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return errs, nil
+}
+
+// upsertNodeSQL writes every node column from nodeInsertArgs
+const upsertNodeSQL = `
+	INSERT INTO nodes (id, type, label, parent_id, properties, source, status, last_verified, last_seen, discovered, capabilities, created_at, updated_at, deleted_at, mac_address, role, discovered_sources, first_seen)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET
+		type = excluded.type,
+		label = excluded.label,
+		parent_id = excluded.parent_id,
+		properties = excluded.properties,
+		source = excluded.source,
+		status = excluded.status,
+		last_verified = excluded.last_verified,
+		last_seen = excluded.last_seen,
+		discovered = excluded.discovered,
+		capabilities = excluded.capabilities,
+		updated_at = excluded.updated_at,
+		deleted_at = excluded.deleted_at,
+		mac_address = excluded.mac_address,
+		role = excluded.role,
+		discovered_sources = excluded.discovered_sources,
+		` + keepEarliestFirstSeen + `
+`
+
 // UpsertNode inserts or updates a node
 func (r *Repository) UpsertNode(ctx context.Context, node *domain.Node) error {
 	now := time.Now()
@@ -267,34 +856,41 @@ func (r *Repository) UpsertNode(ctx context.Context, node *domain.Node) error {
 	if node.Status == "" {
 		node.Status = domain.NodeStatusUnverified
 	}
+	if err := node.PromoteRole(); err != nil {
+		return err
+	}
 
 	args, err := nodeInsertArgs(node)
 	if err != nil {
 		return fmt.Errorf("prepare node args: %w", err)
 	}
 
-	_, err = r.db.ExecContext(ctx, `
-		INSERT INTO nodes (id, type, label, parent_id, properties, source, status, last_verified, last_seen, discovered, capabilities, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		ON CONFLICT(id) DO UPDATE SET
-			type = excluded.type,
-			label = excluded.label,
-			parent_id = excluded.parent_id,
-			properties = excluded.properties,
-			source = excluded.source,
-			status = excluded.status,
-			last_verified = excluded.last_verified,
-			last_seen = excluded.last_seen,
-			discovered = excluded.discovered,
-			capabilities = excluded.capabilities,
-			updated_at = excluded.updated_at
-	`, args...)
+	oldProps, oldDiscovered, exists, err := nodeHistoryValues(ctx, r.db, node.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, upsertNodeSQL, args...)
 
 	if err != nil {
 		return fmt.Errorf("upsert node: %w", err)
 	}
+	if err := ensureNodeSubnet(ctx, r.db, node); err != nil {
+		return err
+	}
 
-	return nil
+	if !exists {
+		return nil
+	}
+	changes, err := diffNodeValues("properties", oldProps, node.Properties)
+	if err != nil {
+		return fmt.Errorf("diff node properties: %w", err)
+	}
+	discoveredChanges, err := diffNodeValues("discovered", oldDiscovered, node.Discovered)
+	if err != nil {
+		return fmt.Errorf("diff node discovered: %w", err)
+	}
+	return recordNodeHistory(ctx, r.db, node.ID, node.Source, append(changes, discoveredChanges...), now)
 }
 
 // UpdateNode updates an existing node (partial update)
@@ -321,6 +917,9 @@ func (r *Repository) UpdateNode(ctx context.Context, id string, updates map[stri
 	if parentID, ok := updates["parent_id"].(string); ok {
 		existing.ParentID = parentID
 	}
+	if role, ok := updates["role"].(string); ok {
+		existing.Role = domain.NodeRole(role)
+	}
 	if props, ok := updates["properties"].(map[string]interface{}); ok {
 		if existing.Properties == nil {
 			existing.Properties = make(map[string]any)
@@ -365,11 +964,43 @@ func (r *Repository) UpdateNode(ctx context.Context, id string, updates map[stri
 		existing.LastSeen = &lastSeen
 	}
 
-	return r.UpsertNode(ctx, existing)
-}
+	if err := r.UpsertNode(ctx, existing); err != nil {
+		return err
+	}
+	if protected, ok := updates["protected"].(bool); ok && protected != existing.Protected {
+		return r.SetNodeProtected(ctx, id, protected)
+	}
+	return nil
+}
+
+// SetNodeProtected sets or clears a node's protected flag. The flag is
+// not written by UpsertNode, so discovery cannot clear it.
+func (r *Repository) SetNodeProtected(ctx context.Context, id string, protected bool) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE nodes SET protected = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		protected, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to set node protection: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("node %s not found", id)
+	}
+	return nil
+}
+
+// DeleteNode deletes a node. A soft delete stamps deleted_at so the node and
+// its edges drop out of listings but can be restored; a hard delete removes
+// the row along with its associated edges.
+func (r *Repository) DeleteNode(ctx context.Context, id string, hard bool) error {
+	if !hard {
+		return r.softDeleteNode(ctx, id)
+	}
 
-// DeleteNode removes a node and its associated edges
-func (r *Repository) DeleteNode(ctx context.Context, id string) error {
 	result, err := r.db.ExecContext(ctx, `DELETE FROM nodes WHERE id = ?`, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete node: %w", err)
@@ -383,9 +1014,426 @@ func (r *Repository) DeleteNode(ctx context.Context, id string) error {
 		return fmt.Errorf("node %s not found", id)
 	}
 
+	// Foreign keys are not enforced on every connection, so clean up explicitly
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM edges WHERE from_id = ? OR to_id = ?`, id, id); err != nil {
+		return fmt.Errorf("failed to delete node edges: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM node_images WHERE node_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete node image: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM node_tags WHERE node_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete node tags: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM node_history WHERE node_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete node history: %w", err)
+	}
+
+	return nil
+}
+
+// softDeleteNode marks a live node as deleted
+func (r *Repository) softDeleteNode(ctx context.Context, id string) error {
+	now := time.Now()
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE nodes SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		now, now, id)
+	if err != nil {
+		return fmt.Errorf("failed to soft-delete node: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("node %s not found", id)
+	}
+	return nil
+}
+
+// RestoreNode clears the soft-delete marker on a node
+func (r *Repository) RestoreNode(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE nodes SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`,
+		time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to restore node: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("deleted node %s not found", id)
+	}
+	return nil
+}
+
+// SetNodeImage stores or replaces the image attached to a node
+func (r *Repository) SetNodeImage(ctx context.Context, img *domain.NodeImage) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO node_images (node_id, content_type, data, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(node_id) DO UPDATE SET
+			content_type = excluded.content_type,
+			data = excluded.data,
+			updated_at = excluded.updated_at
+	`, img.NodeID, img.ContentType, img.Data, img.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to set node image: %w", err)
+	}
+	return nil
+}
+
+// GetNodeImage retrieves the image attached to a node, or nil if none
+func (r *Repository) GetNodeImage(ctx context.Context, nodeID string) (*domain.NodeImage, error) {
+	img := &domain.NodeImage{NodeID: nodeID}
+	err := r.db.QueryRowContext(ctx,
+		`SELECT content_type, data, updated_at FROM node_images WHERE node_id = ?`, nodeID,
+	).Scan(&img.ContentType, &img.Data, &img.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query node image: %w", err)
+	}
+	return img, nil
+}
+
+// DeleteNodeImage removes the image attached to a node
+func (r *Repository) DeleteNodeImage(ctx context.Context, nodeID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM node_images WHERE node_id = ?`, nodeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete node image: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("image for node %s not found", nodeID)
+	}
+	return nil
+}
+
+// AddTag attaches a tag to a live node. Adding a tag twice is a no-op.
+func (r *Repository) AddTag(ctx context.Context, nodeID, tag string) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM nodes WHERE id = ? AND deleted_at IS NULL)`, nodeID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check node: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("node %s not found", nodeID)
+	}
+
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO node_tags (node_id, tag) VALUES (?, ?)`, nodeID, tag,
+	); err != nil {
+		return fmt.Errorf("failed to add tag: %w", err)
+	}
+	return nil
+}
+
+// RemoveTag detaches a tag from a node
+func (r *Repository) RemoveTag(ctx context.Context, nodeID, tag string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM node_tags WHERE node_id = ? AND tag = ?`, nodeID, tag)
+	if err != nil {
+		return fmt.Errorf("failed to remove tag: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return fmt.Errorf("tag %s on node %s not found", tag, nodeID)
+	}
+	return nil
+}
+
+// MergeNodeIdentity writes survivor, the already merged node, and folds
+// mergedID into it in one transaction: the merged node's tags, edges and
+// children move to the survivor and the merged node is hard-deleted. Edges
+// between the two are dropped, and an edge the survivor already has is kept
+// in place of the moved one. A non-nil survivor.Truth replaces its truth.
+func (r *Repository) MergeNodeIdentity(ctx context.Context, survivor *domain.Node, mergedID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	survivor.UpdatedAt = now
+	if err := survivor.PromoteRole(); err != nil {
+		return err
+	}
+	args, err := nodeInsertArgs(survivor)
+	if err != nil {
+		return fmt.Errorf("prepare node args: %w", err)
+	}
+	oldProps, oldDiscovered, _, err := nodeHistoryValues(ctx, tx, survivor.ID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, upsertNodeSQL, args...); err != nil {
+		return fmt.Errorf("upsert node: %w", err)
+	}
+	if err := ensureNodeSubnet(ctx, tx, survivor); err != nil {
+		return err
+	}
+	changes, err := diffNodeValues("properties", oldProps, survivor.Properties)
+	if err != nil {
+		return fmt.Errorf("diff node properties: %w", err)
+	}
+	discoveredChanges, err := diffNodeValues("discovered", oldDiscovered, survivor.Discovered)
+	if err != nil {
+		return fmt.Errorf("diff node discovered: %w", err)
+	}
+	if err := recordNodeHistory(ctx, tx, survivor.ID, survivor.Source, append(changes, discoveredChanges...), now); err != nil {
+		return err
+	}
+
+	if survivor.Truth != nil {
+		data, err := json.Marshal(survivor.Truth)
+		if err != nil {
+			return fmt.Errorf("failed to marshal truth: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE nodes SET truth = ?, truth_status = ? WHERE id = ?`,
+			string(data), domain.TruthStatusAsserted, survivor.ID,
+		); err != nil {
+			return fmt.Errorf("failed to set node truth: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO node_tags (node_id, tag)
+		SELECT ?, tag FROM node_tags WHERE node_id = ?
+	`, survivor.ID, mergedID); err != nil {
+		return fmt.Errorf("failed to move tags: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+edgeColumns+" FROM edges WHERE from_id = ? OR to_id = ?", mergedID, mergedID)
+	if err != nil {
+		return fmt.Errorf("query edges: %w", err)
+	}
+	edges, err := scanEdgeRows(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM edges WHERE from_id = ? OR to_id = ?`, mergedID, mergedID); err != nil {
+		return fmt.Errorf("failed to remove edges: %w", err)
+	}
+	for _, edge := range edges {
+		if edge.FromID == mergedID {
+			edge.FromID = survivor.ID
+		}
+		if edge.ToID == mergedID {
+			edge.ToID = survivor.ID
+		}
+		if edge.FromID == edge.ToID {
+			continue
+		}
+		edge.ID = edge.GenerateID()
+		args, err := edgeInsertArgs(&edge)
+		if err != nil {
+			return fmt.Errorf("prepare edge args: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO edges (id, from_id, to_id, type, properties, speed_mbps)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, args...); err != nil {
+			return fmt.Errorf("failed to repoint edge: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE nodes SET parent_id = ?, updated_at = ? WHERE parent_id = ?`,
+		survivor.ID, now, mergedID,
+	); err != nil {
+		return fmt.Errorf("failed to reparent nodes: %w", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM nodes WHERE id = ?`, mergedID)
+	if err != nil {
+		return fmt.Errorf("failed to delete node: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("node %s not found", mergedID)
+	}
+	// The survivor takes the merged node's layout position unless it has its
+	// own. Discrepancies are dropped: they were measured against the merged
+	// node's truth, and the survivor's are re-detected on its next check.
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO node_positions (node_id, x, y, pinned)
+		SELECT ?, x, y, pinned FROM node_positions WHERE node_id = ?
+	`, survivor.ID, mergedID); err != nil {
+		return fmt.Errorf("failed to move position: %w", err)
+	}
+	for _, table := range []string{"node_images", "node_tags", "node_history", "node_positions", "discrepancies"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE node_id = ?`, mergedID); err != nil {
+			return fmt.Errorf("failed to delete merged node %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ListTags returns the tags on a node in sorted order
+func (r *Repository) ListTags(ctx context.Context, nodeID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT tag FROM node_tags WHERE node_id = ? ORDER BY tag`, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("query tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]string, 0)
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// ApplyTag attaches tag to every live node matching filter in a single
+// transaction. It returns the IDs of all matching nodes and of those that
+// did not already carry the tag, both sorted.
+func (r *Repository) ApplyTag(ctx context.Context, tag string, filter domain.ExportFilter) (matched, added []string, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	where, args := exportFilterClause(filter)
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, EXISTS(SELECT 1 FROM node_tags WHERE node_tags.node_id = nodes.id AND node_tags.tag = ?)
+		FROM nodes WHERE `+where+` ORDER BY id`,
+		append([]interface{}{tag}, args...)...,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query nodes: %w", err)
+	}
+	matched = make([]string, 0)
+	added = make([]string, 0)
+	for rows.Next() {
+		var id string
+		var tagged bool
+		if err := rows.Scan(&id, &tagged); err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("scan node: %w", err)
+		}
+		matched = append(matched, id)
+		if !tagged {
+			added = append(added, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, id := range added {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR IGNORE INTO node_tags (node_id, tag) VALUES (?, ?)`, id, tag,
+		); err != nil {
+			return nil, nil, fmt.Errorf("failed to add tag to node %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit tags: %w", err)
+	}
+	return matched, added, nil
+}
+
+// nodeHistoryValues loads a node's current properties and discovered
+// values for diffing. exists is false if there is no such node.
+func nodeHistoryValues(ctx context.Context, db queryExecer, nodeID string) (props, discovered map[string]any, exists bool, err error) {
+	var propsJSON, discoveredJSON sql.NullString
+	err = db.QueryRowContext(ctx,
+		`SELECT properties, discovered FROM nodes WHERE id = ?`, nodeID,
+	).Scan(&propsJSON, &discoveredJSON)
+	if err == sql.ErrNoRows {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("query node values: %w", err)
+	}
+
+	if err := unmarshalJSONField(propsJSON, &props); err != nil {
+		return nil, nil, false, fmt.Errorf("unmarshal properties: %w", err)
+	}
+	if err := unmarshalJSONField(discoveredJSON, &discovered); err != nil {
+		return nil, nil, false, fmt.Errorf("unmarshal discovered: %w", err)
+	}
+	return props, discovered, true, nil
+}
+
+// recordNodeHistory appends change rows to node_history
+func recordNodeHistory(ctx context.Context, db execer, nodeID, source string, changes []nodeValueChange, changedAt time.Time) error {
+	for _, c := range changes {
+		if _, err := db.ExecContext(ctx, `
+			INSERT INTO node_history (node_id, property, old_value, new_value, source, changed_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, nodeID, c.Property, c.OldValue, c.NewValue, stringToNull(source), changedAt); err != nil {
+			return fmt.Errorf("record node history: %w", err)
+		}
+	}
 	return nil
 }
 
+// GetNodeHistory returns up to limit history entries for a node, newest first
+func (r *Repository) GetNodeHistory(ctx context.Context, nodeID string, limit int) ([]domain.NodeHistoryEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, node_id, property, old_value, new_value, source, changed_at
+		FROM node_history
+		WHERE node_id = ?
+		ORDER BY changed_at DESC, id DESC
+		LIMIT ?
+	`, nodeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query node history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]domain.NodeHistoryEntry, 0)
+	for rows.Next() {
+		var (
+			e                domain.NodeHistoryEntry
+			oldJSON, newJSON sql.NullString
+			source           sql.NullString
+		)
+		if err := rows.Scan(&e.ID, &e.NodeID, &e.Property, &oldJSON, &newJSON, &source, &e.ChangedAt); err != nil {
+			return nil, fmt.Errorf("scan node history: %w", err)
+		}
+		e.Source = nullToString(source)
+		if err := unmarshalJSONField(oldJSON, &e.OldValue); err != nil {
+			return nil, fmt.Errorf("unmarshal old value: %w", err)
+		}
+		if err := unmarshalJSONField(newJSON, &e.NewValue); err != nil {
+			return nil, fmt.Errorf("unmarshal new value: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
 // GetEdge retrieves a single edge by ID
 func (r *Repository) GetEdge(ctx context.Context, id string) (*domain.Edge, error) {
 	var row edgeRow
@@ -404,22 +1452,35 @@ func (r *Repository) GetEdge(ctx context.Context, id string) (*domain.Edge, erro
 	return row.toDomain()
 }
 
-// ListEdges returns all edges, optionally filtered
+// ListEdges returns all edges, optionally filtered.
+// Edges touching a soft-deleted node are hidden.
 func (r *Repository) ListEdges(ctx context.Context, edgeType, fromID, toID string) ([]domain.Edge, error) {
-	query := "SELECT " + edgeColumns + " FROM edges WHERE 1=1"
+	return r.FilterEdges(ctx, domain.EdgeFilter{Type: domain.EdgeType(edgeType), FromID: fromID, ToID: toID})
+}
+
+// FilterEdges returns the edges matching filter. Edges touching a
+// soft-deleted node are hidden.
+func (r *Repository) FilterEdges(ctx context.Context, filter domain.EdgeFilter) ([]domain.Edge, error) {
+	query := "SELECT " + edgeColumns + ` FROM edges
+		WHERE from_id NOT IN (SELECT id FROM nodes WHERE deleted_at IS NOT NULL)
+		  AND to_id NOT IN (SELECT id FROM nodes WHERE deleted_at IS NOT NULL)`
 	args := make([]interface{}, 0)
 
-	if edgeType != "" {
+	if filter.Type != "" {
 		query += " AND type = ?"
-		args = append(args, edgeType)
+		args = append(args, string(filter.Type))
 	}
-	if fromID != "" {
+	if filter.FromID != "" {
 		query += " AND from_id = ?"
-		args = append(args, fromID)
+		args = append(args, filter.FromID)
 	}
-	if toID != "" {
+	if filter.ToID != "" {
 		query += " AND to_id = ?"
-		args = append(args, toID)
+		args = append(args, filter.ToID)
+	}
+	if filter.MinSpeedMbps > 0 {
+		query += " AND speed_mbps >= ?"
+		args = append(args, filter.MinSpeedMbps)
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -431,6 +1492,80 @@ func (r *Repository) ListEdges(ctx context.Context, edgeType, fromID, toID strin
 	return scanEdgeRows(rows)
 }
 
+// CountNodeEdges returns the number of edges touching a node, including
+// edges hidden because either endpoint is soft-deleted
+func (r *Repository) CountNodeEdges(ctx context.Context, id string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM edges WHERE from_id = ? OR to_id = ?`, id, id,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count node edges: %w", err)
+	}
+	return count, nil
+}
+
+// ListDanglingEdges returns edges whose endpoints no longer exist. Foreign
+// key cascades should prevent these, but are not enforced on every connection.
+func (r *Repository) ListDanglingEdges(ctx context.Context) ([]domain.Edge, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+edgeColumns+` FROM edges
+		WHERE from_id NOT IN (SELECT id FROM nodes)
+		   OR to_id NOT IN (SELECT id FROM nodes)
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("query dangling edges: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEdgeRows(rows)
+}
+
+// GetNeighbors returns the live nodes one hop from a node in either
+// direction, each with the edge connecting them. Self-loops are skipped.
+func (r *Repository) GetNeighbors(ctx context.Context, id string) ([]domain.Neighbor, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+edgeColumns+` FROM edges
+		WHERE (from_id = ?1 OR to_id = ?1) AND from_id != to_id
+		ORDER BY id`, id)
+	if err != nil {
+		return nil, fmt.Errorf("query neighbor edges: %w", err)
+	}
+	edges, err := scanEdgeRows(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	neighbors := make([]domain.Neighbor, 0, len(edges))
+	for _, edge := range edges {
+		otherID := edge.ToID
+		if otherID == id {
+			otherID = edge.FromID
+		}
+		node, err := r.GetNode(ctx, otherID)
+		if err != nil {
+			return nil, err
+		}
+		// Dangling or soft-deleted endpoints are not neighbors
+		if node == nil || node.DeletedAt != nil {
+			continue
+		}
+		neighbors = append(neighbors, domain.Neighbor{Node: *node, Edge: edge})
+	}
+	return neighbors, nil
+}
+
+// GetEdgesBetween returns edges connecting a and b in either direction
+func (r *Repository) GetEdgesBetween(ctx context.Context, a, b string) ([]domain.Edge, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+edgeColumns+` FROM edges
+		WHERE (from_id = ?1 AND to_id = ?2) OR (from_id = ?2 AND to_id = ?1)
+		ORDER BY id`, a, b)
+	if err != nil {
+		return nil, fmt.Errorf("query edges between: %w", err)
+	}
+	defer rows.Close()
+	return scanEdgeRows(rows)
+}
+
 // scanEdgeRows scans multiple edge rows into a slice
 func scanEdgeRows(rows *sql.Rows) ([]domain.Edge, error) {
 	edges := make([]domain.Edge, 0)
@@ -483,13 +1618,14 @@ func (r *Repository) UpsertEdge(ctx context.Context, edge *domain.Edge) error {
 	}
 
 	_, err = r.db.ExecContext(ctx, `
-		INSERT INTO edges (id, from_id, to_id, type, properties)
-		VALUES (?, ?, ?, ?, ?)
+		INSERT INTO edges (id, from_id, to_id, type, properties, speed_mbps)
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			from_id = excluded.from_id,
 			to_id = excluded.to_id,
 			type = excluded.type,
-			properties = excluded.properties
+			properties = excluded.properties,
+			speed_mbps = excluded.speed_mbps
 	`, args...)
 
 	if err != nil {
@@ -671,6 +1807,28 @@ func (r *Repository) SavePositions(ctx context.Context, positions []domain.NodeP
 
 // ImportFragment imports a graph fragment with the specified strategy
 func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphFragment, strategy string) (map[string]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := importFragmentTx(ctx, tx, fragment, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// importFragmentTx imports fragment inside tx. Only identity, type, label,
+// properties, source, role and tags are written; verification state and
+// truth are left to the adapters and operators.
+func importFragmentTx(ctx context.Context, tx *sql.Tx, fragment *domain.GraphFragment, strategy string) (map[string]int, error) {
 	result := map[string]int{
 		"nodes_created": 0,
 		"nodes_updated": 0,
@@ -678,12 +1836,6 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		"edges_updated": 0,
 	}
 
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
 	// If replace strategy, clear all data first
 	if strategy == "replace" {
 		if _, err := tx.ExecContext(ctx, `DELETE FROM node_positions`); err != nil {
@@ -692,6 +1844,9 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		if _, err := tx.ExecContext(ctx, `DELETE FROM edges`); err != nil {
 			return nil, fmt.Errorf("failed to clear edges: %w", err)
 		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM node_tags`); err != nil {
+			return nil, fmt.Errorf("failed to clear tags: %w", err)
+		}
 		if _, err := tx.ExecContext(ctx, `DELETE FROM nodes`); err != nil {
 			return nil, fmt.Errorf("failed to clear nodes: %w", err)
 		}
@@ -704,6 +1859,10 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		err := tx.QueryRowContext(ctx, `SELECT 1 FROM nodes WHERE id = ?`, node.ID).Scan(&exists)
 		isUpdate := err == nil && exists
 
+		if err := node.PromoteRole(); err != nil {
+			return nil, fmt.Errorf("failed to import node %s: %w", node.ID, err)
+		}
+
 		var propertiesJSON sql.NullString
 		if node.Properties != nil && len(node.Properties) > 0 {
 			data, err := json.Marshal(node.Properties)
@@ -720,19 +1879,33 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		node.UpdatedAt = now
 
 		_, err = tx.ExecContext(ctx, `
-			INSERT INTO nodes (id, type, label, properties, source, created_at, updated_at)
-			VALUES (?, ?, ?, ?, ?, ?, ?)
+			INSERT INTO nodes (id, type, label, properties, source, created_at, updated_at, role, first_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				type = excluded.type,
 				label = excluded.label,
 				properties = excluded.properties,
 				source = excluded.source,
-				updated_at = excluded.updated_at
-		`, node.ID, node.Type, node.Label, propertiesJSON, node.Source, node.CreatedAt, node.UpdatedAt)
+				updated_at = excluded.updated_at,
+				role = excluded.role,
+				`+keepEarliestFirstSeen+`
+		`, node.ID, node.Type, node.Label, propertiesJSON, node.Source, node.CreatedAt, node.UpdatedAt, stringToNull(string(node.Role)), timePtrToNull(nodeFirstSeen(&node)))
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to import node %s: %w", node.ID, err)
 		}
+		if err := ensureNodeSubnet(ctx, tx, &node); err != nil {
+			return nil, err
+		}
+
+		// Tags are additive; merging never strips tags set in the UI
+		for _, tag := range node.Tags {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT OR IGNORE INTO node_tags (node_id, tag) VALUES (?, ?)`, node.ID, tag,
+			); err != nil {
+				return nil, fmt.Errorf("failed to import tags for node %s: %w", node.ID, err)
+			}
+		}
 
 		if isUpdate {
 			result["nodes_updated"]++
@@ -763,14 +1936,15 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		}
 
 		_, err = tx.ExecContext(ctx, `
-			INSERT INTO edges (id, from_id, to_id, type, properties)
-			VALUES (?, ?, ?, ?, ?)
+			INSERT INTO edges (id, from_id, to_id, type, properties, speed_mbps)
+			VALUES (?, ?, ?, ?, ?, ?)
 			ON CONFLICT(id) DO UPDATE SET
 				from_id = excluded.from_id,
 				to_id = excluded.to_id,
 				type = excluded.type,
-				properties = excluded.properties
-		`, edge.ID, edge.FromID, edge.ToID, edge.Type, propertiesJSON)
+				properties = excluded.properties,
+				speed_mbps = excluded.speed_mbps
+		`, edge.ID, edge.FromID, edge.ToID, edge.Type, propertiesJSON, edgeSpeed(&edge))
 
 		if err != nil {
 			return nil, fmt.Errorf("failed to import edge %s: %w", edge.ID, err)
@@ -783,47 +1957,210 @@ func (r *Repository) ImportFragment(ctx context.Context, fragment *domain.GraphF
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	return result, nil
 }
 
 // ExportFragment exports all nodes and edges as a fragment
 func (r *Repository) ExportFragment(ctx context.Context) (*domain.GraphFragment, error) {
+	return r.ExportFilteredFragment(ctx, domain.ExportFilter{})
+}
+
+// ExportFilteredFragment exports the nodes matching filter, and the edges
+// whose endpoints both match
+func (r *Repository) ExportFilteredFragment(ctx context.Context, filter domain.ExportFilter) (*domain.GraphFragment, error) {
 	fragment := domain.NewGraphFragment()
+	if filter.IsEmpty() {
+		nodes, err := r.ListNodes(ctx, "", "")
+		if err != nil {
+			return nil, err
+		}
+		fragment.Nodes = nodes
 
-	nodes, err := r.ListNodes(ctx, "", "")
+		edges, err := r.ListEdges(ctx, "", "", "")
+		if err != nil {
+			return nil, err
+		}
+		fragment.Edges = edges
+		return fragment, nil
+	}
+
+	where, args := exportFilterClause(filter)
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+nodeColumns+" FROM nodes WHERE "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query nodes: %w", err)
+	}
+	nodes, err := scanNodeRows(rows)
+	rows.Close()
 	if err != nil {
 		return nil, err
 	}
-	fragment.Nodes = nodes
+	fragment.Nodes = nodes
+
+	matching := "SELECT id FROM nodes WHERE " + where
+	edgeArgs := append(append([]interface{}{}, args...), args...)
+	rows, err = r.db.QueryContext(ctx, "SELECT "+edgeColumns+" FROM edges WHERE from_id IN ("+matching+") AND to_id IN ("+matching+")", edgeArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("query edges: %w", err)
+	}
+	defer rows.Close()
+	edges, err := scanEdgeRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	fragment.Edges = edges
+
+	return fragment, nil
+}
+
+// exportFilterClause builds a WHERE condition over nodes for filter
+func exportFilterClause(filter domain.ExportFilter) (string, []interface{}) {
+	where := "deleted_at IS NULL"
+	args := make([]interface{}, 0)
+
+	if filter.Type != "" {
+		where += " AND type = ?"
+		args = append(args, filter.Type)
+	}
+	if filter.Source != "" {
+		where += " AND source = ?"
+		args = append(args, filter.Source)
+	}
+	for _, tag := range filter.Tags {
+		where += " AND EXISTS(SELECT 1 FROM node_tags WHERE node_tags.node_id = nodes.id AND node_tags.tag = ?)"
+		args = append(args, tag)
+	}
+	if filter.CIDR != "" {
+		where += " AND ip_in_cidr(json_extract(properties, '$.ip'), ?)"
+		args = append(args, filter.CIDR)
+	}
+	return where, args
+}
+
+// Vacuum rebuilds the database file to drop dead pages, then checkpoints
+// and truncates the write-ahead log. It runs on a dedicated connection
+// outside any transaction; VACUUM's exclusive lock waits out transactions
+// on other connections for the busy timeout. Only one vacuum runs at a
+// time.
+func (r *Repository) Vacuum(ctx context.Context) (*domain.VacuumResult, error) {
+	if !r.vacuumMu.TryLock() {
+		return nil, fmt.Errorf("vacuum already running")
+	}
+	defer r.vacuumMu.Unlock()
+
+	result := &domain.VacuumResult{BeforeBytes: r.fileSize()}
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `VACUUM`); err != nil {
+		return nil, fmt.Errorf("failed to vacuum: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	result.AfterBytes = r.fileSize()
+	return result, nil
+}
+
+// fileSize returns the combined size of the database file and its
+// write-ahead log, or zero when they are not on disk
+func (r *Repository) fileSize() int64 {
+	var total int64
+	for _, name := range []string{r.path, r.path + "-wal"} {
+		if info, err := os.Stat(name); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// Close closes the database connection
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// UpdateNodeDiscoveredSources replaces the provenance recorded for a node's
+// discovered properties
+func (r *Repository) UpdateNodeDiscoveredSources(ctx context.Context, nodeID string, sources map[string]domain.DiscoveredSource) error {
+	var sourcesJSON sql.NullString
+	if len(sources) > 0 {
+		data, err := json.Marshal(sources)
+		if err != nil {
+			return fmt.Errorf("failed to marshal discovered sources: %w", err)
+		}
+		sourcesJSON = sql.NullString{String: string(data), Valid: true}
+	}
+
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE nodes SET discovered_sources = ? WHERE id = ?
+	`, sourcesJSON, nodeID); err != nil {
+		return fmt.Errorf("failed to update discovered sources: %w", err)
+	}
+	return nil
+}
+
+// MergeNodeDiscovered merges values into a node's discovered properties,
+// recording source as their provenance. Other discovered keys are kept.
+func (r *Repository) MergeNodeDiscovered(ctx context.Context, nodeID, source string, values map[string]any) error {
+	if len(values) == 0 {
+		return nil
+	}
+	sources := make(map[string]domain.DiscoveredSource, len(values))
+	for key := range values {
+		sources[key] = domain.DiscoveredSource{Source: source}
+	}
+	valuesJSON, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovered values: %w", err)
+	}
+	sourcesJSON, err := json.Marshal(sources)
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovered sources: %w", err)
+	}
 
-	edges, err := r.ListEdges(ctx, "", "", "")
-	if err != nil {
-		return nil, err
+	if _, err := r.db.ExecContext(ctx, `
+		UPDATE nodes SET
+			discovered = json_patch(COALESCE(discovered, '{}'), ?),
+			discovered_sources = json_patch(COALESCE(discovered_sources, '{}'), ?)
+		WHERE id = ?
+	`, string(valuesJSON), string(sourcesJSON), nodeID); err != nil {
+		return fmt.Errorf("failed to merge discovered values: %w", err)
 	}
-	fragment.Edges = edges
+	return nil
+}
 
-	return fragment, nil
+// Ping verifies the database connection is still usable
+func (r *Repository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
 }
 
-// Close closes the database connection
-func (r *Repository) Close() error {
-	return r.db.Close()
+// SetVerifyBackoff replaces the re-verify delays applied after consecutive
+// unreachable results. An empty schedule restores DefaultVerifyBackoff.
+func (r *Repository) SetVerifyBackoff(steps []time.Duration) {
+	if len(steps) == 0 {
+		steps = DefaultVerifyBackoff
+	}
+	r.verifyBackoff = steps
 }
 
 // GetNodesForVerification returns nodes that need verification
-// This includes unverified nodes and nodes that haven't been verified recently
+// This includes unverified nodes and nodes that haven't been verified recently,
+// excluding unreachable nodes still inside their backoff window
 func (r *Repository) GetNodesForVerification(ctx context.Context) ([]domain.Node, error) {
 	query := `SELECT ` + nodeColumns + ` FROM nodes
-		WHERE status = 'unverified'
+		WHERE deleted_at IS NULL
+		  AND (verify_backoff_until IS NULL OR verify_backoff_until <= ?)
+		  AND (status = 'unverified'
 		   OR status = 'verifying'
 		   OR last_verified IS NULL
-		   OR last_verified < datetime('now', '-5 minutes')`
+		   OR last_verified < datetime('now', '-5 minutes'))`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(ctx, query, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("query nodes for verification: %w", err)
 	}
@@ -832,7 +2169,63 @@ func (r *Repository) GetNodesForVerification(ctx context.Context) ([]domain.Node
 	return scanNodeRows(rows)
 }
 
-// UpdateNodeVerification updates only the verification-related fields of a node
+// GetNodesByIDs returns the live nodes among ids; unknown IDs are skipped
+func (r *Repository) GetNodesByIDs(ctx context.Context, ids []string) ([]domain.Node, error) {
+	if len(ids) == 0 {
+		return []domain.Node{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+nodeColumns+" FROM nodes WHERE deleted_at IS NULL AND id IN ("+placeholders+") ORDER BY id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query nodes by id: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNodeRows(rows)
+}
+
+// GetNodesUpdatedSince returns live nodes updated at or after since, most
+// recently updated first
+func (r *Repository) GetNodesUpdatedSince(ctx context.Context, since time.Time) ([]domain.Node, error) {
+	// Timestamps are stored in local time and compared as text, so match
+	// the zone of the stored values
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT "+nodeColumns+" FROM nodes WHERE deleted_at IS NULL AND updated_at >= ? ORDER BY updated_at DESC, id",
+		since.Local())
+	if err != nil {
+		return nil, fmt.Errorf("query nodes updated since: %w", err)
+	}
+	defer rows.Close()
+
+	return scanNodeRows(rows)
+}
+
+// SetNodeStatus sets the status of a live node, reporting whether it changed
+func (r *Repository) SetNodeStatus(ctx context.Context, nodeID string, status domain.NodeStatus) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE nodes SET status = ?, updated_at = ?
+		WHERE id = ? AND status != ? AND deleted_at IS NULL
+	`, status, time.Now(), nodeID, status)
+	if err != nil {
+		return false, fmt.Errorf("failed to set node status: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UpdateNodeVerification updates only the verification-related fields of a
+// node. A stale node stays stale on an unreachable result; it takes a
+// successful probe to bring it back.
 func (r *Repository) UpdateNodeVerification(ctx context.Context, nodeID string, status domain.NodeStatus, lastVerified, lastSeen *time.Time, discovered map[string]any) error {
 	var discoveredJSON sql.NullString
 	if discovered != nil && len(discovered) > 0 {
@@ -842,6 +2235,7 @@ func (r *Repository) UpdateNodeVerification(ctx context.Context, nodeID string,
 		}
 		discoveredJSON = sql.NullString{String: string(data), Valid: true}
 	}
+	rawMAC, _ := discovered["mac_address"].(string)
 
 	var lastVerifiedSQL, lastSeenSQL sql.NullTime
 	if lastVerified != nil {
@@ -851,16 +2245,68 @@ func (r *Repository) UpdateNodeVerification(ctx context.Context, nodeID string,
 		lastSeenSQL = sql.NullTime{Time: *lastSeen, Valid: true}
 	}
 
-	_, err := r.db.ExecContext(ctx, `
+	_, oldDiscovered, exists, err := nodeHistoryValues(ctx, r.db, nodeID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = r.db.ExecContext(ctx, `
 		UPDATE nodes
-		SET status = ?, last_verified = ?, last_seen = ?, discovered = ?, updated_at = ?
-		WHERE id = ?
-	`, status, lastVerifiedSQL, lastSeenSQL, discoveredJSON, time.Now(), nodeID)
+		SET status = CASE WHEN status = 'stale' AND ?1 = 'unreachable' THEN status ELSE ?1 END,
+			last_verified = ?2, last_seen = ?3, discovered = ?4, mac_address = ?5, updated_at = ?6,
+			first_seen = COALESCE(first_seen, ?3)
+		WHERE id = ?7
+	`, status, lastVerifiedSQL, lastSeenSQL, discoveredJSON, stringToNull(domain.NormalizeMAC(rawMAC)), now, nodeID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update node verification: %w", err)
 	}
 
+	if !exists {
+		return nil
+	}
+	if err := r.updateVerifyBackoff(ctx, nodeID, status, now); err != nil {
+		return err
+	}
+	changes, err := diffNodeValues("discovered", oldDiscovered, discovered)
+	if err != nil {
+		return fmt.Errorf("diff node discovered: %w", err)
+	}
+	return recordNodeHistory(ctx, r.db, nodeID, "verifier", changes, now)
+}
+
+// updateVerifyBackoff widens the re-verify window after an unreachable
+// result and clears it once the node answers again
+func (r *Repository) updateVerifyBackoff(ctx context.Context, nodeID string, status domain.NodeStatus, now time.Time) error {
+	switch status {
+	case domain.NodeStatusUnreachable:
+		var failures int
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT COALESCE(verify_failures, 0) FROM nodes WHERE id = ?`, nodeID,
+		).Scan(&failures); err != nil {
+			return fmt.Errorf("query verify failures: %w", err)
+		}
+		failures++
+
+		step := failures - 1
+		if step >= len(r.verifyBackoff) {
+			step = len(r.verifyBackoff) - 1
+		}
+		until := now.Add(r.verifyBackoff[step])
+		if _, err := r.db.ExecContext(ctx,
+			`UPDATE nodes SET verify_failures = ?, verify_backoff_until = ? WHERE id = ?`,
+			failures, until, nodeID); err != nil {
+			return fmt.Errorf("update verify backoff: %w", err)
+		}
+
+	case domain.NodeStatusVerified, domain.NodeStatusDegraded:
+		if _, err := r.db.ExecContext(ctx,
+			`UPDATE nodes SET verify_failures = 0, verify_backoff_until = NULL WHERE id = ? AND (verify_failures > 0 OR verify_backoff_until IS NOT NULL)`,
+			nodeID); err != nil {
+			return fmt.Errorf("reset verify backoff: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -913,33 +2359,67 @@ func (r *Repository) HasOperatorTruthHostname(ctx context.Context, nodeID string
 	return false, nil
 }
 
-// ClearGraph removes all nodes, edges, and positions from the database
-func (r *Repository) ClearGraph(ctx context.Context) error {
+// ClearGraph removes nodes, edges, positions and discrepancies. With
+// KeepTruth, nodes carrying operator truth survive along with their
+// positions, tags, images, history, discrepancies and the edges between
+// them. A dry run performs the deletes in a transaction that is rolled
+// back, so the counts are exact.
+func (r *Repository) ClearGraph(ctx context.Context, opts domain.ClearOptions) (*domain.ClearResult, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Delete in order due to foreign key constraints
-	if _, err := tx.ExecContext(ctx, `DELETE FROM node_positions`); err != nil {
-		return fmt.Errorf("failed to clear positions: %w", err)
-	}
-	if _, err := tx.ExecContext(ctx, `DELETE FROM edges`); err != nil {
-		return fmt.Errorf("failed to clear edges: %w", err)
-	}
-	if _, err := tx.ExecContext(ctx, `DELETE FROM nodes`); err != nil {
-		return fmt.Errorf("failed to clear nodes: %w", err)
+	// Restrict each delete to rows belonging to nodes being removed
+	nodeWhere, byNode, byEdge := "", "", ""
+	if opts.KeepTruth {
+		nodeWhere = " WHERE truth IS NULL"
+		doomed := "(SELECT id FROM nodes" + nodeWhere + ")"
+		byNode = " WHERE node_id IN " + doomed
+		byEdge = " WHERE from_id IN " + doomed + " OR to_id IN " + doomed
+	}
+
+	result := &domain.ClearResult{DryRun: opts.DryRun}
+	steps := []struct {
+		name  string
+		query string
+		count *int
+	}{
+		{"positions", `DELETE FROM node_positions` + byNode, &result.Positions},
+		{"edges", `DELETE FROM edges` + byEdge, &result.Edges},
+		{"node images", `DELETE FROM node_images` + byNode, nil},
+		{"node tags", `DELETE FROM node_tags` + byNode, nil},
+		{"node history", `DELETE FROM node_history` + byNode, nil},
+		{"discrepancies", `DELETE FROM discrepancies` + byNode, &result.Discrepancies},
+		{"nodes", `DELETE FROM nodes` + nodeWhere, &result.Nodes},
+	}
+
+	// Delete in order due to foreign key constraints; nodes go last since
+	// the other deletes select by them
+	for _, step := range steps {
+		res, err := tx.ExecContext(ctx, step.query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to clear %s: %w", step.name, err)
+		}
+		if step.count != nil {
+			n, _ := res.RowsAffected()
+			*step.count = int(n)
+		}
 	}
-	if _, err := tx.ExecContext(ctx, `DELETE FROM discrepancies`); err != nil {
-		return fmt.Errorf("failed to clear discrepancies: %w", err)
+
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM nodes`).Scan(&result.KeptNodes); err != nil {
+		return nil, fmt.Errorf("failed to count kept nodes: %w", err)
 	}
 
+	if opts.DryRun {
+		return result, nil
+	}
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	return nil
+	return result, nil
 }
 
 // SetNodeTruth sets or updates the operator truth for a node
@@ -991,7 +2471,8 @@ func (r *Repository) ClearNodeTruth(ctx context.Context, nodeID string) error {
 // GetNodesWithTruth returns all nodes that have operator truth set
 func (r *Repository) GetNodesWithTruth(ctx context.Context) ([]domain.Node, error) {
 	query := `SELECT ` + nodeColumns + ` FROM nodes
-		WHERE truth_status = 'asserted' OR truth_status = 'conflict'`
+		WHERE deleted_at IS NULL
+		  AND (truth_status = 'asserted' OR truth_status = 'conflict')`
 
 	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
@@ -1046,158 +2527,464 @@ func (r *Repository) GetDiscrepancy(ctx context.Context, id string) (*domain.Dis
 		resolution                      sql.NullString
 	)
 
-	err := r.db.QueryRowContext(ctx, `
-		SELECT node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
-		FROM discrepancies WHERE id = ?
-	`, id).Scan(&nodeID, &propertyKey, &truthValueJSON, &actualValueJSON, &source, &detectedAt, &resolvedAt, &resolution)
+	err := r.db.QueryRowContext(ctx, `
+		SELECT node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
+		FROM discrepancies WHERE id = ?
+	`, id).Scan(&nodeID, &propertyKey, &truthValueJSON, &actualValueJSON, &source, &detectedAt, &resolvedAt, &resolution)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query discrepancy: %w", err)
+	}
+
+	d := &domain.Discrepancy{
+		ID:          id,
+		NodeID:      nodeID,
+		PropertyKey: propertyKey,
+		Source:      source,
+		DetectedAt:  detectedAt,
+		Resolution:  resolution.String,
+	}
+
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+
+	if truthValueJSON.Valid {
+		json.Unmarshal([]byte(truthValueJSON.String), &d.TruthValue)
+	}
+	if actualValueJSON.Valid {
+		json.Unmarshal([]byte(actualValueJSON.String), &d.ActualValue)
+	}
+
+	return d, nil
+}
+
+// GetDiscrepanciesByNode returns all discrepancies for a specific node
+func (r *Repository) GetDiscrepanciesByNode(ctx context.Context, nodeID string) ([]domain.Discrepancy, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
+		FROM discrepancies
+		WHERE node_id = ?
+		ORDER BY detected_at DESC
+	`, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanDiscrepancies(rows)
+}
+
+// GetUnresolvedDiscrepancies returns all unresolved discrepancies
+func (r *Repository) GetUnresolvedDiscrepancies(ctx context.Context) ([]domain.Discrepancy, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
+		FROM discrepancies
+		WHERE resolved_at IS NULL
+		ORDER BY detected_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unresolved discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanDiscrepancies(rows)
+}
+
+// CountUnresolvedDiscrepancies returns the number of open discrepancies
+func (r *Repository) CountUnresolvedDiscrepancies(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM discrepancies WHERE resolved_at IS NULL`).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unresolved discrepancies: %w", err)
+	}
+	return count, nil
+}
+
+// ResolveDiscrepancy marks a discrepancy as resolved
+func (r *Repository) ResolveDiscrepancy(ctx context.Context, id string, resolution string) error {
+	// Get the discrepancy first to find the node
+	d, err := r.GetDiscrepancy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return fmt.Errorf("discrepancy not found: %s", id)
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE discrepancies
+		SET resolved_at = ?, resolution = ?
+		WHERE id = ?
+	`, time.Now(), resolution, id)
+
+	if err != nil {
+		return fmt.Errorf("failed to resolve discrepancy: %w", err)
+	}
+
+	return r.refreshNodeDiscrepancyStatus(ctx, d.NodeID)
+}
+
+// DeleteDiscrepancy removes a discrepancy outright and recomputes the
+// node's discrepancy flag from those remaining open
+func (r *Repository) DeleteDiscrepancy(ctx context.Context, id string) error {
+	d, err := r.GetDiscrepancy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return fmt.Errorf("discrepancy %s not found", id)
+	}
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM discrepancies WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete discrepancy: %w", err)
+	}
+
+	return r.refreshNodeDiscrepancyStatus(ctx, d.NodeID)
+}
+
+// refreshNodeDiscrepancyStatus sets a node's discrepancy flag from whether
+// it has any unresolved discrepancies left
+func (r *Repository) refreshNodeDiscrepancyStatus(ctx context.Context, nodeID string) error {
+	var count int
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM discrepancies
+		WHERE node_id = ? AND resolved_at IS NULL
+	`, nodeID).Scan(&count)
+
+	if err != nil {
+		return err
+	}
+
+	return r.UpdateNodeDiscrepancyStatus(ctx, nodeID, count > 0)
+}
+
+// scanDiscrepancies is a helper to scan rows into Discrepancy slice
+func (r *Repository) scanDiscrepancies(rows *sql.Rows) ([]domain.Discrepancy, error) {
+	discrepancies := make([]domain.Discrepancy, 0)
+	for rows.Next() {
+		d, err := scanDiscrepancy(rows)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, d)
+	}
+
+	return discrepancies, rows.Err()
+}
+
+// scanDiscrepancy scans the standard discrepancy columns of the current
+// row, followed by any extra destinations
+func scanDiscrepancy(rows *sql.Rows, extra ...any) (domain.Discrepancy, error) {
+	var (
+		id, nodeID, propertyKey, source string
+		truthValueJSON, actualValueJSON sql.NullString
+		detectedAt                      time.Time
+		resolvedAt                      sql.NullTime
+		resolution                      sql.NullString
+	)
+
+	dest := append([]any{&id, &nodeID, &propertyKey, &truthValueJSON, &actualValueJSON, &source, &detectedAt, &resolvedAt, &resolution}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return domain.Discrepancy{}, fmt.Errorf("failed to scan discrepancy: %w", err)
+	}
+
+	d := domain.Discrepancy{
+		ID:          id,
+		NodeID:      nodeID,
+		PropertyKey: propertyKey,
+		Source:      source,
+		DetectedAt:  detectedAt,
+		Resolution:  resolution.String,
+	}
+
+	if resolvedAt.Valid {
+		d.ResolvedAt = &resolvedAt.Time
+	}
+
+	if truthValueJSON.Valid {
+		json.Unmarshal([]byte(truthValueJSON.String), &d.TruthValue)
+	}
+	if actualValueJSON.Valid {
+		json.Unmarshal([]byte(actualValueJSON.String), &d.ActualValue)
+	}
+
+	return d, nil
+}
+
+// discrepancyPageSize is how many rows EachUnresolvedDiscrepancy reads per query
+const discrepancyPageSize = 500
+
+// EachUnresolvedDiscrepancy calls fn for every unresolved discrepancy,
+// oldest first, with the label of its node ("" if the node is gone). Rows
+// are read a page at a time, keyed on (detected_at, id), and fn only runs
+// once a page's cursor is closed, so a slow consumer neither holds large
+// reports in memory nor pins a connection. An error from fn stops the
+// iteration and is returned.
+func (r *Repository) EachUnresolvedDiscrepancy(ctx context.Context, fn func(d domain.Discrepancy, nodeLabel string) error) error {
+	return r.eachUnresolvedDiscrepancy(ctx, discrepancyPageSize, fn)
+}
+
+func (r *Repository) eachUnresolvedDiscrepancy(ctx context.Context, pageSize int, fn func(d domain.Discrepancy, nodeLabel string) error) error {
+	type row struct {
+		d     domain.Discrepancy
+		label string
+	}
+
+	// The cursor compares detected_at as stored, so it is carried as text
+	var afterAt, afterID string
+	first := true
+	for {
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT d.id, d.node_id, d.property_key, d.truth_value, d.actual_value, d.source,
+			       d.detected_at, d.resolved_at, d.resolution, COALESCE(n.label, ''),
+			       CAST(d.detected_at AS TEXT)
+			FROM discrepancies d
+			LEFT JOIN nodes n ON n.id = d.node_id
+			WHERE d.resolved_at IS NULL
+			  AND (? OR d.detected_at > ? OR (d.detected_at = ? AND d.id > ?))
+			ORDER BY d.detected_at, d.id
+			LIMIT ?
+		`, first, afterAt, afterAt, afterID, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to query unresolved discrepancies: %w", err)
+		}
+
+		page := make([]row, 0, pageSize)
+		for rows.Next() {
+			var label string
+			d, err := scanDiscrepancy(rows, &label, &afterAt)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			page = append(page, row{d: d, label: label})
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read unresolved discrepancies: %w", err)
+		}
+
+		for _, p := range page {
+			if err := fn(p.d, p.label); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].d.ID
+		first = false
+	}
+}
+
+// ==================== Subnet Repository Methods ====================
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// queryExecer is satisfied by both *sql.DB and *sql.Tx
+type queryExecer interface {
+	execer
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil
+// ensureNodeSubnet creates the subnet a node's segmentum names unless it
+// exists. Nodes without a valid CIDR segmentum and subnet nodes are skipped.
+func ensureNodeSubnet(ctx context.Context, db execer, node *domain.Node) error {
+	if node.Type == domain.NodeTypeSubnet {
+		return nil
 	}
+	cidr, err := domain.NormalizeCIDR(node.GetPropertyString("segmentum"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query discrepancy: %w", err)
+		return nil
 	}
+	return ensureSubnet(ctx, db, cidr)
+}
 
-	d := &domain.Discrepancy{
-		ID:          id,
-		NodeID:      nodeID,
-		PropertyKey: propertyKey,
-		Source:      source,
-		DetectedAt:  detectedAt,
-		Resolution:  resolution.String,
+// ensureSubnet inserts an empty subnet row for a normalized CIDR
+func ensureSubnet(ctx context.Context, db execer, cidr string) error {
+	now := time.Now()
+	if _, err := db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO subnets (cidr, created_at, updated_at) VALUES (?, ?, ?)`, cidr, now, now,
+	); err != nil {
+		return fmt.Errorf("ensure subnet %s: %w", cidr, err)
 	}
+	return nil
+}
 
-	if resolvedAt.Valid {
-		d.ResolvedAt = &resolvedAt.Time
+// EnsureSubnet creates the subnet for cidr unless it already exists
+func (r *Repository) EnsureSubnet(ctx context.Context, cidr string) error {
+	normalized, err := domain.NormalizeCIDR(cidr)
+	if err != nil {
+		return err
 	}
+	return ensureSubnet(ctx, r.db, normalized)
+}
 
-	if truthValueJSON.Valid {
-		json.Unmarshal([]byte(truthValueJSON.String), &d.TruthValue)
+// backfillSubnets creates subnets for segmentum values of nodes written
+// before the subnets table existed
+func (r *Repository) backfillSubnets() {
+	ctx := context.Background()
+	counts, err := r.subnetMemberCounts(ctx)
+	if err != nil {
+		return
 	}
-	if actualValueJSON.Valid {
-		json.Unmarshal([]byte(actualValueJSON.String), &d.ActualValue)
+	for cidr := range counts {
+		ensureSubnet(ctx, r.db, cidr)
 	}
-
-	return d, nil
 }
 
-// GetDiscrepanciesByNode returns all discrepancies for a specific node
-func (r *Repository) GetDiscrepanciesByNode(ctx context.Context, nodeID string) ([]domain.Discrepancy, error) {
+// subnetMemberCounts counts live, non-subnet nodes per normalized segmentum
+func (r *Repository) subnetMemberCounts(ctx context.Context) (map[string]int, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
-		FROM discrepancies
-		WHERE node_id = ?
-		ORDER BY detected_at DESC
-	`, nodeID)
+		SELECT json_extract(properties, '$.segmentum') AS segmentum, COUNT(*)
+		FROM nodes
+		WHERE deleted_at IS NULL AND type != ? AND segmentum IS NOT NULL
+		GROUP BY segmentum
+	`, domain.NodeTypeSubnet)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query discrepancies: %w", err)
+		return nil, fmt.Errorf("count subnet members: %w", err)
 	}
 	defer rows.Close()
 
-	return r.scanDiscrepancies(rows)
+	counts := make(map[string]int)
+	for rows.Next() {
+		var segmentum sql.NullString
+		var count int
+		if err := rows.Scan(&segmentum, &count); err != nil {
+			return nil, fmt.Errorf("scan subnet members: %w", err)
+		}
+		if cidr, err := domain.NormalizeCIDR(segmentum.String); err == nil {
+			counts[cidr] += count
+		}
+	}
+	return counts, rows.Err()
 }
 
-// GetUnresolvedDiscrepancies returns all unresolved discrepancies
-func (r *Repository) GetUnresolvedDiscrepancies(ctx context.Context) ([]domain.Discrepancy, error) {
-	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, node_id, property_key, truth_value, actual_value, source, detected_at, resolved_at, resolution
-		FROM discrepancies
-		WHERE resolved_at IS NULL
-		ORDER BY detected_at DESC
-	`)
+// ListSubnets returns all subnets ordered by CIDR, with member counts
+func (r *Repository) ListSubnets(ctx context.Context) ([]domain.Subnet, error) {
+	counts, err := r.subnetMemberCounts(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query unresolved discrepancies: %w", err)
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT cidr, vlan_id, description, gateway, created_at, updated_at FROM subnets ORDER BY cidr`)
+	if err != nil {
+		return nil, fmt.Errorf("query subnets: %w", err)
 	}
 	defer rows.Close()
 
-	return r.scanDiscrepancies(rows)
+	subnets := make([]domain.Subnet, 0)
+	for rows.Next() {
+		subnet, err := scanSubnet(rows)
+		if err != nil {
+			return nil, err
+		}
+		subnet.MemberCount = counts[subnet.CIDR]
+		subnets = append(subnets, *subnet)
+	}
+	return subnets, rows.Err()
 }
 
-// ResolveDiscrepancy marks a discrepancy as resolved
-func (r *Repository) ResolveDiscrepancy(ctx context.Context, id string, resolution string) error {
-	// Get the discrepancy first to find the node
-	d, err := r.GetDiscrepancy(ctx, id)
+// GetSubnet retrieves a subnet with its member count, or nil if not found
+func (r *Repository) GetSubnet(ctx context.Context, cidr string) (*domain.Subnet, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT cidr, vlan_id, description, gateway, created_at, updated_at FROM subnets WHERE cidr = ?`, cidr)
 	if err != nil {
-		return err
-	}
-	if d == nil {
-		return fmt.Errorf("discrepancy not found: %s", id)
+		return nil, fmt.Errorf("query subnet: %w", err)
 	}
+	defer rows.Close()
 
-	_, err = r.db.ExecContext(ctx, `
-		UPDATE discrepancies
-		SET resolved_at = ?, resolution = ?
-		WHERE id = ?
-	`, time.Now(), resolution, id)
-
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	subnet, err := scanSubnet(rows)
 	if err != nil {
-		return fmt.Errorf("failed to resolve discrepancy: %w", err)
+		return nil, err
 	}
+	rows.Close()
 
-	// Check if node has any remaining unresolved discrepancies
-	var count int
-	err = r.db.QueryRowContext(ctx, `
-		SELECT COUNT(*) FROM discrepancies
-		WHERE node_id = ? AND resolved_at IS NULL
-	`, d.NodeID).Scan(&count)
-
+	counts, err := r.subnetMemberCounts(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Update node's discrepancy status
-	return r.UpdateNodeDiscrepancyStatus(ctx, d.NodeID, count > 0)
+	subnet.MemberCount = counts[subnet.CIDR]
+	return subnet, nil
 }
 
-// scanDiscrepancies is a helper to scan rows into Discrepancy slice
-func (r *Repository) scanDiscrepancies(rows *sql.Rows) ([]domain.Discrepancy, error) {
-	discrepancies := make([]domain.Discrepancy, 0)
-	for rows.Next() {
-		var (
-			id, nodeID, propertyKey, source string
-			truthValueJSON, actualValueJSON sql.NullString
-			detectedAt                      time.Time
-			resolvedAt                      sql.NullTime
-			resolution                      sql.NullString
-		)
-
-		if err := rows.Scan(&id, &nodeID, &propertyKey, &truthValueJSON, &actualValueJSON, &source, &detectedAt, &resolvedAt, &resolution); err != nil {
-			return nil, fmt.Errorf("failed to scan discrepancy: %w", err)
-		}
-
-		d := domain.Discrepancy{
-			ID:          id,
-			NodeID:      nodeID,
-			PropertyKey: propertyKey,
-			Source:      source,
-			DetectedAt:  detectedAt,
-			Resolution:  resolution.String,
-		}
-
-		if resolvedAt.Valid {
-			d.ResolvedAt = &resolvedAt.Time
-		}
+// CreateSubnet stores a new subnet
+func (r *Repository) CreateSubnet(ctx context.Context, subnet *domain.Subnet) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO subnets (cidr, vlan_id, description, gateway, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(cidr) DO NOTHING
+	`, subnet.CIDR, intToNull(subnet.VLANID), stringToNull(subnet.Description), stringToNull(subnet.Gateway), subnet.CreatedAt, subnet.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("insert subnet: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("subnet %s already exists", subnet.CIDR)
+	}
+	return nil
+}
 
-		if truthValueJSON.Valid {
-			json.Unmarshal([]byte(truthValueJSON.String), &d.TruthValue)
-		}
-		if actualValueJSON.Valid {
-			json.Unmarshal([]byte(actualValueJSON.String), &d.ActualValue)
-		}
+// UpdateSubnet replaces a subnet's metadata
+func (r *Repository) UpdateSubnet(ctx context.Context, subnet *domain.Subnet) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE subnets SET vlan_id = ?, description = ?, gateway = ?, updated_at = ?
+		WHERE cidr = ?
+	`, intToNull(subnet.VLANID), stringToNull(subnet.Description), stringToNull(subnet.Gateway), subnet.UpdatedAt, subnet.CIDR)
+	if err != nil {
+		return fmt.Errorf("update subnet: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("subnet %s not found", subnet.CIDR)
+	}
+	return nil
+}
 
-		discrepancies = append(discrepancies, d)
+// DeleteSubnet removes a subnet
+func (r *Repository) DeleteSubnet(ctx context.Context, cidr string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM subnets WHERE cidr = ?`, cidr)
+	if err != nil {
+		return fmt.Errorf("delete subnet: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("subnet %s not found", cidr)
+	}
+	return nil
+}
 
-	return discrepancies, rows.Err()
+// scanSubnet scans one subnets row
+func scanSubnet(rows *sql.Rows) (*domain.Subnet, error) {
+	var subnet domain.Subnet
+	var vlanID sql.NullInt64
+	var description, gateway sql.NullString
+	if err := rows.Scan(&subnet.CIDR, &vlanID, &description, &gateway, &subnet.CreatedAt, &subnet.UpdatedAt); err != nil {
+		return nil, fmt.Errorf("scan subnet: %w", err)
+	}
+	subnet.VLANID = int(vlanID.Int64)
+	subnet.Description = nullToString(description)
+	subnet.Gateway = nullToString(gateway)
+	return &subnet, nil
 }
 
 // ==================== Secrets Repository Methods ====================
 
 // CreateSecret creates a new operator secret
 func (r *Repository) CreateSecret(ctx context.Context, secret *domain.Secret) error {
-	dataJSON, err := json.Marshal(secret.Data)
+	data, encrypted, err := secretDataColumn(secret)
 	if err != nil {
-		return fmt.Errorf("failed to marshal secret data: %w", err)
+		return err
 	}
 
 	metadataJSON, err := json.Marshal(secret.Metadata)
@@ -1210,8 +2997,8 @@ func (r *Repository) CreateSecret(ctx context.Context, secret *domain.Secret) er
 	secret.UpdatedAt = now
 
 	query := `
-		INSERT INTO secrets (id, name, type, source, description, data, metadata, immutable, status, status_message, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO secrets (id, name, type, source, description, data, metadata, immutable, status, status_message, created_at, updated_at, encrypted, expires_at, rotation_interval)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err = r.db.ExecContext(ctx, query,
 		secret.ID,
@@ -1219,13 +3006,16 @@ func (r *Repository) CreateSecret(ctx context.Context, secret *domain.Secret) er
 		string(secret.Type),
 		string(secret.Source),
 		secret.Description,
-		string(dataJSON),
+		data,
 		string(metadataJSON),
 		boolToInt(secret.Immutable),
 		string(secret.Status),
 		secret.StatusMessage,
 		secret.CreatedAt,
 		secret.UpdatedAt,
+		boolToInt(encrypted),
+		secret.ExpiresAt,
+		secret.RotationInterval,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create secret: %w", err)
@@ -1237,7 +3027,7 @@ func (r *Repository) CreateSecret(ctx context.Context, secret *domain.Secret) er
 // GetSecret retrieves a secret by ID
 func (r *Repository) GetSecret(ctx context.Context, id string) (*domain.Secret, error) {
 	query := `
-		SELECT id, name, type, source, description, data, metadata, immutable, status, status_message, usage_count, last_used_at, created_at, updated_at
+		SELECT id, name, type, source, description, data, metadata, immutable, status, status_message, usage_count, last_used_at, created_at, updated_at, encrypted, expires_at, rotation_interval
 		FROM secrets WHERE id = ?
 	`
 	row := r.db.QueryRowContext(ctx, query, id)
@@ -1245,7 +3035,9 @@ func (r *Repository) GetSecret(ctx context.Context, id string) (*domain.Secret,
 	var secret domain.Secret
 	var dataJSON, metadataJSON sql.NullString
 	var immutable int
-	var lastUsedAt sql.NullTime
+	var encrypted sql.NullInt64
+	var lastUsedAt, expiresAt sql.NullTime
+	var rotationInterval sql.NullString
 
 	err := row.Scan(
 		&secret.ID,
@@ -1262,6 +3054,9 @@ func (r *Repository) GetSecret(ctx context.Context, id string) (*domain.Secret,
 		&lastUsedAt,
 		&secret.CreatedAt,
 		&secret.UpdatedAt,
+		&encrypted,
+		&expiresAt,
+		&rotationInterval,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -1274,11 +3069,12 @@ func (r *Repository) GetSecret(ctx context.Context, id string) (*domain.Secret,
 	if lastUsedAt.Valid {
 		secret.LastUsedAt = &lastUsedAt.Time
 	}
-
-	if dataJSON.Valid {
-		secret.Data = make(map[string]string)
-		json.Unmarshal([]byte(dataJSON.String), &secret.Data)
+	if expiresAt.Valid {
+		secret.ExpiresAt = &expiresAt.Time
 	}
+	secret.RotationInterval = rotationInterval.String
+
+	setSecretData(&secret, dataJSON, nullToBool(encrypted))
 	if metadataJSON.Valid {
 		secret.Metadata = make(map[string]string)
 		json.Unmarshal([]byte(metadataJSON.String), &secret.Metadata)
@@ -1287,11 +3083,42 @@ func (r *Repository) GetSecret(ctx context.Context, id string) (*domain.Secret,
 	return &secret, nil
 }
 
+// GetSecretByName retrieves the secret with the given name, or nil if
+// there is none. Names are not unique, so a name shared by several
+// secrets is an error.
+func (r *Repository) GetSecretByName(ctx context.Context, name string) (*domain.Secret, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id FROM secrets WHERE name = ? ORDER BY id LIMIT 2`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up secret name: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan secret id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	switch len(ids) {
+	case 0:
+		return nil, nil
+	case 1:
+		return r.GetSecret(ctx, ids[0])
+	}
+	return nil, fmt.Errorf("secret name %q is ambiguous: used by %s and %s", name, ids[0], ids[1])
+}
+
 // UpdateSecret updates an existing secret
 func (r *Repository) UpdateSecret(ctx context.Context, secret *domain.Secret) error {
-	dataJSON, err := json.Marshal(secret.Data)
+	data, encrypted, err := secretDataColumn(secret)
 	if err != nil {
-		return fmt.Errorf("failed to marshal secret data: %w", err)
+		return err
 	}
 
 	metadataJSON, err := json.Marshal(secret.Metadata)
@@ -1304,18 +3131,22 @@ func (r *Repository) UpdateSecret(ctx context.Context, secret *domain.Secret) er
 	query := `
 		UPDATE secrets SET
 			name = ?, type = ?, description = ?, data = ?, metadata = ?,
-			status = ?, status_message = ?, updated_at = ?
+			status = ?, status_message = ?, updated_at = ?, encrypted = ?,
+			expires_at = ?, rotation_interval = ?
 		WHERE id = ? AND immutable = 0
 	`
 	result, err := r.db.ExecContext(ctx, query,
 		secret.Name,
 		string(secret.Type),
 		secret.Description,
-		string(dataJSON),
+		data,
 		string(metadataJSON),
 		string(secret.Status),
 		secret.StatusMessage,
 		secret.UpdatedAt,
+		boolToInt(encrypted),
+		secret.ExpiresAt,
+		secret.RotationInterval,
 		secret.ID,
 	)
 	if err != nil {
@@ -1346,10 +3177,26 @@ func (r *Repository) DeleteSecret(ctx context.Context, id string) error {
 	return nil
 }
 
+// ForceDeleteSecret deletes a secret by ID even if it is immutable. Callers
+// must first establish that the row is stale.
+func (r *Repository) ForceDeleteSecret(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM secrets WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return fmt.Errorf("secret %s not found", id)
+	}
+
+	return nil
+}
+
 // ListSecrets lists all secrets, optionally filtered by type or source
 func (r *Repository) ListSecrets(ctx context.Context, secretType string, source string) ([]domain.Secret, error) {
 	query := `
-		SELECT id, name, type, source, description, data, metadata, immutable, status, status_message, usage_count, last_used_at, created_at, updated_at
+		SELECT id, name, type, source, description, data, metadata, immutable, status, status_message, usage_count, last_used_at, created_at, updated_at, encrypted, expires_at, rotation_interval
 		FROM secrets WHERE 1=1
 	`
 	args := []interface{}{}
@@ -1376,7 +3223,9 @@ func (r *Repository) ListSecrets(ctx context.Context, secretType string, source
 		var secret domain.Secret
 		var dataJSON, metadataJSON sql.NullString
 		var immutable int
-		var lastUsedAt sql.NullTime
+		var encrypted sql.NullInt64
+		var lastUsedAt, expiresAt sql.NullTime
+		var rotationInterval sql.NullString
 
 		err := rows.Scan(
 			&secret.ID,
@@ -1393,6 +3242,9 @@ func (r *Repository) ListSecrets(ctx context.Context, secretType string, source
 			&lastUsedAt,
 			&secret.CreatedAt,
 			&secret.UpdatedAt,
+			&encrypted,
+			&expiresAt,
+			&rotationInterval,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan secret: %w", err)
@@ -1402,11 +3254,12 @@ func (r *Repository) ListSecrets(ctx context.Context, secretType string, source
 		if lastUsedAt.Valid {
 			secret.LastUsedAt = &lastUsedAt.Time
 		}
-
-		if dataJSON.Valid {
-			secret.Data = make(map[string]string)
-			json.Unmarshal([]byte(dataJSON.String), &secret.Data)
+		if expiresAt.Valid {
+			secret.ExpiresAt = &expiresAt.Time
 		}
+		secret.RotationInterval = rotationInterval.String
+
+		setSecretData(&secret, dataJSON, nullToBool(encrypted))
 		if metadataJSON.Valid {
 			secret.Metadata = make(map[string]string)
 			json.Unmarshal([]byte(metadataJSON.String), &secret.Metadata)
@@ -1437,6 +3290,230 @@ func (r *Repository) UpdateSecretStatus(ctx context.Context, id string, status d
 	return err
 }
 
+// CreateMergeOperation records a merge so it can be undone
+func (r *Repository) CreateMergeOperation(ctx context.Context, op *domain.MergeOperation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("marshal merge operation: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO merge_operations (id, parent_id, data, created_at, undone_at) VALUES (?, ?, ?, ?, ?)`,
+		op.ID, op.ParentID, string(data), op.CreatedAt, timePtrToNull(op.UndoneAt))
+	if err != nil {
+		return fmt.Errorf("insert merge operation: %w", err)
+	}
+	return nil
+}
+
+// GetMergeOperation retrieves a merge operation by ID, or nil if not found
+func (r *Repository) GetMergeOperation(ctx context.Context, id string) (*domain.MergeOperation, error) {
+	var data string
+	var undoneAt sql.NullTime
+	err := r.db.QueryRowContext(ctx,
+		`SELECT data, undone_at FROM merge_operations WHERE id = ?`, id,
+	).Scan(&data, &undoneAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query merge operation: %w", err)
+	}
+
+	var op domain.MergeOperation
+	if err := json.Unmarshal([]byte(data), &op); err != nil {
+		return nil, fmt.Errorf("unmarshal merge operation: %w", err)
+	}
+	op.UndoneAt = nullToTimePtr(undoneAt)
+	return &op, nil
+}
+
+// MarkMergeOperationUndone stamps a merge operation as undone. It returns
+// false if the operation was already undone.
+func (r *Repository) MarkMergeOperationUndone(ctx context.Context, id string, at time.Time) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE merge_operations SET undone_at = ? WHERE id = ? AND undone_at IS NULL`, at, id)
+	if err != nil {
+		return false, fmt.Errorf("mark merge operation undone: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// CreateSnapshot stores a snapshot and its graph
+func (r *Repository) CreateSnapshot(ctx context.Context, snap *domain.Snapshot) error {
+	data, err := json.Marshal(snap.Graph)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO snapshots (id, label, node_count, edge_count, data, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		snap.ID, stringToNull(snap.Label), snap.NodeCount, snap.EdgeCount, string(data), snap.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("insert snapshot: %w", err)
+	}
+	return nil
+}
+
+// ListSnapshots returns snapshot metadata, newest first, without graphs
+func (r *Repository) ListSnapshots(ctx context.Context) ([]domain.Snapshot, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, label, node_count, edge_count, created_at FROM snapshots ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, fmt.Errorf("query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]domain.Snapshot, 0)
+	for rows.Next() {
+		var snap domain.Snapshot
+		var label sql.NullString
+		if err := rows.Scan(&snap.ID, &label, &snap.NodeCount, &snap.EdgeCount, &snap.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan snapshot: %w", err)
+		}
+		snap.Label = nullToString(label)
+		snapshots = append(snapshots, snap)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetSnapshot retrieves a snapshot with its graph, or nil if not found
+func (r *Repository) GetSnapshot(ctx context.Context, id string) (*domain.Snapshot, error) {
+	snap := domain.Snapshot{ID: id}
+	var label sql.NullString
+	var data string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT label, node_count, edge_count, data, created_at FROM snapshots WHERE id = ?`, id,
+	).Scan(&label, &snap.NodeCount, &snap.EdgeCount, &data, &snap.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query snapshot: %w", err)
+	}
+	snap.Label = nullToString(label)
+
+	snap.Graph = domain.NewGraphFragment()
+	if err := json.Unmarshal([]byte(data), snap.Graph); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+	return &snap, nil
+}
+
+// RestoreFragment replaces the live graph with fragment in one
+// transaction. Unlike a replace import it also restores verification
+// state, discovered data, truth, the protected flag and positions, and
+// drops discrepancies for nodes that no longer exist. Tags come back with
+// the import itself.
+func (r *Repository) RestoreFragment(ctx context.Context, fragment *domain.GraphFragment) (map[string]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := importFragmentTx(ctx, tx, fragment, "replace")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range fragment.Nodes {
+		if err := restoreNodeStateTx(ctx, tx, &node); err != nil {
+			return nil, fmt.Errorf("failed to restore node %s: %w", node.ID, err)
+		}
+	}
+
+	for _, pos := range fragment.Positions {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO node_positions (node_id, x, y, pinned) VALUES (?, ?, ?, ?)`,
+			pos.NodeID, pos.X, pos.Y, pos.Pinned,
+		); err != nil {
+			return nil, fmt.Errorf("failed to restore position for %s: %w", pos.NodeID, err)
+		}
+	}
+	result["positions"] = len(fragment.Positions)
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM discrepancies WHERE node_id NOT IN (SELECT id FROM nodes)`,
+	); err != nil {
+		return nil, fmt.Errorf("failed to clear orphaned discrepancies: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+// restoreNodeStateTx writes the node state a replace import leaves out:
+// verification, discovered data, capabilities, truth and the protected flag.
+// Tags are already restored by the import.
+func restoreNodeStateTx(ctx context.Context, tx *sql.Tx, node *domain.Node) error {
+	discoveredJSON, err := marshalToNull(node.Discovered)
+	if err != nil {
+		return fmt.Errorf("marshal discovered: %w", err)
+	}
+	capabilitiesJSON, err := marshalToNull(node.Capabilities)
+	if err != nil {
+		return fmt.Errorf("marshal capabilities: %w", err)
+	}
+	var sourcesJSON sql.NullString
+	if len(node.DiscoveredSources) > 0 {
+		if sourcesJSON, err = marshalToNull(node.DiscoveredSources); err != nil {
+			return fmt.Errorf("marshal discovered sources: %w", err)
+		}
+	}
+	var truthJSON sql.NullString
+	if node.Truth != nil {
+		if truthJSON, err = marshalToNull(node.Truth); err != nil {
+			return fmt.Errorf("marshal truth: %w", err)
+		}
+	}
+	firstSeen := timePtrToNull(nodeFirstSeen(node))
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE nodes
+		SET parent_id = ?, status = ?, last_verified = ?, last_seen = ?, discovered = ?,
+			capabilities = ?, created_at = ?, mac_address = ?, discovered_sources = ?,
+			first_seen = COALESCE(min(first_seen, ?), first_seen, ?),
+			truth = ?, truth_status = ?, has_discrepancy = ?, protected = ?
+		WHERE id = ?
+	`, stringToNull(node.ParentID), string(node.Status), timePtrToNull(node.LastVerified), timePtrToNull(node.LastSeen),
+		discoveredJSON, capabilitiesJSON, node.CreatedAt, stringToNull(node.MACAddress()), sourcesJSON,
+		firstSeen, firstSeen,
+		truthJSON, string(node.TruthStatus), node.HasDiscrepancy, node.Protected, node.ID)
+	return err
+}
+
+// secretDataColumn returns the value to store in the data column and
+// whether it is sealed. Sealed data is stored as-is in place of Data.
+func secretDataColumn(secret *domain.Secret) (string, bool, error) {
+	if secret.SealedData != "" {
+		return secret.SealedData, true, nil
+	}
+	dataJSON, err := json.Marshal(secret.Data)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to marshal secret data: %w", err)
+	}
+	return string(dataJSON), false, nil
+}
+
+// setSecretData fills Data from a plaintext row or SealedData from an
+// encrypted one, leaving decryption to the caller
+func setSecretData(secret *domain.Secret, data sql.NullString, encrypted bool) {
+	if !data.Valid {
+		return
+	}
+	if encrypted {
+		secret.SealedData = data.String
+		return
+	}
+	secret.Data = make(map[string]string)
+	json.Unmarshal([]byte(data.String), &secret.Data)
+}
+
 // boolToInt converts bool to int for SQLite
 func boolToInt(b bool) int {
 	if b {
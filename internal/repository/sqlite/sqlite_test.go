@@ -4,7 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -614,6 +619,54 @@ func TestListNodes(t *testing.T) {
 	})
 }
 
+func TestSearchNodes(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	web := domain.NewNode("web-1", domain.NodeTypeServer, "Webserver")
+	web.SetProperty("ip", "10.0.0.10")
+	proxy := domain.NewNode("proxy-1", domain.NodeTypeServer, "proxy")
+	proxy.SetDiscovered("reverse_dns", "web.lan")
+	db := domain.NewNode("db-1", domain.NodeTypeServer, "db")
+	db.SetProperty("ip", "10.0.0.20")
+	pct := domain.NewNode("pct-1", domain.NodeTypeServer, "100%_done")
+	for _, n := range []*domain.Node{web, proxy, db, pct} {
+		assertNoError(t, repo.CreateNode(ctx, n))
+	}
+
+	t.Run("label matches rank above discovered matches", func(t *testing.T) {
+		nodes, err := repo.SearchNodes(ctx, "WEB", 10)
+		assertNoError(t, err)
+		if len(nodes) != 2 {
+			t.Fatalf("got %d results, want 2", len(nodes))
+		}
+		assertEqual(t, "web-1", nodes[0].ID)
+		assertEqual(t, "proxy-1", nodes[1].ID)
+	})
+
+	t.Run("matches ip fragments in properties", func(t *testing.T) {
+		nodes, err := repo.SearchNodes(ctx, "0.0.20", 10)
+		assertNoError(t, err)
+		if len(nodes) != 1 || nodes[0].ID != "db-1" {
+			t.Errorf("got %v, want [db-1]", nodes)
+		}
+	})
+
+	t.Run("wildcards are matched literally", func(t *testing.T) {
+		nodes, err := repo.SearchNodes(ctx, "%_", 10)
+		assertNoError(t, err)
+		if len(nodes) != 1 || nodes[0].ID != "pct-1" {
+			t.Errorf("got %d results, want only pct-1", len(nodes))
+		}
+	})
+
+	t.Run("limit caps results", func(t *testing.T) {
+		nodes, err := repo.SearchNodes(ctx, "-1", 2)
+		assertNoError(t, err)
+		assertEqual(t, 2, len(nodes))
+	})
+}
+
 func TestUpdateNode(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -659,6 +712,37 @@ func TestUpdateNode(t *testing.T) {
 	})
 }
 
+func TestGetNodesUpdatedSince(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	for _, id := range []string{"a", "b", "c"} {
+		assertNoError(t, repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)))
+	}
+	time.Sleep(10 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	assertNoError(t, repo.UpdateNode(ctx, "c", map[string]interface{}{"label": "C"}))
+	time.Sleep(10 * time.Millisecond)
+	assertNoError(t, repo.UpdateNode(ctx, "a", map[string]interface{}{"label": "A"}))
+
+	// The window start is compared regardless of its time zone
+	nodes, err := repo.GetNodesUpdatedSince(ctx, since.UTC())
+	assertNoError(t, err)
+	if len(nodes) != 2 || nodes[0].ID != "a" || nodes[1].ID != "c" {
+		ids := make([]string, len(nodes))
+		for i, n := range nodes {
+			ids[i] = n.ID
+		}
+		t.Errorf("updated since = %v, want [a c]", ids)
+	}
+
+	nodes, err = repo.GetNodesUpdatedSince(ctx, time.Now().Add(time.Minute))
+	assertNoError(t, err)
+	assertEqual(t, 0, len(nodes))
+}
+
 func TestDeleteNode(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -667,7 +751,7 @@ func TestDeleteNode(t *testing.T) {
 		node := domain.NewNode("delete-test", domain.NodeTypeServer, "Delete Me")
 		assertNoError(t, repo.CreateNode(ctx, node))
 
-		err := repo.DeleteNode(ctx, "delete-test")
+		err := repo.DeleteNode(ctx, "delete-test", true)
 		assertNoError(t, err)
 
 		// Verify deleted
@@ -677,13 +761,298 @@ func TestDeleteNode(t *testing.T) {
 	})
 
 	t.Run("delete non-existent node fails", func(t *testing.T) {
-		err := repo.DeleteNode(ctx, "nonexistent")
+		err := repo.DeleteNode(ctx, "nonexistent", true)
 		if err == nil {
 			t.Fatal("expected error deleting non-existent node")
 		}
 	})
 }
 
+func TestSoftDeleteNode(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("soft1", domain.NodeTypeServer, "Soft One")))
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("soft2", domain.NodeTypeServer, "Soft Two")))
+	edge := domain.NewEdge("soft1", "soft2", domain.EdgeTypeEthernet)
+	assertNoError(t, repo.CreateEdge(ctx, edge))
+
+	assertNoError(t, repo.DeleteNode(ctx, "soft1", false))
+
+	t.Run("node kept with deleted_at", func(t *testing.T) {
+		node, err := repo.GetNode(ctx, "soft1")
+		assertNoError(t, err)
+		assertNotNil(t, node)
+		if node.DeletedAt == nil {
+			t.Error("expected DeletedAt to be set")
+		}
+	})
+
+	t.Run("hidden from listings", func(t *testing.T) {
+		graph, err := repo.GetGraph(ctx)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(graph.Nodes))
+		assertEqual(t, "soft2", graph.Nodes[0].ID)
+		assertEqual(t, 0, len(graph.Edges))
+
+		results, err := repo.SearchNodes(ctx, "soft", 10)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(results))
+	})
+
+	t.Run("edge hidden not dropped", func(t *testing.T) {
+		stored, err := repo.GetEdge(ctx, edge.ID)
+		assertNoError(t, err)
+		assertNotNil(t, stored)
+	})
+
+	t.Run("second soft delete fails", func(t *testing.T) {
+		if err := repo.DeleteNode(ctx, "soft1", false); err == nil {
+			t.Fatal("expected error soft-deleting an already deleted node")
+		}
+	})
+
+	t.Run("restore brings node and edge back", func(t *testing.T) {
+		assertNoError(t, repo.RestoreNode(ctx, "soft1"))
+
+		node, err := repo.GetNode(ctx, "soft1")
+		assertNoError(t, err)
+		assertNil(t, node.DeletedAt)
+
+		edges, err := repo.ListEdges(ctx, "", "", "")
+		assertNoError(t, err)
+		assertEqual(t, 1, len(edges))
+
+		if err := repo.RestoreNode(ctx, "soft1"); err == nil {
+			t.Fatal("expected error restoring a live node")
+		}
+	})
+
+	t.Run("create revives soft-deleted id", func(t *testing.T) {
+		assertNoError(t, repo.DeleteNode(ctx, "soft2", false))
+		assertNoError(t, repo.CreateNode(ctx, domain.NewNode("soft2", domain.NodeTypeServer, "Soft Two Again")))
+
+		node, err := repo.GetNode(ctx, "soft2")
+		assertNoError(t, err)
+		assertNil(t, node.DeletedAt)
+		assertEqual(t, "Soft Two Again", node.Label)
+	})
+}
+
+func TestCreateNodes(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("taken", domain.NodeTypeServer, "Taken")))
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("revived", domain.NodeTypeServer, "Old")))
+	assertNoError(t, repo.DeleteNode(ctx, "revived", false))
+
+	t.Run("partial commit", func(t *testing.T) {
+		errs, err := repo.CreateNodes(ctx, []*domain.Node{
+			domain.NewNode("batch1", domain.NodeTypeServer, "Batch One"),
+			domain.NewNode("taken", domain.NodeTypeServer, "Duplicate"),
+			domain.NewNode("revived", domain.NodeTypeServer, "New"),
+		}, false)
+		assertNoError(t, err)
+		assertNil(t, errs[0])
+		assertNotNil(t, errs[1])
+		assertNil(t, errs[2])
+
+		node, err := repo.GetNode(ctx, "batch1")
+		assertNoError(t, err)
+		assertNotNil(t, node)
+
+		taken, err := repo.GetNode(ctx, "taken")
+		assertNoError(t, err)
+		assertEqual(t, "Taken", taken.Label)
+
+		revived, err := repo.GetNode(ctx, "revived")
+		assertNoError(t, err)
+		assertEqual(t, "New", revived.Label)
+		assertNil(t, revived.DeletedAt)
+	})
+
+	t.Run("atomic rollback", func(t *testing.T) {
+		errs, err := repo.CreateNodes(ctx, []*domain.Node{
+			domain.NewNode("batch2", domain.NodeTypeServer, "Batch Two"),
+			domain.NewNode("batch2", domain.NodeTypeServer, "Batch Two Again"),
+		}, true)
+		assertNoError(t, err)
+		assertNil(t, errs[0])
+		assertNotNil(t, errs[1])
+
+		node, err := repo.GetNode(ctx, "batch2")
+		assertNoError(t, err)
+		assertNil(t, node)
+	})
+}
+
+func TestCountNodesByStatus(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	for id, status := range map[string]domain.NodeStatus{
+		"a": domain.NodeStatusVerified,
+		"b": domain.NodeStatusUnreachable,
+		"c": domain.NodeStatusUnreachable,
+		"d": domain.NodeStatusVerified,
+	} {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		node.Status = status
+		assertNoError(t, repo.CreateNode(ctx, node))
+	}
+	assertNoError(t, repo.DeleteNode(ctx, "d", false))
+
+	counts, err := repo.CountNodesByStatus(ctx)
+	assertNoError(t, err)
+	assertEqual(t, 1, counts[domain.NodeStatusVerified])
+	assertEqual(t, 2, counts[domain.NodeStatusUnreachable])
+}
+
+func TestGetNodeByMAC(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	host := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	host.SetDiscovered("mac_address", "AA:BB:CC:00:00:01")
+	assertNoError(t, repo.CreateNode(ctx, host))
+
+	iface := domain.NewNode("nas:eth0", domain.NodeTypeInterface, "eth0")
+	iface.ParentID = host.ID
+	iface.SetDiscovered("mac_address", "aa:bb:cc:00:00:01")
+	assertNoError(t, repo.CreateNode(ctx, iface))
+
+	other := domain.NewNode("192-168-1-30", domain.NodeTypeServer, "printer")
+	assertNoError(t, repo.CreateNode(ctx, other))
+
+	t.Run("matches any notation, preferring top-level nodes", func(t *testing.T) {
+		node, err := repo.GetNodeByMAC(ctx, "aa-bb-cc-00-00-01")
+		assertNoError(t, err)
+		assertNotNil(t, node)
+		assertEqual(t, host.ID, node.ID)
+	})
+
+	t.Run("tracks verification updates", func(t *testing.T) {
+		discovered := map[string]any{"mac_address": "AA:BB:CC:00:00:02"}
+		assertNoError(t, repo.UpdateNodeVerification(ctx, other.ID, domain.NodeStatusVerified, nil, nil, discovered))
+
+		node, err := repo.GetNodeByMAC(ctx, "aa:bb:cc:00:00:02")
+		assertNoError(t, err)
+		assertNotNil(t, node)
+		assertEqual(t, other.ID, node.ID)
+	})
+
+	t.Run("ignores deleted nodes and bad input", func(t *testing.T) {
+		assertNoError(t, repo.DeleteNode(ctx, other.ID, false))
+		node, err := repo.GetNodeByMAC(ctx, "aa:bb:cc:00:00:02")
+		assertNoError(t, err)
+		assertNil(t, node)
+
+		node, err = repo.GetNodeByMAC(ctx, "not-a-mac")
+		assertNoError(t, err)
+		assertNil(t, node)
+	})
+}
+
+func TestNodeRole(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	api := domain.NewNode("k8s-api", domain.NodeTypeServer, "kubernetes-api")
+	api.SetProperty("role", "k8s-control-plane")
+	assertNoError(t, repo.CreateNode(ctx, api))
+
+	gw := domain.NewNode("gw", domain.NodeTypeRouter, "gateway")
+	gw.Role = domain.RoleGateway
+	assertNoError(t, repo.CreateNode(ctx, gw))
+
+	web := domain.NewNode("web", domain.NodeTypeServer, "web")
+	web.SetProperty("role", "webserver")
+	assertNoError(t, repo.CreateNode(ctx, web))
+
+	t.Run("property role is promoted on write", func(t *testing.T) {
+		node, err := repo.GetNode(ctx, api.ID)
+		assertNoError(t, err)
+		assertEqual(t, domain.RoleControlPlane, node.Role)
+		assertEqual(t, "", node.GetPropertyString("role"))
+
+		node, err = repo.GetNode(ctx, web.ID)
+		assertNoError(t, err)
+		assertEqual(t, domain.NodeRole(""), node.Role)
+		assertEqual(t, "webserver", node.GetPropertyString("role"))
+	})
+
+	t.Run("filter by role", func(t *testing.T) {
+		nodes, err := repo.FilterNodes(ctx, domain.NodeFilter{Role: domain.RoleGateway})
+		assertNoError(t, err)
+		assertEqual(t, 1, len(nodes))
+		assertEqual(t, gw.ID, nodes[0].ID)
+	})
+
+	t.Run("backfill extracts legacy properties", func(t *testing.T) {
+		_, err := repo.db.Exec(`INSERT INTO nodes (id, type, label, properties, created_at, updated_at)
+			VALUES ('legacy', 'server', 'legacy', '{"ip":"10.0.0.9","role":"k8s-dns"}', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`)
+		assertNoError(t, err)
+
+		repo.backfillRoles()
+
+		node, err := repo.GetNode(ctx, "legacy")
+		assertNoError(t, err)
+		assertEqual(t, domain.RoleDNS, node.Role)
+		assertEqual(t, "", node.GetPropertyString("role"))
+		assertEqual(t, "10.0.0.9", node.GetPropertyString("ip"))
+
+		node, err = repo.GetNode(ctx, web.ID)
+		assertNoError(t, err)
+		assertEqual(t, "webserver", node.GetPropertyString("role"))
+	})
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"unbounded":         DefaultOptions(),
+		"single connection": {MaxOpenConns: 1},
+		"pooled":            {MaxOpenConns: 8, BusyTimeout: 10 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, err := NewWithOptions(filepath.Join(t.TempDir(), "stress.db"), opts)
+			assertNoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+
+			const workers, perWorker = 8, 20
+			var wg sync.WaitGroup
+			errs := make(chan error, workers*perWorker*2)
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < perWorker; i++ {
+						id := fmt.Sprintf("n-%d-%d", w, i)
+						if err := repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)); err != nil {
+							errs <- fmt.Errorf("create %s: %w", id, err)
+							continue
+						}
+						update := map[string]interface{}{"properties": map[string]interface{}{"i": i}}
+						if err := repo.UpdateNode(ctx, id, update); err != nil {
+							errs <- fmt.Errorf("update %s: %w", id, err)
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				t.Error(err)
+			}
+			nodes, err := repo.ListNodes(ctx, "", "")
+			assertNoError(t, err)
+			assertEqual(t, workers*perWorker, len(nodes))
+		})
+	}
+}
+
 func TestUpsertNode(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -712,6 +1081,54 @@ func TestUpsertNode(t *testing.T) {
 	})
 }
 
+func TestNodeImage(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	node := domain.NewNode("img-node", domain.NodeTypeServer, "With Image")
+	assertNoError(t, repo.CreateNode(ctx, node))
+
+	t.Run("no image by default", func(t *testing.T) {
+		img, err := repo.GetNodeImage(ctx, "img-node")
+		assertNoError(t, err)
+		assertNil(t, img)
+
+		retrieved, err := repo.GetNode(ctx, "img-node")
+		assertNoError(t, err)
+		assertEqual(t, false, retrieved.HasImage)
+	})
+
+	t.Run("set and replace image", func(t *testing.T) {
+		assertNoError(t, repo.SetNodeImage(ctx, &domain.NodeImage{
+			NodeID: "img-node", ContentType: "image/png", Data: []byte("first"), UpdatedAt: time.Now(),
+		}))
+		assertNoError(t, repo.SetNodeImage(ctx, &domain.NodeImage{
+			NodeID: "img-node", ContentType: "image/jpeg", Data: []byte("second"), UpdatedAt: time.Now(),
+		}))
+
+		img, err := repo.GetNodeImage(ctx, "img-node")
+		assertNoError(t, err)
+		assertEqual(t, "image/jpeg", img.ContentType)
+		assertEqual(t, []byte("second"), img.Data)
+
+		retrieved, err := repo.GetNode(ctx, "img-node")
+		assertNoError(t, err)
+		assertEqual(t, true, retrieved.HasImage)
+	})
+
+	t.Run("deleting node removes image", func(t *testing.T) {
+		// Production connections do not enable foreign keys
+		_, err := repo.db.Exec("PRAGMA foreign_keys = OFF")
+		assertNoError(t, err)
+
+		assertNoError(t, repo.DeleteNode(ctx, "img-node", true))
+
+		img, err := repo.GetNodeImage(ctx, "img-node")
+		assertNoError(t, err)
+		assertNil(t, img)
+	})
+}
+
 func TestNodeWithParent(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -863,6 +1280,30 @@ func TestListEdges(t *testing.T) {
 		assertNoError(t, err)
 		assertEqual(t, 2, len(result))
 	})
+
+	t.Run("filter by min speed", func(t *testing.T) {
+		assertNoError(t, repo.UpdateEdge(ctx, domain.NewEdge("b", "c", domain.EdgeTypeEthernet).ID,
+			map[string]interface{}{"properties": map[string]interface{}{"speed_mbps": float64(10000)}}))
+		assertNoError(t, repo.UpdateEdge(ctx, domain.NewEdge("b", "d", domain.EdgeTypeEthernet).ID,
+			map[string]interface{}{"properties": map[string]interface{}{"speed_mbps": float64(1000)}}))
+
+		result, err := repo.FilterEdges(ctx, domain.EdgeFilter{MinSpeedMbps: 1000})
+		assertNoError(t, err)
+		assertEqual(t, 2, len(result))
+
+		result, err = repo.FilterEdges(ctx, domain.EdgeFilter{MinSpeedMbps: 2500})
+		assertNoError(t, err)
+		assertEqual(t, 1, len(result))
+		assertEqual(t, "b", result[0].FromID)
+		assertEqual(t, "c", result[0].ToID)
+
+		// Removing the property clears the column
+		assertNoError(t, repo.UpdateEdge(ctx, domain.NewEdge("b", "c", domain.EdgeTypeEthernet).ID,
+			map[string]interface{}{"properties": map[string]interface{}{"speed_mbps": nil}}))
+		result, err = repo.FilterEdges(ctx, domain.EdgeFilter{MinSpeedMbps: 2500})
+		assertNoError(t, err)
+		assertEqual(t, 0, len(result))
+	})
 }
 
 func TestUpdateEdge(t *testing.T) {
@@ -1252,6 +1693,53 @@ func TestResolveDiscrepancy(t *testing.T) {
 	})
 }
 
+func TestDeleteDiscrepancy(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	node := domain.NewNode("disc-node", domain.NodeTypeServer, "Test")
+	assertNoError(t, repo.CreateNode(ctx, node))
+	assertNoError(t, repo.SetNodeTruth(ctx, "disc-node", &domain.NodeTruth{
+		Properties: map[string]any{"hostname": "truth", "ip": "10.0.0.1"},
+	}))
+
+	for _, d := range []*domain.Discrepancy{
+		{ID: "disc1", NodeID: "disc-node", PropertyKey: "hostname", TruthValue: "truth", ActualValue: "alias", Source: "verifier", DetectedAt: time.Now()},
+		{ID: "disc2", NodeID: "disc-node", PropertyKey: "ip", TruthValue: "10.0.0.1", ActualValue: "10.0.0.2", Source: "verifier", DetectedAt: time.Now()},
+	} {
+		assertNoError(t, repo.CreateDiscrepancy(ctx, d))
+	}
+
+	t.Run("flag stays while others remain open", func(t *testing.T) {
+		assertNoError(t, repo.DeleteDiscrepancy(ctx, "disc1"))
+
+		deleted, err := repo.GetDiscrepancy(ctx, "disc1")
+		assertNoError(t, err)
+		assertNil(t, deleted)
+
+		node, err := repo.GetNode(ctx, "disc-node")
+		assertNoError(t, err)
+		assertEqual(t, true, node.HasDiscrepancy)
+		assertEqual(t, domain.TruthStatusConflict, node.TruthStatus)
+	})
+
+	t.Run("flag clears with the last one", func(t *testing.T) {
+		assertNoError(t, repo.DeleteDiscrepancy(ctx, "disc2"))
+
+		node, err := repo.GetNode(ctx, "disc-node")
+		assertNoError(t, err)
+		assertEqual(t, false, node.HasDiscrepancy)
+		assertEqual(t, domain.TruthStatusAsserted, node.TruthStatus)
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		err := repo.DeleteDiscrepancy(ctx, "missing")
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("DeleteDiscrepancy() error = %v, want not found", err)
+		}
+	})
+}
+
 func TestGetDiscrepanciesByNode(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -1307,6 +1795,37 @@ func TestGetUnresolvedDiscrepancies(t *testing.T) {
 	assertEqual(t, 2, len(unresolved))
 }
 
+func TestEachUnresolvedDiscrepancyPages(t *testing.T) {
+	// One connection: a cursor left open across fn would deadlock the
+	// writes made from inside it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo, err := NewWithOptions(filepath.Join(t.TempDir(), "pages.db"), Options{MaxOpenConns: 1})
+	assertNoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("disc-node", domain.NodeTypeServer, "Test")))
+	detected := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		assertNoError(t, repo.CreateDiscrepancy(ctx, &domain.Discrepancy{
+			ID:          fmt.Sprintf("d-%d", i),
+			NodeID:      "disc-node",
+			PropertyKey: "prop",
+			Source:      "test",
+			DetectedAt:  detected.Add(time.Duration(i/3) * time.Minute), // ties within a minute
+		}))
+	}
+
+	var seen []string
+	err = repo.eachUnresolvedDiscrepancy(ctx, 2, func(d domain.Discrepancy, nodeLabel string) error {
+		seen = append(seen, d.ID)
+		assertEqual(t, "Test", nodeLabel)
+		return repo.ResolveDiscrepancy(ctx, d.ID, "exported")
+	})
+	assertNoError(t, err)
+	assertEqual(t, "d-0,d-1,d-2,d-3,d-4,d-5,d-6", strings.Join(seen, ","))
+}
+
 // ============================================================================
 // Import/Export Tests
 // ============================================================================
@@ -1404,6 +1923,46 @@ func TestExportFragment(t *testing.T) {
 	assertEqual(t, 1, len(fragment.Edges))
 }
 
+func TestExportFilteredFragmentByCIDR(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	inside := domain.NewNode("web", domain.NodeTypeServer, "web")
+	inside.SetProperty("ip", "192.168.10.20")
+	router := domain.NewNode("router", domain.NodeTypeRouter, "router")
+	router.SetProperty("ip", "192.168.10.1")
+	outside := domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	outside.SetProperty("ip", "192.168.20.5")
+	noIP := domain.NewNode("printer", domain.NodeTypeServer, "printer")
+	for _, n := range []*domain.Node{inside, router, outside, noIP} {
+		assertNoError(t, repo.CreateNode(ctx, n))
+	}
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("web", "router", domain.EdgeTypeEthernet)))
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("nas", "router", domain.EdgeTypeEthernet)))
+
+	fragment, err := repo.ExportFilteredFragment(ctx, domain.ExportFilter{CIDR: "192.168.10.0/24"})
+	assertNoError(t, err)
+
+	ids := make([]string, 0, len(fragment.Nodes))
+	for _, n := range fragment.Nodes {
+		ids = append(ids, n.ID)
+	}
+	sort.Strings(ids)
+	assertEqual(t, "router,web", strings.Join(ids, ","))
+
+	// The nas -> router edge is dropped since nas is outside the range
+	assertEqual(t, 1, len(fragment.Edges))
+	assertEqual(t, "web", fragment.Edges[0].FromID)
+
+	t.Run("combined with type", func(t *testing.T) {
+		fragment, err := repo.ExportFilteredFragment(ctx, domain.ExportFilter{Type: domain.NodeTypeServer, CIDR: "192.168.10.0/24"})
+		assertNoError(t, err)
+		assertEqual(t, 1, len(fragment.Nodes))
+		assertEqual(t, "web", fragment.Nodes[0].ID)
+		assertEqual(t, 0, len(fragment.Edges))
+	})
+}
+
 // ============================================================================
 // Verification Tests
 // ============================================================================
@@ -1460,6 +2019,78 @@ func TestUpdateNodeVerification(t *testing.T) {
 	assertEqual(t, "discovered-host", retrieved.Discovered["hostname"])
 }
 
+func TestVerificationBackoff(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	repo.SetVerifyBackoff([]time.Duration{5 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour})
+
+	node := domain.NewNode("dead", domain.NodeTypeServer, "dead")
+	assertNoError(t, repo.CreateNode(ctx, node))
+
+	// due reports whether the node is returned for verification
+	due := func() bool {
+		t.Helper()
+		nodes, err := repo.GetNodesForVerification(ctx)
+		assertNoError(t, err)
+		for _, n := range nodes {
+			if n.ID == node.ID {
+				return true
+			}
+		}
+		return false
+	}
+	// expireBackoff moves the backoff window into the past
+	expireBackoff := func() {
+		t.Helper()
+		_, err := repo.db.Exec(`UPDATE nodes SET verify_backoff_until = ?, last_verified = NULL WHERE id = ?`,
+			time.Now().Add(-time.Second), node.ID)
+		assertNoError(t, err)
+	}
+
+	if !due() {
+		t.Fatal("new node should be due for verification")
+	}
+
+	for i, want := range []time.Duration{5 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour, time.Hour} {
+		now := time.Now()
+		assertNoError(t, repo.UpdateNodeVerification(ctx, node.ID, domain.NodeStatusUnreachable, &now, nil, nil))
+
+		got, err := repo.GetNode(ctx, node.ID)
+		assertNoError(t, err)
+		assertEqual(t, i+1, got.VerifyFailures)
+		assertNotNil(t, got.BackoffUntil)
+		if delay := got.BackoffUntil.Sub(now); delay < want-time.Second || delay > want+time.Second {
+			t.Errorf("failure %d: backoff %s, want %s", i+1, delay, want)
+		}
+
+		// Unverified status would otherwise make the node due immediately
+		_, err = repo.db.Exec(`UPDATE nodes SET status = 'unverified' WHERE id = ?`, node.ID)
+		assertNoError(t, err)
+		if due() {
+			t.Errorf("failure %d: node inside backoff window returned for verification", i+1)
+		}
+		expireBackoff()
+		if !due() {
+			t.Errorf("failure %d: node not due after backoff elapsed", i+1)
+		}
+	}
+
+	t.Run("success resets", func(t *testing.T) {
+		now := time.Now()
+		assertNoError(t, repo.UpdateNodeVerification(ctx, node.ID, domain.NodeStatusVerified, &now, &now, nil))
+
+		got, err := repo.GetNode(ctx, node.ID)
+		assertNoError(t, err)
+		assertEqual(t, 0, got.VerifyFailures)
+		assertNil(t, got.BackoffUntil)
+
+		assertNoError(t, repo.UpdateNodeVerification(ctx, node.ID, domain.NodeStatusUnreachable, &now, nil, nil))
+		got, err = repo.GetNode(ctx, node.ID)
+		assertNoError(t, err)
+		assertEqual(t, 1, got.VerifyFailures)
+	})
+}
+
 func TestUpdateNodeLabel(t *testing.T) {
 	ctx := context.Background()
 	repo := newTestRepo(t)
@@ -1549,8 +2180,9 @@ func TestClearGraph(t *testing.T) {
 	node := domain.NewNode("n1", domain.NodeTypeServer, "N1")
 	assertNoError(t, repo.CreateNode(ctx, node))
 
-	err := repo.ClearGraph(ctx)
+	result, err := repo.ClearGraph(ctx, domain.ClearOptions{})
 	assertNoError(t, err)
+	assertEqual(t, 1, result.Nodes)
 
 	// Verify everything is cleared
 	nodes, err := repo.ListNodes(ctx, "", "")
@@ -1566,6 +2198,86 @@ func TestClearGraph(t *testing.T) {
 	assertEqual(t, 0, len(positions))
 }
 
+// seedClearGraph creates two nodes, one carrying operator truth, with
+// positions, an edge between them and a discrepancy on each
+func seedClearGraph(t *testing.T, repo *Repository) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, id := range []string{"asserted", "discovered"} {
+		assertNoError(t, repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)))
+		assertNoError(t, repo.SavePosition(ctx, domain.NodePosition{NodeID: id, X: 1, Y: 2}))
+		assertNoError(t, repo.CreateDiscrepancy(ctx, &domain.Discrepancy{
+			ID:          "disc-" + id,
+			NodeID:      id,
+			PropertyKey: "hostname",
+			TruthValue:  "a",
+			ActualValue: "b",
+			DetectedAt:  time.Now(),
+		}))
+	}
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("asserted", "discovered", domain.EdgeTypeEthernet)))
+
+	now := time.Now()
+	assertNoError(t, repo.SetNodeTruth(ctx, "asserted", &domain.NodeTruth{
+		AssertedBy: "operator",
+		AssertedAt: &now,
+		Properties: map[string]any{"hostname": "a"},
+	}))
+}
+
+func TestClearGraphOptions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("dry run counts without deleting", func(t *testing.T) {
+		repo := newTestRepo(t)
+		seedClearGraph(t, repo)
+
+		result, err := repo.ClearGraph(ctx, domain.ClearOptions{DryRun: true})
+		assertNoError(t, err)
+		assertEqual(t, true, result.DryRun)
+		assertEqual(t, 2, result.Nodes)
+		assertEqual(t, 1, result.Edges)
+		assertEqual(t, 2, result.Positions)
+		assertEqual(t, 2, result.Discrepancies)
+		assertEqual(t, 0, result.KeptNodes)
+
+		nodes, err := repo.ListNodes(ctx, "", "")
+		assertNoError(t, err)
+		assertEqual(t, 2, len(nodes))
+		edges, err := repo.ListEdges(ctx, "", "", "")
+		assertNoError(t, err)
+		assertEqual(t, 1, len(edges))
+	})
+
+	t.Run("keep truth preserves asserted nodes", func(t *testing.T) {
+		repo := newTestRepo(t)
+		seedClearGraph(t, repo)
+
+		result, err := repo.ClearGraph(ctx, domain.ClearOptions{KeepTruth: true})
+		assertNoError(t, err)
+		assertEqual(t, 1, result.Nodes)
+		assertEqual(t, 1, result.Edges)
+		assertEqual(t, 1, result.Positions)
+		assertEqual(t, 1, result.Discrepancies)
+		assertEqual(t, 1, result.KeptNodes)
+
+		node, err := repo.GetNode(ctx, "asserted")
+		assertNoError(t, err)
+		assertNotNil(t, node)
+		assertNotNil(t, node.Truth)
+		gone, err := repo.GetNode(ctx, "discovered")
+		assertNoError(t, err)
+		assertNil(t, gone)
+
+		positions, err := repo.GetAllPositions(ctx)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(positions))
+		_, ok := positions["asserted"]
+		assertEqual(t, true, ok)
+	})
+}
+
 // ============================================================================
 // JSON Round-trip Tests
 // ============================================================================
@@ -1741,7 +2453,7 @@ func TestCascadeDelete(t *testing.T) {
 	assertNotNil(t, posBefore)
 
 	// Delete node
-	assertNoError(t, repo.DeleteNode(ctx, "cascade1"))
+	assertNoError(t, repo.DeleteNode(ctx, "cascade1", true))
 
 	// Verify edge was cascade deleted
 	deletedEdge, err := repo.GetEdge(ctx, edgeID)
@@ -1754,6 +2466,37 @@ func TestCascadeDelete(t *testing.T) {
 	assertNil(t, position)
 }
 
+func TestDeleteNodeRemovesEdgesWithoutForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	// Production connections do not enable foreign keys
+	_, err := repo.db.Exec("PRAGMA foreign_keys = OFF")
+	assertNoError(t, err)
+
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("a", domain.NodeTypeServer, "A")))
+	assertNoError(t, repo.CreateNode(ctx, domain.NewNode("b", domain.NodeTypeServer, "B")))
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("a", "b", domain.EdgeTypeEthernet)))
+
+	// An edge left behind by an earlier delete without cascades
+	_, err = repo.db.Exec(`INSERT INTO edges (id, from_id, to_id, type, properties) VALUES ('b-ghost', 'b', 'ghost', 'ethernet', '{}')`)
+	assertNoError(t, err)
+
+	count, err := repo.CountNodeEdges(ctx, "a")
+	assertNoError(t, err)
+	assertEqual(t, 1, count)
+
+	assertNoError(t, repo.DeleteNode(ctx, "a", true))
+	count, err = repo.CountNodeEdges(ctx, "a")
+	assertNoError(t, err)
+	assertEqual(t, 0, count)
+
+	dangling, err := repo.ListDanglingEdges(ctx)
+	assertNoError(t, err)
+	assertEqual(t, 1, len(dangling))
+	assertEqual(t, "ghost", dangling[0].ToID)
+}
+
 // ============================================================================
 // Concurrent Access Tests
 // ============================================================================
@@ -1830,8 +2573,8 @@ func TestNodeInsertArgs(t *testing.T) {
 	args, err := nodeInsertArgs(node)
 	assertNoError(t, err)
 
-	// Verify args length (13 fields: added capabilities)
-	assertEqual(t, 13, len(args))
+	// Verify args length (18 fields: added mac_address, role, discovered_sources, first_seen)
+	assertEqual(t, 18, len(args))
 
 	// Verify basic fields
 	assertEqual(t, "test", args[0])
@@ -1846,8 +2589,8 @@ func TestEdgeInsertArgs(t *testing.T) {
 	args, err := edgeInsertArgs(edge)
 	assertNoError(t, err)
 
-	// Verify args length (5 fields)
-	assertEqual(t, 5, len(args))
+	// Verify args length (6 fields)
+	assertEqual(t, 6, len(args))
 
 	// Verify basic fields
 	assertEqual(t, edge.ID, args[0])
@@ -1863,4 +2606,289 @@ func TestEdgeInsertArgs(t *testing.T) {
 	err = json.Unmarshal([]byte(propsJSON.String), &props)
 	assertNoError(t, err)
 	assertEqual(t, "1gbps", props["speed"])
+
+	// No typed speed_mbps, so the extracted column is NULL
+	assertEqual(t, false, args[5].(sql.NullInt64).Valid)
+}
+
+func TestNodeTags(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	web := domain.NewNode("web", domain.NodeTypeServer, "web")
+	db := domain.NewNode("db", domain.NodeTypeServer, "db")
+	cam := domain.NewNode("cam", domain.NodeTypeServer, "cam")
+	for _, n := range []*domain.Node{web, db, cam} {
+		assertNoError(t, repo.CreateNode(ctx, n))
+	}
+
+	assertNoError(t, repo.AddTag(ctx, web.ID, "prod"))
+	assertNoError(t, repo.AddTag(ctx, web.ID, "dmz"))
+	assertNoError(t, repo.AddTag(ctx, web.ID, "prod"))
+	assertNoError(t, repo.AddTag(ctx, db.ID, "prod"))
+	assertNoError(t, repo.AddTag(ctx, cam.ID, "iot"))
+
+	t.Run("node includes sorted tags", func(t *testing.T) {
+		node, err := repo.GetNode(ctx, web.ID)
+		assertNoError(t, err)
+		assertEqual(t, "dmz,prod", strings.Join(node.Tags, ","))
+
+		tags, err := repo.ListTags(ctx, web.ID)
+		assertNoError(t, err)
+		assertEqual(t, "dmz,prod", strings.Join(tags, ","))
+	})
+
+	t.Run("filter requires every tag", func(t *testing.T) {
+		nodes, err := repo.FilterNodes(ctx, domain.NodeFilter{Tags: []string{"prod"}})
+		assertNoError(t, err)
+		assertEqual(t, 2, len(nodes))
+
+		nodes, err = repo.FilterNodes(ctx, domain.NodeFilter{Tags: []string{"prod", "dmz"}})
+		assertNoError(t, err)
+		assertEqual(t, 1, len(nodes))
+		assertEqual(t, web.ID, nodes[0].ID)
+	})
+
+	t.Run("remove and missing", func(t *testing.T) {
+		assertNoError(t, repo.RemoveTag(ctx, web.ID, "dmz"))
+		if err := repo.RemoveTag(ctx, web.ID, "dmz"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("RemoveTag() of absent tag error = %v, want not found", err)
+		}
+		if err := repo.AddTag(ctx, "missing", "prod"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("AddTag() on missing node error = %v, want not found", err)
+		}
+	})
+
+	t.Run("hard delete removes tags", func(t *testing.T) {
+		assertNoError(t, repo.DeleteNode(ctx, cam.ID, true))
+		tags, err := repo.ListTags(ctx, cam.ID)
+		assertNoError(t, err)
+		assertEqual(t, 0, len(tags))
+	})
+}
+
+func TestNodeHistory(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	node := domain.NewNode("host", domain.NodeTypeServer, "host")
+	node.SetProperty("ip", "192.168.1.10")
+	assertNoError(t, repo.CreateNode(ctx, node))
+
+	now := time.Now()
+	for i, hostname := range []string{"alpha.lan", "beta.lan"} {
+		discovered := map[string]any{"reverse_dns": hostname, "ping_latency_ms": 5 + i}
+		assertNoError(t, repo.UpdateNodeVerification(ctx, node.ID, domain.NodeStatusVerified, &now, &now, discovered))
+	}
+
+	history, err := repo.GetNodeHistory(ctx, node.ID, 10)
+	assertNoError(t, err)
+	assertEqual(t, 2, len(history))
+
+	// Newest first; latency is not recorded
+	assertEqual(t, "discovered.reverse_dns", history[0].Property)
+	assertEqual(t, "alpha.lan", history[0].OldValue)
+	assertEqual(t, "beta.lan", history[0].NewValue)
+	assertEqual(t, "verifier", history[0].Source)
+	assertNil(t, history[1].OldValue)
+	assertEqual(t, "alpha.lan", history[1].NewValue)
+
+	t.Run("upsert records property changes", func(t *testing.T) {
+		updated, err := repo.GetNode(ctx, node.ID)
+		assertNoError(t, err)
+		updated.SetProperty("ip", "192.168.1.11")
+		updated.Source = "scanner"
+		assertNoError(t, repo.UpsertNode(ctx, updated))
+
+		history, err := repo.GetNodeHistory(ctx, node.ID, 1)
+		assertNoError(t, err)
+		assertEqual(t, 1, len(history))
+		assertEqual(t, "properties.ip", history[0].Property)
+		assertEqual(t, "192.168.1.11", history[0].NewValue)
+		assertEqual(t, "scanner", history[0].Source)
+	})
+
+	t.Run("hard delete removes history", func(t *testing.T) {
+		assertNoError(t, repo.DeleteNode(ctx, node.ID, true))
+		history, err := repo.GetNodeHistory(ctx, node.ID, 10)
+		assertNoError(t, err)
+		assertEqual(t, 0, len(history))
+	})
+}
+
+func TestGetNeighbors(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	for _, id := range []string{"hub", "in", "out", "gone"} {
+		assertNoError(t, repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)))
+	}
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("in", "hub", domain.EdgeTypeEthernet)))
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("hub", "out", domain.EdgeTypeEthernet)))
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("hub", "gone", domain.EdgeTypeEthernet)))
+	assertNoError(t, repo.DeleteNode(ctx, "gone", false))
+
+	neighbors, err := repo.GetNeighbors(ctx, "hub")
+	assertNoError(t, err)
+
+	got := make(map[string]string)
+	for _, n := range neighbors {
+		got[n.Node.ID] = n.Edge.ID
+	}
+	assertEqual(t, map[string]string{
+		"in":  domain.NewEdge("in", "hub", domain.EdgeTypeEthernet).ID,
+		"out": domain.NewEdge("hub", "out", domain.EdgeTypeEthernet).ID,
+	}, got)
+}
+
+func TestGetEdgesBetween(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	for _, id := range []string{"a", "b", "c"} {
+		assertNoError(t, repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)))
+	}
+	forward := domain.NewEdge("a", "b", domain.EdgeTypeEthernet)
+	backward := domain.NewEdge("b", "a", domain.EdgeTypeVLAN)
+	assertNoError(t, repo.CreateEdge(ctx, forward))
+	assertNoError(t, repo.CreateEdge(ctx, backward))
+	assertNoError(t, repo.CreateEdge(ctx, domain.NewEdge("a", "c", domain.EdgeTypeEthernet)))
+
+	edges, err := repo.GetEdgesBetween(ctx, "a", "b")
+	assertNoError(t, err)
+	ids := make([]string, len(edges))
+	for i, e := range edges {
+		ids[i] = e.ID
+	}
+	want := []string{forward.ID, backward.ID}
+	sort.Strings(want)
+	assertEqual(t, want, ids)
+
+	// Argument order does not matter
+	edges, err = repo.GetEdgesBetween(ctx, "b", "a")
+	assertNoError(t, err)
+	assertEqual(t, 2, len(edges))
+
+	// Unconnected pair yields an empty, non-nil slice
+	edges, err = repo.GetEdgesBetween(ctx, "b", "c")
+	assertNoError(t, err)
+	assertNotNil(t, edges)
+	assertEqual(t, 0, len(edges))
+}
+
+func TestNodeFirstSeen(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	firstSeen := func(id string) *time.Time {
+		t.Helper()
+		node, err := repo.GetNode(ctx, id)
+		assertNoError(t, err)
+		return node.FirstSeen
+	}
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	at := func(days int) *time.Time {
+		ts := base.AddDate(0, 0, days)
+		return &ts
+	}
+
+	// Initial discovery sets first_seen from last_seen
+	node := domain.NewNode("host", domain.NodeTypeServer, "host")
+	node.LastSeen = at(0)
+	assertNoError(t, repo.CreateNode(ctx, node))
+	if got := firstSeen("host"); got == nil || !got.Equal(*at(0)) {
+		t.Fatalf("first_seen = %v, want %v", got, at(0))
+	}
+
+	// Later observations do not move it
+	node.LastSeen = at(5)
+	assertNoError(t, repo.UpsertNode(ctx, node))
+	if got := firstSeen("host"); !got.Equal(*at(0)) {
+		t.Errorf("first_seen after later upsert = %v, want %v", got, at(0))
+	}
+
+	// Re-creating after a soft delete with an earlier timestamp keeps the earliest
+	assertNoError(t, repo.DeleteNode(ctx, "host", false))
+	recreated := domain.NewNode("host", domain.NodeTypeServer, "host")
+	recreated.FirstSeen = at(-10)
+	recreated.LastSeen = at(6)
+	assertNoError(t, repo.CreateNode(ctx, recreated))
+	if got := firstSeen("host"); !got.Equal(*at(-10)) {
+		t.Errorf("first_seen after re-create = %v, want %v", got, at(-10))
+	}
+
+	// ...and a later one never moves it forward
+	assertNoError(t, repo.DeleteNode(ctx, "host", false))
+	again := domain.NewNode("host", domain.NodeTypeServer, "host")
+	again.LastSeen = at(7)
+	errs, err := repo.CreateNodes(ctx, []*domain.Node{again}, false)
+	assertNoError(t, err)
+	assertNoError(t, errs[0])
+	if got := firstSeen("host"); !got.Equal(*at(-10)) {
+		t.Errorf("first_seen after batch re-create = %v, want %v", got, at(-10))
+	}
+
+	// A manual node has none until it is first verified
+	manual := domain.NewNode("manual", domain.NodeTypeServer, "manual")
+	assertNoError(t, repo.CreateNode(ctx, manual))
+	if got := firstSeen("manual"); got != nil {
+		t.Errorf("first_seen of unobserved node = %v, want nil", got)
+	}
+	assertNoError(t, repo.UpdateNodeVerification(ctx, "manual", domain.NodeStatusVerified, at(2), at(2), nil))
+	if got := firstSeen("manual"); got == nil || !got.Equal(*at(2)) {
+		t.Errorf("first_seen after verification = %v, want %v", got, at(2))
+	}
+
+	t.Run("sort by first_seen", func(t *testing.T) {
+		never := domain.NewNode("never", domain.NodeTypeServer, "never")
+		assertNoError(t, repo.CreateNode(ctx, never))
+
+		ids := func(sort string) []string {
+			nodes, err := repo.FilterNodes(ctx, domain.NodeFilter{Sort: sort})
+			assertNoError(t, err)
+			out := make([]string, len(nodes))
+			for i, n := range nodes {
+				out[i] = n.ID
+			}
+			return out
+		}
+		assertEqual(t, []string{"host", "manual", "never"}, ids("first_seen"))
+		assertEqual(t, []string{"manual", "host", "never"}, ids("-first_seen"))
+
+		_, err := repo.FilterNodes(ctx, domain.NodeFilter{Sort: "properties"})
+		if err == nil {
+			t.Error("expected an error for an unknown sort field")
+		}
+	})
+}
+
+func TestVacuum(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+
+	for i := 0; i < 50; i++ {
+		node := domain.NewNode(fmt.Sprintf("node-%d", i), domain.NodeTypeServer, "node")
+		node.SetProperty("ip", fmt.Sprintf("10.0.0.%d", i))
+		assertNoError(t, repo.CreateNode(ctx, node))
+	}
+	for i := 0; i < 25; i++ {
+		assertNoError(t, repo.DeleteNode(ctx, fmt.Sprintf("node-%d", i), true))
+	}
+
+	// Sizes are zero for :memory:, so only completion is checked
+	result, err := repo.Vacuum(ctx)
+	assertNoError(t, err)
+	assertEqual(t, int64(0), result.AfterBytes)
+
+	nodes, err := repo.ListNodes(ctx, "", "")
+	assertNoError(t, err)
+	assertEqual(t, 25, len(nodes))
+
+	t.Run("one at a time", func(t *testing.T) {
+		repo.vacuumMu.Lock()
+		defer repo.vacuumMu.Unlock()
+		if _, err := repo.Vacuum(ctx); err == nil || !strings.Contains(err.Error(), "already running") {
+			t.Errorf("concurrent Vacuum() error = %v, want already running", err)
+		}
+	})
 }
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"specularium/internal/domain"
+)
+
+// ConnectivityReport describes which parts of the topology the self node
+// can reach, treating every edge as undirected
+type ConnectivityReport struct {
+	SelfIDs []string `json:"self_ids"`
+	// Reachable are the nodes connected to a self node, self nodes included
+	Reachable []string `json:"reachable"`
+	// Isolated are the nodes with no path to any self node
+	Isolated []string `json:"isolated"`
+	// Components counts connected components across the whole graph
+	Components int `json:"components"`
+	// Islands are the components without a self node, largest first
+	Islands [][]string `json:"islands"`
+}
+
+// AnalyzeConnectivity splits the live graph into connected components and
+// reports the nodes reachable from the self node and those on islands
+// with no path to it. Edges count in both directions; edges to missing
+// nodes are ignored.
+func (s *GraphService) AnalyzeConnectivity(ctx context.Context) (*ConnectivityReport, error) {
+	nodes, err := s.repo.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	edges, err := s.repo.ListEdges(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ConnectivityReport{
+		SelfIDs:   []string{},
+		Reachable: []string{},
+		Isolated:  []string{},
+		Islands:   [][]string{},
+	}
+	adjacent := make(map[string][]string, len(nodes))
+	for _, node := range nodes {
+		adjacent[node.ID] = nil
+		if node.Type == domain.NodeTypeSelf {
+			report.SelfIDs = append(report.SelfIDs, node.ID)
+		}
+	}
+	if len(report.SelfIDs) == 0 {
+		return nil, fmt.Errorf("self node not found")
+	}
+	sort.Strings(report.SelfIDs)
+
+	for _, edge := range edges {
+		_, fromOK := adjacent[edge.FromID]
+		_, toOK := adjacent[edge.ToID]
+		if !fromOK || !toOK {
+			continue
+		}
+		adjacent[edge.FromID] = append(adjacent[edge.FromID], edge.ToID)
+		adjacent[edge.ToID] = append(adjacent[edge.ToID], edge.FromID)
+	}
+
+	ids := make([]string, 0, len(adjacent))
+	for id := range adjacent {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	self := make(map[string]bool, len(report.SelfIDs))
+	for _, id := range report.SelfIDs {
+		self[id] = true
+	}
+
+	visited := make(map[string]bool, len(ids))
+	for _, start := range ids {
+		if visited[start] {
+			continue
+		}
+		component := connectedComponent(start, adjacent, visited)
+		report.Components++
+
+		hasSelf := false
+		for _, id := range component {
+			if self[id] {
+				hasSelf = true
+				break
+			}
+		}
+		if hasSelf {
+			report.Reachable = append(report.Reachable, component...)
+		} else {
+			report.Isolated = append(report.Isolated, component...)
+			report.Islands = append(report.Islands, component)
+		}
+	}
+
+	sort.Strings(report.Reachable)
+	sort.Strings(report.Isolated)
+	sort.SliceStable(report.Islands, func(i, j int) bool {
+		return len(report.Islands[i]) > len(report.Islands[j])
+	})
+	return report, nil
+}
+
+// connectedComponent returns the sorted IDs reachable from start, marking
+// each as visited
+func connectedComponent(start string, adjacent map[string][]string, visited map[string]bool) []string {
+	visited[start] = true
+	component := []string{start}
+	for queue := []string{start}; len(queue) > 0; queue = queue[1:] {
+		for _, next := range adjacent[queue[0]] {
+			if !visited[next] {
+				visited[next] = true
+				component = append(component, next)
+				queue = append(queue, next)
+			}
+		}
+	}
+	sort.Strings(component)
+	return component
+}
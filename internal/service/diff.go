@@ -0,0 +1,195 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"specularium/internal/codec"
+	"specularium/internal/domain"
+)
+
+// ParseImport parses an import body in the given format (yaml, json,
+// ansible or csv) without writing anything
+func ParseImport(format string, data []byte) (*domain.GraphFragment, error) {
+	var importer codec.Importer
+	switch format {
+	case "", "yaml":
+		importer = codec.NewYAMLCodec()
+	case "json":
+		importer = codec.NewJSONCodec()
+	case "ansible", "ansible-inventory":
+		importer = codec.NewAnsibleCodec()
+	case "csv":
+		importer = codec.NewCSVCodec()
+	case "prometheus-sd":
+		importer = codec.NewPrometheusSDCodec()
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+
+	fragment, err := importer.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", importer.Format(), err)
+	}
+	return fragment, nil
+}
+
+// DiffFragment compares fragment to the current graph and reports what a
+// replace import of it would add, remove and change. Nothing is written.
+// Under a merge import the removed nodes and edges would be kept.
+func (s *GraphService) DiffFragment(ctx context.Context, fragment *domain.GraphFragment) (*domain.GraphDiff, error) {
+	current, err := s.repo.ExportFragment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &domain.GraphDiff{
+		NodesAdded:   []domain.Node{},
+		NodesRemoved: []domain.Node{},
+		NodesChanged: []domain.ObjectChange{},
+		EdgesAdded:   []domain.Edge{},
+		EdgesRemoved: []domain.Edge{},
+		EdgesChanged: []domain.ObjectChange{},
+	}
+
+	existingNodes := make(map[string]domain.Node, len(current.Nodes))
+	for _, node := range current.Nodes {
+		existingNodes[node.ID] = node
+	}
+	incomingNodes := make(map[string]bool, len(fragment.Nodes))
+	for _, node := range fragment.Nodes {
+		incomingNodes[node.ID] = true
+		old, ok := existingNodes[node.ID]
+		if !ok {
+			diff.NodesAdded = append(diff.NodesAdded, node)
+			continue
+		}
+		if fields := diffNodeFields(old, node); len(fields) > 0 {
+			diff.NodesChanged = append(diff.NodesChanged, domain.ObjectChange{ID: node.ID, Fields: fields})
+		}
+	}
+	for _, node := range current.Nodes {
+		if !incomingNodes[node.ID] {
+			diff.NodesRemoved = append(diff.NodesRemoved, node)
+		}
+	}
+
+	existingEdges := make(map[string]domain.Edge, len(current.Edges))
+	for _, edge := range current.Edges {
+		existingEdges[edge.ID] = edge
+	}
+	incomingEdges := make(map[string]bool, len(fragment.Edges))
+	for _, edge := range fragment.Edges {
+		// Import generates missing IDs the same way
+		if edge.ID == "" {
+			edge.ID = edge.GenerateID()
+		}
+		incomingEdges[edge.ID] = true
+		old, ok := existingEdges[edge.ID]
+		if !ok {
+			diff.EdgesAdded = append(diff.EdgesAdded, edge)
+			continue
+		}
+		if fields := diffEdgeFields(old, edge); len(fields) > 0 {
+			diff.EdgesChanged = append(diff.EdgesChanged, domain.ObjectChange{ID: edge.ID, Fields: fields})
+		}
+	}
+	for _, edge := range current.Edges {
+		if !incomingEdges[edge.ID] {
+			diff.EdgesRemoved = append(diff.EdgesRemoved, edge)
+		}
+	}
+
+	sortDiff(diff)
+	return diff, nil
+}
+
+// diffNodeFields lists the fields an import of next would change on old.
+// Import replaces type, label, source and properties wholesale, and adds
+// tags without removing any.
+func diffNodeFields(old, next domain.Node) []domain.FieldChange {
+	var fields []domain.FieldChange
+	if old.Type != next.Type {
+		fields = append(fields, domain.FieldChange{Field: "type", OldValue: old.Type, NewValue: next.Type})
+	}
+	if old.Label != next.Label {
+		fields = append(fields, domain.FieldChange{Field: "label", OldValue: old.Label, NewValue: next.Label})
+	}
+	if old.Source != next.Source {
+		fields = append(fields, domain.FieldChange{Field: "source", OldValue: old.Source, NewValue: next.Source})
+	}
+	fields = append(fields, diffProperties(old.Properties, next.Properties)...)
+
+	merged := slices.Clone(old.Tags)
+	for _, tag := range next.Tags {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+	if len(merged) != len(old.Tags) {
+		sort.Strings(merged)
+		fields = append(fields, domain.FieldChange{Field: "tags", OldValue: old.Tags, NewValue: merged})
+	}
+	return fields
+}
+
+// diffEdgeFields lists the fields an import of next would change on old
+func diffEdgeFields(old, next domain.Edge) []domain.FieldChange {
+	var fields []domain.FieldChange
+	if old.FromID != next.FromID {
+		fields = append(fields, domain.FieldChange{Field: "from_id", OldValue: old.FromID, NewValue: next.FromID})
+	}
+	if old.ToID != next.ToID {
+		fields = append(fields, domain.FieldChange{Field: "to_id", OldValue: old.ToID, NewValue: next.ToID})
+	}
+	if old.Type != next.Type {
+		fields = append(fields, domain.FieldChange{Field: "type", OldValue: old.Type, NewValue: next.Type})
+	}
+	return append(fields, diffProperties(old.Properties, next.Properties)...)
+}
+
+// diffProperties compares property maps key by key. Values are compared
+// by their JSON encoding so numbers parsed from YAML match stored ones.
+func diffProperties(old, next map[string]any) []domain.FieldChange {
+	keys := make(map[string]bool, len(old)+len(next))
+	for k := range old {
+		keys[k] = true
+	}
+	for k := range next {
+		keys[k] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var fields []domain.FieldChange
+	for _, k := range sorted {
+		oldValue, newValue := old[k], next[k]
+		oldJSON, _ := json.Marshal(oldValue)
+		newJSON, _ := json.Marshal(newValue)
+		if !bytes.Equal(oldJSON, newJSON) {
+			fields = append(fields, domain.FieldChange{Field: "properties." + k, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	return fields
+}
+
+// sortDiff orders every list by ID so diffs are stable
+func sortDiff(d *domain.GraphDiff) {
+	byNodeID := func(a, b domain.Node) int { return strings.Compare(a.ID, b.ID) }
+	byEdgeID := func(a, b domain.Edge) int { return strings.Compare(a.ID, b.ID) }
+	byChangeID := func(a, b domain.ObjectChange) int { return strings.Compare(a.ID, b.ID) }
+	slices.SortFunc(d.NodesAdded, byNodeID)
+	slices.SortFunc(d.NodesRemoved, byNodeID)
+	slices.SortFunc(d.NodesChanged, byChangeID)
+	slices.SortFunc(d.EdgesAdded, byEdgeID)
+	slices.SortFunc(d.EdgesRemoved, byEdgeID)
+	slices.SortFunc(d.EdgesChanged, byChangeID)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"specularium/internal/domain"
+)
+
+// EdgeTypeSuggestion is the edge type suggested for linking two nodes
+type EdgeTypeSuggestion struct {
+	FromID string          `json:"from_id"`
+	ToID   string          `json:"to_id"`
+	Type   domain.EdgeType `json:"type"`
+	Reason string          `json:"reason"`
+}
+
+// SuggestEdgeType suggests an edge type for connecting two nodes from
+// their types, parentage and segmentum. It does not create the edge.
+//
+//   - a child node (interface, VM, container) and its parent: virtual
+//   - a node and a synthetic subnet node: ethernet, as subnet inference links them
+//   - nodes in the same segmentum: ethernet
+//   - nodes in different segmenta: aggregation
+//   - two switches or routers with no segmentum: aggregation
+//   - anything else: ethernet
+func (s *GraphService) SuggestEdgeType(ctx context.Context, fromID, toID string) (*EdgeTypeSuggestion, error) {
+	if fromID == "" || toID == "" {
+		return nil, fmt.Errorf("invalid request: from_id and to_id are required")
+	}
+	if fromID == toID {
+		return nil, fmt.Errorf("invalid request: from_id and to_id must differ")
+	}
+
+	from, err := s.liveNode(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.liveNode(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	edgeType, reason := suggestEdgeType(from, to)
+	return &EdgeTypeSuggestion{FromID: fromID, ToID: toID, Type: edgeType, Reason: reason}, nil
+}
+
+// suggestEdgeType applies the SuggestEdgeType heuristic
+func suggestEdgeType(from, to *domain.Node) (domain.EdgeType, string) {
+	if from.ParentID == to.ID || to.ParentID == from.ID {
+		return domain.EdgeTypeVirtual, "child node and its parent"
+	}
+	if from.Type == domain.NodeTypeSubnet || to.Type == domain.NodeTypeSubnet {
+		return domain.EdgeTypeEthernet, "subnet membership"
+	}
+
+	fromNet, fromOK := nodeSegmentum(from)
+	toNet, toOK := nodeSegmentum(to)
+	switch {
+	case fromOK && toOK && fromNet == toNet:
+		return domain.EdgeTypeEthernet, "same segmentum " + fromNet.String()
+	case fromOK && toOK:
+		return domain.EdgeTypeAggregation, fmt.Sprintf("crosses segmenta %s and %s", fromNet, toNet)
+	case isNetworkDevice(from) && isNetworkDevice(to):
+		return domain.EdgeTypeAggregation, "link between network devices"
+	}
+	return domain.EdgeTypeEthernet, "default link"
+}
+
+// nodeSegmentum returns the node's segmentum with host bits cleared
+func nodeSegmentum(node *domain.Node) (netip.Prefix, bool) {
+	prefix, err := netip.ParsePrefix(node.GetPropertyString("segmentum"))
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	return prefix.Masked(), true
+}
+
+// isNetworkDevice reports whether a node forwards traffic for others
+func isNetworkDevice(node *domain.Node) bool {
+	return node.Type == domain.NodeTypeSwitch || node.Type == domain.NodeTypeRouter
+}
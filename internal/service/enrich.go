@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"specularium/internal/domain"
+)
+
+// DefaultEnrichmentStopConfidence stops the pipeline once a hostname is this certain
+const DefaultEnrichmentStopConfidence = 0.9
+
+// DefaultEnrichmentConcurrency caps nodes enriched at once after a scan
+const DefaultEnrichmentConcurrency = 8
+
+// Enricher is a single first-contact enrichment step.
+// Each step sees the node's discovered data and hostname inference as left by
+// earlier steps, and may add to both.
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error
+}
+
+// EnrichmentPipeline runs ordered enrichers once on a newly discovered node
+type EnrichmentPipeline struct {
+	enrichers      []Enricher
+	stopConfidence float64
+}
+
+// NewEnrichmentPipeline creates a pipeline running enrichers in the given order.
+// A stopConfidence of 0 uses DefaultEnrichmentStopConfidence.
+func NewEnrichmentPipeline(enrichers []Enricher, stopConfidence float64) *EnrichmentPipeline {
+	if stopConfidence <= 0 {
+		stopConfidence = DefaultEnrichmentStopConfidence
+	}
+	return &EnrichmentPipeline{
+		enrichers:      enrichers,
+		stopConfidence: stopConfidence,
+	}
+}
+
+// Steps returns the names of the configured steps in order
+func (p *EnrichmentPipeline) Steps() []string {
+	names := make([]string, len(p.enrichers))
+	for i, e := range p.enrichers {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// Run executes the enrichers in order, stopping early once the best hostname
+// candidate reaches the stop confidence. Step failures are logged and skipped.
+// The resulting inference and the steps that ran are recorded on the node.
+func (p *EnrichmentPipeline) Run(ctx context.Context, node *domain.Node) {
+	inference := extractHostnameInference(node.Discovered)
+	if inference == nil {
+		inference = &domain.HostnameInference{}
+	}
+
+	ran := []string{}
+	for _, e := range p.enrichers {
+		if inference.GetBestConfidence() >= p.stopConfidence {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		ran = append(ran, e.Name())
+		if err := e.Enrich(ctx, node, inference); err != nil {
+			log.Printf("Enrichment step %s failed for %s: %v", e.Name(), node.ID, err)
+		}
+	}
+
+	if len(inference.Candidates) > 0 {
+		node.SetDiscovered("hostname_inference", *inference)
+	}
+	node.SetDiscovered("enrichment_steps", ran)
+}
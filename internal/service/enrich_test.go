@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// fakeEnricher records invocations and optionally adds a hostname candidate
+type fakeEnricher struct {
+	name     string
+	hostname string
+	source   domain.ConfidenceSource
+	err      error
+	calls    *[]string
+	sawBest  string
+}
+
+func (f *fakeEnricher) Name() string { return f.name }
+
+func (f *fakeEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	*f.calls = append(*f.calls, f.name)
+	f.sawBest = inference.GetBestHostname()
+	if f.err != nil {
+		return f.err
+	}
+	inference.AddCandidate(f.hostname, f.source, time.Now())
+	return nil
+}
+
+func TestEnrichmentPipelineRun(t *testing.T) {
+	t.Run("runs steps in order and feeds inference forward", func(t *testing.T) {
+		var calls []string
+		banner := &fakeEnricher{name: "banner", hostname: "host.lan", source: domain.SourceSSHBanner, calls: &calls}
+		title := &fakeEnricher{name: "http_title", calls: &calls}
+		p := NewEnrichmentPipeline([]Enricher{banner, title}, 0)
+
+		node := domain.NewNode("n1", domain.NodeTypeServer, "n1")
+		p.Run(context.Background(), node)
+
+		if len(calls) != 2 || calls[0] != "banner" || calls[1] != "http_title" {
+			t.Errorf("calls = %v, want [banner http_title]", calls)
+		}
+		if title.sawBest != "host.lan" {
+			t.Errorf("second step saw best %q, want host.lan", title.sawBest)
+		}
+		inference := extractHostnameInference(node.Discovered)
+		if inference == nil || inference.GetBestHostname() != "host.lan" {
+			t.Errorf("hostname_inference = %+v, want best host.lan", inference)
+		}
+	})
+
+	t.Run("stops early on a confident hostname", func(t *testing.T) {
+		var calls []string
+		ptr := &fakeEnricher{name: "ptr", hostname: "db.lan", source: domain.SourcePTR, calls: &calls}
+		banner := &fakeEnricher{name: "banner", hostname: "x.lan", source: domain.SourceSSHBanner, calls: &calls}
+		p := NewEnrichmentPipeline([]Enricher{ptr, banner}, 0.9)
+
+		node := domain.NewNode("n2", domain.NodeTypeServer, "n2")
+		p.Run(context.Background(), node)
+
+		if len(calls) != 1 || calls[0] != "ptr" {
+			t.Errorf("calls = %v, want [ptr]", calls)
+		}
+		steps, _ := node.GetDiscovered("enrichment_steps")
+		if got, ok := steps.([]string); !ok || len(got) != 1 {
+			t.Errorf("enrichment_steps = %v, want [ptr]", steps)
+		}
+	})
+
+	t.Run("continues past failing steps", func(t *testing.T) {
+		var calls []string
+		broken := &fakeEnricher{name: "tls_cert", err: errors.New("refused"), calls: &calls}
+		title := &fakeEnricher{name: "http_title", hostname: "nas.lan", source: domain.SourceHTTPTitle, calls: &calls}
+		p := NewEnrichmentPipeline([]Enricher{broken, title}, 0)
+
+		node := domain.NewNode("n3", domain.NodeTypeServer, "n3")
+		p.Run(context.Background(), node)
+
+		if len(calls) != 2 {
+			t.Errorf("calls = %v, want both steps", calls)
+		}
+	})
+}
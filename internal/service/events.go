@@ -1,5 +1,14 @@
 package service
 
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"specularium/internal/metrics"
+)
+
 // EventType defines the type of event
 type EventType string
 
@@ -17,11 +26,15 @@ const (
 	EventNodeCreated      EventType = "node-created"
 	EventNodeUpdated      EventType = "node-updated"
 	EventNodeDeleted      EventType = "node-deleted"
+	EventNodeMerged       EventType = "node-merged"
+	EventNodeStale        EventType = "node-stale"
+	EventNodeUnreachable  EventType = "node-unreachable" // status changed to unreachable
 	EventEdgeCreated      EventType = "edge-created"
 	EventEdgeUpdated      EventType = "edge-updated"
 	EventEdgeDeleted      EventType = "edge-deleted"
 	EventPositionsUpdated EventType = "positions_updated"
 	EventGraphUpdated     EventType = "graph-updated"
+	EventReconcileBatch   EventType = "reconcile-batch" // debounced node and edge changes
 
 	// Discovery events
 	EventDiscoveryStarted  EventType = "discovery-started"
@@ -29,42 +42,267 @@ const (
 	EventDiscoveryComplete EventType = "discovery-complete"
 
 	// Truth events
-	EventTruthSet            EventType = "truth-set"
-	EventTruthCleared        EventType = "truth-cleared"
-	EventDiscrepancyCreated  EventType = "discrepancy-created"
-	EventDiscrepancyResolved EventType = "discrepancy-resolved"
+	EventTruthSet             EventType = "truth-set"
+	EventTruthCleared         EventType = "truth-cleared"
+	EventDiscrepancyCreated   EventType = "discrepancy-created"
+	EventDiscrepancyResolved  EventType = "discrepancy-resolved"
+	EventDiscrepancyDismissed EventType = "discrepancy-dismissed"
+
+	// Secret events
+	EventSecretExpiring EventType = "secret-expiring" // due for rotation within the warning window
 )
 
-// Event represents an event that occurred in the system
+// Event represents an event that occurred in the system.
+// Seq and Timestamp are assigned by the bus at publish time.
 type Event struct {
-	Type    EventType   `json:"type"`
-	Payload interface{} `json:"payload,omitempty"`
+	Seq       uint64      `json:"seq"`
+	Timestamp time.Time   `json:"timestamp"`
+	Type      EventType   `json:"type"`
+	Payload   interface{} `json:"payload,omitempty"`
+}
+
+// SequenceID returns the event's sequence number; the SSE hub uses it
+// for the id: field so clients can resume with Last-Event-ID
+func (e Event) SequenceID() uint64 {
+	return e.Seq
+}
+
+// EventTypeName returns the event type; the SSE hub uses it to filter
+// clients subscribed to specific types
+func (e Event) EventTypeName() string {
+	return string(e.Type)
+}
+
+// EventFilter selects the events a subscriber receives
+type EventFilter func(Event) bool
+
+// MatchTypes returns a filter accepting only the given event types
+func MatchTypes(types ...EventType) EventFilter {
+	set := make(map[EventType]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(e Event) bool { return set[e.Type] }
+}
+
+// OverflowPolicy decides what Publish does when a subscriber's channel
+// is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropNewest discards the event being published (the default)
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+	// OverflowDropOldest discards the oldest queued event to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowBlock waits until the subscriber has room
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// ParseOverflowPolicy validates an overflow policy name. An empty name
+// selects OverflowDropNewest.
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(name); policy {
+	case "":
+		return OverflowDropNewest, nil
+	case OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid overflow policy %q: must be drop-newest, drop-oldest or block", name)
 }
 
-// EventBus allows publishing and subscribing to events
+// subscriber is a channel with an optional filter
+type subscriber struct {
+	ch     chan Event
+	filter EventFilter
+}
+
+// EventBus allows publishing and subscribing to events. It optionally
+// retains the most recent events in a ring buffer for replay.
 type EventBus struct {
-	subscribers []chan<- Event
+	mu          sync.RWMutex
+	subscribers []subscriber
+	seq         uint64
+	policy      OverflowPolicy
+
+	history []Event // ring buffer, nil when history is disabled
+	next    int     // index of the next slot to write
+	count   int     // number of retained events
+
+	dropped      atomic.Uint64
+	droppedCount *metrics.Counter
 }
 
 // NewEventBus creates a new event bus
 func NewEventBus() *EventBus {
 	return &EventBus{
-		subscribers: make([]chan<- Event, 0),
+		subscribers: make([]subscriber, 0),
+		policy:      OverflowDropNewest,
 	}
 }
 
-// Subscribe adds a subscriber to receive events
-func (eb *EventBus) Subscribe(ch chan<- Event) {
-	eb.subscribers = append(eb.subscribers, ch)
+// SetOverflowPolicy sets what Publish does when a subscriber falls behind.
+// Under OverflowBlock a stalled subscriber stalls every publisher, so
+// subscribers must drain their channels promptly; the SSE forwarder does,
+// as the hub skips slow clients instead of waiting on them.
+func (eb *EventBus) SetOverflowPolicy(policy OverflowPolicy) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.policy = policy
 }
 
-// Publish sends an event to all subscribers
+// RegisterMetrics adds the dropped event counter to reg
+func (eb *EventBus) RegisterMetrics(reg *metrics.Registry) {
+	counter := reg.NewCounter("specularium_events_dropped_total",
+		"Events dropped because a subscriber's buffer was full.", "type")
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.droppedCount = counter
+}
+
+// Dropped returns how many events subscribers have missed because their
+// buffers were full
+func (eb *EventBus) Dropped() uint64 {
+	return eb.dropped.Load()
+}
+
+// SetHistorySize sets how many recent events are retained for replay.
+// Zero disables history. Existing events are kept, newest first, up to
+// the new size.
+func (eb *EventBus) SetHistorySize(size int) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+
+	retained := eb.historyLocked(size)
+	if size <= 0 {
+		eb.history, eb.next, eb.count = nil, 0, 0
+		return
+	}
+	eb.history = make([]Event, size)
+	copy(eb.history, retained)
+	eb.count = len(retained)
+	eb.next = eb.count % size
+}
+
+// Subscribe adds a subscriber to receive events. With a filter, only
+// events it accepts are sent; without one the subscriber gets everything.
+func (eb *EventBus) Subscribe(ch chan Event, filter ...EventFilter) {
+	sub := subscriber{ch: ch}
+	if len(filter) > 0 {
+		sub.filter = filter[0]
+	}
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.subscribers = append(eb.subscribers, sub)
+}
+
+// Publish stamps the event with the next sequence number and the current
+// time, records it in history and sends it to all subscribers, applying
+// the overflow policy to any whose channel is full
 func (eb *EventBus) Publish(event Event) {
-	for _, ch := range eb.subscribers {
+	eb.mu.Lock()
+	eb.seq++
+	event.Seq = eb.seq
+	event.Timestamp = time.Now().UTC()
+	if len(eb.history) > 0 {
+		eb.history[eb.next] = event
+		eb.next = (eb.next + 1) % len(eb.history)
+		if eb.count < len(eb.history) {
+			eb.count++
+		}
+	}
+	subscribers := eb.subscribers
+	policy := eb.policy
+	eb.mu.Unlock()
+
+	for _, sub := range subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		eb.deliver(sub.ch, event, policy)
+	}
+}
+
+// deliver sends event to ch, handling a full channel according to policy
+func (eb *EventBus) deliver(ch chan Event, event Event, policy OverflowPolicy) {
+	switch policy {
+	case OverflowBlock:
+		ch <- event
+	case OverflowDropOldest:
+		for {
+			select {
+			case ch <- event:
+				return
+			default:
+			}
+			// Make room; the subscriber may have drained it meanwhile
+			select {
+			case oldest := <-ch:
+				eb.recordDrop(oldest)
+			default:
+			}
+		}
+	default:
 		select {
 		case ch <- event:
 		default:
-			// Subscriber is slow, skip
+			eb.recordDrop(event)
 		}
 	}
 }
+
+// recordDrop counts an event a subscriber missed
+func (eb *EventBus) recordDrop(event Event) {
+	eb.dropped.Add(1)
+	eb.mu.RLock()
+	counter := eb.droppedCount
+	eb.mu.RUnlock()
+	if counter != nil {
+		counter.Inc(string(event.Type))
+	}
+}
+
+// History returns up to limit of the most recent events, oldest first.
+// A limit of zero or less returns everything retained.
+func (eb *EventBus) History(limit int) []Event {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	return eb.historyLocked(limit)
+}
+
+// Since returns the retained events published after seq, oldest first.
+// ok is false when events after seq have already dropped out of the
+// history window (or seq is from a previous run), in which case the
+// caller cannot catch up incrementally and should refetch state.
+func (eb *EventBus) Since(seq uint64) (events []Event, ok bool) {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	if seq > eb.seq {
+		return nil, false
+	}
+	if seq == eb.seq {
+		return nil, true
+	}
+
+	retained := eb.historyLocked(0)
+	if len(retained) == 0 || retained[0].Seq > seq+1 {
+		return nil, false
+	}
+	return retained[seq+1-retained[0].Seq:], true
+}
+
+// historyLocked returns the newest limit events in publish order.
+// Caller must hold eb.mu.
+func (eb *EventBus) historyLocked(limit int) []Event {
+	n := eb.count
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	events := make([]Event, n)
+	size := len(eb.history)
+	for i := 0; i < n; i++ {
+		events[i] = eb.history[(eb.next-n+i+size)%size]
+	}
+	return events
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"sort"
+
+	"specularium/internal/domain"
+)
+
+// DefaultCollapseThreshold is the member count above which a subnet's
+// member edges are collapsed when no explicit threshold is given
+const DefaultCollapseThreshold = 16
+
+// FabricSource marks edges synthesized by CollapseSubnetEdges
+const FabricSource = "fabric"
+
+// CollapseSubnetEdges replaces the member edges of each subnet node with
+// more than threshold members by one aggregation edge carrying
+// member_count and the sorted member IDs. The edge runs from the subnet
+// node to its uplink member (a router, then a switch) or loops back to the
+// subnet node when there is none. Subnets listed in expand are left as is.
+// Only the returned view changes; nothing is persisted.
+func CollapseSubnetEdges(graph *domain.Graph, threshold int, expand map[string]bool) *domain.Graph {
+	nodes := make(map[string]*domain.Node, len(graph.Nodes))
+	for i := range graph.Nodes {
+		nodes[graph.Nodes[i].ID] = &graph.Nodes[i]
+	}
+
+	// Group ethernet edges linking a subnet node to a non-subnet member
+	members := make(map[string][]int)
+	for i, edge := range graph.Edges {
+		if subnetID, _, ok := subnetMemberEdge(edge, nodes); ok {
+			members[subnetID] = append(members[subnetID], i)
+		}
+	}
+
+	collapsed := make(map[int]bool)
+	var fabric []domain.Edge
+	subnetIDs := make([]string, 0, len(members))
+	for subnetID := range members {
+		subnetIDs = append(subnetIDs, subnetID)
+	}
+	sort.Strings(subnetIDs)
+
+	for _, subnetID := range subnetIDs {
+		indexes := members[subnetID]
+		if len(indexes) <= threshold || expand[subnetID] {
+			continue
+		}
+
+		memberIDs := make([]string, 0, len(indexes))
+		for _, i := range indexes {
+			collapsed[i] = true
+			_, memberID, _ := subnetMemberEdge(graph.Edges[i], nodes)
+			memberIDs = append(memberIDs, memberID)
+		}
+		sort.Strings(memberIDs)
+
+		edge := domain.Edge{
+			ID:     "fabric-" + subnetID,
+			FromID: subnetID,
+			ToID:   subnetUplink(memberIDs, nodes, subnetID),
+			Type:   domain.EdgeTypeAggregation,
+			Properties: map[string]any{
+				"source":       FabricSource,
+				"collapsed":    true,
+				"member_count": len(memberIDs),
+				"members":      memberIDs,
+			},
+		}
+		fabric = append(fabric, edge)
+	}
+
+	if len(collapsed) == 0 {
+		return graph
+	}
+
+	view := *graph
+	view.Edges = make([]domain.Edge, 0, len(graph.Edges)-len(collapsed)+len(fabric))
+	for i, edge := range graph.Edges {
+		if !collapsed[i] {
+			view.Edges = append(view.Edges, edge)
+		}
+	}
+	view.Edges = append(view.Edges, fabric...)
+	return &view
+}
+
+// subnetMemberEdge reports whether edge is an ethernet edge between a
+// subnet node and a member, returning both IDs
+func subnetMemberEdge(edge domain.Edge, nodes map[string]*domain.Node) (subnetID, memberID string, ok bool) {
+	if edge.Type != domain.EdgeTypeEthernet {
+		return "", "", false
+	}
+	from, to := nodes[edge.FromID], nodes[edge.ToID]
+	if from == nil || to == nil {
+		return "", "", false
+	}
+	switch {
+	case to.Type == domain.NodeTypeSubnet && from.Type != domain.NodeTypeSubnet:
+		return to.ID, from.ID, true
+	case from.Type == domain.NodeTypeSubnet && to.Type != domain.NodeTypeSubnet:
+		return from.ID, to.ID, true
+	}
+	return "", "", false
+}
+
+// subnetUplink picks the member a collapsed subnet attaches through
+func subnetUplink(memberIDs []string, nodes map[string]*domain.Node, subnetID string) string {
+	for _, nodeType := range []domain.NodeType{domain.NodeTypeRouter, domain.NodeTypeSwitch} {
+		for _, id := range memberIDs {
+			if nodes[id].Type == nodeType {
+				return id
+			}
+		}
+	}
+	return subnetID
+}
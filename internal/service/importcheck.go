@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ImportIssue is a problem found while validating an import
+type ImportIssue struct {
+	// Kind is parse, node or edge
+	Kind    string `json:"kind"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportValidation is the outcome of checking an import without applying it
+type ImportValidation struct {
+	Valid  bool          `json:"valid"`
+	Nodes  int           `json:"nodes"`
+	Edges  int           `json:"edges"`
+	Issues []ImportIssue `json:"issues"`
+}
+
+// ValidateImport parses data in format and checks the result for duplicate
+// IDs, unknown node and edge types, and edges referencing nodes found
+// neither in the import nor in the current graph. Nothing is written. A
+// parse failure is reported as an issue; an unsupported format is an error.
+func (s *GraphService) ValidateImport(ctx context.Context, format string, data []byte) (*ImportValidation, error) {
+	result := &ImportValidation{Issues: []ImportIssue{}}
+
+	fragment, err := ParseImport(format, data)
+	if err != nil {
+		if strings.Contains(err.Error(), "unsupported import format") {
+			return nil, err
+		}
+		result.Issues = append(result.Issues, ImportIssue{Kind: "parse", Message: err.Error()})
+		return result, nil
+	}
+	result.Nodes = len(fragment.Nodes)
+	result.Edges = len(fragment.Edges)
+
+	nodeIDs := make(map[string]bool, len(fragment.Nodes))
+	for _, node := range fragment.Nodes {
+		switch {
+		case node.ID == "":
+			result.Issues = append(result.Issues, ImportIssue{Kind: "node", Message: fmt.Sprintf("node %q has no id", node.Label)})
+			continue
+		case nodeIDs[node.ID]:
+			result.Issues = append(result.Issues, ImportIssue{Kind: "node", ID: node.ID, Message: "duplicate node id"})
+		}
+		nodeIDs[node.ID] = true
+		if !node.Type.IsKnown() {
+			result.Issues = append(result.Issues, ImportIssue{Kind: "node", ID: node.ID, Message: fmt.Sprintf("unknown node type %q", node.Type)})
+		}
+	}
+
+	// Endpoints missing from the import are looked up in the graph once each
+	existing := make(map[string]bool)
+	exists := func(id string) (bool, error) {
+		if nodeIDs[id] {
+			return true, nil
+		}
+		if found, ok := existing[id]; ok {
+			return found, nil
+		}
+		node, err := s.repo.GetNode(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		existing[id] = node != nil && node.DeletedAt == nil
+		return existing[id], nil
+	}
+
+	edgeIDs := make(map[string]bool, len(fragment.Edges))
+	for _, edge := range fragment.Edges {
+		id := edge.ID
+		if id == "" {
+			id = edge.GenerateID()
+		}
+		if edgeIDs[id] {
+			result.Issues = append(result.Issues, ImportIssue{Kind: "edge", ID: id, Message: "duplicate edge id"})
+		}
+		edgeIDs[id] = true
+		if !edge.Type.IsKnown() {
+			result.Issues = append(result.Issues, ImportIssue{Kind: "edge", ID: id, Message: fmt.Sprintf("unknown edge type %q", edge.Type)})
+		}
+		for _, endpoint := range []struct{ field, id string }{{"from_id", edge.FromID}, {"to_id", edge.ToID}} {
+			if endpoint.id == "" {
+				result.Issues = append(result.Issues, ImportIssue{Kind: "edge", ID: id, Message: endpoint.field + " is empty"})
+				continue
+			}
+			found, err := exists(endpoint.id)
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				result.Issues = append(result.Issues, ImportIssue{
+					Kind:    "edge",
+					ID:      id,
+					Message: fmt.Sprintf("%s references node %s, which is not in the import or the graph", endpoint.field, endpoint.id),
+				})
+			}
+		}
+	}
+
+	result.Valid = len(result.Issues) == 0
+	return result, nil
+}
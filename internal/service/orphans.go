@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+
+	"specularium/internal/domain"
+)
+
+// OrphanReport lists graph elements that are disconnected or broken
+type OrphanReport struct {
+	// Nodes have no edges and no segmentum subnet inference could link them by
+	Nodes []domain.Node `json:"nodes"`
+	// Inferable have no edges yet, but InferSubnetEdges would link them
+	Inferable []string `json:"inferable"`
+	// DanglingEdges reference a node that no longer exists
+	DanglingEdges []domain.Edge `json:"dangling_edges"`
+}
+
+// FindOrphans reports live nodes with no live edges and edges whose
+// endpoints are missing. Edgeless nodes with a valid segmentum are listed
+// as inferable rather than orphaned, since subnet inference links them.
+func (s *GraphService) FindOrphans(ctx context.Context) (*OrphanReport, error) {
+	nodes, err := s.repo.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	edges, err := s.repo.ListEdges(ctx, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	dangling, err := s.repo.ListDanglingEdges(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	linked := make(map[string]bool, len(nodes))
+	for _, edge := range edges {
+		linked[edge.FromID] = true
+		linked[edge.ToID] = true
+	}
+
+	report := &OrphanReport{
+		Nodes:         []domain.Node{},
+		Inferable:     []string{},
+		DanglingEdges: dangling,
+	}
+	for _, node := range nodes {
+		if linked[node.ID] {
+			continue
+		}
+		if _, err := netip.ParsePrefix(node.GetPropertyString("segmentum")); err == nil && node.Type != domain.NodeTypeSubnet {
+			report.Inferable = append(report.Inferable, node.ID)
+			continue
+		}
+		report.Nodes = append(report.Nodes, node)
+	}
+
+	return report, nil
+}
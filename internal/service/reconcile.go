@@ -3,7 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"sync"
 	"time"
 
 	"specularium/internal/domain"
@@ -13,8 +17,17 @@ import (
 type ReconcileRepository interface {
 	GetNode(ctx context.Context, id string) (*domain.Node, error)
 	UpdateNodeVerification(ctx context.Context, id string, status domain.NodeStatus, lastVerified, lastSeen *time.Time, discovered map[string]any) error
+	UpdateNodeDiscoveredSources(ctx context.Context, id string, sources map[string]domain.DiscoveredSource) error
 	UpdateNodeLabel(ctx context.Context, id string, label string) error
 	HasOperatorTruthHostname(ctx context.Context, nodeID string) (bool, error)
+	UpsertNode(ctx context.Context, node *domain.Node) error
+	GetNodeByMAC(ctx context.Context, mac string) (*domain.Node, error)
+	GetEdge(ctx context.Context, id string) (*domain.Edge, error)
+	UpsertEdge(ctx context.Context, edge *domain.Edge) error
+	ListNodes(ctx context.Context, nodeType, source string) ([]domain.Node, error)
+	AddTag(ctx context.Context, nodeID, tag string) error
+	MergeNodeDiscovered(ctx context.Context, nodeID, source string, values map[string]any) error
+	RestoreNode(ctx context.Context, id string) error
 }
 
 // ReconcileService handles reconciliation of adapter discoveries
@@ -22,26 +35,337 @@ type ReconcileService struct {
 	repo     ReconcileRepository
 	truthSvc *TruthService
 	eventBus *EventBus
+	pipeline *EnrichmentPipeline
+	logger   *slog.Logger
+
+	// creators are sources whose unknown nodes are created rather than skipped
+	creators map[string]bool
+
+	// priorities rank sources; a discovered property set by a higher
+	// priority source is not overwritten by a lower one
+	priorities map[string]int
+
+	// allow and deny are glob patterns selecting the discovered properties
+	// that count as changes and are checked against truth; the rest are
+	// stored without either
+	allow, deny []string
+
+	// debounce coalesces node and edge events into one reconcile-batch
+	// event per window; zero publishes each change as it happens
+	debounce time.Duration
+	batchMu  sync.Mutex
+	batch    *pendingBatch
+}
+
+// ReconcileBatch is the payload of a reconcile-batch event: everything
+// reconcile changed during one debounce window
+type ReconcileBatch struct {
+	Sources []string `json:"sources"`
+	NodeIDs []string `json:"node_ids"`
+	EdgeIDs []string `json:"edge_ids"`
 }
 
+// pendingBatch collects changes until the debounce window closes
+type pendingBatch struct {
+	sources, nodes, edges map[string]bool
+}
+
+// DefaultSourcePriorities ranks discovery sources from most to least
+// trusted. Sources not listed have priority 0.
+var DefaultSourcePriorities = map[string]int{
+	"operator": 100,
+	"snmp":     80,
+	"nmap":     60,
+	"scanner":  40,
+	"verifier": 20,
+}
+
+// DefaultReconcileDeny lists the discovered properties left out of change
+// detection and discrepancy checks unless configured otherwise: latencies
+// and timestamps differ on every probe
+var DefaultReconcileDeny = []string{"*latency*", "*_at", "last_seen", "last_verified"}
+
 // NewReconcileService creates a new reconcile service
 func NewReconcileService(repo ReconcileRepository, truthSvc *TruthService, eventBus *EventBus) *ReconcileService {
 	return &ReconcileService{
-		repo:     repo,
-		truthSvc: truthSvc,
-		eventBus: eventBus,
+		repo:       repo,
+		truthSvc:   truthSvc,
+		eventBus:   eventBus,
+		logger:     slog.Default(),
+		priorities: maps.Clone(DefaultSourcePriorities),
+		deny:       slices.Clone(DefaultReconcileDeny),
+	}
+}
+
+// SetPropertyFilter selects which discovered properties take part in
+// reconciliation: a property participates when it matches an allow
+// pattern, or allow is empty, and matches no deny pattern. Patterns use
+// path.Match syntax ("*_latency_ms"). A nil deny keeps
+// DefaultReconcileDeny; an empty one denies nothing. Other properties are
+// still stored, but changes to them alone raise no node-updated event and
+// they are never compared against operator truth.
+func (r *ReconcileService) SetPropertyFilter(allow, deny []string) error {
+	if deny == nil {
+		deny = DefaultReconcileDeny
+	}
+	for _, pattern := range append(slices.Clone(allow), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid property pattern %q: %w", pattern, err)
+		}
+	}
+	r.allow = slices.Clone(allow)
+	r.deny = slices.Clone(deny)
+	return nil
+}
+
+// participates reports whether discovered property key passes the
+// property filter
+func (r *ReconcileService) participates(key string) bool {
+	if matchesAny(r.deny, key) {
+		return false
+	}
+	return len(r.allow) == 0 || matchesAny(r.allow, key)
+}
+
+// participating returns the entries of discovered that pass the property
+// filter
+func (r *ReconcileService) participating(discovered map[string]any) map[string]any {
+	filtered := make(map[string]any, len(discovered))
+	for key, value := range discovered {
+		if r.participates(key) {
+			filtered[key] = value
+		}
+	}
+	return filtered
+}
+
+// matchesAny reports whether key matches one of the glob patterns
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSourcePriorities overrides the priority of the given sources, keeping
+// the defaults for the rest
+func (r *ReconcileService) SetSourcePriorities(priorities map[string]int) {
+	for source, priority := range priorities {
+		r.priorities[source] = priority
+	}
+}
+
+// stampDiscovered records source as the provenance of every discovered
+// property on a node about to be created
+func (r *ReconcileService) stampDiscovered(source string, node *domain.Node) {
+	for key, value := range node.Discovered {
+		node.SetDiscoveredFrom(source, key, value, r.priorities[source])
+	}
+}
+
+// mergeDiscovered folds incoming discovered properties from source into a
+// copy of the existing node's, keeping values set by higher-priority
+// sources. Properties source reported before but no longer does are
+// dropped, as are properties with no recorded source. Returns the merged
+// node and the incoming properties that were accepted.
+func (r *ReconcileService) mergeDiscovered(source string, existing, incoming *domain.Node) (*domain.Node, map[string]any) {
+	merged := &domain.Node{
+		Discovered:        maps.Clone(existing.Discovered),
+		DiscoveredSources: maps.Clone(existing.DiscoveredSources),
+	}
+	for key := range merged.Discovered {
+		if _, reported := incoming.Discovered[key]; reported {
+			continue
+		}
+		if prev, ok := merged.DiscoveredSources[key]; !ok || prev.Source == source {
+			delete(merged.Discovered, key)
+			delete(merged.DiscoveredSources, key)
+		}
+	}
+
+	accepted := make(map[string]any, len(incoming.Discovered))
+	priority := r.priorities[source]
+	for key, value := range incoming.Discovered {
+		if merged.SetDiscoveredFrom(source, key, value, priority) {
+			accepted[key] = value
+			continue
+		}
+		r.logger.Debug("kept discovered value from higher-priority source",
+			"node_id", existing.ID, "key", key, "source", source,
+			"kept_source", merged.DiscoveredSources[key].Source)
+	}
+	return merged, accepted
+}
+
+// SetDebounce coalesces node-created, node-updated, edge-created and
+// edge-updated events raised within window into a single reconcile-batch
+// event, so a large scan does not flood SSE clients. Writes still happen
+// immediately. Zero or less publishes every change on its own.
+func (r *ReconcileService) SetDebounce(window time.Duration) {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+	r.debounce = window
+}
+
+// publishChange publishes a node or edge event, or adds it to the pending
+// batch when debouncing. The first change of a batch starts its window.
+func (r *ReconcileService) publishChange(source string, event Event) {
+	r.batchMu.Lock()
+	defer r.batchMu.Unlock()
+
+	if r.debounce <= 0 {
+		r.eventBus.Publish(event)
+		return
+	}
+	if r.batch == nil {
+		r.batch = &pendingBatch{sources: map[string]bool{}, nodes: map[string]bool{}, edges: map[string]bool{}}
+		time.AfterFunc(r.debounce, r.flushBatch)
+	}
+	r.batch.sources[source] = true
+	switch payload := event.Payload.(type) {
+	case *domain.Node:
+		r.batch.nodes[payload.ID] = true
+	case *domain.Edge:
+		r.batch.edges[payload.ID] = true
+	}
+}
+
+// flushBatch publishes the pending batch as one reconcile-batch event
+func (r *ReconcileService) flushBatch() {
+	r.batchMu.Lock()
+	batch := r.batch
+	r.batch = nil
+	r.batchMu.Unlock()
+
+	if batch == nil {
+		return
+	}
+	r.eventBus.Publish(Event{
+		Type: EventReconcileBatch,
+		Payload: &ReconcileBatch{
+			Sources: slices.Sorted(maps.Keys(batch.sources)),
+			NodeIDs: slices.Sorted(maps.Keys(batch.nodes)),
+			EdgeIDs: slices.Sorted(maps.Keys(batch.edges)),
+		},
+	})
+}
+
+// SetLogger sets the logger used for reconcile activity
+func (r *ReconcileService) SetLogger(logger *slog.Logger) {
+	r.logger = logger
+}
+
+// SetEnrichmentPipeline sets the first-contact pipeline run on newly discovered nodes
+func (r *ReconcileService) SetEnrichmentPipeline(p *EnrichmentPipeline) {
+	r.pipeline = p
+}
+
+// AllowNodeCreation lets fragments from source create nodes that are not yet
+// in the graph. Other sources only update existing nodes, so a verifier
+// racing a delete cannot resurrect the node.
+func (r *ReconcileService) AllowNodeCreation(source string) {
+	if r.creators == nil {
+		r.creators = make(map[string]bool)
+	}
+	r.creators[source] = true
+}
+
+// FirstContact runs the enrichment pipeline on a node that is about to be created.
+// It is a no-op when no pipeline is configured.
+func (r *ReconcileService) FirstContact(ctx context.Context, node *domain.Node) {
+	if r.pipeline == nil {
+		return
+	}
+	r.pipeline.Run(ctx, node)
+
+	// Use the enriched hostname as the label when discovery found one
+	if inference := extractHostnameInference(node.Discovered); inference != nil && inference.Best != nil {
+		if label := domain.ExtractShortName(inference.Best.Hostname); label != "" {
+			node.Label = label
+		}
+	}
+}
+
+// EnrichCreated runs first-contact enrichment on nodes that were created
+// without it, at most DefaultEnrichmentConcurrency at a time, and stores
+// what it finds. Creation then does not wait on slow enrichment steps.
+// It returns once every node is enriched or ctx is done.
+func (r *ReconcileService) EnrichCreated(ctx context.Context, source string, nodes []domain.Node) {
+	if r.pipeline == nil || len(nodes) == 0 {
+		return
+	}
+
+	sem := make(chan struct{}, DefaultEnrichmentConcurrency)
+	var wg sync.WaitGroup
+	for _, node := range nodes {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(node domain.Node) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := r.enrichCreated(ctx, source, node); err != nil {
+				r.logger.Error("failed to store enrichment", "source", source, "node_id", node.ID, "error", err)
+			}
+		}(node)
+	}
+	wg.Wait()
+}
+
+// enrichCreated enriches one created node and saves the discovered values
+// and label the pipeline changed
+func (r *ReconcileService) enrichCreated(ctx context.Context, source string, node domain.Node) error {
+	before := maps.Clone(node.Discovered)
+	label := node.Label
+	r.FirstContact(ctx, &node)
+
+	changed := make(map[string]any)
+	for key, value := range node.Discovered {
+		if old, ok := before[key]; !ok || !discoveredEqual(map[string]any{key: old}, map[string]any{key: value}) {
+			changed[key] = value
+		}
+	}
+	if err := r.repo.MergeNodeDiscovered(ctx, node.ID, "enrichment", changed); err != nil {
+		return err
+	}
+	if node.Label != label {
+		if hasOperatorHostname, _ := r.repo.HasOperatorTruthHostname(ctx, node.ID); !hasOperatorHostname {
+			if err := r.repo.UpdateNodeLabel(ctx, node.ID, node.Label); err != nil {
+				return err
+			}
+		}
 	}
+
+	stored, err := r.repo.GetNode(ctx, node.ID)
+	if err != nil || stored == nil {
+		return err
+	}
+	r.publishChange(source, Event{
+		Type:    EventNodeUpdated,
+		Payload: stored,
+	})
+	return nil
 }
 
 // ReconcileFragment reconciles adapter discoveries with existing nodes
-// Updates node status/discovered fields and checks for discrepancies
+// Updates node status/discovered fields and checks for discrepancies.
+// New child nodes (e.g. interfaces) of known parents are created, and edges
+// are upserted once both endpoints exist. Fragments carrying VLAN
+// membership refresh the vlan edges between interfaces.
 func (r *ReconcileService) ReconcileFragment(ctx context.Context, source string, fragment *domain.GraphFragment) error {
+	start := time.Now()
 	changedCount := 0
 
 	for _, node := range fragment.Nodes {
 		changed, err := r.reconcileNode(ctx, source, node)
 		if err != nil {
-			log.Printf("Failed to reconcile node %s: %v", node.ID, err)
+			r.logger.Error("failed to reconcile node", "source", source, "node_id", node.ID, "error", err)
 			continue
 		}
 		if changed {
@@ -49,13 +373,191 @@ func (r *ReconcileService) ReconcileFragment(ctx context.Context, source string,
 		}
 	}
 
+	for _, edge := range fragment.Edges {
+		changed, err := r.reconcileEdge(ctx, source, edge)
+		if err != nil {
+			r.logger.Error("failed to reconcile edge", "source", source, "edge_id", edge.ID, "error", err)
+			continue
+		}
+		if changed {
+			changedCount++
+		}
+	}
+
+	if slices.ContainsFunc(fragment.Nodes, func(n domain.Node) bool { return len(n.VLANs()) > 0 }) {
+		changed, err := r.syncVLANs(ctx)
+		if err != nil {
+			r.logger.Error("failed to sync VLAN edges", "source", source, "error", err)
+		}
+		changedCount += changed
+	}
+
+	level := slog.LevelDebug
 	if changedCount > 0 {
-		log.Printf("Reconciled %d changed nodes from %s", changedCount, source)
+		level = slog.LevelInfo
 	}
+	r.logger.Log(ctx, level, "reconciled fragment",
+		"source", source,
+		"nodes", len(fragment.Nodes),
+		"edges", len(fragment.Edges),
+		"changed", changedCount,
+		"duration", time.Since(start))
 
 	return nil
 }
 
+// createNode creates a newly discovered node after first-contact enrichment
+func (r *ReconcileService) createNode(ctx context.Context, source string, node domain.Node) (bool, error) {
+	r.FirstContact(ctx, &node)
+	if err := r.repo.UpsertNode(ctx, &node); err != nil {
+		return false, fmt.Errorf("create node: %w", err)
+	}
+
+	r.publishChange(source, Event{
+		Type:    EventNodeCreated,
+		Payload: &node,
+	})
+	return true, nil
+}
+
+// MergeByMAC folds a newly seen top-level node into an existing node with
+// the same discovered MAC address, so a DHCP lease change updates the
+// known node instead of creating a duplicate. The existing node keeps its
+// ID and label; its IP is replaced and the old one appended to the
+// ip_history property; a protected node keeps its properties and label and
+// only takes the discovered values. Returns false when there is nothing
+// to merge.
+func (r *ReconcileService) MergeByMAC(ctx context.Context, source string, node *domain.Node) (bool, error) {
+	mac := node.MACAddress()
+	if mac == "" || node.ParentID != "" {
+		return false, nil
+	}
+
+	existing, err := r.repo.GetNodeByMAC(ctx, mac)
+	if err != nil {
+		return false, fmt.Errorf("lookup by mac: %w", err)
+	}
+	if existing == nil || existing.ID == node.ID || existing.ParentID != "" {
+		return false, nil
+	}
+
+	merged := *existing
+	oldIP := existing.GetPropertyString("ip")
+	newIP := node.GetPropertyString("ip")
+
+	if !existing.Protected {
+		merged.Properties = make(map[string]any, len(existing.Properties)+len(node.Properties))
+		for k, v := range existing.Properties {
+			merged.Properties[k] = v
+		}
+		for k, v := range node.Properties {
+			merged.Properties[k] = v
+		}
+		if oldIP != "" && newIP != "" && oldIP != newIP {
+			history, _ := existing.Properties["ip_history"].([]any)
+			merged.Properties["ip_history"] = append(history, map[string]any{
+				"ip":          oldIP,
+				"replaced_at": time.Now().UTC().Format(time.RFC3339),
+			})
+			if merged.Label == oldIP {
+				merged.Label = node.Label
+			}
+		}
+		if node.Role != "" {
+			merged.Role = node.Role
+		}
+	}
+
+	merged.Discovered = maps.Clone(existing.Discovered)
+	merged.DiscoveredSources = maps.Clone(existing.DiscoveredSources)
+	for k, v := range node.Discovered {
+		merged.SetDiscoveredFrom(source, k, v, r.priorities[source])
+	}
+	merged.Status = node.Status
+	merged.LastVerified = node.LastVerified
+	merged.LastSeen = node.LastSeen
+
+	if err := r.repo.UpsertNode(ctx, &merged); err != nil {
+		return false, fmt.Errorf("merge node: %w", err)
+	}
+	r.logger.Info("merged node by MAC",
+		"node_id", merged.ID, "merged_id", node.ID, "mac", mac, "old_ip", oldIP, "ip", newIP)
+
+	if _, err := r.truthSvc.CheckDiscrepancies(ctx, merged.ID, r.participating(merged.Discovered), source); err != nil {
+		r.logger.Warn("failed to check discrepancies", "node_id", merged.ID, "error", err)
+	}
+
+	r.eventBus.Publish(Event{
+		Type: EventNodeMerged,
+		Payload: map[string]any{
+			"id":          merged.ID,
+			"merged_id":   node.ID,
+			"mac_address": mac,
+			"old_ip":      oldIP,
+			"new_ip":      newIP,
+			"node":        &merged,
+		},
+	})
+	return true, nil
+}
+
+// createChildNode creates a node discovered beneath an existing parent
+func (r *ReconcileService) createChildNode(ctx context.Context, source string, node domain.Node) (bool, error) {
+	parent, err := r.repo.GetNode(ctx, node.ParentID)
+	if err != nil {
+		return false, fmt.Errorf("get parent: %w", err)
+	}
+	if parent == nil {
+		r.logger.Debug("parent not found during reconcile", "node_id", node.ID, "parent_id", node.ParentID)
+		return false, nil
+	}
+
+	if err := r.repo.UpsertNode(ctx, &node); err != nil {
+		return false, fmt.Errorf("create node: %w", err)
+	}
+
+	r.publishChange(source, Event{
+		Type:    EventNodeCreated,
+		Payload: &node,
+	})
+	return true, nil
+}
+
+// reconcileEdge upserts an edge when both endpoints exist and it is new or changed
+func (r *ReconcileService) reconcileEdge(ctx context.Context, source string, edge domain.Edge) (bool, error) {
+	for _, id := range []string{edge.FromID, edge.ToID} {
+		endpoint, err := r.repo.GetNode(ctx, id)
+		if err != nil {
+			return false, fmt.Errorf("get endpoint: %w", err)
+		}
+		if endpoint == nil {
+			return false, nil
+		}
+	}
+
+	existing, err := r.repo.GetEdge(ctx, edge.ID)
+	if err != nil {
+		return false, fmt.Errorf("get edge: %w", err)
+	}
+	if existing != nil && existing.Type == edge.Type && discoveredEqual(existing.Properties, edge.Properties) {
+		return false, nil
+	}
+
+	if err := r.repo.UpsertEdge(ctx, &edge); err != nil {
+		return false, fmt.Errorf("upsert edge: %w", err)
+	}
+
+	eventType := EventEdgeCreated
+	if existing != nil {
+		eventType = EventEdgeUpdated
+	}
+	r.publishChange(source, Event{
+		Type:    eventType,
+		Payload: &edge,
+	})
+	return true, nil
+}
+
 // reconcileNode handles reconciliation of a single node
 func (r *ReconcileService) reconcileNode(ctx context.Context, source string, node domain.Node) (bool, error) {
 	// Get existing node to compare
@@ -64,45 +566,89 @@ func (r *ReconcileService) reconcileNode(ctx context.Context, source string, nod
 		return false, fmt.Errorf("get node: %w", err)
 	}
 	if existing == nil {
+		r.stampDiscovered(source, &node)
+		if node.ParentID != "" {
+			return r.createChildNode(ctx, source, node)
+		}
+		if merged, err := r.MergeByMAC(ctx, source, &node); err != nil || merged {
+			return merged, err
+		}
+		if r.creators[source] {
+			return r.createNode(ctx, source, node)
+		}
 		// Node doesn't exist (shouldn't happen for verifier, but handle it)
-		log.Printf("Node %s not found during verification reconcile", node.ID)
+		r.logger.Debug("node not found during reconcile", "source", source, "node_id", node.ID)
 		return false, nil
 	}
 
-	// Check if verification data actually changed
+	// A soft-deleted node that is seen again comes back with its history;
+	// one that is still unreachable stays deleted
+	revived := false
+	if existing.DeletedAt != nil {
+		if node.Status == domain.NodeStatusUnreachable {
+			return false, nil
+		}
+		if err := r.repo.RestoreNode(ctx, node.ID); err != nil {
+			return false, fmt.Errorf("revive node: %w", err)
+		}
+		existing.DeletedAt = nil
+		revived = true
+		r.logger.Info("revived deleted node", "source", source, "node_id", node.ID)
+	}
+
+	merged, accepted := r.mergeDiscovered(source, existing, &node)
+
+	// Check if verification data actually changed, ignoring properties
+	// outside the property filter
 	statusChanged := existing.Status != node.Status
-	discoveredChanged := !discoveredEqual(existing.Discovered, node.Discovered)
+	discoveredChanged := !discoveredEqual(r.participating(existing.Discovered), r.participating(merged.Discovered))
+	sourcesChanged := !maps.Equal(existing.DiscoveredSources, merged.DiscoveredSources)
 
-	if !statusChanged && !discoveredChanged {
-		// No changes, skip update and event
+	if !statusChanged && !discoveredChanged && !sourcesChanged && !revived {
+		// Only filtered properties such as latency moved: store them
+		// quietly, without an event
+		if !discoveredEqual(existing.Discovered, merged.Discovered) {
+			if err := r.repo.UpdateNodeVerification(ctx, node.ID, node.Status, node.LastVerified, node.LastSeen, merged.Discovered); err != nil {
+				return false, fmt.Errorf("update verification: %w", err)
+			}
+		}
 		return false, nil
 	}
 
 	// Update verification status
-	if err := r.repo.UpdateNodeVerification(ctx, node.ID, node.Status, node.LastVerified, node.LastSeen, node.Discovered); err != nil {
+	if err := r.repo.UpdateNodeVerification(ctx, node.ID, node.Status, node.LastVerified, node.LastSeen, merged.Discovered); err != nil {
 		return false, fmt.Errorf("update verification: %w", err)
 	}
+	if sourcesChanged {
+		if err := r.repo.UpdateNodeDiscoveredSources(ctx, node.ID, merged.DiscoveredSources); err != nil {
+			return false, fmt.Errorf("update discovered sources: %w", err)
+		}
+	}
 
 	// Check for discrepancies against operator truth
-	discrepancies, err := r.truthSvc.CheckDiscrepancies(ctx, node.ID, node.Discovered, source)
+	discrepancies, err := r.truthSvc.CheckDiscrepancies(ctx, node.ID, r.participating(accepted), source)
 	if err != nil {
-		log.Printf("Failed to check discrepancies for %s: %v", node.ID, err)
+		r.logger.Warn("failed to check discrepancies", "node_id", node.ID, "error", err)
 	} else if len(discrepancies) > 0 {
-		log.Printf("Node %s has %d new discrepancies with operator truth", node.ID, len(discrepancies))
+		r.logger.Info("new discrepancies with operator truth", "node_id", node.ID, "count", len(discrepancies))
 	}
 
-	// Auto-update label from hostname inference if no operator truth
-	if inference := extractHostnameInference(node.Discovered); inference != nil && inference.Best != nil {
+	// Auto-update label from hostname inference if no operator truth and
+	// the node is not protected
+	if inference := extractHostnameInference(merged.Discovered); inference != nil && inference.Best != nil && !existing.Protected {
 		hasOperatorHostname, _ := r.repo.HasOperatorTruthHostname(ctx, node.ID)
 		if !hasOperatorHostname {
 			newLabel := domain.ExtractShortName(inference.Best.Hostname)
 			if newLabel != "" && newLabel != existing.Label {
 				if err := r.repo.UpdateNodeLabel(ctx, node.ID, newLabel); err != nil {
-					log.Printf("Failed to update label for %s: %v", node.ID, err)
+					r.logger.Warn("failed to update label", "node_id", node.ID, "error", err)
 				} else {
-					log.Printf("Auto-updated label for %s: %s -> %s (confidence: %.0f%%, source: %s)",
-						node.ID, existing.Label, newLabel,
-						inference.Best.Confidence*100, inference.Best.Source)
+					r.logger.Info("auto-updated label",
+						"node_id", node.ID,
+						"old_label", existing.Label,
+						"label", newLabel,
+						"confidence", inference.Best.Confidence,
+						"hostname_source", inference.Best.Source)
 				}
 			}
 		}
@@ -114,12 +660,30 @@ func (r *ReconcileService) reconcileNode(ctx context.Context, source string, nod
 		return false, fmt.Errorf("fetch updated node: %w", err)
 	}
 
-	// Emit node-updated event with full node data for incremental UI update
-	r.eventBus.Publish(Event{
-		Type:    EventNodeUpdated,
+	// Emit node-updated event with full node data for incremental UI
+	// update; a revived node is new to clients
+	eventType := EventNodeUpdated
+	if revived {
+		eventType = EventNodeCreated
+	}
+	r.publishChange(source, Event{
+		Type:    eventType,
 		Payload: updatedNode,
 	})
 
+	// Stale nodes stay stale when unreachable, so they don't alert again
+	if statusChanged && node.Status == domain.NodeStatusUnreachable && existing.Status != domain.NodeStatusStale {
+		r.eventBus.Publish(Event{
+			Type: EventNodeUnreachable,
+			Payload: map[string]any{
+				"node_id":         node.ID,
+				"label":           updatedNode.Label,
+				"previous_status": existing.Status,
+				"source":          source,
+			},
+		})
+	}
+
 	return true, nil
 }
 
@@ -188,6 +752,7 @@ func extractHostnameInference(discovered map[string]any) *domain.HostnameInferen
 						Hostname:   getStringField(cm, "hostname"),
 						Confidence: getFloatField(cm, "confidence"),
 						Source:     domain.ConfidenceSource(getStringField(cm, "source")),
+						ObservedAt: getTimeField(cm, "observed_at"),
 					}
 					inference.Candidates = append(inference.Candidates, candidate)
 				}
@@ -199,6 +764,7 @@ func extractHostnameInference(discovered map[string]any) *domain.HostnameInferen
 				Hostname:   getStringField(best, "hostname"),
 				Confidence: getFloatField(best, "confidence"),
 				Source:     domain.ConfidenceSource(getStringField(best, "source")),
+				ObservedAt: getTimeField(best, "observed_at"),
 			}
 		}
 
@@ -222,3 +788,13 @@ func getFloatField(m map[string]interface{}, key string) float64 {
 	}
 	return 0
 }
+
+// getTimeField safely extracts an RFC 3339 time field from a map
+func getTimeField(m map[string]interface{}, key string) time.Time {
+	if v, ok := m[key].(string); ok {
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
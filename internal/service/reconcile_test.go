@@ -0,0 +1,677 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// barrierEnricher names each node after its ID, but only once want calls
+// are in flight together, proving the steps run concurrently
+type barrierEnricher struct {
+	mu       sync.Mutex
+	inFlight int
+	want     int
+	ready    chan struct{}
+}
+
+func (b *barrierEnricher) Name() string { return "ptr" }
+
+func (b *barrierEnricher) Enrich(ctx context.Context, node *domain.Node, inference *domain.HostnameInference) error {
+	b.mu.Lock()
+	b.inFlight++
+	if b.inFlight == b.want {
+		close(b.ready)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.ready:
+	case <-time.After(2 * time.Second):
+		return fmt.Errorf("enrichment ran serially")
+	}
+	inference.AddCandidate(node.ID+".lan", domain.SourcePTR, time.Now())
+	return nil
+}
+
+func TestReconcileEnrichCreated(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	nodes := make([]domain.Node, 0, 3)
+	for _, id := range []string{"printer", "nas", "camera"} {
+		node := domain.NewNode(id, domain.NodeTypeUnknown, "10.0.0.1")
+		if err := repo.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+		nodes = append(nodes, *node)
+	}
+
+	svc.SetEnrichmentPipeline(NewEnrichmentPipeline([]Enricher{
+		&barrierEnricher{want: len(nodes), ready: make(chan struct{})},
+	}, 0))
+	svc.EnrichCreated(ctx, "scanner", nodes)
+
+	for _, id := range []string{"printer", "nas", "camera"} {
+		node, err := repo.GetNode(ctx, id)
+		if err != nil || node == nil {
+			t.Fatalf("GetNode(%s) = %v, %v", id, node, err)
+		}
+		if node.Label != id {
+			t.Errorf("%s label = %q, want the enriched hostname", id, node.Label)
+		}
+		if _, ok := node.GetDiscovered("hostname_inference"); !ok {
+			t.Errorf("%s has no stored hostname inference", id)
+		}
+	}
+}
+
+func TestReconcileServiceFirstContact(t *testing.T) {
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	node := domain.NewNode("ip-10-0-0-5", domain.NodeTypeServer, "10.0.0.5")
+
+	// No pipeline configured leaves the node untouched
+	svc.FirstContact(context.Background(), node)
+	if _, ok := node.GetDiscovered("enrichment_steps"); ok {
+		t.Fatal("FirstContact without a pipeline should not enrich")
+	}
+
+	var calls []string
+	svc.SetEnrichmentPipeline(NewEnrichmentPipeline([]Enricher{
+		&fakeEnricher{name: "ptr", hostname: "printer.home.lan", source: domain.SourcePTR, calls: &calls},
+	}, 0))
+	svc.FirstContact(context.Background(), node)
+
+	if node.Label != "printer" {
+		t.Errorf("Label = %q, want printer", node.Label)
+	}
+}
+
+func TestReconcileFragmentCreatesChildNodesAndEdges(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	for _, id := range []string{"core-sw", "nas"} {
+		if err := repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeSwitch, id)); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+	}
+
+	fragment := domain.NewGraphFragment()
+	iface := domain.NewNode("core-sw:Gi0-1", domain.NodeTypeInterface, "Gi0/1")
+	iface.ParentID = "core-sw"
+	fragment.AddNode(*iface)
+	orphan := domain.NewNode("missing:eth0", domain.NodeTypeInterface, "eth0")
+	orphan.ParentID = "missing"
+	fragment.AddNode(*orphan)
+	fragment.AddEdge(*domain.NewEdge("core-sw:Gi0-1", "nas", domain.EdgeTypeEthernet))
+	fragment.AddEdge(*domain.NewEdge("core-sw:Gi0-1", "ghost", domain.EdgeTypeEthernet))
+
+	if err := svc.ReconcileFragment(ctx, "snmp", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	if e := nextEvent(t, events); e.Type != EventNodeCreated {
+		t.Errorf("got %s, want node-created for the interface", e.Type)
+	}
+	if e := nextEvent(t, events); e.Type != EventEdgeCreated {
+		t.Errorf("got %s, want edge-created", e.Type)
+	}
+
+	if n, _ := repo.GetNode(ctx, "missing:eth0"); n != nil {
+		t.Error("child of an unknown parent should not be created")
+	}
+	edges, _ := repo.ListEdges(ctx, "", "", "")
+	if len(edges) != 1 {
+		t.Errorf("got %d edges, want 1 (dangling edge skipped)", len(edges))
+	}
+
+	// Reconciling the same fragment again is a no-op for the edge
+	if err := svc.ReconcileFragment(ctx, "snmp", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+	for len(events) > 0 {
+		if e := <-events; e.Type == EventEdgeUpdated || e.Type == EventEdgeCreated {
+			t.Errorf("unchanged edge republished as %s", e.Type)
+		}
+	}
+}
+
+func TestReconcileFragmentNodeCreationOptIn(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*domain.NewNode("192-168-1-20", domain.NodeTypeUnknown, "nas"))
+
+	if err := svc.ReconcileFragment(ctx, "verifier", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+	if n, _ := repo.GetNode(ctx, "192-168-1-20"); n != nil {
+		t.Fatal("sources without opt-in should not create nodes")
+	}
+
+	svc.AllowNodeCreation("mdns")
+	if err := svc.ReconcileFragment(ctx, "mdns", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+	if n, _ := repo.GetNode(ctx, "192-168-1-20"); n == nil {
+		t.Fatal("opted-in source should create the node")
+	}
+}
+
+func TestReconcileFragmentDebounce(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+	svc.AllowNodeCreation("scanner")
+
+	const window = 100 * time.Millisecond
+	svc.SetDebounce(window)
+
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		fragment := domain.NewGraphFragment()
+		fragment.AddNode(*domain.NewNode(fmt.Sprintf("host-%02d", i), domain.NodeTypeServer, fmt.Sprintf("host %d", i)))
+		if err := svc.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+			t.Fatalf("ReconcileFragment failed: %v", err)
+		}
+	}
+	// Writes are not deferred by the debounce
+	if n, _ := repo.GetNode(ctx, "host-49"); n == nil {
+		t.Fatal("node should be written before the batch event")
+	}
+	elapsed := time.Since(start)
+
+	seen := make(map[string]bool)
+	batches := 0
+	timeout := time.After(5 * time.Second)
+	for len(seen) < 50 {
+		select {
+		case e := <-events:
+			batch, ok := e.Payload.(*ReconcileBatch)
+			if e.Type != EventReconcileBatch || !ok {
+				t.Fatalf("got %s event, want only reconcile-batch", e.Type)
+			}
+			if !slices.Equal(batch.Sources, []string{"scanner"}) {
+				t.Errorf("batch sources = %v, want [scanner]", batch.Sources)
+			}
+			batches++
+			for _, id := range batch.NodeIDs {
+				seen[id] = true
+			}
+		case <-timeout:
+			t.Fatalf("batches covered %d of 50 nodes", len(seen))
+		}
+	}
+
+	// One batch per window the feed spanned, plus the one still open
+	if limit := int(elapsed/window) + 2; batches > limit {
+		t.Errorf("got %d batch events for 50 fragments in %v, want at most %d", batches, elapsed, limit)
+	}
+}
+
+func TestReconcileFragmentMergesByMAC(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+	svc.AllowNodeCreation("scanner")
+
+	original := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	original.SetProperty("ip", "192.168.1.20")
+	original.SetDiscovered("mac_address", "AA:BB:CC:00:00:01")
+	original.SetDiscovered("reverse_dns", "nas.lan")
+	if err := repo.CreateNode(ctx, original); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	// Same box after a DHCP lease change
+	moved := domain.NewNode("192-168-1-45", domain.NodeTypeServer, "192.168.1.45")
+	moved.SetProperty("ip", "192.168.1.45")
+	moved.SetDiscovered("mac_address", "aa:bb:cc:00:00:01")
+	moved.Status = domain.NodeStatusVerified
+
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*moved)
+	if err := svc.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	if n, _ := repo.GetNode(ctx, moved.ID); n != nil {
+		t.Error("duplicate node should not be created")
+	}
+	node, err := repo.GetNode(ctx, original.ID)
+	if err != nil || node == nil {
+		t.Fatalf("GetNode(%s) = %v, %v", original.ID, node, err)
+	}
+	if ip := node.GetPropertyString("ip"); ip != "192.168.1.45" {
+		t.Errorf("ip = %s, want 192.168.1.45", ip)
+	}
+	history, _ := node.Properties["ip_history"].([]any)
+	if len(history) != 1 || history[0].(map[string]any)["ip"] != "192.168.1.20" {
+		t.Errorf("ip_history = %v, want old ip recorded", node.Properties["ip_history"])
+	}
+	if node.Label != "nas" || node.Discovered["reverse_dns"] != "nas.lan" || node.Status != domain.NodeStatusVerified {
+		t.Errorf("merged node = %+v, want label and discoveries kept", node)
+	}
+
+	e := nextEvent(t, events)
+	payload, _ := e.Payload.(map[string]any)
+	if e.Type != EventNodeMerged || payload["id"] != original.ID || payload["merged_id"] != moved.ID {
+		t.Errorf("event = %s %v, want node-merged", e.Type, e.Payload)
+	}
+}
+
+func TestReconcileFragmentRevivesDeletedNode(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+	svc.AllowNodeCreation("scanner")
+
+	node := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	node.SetProperty("ip", "192.168.1.20")
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := repo.DeleteNode(ctx, node.ID, false); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+
+	// Still gone: an unreachable sighting leaves it deleted
+	gone := *node
+	gone.Status = domain.NodeStatusUnreachable
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(gone)
+	if err := svc.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+	if n, _ := repo.GetNode(ctx, node.ID); n == nil || n.DeletedAt == nil {
+		t.Fatalf("unreachable rescan revived node: %+v", n)
+	}
+
+	// Rescanned and answering: it comes back
+	seen := *node
+	seen.Status = domain.NodeStatusVerified
+	fragment = domain.NewGraphFragment()
+	fragment.AddNode(seen)
+	if err := svc.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	got, err := repo.GetNode(ctx, node.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetNode(%s) = %v, %v", node.ID, got, err)
+	}
+	if got.DeletedAt != nil || got.Status != domain.NodeStatusVerified || got.Label != "nas" {
+		t.Errorf("rescanned node = %+v, want live and verified with its label", got)
+	}
+	if e := nextEvent(t, events); e.Type != EventNodeCreated {
+		t.Errorf("event = %s, want node-created", e.Type)
+	}
+}
+
+func TestReconcileFragmentProtectedNode(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+	svc.AllowNodeCreation("scanner")
+
+	curated := domain.NewNode("core-nas", domain.NodeTypeServer, "Core NAS")
+	curated.SetProperty("ip", "192.168.1.20")
+	curated.SetProperty("location", "rack 1")
+	curated.SetDiscovered("mac_address", "AA:BB:CC:00:00:02")
+	if err := repo.CreateNode(ctx, curated); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := repo.SetNodeProtected(ctx, curated.ID, true); err != nil {
+		t.Fatalf("SetNodeProtected failed: %v", err)
+	}
+
+	// A scan reports the same MAC under a different IP, label and properties
+	conflicting := domain.NewNode("192-168-1-99", domain.NodeTypeServer, "192.168.1.99")
+	conflicting.SetProperty("ip", "192.168.1.99")
+	conflicting.SetProperty("location", "unknown")
+	conflicting.SetDiscovered("mac_address", "aa:bb:cc:00:00:02")
+	conflicting.SetDiscovered("reverse_dns", "nas-99.lan")
+	conflicting.Status = domain.NodeStatusVerified
+
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*conflicting)
+	if err := svc.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	if n, _ := repo.GetNode(ctx, conflicting.ID); n != nil {
+		t.Error("duplicate node should not be created")
+	}
+	node, err := repo.GetNode(ctx, curated.ID)
+	if err != nil || node == nil {
+		t.Fatalf("GetNode(%s) = %v, %v", curated.ID, node, err)
+	}
+	if node.Label != "Core NAS" || node.GetPropertyString("ip") != "192.168.1.20" || node.GetPropertyString("location") != "rack 1" {
+		t.Errorf("protected node = label %q, properties %v; want curated values kept", node.Label, node.Properties)
+	}
+	if _, ok := node.Properties["ip_history"]; ok {
+		t.Error("protected node should not record ip_history")
+	}
+	if !node.Protected || node.Discovered["reverse_dns"] != "nas-99.lan" || node.Status != domain.NodeStatusVerified {
+		t.Errorf("protected node = %+v, want still protected with discoveries and status updated", node)
+	}
+}
+
+func TestReconcileFragmentSourcePriority(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	if err := repo.CreateNode(ctx, domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	report := func(source, reverseDNS string) *domain.Node {
+		t.Helper()
+		seen := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+		seen.Status = domain.NodeStatusVerified
+		if reverseDNS != "" {
+			seen.SetDiscovered("reverse_dns", reverseDNS)
+		}
+		seen.SetDiscovered(source+"_seen", true)
+		fragment := domain.NewGraphFragment()
+		fragment.AddNode(*seen)
+		if err := svc.ReconcileFragment(ctx, source, fragment); err != nil {
+			t.Fatalf("ReconcileFragment(%s) failed: %v", source, err)
+		}
+		node, err := repo.GetNode(ctx, "192-168-1-20")
+		if err != nil || node == nil {
+			t.Fatalf("GetNode = %v, %v", node, err)
+		}
+		return node
+	}
+
+	report("scanner", "old-name.lan")
+	node := report("nmap", "nas.lan")
+	if got := node.Discovered["reverse_dns"]; got != "nas.lan" {
+		t.Errorf("after nmap reverse_dns = %v, want nas.lan", got)
+	}
+
+	// The scanner runs again with its stale answer and must not win
+	node = report("scanner", "old-name.lan")
+	if got := node.Discovered["reverse_dns"]; got != "nas.lan" {
+		t.Errorf("scanner overwrote nmap: reverse_dns = %v", got)
+	}
+	if got := node.DiscoveredSources["reverse_dns"]; got.Source != "nmap" || got.Priority != DefaultSourcePriorities["nmap"] {
+		t.Errorf("reverse_dns provenance = %+v, want nmap", got)
+	}
+	if node.Discovered["nmap_seen"] != true || node.Discovered["scanner_seen"] != true {
+		t.Errorf("discovered = %v, want properties from both sources kept", node.Discovered)
+	}
+
+	// A lower-priority source that doesn't report the key leaves it alone
+	node = report("verifier", "")
+	if got := node.Discovered["reverse_dns"]; got != "nas.lan" {
+		t.Errorf("verifier dropped reverse_dns: %v", got)
+	}
+
+	svc.SetSourcePriorities(map[string]int{"scanner": 90})
+	node = report("scanner", "renamed.lan")
+	if got := node.Discovered["reverse_dns"]; got != "renamed.lan" {
+		t.Errorf("configured priority ignored: reverse_dns = %v, want renamed.lan", got)
+	}
+}
+
+func TestReconcileFragmentPropertyFilter(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	truthSvc := NewTruthService(repo, bus)
+	svc := NewReconcileService(repo, truthSvc, bus)
+
+	nas := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	nas.Status = domain.NodeStatusVerified
+	if err := repo.CreateNode(ctx, nas); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	// Stored directly: SetTruth only accepts truthable properties
+	if err := repo.SetNodeTruth(ctx, nas.ID, &domain.NodeTruth{
+		Properties: map[string]any{"ping_latency_ms": 1, "hostname": "nas.lan"},
+	}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	for len(events) > 0 {
+		<-events
+	}
+
+	report := func(latency int64, hostname string) *domain.Node {
+		t.Helper()
+		seen := domain.NewNode(nas.ID, domain.NodeTypeServer, "nas")
+		seen.Status = domain.NodeStatusVerified
+		seen.SetDiscovered("ping_latency_ms", latency)
+		seen.SetDiscovered("hostname", hostname)
+		fragment := domain.NewGraphFragment()
+		fragment.AddNode(*seen)
+		if err := svc.ReconcileFragment(ctx, "verifier", fragment); err != nil {
+			t.Fatalf("ReconcileFragment failed: %v", err)
+		}
+		node, err := repo.GetNode(ctx, nas.ID)
+		if err != nil || node == nil {
+			t.Fatalf("GetNode = %v, %v", node, err)
+		}
+		return node
+	}
+	openKeys := func() []string {
+		t.Helper()
+		discrepancies, err := truthSvc.GetDiscrepanciesByNode(ctx, nas.ID)
+		if err != nil {
+			t.Fatalf("GetDiscrepanciesByNode failed: %v", err)
+		}
+		var keys []string
+		for _, d := range discrepancies {
+			if !d.IsResolved() {
+				keys = append(keys, d.PropertyKey)
+			}
+		}
+		slices.Sort(keys)
+		return keys
+	}
+
+	report(12, "nas.lan")
+	if keys := openKeys(); len(keys) != 0 {
+		t.Errorf("open discrepancies = %v, want none for the denied latency", keys)
+	}
+	for len(events) > 0 {
+		<-events
+	}
+
+	// Latency alone changes: stored, but no event and no discrepancy
+	node := report(30, "nas.lan")
+	if got := node.Discovered["ping_latency_ms"]; got != float64(30) && got != int64(30) {
+		t.Errorf("ping_latency_ms = %v, want the new latency stored", got)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %s event for a latency-only change, want none", (<-events).Type)
+	}
+	if keys := openKeys(); len(keys) != 0 {
+		t.Errorf("open discrepancies = %v, want none for the denied latency", keys)
+	}
+
+	// Participating properties are still checked against truth
+	report(30, "other.lan")
+	if keys := openKeys(); !slices.Equal(keys, []string{"hostname"}) {
+		t.Errorf("open discrepancies = %v, want [hostname]", keys)
+	}
+
+	// An empty deny list lets latency be compared again
+	if err := svc.SetPropertyFilter(nil, []string{}); err != nil {
+		t.Fatalf("SetPropertyFilter failed: %v", err)
+	}
+	report(45, "other.lan")
+	if keys := openKeys(); !slices.Equal(keys, []string{"hostname", "ping_latency_ms"}) {
+		t.Errorf("open discrepancies = %v, want latency included without the deny list", keys)
+	}
+
+	if err := svc.SetPropertyFilter([]string{"["}, nil); err == nil {
+		t.Error("SetPropertyFilter accepted a malformed pattern")
+	}
+}
+
+// captureHandler is a slog.Handler that keeps every record it handles
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r.Clone())
+	return nil
+}
+
+// find returns the attributes of the first record with msg
+func (h *captureHandler) find(msg string) (map[string]slog.Value, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := make(map[string]slog.Value)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		return attrs, true
+	}
+	return nil, false
+}
+
+func TestReconcileFragmentLogsStructuredFields(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+	capture := &captureHandler{}
+	svc.SetLogger(slog.New(capture))
+
+	node := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	seen := domain.NewNode("192-168-1-20", domain.NodeTypeServer, "nas")
+	seen.Status = domain.NodeStatusVerified
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*seen)
+	fragment.AddNode(*domain.NewNode("192-168-1-99", domain.NodeTypeUnknown, "ghost"))
+	if err := svc.ReconcileFragment(ctx, "verifier", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	attrs, ok := capture.find("reconciled fragment")
+	if !ok {
+		t.Fatal("expected a reconciled fragment log record")
+	}
+	if got := attrs["source"].String(); got != "verifier" {
+		t.Errorf("expected source verifier, got %q", got)
+	}
+	if got := attrs["nodes"].Int64(); got != 2 {
+		t.Errorf("expected nodes 2, got %d", got)
+	}
+	if got := attrs["changed"].Int64(); got != 1 {
+		t.Errorf("expected changed 1, got %d", got)
+	}
+	if attrs["duration"].Kind() != slog.KindDuration {
+		t.Errorf("expected duration attribute, got %v", attrs["duration"].Kind())
+	}
+
+	attrs, ok = capture.find("node not found during reconcile")
+	if !ok {
+		t.Fatal("expected a node not found log record")
+	}
+	if got := attrs["node_id"].String(); got != "192-168-1-99" {
+		t.Errorf("expected node_id 192-168-1-99, got %q", got)
+	}
+}
+
+func TestReconcileFragmentVLANEdges(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	for _, id := range []string{"sw1", "sw2"} {
+		if err := repo.UpsertNode(ctx, domain.NewNode(id, domain.NodeTypeSwitch, id)); err != nil {
+			t.Fatalf("UpsertNode failed: %v", err)
+		}
+	}
+
+	fragment := domain.NewGraphFragment()
+	for _, parent := range []string{"sw1", "sw2"} {
+		iface := domain.NewNode(parent+":gi1", domain.NodeTypeInterface, "gi1")
+		iface.ParentID = parent
+		iface.Discovered = map[string]any{domain.LinkVLANProperty: 100}
+		fragment.AddNode(*iface)
+	}
+	if err := svc.ReconcileFragment(ctx, "snmp", fragment); err != nil {
+		t.Fatalf("ReconcileFragment failed: %v", err)
+	}
+
+	edge, err := repo.GetEdge(ctx, domain.NewEdge("sw1:gi1", "sw2:gi1", domain.EdgeTypeVLAN).ID)
+	if err != nil {
+		t.Fatalf("GetEdge failed: %v", err)
+	}
+	if edge == nil || edge.Type != domain.EdgeTypeVLAN {
+		t.Fatalf("expected vlan edge between interfaces, got %+v", edge)
+	}
+	if id, ok := edge.VLANID(); !ok || id != 100 {
+		t.Errorf("edge vlan_id = %d, %v; want 100", id, ok)
+	}
+
+	for _, id := range []string{"sw1:gi1", "sw2:gi1"} {
+		tags, err := repo.ListTags(ctx, id)
+		if err != nil {
+			t.Fatalf("ListTags failed: %v", err)
+		}
+		if !slices.Contains(tags, "vlan:100") {
+			t.Errorf("%s tags = %v, want vlan:100", id, tags)
+		}
+	}
+
+	vlans, err := NewGraphService(repo, bus).ListVLANs(ctx)
+	if err != nil {
+		t.Fatalf("ListVLANs failed: %v", err)
+	}
+	if len(vlans) != 1 || vlans[0].ID != 100 {
+		t.Fatalf("expected only VLAN 100, got %+v", vlans)
+	}
+	if !slices.Equal(vlans[0].Members, []string{"sw1:gi1", "sw2:gi1"}) {
+		t.Errorf("members = %v", vlans[0].Members)
+	}
+	if !slices.Equal(vlans[0].Devices, []string{"sw1", "sw2"}) {
+		t.Errorf("devices = %v", vlans[0].Devices)
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SecretKeyEnv names the environment variable holding the base64 key used
+// to encrypt operator secret data at rest
+const SecretKeyEnv = "SPECULARIUM_SECRET_KEY"
+
+// SecretKeySize is the length in bytes of a decoded secret key
+const SecretKeySize = 32
+
+// sealedPrefix versions the sealed data format. v1 data was sealed with a
+// key derived from a passphrase and can no longer be opened.
+const (
+	sealedPrefix       = "v2:"
+	legacySealedPrefix = "v1:"
+)
+
+// SecretCipher seals secret data with AES-256-GCM. The secret ID is bound
+// as additional data, so sealed values cannot be swapped between secrets.
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher creates a cipher from a base64-encoded 32-byte random key,
+// e.g. the output of "openssl rand -base64 32". Passphrases are refused:
+// without a salt and a slow KDF they are open to offline guessing.
+func NewSecretCipher(encodedKey string) (*SecretCipher, error) {
+	if encodedKey == "" {
+		return nil, fmt.Errorf("secret key is empty")
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedKey))
+	if err != nil || len(key) != SecretKeySize {
+		return nil, fmt.Errorf("secret key must be %d random bytes, base64-encoded (openssl rand -base64 %d)", SecretKeySize, SecretKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretCipher{aead: aead}, nil
+}
+
+// Seal encrypts a secret's data map
+func (c *SecretCipher) Seal(id string, data map[string]string) (string, error) {
+	plaintext, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("marshal secret data: %w", err)
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(id))
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts data produced by Seal for the same secret ID
+func (c *SecretCipher) Open(id, sealed string) (map[string]string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		if strings.HasPrefix(sealed, legacySealedPrefix) {
+			return nil, fmt.Errorf("secret data was sealed with a passphrase key; re-enter the secret")
+		}
+		return nil, fmt.Errorf("unknown sealed data format")
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("decode sealed data: %w", err)
+	}
+
+	nonceSize := c.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return nil, fmt.Errorf("sealed data too short")
+	}
+	plaintext, err := c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt secret data: wrong key or corrupted data")
+	}
+
+	var data map[string]string
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal secret data: %w", err)
+	}
+	return data, nil
+}
@@ -2,10 +2,12 @@ package service
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,8 +19,10 @@ import (
 type SecretsRepository interface {
 	CreateSecret(ctx context.Context, secret *domain.Secret) error
 	GetSecret(ctx context.Context, id string) (*domain.Secret, error)
+	GetSecretByName(ctx context.Context, name string) (*domain.Secret, error)
 	UpdateSecret(ctx context.Context, secret *domain.Secret) error
 	DeleteSecret(ctx context.Context, id string) error
+	ForceDeleteSecret(ctx context.Context, id string) error
 	ListSecrets(ctx context.Context, secretType string, source string) ([]domain.Secret, error)
 	UpdateSecretUsage(ctx context.Context, id string) error
 	UpdateSecretStatus(ctx context.Context, id string, status domain.SecretStatus, message string) error
@@ -30,6 +34,9 @@ type SecretsService struct {
 	eventBus      *EventBus
 	mountedPaths  []string // Paths to scan for mounted secrets
 	mountedSecrets map[string]*domain.Secret // Cache of mounted secrets
+	cipher        *SecretCipher // Encrypts operator secret data at rest; nil stores plaintext
+	tester        SecretTester  // Live secret checks; nil disables TestSecret
+	expiryWarned  map[string]time.Time // Rotation due time last announced per secret
 	mu            sync.RWMutex
 }
 
@@ -40,9 +47,15 @@ func NewSecretsService(repo SecretsRepository, eventBus *EventBus) *SecretsServi
 		eventBus:       eventBus,
 		mountedPaths:   []string{"/secrets", "/run/secrets"},
 		mountedSecrets: make(map[string]*domain.Secret),
+		expiryWarned:   make(map[string]time.Time),
 	}
 }
 
+// SetCipher enables encryption of operator secret data at rest
+func (s *SecretsService) SetCipher(c *SecretCipher) {
+	s.cipher = c
+}
+
 // SetMountedPaths configures the paths to scan for mounted secrets
 func (s *SecretsService) SetMountedPaths(paths []string) {
 	s.mu.Lock()
@@ -254,7 +267,46 @@ func (s *SecretsService) GetSecret(ctx context.Context, id string) (*domain.Secr
 	s.mu.RUnlock()
 
 	// Check DB for operator secrets
-	return s.repo.GetSecret(ctx, id)
+	secret, err := s.repo.GetSecret(ctx, id)
+	if err != nil || secret == nil {
+		return secret, err
+	}
+	if err := s.openSecret(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// GetByName retrieves a secret by its name, or nil if no secret has it.
+// Mounted secrets take precedence over operator secrets, as in GetSecret.
+// A name shared by several secrets from the same source is an error.
+func (s *SecretsService) GetByName(ctx context.Context, name string) (*domain.Secret, error) {
+	s.mu.RLock()
+	var mounted []*domain.Secret
+	for _, secret := range s.mountedSecrets {
+		if secret.Name == name {
+			mounted = append(mounted, secret)
+		}
+	}
+	s.mu.RUnlock()
+
+	switch len(mounted) {
+	case 0:
+	case 1:
+		return mounted[0], nil
+	default:
+		sort.Slice(mounted, func(i, j int) bool { return mounted[i].ID < mounted[j].ID })
+		return nil, fmt.Errorf("secret name %q is ambiguous: used by %s and %s", name, mounted[0].ID, mounted[1].ID)
+	}
+
+	secret, err := s.repo.GetSecretByName(ctx, name)
+	if err != nil || secret == nil {
+		return secret, err
+	}
+	if err := s.openSecret(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
 }
 
 // GetSecretValue retrieves a specific value from a secret
@@ -306,6 +358,9 @@ func (s *SecretsService) ListSecrets(ctx context.Context, secretType string, sou
 			return nil, err
 		}
 		for _, secret := range dbSecrets {
+			if err := s.openSecret(&secret); err != nil {
+				return nil, err
+			}
 			summaries = append(summaries, secret.ToSummary())
 		}
 	}
@@ -325,6 +380,9 @@ func (s *SecretsService) CreateSecret(ctx context.Context, secret *domain.Secret
 	if secret.Type == "" {
 		return fmt.Errorf("secret type is required")
 	}
+	if err := validateRotation(secret); err != nil {
+		return err
+	}
 
 	// Check for conflicts with mounted secrets
 	s.mu.RLock()
@@ -339,7 +397,12 @@ func (s *SecretsService) CreateSecret(ctx context.Context, secret *domain.Secret
 	secret.Immutable = false
 	secret.Status = domain.SecretStatusUnknown
 
-	if err := s.repo.CreateSecret(ctx, secret); err != nil {
+	if err := s.sealSecret(secret); err != nil {
+		return err
+	}
+	err := s.repo.CreateSecret(ctx, secret)
+	secret.SealedData = ""
+	if err != nil {
 		return err
 	}
 
@@ -362,7 +425,16 @@ func (s *SecretsService) UpdateSecret(ctx context.Context, secret *domain.Secret
 	}
 	s.mu.RUnlock()
 
-	if err := s.repo.UpdateSecret(ctx, secret); err != nil {
+	if err := validateRotation(secret); err != nil {
+		return err
+	}
+
+	if err := s.sealSecret(secret); err != nil {
+		return err
+	}
+	err := s.repo.UpdateSecret(ctx, secret)
+	secret.SealedData = ""
+	if err != nil {
 		return err
 	}
 
@@ -375,8 +447,47 @@ func (s *SecretsService) UpdateSecret(ctx context.Context, secret *domain.Secret
 	return nil
 }
 
-// DeleteSecret deletes an operator secret
-func (s *SecretsService) DeleteSecret(ctx context.Context, id string) error {
+// sealSecret encrypts a secret's data into SealedData for storage when a
+// cipher is configured
+func (s *SecretsService) sealSecret(secret *domain.Secret) error {
+	if s.cipher == nil {
+		return nil
+	}
+	sealed, err := s.cipher.Seal(secret.ID, secret.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret %s: %w", secret.ID, err)
+	}
+	secret.SealedData = sealed
+	return nil
+}
+
+// openSecret decrypts SealedData read from storage back into Data
+func (s *SecretsService) openSecret(secret *domain.Secret) error {
+	if secret.SealedData == "" {
+		return nil
+	}
+	if s.cipher == nil {
+		return fmt.Errorf("secret %s is encrypted but %s is not set", secret.ID, SecretKeyEnv)
+	}
+	data, err := s.cipher.Open(secret.ID, secret.SealedData)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt secret %s: %w", secret.ID, err)
+	}
+	secret.Data = data
+	secret.SealedData = ""
+	return nil
+}
+
+// DeleteSecret deletes an operator secret. With force it also deletes a
+// stored immutable row left behind by a mount that no longer exists; mounts
+// are rescanned first and a secret that is still mounted is never deleted.
+func (s *SecretsService) DeleteSecret(ctx context.Context, id string, force bool) error {
+	if force {
+		if err := s.LoadMountedSecrets(); err != nil {
+			return fmt.Errorf("cannot verify mounts before force delete: %w", err)
+		}
+	}
+
 	// Check if it's a mounted secret
 	s.mu.RLock()
 	if _, exists := s.mountedSecrets[id]; exists {
@@ -385,7 +496,11 @@ func (s *SecretsService) DeleteSecret(ctx context.Context, id string) error {
 	}
 	s.mu.RUnlock()
 
-	if err := s.repo.DeleteSecret(ctx, id); err != nil {
+	if force {
+		if err := s.forceDeleteStaleMount(ctx, id); err != nil {
+			return err
+		}
+	} else if err := s.repo.DeleteSecret(ctx, id); err != nil {
 		return err
 	}
 
@@ -398,6 +513,30 @@ func (s *SecretsService) DeleteSecret(ctx context.Context, id string) error {
 	return nil
 }
 
+// forceDeleteStaleMount deletes a stored secret whose mount is gone.
+// Operator secrets are deleted normally.
+func (s *SecretsService) forceDeleteStaleMount(ctx context.Context, id string) error {
+	secret, err := s.repo.GetSecret(ctx, id)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("secret %s not found", id)
+	}
+	if secret.Source != domain.SecretSourceMounted {
+		return s.repo.DeleteSecret(ctx, id)
+	}
+
+	// The rescan can miss a file it failed to read, so check the path too
+	if path := secret.Metadata["path"]; path != "" {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("cannot delete mounted secret %s: %s still exists", id, path)
+		}
+	}
+
+	return s.repo.ForceDeleteSecret(ctx, id)
+}
+
 // UpdateSecretStatus updates the operational status of a secret
 func (s *SecretsService) UpdateSecretStatus(ctx context.Context, id string, status domain.SecretStatus, message string) error {
 	// For mounted secrets, just update the cache
@@ -427,3 +566,201 @@ func (s *SecretsService) ResolveSecretRef(ctx context.Context, ref domain.Secret
 	}
 	return s.GetSecretValue(ctx, ref.ID, key)
 }
+
+// ValidateAPIToken reports whether token matches the token of any
+// api_token secret, mounted or operator-created. Secrets marked invalid or
+// expired are not accepted.
+func (s *SecretsService) ValidateAPIToken(ctx context.Context, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+
+	candidates := make([]domain.Secret, 0)
+	s.mu.RLock()
+	for _, secret := range s.mountedSecrets {
+		if secret.Type == domain.SecretTypeAPIToken {
+			candidates = append(candidates, *secret)
+		}
+	}
+	s.mu.RUnlock()
+
+	dbSecrets, err := s.repo.ListSecrets(ctx, string(domain.SecretTypeAPIToken), string(domain.SecretSourceOperator))
+	if err != nil {
+		return false, err
+	}
+	for i := range dbSecrets {
+		// One unreadable token must not lock out every other token
+		if err := s.openSecret(&dbSecrets[i]); err != nil {
+			log.Printf("Warning: skipping API token: %v", err)
+			continue
+		}
+		candidates = append(candidates, dbSecrets[i])
+	}
+
+	for _, secret := range candidates {
+		if secret.Status == domain.SecretStatusInvalid || secret.Status == domain.SecretStatusExpired {
+			continue
+		}
+		expected, ok := secret.Data["token"]
+		if !ok {
+			expected = secret.Data["value"]
+		}
+		if expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1 {
+			if secret.Source == domain.SecretSourceOperator {
+				s.repo.UpdateSecretUsage(ctx, secret.ID)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SecretTester performs a live check of a secret against a target
+type SecretTester interface {
+	TestSecret(ctx context.Context, secret *domain.Secret, target string) (string, error)
+}
+
+// SetTester sets the tester used by TestSecret
+func (s *SecretsService) SetTester(t SecretTester) {
+	s.tester = t
+}
+
+// TestSecret validates a secret against a target and records the outcome
+// as the secret's status. A failed check is a result, not an error; errors
+// are reserved for unknown secrets, untestable types and bad requests.
+func (s *SecretsService) TestSecret(ctx context.Context, id, target string) (*domain.SecretTestResult, error) {
+	secret, err := s.GetSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("secret %s not found", id)
+	}
+	if !secret.Type.IsTestable() {
+		return nil, fmt.Errorf("secret type %s does not support testing", secret.Type)
+	}
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("target is required")
+	}
+	if s.tester == nil {
+		return nil, fmt.Errorf("secret testing is not available")
+	}
+
+	start := time.Now()
+	message, testErr := s.tester.TestSecret(ctx, secret, target)
+	result := &domain.SecretTestResult{
+		SecretID:   id,
+		Type:       secret.Type,
+		Target:     target,
+		Success:    testErr == nil,
+		Message:    message,
+		DurationMs: time.Since(start).Milliseconds(),
+		TestedAt:   start,
+	}
+
+	status := domain.SecretStatusValid
+	if testErr != nil {
+		status = domain.SecretStatusInvalid
+		result.Message = testErr.Error()
+	}
+	if err := s.UpdateSecretStatus(ctx, id, status, fmt.Sprintf("Tested against %s: %s", target, result.Message)); err != nil {
+		return nil, err
+	}
+
+	s.eventBus.Publish(Event{
+		Type:    EventType("secret-tested"),
+		Payload: result,
+	})
+
+	return result, nil
+}
+
+// validateRotation checks that a secret's rotation interval, if set, is a
+// positive duration
+func validateRotation(secret *domain.Secret) error {
+	if secret.RotationInterval == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(secret.RotationInterval)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid rotation_interval %q: must be a positive duration such as \"2160h\"", secret.RotationInterval)
+	}
+	return nil
+}
+
+// ListExpiringSecrets returns secrets whose rotation is due before
+// now+within, including those already overdue, soonest first
+func (s *SecretsService) ListExpiringSecrets(ctx context.Context, now time.Time, within time.Duration) ([]domain.SecretSummary, error) {
+	summaries, err := s.ListSecrets(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := now.Add(within)
+	expiring := []domain.SecretSummary{}
+	for _, summary := range summaries {
+		if summary.RotationDueAt != nil && !summary.RotationDueAt.After(cutoff) {
+			expiring = append(expiring, summary)
+		}
+	}
+	sort.Slice(expiring, func(i, j int) bool {
+		return expiring[i].RotationDueAt.Before(*expiring[j].RotationDueAt)
+	})
+	return expiring, nil
+}
+
+// SweepExpiringSecrets publishes a secret-expiring event for each secret
+// due for rotation within the window. A secret is announced once per due
+// time, so rotating it or changing its schedule re-arms the warning.
+// Returns the number of events published.
+func (s *SecretsService) SweepExpiringSecrets(ctx context.Context, now time.Time, within time.Duration) (int, error) {
+	expiring, err := s.ListExpiringSecrets(ctx, now, within)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, summary := range expiring {
+		if warned, ok := s.expiryWarned[summary.ID]; ok && warned.Equal(*summary.RotationDueAt) {
+			continue
+		}
+		s.expiryWarned[summary.ID] = *summary.RotationDueAt
+		s.eventBus.Publish(Event{
+			Type: EventSecretExpiring,
+			Payload: map[string]interface{}{
+				"id":              summary.ID,
+				"name":            summary.Name,
+				"rotation_due_at": summary.RotationDueAt,
+				"expired":         !summary.RotationDueAt.After(now),
+			},
+		})
+		published++
+	}
+	return published, nil
+}
+
+// RunExpirySweeper checks for secrets due for rotation on every interval
+// tick until ctx is cancelled
+func (s *SecretsService) RunExpirySweeper(ctx context.Context, interval, within time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.SweepExpiringSecrets(ctx, time.Now(), within)
+			if err != nil {
+				log.Printf("Secret expiry sweep failed: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Secret expiry sweep: %d secret(s) due for rotation", n)
+			}
+		}
+	}
+}
@@ -5,9 +5,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"specularium/internal/codec"
 	"specularium/internal/domain"
+	"specularium/internal/metrics"
 	"specularium/internal/repository/sqlite"
 )
 
@@ -15,6 +20,8 @@ import (
 type GraphService struct {
 	repo     *sqlite.Repository
 	eventBus *EventBus
+
+	evidenceHalfLife time.Duration // capability evidence decay on read, 0 disables
 }
 
 // NewGraphService creates a new graph service
@@ -25,26 +32,243 @@ func NewGraphService(repo *sqlite.Repository, eventBus *EventBus) *GraphService
 	}
 }
 
-// GetGraph returns the complete graph with nodes, edges, and positions
+// SetEvidenceHalfLife sets the age at which capability evidence counts for
+// half its original confidence. Zero disables decay.
+func (s *GraphService) SetEvidenceHalfLife(d time.Duration) {
+	s.evidenceHalfLife = d
+}
+
+// RegisterMetrics exposes node counts, read from the repository at scrape time
+func (s *GraphService) RegisterMetrics(reg *metrics.Registry) {
+	countStatus := func(match func(domain.NodeStatus) bool) func(ctx context.Context) (float64, error) {
+		return func(ctx context.Context) (float64, error) {
+			counts, err := s.repo.CountNodesByStatus(ctx)
+			if err != nil {
+				return 0, err
+			}
+			total := 0
+			for status, n := range counts {
+				if match(status) {
+					total += n
+				}
+			}
+			return float64(total), nil
+		}
+	}
+
+	reg.GaugeFunc("specularium_nodes_total", "Number of nodes in the graph.",
+		countStatus(func(domain.NodeStatus) bool { return true }))
+	reg.GaugeFunc("specularium_nodes_unreachable", "Number of nodes that failed their last verification.",
+		countStatus(func(status domain.NodeStatus) bool { return status == domain.NodeStatusUnreachable }))
+}
+
+// GetGraph returns the complete graph with nodes, edges, and positions.
+// Capabilities carry their confidence and status but not their evidence,
+// which GetNodeCapabilities returns per node.
 func (s *GraphService) GetGraph(ctx context.Context) (*domain.Graph, error) {
-	return s.repo.GetGraph(ctx)
+	graph, err := s.repo.GetGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshCapabilities(graph.Nodes)
+	for i := range graph.Nodes {
+		graph.Nodes[i].SummarizeCapabilities()
+	}
+	return graph, nil
+}
+
+// GetSubgraph returns the nodes matching filter, the edges between them
+// and their positions. An empty filter returns the whole graph.
+func (s *GraphService) GetSubgraph(ctx context.Context, filter domain.ExportFilter) (*domain.Graph, error) {
+	if filter.IsEmpty() {
+		return s.GetGraph(ctx)
+	}
+
+	fragment, err := s.ExportFragment(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := s.repo.GetAllPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := domain.NewGraph()
+	graph.Nodes = fragment.Nodes
+	graph.Edges = fragment.Edges
+	for _, node := range graph.Nodes {
+		if pos, ok := positions[node.ID]; ok {
+			graph.Positions[node.ID] = pos
+		}
+	}
+	s.refreshCapabilities(graph.Nodes)
+	for i := range graph.Nodes {
+		graph.Nodes[i].SummarizeCapabilities()
+	}
+	return graph, nil
+}
+
+// GetStats returns node, edge, status, truth and discrepancy counts.
+// The common statuses are always present, even when zero.
+func (s *GraphService) GetStats(ctx context.Context) (*domain.GraphStats, error) {
+	stats, err := s.repo.GetGraphStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range []domain.NodeStatus{
+		domain.NodeStatusVerified,
+		domain.NodeStatusUnreachable,
+		domain.NodeStatusDegraded,
+		domain.NodeStatusUnverified,
+	} {
+		if _, ok := stats.NodesByStatus[status]; !ok {
+			stats.NodesByStatus[status] = 0
+		}
+	}
+	return stats, nil
 }
 
-// GetNode retrieves a single node by ID
+// GetNode retrieves a single node by ID. Soft-deleted nodes are not found.
 func (s *GraphService) GetNode(ctx context.Context, id string) (*domain.Node, error) {
+	node, err := s.liveNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	node.RefreshCapabilities(time.Now(), s.evidenceHalfLife)
+	return node, nil
+}
+
+// liveNode returns a node that exists and is not soft-deleted
+func (s *GraphService) liveNode(ctx context.Context, id string) (*domain.Node, error) {
 	node, err := s.repo.GetNode(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	if node == nil {
+	if node == nil || node.DeletedAt != nil {
 		return nil, fmt.Errorf("node %s not found", id)
 	}
 	return node, nil
 }
 
-// ListNodes returns all nodes, optionally filtered
-func (s *GraphService) ListNodes(ctx context.Context, nodeType, source string) ([]domain.Node, error) {
-	return s.repo.ListNodes(ctx, nodeType, source)
+// GetNodeCapabilities returns a node's capabilities with their full
+// evidence lists
+func (s *GraphService) GetNodeCapabilities(ctx context.Context, id string) (map[domain.CapabilityType]*domain.Capability, error) {
+	node, err := s.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if node.Capabilities == nil {
+		return map[domain.CapabilityType]*domain.Capability{}, nil
+	}
+	return node.Capabilities, nil
+}
+
+// SelectNodesForVerification resolves a re-verify request to node IDs:
+// the given IDs, which must all exist, or else every node whose IP falls
+// within segmentum
+func (s *GraphService) SelectNodesForVerification(ctx context.Context, ids []string, segmentum string) ([]string, error) {
+	if len(ids) > 0 {
+		nodes, err := s.repo.GetNodesByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		found := make(map[string]bool, len(nodes))
+		for _, node := range nodes {
+			found[node.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				return nil, fmt.Errorf("node %s not found", id)
+			}
+		}
+		selected := slices.Sorted(maps.Keys(found))
+		return selected, nil
+	}
+
+	if segmentum == "" {
+		return nil, fmt.Errorf("invalid request: node_ids or segmentum is required")
+	}
+	fragment, err := s.ExportFragment(ctx, domain.ExportFilter{CIDR: segmentum})
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]string, len(fragment.Nodes))
+	for i, node := range fragment.Nodes {
+		selected[i] = node.ID
+	}
+	slices.Sort(selected)
+	return selected, nil
+}
+
+// ListNodes returns the nodes matching filter. A node must carry every
+// filter tag to match; the role may be given as an alias.
+func (s *GraphService) ListNodes(ctx context.Context, filter domain.NodeFilter) ([]domain.Node, error) {
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	filter.Tags = tags
+
+	role, err := domain.NormalizeRole(string(filter.Role))
+	if err != nil {
+		return nil, fmt.Errorf("invalid role: %w", err)
+	}
+	filter.Role = role
+
+	nodes, err := s.repo.FilterNodes(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshCapabilities(nodes)
+	return nodes, nil
+}
+
+// GetNodesUpdatedSince returns nodes changed at or after since, most
+// recently updated first
+func (s *GraphService) GetNodesUpdatedSince(ctx context.Context, since time.Time) ([]domain.Node, error) {
+	nodes, err := s.repo.GetNodesUpdatedSince(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshCapabilities(nodes)
+	return nodes, nil
+}
+
+// Search result limits
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 500
+)
+
+// SearchNodes finds nodes matching a substring of their label, ID, properties
+// or discovered data, best matches first
+func (s *GraphService) SearchNodes(ctx context.Context, query string, limit int) ([]domain.Node, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("search query required")
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	nodes, err := s.repo.SearchNodes(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	s.refreshCapabilities(nodes)
+	return nodes, nil
+}
+
+// refreshCapabilities applies evidence decay to nodes read from storage,
+// since stored confidence reflects the time evidence was last added
+func (s *GraphService) refreshCapabilities(nodes []domain.Node) {
+	now := time.Now()
+	for i := range nodes {
+		nodes[i].RefreshCapabilities(now, s.evidenceHalfLife)
+	}
 }
 
 // CreateNode creates a new node
@@ -59,40 +283,434 @@ func (s *GraphService) CreateNode(ctx context.Context, node *domain.Node) error
 
 	s.eventBus.Publish(Event{
 		Type:    EventNodeCreated,
-		Payload: map[string]string{"node_id": node.ID, "type": string(node.Type)},
+		Payload: node,
 	})
 
 	return nil
 }
 
+// NodeCreateResult reports the outcome for one node of a batch create
+type NodeCreateResult struct {
+	ID      string `json:"id"`
+	Created bool   `json:"created"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CreateNodes creates many nodes in one transaction and reports a result
+// per node, in input order. Invalid or conflicting nodes are skipped and
+// the rest committed, unless atomic is set, in which case any failure
+// leaves the graph untouched.
+func (s *GraphService) CreateNodes(ctx context.Context, nodes []*domain.Node, atomic bool) ([]NodeCreateResult, error) {
+	results := make([]NodeCreateResult, len(nodes))
+	valid := make([]*domain.Node, 0, len(nodes))
+	validIdx := make([]int, 0, len(nodes))
+	for i, node := range nodes {
+		results[i].ID = node.ID
+		if err := s.validateNode(node); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		valid = append(valid, node)
+		validIdx = append(validIdx, i)
+	}
+
+	rollback := atomic && len(valid) < len(nodes)
+	if !rollback && len(valid) > 0 {
+		errs, err := s.repo.CreateNodes(ctx, valid, atomic)
+		if err != nil {
+			return nil, err
+		}
+		for j, err := range errs {
+			if err != nil {
+				results[validIdx[j]].Error = err.Error()
+				rollback = atomic
+			}
+		}
+	}
+
+	if rollback {
+		for i := range results {
+			if results[i].Error == "" {
+				results[i].Error = "not created: batch rolled back"
+			}
+		}
+		return results, nil
+	}
+
+	for j, node := range valid {
+		if results[validIdx[j]].Error != "" {
+			continue
+		}
+		results[validIdx[j]].Created = true
+		s.eventBus.Publish(Event{
+			Type:    EventNodeCreated,
+			Payload: node,
+		})
+	}
+
+	return results, nil
+}
+
 // UpdateNode updates an existing node
 func (s *GraphService) UpdateNode(ctx context.Context, id string, updates map[string]interface{}) error {
+	if raw, ok := updates["protected"]; ok {
+		if _, ok := raw.(bool); !ok {
+			return fmt.Errorf("invalid node: protected must be a boolean")
+		}
+	}
+	if props, ok := updates["properties"].(map[string]interface{}); ok {
+		if raw, ok := props[domain.ProbeConfigProperty]; ok && raw != nil {
+			if _, err := domain.ParseProbeConfig(raw); err != nil {
+				return fmt.Errorf("invalid node: %w", err)
+			}
+		}
+	}
+
+	if _, err := s.liveNode(ctx, id); err != nil {
+		return err
+	}
 	if err := s.repo.UpdateNode(ctx, id, updates); err != nil {
 		return err
 	}
 
-	s.eventBus.Publish(Event{
-		Type:    EventNodeUpdated,
-		Payload: map[string]string{"node_id": id},
-	})
+	// Publish the stored node so clients can patch local state
+	s.publishNodeUpdated(ctx, id)
 
 	return nil
 }
 
-// DeleteNode removes a node and its connections
-func (s *GraphService) DeleteNode(ctx context.Context, id string) error {
-	if err := s.repo.DeleteNode(ctx, id); err != nil {
-		return err
+// NodeDeleteResult reports what a node deletion took with it
+type NodeDeleteResult struct {
+	ID   string `json:"id"`
+	Hard bool   `json:"hard"`
+	// EdgesDeleted counts edges removed with the node, or hidden with it
+	// on a soft delete
+	EdgesDeleted int `json:"edges_deleted"`
+}
+
+// DeleteNode removes a node and its connections. Unless hard is set the
+// node is soft-deleted and can be brought back with RestoreNode. Protected
+// nodes are refused unless force is set.
+func (s *GraphService) DeleteNode(ctx context.Context, id string, hard, force bool) (*NodeDeleteResult, error) {
+	if !force {
+		node, err := s.repo.GetNode(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if node != nil && node.Protected {
+			return nil, fmt.Errorf("node %s is protected", id)
+		}
+	}
+
+	edges, err := s.repo.CountNodeEdges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteNode(ctx, id, hard); err != nil {
+		return nil, err
 	}
 
 	s.eventBus.Publish(Event{
 		Type:    EventNodeDeleted,
-		Payload: map[string]string{"node_id": id},
+		Payload: map[string]string{"id": id},
 	})
 
+	return &NodeDeleteResult{ID: id, Hard: hard, EdgesDeleted: edges}, nil
+}
+
+// RestoreNode brings back a soft-deleted node along with its hidden edges
+func (s *GraphService) RestoreNode(ctx context.Context, id string) (*domain.Node, error) {
+	if err := s.repo.RestoreNode(ctx, id); err != nil {
+		return nil, err
+	}
+
+	node, err := s.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clients dropped the node and its edges on delete, so send them again
+	s.eventBus.Publish(Event{
+		Type:    EventNodeCreated,
+		Payload: node,
+	})
+	outgoing, err := s.repo.ListEdges(ctx, "", id, "")
+	if err != nil {
+		return nil, err
+	}
+	incoming, err := s.repo.ListEdges(ctx, "", "", id)
+	if err != nil {
+		return nil, err
+	}
+	for _, edge := range incoming {
+		// Self-loops are already in outgoing
+		if edge.FromID != id {
+			outgoing = append(outgoing, edge)
+		}
+	}
+	for i := range outgoing {
+		s.eventBus.Publish(Event{
+			Type:    EventEdgeCreated,
+			Payload: &outgoing[i],
+		})
+	}
+
+	return node, nil
+}
+
+// SetNodeImage validates and stores an image for a node, replacing any existing one
+func (s *GraphService) SetNodeImage(ctx context.Context, id, contentType string, data []byte) error {
+	if len(data) == 0 {
+		return fmt.Errorf("image is empty")
+	}
+	if len(data) > domain.MaxNodeImageSize {
+		return fmt.Errorf("image exceeds %d bytes", domain.MaxNodeImageSize)
+	}
+	if !domain.NodeImageTypes[contentType] {
+		return fmt.Errorf("unsupported image type: %s", contentType)
+	}
+
+	if _, err := s.GetNode(ctx, id); err != nil {
+		return err
+	}
+
+	img := &domain.NodeImage{
+		NodeID:      id,
+		ContentType: contentType,
+		Data:        data,
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.repo.SetNodeImage(ctx, img); err != nil {
+		return err
+	}
+
+	s.publishNodeUpdated(ctx, id)
 	return nil
 }
 
+// GetNodeImage retrieves the image attached to a node
+func (s *GraphService) GetNodeImage(ctx context.Context, id string) (*domain.NodeImage, error) {
+	if _, err := s.liveNode(ctx, id); err != nil {
+		return nil, err
+	}
+	img, err := s.repo.GetNodeImage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, fmt.Errorf("image for node %s not found", id)
+	}
+	return img, nil
+}
+
+// DeleteNodeImage removes the image attached to a node
+func (s *GraphService) DeleteNodeImage(ctx context.Context, id string) error {
+	if _, err := s.liveNode(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteNodeImage(ctx, id); err != nil {
+		return err
+	}
+
+	s.publishNodeUpdated(ctx, id)
+	return nil
+}
+
+// AddTags attaches tags to a node and returns the node's full tag list
+func (s *GraphService) AddTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required")
+	}
+
+	for _, tag := range tags {
+		if err := s.repo.AddTag(ctx, id, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	s.publishNodeUpdated(ctx, id)
+	return s.repo.ListTags(ctx, id)
+}
+
+// RemoveTags detaches tags from a node and returns the remaining tags.
+// Nothing is removed unless every tag is present on the node.
+func (s *GraphService) RemoveTags(ctx context.Context, id string, tags []string) ([]string, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("at least one tag is required")
+	}
+
+	if _, err := s.GetNode(ctx, id); err != nil {
+		return nil, err
+	}
+	current, err := s.repo.ListTags(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if !slices.Contains(current, tag) {
+			return nil, fmt.Errorf("tag %s on node %s not found", tag, id)
+		}
+	}
+
+	for _, tag := range tags {
+		if err := s.repo.RemoveTag(ctx, id, tag); err != nil {
+			return nil, err
+		}
+	}
+
+	s.publishNodeUpdated(ctx, id)
+	return s.repo.ListTags(ctx, id)
+}
+
+// TagApplyResult reports a bulk tag application
+type TagApplyResult struct {
+	Tag     string   `json:"tag"`
+	Matched int      `json:"matched"`
+	Tagged  []string `json:"tagged"` // matching nodes that did not already carry the tag
+}
+
+// ApplyTag attaches tag to every live node matching filter at once. The
+// filter must set at least a type, source or segmentum so a bare call
+// cannot tag the whole graph.
+func (s *GraphService) ApplyTag(ctx context.Context, tag string, filter domain.ExportFilter) (*TagApplyResult, error) {
+	tag, err := domain.NormalizeTag(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+	if filter.Type == "" && filter.Source == "" && filter.CIDR == "" {
+		return nil, fmt.Errorf("invalid request: type, source or segmentum is required")
+	}
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+
+	matched, added, err := s.repo.ApplyTag(ctx, tag, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(added) > 0 {
+		s.eventBus.Publish(Event{
+			Type: EventGraphUpdated,
+			Payload: map[string]any{
+				"action": "tag",
+				"tag":    tag,
+				"nodes":  added,
+			},
+		})
+	}
+	return &TagApplyResult{Tag: tag, Matched: len(matched), Tagged: added}, nil
+}
+
+// Node history limits
+const (
+	DefaultHistoryLimit = 100
+	MaxHistoryLimit     = 1000
+)
+
+// GetNodeHistory returns recorded property changes for a node, newest first
+func (s *GraphService) GetNodeHistory(ctx context.Context, id string, limit int) ([]domain.NodeHistoryEntry, error) {
+	node, err := s.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", id)
+	}
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if limit > MaxHistoryLimit {
+		limit = MaxHistoryLimit
+	}
+
+	return s.repo.GetNodeHistory(ctx, id, limit)
+}
+
+// MaxNeighborDepth bounds how many hops GetNeighborhood walks
+const MaxNeighborDepth = 3
+
+// GetNeighborhood returns the subgraph within depth hops of a node,
+// including the node itself. Depth is clamped to [1, MaxNeighborDepth].
+func (s *GraphService) GetNeighborhood(ctx context.Context, id string, depth int) (*domain.GraphFragment, error) {
+	root, err := s.repo.GetNode(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if root == nil || root.DeletedAt != nil {
+		return nil, fmt.Errorf("node %s not found", id)
+	}
+	if depth < 1 {
+		depth = 1
+	}
+	if depth > MaxNeighborDepth {
+		depth = MaxNeighborDepth
+	}
+
+	fragment := domain.NewGraphFragment()
+	fragment.AddNode(*root)
+	seenNodes := map[string]bool{id: true}
+	seenEdges := make(map[string]bool)
+
+	frontier := []string{id}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, nodeID := range frontier {
+			neighbors, err := s.repo.GetNeighbors(ctx, nodeID)
+			if err != nil {
+				return nil, err
+			}
+			for _, n := range neighbors {
+				if !seenEdges[n.Edge.ID] {
+					seenEdges[n.Edge.ID] = true
+					fragment.AddEdge(n.Edge)
+				}
+				if !seenNodes[n.Node.ID] {
+					seenNodes[n.Node.ID] = true
+					fragment.AddNode(n.Node)
+					next = append(next, n.Node.ID)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return fragment, nil
+}
+
+// normalizeTags validates and lowercases tags, dropping duplicates
+func normalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag, err := domain.NormalizeTag(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag: %w", err)
+		}
+		if !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// publishNodeUpdated emits node-updated with the stored node
+func (s *GraphService) publishNodeUpdated(ctx context.Context, id string) {
+	if node, err := s.repo.GetNode(ctx, id); err == nil && node != nil {
+		s.eventBus.Publish(Event{
+			Type:    EventNodeUpdated,
+			Payload: node,
+		})
+	}
+}
+
 // GetEdge retrieves a single edge by ID
 func (s *GraphService) GetEdge(ctx context.Context, id string) (*domain.Edge, error) {
 	edge, err := s.repo.GetEdge(ctx, id)
@@ -105,9 +723,14 @@ func (s *GraphService) GetEdge(ctx context.Context, id string) (*domain.Edge, er
 	return edge, nil
 }
 
-// ListEdges returns all edges, optionally filtered
-func (s *GraphService) ListEdges(ctx context.Context, edgeType, fromID, toID string) ([]domain.Edge, error) {
-	return s.repo.ListEdges(ctx, edgeType, fromID, toID)
+// ListEdges returns the edges matching filter
+func (s *GraphService) ListEdges(ctx context.Context, filter domain.EdgeFilter) ([]domain.Edge, error) {
+	return s.repo.FilterEdges(ctx, filter)
+}
+
+// GetEdgesBetween returns edges connecting two nodes in either direction
+func (s *GraphService) GetEdgesBetween(ctx context.Context, a, b string) ([]domain.Edge, error) {
+	return s.repo.GetEdgesBetween(ctx, a, b)
 }
 
 // CreateEdge creates a new edge
@@ -122,7 +745,7 @@ func (s *GraphService) CreateEdge(ctx context.Context, edge *domain.Edge) error
 
 	s.eventBus.Publish(Event{
 		Type:    EventEdgeCreated,
-		Payload: map[string]string{"edge_id": edge.ID},
+		Payload: edge,
 	})
 
 	return nil
@@ -130,14 +753,22 @@ func (s *GraphService) CreateEdge(ctx context.Context, edge *domain.Edge) error
 
 // UpdateEdge updates an existing edge
 func (s *GraphService) UpdateEdge(ctx context.Context, id string, updates map[string]interface{}) error {
+	if props, ok := updates["properties"].(map[string]interface{}); ok {
+		if err := (&domain.Edge{Properties: props}).ValidateLinkProperties(); err != nil {
+			return err
+		}
+	}
 	if err := s.repo.UpdateEdge(ctx, id, updates); err != nil {
 		return err
 	}
 
-	s.eventBus.Publish(Event{
-		Type:    EventEdgeUpdated,
-		Payload: map[string]string{"edge_id": id},
-	})
+	// Publish the stored edge so clients can patch local state
+	if edge, err := s.repo.GetEdge(ctx, id); err == nil && edge != nil {
+		s.eventBus.Publish(Event{
+			Type:    EventEdgeUpdated,
+			Payload: edge,
+		})
+	}
 
 	return nil
 }
@@ -150,7 +781,7 @@ func (s *GraphService) DeleteEdge(ctx context.Context, id string) error {
 
 	s.eventBus.Publish(Event{
 		Type:    EventEdgeDeleted,
-		Payload: map[string]string{"edge_id": id},
+		Payload: map[string]string{"id": id},
 	})
 
 	return nil
@@ -180,22 +811,58 @@ func (s *GraphService) SavePosition(ctx context.Context, pos domain.NodePosition
 	return nil
 }
 
-// SavePositions saves multiple node positions
-func (s *GraphService) SavePositions(ctx context.Context, positions []domain.NodePosition) error {
-	if len(positions) == 0 {
-		return nil
-	}
+// PositionRepository is the slice of the repository used to persist layout
+type PositionRepository interface {
+	GetAllPositions(ctx context.Context) (map[string]domain.NodePosition, error)
+	SavePositions(ctx context.Context, positions []domain.NodePosition) error
+}
 
-	if err := s.repo.SavePositions(ctx, positions); err != nil {
-		return err
+// SavePositions saves the positions whose x, y or pinned state differ from
+// what is stored and returns how many were written. The UI posts every node
+// on each drag, so most of a batch is usually unchanged.
+func (s *GraphService) SavePositions(ctx context.Context, positions []domain.NodePosition) (int, error) {
+	written, err := saveChangedPositions(ctx, s.repo, positions)
+	if err != nil || written == 0 {
+		return written, err
 	}
 
 	s.eventBus.Publish(Event{
 		Type:    EventPositionsUpdated,
-		Payload: map[string]int{"count": len(positions)},
+		Payload: map[string]int{"count": written},
 	})
 
-	return nil
+	return written, nil
+}
+
+// saveChangedPositions upserts only the positions that differ from the
+// stored layout. A node repeated in the batch is compared against its
+// earlier entry, so the last value wins without redundant writes.
+func saveChangedPositions(ctx context.Context, repo PositionRepository, positions []domain.NodePosition) (int, error) {
+	if len(positions) == 0 {
+		return 0, nil
+	}
+
+	current, err := repo.GetAllPositions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	changed := make([]domain.NodePosition, 0, len(positions))
+	for _, pos := range positions {
+		if prev, ok := current[pos.NodeID]; ok && prev == pos {
+			continue
+		}
+		current[pos.NodeID] = pos
+		changed = append(changed, pos)
+	}
+
+	if len(changed) == 0 {
+		return 0, nil
+	}
+	if err := repo.SavePositions(ctx, changed); err != nil {
+		return 0, err
+	}
+	return len(changed), nil
 }
 
 // ImportResult represents the result of an import operation
@@ -229,6 +896,69 @@ func (s *GraphService) ImportAnsibleInventory(ctx context.Context, data []byte,
 	return s.importFragment(ctx, fragment, strategy)
 }
 
+// ImportPrometheusSD imports target hosts from a Prometheus file_sd JSON
+// file. Imports only write node identity and properties, so each host's
+// scrape ports are merged into its discovered values afterwards.
+func (s *GraphService) ImportPrometheusSD(ctx context.Context, data []byte, strategy string) (*ImportResult, error) {
+	fragment, err := codec.NewPrometheusSDCodec().Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.importFragment(ctx, fragment, strategy)
+	if err != nil {
+		return nil, err
+	}
+	for _, node := range fragment.Nodes {
+		if err := s.repo.MergeNodeDiscovered(ctx, node.ID, codec.PrometheusSDSource, node.Discovered); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CSVImportResult is an ImportResult with the CSV rows that were skipped
+type CSVImportResult struct {
+	ImportResult
+	RowErrors      []codec.CSVRowError `json:"row_errors"`
+	IgnoredColumns []string            `json:"ignored_columns,omitempty"`
+	RolledBack     bool                `json:"rolled_back,omitempty"`
+}
+
+// ImportCSV imports nodes from a CSV inventory (id, label, type, ip,
+// source, tags). Rows that fail to parse are reported and skipped; with
+// atomic set, any bad row aborts the import and nothing is written.
+func (s *GraphService) ImportCSV(ctx context.Context, r io.Reader, strategy string, atomic bool) (*CSVImportResult, error) {
+	if strategy == "" {
+		strategy = "merge"
+	}
+	if strategy != "merge" && strategy != "replace" {
+		return nil, fmt.Errorf("invalid strategy %s, must be 'merge' or 'replace'", strategy)
+	}
+
+	parsed, err := codec.NewCSVCodec().ParseRows(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+
+	result := &CSVImportResult{
+		RowErrors:      parsed.Errors,
+		IgnoredColumns: parsed.IgnoredColumns,
+	}
+	if atomic && len(parsed.Errors) > 0 {
+		result.Strategy = strategy
+		result.RolledBack = true
+		return result, nil
+	}
+
+	imported, err := s.importFragment(ctx, parsed.Fragment, strategy)
+	if err != nil {
+		return nil, err
+	}
+	result.ImportResult = *imported
+	return result, nil
+}
+
 // importFragment imports a graph fragment with the specified strategy
 func (s *GraphService) importFragment(ctx context.Context, fragment *domain.GraphFragment, strategy string) (*ImportResult, error) {
 	if strategy == "" {
@@ -260,48 +990,72 @@ func (s *GraphService) importFragment(ctx context.Context, fragment *domain.Grap
 	return result, nil
 }
 
-// ExportJSON exports the graph as JSON
-func (s *GraphService) ExportJSON(ctx context.Context) ([]byte, error) {
-	fragment, err := s.repo.ExportFragment(ctx)
-	if err != nil {
+// ExportFragment loads the graph, or the part of it matching filter, for
+// an export. Exports load first and encode with the Export* writers after,
+// so a load failure can still be reported before anything is written.
+func (s *GraphService) ExportFragment(ctx context.Context, filter domain.ExportFilter) (*domain.GraphFragment, error) {
+	if err := filter.Normalize(); err != nil {
 		return nil, err
 	}
+	return s.repo.ExportFilteredFragment(ctx, filter)
+}
 
-	var buf bytes.Buffer
-	codec := codec.NewJSONCodec()
-	if err := codec.Export(fragment, &buf); err != nil {
-		return nil, err
+// ExportJSON writes fragment as JSON, compact unless pretty
+func (s *GraphService) ExportJSON(w io.Writer, fragment *domain.GraphFragment, pretty bool) error {
+	exporter := codec.NewCompactJSONCodec()
+	if pretty {
+		exporter = codec.NewJSONCodec()
 	}
+	return exporter.Export(fragment, w)
+}
 
-	return buf.Bytes(), nil
+// ExportYAML writes fragment as YAML
+func (s *GraphService) ExportYAML(w io.Writer, fragment *domain.GraphFragment) error {
+	codec := codec.NewYAMLCodec()
+	return codec.Export(fragment, w)
 }
 
-// ExportYAML exports the graph as YAML
-func (s *GraphService) ExportYAML(ctx context.Context, w io.Writer) error {
-	fragment, err := s.repo.ExportFragment(ctx)
-	if err != nil {
-		return err
-	}
+// ExportAnsibleInventory writes fragment as Ansible inventory
+func (s *GraphService) ExportAnsibleInventory(w io.Writer, fragment *domain.GraphFragment) error {
+	codec := codec.NewAnsibleCodec()
+	return codec.Export(fragment, w)
+}
 
-	codec := codec.NewYAMLCodec()
+// ExportCSV writes the nodes of fragment as CSV
+func (s *GraphService) ExportCSV(w io.Writer, fragment *domain.GraphFragment) error {
+	codec := codec.NewCSVCodec()
 	return codec.Export(fragment, w)
 }
 
-// ExportAnsibleInventory exports the graph as Ansible inventory
-func (s *GraphService) ExportAnsibleInventory(ctx context.Context, w io.Writer) error {
-	fragment, err := s.repo.ExportFragment(ctx)
-	if err != nil {
-		return err
-	}
+// ExportGraphML writes fragment as GraphML, including layout positions
+func (s *GraphService) ExportGraphML(w io.Writer, fragment *domain.GraphFragment, positions map[string]domain.NodePosition) error {
+	codec := codec.NewGraphMLCodec(positions)
+	return codec.Export(fragment, w)
+}
 
-	codec := codec.NewAnsibleCodec()
+// ExportDOT writes fragment as a Graphviz digraph
+func (s *GraphService) ExportDOT(w io.Writer, fragment *domain.GraphFragment) error {
+	codec := codec.NewDOTCodec()
 	return codec.Export(fragment, w)
 }
 
-// ClearGraph removes all nodes, edges, and positions
-func (s *GraphService) ClearGraph(ctx context.Context) error {
-	if err := s.repo.ClearGraph(ctx); err != nil {
-		return err
+// ExportMermaid writes fragment as a Mermaid flowchart of at most maxNodes
+// nodes (zero uses codec.DefaultMermaidMaxNodes)
+func (s *GraphService) ExportMermaid(w io.Writer, fragment *domain.GraphFragment, maxNodes int) error {
+	codec := codec.NewMermaidCodec(maxNodes)
+	return codec.Export(fragment, w)
+}
+
+// ClearGraph removes nodes, edges, positions and discrepancies, or with
+// opts.DryRun only reports what would be removed. opts.KeepTruth keeps
+// nodes the operator has asserted truth for.
+func (s *GraphService) ClearGraph(ctx context.Context, opts domain.ClearOptions) (*domain.ClearResult, error) {
+	result, err := s.repo.ClearGraph(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return result, nil
 	}
 
 	s.eventBus.Publish(Event{
@@ -309,7 +1063,7 @@ func (s *GraphService) ClearGraph(ctx context.Context) error {
 		Payload: map[string]string{"action": "cleared"},
 	})
 
-	return nil
+	return result, nil
 }
 
 // Validation helpers
@@ -324,7 +1078,10 @@ func (s *GraphService) validateNode(node *domain.Node) error {
 	if node.Label == "" {
 		return fmt.Errorf("node label required")
 	}
-	return nil
+	if _, err := node.ProbeConfig(); err != nil {
+		return fmt.Errorf("invalid node: %w", err)
+	}
+	return node.PromoteRole()
 }
 
 func (s *GraphService) validateEdge(edge *domain.Edge) error {
@@ -340,13 +1097,15 @@ func (s *GraphService) validateEdge(edge *domain.Edge) error {
 	if edge.FromID == edge.ToID {
 		return fmt.Errorf("edge from_id and to_id cannot be the same")
 	}
-	return nil
+	return edge.ValidateLinkProperties()
 }
 
 // MergeNodesAsInterfaces merges multiple nodes into a parent with interface children
 // The original nodes are converted to interface type with parent_id set
 // Edges to/from the original nodes are remapped to the corresponding interfaces
-func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []string, parentID string, parentType domain.NodeType) ([]string, error) {
+// The pre-merge state is recorded in the returned operation so UnmergeNodes
+// can undo the merge.
+func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []string, parentID string, parentType domain.NodeType) (*domain.MergeOperation, error) {
 	if len(nodeIDs) < 2 {
 		return nil, fmt.Errorf("at least 2 nodes required for merge")
 	}
@@ -373,6 +1132,14 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 		nodes = append(nodes, node)
 	}
 
+	op, err := s.snapshotMerge(ctx, parentID, nodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateMergeOperation(ctx, op); err != nil {
+		return nil, err
+	}
+
 	// Create parent node
 	parentNode := &domain.Node{
 		ID:     parentID,
@@ -391,10 +1158,9 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 	}
 
 	// Convert each node to an interface
-	interfaceIDs := make([]string, 0, len(nodes))
 	for i, node := range nodes {
 		interfaceName := fmt.Sprintf("eth%d", i)
-		interfaceID := fmt.Sprintf("%s:%s", parentID, interfaceName)
+		interfaceID := op.InterfaceIDs[i]
 
 		// Create new interface node with data from original
 		interfaceNode := &domain.Node{
@@ -404,7 +1170,7 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 			ParentID:   parentID,
 			Source:     node.Source,
 			Status:     node.Status,
-			Properties: node.Properties,
+			Properties: maps.Clone(node.Properties),
 			Discovered: node.Discovered,
 		}
 
@@ -419,8 +1185,6 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 			return nil, fmt.Errorf("failed to create interface node: %w", err)
 		}
 
-		interfaceIDs = append(interfaceIDs, interfaceID)
-
 		// Get edges connected to original node and remap them
 		edges, err := s.repo.ListEdges(ctx, "", node.ID, "")
 		if err != nil {
@@ -444,7 +1208,7 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 		}
 
 		// Delete original node (edges will cascade)
-		if err := s.repo.DeleteNode(ctx, node.ID); err != nil {
+		if err := s.repo.DeleteNode(ctx, node.ID, true); err != nil {
 			return nil, fmt.Errorf("failed to delete original node %s: %w", node.ID, err)
 		}
 	}
@@ -456,10 +1220,274 @@ func (s *GraphService) MergeNodesAsInterfaces(ctx context.Context, nodeIDs []str
 			Payload: map[string]any{
 				"action":     "merge",
 				"parent_id":  parentID,
-				"interfaces": interfaceIDs,
+				"interfaces": op.InterfaceIDs,
+				"merge_id":   op.ID,
+			},
+		})
+	}
+
+	return op, nil
+}
+
+// snapshotMerge records the nodes about to be merged along with their
+// positions and every edge touching them
+func (s *GraphService) snapshotMerge(ctx context.Context, parentID string, nodes []*domain.Node) (*domain.MergeOperation, error) {
+	op := &domain.MergeOperation{
+		ID:            generateID(),
+		ParentID:      parentID,
+		ParentCreated: true,
+		InterfaceIDs:  make([]string, 0, len(nodes)),
+		Nodes:         make([]domain.Node, 0, len(nodes)),
+		Edges:         []domain.Edge{},
+		Positions:     []domain.NodePosition{},
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	seenEdges := make(map[string]bool)
+	for i, node := range nodes {
+		op.InterfaceIDs = append(op.InterfaceIDs, fmt.Sprintf("%s:eth%d", parentID, i))
+		op.Nodes = append(op.Nodes, *node)
+
+		pos, err := s.repo.GetPosition(ctx, node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get position of %s: %w", node.ID, err)
+		}
+		if pos != nil {
+			op.Positions = append(op.Positions, *pos)
+		}
+
+		outgoing, err := s.repo.ListEdges(ctx, "", node.ID, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get edges for node %s: %w", node.ID, err)
+		}
+		incoming, err := s.repo.ListEdges(ctx, "", "", node.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get edges for node %s: %w", node.ID, err)
+		}
+		for _, edge := range append(outgoing, incoming...) {
+			if !seenEdges[edge.ID] {
+				seenEdges[edge.ID] = true
+				op.Edges = append(op.Edges, edge)
+			}
+		}
+	}
+	return op, nil
+}
+
+// UnmergeNodes undoes a merge: the interfaces it created are removed, the
+// original nodes are restored with their types, parents, tags, truth and
+// positions, and their original edges are recreated where both endpoints
+// still exist. The parent is removed if the merge created it.
+func (s *GraphService) UnmergeNodes(ctx context.Context, mergeID string) (*domain.MergeOperation, error) {
+	op, err := s.repo.GetMergeOperation(ctx, mergeID)
+	if err != nil {
+		return nil, err
+	}
+	if op == nil {
+		return nil, fmt.Errorf("merge operation %s not found", mergeID)
+	}
+	if op.UndoneAt != nil {
+		return nil, fmt.Errorf("merge operation %s already undone", mergeID)
+	}
+
+	// Refuse to clobber nodes recreated under an original ID since the merge
+	for _, node := range op.Nodes {
+		existing, err := s.repo.GetNode(ctx, node.ID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.DeletedAt == nil {
+			return nil, fmt.Errorf("node with ID %s already exists", node.ID)
+		}
+	}
+
+	removeIDs := slices.Clone(op.InterfaceIDs)
+	if op.ParentCreated {
+		removeIDs = append(removeIDs, op.ParentID)
+	}
+	for _, id := range removeIDs {
+		existing, err := s.repo.GetNode(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			continue
+		}
+		// Edges are removed explicitly since foreign keys may not cascade
+		outgoing, err := s.repo.ListEdges(ctx, "", id, "")
+		if err != nil {
+			return nil, err
+		}
+		incoming, err := s.repo.ListEdges(ctx, "", "", id)
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range append(outgoing, incoming...) {
+			if err := s.repo.DeleteEdge(ctx, edge.ID); err != nil && !strings.Contains(err.Error(), "not found") {
+				return nil, fmt.Errorf("failed to delete edge %s: %w", edge.ID, err)
+			}
+		}
+		if err := s.repo.DeleteNode(ctx, id, true); err != nil {
+			return nil, fmt.Errorf("failed to delete merged node %s: %w", id, err)
+		}
+	}
+
+	for _, node := range op.Nodes {
+		node.DeletedAt = nil
+		if err := s.repo.UpsertNode(ctx, &node); err != nil {
+			return nil, fmt.Errorf("failed to restore node %s: %w", node.ID, err)
+		}
+		for _, tag := range node.Tags {
+			if err := s.repo.AddTag(ctx, node.ID, tag); err != nil {
+				return nil, fmt.Errorf("failed to restore tags of %s: %w", node.ID, err)
+			}
+		}
+		if node.Truth != nil {
+			if err := s.repo.SetNodeTruth(ctx, node.ID, node.Truth); err != nil {
+				return nil, fmt.Errorf("failed to restore truth of %s: %w", node.ID, err)
+			}
+		}
+	}
+	if err := s.repo.SavePositions(ctx, op.Positions); err != nil {
+		return nil, fmt.Errorf("failed to restore positions: %w", err)
+	}
+
+	for _, edge := range op.Edges {
+		from, err := s.repo.GetNode(ctx, edge.FromID)
+		if err != nil {
+			return nil, err
+		}
+		to, err := s.repo.GetNode(ctx, edge.ToID)
+		if err != nil {
+			return nil, err
+		}
+		if from == nil || to == nil {
+			continue
+		}
+		if err := s.repo.UpsertEdge(ctx, &edge); err != nil {
+			return nil, fmt.Errorf("failed to restore edge %s: %w", edge.ID, err)
+		}
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.repo.MarkMergeOperationUndone(ctx, op.ID, now); err != nil {
+		return nil, err
+	}
+	op.UndoneAt = &now
+
+	restored := make([]string, len(op.Nodes))
+	for i, node := range op.Nodes {
+		restored[i] = node.ID
+	}
+	if s.eventBus != nil {
+		s.eventBus.Publish(Event{
+			Type: EventGraphUpdated,
+			Payload: map[string]any{
+				"action":    "unmerge",
+				"merge_id":  op.ID,
+				"parent_id": op.ParentID,
+				"restored":  restored,
 			},
 		})
 	}
 
-	return interfaceIDs, nil
+	return op, nil
+}
+
+// MergeNodesByIdentity folds mergedID into survivorID when an operator
+// knows both are the same device. Unlike MergeNodesAsInterfaces no children
+// are created: the survivor gains the merged node's tags, its edges and
+// children, and any discovered values, properties and truth it lacks, so
+// the survivor wins every conflict. The merged node is then deleted, which
+// like DeleteNode is refused for a protected node unless forced. All writes
+// happen in one transaction.
+func (s *GraphService) MergeNodesByIdentity(ctx context.Context, survivorID, mergedID string, force bool) (*domain.Node, error) {
+	if survivorID == "" || mergedID == "" {
+		return nil, fmt.Errorf("invalid request: survivor and merged node IDs are required")
+	}
+	if survivorID == mergedID {
+		return nil, fmt.Errorf("invalid request: cannot merge node %s into itself", survivorID)
+	}
+
+	survivor, err := s.liveNode(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := s.liveNode(ctx, mergedID)
+	if err != nil {
+		return nil, err
+	}
+	if merged.Protected && !force {
+		return nil, fmt.Errorf("node %s is protected", mergedID)
+	}
+
+	result := *survivor
+	result.Properties = fillMissing(survivor.Properties, merged.Properties)
+	result.Discovered = fillMissing(survivor.Discovered, merged.Discovered)
+	result.DiscoveredSources = maps.Clone(survivor.DiscoveredSources)
+	for key, src := range merged.DiscoveredSources {
+		if _, ok := result.DiscoveredSources[key]; !ok {
+			if result.DiscoveredSources == nil {
+				result.DiscoveredSources = make(map[string]domain.DiscoveredSource)
+			}
+			result.DiscoveredSources[key] = src
+		}
+	}
+	if result.Role == "" {
+		result.Role = merged.Role
+	}
+	if result.FirstSeen == nil || (merged.FirstSeen != nil && merged.FirstSeen.Before(*result.FirstSeen)) {
+		result.FirstSeen = merged.FirstSeen
+	}
+
+	// Leaving Truth nil keeps the survivor's truth as it is
+	result.Truth = nil
+	if merged.Truth != nil {
+		truth := &domain.NodeTruth{}
+		if survivor.Truth != nil {
+			*truth = *survivor.Truth
+		} else {
+			truth.AssertedBy = merged.Truth.AssertedBy
+			truth.AssertedAt = merged.Truth.AssertedAt
+		}
+		truth.Properties = fillMissing(truth.Properties, merged.Truth.Properties)
+		result.Truth = truth
+	}
+
+	if err := s.repo.MergeNodeIdentity(ctx, &result, mergedID); err != nil {
+		return nil, fmt.Errorf("failed to merge node %s into %s: %w", mergedID, survivorID, err)
+	}
+
+	node, err := s.GetNode(ctx, survivorID)
+	if err != nil {
+		return nil, err
+	}
+	if s.eventBus != nil {
+		s.eventBus.Publish(Event{
+			Type: EventNodeMerged,
+			Payload: map[string]any{
+				"id":        survivorID,
+				"merged_id": mergedID,
+				"node":      node,
+			},
+		})
+	}
+	return node, nil
+}
+
+// fillMissing returns a copy of dst with the keys only src has added
+func fillMissing(dst, src map[string]any) map[string]any {
+	if len(dst) == 0 && len(src) == 0 {
+		return dst
+	}
+	result := maps.Clone(dst)
+	if result == nil {
+		result = make(map[string]any, len(src))
+	}
+	for key, value := range src {
+		if _, ok := result[key]; !ok {
+			result[key] = value
+		}
+	}
+	return result
 }
@@ -1,9 +1,19 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"specularium/internal/domain"
+	"specularium/internal/metrics"
+	"specularium/internal/repository/sqlite"
 )
 
 func TestGraphServiceValidateNode(t *testing.T) {
@@ -128,3 +138,1801 @@ func TestImportResult(t *testing.T) {
 	})
 }
 
+
+// newTestRepo creates a repository backed by a temporary database file
+func newTestRepo(t *testing.T) *sqlite.Repository {
+	t.Helper()
+	repo, err := sqlite.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to create test repository: %v", err)
+	}
+	t.Cleanup(func() {
+		repo.Close()
+	})
+	return repo
+}
+
+// newTestEventBus returns an event bus and a channel receiving every published event
+func newTestEventBus() (*EventBus, chan Event) {
+	events := make(chan Event, 64)
+	bus := NewEventBus()
+	bus.Subscribe(events)
+	return bus, events
+}
+
+// newTestGraphService creates a GraphService backed by a temporary database
+// and returns a channel receiving every published event
+func newTestGraphService(t *testing.T) (*GraphService, chan Event) {
+	t.Helper()
+	bus, events := newTestEventBus()
+	return NewGraphService(newTestRepo(t), bus), events
+}
+
+// nextEvent returns the next published event or fails the test
+func nextEvent(t *testing.T, events chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	default:
+		t.Fatal("expected an event to be published")
+		return Event{}
+	}
+}
+
+func TestGraphServiceEntityEvents(t *testing.T) {
+	svc, events := newTestGraphService(t)
+	ctx := context.Background()
+
+	t.Run("node lifecycle publishes entities", func(t *testing.T) {
+		node := domain.NewNode("web1", domain.NodeTypeServer, "Web 1")
+		node.SetProperty("ip", "10.0.0.10")
+		if err := svc.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+		e := nextEvent(t, events)
+		created, ok := e.Payload.(*domain.Node)
+		if e.Type != EventNodeCreated || !ok || created.ID != "web1" {
+			t.Fatalf("got %s %#v, want node-created with node web1", e.Type, e.Payload)
+		}
+		if created.Label != "Web 1" || created.Type != domain.NodeTypeServer || created.GetPropertyString("ip") != "10.0.0.10" {
+			t.Errorf("node-created payload = %+v, want the full node body", created)
+		}
+
+		if err := svc.UpdateNode(ctx, "web1", map[string]interface{}{"label": "Web One"}); err != nil {
+			t.Fatalf("UpdateNode failed: %v", err)
+		}
+		e = nextEvent(t, events)
+		if updated, ok := e.Payload.(*domain.Node); e.Type != EventNodeUpdated || !ok || updated.Label != "Web One" {
+			t.Errorf("got %s %#v, want node-updated with new label", e.Type, e.Payload)
+		}
+
+		if _, err := svc.DeleteNode(ctx, "web1", false, false); err != nil {
+			t.Fatalf("DeleteNode failed: %v", err)
+		}
+		e = nextEvent(t, events)
+		if deleted, ok := e.Payload.(map[string]string); e.Type != EventNodeDeleted || !ok || deleted["id"] != "web1" {
+			t.Errorf("got %s %#v, want node-deleted with id web1", e.Type, e.Payload)
+		}
+	})
+
+	t.Run("edge lifecycle publishes entities", func(t *testing.T) {
+		for _, id := range []string{"a", "b"} {
+			if err := svc.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)); err != nil {
+				t.Fatalf("CreateNode failed: %v", err)
+			}
+			nextEvent(t, events)
+		}
+
+		edge := domain.NewEdge("a", "b", domain.EdgeTypeEthernet)
+		if err := svc.CreateEdge(ctx, edge); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+		e := nextEvent(t, events)
+		if created, ok := e.Payload.(*domain.Edge); e.Type != EventEdgeCreated || !ok || created.ID != edge.ID {
+			t.Errorf("got %s %#v, want edge-created with edge %s", e.Type, e.Payload, edge.ID)
+		}
+
+		if err := svc.DeleteEdge(ctx, edge.ID); err != nil {
+			t.Fatalf("DeleteEdge failed: %v", err)
+		}
+		e = nextEvent(t, events)
+		if deleted, ok := e.Payload.(map[string]string); e.Type != EventEdgeDeleted || !ok || deleted["id"] != edge.ID {
+			t.Errorf("got %s %#v, want edge-deleted with id %s", e.Type, e.Payload, edge.ID)
+		}
+	})
+
+	t.Run("restore publishes node and edges", func(t *testing.T) {
+		edge := domain.NewEdge("a", "b", domain.EdgeTypeEthernet)
+		if err := svc.CreateEdge(ctx, edge); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+		nextEvent(t, events)
+		if _, err := svc.DeleteNode(ctx, "b", false, false); err != nil {
+			t.Fatalf("DeleteNode failed: %v", err)
+		}
+		nextEvent(t, events)
+
+		if _, err := svc.RestoreNode(ctx, "b"); err != nil {
+			t.Fatalf("RestoreNode failed: %v", err)
+		}
+		e := nextEvent(t, events)
+		if restored, ok := e.Payload.(*domain.Node); e.Type != EventNodeCreated || !ok || restored.ID != "b" {
+			t.Errorf("got %s %#v, want node-created with node b", e.Type, e.Payload)
+		}
+		e = nextEvent(t, events)
+		if restored, ok := e.Payload.(*domain.Edge); e.Type != EventEdgeCreated || !ok || restored.ID != edge.ID {
+			t.Errorf("got %s %#v, want edge-created with edge %s", e.Type, e.Payload, edge.ID)
+		}
+	})
+}
+
+func TestGraphServiceSoftDeletedNodeNotFound(t *testing.T) {
+	svc, _ := newTestGraphService(t)
+	ctx := context.Background()
+
+	if err := svc.CreateNode(ctx, domain.NewNode("nas", domain.NodeTypeServer, "NAS")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := svc.SetNodeImage(ctx, "nas", "image/png", []byte("\x89PNG")); err != nil {
+		t.Fatalf("SetNodeImage failed: %v", err)
+	}
+	if _, err := svc.DeleteNode(ctx, "nas", false, false); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+
+	checks := map[string]error{
+		"GetNode":         func() error { _, err := svc.GetNode(ctx, "nas"); return err }(),
+		"UpdateNode":      svc.UpdateNode(ctx, "nas", map[string]interface{}{"label": "renamed"}),
+		"SetNodeImage":    svc.SetNodeImage(ctx, "nas", "image/png", []byte("\x89PNG")),
+		"GetNodeImage":    func() error { _, err := svc.GetNodeImage(ctx, "nas"); return err }(),
+		"DeleteNodeImage": svc.DeleteNodeImage(ctx, "nas"),
+	}
+	for name, err := range checks {
+		if err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("%s on a soft-deleted node error = %v, want not found", name, err)
+		}
+	}
+
+	node, err := svc.RestoreNode(ctx, "nas")
+	if err != nil {
+		t.Fatalf("RestoreNode failed: %v", err)
+	}
+	if node.Label != "NAS" {
+		t.Errorf("label = %q, want the update refused while deleted", node.Label)
+	}
+}
+
+func TestGraphServiceNodeImage(t *testing.T) {
+	svc, events := newTestGraphService(t)
+	ctx := context.Background()
+
+	node := domain.NewNode("nas", domain.NodeTypeServer, "NAS")
+	if err := svc.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	nextEvent(t, events)
+
+	tests := []struct {
+		name        string
+		id          string
+		contentType string
+		data        []byte
+		wantErr     bool
+	}{
+		{"valid png", "nas", "image/png", []byte("\x89PNG"), false},
+		{"unsupported type", "nas", "image/svg+xml", []byte("<svg/>"), true},
+		{"empty image", "nas", "image/png", nil, true},
+		{"too large", "nas", "image/png", make([]byte, domain.MaxNodeImageSize+1), true},
+		{"unknown node", "ghost", "image/png", []byte("\x89PNG"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.SetNodeImage(ctx, tt.id, tt.contentType, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SetNodeImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	e := nextEvent(t, events)
+	if updated, ok := e.Payload.(*domain.Node); e.Type != EventNodeUpdated || !ok || !updated.HasImage {
+		t.Errorf("got %s %#v, want node-updated with has_image", e.Type, e.Payload)
+	}
+
+	if _, err := svc.DeleteNode(ctx, "nas", true, false); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+	if _, err := svc.GetNodeImage(ctx, "nas"); err == nil {
+		t.Error("image should be removed with its node")
+	}
+}
+
+func TestGraphServiceProtectedNode(t *testing.T) {
+	svc, events := newTestGraphService(t)
+	ctx := context.Background()
+
+	if err := svc.CreateNode(ctx, domain.NewNode("core-sw", domain.NodeTypeSwitch, "Core Switch")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	nextEvent(t, events)
+
+	if err := svc.UpdateNode(ctx, "core-sw", map[string]interface{}{"protected": "yes"}); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("UpdateNode(protected: \"yes\") error = %v, want invalid", err)
+	}
+	if err := svc.UpdateNode(ctx, "core-sw", map[string]interface{}{"protected": true}); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	e := nextEvent(t, events)
+	if updated, ok := e.Payload.(*domain.Node); e.Type != EventNodeUpdated || !ok || !updated.Protected {
+		t.Errorf("got %s %#v, want node-updated with protected", e.Type, e.Payload)
+	}
+
+	if _, err := svc.DeleteNode(ctx, "core-sw", false, false); err == nil || !strings.Contains(err.Error(), "is protected") {
+		t.Fatalf("DeleteNode error = %v, want protected", err)
+	}
+	if _, err := svc.GetNode(ctx, "core-sw"); err != nil {
+		t.Errorf("protected node should survive delete: %v", err)
+	}
+
+	// Label edits keep the flag
+	if err := svc.UpdateNode(ctx, "core-sw", map[string]interface{}{"label": "Core"}); err != nil {
+		t.Fatalf("UpdateNode failed: %v", err)
+	}
+	if node, _ := svc.GetNode(ctx, "core-sw"); node == nil || !node.Protected {
+		t.Errorf("node = %+v, want still protected", node)
+	}
+
+	if _, err := svc.DeleteNode(ctx, "core-sw", false, true); err != nil {
+		t.Fatalf("forced DeleteNode failed: %v", err)
+	}
+	if node, _ := svc.GetNode(ctx, "core-sw"); node != nil && node.DeletedAt == nil {
+		t.Error("forced delete should remove the protected node")
+	}
+}
+
+func TestEventBusHistory(t *testing.T) {
+	bus, events := newTestEventBus()
+	bus.SetHistorySize(3)
+
+	for i := 0; i < 5; i++ {
+		bus.Publish(Event{Type: EventNodeUpdated, Payload: i})
+	}
+
+	first := nextEvent(t, events)
+	if first.Seq != 1 || first.Timestamp.IsZero() {
+		t.Errorf("first event seq = %d, timestamp = %v; want seq 1 with a timestamp", first.Seq, first.Timestamp)
+	}
+
+	// Ring keeps the newest three, oldest first
+	history := bus.History(0)
+	if len(history) != 3 {
+		t.Fatalf("got %d events, want 3", len(history))
+	}
+	for i, e := range history {
+		if want := uint64(i + 3); e.Seq != want {
+			t.Errorf("history[%d].Seq = %d, want %d", i, e.Seq, want)
+		}
+	}
+
+	limited := bus.History(2)
+	if len(limited) != 2 || limited[0].Seq != 4 || limited[1].Seq != 5 {
+		t.Errorf("History(2) = %+v, want seqs 4, 5", limited)
+	}
+
+	if since, ok := bus.Since(3); !ok || len(since) != 2 || since[0].Seq != 4 {
+		t.Errorf("Since(3) = %+v, %v; want seqs 4, 5", since, ok)
+	}
+	if _, ok := bus.Since(1); ok {
+		t.Error("Since(1) should report the gap as unavailable")
+	}
+	if _, ok := bus.Since(9); ok {
+		t.Error("Since() with a future seq should report unavailable")
+	}
+
+	// Shrinking keeps the newest events
+	bus.SetHistorySize(1)
+	if h := bus.History(0); len(h) != 1 || h[0].Seq != 5 {
+		t.Errorf("after shrink History = %+v, want seq 5", h)
+	}
+
+	bus.SetHistorySize(0)
+	bus.Publish(Event{Type: EventNodeUpdated})
+	if h := bus.History(0); len(h) != 0 {
+		t.Errorf("disabled history returned %d events", len(h))
+	}
+}
+
+func TestEventBusSubscribeFilter(t *testing.T) {
+	bus, all := newTestEventBus()
+	discrepancies := make(chan Event, 8)
+	bus.Subscribe(discrepancies, MatchTypes(EventDiscrepancyCreated))
+
+	bus.Publish(Event{Type: EventDiscoveryProgress})
+	bus.Publish(Event{Type: EventDiscrepancyCreated})
+	bus.Publish(Event{Type: EventDiscoveryProgress})
+
+	if e := nextEvent(t, discrepancies); e.Type != EventDiscrepancyCreated || e.Seq != 2 {
+		t.Errorf("got %s seq %d, want discrepancy-created seq 2", e.Type, e.Seq)
+	}
+	if len(discrepancies) != 0 {
+		t.Errorf("filtered subscriber got %d extra events", len(discrepancies))
+	}
+	if len(all) != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3", len(all))
+	}
+}
+
+func TestEventBusOverflow(t *testing.T) {
+	fill := func(policy OverflowPolicy) (*EventBus, chan Event) {
+		bus := NewEventBus()
+		bus.SetOverflowPolicy(policy)
+		ch := make(chan Event, 2)
+		bus.Subscribe(ch)
+		for i := 0; i < 3; i++ {
+			bus.Publish(Event{Type: EventDiscoveryProgress})
+		}
+		return bus, ch
+	}
+	seqs := func(ch chan Event) []uint64 {
+		var out []uint64
+		for len(ch) > 0 {
+			out = append(out, (<-ch).Seq)
+		}
+		return out
+	}
+
+	t.Run("drop newest", func(t *testing.T) {
+		bus, ch := fill(OverflowDropNewest)
+		if got := seqs(ch); !slices.Equal(got, []uint64{1, 2}) {
+			t.Errorf("queued seqs = %v, want [1 2]", got)
+		}
+		if bus.Dropped() != 1 {
+			t.Errorf("Dropped() = %d, want 1", bus.Dropped())
+		}
+	})
+
+	t.Run("drop oldest", func(t *testing.T) {
+		bus, ch := fill(OverflowDropOldest)
+		if got := seqs(ch); !slices.Equal(got, []uint64{2, 3}) {
+			t.Errorf("queued seqs = %v, want [2 3]", got)
+		}
+		if bus.Dropped() != 1 {
+			t.Errorf("Dropped() = %d, want 1", bus.Dropped())
+		}
+	})
+
+	t.Run("block", func(t *testing.T) {
+		bus := NewEventBus()
+		bus.SetOverflowPolicy(OverflowBlock)
+		ch := make(chan Event, 1)
+		bus.Subscribe(ch)
+		bus.Publish(Event{Type: EventDiscoveryProgress})
+
+		published := make(chan struct{})
+		go func() {
+			bus.Publish(Event{Type: EventDiscoveryProgress})
+			close(published)
+		}()
+		select {
+		case <-published:
+			t.Fatal("Publish() did not block on a full subscriber")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		if e := nextEvent(t, ch); e.Seq != 1 {
+			t.Errorf("first event seq = %d, want 1", e.Seq)
+		}
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatal("Publish() did not proceed once the subscriber drained")
+		}
+		if e := nextEvent(t, ch); e.Seq != 2 {
+			t.Errorf("second event seq = %d, want 2", e.Seq)
+		}
+		if bus.Dropped() != 0 {
+			t.Errorf("Dropped() = %d, want 0", bus.Dropped())
+		}
+	})
+
+	t.Run("drops are exported", func(t *testing.T) {
+		bus := NewEventBus()
+		reg := metrics.NewRegistry()
+		bus.RegisterMetrics(reg)
+		bus.Subscribe(make(chan Event))
+		bus.Publish(Event{Type: EventNodeUpdated})
+
+		var out strings.Builder
+		if err := reg.Write(context.Background(), &out); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if want := `specularium_events_dropped_total{type="node-updated"} 1`; !strings.Contains(out.String(), want) {
+			t.Errorf("metrics output missing %q:\n%s", want, out.String())
+		}
+	})
+
+	if _, err := ParseOverflowPolicy("drop-everything"); err == nil || !strings.Contains(err.Error(), "invalid overflow policy") {
+		t.Errorf("ParseOverflowPolicy() error = %v, want invalid overflow policy", err)
+	}
+}
+
+func TestGraphServiceCreateNodes(t *testing.T) {
+	ctx := context.Background()
+	svc, events := newTestGraphService(t)
+
+	results, err := svc.CreateNodes(ctx, []*domain.Node{
+		domain.NewNode("a", domain.NodeTypeServer, "A"),
+		{ID: "b", Type: domain.NodeTypeServer}, // missing label
+	}, true)
+	if err != nil {
+		t.Fatalf("CreateNodes() error = %v", err)
+	}
+	if results[0].Created || results[0].Error == "" || results[1].Error == "" {
+		t.Errorf("atomic batch with an invalid node should create nothing: %+v", results)
+	}
+	if len(events) != 0 {
+		t.Errorf("rolled back batch published %d events", len(events))
+	}
+
+	results, err = svc.CreateNodes(ctx, []*domain.Node{
+		domain.NewNode("a", domain.NodeTypeServer, "A"),
+		{ID: "b", Type: domain.NodeTypeServer},
+		domain.NewNode("c", domain.NodeTypeServer, "C"),
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateNodes() error = %v", err)
+	}
+	if !results[0].Created || results[1].Created || !results[2].Created {
+		t.Errorf("results = %+v, want a and c created", results)
+	}
+	for _, want := range []string{"a", "c"} {
+		e := nextEvent(t, events)
+		if node, ok := e.Payload.(*domain.Node); e.Type != EventNodeCreated || !ok || node.ID != want {
+			t.Errorf("event = %s %+v, want node-created for %s", e.Type, e.Payload, want)
+		}
+	}
+}
+
+func TestGraphServiceGetStats(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	seed := []struct {
+		id       string
+		nodeType domain.NodeType
+		status   domain.NodeStatus
+	}{
+		{"sw", domain.NodeTypeSwitch, domain.NodeStatusVerified},
+		{"web", domain.NodeTypeServer, domain.NodeStatusVerified},
+		{"db", domain.NodeTypeServer, domain.NodeStatusUnreachable},
+		{"cam", domain.NodeTypeServer, domain.NodeStatusDegraded},
+		{"old", domain.NodeTypeServer, domain.NodeStatusVerified},
+	}
+	for _, s := range seed {
+		n := domain.NewNode(s.id, s.nodeType, s.id)
+		n.Status = s.status
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", s.id, err)
+		}
+	}
+	for _, e := range []*domain.Edge{
+		domain.NewEdge("sw", "web", domain.EdgeTypeEthernet),
+		domain.NewEdge("sw", "db", domain.EdgeTypeEthernet),
+		domain.NewEdge("web", "db", domain.EdgeTypeVirtual),
+		domain.NewEdge("sw", "old", domain.EdgeTypeEthernet),
+	} {
+		if err := repo.CreateEdge(ctx, e); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+
+	// Soft-deleted nodes and their edges are excluded
+	if err := repo.DeleteNode(ctx, "old", false); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+
+	if err := repo.SetNodeTruth(ctx, "web", &domain.NodeTruth{Properties: map[string]any{"ip": "10.0.0.10"}}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	d := &domain.Discrepancy{ID: "d1", NodeID: "web", PropertyKey: "ip", Source: "test", DetectedAt: time.Now()}
+	if err := repo.CreateDiscrepancy(ctx, d); err != nil {
+		t.Fatalf("CreateDiscrepancy failed: %v", err)
+	}
+
+	stats, err := svc.GetStats(ctx)
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+
+	if stats.Nodes != 4 || stats.Edges != 3 {
+		t.Errorf("totals = %d nodes, %d edges, want 4 and 3", stats.Nodes, stats.Edges)
+	}
+	if stats.NodesByType[domain.NodeTypeServer] != 3 || stats.NodesByType[domain.NodeTypeSwitch] != 1 {
+		t.Errorf("nodes_by_type = %v", stats.NodesByType)
+	}
+	if stats.EdgesByType[domain.EdgeTypeEthernet] != 2 || stats.EdgesByType[domain.EdgeTypeVirtual] != 1 {
+		t.Errorf("edges_by_type = %v", stats.EdgesByType)
+	}
+	wantStatus := map[domain.NodeStatus]int{
+		domain.NodeStatusVerified:    2,
+		domain.NodeStatusUnreachable: 1,
+		domain.NodeStatusDegraded:    1,
+		domain.NodeStatusUnverified:  0,
+	}
+	for status, want := range wantStatus {
+		if got, ok := stats.NodesByStatus[status]; !ok || got != want {
+			t.Errorf("nodes_by_status[%s] = %d (present %v), want %d", status, got, ok, want)
+		}
+	}
+	if stats.NodesWithTruth != 1 || stats.OpenDiscrepancies != 1 {
+		t.Errorf("truth = %d, discrepancies = %d, want 1 and 1", stats.NodesWithTruth, stats.OpenDiscrepancies)
+	}
+}
+
+func TestGraphServiceInferSubnetEdges(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	seed := map[string]string{
+		"web":     "192.168.1.0/24",
+		"db":      "192.168.1.0/24",
+		"cam":     "10.0.5.0/24",
+		"printer": "",
+	}
+	for id, segmentum := range seed {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		if segmentum != "" {
+			node.SetProperty("segmentum", segmentum)
+		}
+		if err := repo.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+
+	result, err := svc.InferSubnetEdges(ctx)
+	if err != nil {
+		t.Fatalf("InferSubnetEdges failed: %v", err)
+	}
+	if result.Subnets != 2 || result.NodesCreated != 2 || result.EdgesCreated != 3 {
+		t.Errorf("result = %+v, want 2 subnets, 2 nodes, 3 edges", result)
+	}
+
+	members := func(subnetID string) []string {
+		t.Helper()
+		edges, err := repo.ListEdges(ctx, "", "", subnetID)
+		if err != nil {
+			t.Fatalf("ListEdges failed: %v", err)
+		}
+		ids := make([]string, 0, len(edges))
+		for _, e := range edges {
+			if e.Properties["source"] != InferredSource {
+				t.Errorf("edge %s source = %v, want %s", e.ID, e.Properties["source"], InferredSource)
+			}
+			ids = append(ids, e.FromID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	if got := members("subnet-192-168-1-0-24"); strings.Join(got, ",") != "db,web" {
+		t.Errorf("192.168.1.0/24 members = %v, want db,web", got)
+	}
+	if got := members("subnet-10-0-5-0-24"); strings.Join(got, ",") != "cam" {
+		t.Errorf("10.0.5.0/24 members = %v, want cam", got)
+	}
+
+	// Re-running adds nothing
+	again, err := svc.InferSubnetEdges(ctx)
+	if err != nil {
+		t.Fatalf("second InferSubnetEdges failed: %v", err)
+	}
+	if again.NodesCreated != 0 || again.EdgesCreated != 0 {
+		t.Errorf("re-run result = %+v, want nothing created", again)
+	}
+	edges, _ := repo.ListEdges(ctx, "", "", "")
+	if len(edges) != 3 {
+		t.Errorf("got %d edges after re-run, want 3", len(edges))
+	}
+}
+
+func TestGraphServiceSuggestEdgeType(t *testing.T) {
+	svc, _ := newTestGraphService(t)
+	ctx := context.Background()
+
+	node := func(id string, nodeType domain.NodeType, parent, segmentum string) {
+		t.Helper()
+		n := domain.NewNode(id, nodeType, id)
+		n.ParentID = parent
+		if segmentum != "" {
+			n.SetProperty("segmentum", segmentum)
+		}
+		if err := svc.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	node("host", domain.NodeTypeServer, "", "192.168.1.0/24")
+	node("host-eth0", domain.NodeTypeInterface, "host", "192.168.1.0/24")
+	node("db", domain.NodeTypeServer, "", "192.168.1.7/24")
+	node("cam", domain.NodeTypeServer, "", "10.0.5.0/24")
+	node("core", domain.NodeTypeSwitch, "", "")
+	node("edge", domain.NodeTypeRouter, "", "")
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     domain.EdgeType
+		wantErr  string
+	}{
+		{"interface to parent", "host-eth0", "host", domain.EdgeTypeVirtual, ""},
+		{"parent to interface", "host", "host-eth0", domain.EdgeTypeVirtual, ""},
+		{"same segmentum", "host", "db", domain.EdgeTypeEthernet, ""},
+		{"cross subnet", "db", "cam", domain.EdgeTypeAggregation, ""},
+		{"network devices", "core", "edge", domain.EdgeTypeAggregation, ""},
+		{"default", "core", "cam", domain.EdgeTypeEthernet, ""},
+		{"same node", "db", "db", "", "invalid"},
+		{"unknown node", "db", "ghost", "", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.SuggestEdgeType(ctx, tt.from, tt.to)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("SuggestEdgeType error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SuggestEdgeType failed: %v", err)
+			}
+			if got.Type != tt.want || got.Reason == "" {
+				t.Errorf("SuggestEdgeType = %+v, want type %s with a reason", got, tt.want)
+			}
+		})
+	}
+
+	// Suggesting never creates the edge
+	if edges, _ := svc.ListEdges(ctx, domain.EdgeFilter{}); len(edges) != 0 {
+		t.Errorf("got %d edges, want none", len(edges))
+	}
+}
+
+func TestGraphServiceSubnets(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	// A scan records the range and creates its hosts with a segmentum
+	if err := repo.EnsureSubnet(ctx, "10.9.0.0/24"); err != nil {
+		t.Fatalf("EnsureSubnet failed: %v", err)
+	}
+	host := domain.NewNode("scan-host", domain.NodeTypeServer, "scan-host")
+	host.SetProperty("segmentum", "192.168.50.7/24")
+	if err := repo.CreateNode(ctx, host); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	subnets, err := svc.ListSubnets(ctx)
+	if err != nil {
+		t.Fatalf("ListSubnets failed: %v", err)
+	}
+	if len(subnets) != 2 || subnets[0].CIDR != "10.9.0.0/24" || subnets[1].CIDR != "192.168.50.0/24" {
+		t.Fatalf("subnets = %+v, want 10.9.0.0/24 and 192.168.50.0/24", subnets)
+	}
+	if subnets[0].MemberCount != 0 || subnets[1].MemberCount != 1 {
+		t.Errorf("member counts = %d, %d, want 0 and 1", subnets[0].MemberCount, subnets[1].MemberCount)
+	}
+
+	updated, err := svc.UpdateSubnet(ctx, "192.168.50.0/24", &domain.Subnet{VLANID: 50, Gateway: "192.168.50.1"})
+	if err != nil {
+		t.Fatalf("UpdateSubnet failed: %v", err)
+	}
+	if updated.VLANID != 50 || updated.Gateway != "192.168.50.1" || updated.MemberCount != 1 {
+		t.Errorf("updated subnet = %+v", updated)
+	}
+	if _, err := svc.UpdateSubnet(ctx, "192.168.50.0/24", &domain.Subnet{Gateway: "10.0.0.1"}); err == nil || !strings.Contains(err.Error(), "invalid gateway") {
+		t.Errorf("gateway outside subnet error = %v, want invalid gateway", err)
+	}
+	if _, err := svc.CreateSubnet(ctx, &domain.Subnet{CIDR: "192.168.50.0/24"}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("duplicate create error = %v, want already exists", err)
+	}
+
+	err = svc.DeleteSubnet(ctx, "192.168.50.0/24")
+	if err == nil || !strings.Contains(err.Error(), "member nodes") {
+		t.Fatalf("DeleteSubnet with members error = %v, want member nodes", err)
+	}
+
+	if _, err := svc.DeleteNode(ctx, "scan-host", true, false); err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+	if err := svc.DeleteSubnet(ctx, "192.168.50.0/24"); err != nil {
+		t.Fatalf("DeleteSubnet without members failed: %v", err)
+	}
+	if _, err := svc.GetSubnet(ctx, "192.168.50.0/24"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetSubnet after delete error = %v, want not found", err)
+	}
+}
+
+func TestGraphServiceGetNeighborhood(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	// Chain a -> b <- c -> d; edge direction must not matter
+	for _, id := range []string{"a", "b", "c", "d"} {
+		if err := repo.CreateNode(ctx, domain.NewNode(id, domain.NodeTypeServer, id)); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	for _, pair := range [][2]string{{"a", "b"}, {"c", "b"}, {"c", "d"}} {
+		if err := repo.CreateEdge(ctx, domain.NewEdge(pair[0], pair[1], domain.EdgeTypeEthernet)); err != nil {
+			t.Fatalf("CreateEdge(%s, %s) failed: %v", pair[0], pair[1], err)
+		}
+	}
+
+	ids := func(f *domain.GraphFragment) string {
+		out := make([]string, 0, len(f.Nodes))
+		for _, n := range f.Nodes {
+			out = append(out, n.ID)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		depth     int
+		wantNodes string
+		wantEdges int
+	}{
+		{1, "a,b", 1},
+		{2, "a,b,c", 2},
+		{10, "a,b,c,d", 3}, // capped at MaxNeighborDepth
+	}
+	for _, tt := range tests {
+		fragment, err := svc.GetNeighborhood(ctx, "a", tt.depth)
+		if err != nil {
+			t.Fatalf("GetNeighborhood(depth=%d) failed: %v", tt.depth, err)
+		}
+		if got := ids(fragment); got != tt.wantNodes {
+			t.Errorf("depth=%d nodes = %s, want %s", tt.depth, got, tt.wantNodes)
+		}
+		if len(fragment.Edges) != tt.wantEdges {
+			t.Errorf("depth=%d edges = %d, want %d", tt.depth, len(fragment.Edges), tt.wantEdges)
+		}
+	}
+
+	if _, err := svc.GetNeighborhood(ctx, "missing", 1); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestGraphServiceCapabilities(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	node := domain.NewNode("k8s-worker", domain.NodeTypeServer, "k8s-worker")
+	node.AddEvidence(domain.CapabilityKubernetes, domain.Evidence{
+		Source:     domain.EvidenceSourceK8sAPI,
+		Property:   "is_k8s_node",
+		Value:      true,
+		Confidence: domain.EvidenceConfidence[domain.EvidenceSourceK8sAPI],
+		ObservedAt: time.Now(),
+	})
+	node.AddEvidence(domain.CapabilityKubernetes, domain.Evidence{
+		Source:     domain.EvidenceSourcePortScan,
+		Property:   "kubelet_port",
+		Value:      10250,
+		Confidence: domain.EvidenceConfidence[domain.EvidenceSourcePortScan],
+		ObservedAt: time.Now(),
+	})
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	graph, err := svc.GetGraph(ctx)
+	if err != nil {
+		t.Fatalf("GetGraph failed: %v", err)
+	}
+	k8s := graph.Nodes[0].Capabilities[domain.CapabilityKubernetes]
+	if k8s == nil {
+		t.Fatal("graph node is missing its kubernetes capability")
+	}
+	if k8s.Status != "confirmed" {
+		t.Errorf("status = %q (confidence %.2f), want confirmed", k8s.Status, k8s.Confidence)
+	}
+	if len(k8s.Evidence) != 0 {
+		t.Errorf("graph capability carries %d evidence entries, want none", len(k8s.Evidence))
+	}
+
+	caps, err := svc.GetNodeCapabilities(ctx, "k8s-worker")
+	if err != nil {
+		t.Fatalf("GetNodeCapabilities failed: %v", err)
+	}
+	if got := caps[domain.CapabilityKubernetes]; got == nil || len(got.Evidence) != 2 || got.Status != "confirmed" {
+		t.Errorf("capabilities = %+v, want confirmed with 2 evidence entries", got)
+	}
+
+	if _, err := svc.GetNodeCapabilities(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing node error = %v, want not found", err)
+	}
+}
+
+func TestGraphServiceImportCSV(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	data := "id,label,type,ip,tags\nweb,Web,server,10.0.0.5,prod\nbroken,,,not-an-ip,\n"
+
+	t.Run("atomic aborts on bad rows", func(t *testing.T) {
+		result, err := svc.ImportCSV(ctx, strings.NewReader(data), "merge", true)
+		if err != nil {
+			t.Fatalf("ImportCSV failed: %v", err)
+		}
+		if !result.RolledBack || len(result.RowErrors) != 1 || result.NodesCreated != 0 {
+			t.Errorf("result = %+v", result)
+		}
+		if node, _ := repo.GetNode(ctx, "web"); node != nil {
+			t.Error("atomic import should not write any nodes")
+		}
+	})
+
+	t.Run("non-atomic imports good rows", func(t *testing.T) {
+		result, err := svc.ImportCSV(ctx, strings.NewReader(data), "merge", false)
+		if err != nil {
+			t.Fatalf("ImportCSV failed: %v", err)
+		}
+		if result.RolledBack || result.NodesCreated != 1 || len(result.RowErrors) != 1 || result.RowErrors[0].Row != 3 {
+			t.Errorf("result = %+v", result)
+		}
+
+		node, err := repo.GetNode(ctx, "web")
+		if err != nil || node == nil {
+			t.Fatalf("GetNode(web) = %v, %v", node, err)
+		}
+		if node.GetPropertyString("ip") != "10.0.0.5" || strings.Join(node.Tags, ",") != "prod" {
+			t.Errorf("imported node = %+v", node)
+		}
+
+		fragment, err := svc.ExportFragment(ctx, domain.ExportFilter{})
+		if err != nil {
+			t.Fatalf("ExportFragment failed: %v", err)
+		}
+		var buf strings.Builder
+		if err := svc.ExportCSV(&buf, fragment); err != nil {
+			t.Fatalf("ExportCSV failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), "web,Web,server,10.0.0.5,csv,prod") {
+			t.Errorf("ExportCSV = %q", buf.String())
+		}
+	})
+}
+
+func TestGraphServiceImportPrometheusSD(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	data := `[
+		{"targets": ["10.0.0.5:9100", "nas.lan:9100"], "labels": {"job": "node", "env": "prod", "__meta_x": "skip"}},
+		{"targets": ["10.0.0.5:9182"], "labels": {"job": "windows", "env": "prod"}}
+	]`
+
+	result, err := svc.ImportPrometheusSD(ctx, []byte(data), "merge")
+	if err != nil {
+		t.Fatalf("ImportPrometheusSD failed: %v", err)
+	}
+	if result.NodesCreated != 2 || result.Strategy != "merge" {
+		t.Errorf("result = %+v, want 2 nodes created", result)
+	}
+
+	// Both ports fold into one node; the later group's job label wins
+	host, err := repo.GetNode(ctx, "10-0-0-5")
+	if err != nil || host == nil {
+		t.Fatalf("GetNode(10-0-0-5) = %v, %v", host, err)
+	}
+	if host.GetPropertyString("ip") != "10.0.0.5" || host.GetPropertyString("job") != "windows" || host.GetPropertyString("env") != "prod" {
+		t.Errorf("host properties = %v", host.Properties)
+	}
+	if _, ok := host.Properties["__meta_x"]; ok {
+		t.Error("internal labels should not become properties")
+	}
+	if ports, _ := json.Marshal(host.Discovered["ports"]); string(ports) != "[9100,9182]" {
+		t.Errorf("discovered ports = %s, want [9100,9182]", ports)
+	}
+	if host.DiscoveredSources["ports"].Source != "prometheus" {
+		t.Errorf("ports provenance = %+v, want prometheus", host.DiscoveredSources["ports"])
+	}
+
+	nas, err := repo.GetNode(ctx, "nas-lan")
+	if err != nil || nas == nil {
+		t.Fatalf("GetNode(nas-lan) = %v, %v", nas, err)
+	}
+	if nas.Label != "nas" || nas.GetPropertyString("hostname") != "nas.lan" || nas.GetPropertyString("job") != "node" {
+		t.Errorf("nas node = %+v", nas)
+	}
+
+	// Replace drops nodes missing from the file
+	if _, err := svc.ImportPrometheusSD(ctx, []byte(`[{"targets": ["nas.lan:9100"]}]`), "replace"); err != nil {
+		t.Fatalf("replace ImportPrometheusSD failed: %v", err)
+	}
+	if node, _ := repo.GetNode(ctx, "10-0-0-5"); node != nil {
+		t.Error("replace import should remove hosts not in the file")
+	}
+
+	if _, err := svc.ImportPrometheusSD(ctx, []byte(`[{"targets": ["10.0.0.5:99999"]}]`), "merge"); err == nil {
+		t.Error("out-of-range port should fail the import")
+	}
+}
+
+func TestGraphServiceDiffFragment(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	for _, id := range []string{"keep", "relabel", "drop"} {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		node.SetProperty("ip", "10.0.0.1")
+		if err := repo.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	if err := repo.CreateEdge(ctx, domain.NewEdge("keep", "relabel", domain.EdgeTypeEthernet)); err != nil {
+		t.Fatalf("CreateEdge failed: %v", err)
+	}
+
+	fragment, err := ParseImport("yaml", []byte(`
+nodes:
+  - {id: keep, type: server, label: keep, properties: {ip: 10.0.0.1}}
+  - {id: relabel, type: server, label: Relabelled, properties: {ip: 10.0.0.1}}
+  - {id: fresh, type: vm, label: fresh}
+edges:
+  - {from_id: keep, to_id: relabel, type: ethernet}
+`))
+	if err != nil {
+		t.Fatalf("ParseImport failed: %v", err)
+	}
+
+	diff, err := svc.DiffFragment(ctx, fragment)
+	if err != nil {
+		t.Fatalf("DiffFragment failed: %v", err)
+	}
+
+	if len(diff.NodesAdded) != 1 || diff.NodesAdded[0].ID != "fresh" {
+		t.Errorf("nodes added = %+v", diff.NodesAdded)
+	}
+	if len(diff.NodesRemoved) != 1 || diff.NodesRemoved[0].ID != "drop" {
+		t.Errorf("nodes removed = %+v", diff.NodesRemoved)
+	}
+	if len(diff.NodesChanged) != 1 || diff.NodesChanged[0].ID != "relabel" {
+		t.Fatalf("nodes changed = %+v", diff.NodesChanged)
+	}
+	fields := diff.NodesChanged[0].Fields
+	if len(fields) != 1 || fields[0].Field != "label" || fields[0].OldValue != "relabel" || fields[0].NewValue != "Relabelled" {
+		t.Errorf("changed fields = %+v", fields)
+	}
+	if len(diff.EdgesAdded)+len(diff.EdgesRemoved)+len(diff.EdgesChanged) != 0 {
+		t.Errorf("unexpected edge changes: %+v", diff)
+	}
+
+	// Nothing was written
+	if node, _ := repo.GetNode(ctx, "fresh"); node != nil {
+		t.Error("diff created a node")
+	}
+	if node, _ := repo.GetNode(ctx, "relabel"); node == nil || node.Label != "relabel" {
+		t.Errorf("diff modified a node: %+v", node)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("diff published %s", e.Type)
+	default:
+	}
+}
+
+func TestGraphServiceSweepStale(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	seen := map[string]time.Duration{
+		"fresh":   time.Hour,
+		"old":     8 * 24 * time.Hour,
+		"ancient": 40 * 24 * time.Hour,
+		"truth":   40 * 24 * time.Hour,
+		"pinned":  40 * 24 * time.Hour,
+	}
+	for id, age := range seen {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		if err := repo.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+		lastSeen := now.Add(-age)
+		if err := repo.UpdateNodeVerification(ctx, id, domain.NodeStatusVerified, &lastSeen, &lastSeen, nil); err != nil {
+			t.Fatalf("UpdateNodeVerification(%s) failed: %v", id, err)
+		}
+	}
+	// Imported and never seen: not the sweeper's business
+	if err := repo.CreateNode(ctx, domain.NewNode("imported", domain.NodeTypeServer, "imported")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := repo.SetNodeTruth(ctx, "truth", &domain.NodeTruth{AssertedBy: "ops", Properties: map[string]any{"hostname": "keep"}}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	if err := repo.SavePosition(ctx, domain.NodePosition{NodeID: "pinned", X: 1, Y: 2, Pinned: true}); err != nil {
+		t.Fatalf("SavePosition failed: %v", err)
+	}
+
+	status := func(id string) domain.NodeStatus {
+		t.Helper()
+		node, err := repo.GetNode(ctx, id)
+		if err != nil || node == nil {
+			t.Fatalf("GetNode(%s) = %v, %v", id, node, err)
+		}
+		if node.DeletedAt != nil {
+			return "deleted"
+		}
+		return node.Status
+	}
+
+	policy := StalePolicy{StaleAfter: 7 * 24 * time.Hour, DeleteAfter: 30 * 24 * time.Hour}
+	result, err := svc.SweepStale(ctx, now, policy)
+	if err != nil {
+		t.Fatalf("SweepStale failed: %v", err)
+	}
+	if result.MarkedStale != 1 || result.Deleted != 1 || result.Skipped != 2 {
+		t.Errorf("result = %+v", result)
+	}
+	for id, want := range map[string]domain.NodeStatus{
+		"fresh":    domain.NodeStatusVerified,
+		"old":      domain.NodeStatusStale,
+		"ancient":  "deleted",
+		"truth":    domain.NodeStatusVerified,
+		"pinned":   domain.NodeStatusVerified,
+		"imported": domain.NodeStatusUnverified,
+	} {
+		if got := status(id); got != want {
+			t.Errorf("%s status = %s, want %s", id, got, want)
+		}
+	}
+
+	var stale []string
+	for len(events) > 0 {
+		if e := <-events; e.Type == EventNodeStale {
+			stale = append(stale, e.Payload.(map[string]any)["id"].(string))
+		}
+	}
+	if strings.Join(stale, ",") != "old" {
+		t.Errorf("node-stale events for %v, want [old]", stale)
+	}
+
+	t.Run("repeat sweep does not re-announce", func(t *testing.T) {
+		result, err := svc.SweepStale(ctx, now.Add(time.Minute), policy)
+		if err != nil {
+			t.Fatalf("SweepStale failed: %v", err)
+		}
+		if result.MarkedStale != 0 {
+			t.Errorf("MarkedStale = %d, want 0", result.MarkedStale)
+		}
+	})
+
+	t.Run("unreachable keeps stale, a reply clears it", func(t *testing.T) {
+		lastSeen := now.Add(-seen["old"])
+		if err := repo.UpdateNodeVerification(ctx, "old", domain.NodeStatusUnreachable, &now, &lastSeen, nil); err != nil {
+			t.Fatalf("UpdateNodeVerification failed: %v", err)
+		}
+		if got := status("old"); got != domain.NodeStatusStale {
+			t.Errorf("status after unreachable = %s, want stale", got)
+		}
+
+		later := now.Add(time.Hour)
+		if err := repo.UpdateNodeVerification(ctx, "old", domain.NodeStatusVerified, &later, &later, nil); err != nil {
+			t.Fatalf("UpdateNodeVerification failed: %v", err)
+		}
+		if got := status("old"); got != domain.NodeStatusVerified {
+			t.Errorf("status after reply = %s, want verified", got)
+		}
+		result, err := svc.SweepStale(ctx, later.Add(time.Hour), policy)
+		if err != nil {
+			t.Fatalf("SweepStale failed: %v", err)
+		}
+		if result.MarkedStale != 0 {
+			t.Errorf("recently seen node marked stale again")
+		}
+	})
+
+	t.Run("deleted node comes back when rediscovered", func(t *testing.T) {
+		reconcile := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+		later := now.Add(time.Hour)
+		node := domain.NewNode("ancient", domain.NodeTypeServer, "ancient")
+		node.Status = domain.NodeStatusVerified
+		node.LastVerified = &later
+		node.LastSeen = &later
+
+		fragment := domain.NewGraphFragment()
+		fragment.AddNode(*node)
+		if err := reconcile.ReconcileFragment(ctx, "scanner", fragment); err != nil {
+			t.Fatalf("ReconcileFragment failed: %v", err)
+		}
+		if got := status("ancient"); got != domain.NodeStatusVerified {
+			t.Fatalf("status after rediscovery = %s, want verified", got)
+		}
+
+		result, err := svc.SweepStale(ctx, later.Add(time.Hour), policy)
+		if err != nil {
+			t.Fatalf("SweepStale failed: %v", err)
+		}
+		if result.Deleted != 0 || status("ancient") != domain.NodeStatusVerified {
+			t.Errorf("revived node swept again: %+v", result)
+		}
+	})
+}
+
+func TestGraphServiceUnmergeNodes(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestGraphService(t)
+	repo := svc.repo
+
+	nas := domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	nas.SetProperty("ip", "192.168.1.20")
+	nas.Tags = []string{"storage"}
+	nasMgmt := domain.NewNode("nas-mgmt", domain.NodeTypeUnknown, "nas-mgmt")
+	nasMgmt.SetProperty("ip", "10.0.0.20")
+	sw := domain.NewNode("switch", domain.NodeTypeSwitch, "switch")
+	for _, n := range []*domain.Node{nas, nasMgmt, sw} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+	}
+	if err := repo.AddTag(ctx, "nas", "storage"); err != nil {
+		t.Fatalf("AddTag failed: %v", err)
+	}
+	uplink := domain.NewEdge("nas", "switch", domain.EdgeTypeEthernet)
+	downlink := domain.NewEdge("switch", "nas-mgmt", domain.EdgeTypeEthernet)
+	for _, e := range []*domain.Edge{uplink, downlink} {
+		if err := repo.CreateEdge(ctx, e); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+	positions := []domain.NodePosition{
+		{NodeID: "nas", X: 10, Y: 20, Pinned: true},
+		{NodeID: "nas-mgmt", X: 30, Y: 40},
+	}
+	if err := repo.SavePositions(ctx, positions); err != nil {
+		t.Fatalf("SavePositions failed: %v", err)
+	}
+
+	op, err := svc.MergeNodesAsInterfaces(ctx, []string{"nas", "nas-mgmt"}, "nas-box", domain.NodeTypeServer)
+	if err != nil {
+		t.Fatalf("MergeNodesAsInterfaces failed: %v", err)
+	}
+	if op.ID == "" || len(op.InterfaceIDs) != 2 {
+		t.Fatalf("unexpected merge operation: %+v", op)
+	}
+	if n, _ := repo.GetNode(ctx, "nas"); n != nil {
+		t.Fatal("original node should be gone after merge")
+	}
+
+	if _, err := svc.UnmergeNodes(ctx, op.ID); err != nil {
+		t.Fatalf("UnmergeNodes failed: %v", err)
+	}
+
+	for _, id := range append(op.InterfaceIDs, "nas-box") {
+		if n, _ := repo.GetNode(ctx, id); n != nil {
+			t.Errorf("merged node %s should be removed", id)
+		}
+	}
+	restored, err := repo.GetNode(ctx, "nas")
+	if err != nil || restored == nil {
+		t.Fatalf("nas not restored: %v", err)
+	}
+	if restored.Type != domain.NodeTypeServer || restored.ParentID != "" {
+		t.Errorf("expected standalone server, got type=%s parent=%q", restored.Type, restored.ParentID)
+	}
+	if restored.GetPropertyString("ip") != "192.168.1.20" {
+		t.Errorf("expected ip restored, got %v", restored.Properties)
+	}
+	if _, ok := restored.Properties["original_id"]; ok {
+		t.Error("merge-only properties should not survive unmerge")
+	}
+	if len(restored.Tags) != 1 || restored.Tags[0] != "storage" {
+		t.Errorf("expected tags restored, got %v", restored.Tags)
+	}
+	mgmt, _ := repo.GetNode(ctx, "nas-mgmt")
+	if mgmt == nil || mgmt.Type != domain.NodeTypeUnknown {
+		t.Errorf("expected nas-mgmt restored as unknown, got %+v", mgmt)
+	}
+
+	for _, want := range positions {
+		got, err := repo.GetPosition(ctx, want.NodeID)
+		if err != nil || got == nil {
+			t.Fatalf("position of %s not restored: %v", want.NodeID, err)
+		}
+		if *got != want {
+			t.Errorf("expected position %+v, got %+v", want, *got)
+		}
+	}
+
+	for _, e := range []*domain.Edge{uplink, downlink} {
+		if got, _ := repo.GetEdge(ctx, e.ID); got == nil {
+			t.Errorf("edge %s not restored", e.ID)
+		}
+	}
+
+	if _, err := svc.UnmergeNodes(ctx, op.ID); err == nil || !strings.Contains(err.Error(), "already undone") {
+		t.Errorf("expected already undone error, got %v", err)
+	}
+	if _, err := svc.UnmergeNodes(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+// spyPositionRepo counts the positions written through SavePositions
+type spyPositionRepo struct {
+	stored  map[string]domain.NodePosition
+	written int
+}
+
+func (r *spyPositionRepo) GetAllPositions(ctx context.Context) (map[string]domain.NodePosition, error) {
+	out := make(map[string]domain.NodePosition, len(r.stored))
+	for id, pos := range r.stored {
+		out[id] = pos
+	}
+	return out, nil
+}
+
+func (r *spyPositionRepo) SavePositions(ctx context.Context, positions []domain.NodePosition) error {
+	for _, pos := range positions {
+		r.stored[pos.NodeID] = pos
+	}
+	r.written += len(positions)
+	return nil
+}
+
+func TestSaveChangedPositions(t *testing.T) {
+	ctx := context.Background()
+	spy := &spyPositionRepo{stored: make(map[string]domain.NodePosition)}
+
+	batch := make([]domain.NodePosition, 100)
+	for i := range batch {
+		batch[i] = domain.NodePosition{NodeID: fmt.Sprintf("node-%d", i), X: float64(i), Y: float64(i * 2)}
+	}
+	if n, err := saveChangedPositions(ctx, spy, batch); err != nil || n != 100 {
+		t.Fatalf("initial save = %d, %v; want 100", n, err)
+	}
+
+	spy.written = 0
+	batch[3].X += 15
+	batch[42].Y -= 7
+	batch[99].Pinned = true
+	n, err := saveChangedPositions(ctx, spy, batch)
+	if err != nil {
+		t.Fatalf("saveChangedPositions failed: %v", err)
+	}
+	if n != 3 || spy.written != 3 {
+		t.Errorf("wrote %d (spy saw %d), want 3", n, spy.written)
+	}
+	if !spy.stored["node-99"].Pinned {
+		t.Error("pinned change was not saved")
+	}
+
+	spy.written = 0
+	if n, _ := saveChangedPositions(ctx, spy, batch); n != 0 || spy.written != 0 {
+		t.Errorf("unchanged batch wrote %d (spy saw %d), want 0", n, spy.written)
+	}
+}
+
+func TestGraphServiceSnapshots(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewGraphService(repo, bus)
+
+	router := domain.NewNode("router", domain.NodeTypeRouter, "Router")
+	server := domain.NewNode("server", domain.NodeTypeServer, "Server")
+	for _, n := range []*domain.Node{router, server} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+	}
+	edge := domain.NewEdge("router", "server", domain.EdgeTypeEthernet)
+	if err := repo.CreateEdge(ctx, edge); err != nil {
+		t.Fatalf("CreateEdge failed: %v", err)
+	}
+	if err := repo.SavePosition(ctx, domain.NodePosition{NodeID: "server", X: 10, Y: 20, Pinned: true}); err != nil {
+		t.Fatalf("SavePosition failed: %v", err)
+	}
+	truth := &domain.NodeTruth{AssertedBy: "operator", Properties: map[string]any{"hostname": "server"}}
+	if err := repo.SetNodeTruth(ctx, "server", truth); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	if err := repo.AddTag(ctx, "server", "prod"); err != nil {
+		t.Fatalf("AddTag failed: %v", err)
+	}
+	if err := repo.SetNodeProtected(ctx, "server", true); err != nil {
+		t.Fatalf("SetNodeProtected failed: %v", err)
+	}
+
+	snap, err := svc.CreateSnapshot(ctx, "before change")
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if snap.NodeCount != 2 || snap.EdgeCount != 1 {
+		t.Errorf("snapshot counts = %d nodes, %d edges; want 2, 1", snap.NodeCount, snap.EdgeCount)
+	}
+
+	// Mutate everything the snapshot covers
+	if err := repo.DeleteEdge(ctx, edge.ID); err != nil {
+		t.Fatalf("DeleteEdge failed: %v", err)
+	}
+	if err := repo.ClearNodeTruth(ctx, "server"); err != nil {
+		t.Fatalf("ClearNodeTruth failed: %v", err)
+	}
+	if err := repo.RemoveTag(ctx, "server", "prod"); err != nil {
+		t.Fatalf("RemoveTag failed: %v", err)
+	}
+	if err := repo.SetNodeProtected(ctx, "server", false); err != nil {
+		t.Fatalf("SetNodeProtected failed: %v", err)
+	}
+	if err := repo.SavePosition(ctx, domain.NodePosition{NodeID: "server", X: 99, Y: 99}); err != nil {
+		t.Fatalf("SavePosition failed: %v", err)
+	}
+	if err := repo.CreateNode(ctx, domain.NewNode("extra", domain.NodeTypeServer, "Extra")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	list, err := svc.ListSnapshots(ctx)
+	if err != nil || len(list) != 1 || list[0].Label != "before change" {
+		t.Fatalf("ListSnapshots = %+v, %v; want the one labelled snapshot", list, err)
+	}
+
+	for len(events) > 0 {
+		<-events
+	}
+	if _, err := svc.RestoreSnapshot(ctx, snap.ID); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	if e := nextEvent(t, events); e.Type != EventGraphUpdated {
+		t.Errorf("event = %s, want %s", e.Type, EventGraphUpdated)
+	}
+
+	nodes, err := repo.ListNodes(ctx, "", "")
+	if err != nil {
+		t.Fatalf("ListNodes failed: %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Errorf("restored %d nodes, want 2", len(nodes))
+	}
+	if extra, _ := repo.GetNode(ctx, "extra"); extra != nil {
+		t.Error("node created after the snapshot survived the restore")
+	}
+	restored, err := repo.GetNode(ctx, "server")
+	if err != nil || restored == nil {
+		t.Fatalf("GetNode(server) = %v, %v", restored, err)
+	}
+	if restored.Truth == nil || restored.Truth.Properties["hostname"] != "server" {
+		t.Errorf("truth = %+v, want hostname=server", restored.Truth)
+	}
+	if !restored.Protected || !slices.Equal(restored.Tags, []string{"prod"}) {
+		t.Errorf("protected = %v, tags = %v; want protected with tag prod", restored.Protected, restored.Tags)
+	}
+	edges, err := repo.ListEdges(ctx, "", "", "")
+	if err != nil || len(edges) != 1 || edges[0].ID != edge.ID {
+		t.Errorf("edges = %+v, %v; want the original edge", edges, err)
+	}
+	positions, err := repo.GetAllPositions(ctx)
+	if err != nil {
+		t.Fatalf("GetAllPositions failed: %v", err)
+	}
+	if pos := positions["server"]; pos.X != 10 || pos.Y != 20 || !pos.Pinned {
+		t.Errorf("position = %+v, want pinned at (10, 20)", pos)
+	}
+
+	if _, err := svc.RestoreSnapshot(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing snapshot error = %v, want not found", err)
+	}
+}
+
+func TestGraphServiceSelectNodesForVerification(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestGraphService(t)
+
+	for id, ip := range map[string]string{
+		"lab-1":  "10.0.1.10",
+		"lab-2":  "10.0.1.20",
+		"home-1": "192.168.1.10",
+	} {
+		node := domain.NewNode(id, domain.NodeTypeServer, id)
+		node.SetProperty("ip", ip)
+		if err := svc.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+
+	ids, err := svc.SelectNodesForVerification(ctx, nil, "10.0.1.0/24")
+	if err != nil {
+		t.Fatalf("select by segmentum failed: %v", err)
+	}
+	if !slices.Equal(ids, []string{"lab-1", "lab-2"}) {
+		t.Errorf("segmentum selection = %v, want [lab-1 lab-2]", ids)
+	}
+
+	ids, err = svc.SelectNodesForVerification(ctx, []string{"home-1", "lab-2"}, "10.0.1.0/24")
+	if err != nil {
+		t.Fatalf("select by IDs failed: %v", err)
+	}
+	if !slices.Equal(ids, []string{"home-1", "lab-2"}) {
+		t.Errorf("ID selection = %v, want [home-1 lab-2]", ids)
+	}
+
+	if _, err := svc.SelectNodesForVerification(ctx, []string{"lab-1", "missing"}, ""); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown ID error = %v, want not found", err)
+	}
+	if _, err := svc.SelectNodesForVerification(ctx, nil, ""); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("empty request error = %v, want invalid", err)
+	}
+	if _, err := svc.SelectNodesForVerification(ctx, nil, "not-a-cidr"); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("bad segmentum error = %v, want invalid", err)
+	}
+}
+
+func TestGraphServiceFindOrphans(t *testing.T) {
+	svc, _ := newTestGraphService(t)
+	ctx := context.Background()
+
+	for _, n := range []*domain.Node{
+		domain.NewNode("router", domain.NodeTypeRouter, "router"),
+		domain.NewNode("nas", domain.NodeTypeServer, "nas"),
+		domain.NewNode("lonely", domain.NodeTypeServer, "lonely"),
+		domain.NewNode("printer", domain.NodeTypeServer, "printer"),
+		domain.NewNode("gone", domain.NodeTypeServer, "gone"),
+	} {
+		if n.ID == "printer" {
+			n.SetProperty("segmentum", "192.168.1.0/24")
+		}
+		if err := svc.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", n.ID, err)
+		}
+	}
+	for _, e := range []*domain.Edge{
+		domain.NewEdge("router", "nas", domain.EdgeTypeEthernet),
+		domain.NewEdge("gone", "lonely", domain.EdgeTypeEthernet),
+	} {
+		if err := svc.CreateEdge(ctx, e); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+
+	// The edge to a soft-deleted node is hidden, leaving lonely edgeless
+	result, err := svc.DeleteNode(ctx, "gone", false, false)
+	if err != nil {
+		t.Fatalf("DeleteNode failed: %v", err)
+	}
+	if result.EdgesDeleted != 1 {
+		t.Errorf("EdgesDeleted = %d, want 1", result.EdgesDeleted)
+	}
+
+	report, err := svc.FindOrphans(ctx)
+	if err != nil {
+		t.Fatalf("FindOrphans failed: %v", err)
+	}
+	if len(report.Nodes) != 1 || report.Nodes[0].ID != "lonely" {
+		t.Errorf("orphans = %+v, want [lonely]", report.Nodes)
+	}
+	if len(report.Inferable) != 1 || report.Inferable[0] != "printer" {
+		t.Errorf("inferable = %v, want [printer]", report.Inferable)
+	}
+	if len(report.DanglingEdges) != 0 {
+		t.Errorf("dangling edges = %+v, want none", report.DanglingEdges)
+	}
+}
+
+func TestGraphServiceValidateImport(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	svc := NewGraphService(repo, NewEventBus())
+
+	if err := repo.CreateNode(ctx, domain.NewNode("core", domain.NodeTypeSwitch, "core")); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	result, err := svc.ValidateImport(ctx, "yaml", []byte(`
+nodes:
+  - {id: web, type: server, label: web}
+  - {id: web, type: server, label: web again}
+  - {id: tv, type: television, label: tv}
+edges:
+  - {from_id: web, to_id: core, type: ethernet}
+  - {from_id: web, to_id: ghost, type: ethernet}
+`))
+	if err != nil {
+		t.Fatalf("ValidateImport failed: %v", err)
+	}
+	if result.Valid || result.Nodes != 3 || result.Edges != 2 {
+		t.Errorf("result = %+v", result)
+	}
+
+	var messages []string
+	for _, issue := range result.Issues {
+		messages = append(messages, issue.Kind+" "+issue.ID+": "+issue.Message)
+	}
+	joined := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"node web: duplicate node id",
+		`node tv: unknown node type "television"`,
+		"to_id references node ghost",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("issues missing %q:\n%s", want, joined)
+		}
+	}
+	if len(result.Issues) != 3 {
+		t.Errorf("got %d issues, want 3:\n%s", len(result.Issues), joined)
+	}
+
+	// Nothing was imported
+	if node, _ := repo.GetNode(ctx, "web"); node != nil {
+		t.Error("validation wrote node web")
+	}
+
+	result, err = svc.ValidateImport(ctx, "yaml", []byte("nodes: [unclosed"))
+	if err != nil {
+		t.Fatalf("ValidateImport failed: %v", err)
+	}
+	if result.Valid || len(result.Issues) != 1 || result.Issues[0].Kind != "parse" {
+		t.Errorf("parse failure result = %+v", result)
+	}
+
+	if _, err := svc.ValidateImport(ctx, "xml", nil); err == nil {
+		t.Error("expected unsupported format error")
+	}
+}
+
+func TestCollapseSubnetEdges(t *testing.T) {
+	svc, _ := newTestGraphService(t)
+	ctx := context.Background()
+
+	addNode := func(id string, nodeType domain.NodeType, segmentum string) {
+		t.Helper()
+		node := domain.NewNode(id, nodeType, id)
+		node.SetProperty("segmentum", segmentum)
+		if err := svc.CreateNode(ctx, node); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", id, err)
+		}
+	}
+	addNode("lan-gw", domain.NodeTypeRouter, "192.168.1.0/24")
+	for i := 1; i < 60; i++ {
+		addNode(fmt.Sprintf("lan-%02d", i), domain.NodeTypeServer, "192.168.1.0/24")
+	}
+	for i := 1; i <= 3; i++ {
+		addNode(fmt.Sprintf("lab-%d", i), domain.NodeTypeServer, "10.0.5.0/24")
+	}
+	if _, err := svc.InferSubnetEdges(ctx); err != nil {
+		t.Fatalf("InferSubnetEdges failed: %v", err)
+	}
+
+	graph, err := svc.GetGraph(ctx)
+	if err != nil {
+		t.Fatalf("GetGraph failed: %v", err)
+	}
+	if len(graph.Edges) != 63 {
+		t.Fatalf("got %d edges before collapsing, want 63", len(graph.Edges))
+	}
+
+	view := CollapseSubnetEdges(graph, DefaultCollapseThreshold, nil)
+	if len(view.Edges) != 4 {
+		t.Fatalf("got %d edges after collapsing, want 3 lab edges and 1 fabric edge", len(view.Edges))
+	}
+	var fabric *domain.Edge
+	for i, edge := range view.Edges {
+		if edge.ID == "fabric-subnet-192-168-1-0-24" {
+			fabric = &view.Edges[i]
+		} else if edge.ToID != "subnet-10-0-5-0-24" {
+			t.Errorf("unexpected edge %s -> %s", edge.FromID, edge.ToID)
+		}
+	}
+	if fabric == nil {
+		t.Fatal("missing fabric edge for the /24")
+	}
+	if fabric.Type != domain.EdgeTypeAggregation || fabric.ToID != "lan-gw" || fabric.Properties["member_count"] != 60 {
+		t.Errorf("fabric edge = %+v", fabric)
+	}
+	if len(graph.Edges) != 63 {
+		t.Error("collapsing modified the source graph")
+	}
+
+	// An expanded subnet keeps its member edges
+	view = CollapseSubnetEdges(graph, DefaultCollapseThreshold, map[string]bool{"subnet-192-168-1-0-24": true})
+	if len(view.Edges) != 63 {
+		t.Errorf("got %d edges with the /24 expanded, want 63", len(view.Edges))
+	}
+}
+
+func TestGraphServiceAnalyzeConnectivity(t *testing.T) {
+	svc, _ := newTestGraphService(t)
+	ctx := context.Background()
+
+	if _, err := svc.AnalyzeConnectivity(ctx); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("AnalyzeConnectivity() without a self node error = %v, want not found", err)
+	}
+
+	for _, n := range []*domain.Node{
+		domain.NewNode("self", domain.NodeTypeSelf, "self"),
+		domain.NewNode("router", domain.NodeTypeRouter, "router"),
+		domain.NewNode("nas", domain.NodeTypeServer, "nas"),
+		domain.NewNode("lab-switch", domain.NodeTypeSwitch, "lab-switch"),
+		domain.NewNode("lab-host", domain.NodeTypeServer, "lab-host"),
+	} {
+		if err := svc.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode(%s) failed: %v", n.ID, err)
+		}
+	}
+	// The nas edge points at the router, so reaching it needs the edge
+	// walked backwards
+	for _, e := range []*domain.Edge{
+		domain.NewEdge("self", "router", domain.EdgeTypeEthernet),
+		domain.NewEdge("nas", "router", domain.EdgeTypeEthernet),
+		domain.NewEdge("lab-host", "lab-switch", domain.EdgeTypeEthernet),
+	} {
+		if err := svc.CreateEdge(ctx, e); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+
+	report, err := svc.AnalyzeConnectivity(ctx)
+	if err != nil {
+		t.Fatalf("AnalyzeConnectivity failed: %v", err)
+	}
+	if report.Components != 2 {
+		t.Errorf("components = %d, want 2", report.Components)
+	}
+	if got := strings.Join(report.Reachable, ","); got != "nas,router,self" {
+		t.Errorf("reachable = %s, want nas,router,self", got)
+	}
+	if got := strings.Join(report.Isolated, ","); got != "lab-host,lab-switch" {
+		t.Errorf("isolated = %s, want lab-host,lab-switch", got)
+	}
+	if len(report.Islands) != 1 || len(report.Islands[0]) != 2 {
+		t.Errorf("islands = %v, want one island of 2", report.Islands)
+	}
+}
+
+func TestGraphServiceMergeNodesByIdentity(t *testing.T) {
+	ctx := context.Background()
+	svc, events := newTestGraphService(t)
+	repo := svc.repo
+
+	nas := domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	nas.SetProperty("ip", "192.168.1.20")
+	nas.Discovered = map[string]any{"hostname": "nas"}
+	dup := domain.NewNode("host-10-0-0-20", domain.NodeTypeUnknown, "10.0.0.20")
+	dup.SetProperty("ip", "10.0.0.20")
+	dup.SetProperty("mac_address", "aa:bb:cc:dd:ee:20")
+	dup.Discovered = map[string]any{"hostname": "nas-mgmt", "os": "linux"}
+	sw := domain.NewNode("switch", domain.NodeTypeSwitch, "switch")
+	router := domain.NewNode("router", domain.NodeTypeRouter, "router")
+	for _, n := range []*domain.Node{nas, dup, sw, router} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+	}
+	port := domain.NewNode("host-10-0-0-20:eth0", domain.NodeTypeInterface, "eth0")
+	port.ParentID = dup.ID
+	if err := repo.CreateNode(ctx, port); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+
+	if err := repo.SetNodeTruth(ctx, "nas", &domain.NodeTruth{
+		Properties: map[string]any{"hostname": "nas", "ip": "192.168.1.20"},
+	}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	if err := repo.SetNodeTruth(ctx, dup.ID, &domain.NodeTruth{
+		Properties: map[string]any{"hostname": "wrong", "location": "rack 2"},
+	}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+	if err := repo.AddTag(ctx, dup.ID, "storage"); err != nil {
+		t.Fatalf("AddTag failed: %v", err)
+	}
+	if err := repo.SavePosition(ctx, domain.NodePosition{NodeID: dup.ID, X: 40, Y: 80, Pinned: true}); err != nil {
+		t.Fatalf("SavePosition failed: %v", err)
+	}
+	if err := repo.CreateDiscrepancy(ctx, &domain.Discrepancy{
+		ID: "d-dup", NodeID: dup.ID, PropertyKey: "hostname", TruthValue: "wrong", ActualValue: "nas-mgmt", Source: "verifier", DetectedAt: time.Now(),
+	}); err != nil {
+		t.Fatalf("CreateDiscrepancy failed: %v", err)
+	}
+
+	edges := []*domain.Edge{
+		domain.NewEdge("nas", "switch", domain.EdgeTypeEthernet),  // already on the survivor
+		domain.NewEdge(dup.ID, "switch", domain.EdgeTypeEthernet), // duplicates the above
+		domain.NewEdge("router", dup.ID, domain.EdgeTypeEthernet), // moves to nas
+		domain.NewEdge(dup.ID, "nas", domain.EdgeTypeVirtual),     // becomes a self loop
+	}
+	for _, e := range edges {
+		if err := repo.CreateEdge(ctx, e); err != nil {
+			t.Fatalf("CreateEdge failed: %v", err)
+		}
+	}
+
+	if err := repo.SetNodeProtected(ctx, dup.ID, true); err != nil {
+		t.Fatalf("SetNodeProtected failed: %v", err)
+	}
+	if _, err := svc.MergeNodesByIdentity(ctx, "nas", dup.ID, false); err == nil || !strings.Contains(err.Error(), "is protected") {
+		t.Fatalf("merging a protected node error = %v, want is protected", err)
+	}
+	if kept, _ := repo.GetNode(ctx, dup.ID); kept == nil {
+		t.Fatal("refused merge should keep the protected node")
+	}
+
+	node, err := svc.MergeNodesByIdentity(ctx, "nas", dup.ID, true)
+	if err != nil {
+		t.Fatalf("MergeNodesByIdentity failed: %v", err)
+	}
+
+	if gone, _ := repo.GetNode(ctx, dup.ID); gone != nil {
+		t.Error("merged node should be deleted")
+	}
+	if node.GetPropertyString("ip") != "192.168.1.20" || node.GetPropertyString("mac_address") != "aa:bb:cc:dd:ee:20" {
+		t.Errorf("properties = %v, want survivor ip plus merged mac_address", node.Properties)
+	}
+	if node.Discovered["hostname"] != "nas" || node.Discovered["os"] != "linux" {
+		t.Errorf("discovered = %v, want survivor hostname plus merged os", node.Discovered)
+	}
+	if node.Truth == nil || node.Truth.Properties["hostname"] != "nas" || node.Truth.Properties["location"] != "rack 2" {
+		t.Errorf("truth = %+v, want survivor hostname kept and merged location added", node.Truth)
+	}
+	if tags, _ := repo.ListTags(ctx, "nas"); !slices.Contains(tags, "storage") {
+		t.Errorf("tags = %v, want storage carried over", tags)
+	}
+	if child, _ := repo.GetNode(ctx, port.ID); child == nil || child.ParentID != "nas" {
+		t.Errorf("child = %+v, want it reparented to nas", child)
+	}
+	positions, err := repo.GetAllPositions(ctx)
+	if err != nil {
+		t.Fatalf("GetAllPositions failed: %v", err)
+	}
+	if _, ok := positions[dup.ID]; ok || !positions["nas"].Pinned || positions["nas"].X != 40 {
+		t.Errorf("positions = %+v, want the merged position moved to nas", positions)
+	}
+	if discrepancies, _ := repo.GetDiscrepanciesByNode(ctx, dup.ID); len(discrepancies) != 0 {
+		t.Errorf("merged node discrepancies = %+v, want none left", discrepancies)
+	}
+
+	remaining, err := repo.ListEdges(ctx, "", "", "")
+	if err != nil {
+		t.Fatalf("ListEdges failed: %v", err)
+	}
+	got := make([]string, 0, len(remaining))
+	for _, e := range remaining {
+		got = append(got, e.FromID+">"+e.ToID)
+	}
+	slices.Sort(got)
+	if want := []string{"nas>switch", "router>nas"}; !slices.Equal(got, want) {
+		t.Errorf("edges = %v, want %v", got, want)
+	}
+
+	if e := nextEvent(t, events); e.Type != EventNodeMerged {
+		t.Errorf("event = %s, want %s", e.Type, EventNodeMerged)
+	}
+
+	if _, err := svc.MergeNodesByIdentity(ctx, "nas", "nas", false); err == nil || !strings.Contains(err.Error(), "invalid request") {
+		t.Errorf("merging a node into itself error = %v, want invalid request", err)
+	}
+	if _, err := svc.MergeNodesByIdentity(ctx, "nas", dup.ID, false); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("merging a deleted node error = %v, want not found", err)
+	}
+}
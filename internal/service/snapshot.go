@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// CreateSnapshot saves the full graph (nodes with truth, edges and
+// positions) under an optional label
+func (s *GraphService) CreateSnapshot(ctx context.Context, label string) (*domain.Snapshot, error) {
+	fragment, err := s.repo.ExportFragment(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := s.repo.GetAllPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pos := range positions {
+		fragment.Positions = append(fragment.Positions, pos)
+	}
+	sort.Slice(fragment.Positions, func(i, j int) bool {
+		return fragment.Positions[i].NodeID < fragment.Positions[j].NodeID
+	})
+
+	snap := &domain.Snapshot{
+		ID:        generateID(),
+		Label:     label,
+		CreatedAt: time.Now().UTC(),
+		NodeCount: len(fragment.Nodes),
+		EdgeCount: len(fragment.Edges),
+		Graph:     fragment,
+	}
+	if err := s.repo.CreateSnapshot(ctx, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// ListSnapshots returns saved snapshots, newest first
+func (s *GraphService) ListSnapshots(ctx context.Context) ([]domain.Snapshot, error) {
+	return s.repo.ListSnapshots(ctx)
+}
+
+// RestoreSnapshot replaces the live graph with a saved snapshot
+func (s *GraphService) RestoreSnapshot(ctx context.Context, id string) (*domain.Snapshot, error) {
+	snap, err := s.repo.GetSnapshot(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil {
+		return nil, fmt.Errorf("snapshot %s not found", id)
+	}
+
+	if _, err := s.repo.RestoreFragment(ctx, snap.Graph); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot %s: %w", id, err)
+	}
+
+	s.eventBus.Publish(Event{
+		Type: EventGraphUpdated,
+		Payload: map[string]any{
+			"action":      "restored_snapshot",
+			"snapshot_id": snap.ID,
+			"nodes":       snap.NodeCount,
+			"edges":       snap.EdgeCount,
+		},
+	})
+	return snap, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// StalePolicy sets when unseen nodes are marked stale and, optionally,
+// soft-deleted
+type StalePolicy struct {
+	// StaleAfter marks a node stale once last_seen is older than this
+	StaleAfter time.Duration
+	// DeleteAfter soft-deletes a node once last_seen is older than this (0 = never)
+	DeleteAfter time.Duration
+}
+
+// StaleSweepResult summarizes one stale sweep
+type StaleSweepResult struct {
+	MarkedStale int `json:"marked_stale"`
+	Deleted     int `json:"deleted"`
+	Skipped     int `json:"skipped"`
+	Failed      int `json:"failed"`
+}
+
+// SweepStale marks nodes not seen since now-StaleAfter as stale and
+// soft-deletes those past DeleteAfter. Nodes that were never seen (imported
+// but not yet verified) are left alone, as are nodes with operator truth or
+// a pinned position, since the operator has vouched for them. A node that
+// cannot be updated is logged and counted as failed; the sweep goes on.
+func (s *GraphService) SweepStale(ctx context.Context, now time.Time, policy StalePolicy) (*StaleSweepResult, error) {
+	nodes, err := s.repo.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	positions, err := s.repo.GetAllPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StaleSweepResult{}
+	for _, node := range nodes {
+		if node.LastSeen == nil {
+			continue
+		}
+		age := now.Sub(*node.LastSeen)
+		if age < policy.StaleAfter && (policy.DeleteAfter <= 0 || age < policy.DeleteAfter) {
+			continue
+		}
+		if node.Truth != nil || node.Protected || positions[node.ID].Pinned {
+			result.Skipped++
+			continue
+		}
+
+		// One bad node must not stall the sweep for the rest
+		if policy.DeleteAfter > 0 && age >= policy.DeleteAfter {
+			if err := s.repo.DeleteNode(ctx, node.ID, false); err != nil {
+				log.Printf("Stale sweep: failed to delete node %s: %v", node.ID, err)
+				result.Failed++
+				continue
+			}
+			result.Deleted++
+			s.eventBus.Publish(Event{
+				Type:    EventNodeDeleted,
+				Payload: map[string]string{"id": node.ID, "reason": "stale"},
+			})
+			continue
+		}
+
+		changed, err := s.repo.SetNodeStatus(ctx, node.ID, domain.NodeStatusStale)
+		if err != nil {
+			log.Printf("Stale sweep: failed to mark node %s stale: %v", node.ID, err)
+			result.Failed++
+			continue
+		}
+		if !changed {
+			continue
+		}
+		result.MarkedStale++
+		previous := node.Status
+		node.Status = domain.NodeStatusStale
+		s.eventBus.Publish(Event{
+			Type: EventNodeStale,
+			Payload: map[string]any{
+				"id":              node.ID,
+				"last_seen":       node.LastSeen,
+				"previous_status": previous,
+				"node":            &node,
+			},
+		})
+	}
+
+	return result, nil
+}
+
+// RunStaleSweeper sweeps on every interval tick until ctx is cancelled
+func (s *GraphService) RunStaleSweeper(ctx context.Context, interval time.Duration, policy StalePolicy) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := s.SweepStale(ctx, time.Now(), policy)
+			if err != nil {
+				log.Printf("Stale sweep failed: %v", err)
+				continue
+			}
+			if result.MarkedStale > 0 || result.Deleted > 0 || result.Failed > 0 {
+				log.Printf("Stale sweep: %d marked stale, %d deleted, %d failed", result.MarkedStale, result.Deleted, result.Failed)
+			}
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+// InferredSource marks nodes and edges created by inference rather than
+// discovered or imported, so they can be told apart and cleared separately
+const InferredSource = "inferred"
+
+// InferEdgesResult summarizes a subnet edge inference run
+type InferEdgesResult struct {
+	Subnets      int `json:"subnets"`
+	NodesCreated int `json:"nodes_created"`
+	EdgesCreated int `json:"edges_created"`
+}
+
+// InferSubnetEdges groups nodes by their segmentum property and links each
+// member to a synthetic subnet node with an ethernet edge. Existing subnet
+// nodes and edges are reused, so re-running only adds what is missing.
+func (s *GraphService) InferSubnetEdges(ctx context.Context) (*InferEdgesResult, error) {
+	nodes, err := s.repo.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[string][]string)
+	for _, node := range nodes {
+		if node.Type == domain.NodeTypeSubnet {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(node.GetPropertyString("segmentum"))
+		if err != nil {
+			continue
+		}
+		cidr := prefix.Masked().String()
+		members[cidr] = append(members[cidr], node.ID)
+	}
+
+	cidrs := make([]string, 0, len(members))
+	for cidr := range members {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+
+	result := &InferEdgesResult{Subnets: len(cidrs)}
+	for _, cidr := range cidrs {
+		subnetID := subnetNodeID(cidr)
+		created, err := s.ensureSubnetNode(ctx, subnetID, cidr)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			result.NodesCreated++
+		}
+
+		for _, memberID := range members[cidr] {
+			edge := domain.NewEdge(memberID, subnetID, domain.EdgeTypeEthernet)
+			existing, err := s.repo.GetEdge(ctx, edge.ID)
+			if err != nil {
+				return nil, err
+			}
+			if existing != nil {
+				continue
+			}
+
+			edge.SetProperty("source", InferredSource)
+			if err := s.repo.CreateEdge(ctx, edge); err != nil {
+				return nil, fmt.Errorf("create edge %s -> %s: %w", memberID, subnetID, err)
+			}
+			result.EdgesCreated++
+		}
+	}
+
+	if result.NodesCreated > 0 || result.EdgesCreated > 0 {
+		s.eventBus.Publish(Event{
+			Type:    EventGraphUpdated,
+			Payload: result,
+		})
+	}
+
+	return result, nil
+}
+
+// ensureSubnetNode creates the subnet node for cidr unless a live one exists
+func (s *GraphService) ensureSubnetNode(ctx context.Context, id, cidr string) (bool, error) {
+	existing, err := s.repo.GetNode(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && existing.DeletedAt == nil {
+		return false, nil
+	}
+
+	node := domain.NewNode(id, domain.NodeTypeSubnet, cidr)
+	node.Source = InferredSource
+	node.SetProperty("segmentum", cidr)
+	if err := s.repo.CreateNode(ctx, node); err != nil {
+		return false, fmt.Errorf("create subnet node %s: %w", id, err)
+	}
+	return true, nil
+}
+
+// subnetNodeID derives a stable node ID from a CIDR,
+// e.g. 192.168.1.0/24 -> subnet-192-168-1-0-24
+func subnetNodeID(cidr string) string {
+	return "subnet-" + strings.NewReplacer(".", "-", ":", "-", "/", "-").Replace(cidr)
+}
+
+// ListSubnets returns all subnets with their member counts
+func (s *GraphService) ListSubnets(ctx context.Context) ([]domain.Subnet, error) {
+	return s.repo.ListSubnets(ctx)
+}
+
+// GetSubnet returns a subnet by CIDR. The CIDR is normalized first, so
+// 10.0.0.7/24 finds 10.0.0.0/24.
+func (s *GraphService) GetSubnet(ctx context.Context, cidr string) (*domain.Subnet, error) {
+	normalized, err := domain.NormalizeCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	subnet, err := s.repo.GetSubnet(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if subnet == nil {
+		return nil, fmt.Errorf("subnet %s not found", normalized)
+	}
+	return subnet, nil
+}
+
+// CreateSubnet registers a subnet ahead of any node referencing it
+func (s *GraphService) CreateSubnet(ctx context.Context, subnet *domain.Subnet) (*domain.Subnet, error) {
+	if err := subnet.Validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	subnet.CreatedAt = now
+	subnet.UpdatedAt = now
+	if err := s.repo.CreateSubnet(ctx, subnet); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSubnet(ctx, subnet.CIDR)
+}
+
+// UpdateSubnet replaces a subnet's VLAN, description and gateway
+func (s *GraphService) UpdateSubnet(ctx context.Context, cidr string, subnet *domain.Subnet) (*domain.Subnet, error) {
+	subnet.CIDR = cidr
+	if err := subnet.Validate(); err != nil {
+		return nil, err
+	}
+	subnet.UpdatedAt = time.Now()
+	if err := s.repo.UpdateSubnet(ctx, subnet); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSubnet(ctx, subnet.CIDR)
+}
+
+// DeleteSubnet removes a subnet. It is refused while live nodes still
+// reference the subnet through their segmentum.
+func (s *GraphService) DeleteSubnet(ctx context.Context, cidr string) error {
+	subnet, err := s.GetSubnet(ctx, cidr)
+	if err != nil {
+		return err
+	}
+	if subnet.MemberCount > 0 {
+		return fmt.Errorf("subnet %s still has %d member nodes", subnet.CIDR, subnet.MemberCount)
+	}
+	return s.repo.DeleteSubnet(ctx, subnet.CIDR)
+}
@@ -3,11 +3,17 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"encoding/csv"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
 	"time"
 
 	"specularium/internal/domain"
+	"specularium/internal/metrics"
 	"specularium/internal/repository/sqlite"
 )
 
@@ -25,6 +31,15 @@ func NewTruthService(repo *sqlite.Repository, eventBus *EventBus) *TruthService
 	}
 }
 
+// RegisterMetrics exposes the open discrepancy count, read at scrape time
+func (s *TruthService) RegisterMetrics(reg *metrics.Registry) {
+	reg.GaugeFunc("specularium_discrepancies_open", "Number of unresolved discrepancies with operator truth.",
+		func(ctx context.Context) (float64, error) {
+			n, err := s.repo.CountUnresolvedDiscrepancies(ctx)
+			return float64(n), err
+		})
+}
+
 // SetTruth locks specific properties as operator truth for a node
 func (s *TruthService) SetTruth(ctx context.Context, nodeID string, properties map[string]any, operator string) error {
 	// Verify node exists
@@ -106,7 +121,8 @@ func (s *TruthService) GetTruth(ctx context.Context, nodeID string) (*domain.Nod
 	return node.Truth, nil
 }
 
-// CheckDiscrepancies compares discovered values against truth and creates discrepancy records
+// CheckDiscrepancies compares discovered values against truth and creates discrepancy records.
+// Open discrepancies whose property matches truth again are auto-resolved.
 // Returns the list of new discrepancies created
 func (s *TruthService) CheckDiscrepancies(ctx context.Context, nodeID string, discovered map[string]any, source string) ([]domain.Discrepancy, error) {
 	node, err := s.repo.GetNode(ctx, nodeID)
@@ -122,6 +138,18 @@ func (s *TruthService) CheckDiscrepancies(ctx context.Context, nodeID string, di
 		return nil, nil
 	}
 
+	// Index open discrepancies by property
+	existing, err := s.repo.GetDiscrepanciesByNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[string]domain.Discrepancy)
+	for _, d := range existing {
+		if !d.IsResolved() {
+			open[d.PropertyKey] = d
+		}
+	}
+
 	var newDiscrepancies []domain.Discrepancy
 	now := time.Now()
 
@@ -143,62 +171,53 @@ func (s *TruthService) CheckDiscrepancies(ctx context.Context, nodeID string, di
 		}
 
 		// Compare values
-		if !domain.CompareValues(truthValue, actualValue) {
-			// Check if an unresolved discrepancy already exists for this property
-			existing, _ := s.findUnresolvedDiscrepancy(ctx, nodeID, key)
-			if existing != nil {
-				// Update the actual value in the existing discrepancy
-				continue
-			}
-
-			// Create new discrepancy
-			d := domain.Discrepancy{
-				ID:          generateID(),
-				NodeID:      nodeID,
-				PropertyKey: key,
-				TruthValue:  truthValue,
-				ActualValue: actualValue,
-				Source:      source,
-				DetectedAt:  now,
+		if domain.CompareValues(truthValue, actualValue) {
+			// Reality drifted back to truth - close any open discrepancy
+			if d, ok := open[key]; ok {
+				if err := s.ResolveDiscrepancy(ctx, d.ID, domain.ResolutionAutoMatched); err != nil {
+					return nil, fmt.Errorf("failed to auto-resolve discrepancy: %w", err)
+				}
 			}
-
-			if err := s.repo.CreateDiscrepancy(ctx, &d); err != nil {
-				return nil, fmt.Errorf("failed to create discrepancy: %w", err)
-			}
-
-			newDiscrepancies = append(newDiscrepancies, d)
-
-			s.eventBus.Publish(Event{
-				Type: EventDiscrepancyCreated,
-				Payload: map[string]interface{}{
-					"discrepancy_id": d.ID,
-					"node_id":        nodeID,
-					"property":       key,
-					"truth":          truthValue,
-					"actual":         actualValue,
-					"source":         source,
-				},
-			})
+			continue
 		}
-	}
 
-	return newDiscrepancies, nil
-}
+		// Check if an unresolved discrepancy already exists for this property
+		if _, ok := open[key]; ok {
+			// Update the actual value in the existing discrepancy
+			continue
+		}
 
-// findUnresolvedDiscrepancy finds an existing unresolved discrepancy for a node/property
-func (s *TruthService) findUnresolvedDiscrepancy(ctx context.Context, nodeID, propertyKey string) (*domain.Discrepancy, error) {
-	discrepancies, err := s.repo.GetDiscrepanciesByNode(ctx, nodeID)
-	if err != nil {
-		return nil, err
-	}
+		// Create new discrepancy
+		d := domain.Discrepancy{
+			ID:          generateID(),
+			NodeID:      nodeID,
+			PropertyKey: key,
+			TruthValue:  truthValue,
+			ActualValue: actualValue,
+			Source:      source,
+			DetectedAt:  now,
+		}
 
-	for _, d := range discrepancies {
-		if d.PropertyKey == propertyKey && !d.IsResolved() {
-			return &d, nil
+		if err := s.repo.CreateDiscrepancy(ctx, &d); err != nil {
+			return nil, fmt.Errorf("failed to create discrepancy: %w", err)
 		}
+
+		newDiscrepancies = append(newDiscrepancies, d)
+
+		s.eventBus.Publish(Event{
+			Type: EventDiscrepancyCreated,
+			Payload: map[string]interface{}{
+				"discrepancy_id": d.ID,
+				"node_id":        nodeID,
+				"property":       key,
+				"truth":          truthValue,
+				"actual":         actualValue,
+				"source":         source,
+			},
+		})
 	}
 
-	return nil, nil
+	return newDiscrepancies, nil
 }
 
 // reconcileDiscrepancies resolves discrepancies when truth is updated to match actual values
@@ -250,6 +269,33 @@ func (s *TruthService) ResolveDiscrepancy(ctx context.Context, discrepancyID str
 	return nil
 }
 
+// DeleteDiscrepancy dismisses a false-positive discrepancy by removing it
+// entirely, rather than recording a resolution
+func (s *TruthService) DeleteDiscrepancy(ctx context.Context, discrepancyID string) error {
+	d, err := s.repo.GetDiscrepancy(ctx, discrepancyID)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return fmt.Errorf("discrepancy %s not found", discrepancyID)
+	}
+
+	if err := s.repo.DeleteDiscrepancy(ctx, discrepancyID); err != nil {
+		return err
+	}
+
+	s.eventBus.Publish(Event{
+		Type: EventDiscrepancyDismissed,
+		Payload: map[string]interface{}{
+			"discrepancy_id": discrepancyID,
+			"node_id":        d.NodeID,
+			"property":       d.PropertyKey,
+		},
+	})
+
+	return nil
+}
+
 // GetDiscrepanciesByNode returns all discrepancies for a node
 func (s *TruthService) GetDiscrepanciesByNode(ctx context.Context, nodeID string) ([]domain.Discrepancy, error) {
 	return s.repo.GetDiscrepanciesByNode(ctx, nodeID)
@@ -265,6 +311,57 @@ func (s *TruthService) GetDiscrepancy(ctx context.Context, id string) (*domain.D
 	return s.repo.GetDiscrepancy(ctx, id)
 }
 
+// discrepancyCSVHeader is the header row of the discrepancy report
+var discrepancyCSVHeader = []string{"node_id", "node_label", "property", "truth_value", "actual_value", "source", "detected_at"}
+
+// ExportDiscrepanciesCSV writes every unresolved discrepancy to w as CSV,
+// oldest first, flushing each row as it is written; rows are read a page
+// at a time rather than buffering the set
+func (s *TruthService) ExportDiscrepanciesCSV(ctx context.Context, w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(discrepancyCSVHeader); err != nil {
+		return err
+	}
+
+	err := s.repo.EachUnresolvedDiscrepancy(ctx, func(d domain.Discrepancy, nodeLabel string) error {
+		if err := cw.Write([]string{
+			d.NodeID,
+			nodeLabel,
+			d.PropertyKey,
+			csvValue(d.TruthValue),
+			csvValue(d.ActualValue),
+			d.Source,
+			d.DetectedAt.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue renders a truth or discovered value for a CSV cell: strings as
+// they are, nil as empty and anything else as JSON
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
 // UpdateTruthProperty updates a single property in the truth assertion
 func (s *TruthService) UpdateTruthProperty(ctx context.Context, nodeID, key string, value any, operator string) error {
 	node, err := s.repo.GetNode(ctx, nodeID)
@@ -295,6 +392,341 @@ func (s *TruthService) UpdateTruthProperty(ctx context.Context, nodeID, key stri
 	return s.repo.SetNodeTruth(ctx, nodeID, truth)
 }
 
+// HostnameCandidates lists a node's hostname candidates, best first, with
+// the one hostname inference selected and any operator-asserted hostname
+type HostnameCandidates struct {
+	NodeID     string                     `json:"node_id"`
+	Candidates []domain.HostnameCandidate `json:"candidates"`
+	Selected   *domain.HostnameCandidate  `json:"selected"`
+	Truth      string                     `json:"truth_hostname,omitempty"`
+}
+
+// GetHostnameCandidates returns every hostname candidate discovered for a node
+func (s *TruthService) GetHostnameCandidates(ctx context.Context, nodeID string) (*HostnameCandidates, error) {
+	node, err := s.repo.GetNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+
+	result := &HostnameCandidates{
+		NodeID:     nodeID,
+		Candidates: []domain.HostnameCandidate{},
+	}
+	if inference := extractHostnameInference(node.Discovered); inference != nil {
+		result.Candidates = inference.Ranked()
+	}
+	if len(result.Candidates) > 0 {
+		result.Selected = &result.Candidates[0]
+	}
+	if node.Truth != nil {
+		result.Truth, _ = node.Truth.Properties["hostname"].(string)
+	}
+	return result, nil
+}
+
+// Per-property statuses in a node reconciliation
+const (
+	ReconciliationMatch        = "match"        // discovered value equals truth
+	ReconciliationDiscrepancy  = "discrepancy"  // values differ and an open discrepancy tracks it
+	ReconciliationDiffers      = "differs"      // values differ but no discrepancy is open yet
+	ReconciliationUndiscovered = "undiscovered" // truth asserted, nothing discovered
+	ReconciliationUnasserted   = "unasserted"   // discovered, no truth asserted
+)
+
+// PropertyReconciliation compares one property's truth and discovered values
+type PropertyReconciliation struct {
+	Property      string `json:"property"`
+	Truth         any    `json:"truth,omitempty"`
+	Discovered    any    `json:"discovered,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Status        string `json:"status"`
+	DiscrepancyID string `json:"discrepancy_id,omitempty"`
+}
+
+// NodeReconciliation is a node's truth set side by side with what was discovered
+type NodeReconciliation struct {
+	NodeID     string                   `json:"node_id"`
+	Properties []PropertyReconciliation `json:"properties"`
+}
+
+// GetReconciliation compares a node's truth against its latest discovered
+// values. It covers every asserted property plus any truthable property
+// that has been discovered, sorted by property name. Like
+// CheckDiscrepancies, an asserted property not in the discovered map is
+// compared against the node's properties.
+func (s *TruthService) GetReconciliation(ctx context.Context, nodeID string) (*NodeReconciliation, error) {
+	node, err := s.repo.GetNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if node == nil {
+		return nil, fmt.Errorf("node %s not found", nodeID)
+	}
+
+	discrepancies, err := s.repo.GetDiscrepanciesByNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[string]domain.Discrepancy)
+	for _, d := range discrepancies {
+		if !d.IsResolved() {
+			open[d.PropertyKey] = d
+		}
+	}
+
+	var truth map[string]any
+	if node.Truth != nil {
+		truth = node.Truth.Properties
+	}
+	keys := make(map[string]bool)
+	for key := range truth {
+		keys[key] = true
+	}
+	for _, key := range domain.TruthableProperties {
+		if _, ok := node.Discovered[key]; ok {
+			keys[key] = true
+		}
+	}
+
+	result := &NodeReconciliation{NodeID: nodeID, Properties: []PropertyReconciliation{}}
+	for key := range keys {
+		p := PropertyReconciliation{Property: key}
+		truthValue, asserted := truth[key]
+		if asserted {
+			p.Truth = truthValue
+		}
+
+		discovered, found := node.Discovered[key]
+		if found {
+			p.Source = node.DiscoveredSources[key].Source
+		} else if asserted {
+			discovered, found = node.Properties[key]
+			if found {
+				p.Source = node.Source
+			}
+		}
+		if found {
+			p.Discovered = discovered
+		}
+		if d, ok := open[key]; ok {
+			p.DiscrepancyID = d.ID
+			if p.Source == "" {
+				p.Source = d.Source
+			}
+		}
+
+		switch {
+		case !asserted:
+			p.Status = ReconciliationUnasserted
+		case !found:
+			p.Status = ReconciliationUndiscovered
+		case domain.CompareValues(truthValue, discovered):
+			p.Status = ReconciliationMatch
+		case p.DiscrepancyID != "":
+			p.Status = ReconciliationDiscrepancy
+		default:
+			p.Status = ReconciliationDiffers
+		}
+		result.Properties = append(result.Properties, p)
+	}
+
+	sort.Slice(result.Properties, func(i, j int) bool {
+		return result.Properties[i].Property < result.Properties[j].Property
+	})
+	return result, nil
+}
+
+// PinHostnameCandidate asserts one of a node's hostname candidates as
+// operator truth, keeping any other truth properties already set
+func (s *TruthService) PinHostnameCandidate(ctx context.Context, nodeID, hostname, operator string) error {
+	candidates, err := s.GetHostnameCandidates(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+
+	hostname = strings.ToLower(strings.TrimSpace(hostname))
+	found := false
+	for _, c := range candidates.Candidates {
+		if c.Hostname == hostname {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("hostname %q is not a candidate for node %s", hostname, nodeID)
+	}
+
+	truth, err := s.GetTruth(ctx, nodeID)
+	if err != nil {
+		return err
+	}
+	properties := map[string]any{"hostname": hostname}
+	if truth != nil {
+		for k, v := range truth.Properties {
+			if k != "hostname" {
+				properties[k] = v
+			}
+		}
+	}
+	return s.SetTruth(ctx, nodeID, properties, operator)
+}
+
+// TruthImportResult summarizes a bulk truth import
+type TruthImportResult struct {
+	Applied        int              `json:"applied"`
+	Unmatched      []TruthImportRow `json:"unmatched"`
+	Failed         []TruthImportRow `json:"failed"`
+	IgnoredColumns []string         `json:"ignored_columns,omitempty"`
+	Discrepancies  int              `json:"discrepancies"`
+}
+
+// TruthImportRow identifies a CSV row that could not be applied
+type TruthImportRow struct {
+	Row      int    `json:"row"`
+	Identity string `json:"identity"`
+	Reason   string `json:"reason"`
+}
+
+// truthIdentityColumns are the columns that can identify a node, in priority order
+var truthIdentityColumns = []string{"id", "ip", "hostname"}
+
+// ImportTruthCSV asserts operator truth for many nodes from a CSV.
+// The header must include an identity column (id, ip or hostname); every other
+// truthable column becomes a truth property. Empty cells are skipped.
+func (s *TruthService) ImportTruthCSV(ctx context.Context, r io.Reader, operator string) (*TruthImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+
+	identityCol := -1
+	identityKey := ""
+	for _, key := range truthIdentityColumns {
+		for i, col := range header {
+			if col == key {
+				identityCol, identityKey = i, key
+				break
+			}
+		}
+		if identityCol >= 0 {
+			break
+		}
+	}
+	if identityCol < 0 {
+		return nil, fmt.Errorf("CSV must include an identity column (id, ip or hostname)")
+	}
+
+	result := &TruthImportResult{
+		Unmatched: []TruthImportRow{},
+		Failed:    []TruthImportRow{},
+	}
+
+	propertyCols := make(map[int]string)
+	for i, col := range header {
+		if i == identityCol && col == "id" {
+			continue
+		}
+		if domain.IsTruthable(col) {
+			propertyCols[i] = col
+		} else if col != "id" {
+			result.IgnoredColumns = append(result.IgnoredColumns, col)
+		}
+	}
+
+	nodes, err := s.repo.ListNodes(ctx, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	row := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", row, err)
+		}
+
+		identity := ""
+		if identityCol < len(record) {
+			identity = strings.TrimSpace(record[identityCol])
+		}
+		node := matchNode(nodes, identityKey, identity)
+		if node == nil {
+			result.Unmatched = append(result.Unmatched, TruthImportRow{Row: row, Identity: identity, Reason: "no matching node"})
+			continue
+		}
+
+		properties := make(map[string]any)
+		for i, key := range propertyCols {
+			if i < len(record) {
+				if value := strings.TrimSpace(record[i]); value != "" {
+					properties[key] = value
+				}
+			}
+		}
+		if len(properties) == 0 {
+			result.Failed = append(result.Failed, TruthImportRow{Row: row, Identity: identity, Reason: "no truth properties"})
+			continue
+		}
+
+		if err := s.SetTruth(ctx, node.ID, properties, operator); err != nil {
+			result.Failed = append(result.Failed, TruthImportRow{Row: row, Identity: identity, Reason: err.Error()})
+			continue
+		}
+		result.Applied++
+
+		discrepancies, err := s.CheckDiscrepancies(ctx, node.ID, node.Discovered, "truth-import")
+		if err != nil {
+			return nil, err
+		}
+		result.Discrepancies += len(discrepancies)
+	}
+
+	return result, nil
+}
+
+// matchNode finds the node identified by an id, ip or hostname value
+func matchNode(nodes []domain.Node, key, value string) *domain.Node {
+	if value == "" {
+		return nil
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		switch key {
+		case "id":
+			if n.ID == value {
+				return n
+			}
+		case "ip":
+			if n.GetPropertyString("ip") == value {
+				return n
+			}
+		case "hostname":
+			if strings.EqualFold(n.GetPropertyString("hostname"), value) || strings.EqualFold(n.Label, value) {
+				return n
+			}
+			if dns, ok := n.GetDiscovered("reverse_dns"); ok {
+				if name, ok := dns.(string); ok && strings.EqualFold(name, value) {
+					return n
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // generateID creates a random ID for discrepancies
 func generateID() string {
 	b := make([]byte, 16)
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"specularium/internal/domain"
+)
+
+func TestTruthServiceImportTruthCSV(t *testing.T) {
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewTruthService(repo, bus)
+	ctx := context.Background()
+
+	web := domain.NewNode("web1", domain.NodeTypeServer, "web1")
+	web.SetProperty("ip", "10.0.0.10")
+	web.SetDiscovered("hostname", "old-web")
+	db := domain.NewNode("db1", domain.NodeTypeServer, "db1")
+	db.SetProperty("ip", "10.0.0.20")
+	for _, n := range []*domain.Node{web, db} {
+		if err := repo.CreateNode(ctx, n); err != nil {
+			t.Fatalf("CreateNode failed: %v", err)
+		}
+	}
+
+	t.Run("matches rows by ip and reports unmatched", func(t *testing.T) {
+		data := "ip,hostname,owner,rack\n" +
+			"10.0.0.10,web1,alice,r1\n" +
+			"10.0.0.20,,bob,r2\n" +
+			"10.0.0.99,ghost,carol,r3\n"
+
+		result, err := svc.ImportTruthCSV(ctx, strings.NewReader(data), "tester")
+		if err != nil {
+			t.Fatalf("ImportTruthCSV failed: %v", err)
+		}
+		if result.Applied != 2 {
+			t.Errorf("Applied = %d, want 2", result.Applied)
+		}
+		if len(result.Unmatched) != 1 || result.Unmatched[0].Row != 4 || result.Unmatched[0].Identity != "10.0.0.99" {
+			t.Errorf("Unmatched = %+v, want row 4 (10.0.0.99)", result.Unmatched)
+		}
+		if len(result.IgnoredColumns) != 1 || result.IgnoredColumns[0] != "rack" {
+			t.Errorf("IgnoredColumns = %v, want [rack]", result.IgnoredColumns)
+		}
+		// web1 discovered hostname disagrees with asserted truth
+		if result.Discrepancies != 1 {
+			t.Errorf("Discrepancies = %d, want 1", result.Discrepancies)
+		}
+
+		truth, err := svc.GetTruth(ctx, "db1")
+		if err != nil {
+			t.Fatalf("GetTruth failed: %v", err)
+		}
+		if truth == nil || truth.Properties["owner"] != "bob" || truth.AssertedBy != "tester" {
+			t.Errorf("db1 truth = %+v, want owner bob asserted by tester", truth)
+		}
+		if _, ok := truth.Properties["hostname"]; ok {
+			t.Error("empty cells should not be asserted")
+		}
+	})
+
+	t.Run("matches rows by id", func(t *testing.T) {
+		result, err := svc.ImportTruthCSV(ctx, strings.NewReader("id,location\ndb1,basement\n"), "tester")
+		if err != nil {
+			t.Fatalf("ImportTruthCSV failed: %v", err)
+		}
+		if result.Applied != 1 {
+			t.Errorf("Applied = %d, want 1", result.Applied)
+		}
+	})
+
+	t.Run("requires identity column", func(t *testing.T) {
+		if _, err := svc.ImportTruthCSV(ctx, strings.NewReader("owner\nalice\n"), "tester"); err == nil {
+			t.Error("expected error without identity column")
+		}
+	})
+}
+
+func TestReconcileFragmentAutoResolvesDiscrepancies(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, events := newTestEventBus()
+	svc := NewReconcileService(repo, NewTruthService(repo, bus), bus)
+
+	node := domain.NewNode("web1", domain.NodeTypeServer, "web1")
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	truth := &domain.NodeTruth{Properties: map[string]any{"hostname": "web1"}}
+	if err := repo.SetNodeTruth(ctx, node.ID, truth); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+
+	// discover reconciles a hostname and returns the discrepancy event types
+	discover := func(hostname string) []EventType {
+		t.Helper()
+		update := domain.NewNode(node.ID, domain.NodeTypeServer, "web1")
+		update.SetDiscovered("hostname", hostname)
+		fragment := domain.NewGraphFragment()
+		fragment.AddNode(*update)
+		if err := svc.ReconcileFragment(ctx, "verifier", fragment); err != nil {
+			t.Fatalf("ReconcileFragment failed: %v", err)
+		}
+
+		var types []EventType
+		for {
+			select {
+			case e := <-events:
+				if e.Type == EventDiscrepancyCreated || e.Type == EventDiscrepancyResolved {
+					types = append(types, e.Type)
+				}
+			default:
+				return types
+			}
+		}
+	}
+	hasDiscrepancy := func() bool {
+		t.Helper()
+		n, err := repo.GetNode(ctx, node.ID)
+		if err != nil || n == nil {
+			t.Fatalf("GetNode = %v, %v", n, err)
+		}
+		return n.HasDiscrepancy
+	}
+
+	for round, want := range []struct {
+		hostname    string
+		event       EventType
+		discrepancy bool
+	}{
+		{"web1-old", EventDiscrepancyCreated, true},
+		{"web1", EventDiscrepancyResolved, false},
+		{"web1-old", EventDiscrepancyCreated, true},
+		{"web1", EventDiscrepancyResolved, false},
+	} {
+		got := discover(want.hostname)
+		if len(got) != 1 || got[0] != want.event {
+			t.Errorf("round %d (%s): events = %v, want [%s]", round, want.hostname, got, want.event)
+		}
+		if hasDiscrepancy() != want.discrepancy {
+			t.Errorf("round %d (%s): has_discrepancy = %v, want %v", round, want.hostname, !want.discrepancy, want.discrepancy)
+		}
+	}
+
+	// Matching again with nothing open is a no-op
+	if got := discover("web1"); len(got) != 0 {
+		t.Errorf("events = %v, want none", got)
+	}
+
+	discrepancies, err := repo.GetDiscrepanciesByNode(ctx, node.ID)
+	if err != nil {
+		t.Fatalf("GetDiscrepanciesByNode failed: %v", err)
+	}
+	if len(discrepancies) != 2 {
+		t.Fatalf("got %d discrepancies, want 2", len(discrepancies))
+	}
+	for _, d := range discrepancies {
+		if d.Resolution != string(domain.ResolutionAutoMatched) {
+			t.Errorf("discrepancy %s resolution = %q, want auto_matched", d.ID, d.Resolution)
+		}
+	}
+}
+
+func TestTruthServiceHostnameCandidates(t *testing.T) {
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewTruthService(repo, bus)
+	ctx := context.Background()
+
+	// PTR and the SMTP banner disagree; the banner was seen more recently
+	now := time.Now().UTC().Truncate(time.Second)
+	inference := &domain.HostnameInference{}
+	inference.AddCandidate("nas.lan", domain.SourcePTR, now.Add(-time.Hour))
+	inference.AddCandidate("mail.example.com", domain.SourceSMTPBanner, now)
+
+	node := domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	node.SetDiscovered("hostname_inference", inference)
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	if err := repo.SetNodeTruth(ctx, "nas", &domain.NodeTruth{Properties: map[string]any{"owner": "alice"}}); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+
+	result, err := svc.GetHostnameCandidates(ctx, "nas")
+	if err != nil {
+		t.Fatalf("GetHostnameCandidates failed: %v", err)
+	}
+	if len(result.Candidates) != 2 {
+		t.Fatalf("expected both candidates listed, got %+v", result.Candidates)
+	}
+	if result.Selected == nil || result.Selected.Source != domain.SourcePTR || result.Selected.Hostname != "nas.lan" {
+		t.Errorf("expected PTR candidate selected, got %+v", result.Selected)
+	}
+	if result.Candidates[1].Source != domain.SourceSMTPBanner || !result.Candidates[1].ObservedAt.Equal(now) {
+		t.Errorf("expected SMTP candidate second with its timestamp, got %+v", result.Candidates[1])
+	}
+	if result.Truth != "" {
+		t.Errorf("expected no truth hostname, got %q", result.Truth)
+	}
+
+	if err := svc.PinHostnameCandidate(ctx, "nas", "unknown.lan", "tester"); err == nil || !strings.Contains(err.Error(), "not a candidate") {
+		t.Errorf("expected not a candidate error, got %v", err)
+	}
+	if err := svc.PinHostnameCandidate(ctx, "nas", "Mail.Example.com", "tester"); err != nil {
+		t.Fatalf("PinHostnameCandidate failed: %v", err)
+	}
+
+	truth, err := svc.GetTruth(ctx, "nas")
+	if err != nil || truth == nil {
+		t.Fatalf("GetTruth failed: %v", err)
+	}
+	if truth.Properties["hostname"] != "mail.example.com" || truth.Properties["owner"] != "alice" {
+		t.Errorf("expected pinned hostname alongside existing truth, got %v", truth.Properties)
+	}
+	if truth.AssertedBy != "tester" {
+		t.Errorf("expected asserted by tester, got %q", truth.AssertedBy)
+	}
+
+	result, err = svc.GetHostnameCandidates(ctx, "nas")
+	if err != nil {
+		t.Fatalf("GetHostnameCandidates failed: %v", err)
+	}
+	if result.Truth != "mail.example.com" {
+		t.Errorf("expected truth hostname mail.example.com, got %q", result.Truth)
+	}
+
+	if _, err := svc.GetHostnameCandidates(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestTruthServiceExportDiscrepanciesCSV(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewTruthService(repo, bus)
+
+	node := domain.NewNode("web1", domain.NodeTypeServer, "Web Server")
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	detected := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, d := range []*domain.Discrepancy{
+		{ID: "d-open", NodeID: "web1", PropertyKey: "hostname", TruthValue: "web1", ActualValue: "web-01", Source: "verifier", DetectedAt: detected},
+		{ID: "d-resolved", NodeID: "web1", PropertyKey: "ip", TruthValue: "10.0.0.1", ActualValue: "10.0.0.2", Source: "scanner", DetectedAt: detected},
+	} {
+		if err := repo.CreateDiscrepancy(ctx, d); err != nil {
+			t.Fatalf("CreateDiscrepancy failed: %v", err)
+		}
+	}
+	if err := repo.ResolveDiscrepancy(ctx, "d-resolved", "dismissed"); err != nil {
+		t.Fatalf("ResolveDiscrepancy failed: %v", err)
+	}
+
+	var out strings.Builder
+	if err := svc.ExportDiscrepanciesCSV(ctx, &out); err != nil {
+		t.Fatalf("ExportDiscrepanciesCSV failed: %v", err)
+	}
+
+	want := "node_id,node_label,property,truth_value,actual_value,source,detected_at\n" +
+		"web1,Web Server,hostname,web1,web-01,verifier,2024-03-01T12:00:00Z\n"
+	if out.String() != want {
+		t.Errorf("CSV =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestTruthServiceGetReconciliation(t *testing.T) {
+	ctx := context.Background()
+	repo := newTestRepo(t)
+	bus, _ := newTestEventBus()
+	svc := NewTruthService(repo, bus)
+
+	node := domain.NewNode("nas", domain.NodeTypeServer, "nas")
+	node.SetProperty("ip", "192.168.1.20")
+	node.SetDiscoveredFrom("verifier", "hostname", "nas", 0)
+	node.SetDiscoveredFrom("scanner", "mac_address", "AA:BB:CC:DD:EE:FF", 0)
+	if err := repo.CreateNode(ctx, node); err != nil {
+		t.Fatalf("CreateNode failed: %v", err)
+	}
+	truth := &domain.NodeTruth{Properties: map[string]any{
+		"hostname": "nas",
+		"ip":       "192.168.1.10",
+		"owner":    "ops",
+	}}
+	if err := repo.SetNodeTruth(ctx, node.ID, truth); err != nil {
+		t.Fatalf("SetNodeTruth failed: %v", err)
+	}
+
+	// Before any check the divergent IP has no discrepancy yet
+	got, err := svc.GetReconciliation(ctx, node.ID)
+	if err != nil {
+		t.Fatalf("GetReconciliation failed: %v", err)
+	}
+	if status := reconciliationStatuses(got); status["ip"] != ReconciliationDiffers {
+		t.Errorf("ip status before check = %q, want %q", status["ip"], ReconciliationDiffers)
+	}
+
+	created, err := svc.CheckDiscrepancies(ctx, node.ID, map[string]any{"hostname": "nas"}, "verifier")
+	if err != nil || len(created) != 1 {
+		t.Fatalf("CheckDiscrepancies = %v, %v; want one ip discrepancy", created, err)
+	}
+
+	got, err = svc.GetReconciliation(ctx, node.ID)
+	if err != nil {
+		t.Fatalf("GetReconciliation failed: %v", err)
+	}
+	want := map[string]string{
+		"hostname":    ReconciliationMatch,
+		"ip":          ReconciliationDiscrepancy,
+		"mac_address": ReconciliationUnasserted,
+		"owner":       ReconciliationUndiscovered,
+	}
+	if status := reconciliationStatuses(got); !reflect.DeepEqual(status, want) {
+		t.Errorf("statuses = %v, want %v", status, want)
+	}
+
+	for _, p := range got.Properties {
+		switch p.Property {
+		case "hostname":
+			if p.Source != "verifier" || p.Truth != "nas" || p.Discovered != "nas" {
+				t.Errorf("hostname = %+v, want nas from verifier", p)
+			}
+		case "ip":
+			if p.Truth != "192.168.1.10" || p.Discovered != "192.168.1.20" || p.DiscrepancyID != created[0].ID {
+				t.Errorf("ip = %+v, want truth .10, discovered .20, discrepancy %s", p, created[0].ID)
+			}
+		}
+	}
+
+	if _, err := svc.GetReconciliation(ctx, "missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("GetReconciliation(missing) error = %v, want not found", err)
+	}
+}
+
+// reconciliationStatuses maps each property to its status
+func reconciliationStatuses(r *NodeReconciliation) map[string]string {
+	statuses := make(map[string]string)
+	for _, p := range r.Properties {
+		statuses[p.Property] = p.Status
+	}
+	return statuses
+}
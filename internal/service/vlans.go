@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"specularium/internal/domain"
+)
+
+// vlanSource is the source recorded on edges and events produced by VLAN
+// membership inference
+const vlanSource = "vlan"
+
+// VLAN is a VLAN and the interfaces that are members of it
+type VLAN struct {
+	ID      int      `json:"vlan_id"`
+	Members []string `json:"members"` // interface node IDs
+	Devices []string `json:"devices"` // parent node IDs of the members
+}
+
+// vlanTag is the tag marking an interface as a member of a VLAN
+func vlanTag(id int) string {
+	return fmt.Sprintf("vlan:%d", id)
+}
+
+// vlanMembers groups interface nodes by the VLANs they carry
+func vlanMembers(nodes []domain.Node) map[int][]domain.Node {
+	members := make(map[int][]domain.Node)
+	for _, node := range nodes {
+		for _, id := range node.VLANs() {
+			members[id] = append(members[id], node)
+		}
+	}
+	return members
+}
+
+// syncVLANs tags every interface with its VLAN membership and links
+// interfaces on different devices that share a VLAN with a vlan edge. An
+// edge carries the lowest shared VLAN as vlan_id and all of them as vlans;
+// more than one shared VLAN marks the link as a trunk.
+func (r *ReconcileService) syncVLANs(ctx context.Context) (int, error) {
+	nodes, err := r.repo.ListNodes(ctx, string(domain.NodeTypeInterface), "")
+	if err != nil {
+		return 0, fmt.Errorf("list interfaces: %w", err)
+	}
+
+	changed := 0
+	shared := make(map[[2]string][]int)
+	for id, members := range vlanMembers(nodes) {
+		for i, a := range members {
+			if err := r.repo.AddTag(ctx, a.ID, vlanTag(id)); err != nil {
+				return changed, fmt.Errorf("tag %s: %w", a.ID, err)
+			}
+			for _, b := range members[i+1:] {
+				if a.ParentID != "" && a.ParentID == b.ParentID {
+					continue
+				}
+				pair := [2]string{a.ID, b.ID}
+				if b.ID < a.ID {
+					pair = [2]string{b.ID, a.ID}
+				}
+				shared[pair] = append(shared[pair], id)
+			}
+		}
+	}
+
+	for pair, ids := range shared {
+		slices.Sort(ids)
+		edge := domain.NewEdge(pair[0], pair[1], domain.EdgeTypeVLAN)
+		if err := edge.SetVLANID(ids[0]); err != nil {
+			return changed, err
+		}
+		edge.SetProperty(domain.LinkVLANsProperty, ids)
+		edge.SetProperty("trunk", len(ids) > 1)
+		edge.SetProperty("source", vlanSource)
+
+		ok, err := r.reconcileEdge(ctx, vlanSource, *edge)
+		if err != nil {
+			return changed, err
+		}
+		if ok {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// ListVLANs returns every VLAN carried by an interface node, in VLAN ID
+// order, with its member interfaces and their devices
+func (s *GraphService) ListVLANs(ctx context.Context) ([]VLAN, error) {
+	nodes, err := s.repo.ListNodes(ctx, string(domain.NodeTypeInterface), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+
+	vlans := make([]VLAN, 0)
+	for id, members := range vlanMembers(nodes) {
+		vlan := VLAN{ID: id, Members: make([]string, 0, len(members)), Devices: make([]string, 0)}
+		for _, member := range members {
+			vlan.Members = append(vlan.Members, member.ID)
+			if member.ParentID != "" {
+				vlan.Devices = append(vlan.Devices, member.ParentID)
+			}
+		}
+		slices.Sort(vlan.Members)
+		slices.Sort(vlan.Devices)
+		vlan.Devices = slices.Compact(vlan.Devices)
+		vlans = append(vlans, vlan)
+	}
+	slices.SortFunc(vlans, func(a, b VLAN) int { return a.ID - b.ID })
+	return vlans, nil
+}
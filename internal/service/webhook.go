@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Webhook delivery defaults applied when options are unset
+const (
+	DefaultWebhookTimeout     = 10 * time.Second
+	DefaultWebhookRetries     = 3
+	DefaultWebhookBackoff     = time.Second
+	DefaultWebhookConcurrency = 4
+)
+
+// DefaultWebhookEvents are sent to webhooks that don't list event types
+var DefaultWebhookEvents = []EventType{EventNodeUnreachable, EventDiscrepancyCreated}
+
+// Webhook is a URL notified of selected events
+type Webhook struct {
+	URL string
+	// Events selects the event types sent (empty = DefaultWebhookEvents)
+	Events []EventType
+	// Payload is a text/template rendering the request body. The template
+	// sees the event as decoded JSON (.type, .seq, .timestamp, .payload)
+	// and has a json function for quoting values. Empty sends the event
+	// itself as JSON.
+	Payload string
+	// Headers are added to every request, e.g. for authorization
+	Headers map[string]string
+}
+
+// WebhookOptions controls webhook delivery
+type WebhookOptions struct {
+	// Timeout bounds a single request (0 = default)
+	Timeout time.Duration
+	// Retries is how many times a failed delivery is retried (0 = default,
+	// negative disables retries)
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each
+	// subsequent one (0 = default)
+	Backoff time.Duration
+}
+
+// webhook is a Webhook with its template compiled and event set built
+type webhook struct {
+	Webhook
+	events  map[EventType]bool
+	payload *template.Template
+}
+
+// WebhookNotifier posts selected bus events to webhook URLs. Delivery runs
+// off the bus goroutine; failures are logged and never block publishers.
+type WebhookNotifier struct {
+	hooks   []webhook
+	client  *http.Client
+	retries int
+	backoff time.Duration
+	logger  *slog.Logger
+	sem     chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewWebhookNotifier creates a notifier for hooks. It fails when a payload
+// template does not parse.
+func NewWebhookNotifier(hooks []Webhook, opts WebhookOptions) (*WebhookNotifier, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWebhookTimeout
+	}
+	switch {
+	case opts.Retries < 0:
+		opts.Retries = 0
+	case opts.Retries == 0:
+		opts.Retries = DefaultWebhookRetries
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultWebhookBackoff
+	}
+
+	n := &WebhookNotifier{
+		client:  &http.Client{Timeout: opts.Timeout},
+		retries: opts.Retries,
+		backoff: opts.Backoff,
+		logger:  slog.Default(),
+		sem:     make(chan struct{}, DefaultWebhookConcurrency),
+	}
+	for i, h := range hooks {
+		if h.URL == "" {
+			return nil, fmt.Errorf("webhook %d: url is required", i)
+		}
+		compiled := webhook{Webhook: h, events: make(map[EventType]bool)}
+		events := h.Events
+		if len(events) == 0 {
+			events = DefaultWebhookEvents
+		}
+		for _, t := range events {
+			compiled.events[t] = true
+		}
+		if h.Payload != "" {
+			tmpl, err := template.New(h.URL).Funcs(template.FuncMap{"json": webhookJSON}).Parse(h.Payload)
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: invalid payload template: %w", h.URL, err)
+			}
+			compiled.payload = tmpl
+		}
+		n.hooks = append(n.hooks, compiled)
+	}
+	return n, nil
+}
+
+// SetLogger sets the logger used for delivery failures
+func (n *WebhookNotifier) SetLogger(logger *slog.Logger) {
+	n.logger = logger
+}
+
+// Start subscribes to bus and delivers matching events until ctx is done
+func (n *WebhookNotifier) Start(ctx context.Context, bus *EventBus) {
+	ch := make(chan Event, 100)
+	bus.Subscribe(ch, n.wants)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-ch:
+				n.dispatch(ctx, event)
+			}
+		}
+	}()
+}
+
+// Wait blocks until in-flight deliveries finish
+func (n *WebhookNotifier) Wait() {
+	n.wg.Wait()
+}
+
+// wants reports whether any webhook is subscribed to the event's type
+func (n *WebhookNotifier) wants(e Event) bool {
+	for _, h := range n.hooks {
+		if h.events[e.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+// dispatch starts a delivery to each webhook subscribed to the event. It
+// blocks while DefaultWebhookConcurrency deliveries are in flight; the bus
+// drops events for us in the meantime rather than waiting.
+func (n *WebhookNotifier) dispatch(ctx context.Context, event Event) {
+	for i := range n.hooks {
+		h := &n.hooks[i]
+		if !h.events[event.Type] {
+			continue
+		}
+		body, err := h.render(event)
+		if err != nil {
+			n.logger.Warn("failed to render webhook payload", "url", h.URL, "event", event.Type, "error", err)
+			continue
+		}
+
+		select {
+		case n.sem <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		n.wg.Add(1)
+		go func() {
+			defer func() {
+				<-n.sem
+				n.wg.Done()
+			}()
+			n.deliver(ctx, h, event, body)
+		}()
+	}
+}
+
+// deliver posts body to the webhook, retrying network errors, 429s and
+// 5xx responses with exponential backoff
+func (n *WebhookNotifier) deliver(ctx context.Context, h *webhook, event Event, body []byte) {
+	delay := n.backoff
+	var err error
+	for attempt := 0; attempt <= n.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(delay):
+				delay *= 2
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		var retry bool
+		if retry, err = n.post(ctx, h, body); err == nil {
+			return
+		} else if !retry {
+			break
+		}
+	}
+	n.logger.Warn("webhook delivery failed",
+		"url", h.URL, "event", event.Type, "seq", event.Seq, "error", err)
+}
+
+// post sends one request and reports whether a failure is worth retrying
+func (n *WebhookNotifier) post(ctx context.Context, h *webhook, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "specularium-webhook")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// render produces the request body for event
+func (h *webhook) render(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	if h.payload == nil {
+		return data, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := h.payload.Execute(&buf, fields); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// webhookJSON encodes v as JSON for use inside payload templates
+func webhookJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// webhookServer records request bodies, failing the first failures requests
+func webhookServer(t *testing.T, failures int32) (*httptest.Server, <-chan []byte) {
+	t.Helper()
+	bodies := make(chan []byte, 8)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		bodies <- body
+	}))
+	t.Cleanup(srv.Close)
+	return srv, bodies
+}
+
+func receiveBody(t *testing.T, bodies <-chan []byte) map[string]any {
+	t.Helper()
+	select {
+	case body := <-bodies:
+		var got map[string]any
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Fatalf("payload is not JSON: %v: %s", err, body)
+		}
+		return got
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for webhook")
+		return nil
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	discrepancy := Event{
+		Type: EventDiscrepancyCreated,
+		Payload: map[string]any{
+			"discrepancy_id": "d-1",
+			"node_id":        "192-168-1-20",
+			"property":       "ip",
+			"truth":          "192.168.1.20",
+			"actual":         "192.168.1.21",
+		},
+	}
+
+	t.Run("default payload", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		srv, bodies := webhookServer(t, 0)
+		bus, _ := newTestEventBus()
+
+		n, err := NewWebhookNotifier([]Webhook{{URL: srv.URL}}, WebhookOptions{})
+		if err != nil {
+			t.Fatalf("NewWebhookNotifier failed: %v", err)
+		}
+		n.Start(ctx, bus)
+
+		bus.Publish(Event{Type: EventDiscoveryProgress})
+		bus.Publish(discrepancy)
+
+		got := receiveBody(t, bodies)
+		if got["type"] != string(EventDiscrepancyCreated) || got["seq"] != float64(2) || got["timestamp"] == nil {
+			t.Errorf("payload = %v, want discrepancy-created seq 2 with timestamp", got)
+		}
+		payload, _ := got["payload"].(map[string]any)
+		if payload["node_id"] != "192-168-1-20" || payload["property"] != "ip" || payload["actual"] != "192.168.1.21" {
+			t.Errorf("payload.payload = %v", payload)
+		}
+		n.Wait()
+		if len(bodies) != 0 {
+			t.Error("unselected event types should not be delivered")
+		}
+	})
+
+	t.Run("template and retry", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		srv, bodies := webhookServer(t, 2)
+		bus, _ := newTestEventBus()
+
+		n, err := NewWebhookNotifier([]Webhook{{
+			URL:     srv.URL,
+			Events:  []EventType{EventDiscrepancyCreated},
+			Payload: `{"text": {{printf "%s on %s" .type .payload.node_id | json}}}`,
+		}}, WebhookOptions{Backoff: time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewWebhookNotifier failed: %v", err)
+		}
+		n.Start(ctx, bus)
+		bus.Publish(discrepancy)
+
+		got := receiveBody(t, bodies)
+		if got["text"] != "discrepancy-created on 192-168-1-20" {
+			t.Errorf("payload = %v", got)
+		}
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		if _, err := NewWebhookNotifier([]Webhook{{URL: "http://example.invalid", Payload: "{{.type"}}, WebhookOptions{}); err == nil {
+			t.Error("expected an error for an unparseable template")
+		}
+	})
+}